@@ -0,0 +1,13 @@
+//go:build faultinject
+
+package main
+
+import (
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/faultinject"
+)
+
+// Fault-injection builds add the -fault-* flags to every command.
+func init() {
+	cli.AddFlagHook(faultinject.RegisterFlags, faultinject.InstallFromFlags)
+}
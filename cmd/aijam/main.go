@@ -0,0 +1,38 @@
+// Command aijam bundles the jam tools into a single binary with subcommands.
+package main
+
+import (
+	"os"
+
+	"elastic-ai-jam-2025/internal/analyze"
+	"elastic-ai-jam-2025/internal/attack"
+	"elastic-ai-jam-2025/internal/cleanup"
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/flood"
+	"elastic-ai-jam-2025/internal/play"
+	"elastic-ai-jam-2025/internal/reportcmd"
+	"elastic-ai-jam-2025/internal/selfplay"
+	"elastic-ai-jam-2025/internal/smoke"
+	"elastic-ai-jam-2025/internal/tscmd"
+	"elastic-ai-jam-2025/internal/validate"
+)
+
+var commands = []cli.Command{
+	{Name: "play", Summary: "register players over TCP and have them play games", Run: play.Run, Flags: play.Flags},
+	{Name: "flood", Summary: "mass-register players over TCP", Run: flood.Run, Flags: flood.Flags},
+	{Name: "attack", Summary: "flood the detail endpoint of a player's current game", Run: attack.Run, Flags: attack.Flags},
+	{Name: "analyze", Summary: "fetch the leaderboard and each player's game history", Run: analyze.Run, Flags: analyze.Flags},
+	{Name: "watch", Summary: "poll the leaderboard and print chip changes", Run: analyze.RunWatch, Flags: analyze.WatchFlags},
+	{Name: "track", Summary: "follow one player live: games joined, results and chips (track -player <id>)", Run: analyze.RunTrack, Flags: analyze.TrackFlags},
+	{Name: "selfplay", Summary: "run play against an in-process mock server", Run: selfplay.Run, Flags: selfplay.Flags},
+	{Name: "report", Summary: "compare two JSON run reports (report diff <old> <new>)", Run: reportcmd.Run},
+	{Name: "timeseries", Summary: "merge run time series files for overlays (timeseries merge <files>)", Run: tscmd.Run},
+	{Name: "validate-protocol", Summary: "check server messages against the expected shapes", Run: validate.Run, Flags: validate.Flags},
+	{Name: "smoke", Summary: "check registration, seating and the HTTP API in under a minute", Run: smoke.Run, Flags: smoke.Flags},
+	{Name: "cleanup", Summary: "delete or park the accounts of earlier runs", Run: cleanup.Run, Flags: cleanup.Flags},
+	cli.ConfigCommand(),
+}
+
+func main() {
+	os.Exit(cli.Dispatch("aijam", commands, os.Args[1:]))
+}
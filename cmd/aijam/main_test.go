@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+// TestCommands builds the flag set of every command, which panics on a flag
+// registered twice, and checks that names and aliases are unique.
+func TestCommands(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, cmd := range commands {
+		for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+			if seen[name] {
+				t.Errorf("command name %q is used twice", name)
+			}
+			seen[name] = true
+		}
+		if cmd.Run == nil {
+			t.Errorf("command %s has no Run", cmd.Name)
+		}
+		if cmd.Flags != nil && cmd.Flags() == nil {
+			t.Errorf("command %s has no flag set", cmd.Name)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+// Package analyze implements the "analyze", "watch" and "track" commands,
+// which read the leaderboard and player game history from the REST API.
+package analyze
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/latency"
+	"elastic-ai-jam-2025/internal/manifest"
+	"elastic-ai-jam-2025/internal/play"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Config is the configuration of the analyze, watch and track commands.
+type Config struct {
+	cli.Common
+
+	LeaderboardLimit int // Max number of leaderboard entries to fetch
+	PlayerGamesLimit int // Max number of games to fetch per player
+	// Epoch restricts the leaderboard to one epoch; allEpochs shows every
+	// epoch, each in its own section.
+	Epoch int
+	// TopHands is the number of starting hands in the showdown table.
+	TopHands int
+	// TopOpponents is the number of opponents in the -games-manifest
+	// toughest opponents table.
+	TopOpponents int
+
+	// GamesManifest, when set, is a play -games-manifest file: analyze then
+	// fetches the details of those games only, instead of walking the
+	// leaderboard.
+	GamesManifest string
+
+	// Results, when set, is a play -results-out file recorded with
+	// -record-hands and -enrich: analyze then computes, without any
+	// request, where the chips our players lost went; see chipflow.go.
+	Results string
+}
+
+// DefaultConfig returns the defaults the standalone analyzer used.
+func DefaultConfig() Config {
+	common := cli.DefaultCommon()
+	// The analyzer always printed every request and raw response body.
+	common.LogLevel = "debug"
+	return Config{
+		Common:           common,
+		LeaderboardLimit: 100,
+		PlayerGamesLimit: 50,
+		Epoch:            allEpochs,
+		TopHands:         20,
+		TopOpponents:     20,
+	}
+}
+
+// RegisterFlags adds the analyze flags to fs.
+func (cfg *Config) RegisterFlags(fs *flag.FlagSet) {
+	cfg.Common.Register(fs)
+	cfg.Common.RegisterClockSkewFlag(fs)
+	cfg.Common.RegisterFixtureFlags(fs)
+	cfg.Common.RegisterSlowestFlags(fs)
+	fs.IntVar(&cfg.LeaderboardLimit, "leaderboard-limit", cfg.LeaderboardLimit, "max number of leaderboard entries to fetch")
+	fs.IntVar(&cfg.PlayerGamesLimit, "games-limit", cfg.PlayerGamesLimit, "max number of games to fetch per player")
+	fs.IntVar(&cfg.Epoch, "epoch", cfg.Epoch, "only show leaderboard entries of this epoch (-1: every epoch, grouped)")
+	fs.IntVar(&cfg.TopHands, "top-hands", cfg.TopHands, "starting hands listed in the showdown table (0: all)")
+	fs.IntVar(&cfg.TopOpponents, "top-opponents", cfg.TopOpponents, "opponents listed in the -games-manifest toughest opponents and -results chip flow tables (0: all)")
+	fs.StringVar(&cfg.GamesManifest, "games-manifest", cfg.GamesManifest, "analyze only the games of this play -games-manifest file")
+	fs.StringVar(&cfg.Results, "results", cfg.Results, "compute the chip flow between our players and their opponents from this play -results-out file, recorded with -record-hands and -enrich, without fetching anything")
+}
+
+func newFlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	return fs
+}
+
+// Flags returns the analyze flag set with default values.
+func Flags() *flag.FlagSet {
+	cfg := DefaultConfig()
+	return newFlagSet(&cfg)
+}
+
+// Run is the entry point of the analyze command.
+func Run(args []string) int {
+	cfg := DefaultConfig()
+	fs := newFlagSet(&cfg)
+	if code, stop := cli.Parse(fs, args); stop {
+		return code
+	}
+	closeLog, err := cfg.SetupLogging()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer closeLog()
+	if err := cfg.SetupFixtures(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	cfg.SetupSlowest()
+
+	rep := report.New("analyze", cli.Effective(fs))
+	api := httpapi.New(cfg.BaseURL.First(), cfg.RequestTimeout)
+	var code int
+	if cfg.Results != "" {
+		if cfg.GamesManifest != "" {
+			fmt.Fprintln(os.Stderr, "Error: -results and -games-manifest are mutually exclusive")
+			return 2
+		}
+		sessions, err := readResults(cfg.Results)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		code = analyzeResults(&cfg, sessions, rep)
+	} else if cfg.GamesManifest != "" {
+		m, err := manifest.Read(cfg.GamesManifest)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		code = analyzeManifest(&cfg, m, api, rep)
+	} else {
+		code = analyze(&cfg, api, rep)
+	}
+	cfg.CheckClockSkew()
+	httpapi.Fixtures.Print(os.Stdout)
+	httpapi.Slowest.Print(os.Stdout)
+	status := ""
+	if code != 0 {
+		status = report.StatusFailed
+	}
+	rep.Finish(status, "")
+	cfg.WriteReport(rep)
+	return code
+}
+
+func analyze(cfg *Config, api *httpapi.Client, rep *report.Report) int {
+	var fetchErrors errclass.Counter
+	var leaderboardLatency, gamesLatency latency.Histogram
+	defer func() {
+		rep.SetErrors(fetchErrors.Snapshot())
+		rep.Latencies["leaderboard"] = leaderboardLatency.Summary()
+		rep.Latencies["player_games"] = gamesLatency.Summary()
+	}()
+
+	fmt.Println("Fetching leaderboard...")
+
+	// 1. Get Leaderboard
+	start := time.Now()
+	leaderboardData, err := api.Leaderboard(cfg.LeaderboardLimit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching leaderboard: %v\n", err)
+		fetchErrors.AddErr(err)
+		return 1
+	}
+	leaderboardLatency.Since(start)
+
+	if len(leaderboardData.Entries) == 0 {
+		fmt.Println("Leaderboard is empty or no entries found (check DEBUG output for raw response).")
+		// If an empty list is a valid scenario and we got a 200 OK, it's genuinely empty.
+		return 0
+	}
+
+	fmt.Printf("Found %d players on the leaderboard (up to %d requested).\n", len(leaderboardData.Entries), cfg.LeaderboardLimit)
+	groups := groupByEpoch(leaderboardData.Entries, cfg.Epoch)
+	if len(groups) == 0 {
+		fmt.Printf("No leaderboard entries in epoch %d.\n", cfg.Epoch)
+		return 0
+	}
+	for _, g := range groups {
+		ep := strconv.Itoa(g.Epoch)
+		rep.Counters["players"] += int64(len(g.Entries))
+		rep.Counters["players_epoch_"+ep] = int64(len(g.Entries))
+		rep.Counters["chips_epoch_"+ep] = int64(g.chips())
+		rep.Counters["leaderboard_games_epoch_"+ep] = int64(g.games())
+	}
+	fmt.Println("-------------------------------------------------------------")
+
+	// 2. For each player, get their games
+	hands := newHandTally()
+	for _, g := range groups {
+		fmt.Printf("\n=== Epoch %d: %d players, %d chips, %d games ===\n", g.Epoch, len(g.Entries), g.chips(), g.games())
+		analyzePlayers(cfg, api, rep, g.Entries, hands, &fetchErrors, &gamesLatency)
+	}
+
+	// 3. Tally the hands shown down in those games
+	hands.print(os.Stdout, cfg.TopHands)
+	hands.fill(rep)
+
+	fmt.Println("\nFinished processing leaderboard and player games.")
+	return 0
+}
+
+// analyzePlayers fetches and prints the games of the players of entries, and
+// adds them to hands.
+func analyzePlayers(cfg *Config, api *httpapi.Client, rep *report.Report, entries []httpapi.LeaderboardEntry, hands *handTally, fetchErrors *errclass.Counter, gamesLatency *latency.Histogram) {
+	for i, playerEntry := range entries {
+		fmt.Printf("\n[%d/%d] Fetching games for player: %s (Epoch: %d, Chips: %d, Games: %d)\n",
+			i+1, len(entries), playerEntry.PlayerID, playerEntry.Epoch, playerEntry.Chips, playerEntry.GameCount)
+
+		start := time.Now()
+		playerGamesData, err := api.PlayerGames(playerEntry.PlayerID, cfg.PlayerGamesLimit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Error fetching games for player %s: %v\n", playerEntry.PlayerID, err)
+			fetchErrors.AddErr(err)
+			rep.Counters["player_fetch_errors"]++
+			continue
+		}
+		gamesLatency.Since(start)
+		rep.Counters["games"] += int64(len(playerGamesData.Games))
+
+		if len(playerGamesData.Games) == 0 {
+			fmt.Printf("  Player %s has no game history recorded (or none within the limit of %d, check DEBUG for raw response).\n", playerEntry.PlayerID, cfg.PlayerGamesLimit)
+			continue
+		}
+
+		fmt.Printf("  Found %d games for player %s (up to %d requested):\n", len(playerGamesData.Games), playerEntry.PlayerID, cfg.PlayerGamesLimit)
+		for _, game := range playerGamesData.Games {
+			fmt.Printf("    - Game ID: %s, Timestamp: %s, Chips Delta: %d\n",
+				game.Game.GameID, game.Game.Timestamp, game.User.ChipsDelta)
+			hands.add(&game.Game)
+		}
+		fmt.Println("-------------------------------------------------------------")
+	}
+}
+
+// analyzeManifest fetches the details of the games of m and prints, for
+// each, our players and the others seated at it. It then fetches our
+// players' games for their chip deltas and ranks the opponents they met.
+func analyzeManifest(cfg *Config, m *manifest.Manifest, api *httpapi.Client, rep *report.Report) int {
+	var fetchErrors errclass.Counter
+	var gameLatency, gamesLatency latency.Histogram
+	defer func() {
+		rep.SetErrors(fetchErrors.Snapshot())
+		rep.Latencies["game"] = gameLatency.Summary()
+		rep.Latencies["player_games"] = gamesLatency.Summary()
+	}()
+	rep.Counters["games"] = int64(len(m.Games))
+
+	fmt.Printf("Fetching %d games from the manifest (created %s)...\n", len(m.Games), m.CreatedAt.Format(time.RFC3339))
+	fmt.Println("-------------------------------------------------------------")
+	details := make(map[string]*httpapi.GameDetail, len(m.Games))
+	for i, g := range m.Games {
+		fmt.Printf("\n[%d/%d] Game %s (observed %s to %s, end: %s)\n", i+1, len(m.Games), g.GameID,
+			g.FirstEvent.Format(time.RFC3339), g.LastEvent.Format(time.RFC3339), orNone(g.TerminalEvent))
+		fmt.Printf("  Our players: %s\n", strings.Join(g.Players, ", "))
+
+		start := time.Now()
+		detail, err := api.Game(g.GameID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Error fetching game %s: %v\n", g.GameID, err)
+			fetchErrors.AddErr(err)
+			rep.Counters["game_fetch_errors"]++
+			continue
+		}
+		gameLatency.Since(start)
+		details[g.GameID] = detail
+
+		ours := make(map[string]bool, len(g.Players))
+		for _, p := range g.Players {
+			ours[p] = true
+		}
+		for _, p := range detail.GameState.Players {
+			who := "other"
+			if ours[p.PlayerID] {
+				who = "ours"
+			}
+			fmt.Printf("    - %-30s chips %8d  (%s)\n", p.PlayerID, p.Chips, who)
+		}
+		if len(detail.GameState.Winners) > 0 {
+			fmt.Printf("  Winners: %s\n", detail.GameState.Winners)
+		}
+	}
+
+	deltas := make(map[string]map[string]int)
+	for _, player := range ourPlayers(m) {
+		start := time.Now()
+		games, err := api.PlayerGames(player, cfg.PlayerGamesLimit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Error fetching games for player %s: %v\n", player, err)
+			fetchErrors.AddErr(err)
+			rep.Counters["player_fetch_errors"]++
+			continue
+		}
+		gamesLatency.Since(start)
+		for _, g := range games.Games {
+			if deltas[g.Game.GameID] == nil {
+				deltas[g.Game.GameID] = make(map[string]int)
+			}
+			deltas[g.Game.GameID][player] = g.User.ChipsDelta
+		}
+	}
+	opponents := toughestOpponents(m, details, deltas)
+	printOpponents(os.Stdout, opponents, cfg.TopOpponents)
+	fillOpponents(rep, opponents)
+
+	fmt.Println("\nFinished processing the manifest games.")
+	if n := rep.Counters["game_fetch_errors"]; n > 0 && n == int64(len(m.Games)) {
+		return 1
+	}
+	return 0
+}
+
+// analyzeResults prints and records the chip flow of the sessions. A run
+// without hand records has nothing to attribute, which fails the command.
+func analyzeResults(cfg *Config, sessions []*play.SessionResult, rep *report.Report) int {
+	rep.Counters["sessions"] = int64(len(sessions))
+	hands := 0
+	for _, s := range sessions {
+		hands += len(s.HandLog)
+	}
+	rep.Counters["hands"] = int64(hands)
+	fmt.Printf("Read %d sessions and %d hands from %s\n", len(sessions), hands, cfg.Results)
+	if hands == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no hand records: the results need play -record-hands")
+		return 1
+	}
+	flows, totals := chipFlows(sessions)
+	printChipFlows(os.Stdout, flows, totals, cfg.TopOpponents)
+	fillChipFlows(rep, flows, totals)
+	return 0
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none seen"
+	}
+	return s
+}
+
+// ourPlayers returns every player of the manifest, sorted.
+func ourPlayers(m *manifest.Manifest) []string {
+	seen := make(map[string]bool)
+	var players []string
+	for _, g := range m.Games {
+		for _, p := range g.Players {
+			if !seen[p] {
+				seen[p] = true
+				players = append(players, p)
+			}
+		}
+	}
+	sort.Strings(players)
+	return players
+}
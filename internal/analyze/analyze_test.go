@@ -0,0 +1,145 @@
+package analyze
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// recordFixtures rerecords testdata/fixtures from fakeAPI instead of
+// replaying them: go test ./internal/analyze -run Fixtures -record-fixtures
+var recordFixtures = flag.Bool("record-fixtures", false, "record testdata/fixtures from the fake API instead of replaying them")
+
+// fakeAPI serves a leaderboard of three players over two epochs, and their
+// games: alice won a showdown against bob with AKs over QQ, and played a
+// game without cards; carol has no games.
+func fakeAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	showdown := map[string]any{
+		"game_id": "g1", "type": "event_game_over", "timestamp": "2025-06-01T10:00:00Z",
+		"game_state": map[string]any{
+			"players": []map[string]any{
+				{"player_id": "alice", "chips": 2000, "hand": []string{"Ah", "Kh"}},
+				{"player_id": "bob", "chips": 0, "hand": []string{"Qs", "Qd"}},
+			},
+			"table":   []string{"2c", "7d", "9h", "Jh", "3h"},
+			"winners": []string{"alice"},
+		},
+	}
+	quiet := map[string]any{
+		"game_id": "g2", "type": "event_game_over", "timestamp": "2025-06-01T11:00:00Z",
+		"game_state": map[string]any{"players": []map[string]any{{"player_id": "alice", "chips": 2100}}},
+	}
+	routes := map[string]any{
+		"/leaderboard": map[string]any{"entries": []map[string]any{
+			{"player_id": "alice", "chips": 2100, "max_chips": 2100, "epoch": 1, "game_count": 2},
+			{"player_id": "bob", "chips": 0, "max_chips": 1000, "epoch": 1, "game_count": 1},
+			{"player_id": "carol", "chips": 1000, "max_chips": 1000, "epoch": 2, "game_count": 0},
+		}},
+		"/players/alice/games": map[string]any{"games": []map[string]any{
+			{"user": map[string]any{"username": "alice", "game_id": "g1", "chips_delta": 1000}, "game": showdown},
+			{"user": map[string]any{"username": "alice", "game_id": "g2", "chips_delta": 100}, "game": quiet},
+		}},
+		"/players/bob/games": map[string]any{"games": []map[string]any{
+			{"user": map[string]any{"username": "bob", "game_id": "g1", "chips_delta": -1000}, "game": showdown},
+		}},
+		"/players/carol/games": map[string]any{"games": []any{}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := routes[r.URL.Path[len(httpapi.APIPrefix):]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAnalyzeFromFixtures(t *testing.T) {
+	t.Cleanup(func() { httpapi.Fixtures = nil })
+	fixtures := filepath.Join("testdata", "fixtures")
+	reportOut := filepath.Join(t.TempDir(), "report.json")
+	args := []string{"-log-level", "error", "-run-id", "fixtures", "-report-out", reportOut}
+	if *recordFixtures {
+		if err := os.RemoveAll(fixtures); err != nil {
+			t.Fatal(err)
+		}
+		args = append(args, "-base-url", fakeAPI(t).URL, "-record-dir", fixtures)
+	} else {
+		// Nothing listens there: every request must be replayed.
+		args = append(args, "-base-url", "http://api.invalid", "-replay-dir", fixtures)
+	}
+	if code := Run(args); code != 0 {
+		t.Fatalf("analyze exited with %d", code)
+	}
+
+	raw, err := os.ReadFile(reportOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rep report.Report
+	if err := json.Unmarshal(raw, &rep); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{
+		"players":             3,
+		"players_epoch_1":     2,
+		"players_epoch_2":     1,
+		"chips_epoch_1":       2100,
+		"games":               3,
+		"showdown_games":      1,
+		"games_without_cards": 1,
+		"games_unreadable":    0,
+	}
+	if *recordFixtures {
+		want["http_fixtures_recorded"] = 4
+	} else {
+		want["http_fixtures_replayed"] = 4
+	}
+	for name, n := range want {
+		if got := rep.Counters[name]; got != n {
+			t.Errorf("counter %s = %d, want %d", name, got, n)
+		}
+	}
+	hands := rep.Sub["starting_hands"]
+	if hands == nil {
+		t.Fatal("no starting_hands sub-report")
+	}
+	for name, n := range map[string]int64{"AKs_shown": 1, "AKs_won": 1, "QQ_shown": 1, "QQ_won": 0} {
+		if got := hands.Counters[name]; got != n {
+			t.Errorf("starting hands counter %s = %d, want %d", name, got, n)
+		}
+	}
+}
+
+func TestAnalyzeFixtureMiss(t *testing.T) {
+	t.Cleanup(func() { httpapi.Fixtures = nil })
+	reportOut := filepath.Join(t.TempDir(), "report.json")
+	// Only 10 leaderboard entries were never recorded.
+	code := Run([]string{"-log-level", "error", "-report-out", reportOut, "-base-url", "http://api.invalid",
+		"-replay-dir", filepath.Join("testdata", "fixtures"), "-leaderboard-limit", "10"})
+	if code == 0 {
+		t.Fatal("analyze succeeded on a request with no fixture")
+	}
+	raw, err := os.ReadFile(reportOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rep report.Report
+	if err := json.Unmarshal(raw, &rep); err != nil {
+		t.Fatal(err)
+	}
+	if rep.Status != report.StatusFailed {
+		t.Errorf("status = %q, want %q", rep.Status, report.StatusFailed)
+	}
+}
@@ -0,0 +1,251 @@
+package analyze
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/play"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// unknownPlayer is the bucket of the chips whose counterpart could not be
+// told: games without winners, or without opponents, in their details.
+const unknownPlayer = "unknown"
+
+// chipFlow is the chips that went between our fleet and one opposing
+// player, or the unknownPlayer bucket.
+type chipFlow struct {
+	PlayerID string
+	// FromUs is what our players lost to the player, and ToUs what they
+	// won from it.
+	FromUs int64
+	ToUs   int64
+	// Games is the number of games the player took part in the flow of.
+	Games int64
+}
+
+// Net is what the player gained from us, negative when we gained.
+func (f chipFlow) Net() int64 { return f.FromUs - f.ToUs }
+
+// chipFlowTotals sums up a chip flow computation.
+type chipFlowTotals struct {
+	// Games is the number of games with a net chip change for our fleet,
+	// and Unattributed those whose counterpart was unknown.
+	Games        int64
+	Unattributed int64
+	// Lost and Won are our fleet's net losses and gains over those games.
+	Lost int64
+	Won  int64
+}
+
+// flowGame is what the results tell about a game: our players' net chips,
+// from their hands, and the game's players and winners, from -enrich.
+type flowGame struct {
+	delta    int64
+	hands    bool
+	enriched bool
+	players  []string
+	winners  []string
+}
+
+// chipFlows joins our sessions' hand records with the enriched details of
+// their games. In each game, our fleet's net chips over the hands recorded
+// are what moved: a loss went to the game's winners outside the fleet, a
+// gain came from the opponents at the table, split evenly between them. A
+// game lacking those players, in its details or for want of -enrich, goes
+// to the unknownPlayer bucket. Hands with an unknown stack at either end are
+// left out. Flows are ranked by the chips the player gained from us, then
+// by ID.
+func chipFlows(sessions []*play.SessionResult) ([]chipFlow, chipFlowTotals) {
+	ours := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		ours[s.Player] = true
+	}
+	games := make(map[string]*flowGame)
+	var order []string
+	game := func(id string) *flowGame {
+		g := games[id]
+		if g == nil {
+			g = &flowGame{}
+			games[id] = g
+			order = append(order, id)
+		}
+		return g
+	}
+	for _, s := range sessions {
+		for _, h := range s.HandLog {
+			if h.ChipsStart < 0 || h.ChipsEnd < 0 {
+				continue
+			}
+			g := game(h.GameID)
+			g.delta += int64(h.ChipsEnd - h.ChipsStart)
+			g.hands = true
+		}
+	}
+	for _, s := range sessions {
+		for _, r := range s.Games {
+			if !r.Enriched || games[r.GameID] == nil || games[r.GameID].enriched {
+				continue
+			}
+			g := games[r.GameID]
+			g.enriched = true
+			for _, p := range r.Players {
+				if !ours[p.PlayerID] {
+					g.players = append(g.players, p.PlayerID)
+				}
+			}
+			for _, id := range httpapi.WinnerIDs(r.Winners) {
+				if !ours[id] {
+					g.winners = append(g.winners, id)
+				}
+			}
+		}
+	}
+
+	byID := make(map[string]*chipFlow)
+	flow := func(id string) *chipFlow {
+		f := byID[id]
+		if f == nil {
+			f = &chipFlow{PlayerID: id}
+			byID[id] = f
+		}
+		return f
+	}
+	var totals chipFlowTotals
+	for _, id := range order {
+		g := games[id]
+		if !g.hands || g.delta == 0 {
+			continue
+		}
+		totals.Games++
+		counterparts := g.players
+		if g.delta < 0 {
+			totals.Lost -= g.delta
+			counterparts = g.winners
+		} else {
+			totals.Won += g.delta
+		}
+		counterparts = sortedUnique(counterparts)
+		if len(counterparts) == 0 {
+			counterparts = []string{unknownPlayer}
+			totals.Unattributed++
+		}
+		for i, share := range split(abs64(g.delta), counterparts) {
+			f := flow(counterparts[i])
+			if g.delta < 0 {
+				f.FromUs += share
+			} else {
+				f.ToUs += share
+			}
+			f.Games++
+		}
+	}
+
+	out := make([]chipFlow, 0, len(byID))
+	for _, f := range byID {
+		out = append(out, *f)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Net() != out[j].Net() {
+			return out[i].Net() > out[j].Net()
+		}
+		return out[i].PlayerID < out[j].PlayerID
+	})
+	return out, totals
+}
+
+// split divides n chips between the players, the first ones taking the
+// remainder, so the shares add up to n.
+func split(n int64, players []string) []int64 {
+	shares := make([]int64, len(players))
+	for i := range shares {
+		shares[i] = n / int64(len(players))
+		if int64(i) < n%int64(len(players)) {
+			shares[i]++
+		}
+	}
+	return shares
+}
+
+func sortedUnique(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	var out []string
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// readResults reads a play -results-out file, skipping lines that are not
+// session results.
+func readResults(path string) ([]*play.SessionResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var sessions []*play.SessionResult
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		var r play.SessionResult
+		if json.Unmarshal(sc.Bytes(), &r) == nil && r.Player != "" {
+			sessions = append(sessions, &r)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading results %s: %w", path, err)
+	}
+	return sessions, nil
+}
+
+// printChipFlows writes the totals and the top beneficiaries of our losses.
+func printChipFlows(w io.Writer, flows []chipFlow, totals chipFlowTotals, top int) {
+	fmt.Fprintf(w, "\nChip flow over %d games: our players lost %d and won %d chips; %d games with an unknown counterpart\n",
+		totals.Games, totals.Lost, totals.Won, totals.Unattributed)
+	if len(flows) == 0 {
+		return
+	}
+	if top > 0 && len(flows) > top {
+		flows = flows[:top]
+	}
+	fmt.Fprintf(w, "  %4s  %-30s %10s %10s %10s %6s\n", "rank", "player", "from us", "to us", "net", "games")
+	for i, f := range flows {
+		fmt.Fprintf(w, "  %4d  %-30s %10d %10d %+10d %6d\n", i+1, f.PlayerID, f.FromUs, f.ToUs, f.Net(), f.Games)
+	}
+}
+
+// fillChipFlows adds a "chip_flow" sub-report with <player>_from_us,
+// <player>_to_us and <player>_games counters per player, and the totals as
+// chip_flow_* counters.
+func fillChipFlows(rep *report.Report, flows []chipFlow, totals chipFlowTotals) {
+	rep.Counters["chip_flow_games"] = totals.Games
+	rep.Counters["chip_flow_unattributed_games"] = totals.Unattributed
+	rep.Counters["chip_flow_lost"] = totals.Lost
+	rep.Counters["chip_flow_won"] = totals.Won
+	if len(flows) == 0 {
+		return
+	}
+	sec := rep.SubSection("chip_flow")
+	for _, f := range flows {
+		sec.Counters[f.PlayerID+"_from_us"] = f.FromUs
+		sec.Counters[f.PlayerID+"_to_us"] = f.ToUs
+		sec.Counters[f.PlayerID+"_games"] = f.Games
+	}
+}
@@ -0,0 +1,167 @@
+package analyze
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/play"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// hands returns the hand records of game, one per pair of stacks: the
+// stack at the start of the hand and at its end.
+func hands(game string, stacks ...int) []play.HandResult {
+	var out []play.HandResult
+	for i := 0; i+1 < len(stacks); i += 2 {
+		out = append(out, play.HandResult{GameID: game, Hand: i/2 + 1, ChipsStart: stacks[i], ChipsEnd: stacks[i+1]})
+	}
+	return out
+}
+
+// enriched returns the enriched details of game, with its winners as the
+// API sent them and its players.
+func enriched(game, winners string, players ...string) play.GameResult {
+	r := play.GameResult{GameID: game, Enriched: true, Winners: json.RawMessage(winners)}
+	for _, p := range players {
+		r.Players = append(r.Players, httpapi.ListedPlayer{PlayerID: p})
+	}
+	return r
+}
+
+// flowSessions are the results of two of our players over a night:
+//   - g1: me1 lost 500 to x, with y at the table.
+//   - g2: me1 won 300 and me2 lost 100, from x and z; me2's later copy of
+//     the details, with other players, is ignored.
+//   - g3: me2 lost 301 to y and z, the winners given as objects.
+//   - g4: me1 lost 1000, without -enrich details.
+//   - g5: me2 won 50 at a table of our own players only.
+//   - g6: a hand with an unknown end and an even one, no net change.
+//   - g7: details without hands.
+func flowSessions() []*play.SessionResult {
+	var me1, me2 play.SessionResult
+	me1.Player, me2.Player = "me1", "me2"
+	me1.HandLog = append(hands("g1", 1000, 700, 700, 500), hands("g2", 1000, 1300)...)
+	me1.HandLog = append(me1.HandLog, hands("g4", 1000, 0)...)
+	me1.HandLog = append(me1.HandLog, hands("g6", 1000, -1, 1000, 1000)...)
+	me1.Games = []play.GameResult{
+		enriched("g1", `["x"]`, "me1", "x", "y"),
+		enriched("g2", `["me1"]`, "me1", "me2", "x", "z"),
+		{GameID: "g4", EnrichError: "timeout"},
+		enriched("g6", `["x"]`, "me1", "x"),
+		enriched("g7", `["x"]`, "me1", "x"),
+	}
+	me2.HandLog = append(hands("g2", 1000, 900), hands("g3", 1000, 699)...)
+	me2.HandLog = append(me2.HandLog, hands("g5", 1000, 1050)...)
+	me2.Games = []play.GameResult{
+		enriched("g2", `["me1"]`, "me2", "w"),
+		enriched("g3", `[{"player_id":"z"},{"player_id":"y"}]`, "me2", "y", "z"),
+		enriched("g5", `["me2"]`, "me2", "me1"),
+	}
+	return []*play.SessionResult{&me1, &me2}
+}
+
+func TestChipFlows(t *testing.T) {
+	flows, totals := chipFlows(flowSessions())
+	want := []chipFlow{
+		{PlayerID: unknownPlayer, FromUs: 1000, ToUs: 50, Games: 2},
+		{PlayerID: "x", FromUs: 500, ToUs: 100, Games: 2},
+		// The odd chip of g3 goes to the first winner by ID.
+		{PlayerID: "y", FromUs: 151, Games: 1},
+		{PlayerID: "z", FromUs: 150, ToUs: 100, Games: 2},
+	}
+	if !reflect.DeepEqual(flows, want) {
+		t.Errorf("flows = %+v, want %+v", flows, want)
+	}
+	wantTotals := chipFlowTotals{Games: 5, Unattributed: 2, Lost: 1801, Won: 250}
+	if totals != wantTotals {
+		t.Errorf("totals = %+v, want %+v", totals, wantTotals)
+	}
+	var fromUs, toUs int64
+	for _, f := range flows {
+		fromUs += f.FromUs
+		toUs += f.ToUs
+	}
+	if fromUs != totals.Lost || toUs != totals.Won {
+		t.Errorf("flows add up to %d lost and %d won, totals say %d and %d", fromUs, toUs, totals.Lost, totals.Won)
+	}
+
+	if flows, totals := chipFlows(nil); len(flows) != 0 || totals != (chipFlowTotals{}) {
+		t.Errorf("no sessions gave %+v, %+v", flows, totals)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		n       int64
+		players []string
+		want    []int64
+	}{
+		{100, []string{"a"}, []int64{100}},
+		{100, []string{"a", "b"}, []int64{50, 50}},
+		{101, []string{"a", "b"}, []int64{51, 50}},
+		{5, []string{"a", "b", "c"}, []int64{2, 2, 1}},
+		{1, []string{"a", "b", "c"}, []int64{1, 0, 0}},
+	}
+	for _, tt := range tests {
+		if got := split(tt.n, tt.players); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("split(%d, %q) = %v, want %v", tt.n, tt.players, got, tt.want)
+		}
+	}
+}
+
+func TestChipFlowOutput(t *testing.T) {
+	flows, totals := chipFlows(flowSessions())
+	var b strings.Builder
+	printChipFlows(&b, flows, totals, 2)
+	out := b.String()
+	if !strings.Contains(out, "Chip flow over 5 games: our players lost 1801 and won 250 chips; 2 games with an unknown counterpart") {
+		t.Errorf("printed totals:\n%s", out)
+	}
+	if !strings.Contains(out, "unknown") || !strings.Contains(out, "+400") || strings.Contains(out, " y ") {
+		t.Errorf("printed the top 2 as:\n%s", out)
+	}
+
+	rep := report.New("analyze", nil)
+	fillChipFlows(rep, flows, totals)
+	for name, want := range map[string]int64{"chip_flow_games": 5, "chip_flow_unattributed_games": 2, "chip_flow_lost": 1801, "chip_flow_won": 250} {
+		if got := rep.Counters[name]; got != want {
+			t.Errorf("%s = %d, want %d", name, got, want)
+		}
+	}
+	sec := rep.SubSection("chip_flow")
+	if sec.Counters["x_from_us"] != 500 || sec.Counters["z_to_us"] != 100 || sec.Counters["unknown_games"] != 2 {
+		t.Errorf("chip_flow counters = %v", sec.Counters)
+	}
+}
+
+func TestReadResults(t *testing.T) {
+	var lines []string
+	for _, s := range flowSessions() {
+		b, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(b))
+	}
+	// A summary line and a torn last line are not session results.
+	lines = append(lines, `{"type":"summary","sessions":2}`, `{"player":"me3","hand_lo`)
+	path := filepath.Join(t.TempDir(), "results.ndjson")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := readResults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 || !reflect.DeepEqual(sessions, flowSessions()) {
+		t.Errorf("read %d sessions, want the 2 written", len(sessions))
+	}
+	if _, err := readResults(path + ".missing"); err == nil {
+		t.Error("reading a missing file did not fail")
+	}
+}
@@ -0,0 +1,84 @@
+package analyze
+
+import (
+	"fmt"
+	"sort"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+)
+
+// allEpochs is the -epoch value that selects every epoch.
+const allEpochs = -1
+
+// epochGroup is the leaderboard entries of one epoch, in leaderboard order.
+// The jam resets chips between epochs, so chips are only comparable within
+// one group.
+type epochGroup struct {
+	Epoch   int
+	Entries []httpapi.LeaderboardEntry
+}
+
+// chips returns the total chips of the group.
+func (g epochGroup) chips() int {
+	n := 0
+	for _, e := range g.Entries {
+		n += e.Chips
+	}
+	return n
+}
+
+// games returns the total game count of the group.
+func (g epochGroup) games() int {
+	n := 0
+	for _, e := range g.Entries {
+		n += e.GameCount
+	}
+	return n
+}
+
+// groupByEpoch splits entries by epoch, latest epoch first, keeping the
+// order of the entries within each epoch. With epoch other than allEpochs
+// only that epoch is returned, if it has entries.
+func groupByEpoch(entries []httpapi.LeaderboardEntry, epoch int) []epochGroup {
+	byEpoch := make(map[int][]httpapi.LeaderboardEntry)
+	for _, e := range entries {
+		if epoch != allEpochs && e.Epoch != epoch {
+			continue
+		}
+		byEpoch[e.Epoch] = append(byEpoch[e.Epoch], e)
+	}
+	groups := make([]epochGroup, 0, len(byEpoch))
+	for ep, es := range byEpoch {
+		groups = append(groups, epochGroup{Epoch: ep, Entries: es})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Epoch > groups[j].Epoch })
+	return groups
+}
+
+// epochsOf returns the epochs of a snapshot, in ascending order.
+func epochsOf(snapshot map[string]httpapi.LeaderboardEntry) []int {
+	seen := make(map[int]bool)
+	var epochs []int
+	for _, e := range snapshot {
+		if !seen[e.Epoch] {
+			seen[e.Epoch] = true
+			epochs = append(epochs, e.Epoch)
+		}
+	}
+	sort.Ints(epochs)
+	return epochs
+}
+
+// describeChange tells how a player's leaderboard entry changed between two
+// snapshots, and false if it did not. A new epoch is reported as a reset
+// rather than a chip delta, since the chips were reset with it.
+func describeChange(prev, cur httpapi.LeaderboardEntry) (string, bool) {
+	switch {
+	case prev.Epoch != cur.Epoch:
+		return fmt.Sprintf("reset (epoch %d -> %d), now %d chips", prev.Epoch, cur.Epoch, cur.Chips), true
+	case prev.Chips != cur.Chips:
+		return fmt.Sprintf("chips %d -> %d (%+d) in epoch %d", prev.Chips, cur.Chips, cur.Chips-prev.Chips, cur.Epoch), true
+	default:
+		return "", false
+	}
+}
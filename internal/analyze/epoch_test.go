@@ -0,0 +1,110 @@
+package analyze
+
+import (
+	"reflect"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+)
+
+func TestGroupByEpoch(t *testing.T) {
+	entries := []httpapi.LeaderboardEntry{
+		{PlayerID: "a", Chips: 3000, Epoch: 1, GameCount: 4},
+		{PlayerID: "b", Chips: 2500, Epoch: 2, GameCount: 1},
+		{PlayerID: "c", Chips: 1000, Epoch: 1, GameCount: 2},
+		{PlayerID: "d", Chips: 500, Epoch: 2, GameCount: 3},
+		{PlayerID: "e", Chips: 100, Epoch: 0},
+	}
+	ids := func(groups []epochGroup) map[int][]string {
+		out := make(map[int][]string)
+		for _, g := range groups {
+			for _, e := range g.Entries {
+				out[g.Epoch] = append(out[g.Epoch], e.PlayerID)
+			}
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		epoch  int
+		order  []int
+		want   map[int][]string
+		chips  []int
+		played []int
+	}{
+		{
+			name:   "all epochs, latest first",
+			epoch:  allEpochs,
+			order:  []int{2, 1, 0},
+			want:   map[int][]string{2: {"b", "d"}, 1: {"a", "c"}, 0: {"e"}},
+			chips:  []int{3000, 4000, 100},
+			played: []int{4, 6, 0},
+		},
+		{
+			name:   "one epoch",
+			epoch:  1,
+			order:  []int{1},
+			want:   map[int][]string{1: {"a", "c"}},
+			chips:  []int{4000},
+			played: []int{6},
+		},
+		{
+			name:  "epoch without entries",
+			epoch: 7,
+			order: []int{},
+			want:  map[int][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups := groupByEpoch(entries, tt.epoch)
+			order := []int{}
+			var chips, played []int
+			for _, g := range groups {
+				order = append(order, g.Epoch)
+				chips = append(chips, g.chips())
+				played = append(played, g.games())
+			}
+			if !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("epochs = %v, want %v", order, tt.order)
+			}
+			if got := ids(groups); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("players = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(chips, tt.chips) || !reflect.DeepEqual(played, tt.played) {
+				t.Errorf("chips %v and games %v, want %v and %v", chips, played, tt.chips, tt.played)
+			}
+		})
+	}
+}
+
+func TestEpochsOf(t *testing.T) {
+	snapshot := map[string]httpapi.LeaderboardEntry{
+		"a": {Epoch: 3}, "b": {Epoch: 1}, "c": {Epoch: 3}, "d": {Epoch: 2},
+	}
+	if got, want := epochsOf(snapshot), []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("epochsOf = %v, want %v", got, want)
+	}
+}
+
+func TestDescribeChange(t *testing.T) {
+	tests := []struct {
+		name      string
+		prev, cur httpapi.LeaderboardEntry
+		want      string
+		changed   bool
+	}{
+		{"unchanged", httpapi.LeaderboardEntry{Chips: 1000, Epoch: 1}, httpapi.LeaderboardEntry{Chips: 1000, Epoch: 1}, "", false},
+		{"won", httpapi.LeaderboardEntry{Chips: 1000, Epoch: 1}, httpapi.LeaderboardEntry{Chips: 1500, Epoch: 1}, "chips 1000 -> 1500 (+500) in epoch 1", true},
+		{"lost", httpapi.LeaderboardEntry{Chips: 1000, Epoch: 1}, httpapi.LeaderboardEntry{Chips: 400, Epoch: 1}, "chips 1000 -> 400 (-600) in epoch 1", true},
+		// Not a 49000 chip loss: the new epoch reset the stack.
+		{"new epoch", httpapi.LeaderboardEntry{Chips: 50000, Epoch: 1}, httpapi.LeaderboardEntry{Chips: 1000, Epoch: 2}, "reset (epoch 1 -> 2), now 1000 chips", true},
+		{"new epoch, same chips", httpapi.LeaderboardEntry{Chips: 1000, Epoch: 1}, httpapi.LeaderboardEntry{Chips: 1000, Epoch: 2}, "reset (epoch 1 -> 2), now 1000 chips", true},
+	}
+	for _, tt := range tests {
+		got, changed := describeChange(tt.prev, tt.cur)
+		if got != tt.want || changed != tt.changed {
+			t.Errorf("%s: describeChange = %q, %v; want %q, %v", tt.name, got, changed, tt.want, tt.changed)
+		}
+	}
+}
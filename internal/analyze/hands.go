@@ -0,0 +1,171 @@
+package analyze
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// ranks orders card ranks from lowest to highest.
+const ranks = "23456789TJQKA"
+
+// handStats is how often a starting hand was shown down and how often its
+// holder won the game.
+type handStats struct {
+	Hand  string
+	Shown int64
+	Won   int64
+}
+
+// handTally collects the starting hands shown in the games fetched. Each
+// game is counted once, however many of its players were fetched.
+type handTally struct {
+	seen  map[string]bool
+	hands map[string]*handStats
+
+	showdowns int64
+	// noCards counts the games whose state shows no hole cards.
+	noCards int64
+	// unreadable counts the games whose state could not be decoded.
+	unreadable int64
+}
+
+func newHandTally() *handTally {
+	return &handTally{seen: make(map[string]bool), hands: make(map[string]*handStats)}
+}
+
+// add tallies the shown hands of g, once per game.
+func (t *handTally) add(g *httpapi.PlayerGameDetail) {
+	if g.GameID == "" || t.seen[g.GameID] {
+		return
+	}
+	t.seen[g.GameID] = true
+	state, err := g.HandState()
+	if err != nil {
+		t.unreadable++
+		return
+	}
+	winners := make(map[string]bool)
+	for _, id := range httpapi.WinnerIDs(state.Winners) {
+		winners[id] = true
+	}
+	shown := false
+	for _, p := range state.Players {
+		hand, ok := startingHand(p.Hand)
+		if !ok {
+			continue
+		}
+		shown = true
+		s := t.hands[hand]
+		if s == nil {
+			s = &handStats{Hand: hand}
+			t.hands[hand] = s
+		}
+		s.Shown++
+		if winners[p.PlayerID] {
+			s.Won++
+		}
+	}
+	if shown {
+		t.showdowns++
+	} else {
+		t.noCards++
+	}
+}
+
+// ranked returns the hands by wins, then by win rate, then by name.
+func (t *handTally) ranked() []handStats {
+	out := make([]handStats, 0, len(t.hands))
+	for _, s := range t.hands {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Won != b.Won {
+			return a.Won > b.Won
+		}
+		if a.Won*b.Shown != b.Won*a.Shown {
+			return a.Won*b.Shown > b.Won*a.Shown
+		}
+		return a.Hand < b.Hand
+	})
+	return out
+}
+
+// print writes the top hands as a ranked table.
+func (t *handTally) print(w io.Writer, top int) {
+	fmt.Fprintf(w, "\nShowdowns: %d games with hole cards, %d without, %d unreadable.\n", t.showdowns, t.noCards, t.unreadable)
+	ranked := t.ranked()
+	if len(ranked) == 0 {
+		return
+	}
+	if top > 0 && len(ranked) > top {
+		ranked = ranked[:top]
+	}
+	fmt.Fprintln(w, "Starting hands that won most often:")
+	fmt.Fprintf(w, "  %4s  %-5s %6s %6s %7s\n", "rank", "hand", "won", "shown", "win%")
+	for i, s := range ranked {
+		fmt.Fprintf(w, "  %4d  %-5s %6d %6d %6.1f%%\n", i+1, s.Hand, s.Won, s.Shown, 100*float64(s.Won)/float64(s.Shown))
+	}
+}
+
+// fill adds the showdown counters to rep, and a "starting_hands" sub-report
+// with <hand>_shown and <hand>_won counters.
+func (t *handTally) fill(rep *report.Report) {
+	rep.Counters["showdown_games"] = t.showdowns
+	rep.Counters["games_without_cards"] = t.noCards
+	rep.Counters["games_unreadable"] = t.unreadable
+	if len(t.hands) == 0 {
+		return
+	}
+	sec := rep.SubSection("starting_hands")
+	for _, s := range t.hands {
+		sec.Counters[s.Hand+"_shown"] = s.Shown
+		sec.Counters[s.Hand+"_won"] = s.Won
+	}
+}
+
+// startingHand names two hole cards the usual way: the higher rank first,
+// then "s" if suited or "o" if not, as in "AKs"; a pair is just its ranks,
+// as in "QQ". Cards are a rank (2-9, 10 or T, J, Q, K, A) followed by a
+// one-letter suit, in any case. It returns false for anything else.
+func startingHand(cards []string) (string, bool) {
+	if len(cards) != 2 {
+		return "", false
+	}
+	r1, s1, ok1 := parseCard(cards[0])
+	r2, s2, ok2 := parseCard(cards[1])
+	if !ok1 || !ok2 {
+		return "", false
+	}
+	if strings.IndexByte(ranks, r1) < strings.IndexByte(ranks, r2) {
+		r1, r2 = r2, r1
+	}
+	switch {
+	case r1 == r2:
+		return string([]byte{r1, r2}), true
+	case s1 == s2:
+		return string([]byte{r1, r2, 's'}), true
+	default:
+		return string([]byte{r1, r2, 'o'}), true
+	}
+}
+
+func parseCard(card string) (rank, suit byte, ok bool) {
+	card = strings.ToUpper(strings.TrimSpace(card))
+	if len(card) < 2 {
+		return 0, 0, false
+	}
+	r, s := card[:len(card)-1], card[len(card)-1]
+	if r == "10" {
+		r = "T"
+	}
+	if len(r) != 1 || strings.IndexByte(ranks, r[0]) < 0 || !strings.ContainsRune("CDHS", rune(s)) {
+		return 0, 0, false
+	}
+	return r[0], s, true
+}
@@ -0,0 +1,90 @@
+package analyze
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+)
+
+func TestStartingHand(t *testing.T) {
+	tests := []struct {
+		cards []string
+		want  string
+		ok    bool
+	}{
+		{[]string{"Ah", "Kh"}, "AKs", true},
+		{[]string{"Kh", "Ah"}, "AKs", true},
+		{[]string{"Qs", "Qd"}, "QQ", true},
+		{[]string{"2c", "7d"}, "72o", true},
+		{[]string{"10h", "9h"}, "T9s", true},
+		{[]string{"th", " jc "}, "JTo", true},
+		{[]string{"Ah"}, "", false},
+		{[]string{"Ah", "Kh", "Qh"}, "", false},
+		{[]string{"Ah", "1h"}, "", false},
+		{[]string{"Ah", "Kx"}, "", false},
+		{[]string{"Ah", "?"}, "", false},
+		{nil, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := startingHand(tt.cards)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("startingHand(%q) = %q, %v; want %q, %v", tt.cards, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// gameDetail decodes a game as the players' games endpoint lists it.
+func gameDetail(t *testing.T, raw string) *httpapi.PlayerGameDetail {
+	t.Helper()
+	var g httpapi.PlayerGameDetail
+	if err := json.Unmarshal([]byte(raw), &g); err != nil {
+		t.Fatal(err)
+	}
+	return &g
+}
+
+func TestHandTally(t *testing.T) {
+	games := []string{
+		// Winners as player IDs.
+		`{"game_id":"g1","game_state":{"players":[
+			{"player_id":"alice","chips":2000,"hand":["Ah","Kh"]},
+			{"player_id":"bob","chips":0,"hand":["Qs","Qd"]}],
+			"table":["2c","7d","9h","Jh","3h"],"winners":["alice"]}}`,
+		// The same game, fetched for another player: counted once.
+		`{"game_id":"g1","game_state":{"players":[
+			{"player_id":"alice","chips":2000,"hand":["Ah","Kh"]},
+			{"player_id":"bob","chips":0,"hand":["Qs","Qd"]}],"winners":["alice"]}}`,
+		// Winners as objects.
+		`{"game_id":"g2","game_state":{"players":[
+			{"player_id":"carol","hand":["Qh","Qc"]},
+			{"player_id":"dave","hand":["Kd","As"]}],"winners":[{"player_id":"carol","amount":400}]}}`,
+		// One player showed, the other mucked.
+		`{"game_id":"g3","game_state":{"players":[
+			{"player_id":"alice","hand":["Kc","Ac"]},
+			{"player_id":"bob"}],"winners":["alice"]}}`,
+		// No cards at all.
+		`{"game_id":"g4","game_state":{"players":[{"player_id":"alice","chips":2100}]}}`,
+		`{"game_id":"g5","game_state":{}}`,
+		// Players of the wrong shape.
+		`{"game_id":"g6","game_state":{"players":"alice,bob"}}`,
+		// No game ID: skipped.
+		`{"game_state":{"players":[{"player_id":"alice","hand":["2c","2d"]}]}}`,
+	}
+	tally := newHandTally()
+	for _, raw := range games {
+		tally.add(gameDetail(t, raw))
+	}
+	if tally.showdowns != 3 || tally.noCards != 2 || tally.unreadable != 1 {
+		t.Errorf("%d showdowns, %d without cards, %d unreadable; want 3, 2 and 1", tally.showdowns, tally.noCards, tally.unreadable)
+	}
+	want := []handStats{
+		{Hand: "AKs", Shown: 2, Won: 2},
+		{Hand: "QQ", Shown: 2, Won: 1},
+		{Hand: "AKo", Shown: 1, Won: 0},
+	}
+	if got := tally.ranked(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ranked = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,106 @@
+package analyze
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/manifest"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// opponentStats is how one opposing player fared against our players.
+type opponentStats struct {
+	PlayerID string
+	// Games is the number of games shared with our players, and Wins those
+	// the opponent won.
+	Games int64
+	Wins  int64
+	// OurDelta is the net chips our players won in those games; negative
+	// when the opponent took chips from us.
+	OurDelta int64
+}
+
+// toughestOpponents joins the manifest with the game details and our
+// players' chip deltas, keyed by game ID and then by player. Games without
+// details are skipped; a missing delta counts as zero. Opponents are ranked
+// by wins, then by the chips they took from us, then by ID.
+func toughestOpponents(m *manifest.Manifest, details map[string]*httpapi.GameDetail, deltas map[string]map[string]int) []opponentStats {
+	byID := make(map[string]*opponentStats)
+	for _, g := range m.Games {
+		d := details[g.GameID]
+		if d == nil {
+			continue
+		}
+		ours := make(map[string]bool, len(g.Players))
+		ourDelta := 0
+		for _, p := range g.Players {
+			ours[p] = true
+			ourDelta += deltas[g.GameID][p]
+		}
+		winners := make(map[string]bool)
+		for _, id := range httpapi.WinnerIDs(d.GameState.Winners) {
+			winners[id] = true
+		}
+		for _, p := range d.GameState.Players {
+			if ours[p.PlayerID] {
+				continue
+			}
+			s := byID[p.PlayerID]
+			if s == nil {
+				s = &opponentStats{PlayerID: p.PlayerID}
+				byID[p.PlayerID] = s
+			}
+			s.Games++
+			if winners[p.PlayerID] {
+				s.Wins++
+			}
+			s.OurDelta += int64(ourDelta)
+		}
+	}
+	out := make([]opponentStats, 0, len(byID))
+	for _, s := range byID {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		if a.OurDelta != b.OurDelta {
+			return a.OurDelta < b.OurDelta
+		}
+		return a.PlayerID < b.PlayerID
+	})
+	return out
+}
+
+// printOpponents writes the top opponents as a ranked table.
+func printOpponents(w io.Writer, opponents []opponentStats, top int) {
+	if len(opponents) == 0 {
+		return
+	}
+	if top > 0 && len(opponents) > top {
+		opponents = opponents[:top]
+	}
+	fmt.Fprintln(w, "\nToughest opponents:")
+	fmt.Fprintf(w, "  %4s  %-30s %6s %6s %7s %10s\n", "rank", "player", "games", "wins", "win%", "our chips")
+	for i, s := range opponents {
+		fmt.Fprintf(w, "  %4d  %-30s %6d %6d %6.1f%% %+10d\n", i+1, s.PlayerID, s.Games, s.Wins, 100*float64(s.Wins)/float64(s.Games), s.OurDelta)
+	}
+}
+
+// fillOpponents adds a "toughest_opponents" sub-report with <player>_games,
+// <player>_wins and <player>_our_delta counters per opponent.
+func fillOpponents(rep *report.Report, opponents []opponentStats) {
+	if len(opponents) == 0 {
+		return
+	}
+	sec := rep.SubSection("toughest_opponents")
+	for _, s := range opponents {
+		sec.Counters[s.PlayerID+"_games"] = s.Games
+		sec.Counters[s.PlayerID+"_wins"] = s.Wins
+		sec.Counters[s.PlayerID+"_our_delta"] = s.OurDelta
+	}
+}
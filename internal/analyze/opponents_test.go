@@ -0,0 +1,63 @@
+package analyze
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/manifest"
+)
+
+func TestToughestOpponents(t *testing.T) {
+	detail := func(winners string, players ...string) *httpapi.GameDetail {
+		d := &httpapi.GameDetail{GameState: httpapi.GameDetailState{Winners: json.RawMessage(winners)}}
+		for _, p := range players {
+			d.GameState.Players = append(d.GameState.Players, httpapi.ListedPlayer{PlayerID: p})
+		}
+		return d
+	}
+	m := &manifest.Manifest{Games: []manifest.Game{
+		{GameID: "g1", Players: []string{"me1"}},
+		{GameID: "g2", Players: []string{"me1", "me2"}},
+		{GameID: "g3", Players: []string{"me2"}},
+		{GameID: "g4", Players: []string{"me1"}}, // no details fetched
+	}}
+	details := map[string]*httpapi.GameDetail{
+		"g1": detail(`["x"]`, "me1", "x", "y"),
+		"g2": detail(`["me1"]`, "me1", "me2", "x"),
+		"g3": detail(`[{"player_id":"z"}]`, "me2", "z"),
+		"g9": detail(`["w"]`, "w"), // not one of our games
+	}
+	deltas := map[string]map[string]int{
+		"g1": {"me1": -500},
+		"g2": {"me1": 300, "me2": -100},
+		"g4": {"me1": -1000},
+	}
+	want := []opponentStats{
+		{PlayerID: "x", Games: 2, Wins: 1, OurDelta: -300},
+		{PlayerID: "z", Games: 1, Wins: 1, OurDelta: 0},
+		{PlayerID: "y", Games: 1, Wins: 0, OurDelta: -500},
+	}
+	if got := toughestOpponents(m, details, deltas); !reflect.DeepEqual(got, want) {
+		t.Errorf("toughestOpponents =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestToughestOpponentsEmpty(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       *manifest.Manifest
+		details map[string]*httpapi.GameDetail
+	}{
+		{"no games", &manifest.Manifest{}, nil},
+		{"no details", &manifest.Manifest{Games: []manifest.Game{{GameID: "g1", Players: []string{"me"}}}}, nil},
+		{"only us", &manifest.Manifest{Games: []manifest.Game{{GameID: "g1", Players: []string{"me"}}}},
+			map[string]*httpapi.GameDetail{"g1": {GameState: httpapi.GameDetailState{Players: []httpapi.ListedPlayer{{PlayerID: "me"}}}}}},
+	}
+	for _, tt := range tests {
+		if got := toughestOpponents(tt.m, tt.details, nil); len(got) != 0 {
+			t.Errorf("%s: toughestOpponents = %+v, want none", tt.name, got)
+		}
+	}
+}
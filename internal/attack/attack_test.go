@@ -0,0 +1,77 @@
+package attack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/metrics"
+)
+
+func TestAttackWorkerChecksJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		successful  int64
+		notJSON     int64
+	}{
+		{"json", http.StatusOK, "application/json", `{"game_id":"g-1"}`, 3, 0},
+		{"json with charset", http.StatusOK, "application/json; charset=utf-8", `{}`, 3, 0},
+		{"html with 200", http.StatusOK, "text/html", "<html><body>Bad gateway</body></html>", 0, 3},
+		{"no content type, markup", http.StatusOK, "", "<html></html>", 0, 3},
+		{"no content type, json", http.StatusOK, "", `{}`, 3, 0},
+		{"server error", http.StatusServiceUnavailable, "text/html", "<html></html>", 0, 0},
+	}
+	defer func(b *requestBudget) { budget = b }(budget)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var accept []string
+			var mu sync.Mutex
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				accept = append(accept, r.Header.Get("Accept"))
+				mu.Unlock()
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				} else {
+					w.Header()["Content-Type"] = nil // no sniffing
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			budget = newRequestBudget(3)
+			own := newEndpointStats(metrics.New(), endpointMetricNames)
+			var wg sync.WaitGroup
+			wg.Add(1)
+			attackWorker(context.Background(), srv.Client(), srv.URL+"/games/g-1", own, make(chan struct{}), &wg)
+
+			if got := own.requests.Load(); got != 3 {
+				t.Errorf("requests = %d, want 3", got)
+			}
+			if got := own.successful.Load(); got != tt.successful {
+				t.Errorf("successful = %d, want %d", got, tt.successful)
+			}
+			if got := own.failed.Load(); got != 3-tt.successful {
+				t.Errorf("failed = %d, want %d", got, 3-tt.successful)
+			}
+			if got := own.notJSON(); got != tt.notJSON {
+				t.Errorf("not_json = %d, want %d", got, tt.notJSON)
+			}
+			if tt.status != http.StatusOK && own.failures.Snapshot()[errclass.HTTPStatus] != 3 {
+				t.Errorf("failures = %v, want 3 %s", own.failures.Snapshot(), errclass.HTTPStatus)
+			}
+			for _, a := range accept {
+				if a != "application/json" {
+					t.Errorf("Accept = %q, want application/json", a)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package attack
+
+import "sync/atomic"
+
+// budgetBatch is how many requests an attacker takes from the budget at a
+// time, so thousands of attackers do not all contend on the shared counter
+// at every request.
+const budgetBatch = 16
+
+// requestBudget is the number of requests the attackers may still send,
+// shared by all of them. A nil *requestBudget is unlimited.
+type requestBudget struct {
+	left atomic.Int64
+}
+
+// newRequestBudget returns a budget of max requests, or nil if max is not
+// positive.
+func newRequestBudget(max int64) *requestBudget {
+	if max <= 0 {
+		return nil
+	}
+	b := &requestBudget{}
+	b.left.Store(max)
+	return b
+}
+
+// take removes up to n requests from the budget and returns how many it
+// got, 0 once the budget is spent.
+func (b *requestBudget) take(n int64) int64 {
+	for {
+		left := b.left.Load()
+		if left <= 0 {
+			return 0
+		}
+		got := min(n, left)
+		if b.left.CompareAndSwap(left, left-got) {
+			return got
+		}
+	}
+}
+
+// spent reports whether every request of the budget was handed out.
+func (b *requestBudget) spent() bool {
+	return b != nil && b.left.Load() <= 0
+}
+
+// allowance is one attacker's share of a budget, taken in batches.
+type allowance struct {
+	budget *requestBudget
+	local  int64
+}
+
+// next reports whether the attacker may send one more request.
+func (a *allowance) next() bool {
+	if a.budget == nil {
+		return true
+	}
+	if a.local == 0 {
+		a.local = a.budget.take(budgetBatch)
+		if a.local == 0 {
+			return false
+		}
+	}
+	a.local--
+	return true
+}
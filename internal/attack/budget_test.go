@@ -0,0 +1,75 @@
+package attack
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestBudgetExhaustedExactly has attackers draw from one budget until it is
+// spent; together they must send exactly its size. Run it with -race.
+func TestBudgetExhaustedExactly(t *testing.T) {
+	tests := []struct {
+		name      string
+		max       int64
+		attackers int
+	}{
+		{"fewer requests than attackers", 5, 32},
+		{"less than one batch each", 100, 32},
+		{"not a multiple of the batch", 10007, 32},
+		{"single attacker", 1000, 1},
+		{"many attackers", 100000, 256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newRequestBudget(tt.max)
+			var sent atomic.Int64
+			var wg sync.WaitGroup
+			for range tt.attackers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					a := allowance{budget: b}
+					for a.next() {
+						sent.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+			if got := sent.Load(); got != tt.max {
+				t.Errorf("sent %d requests, want %d", got, tt.max)
+			}
+			if !b.spent() {
+				t.Error("budget not spent")
+			}
+			if got := b.take(1); got != 0 {
+				t.Errorf("take after the budget was spent = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestBudgetTake(t *testing.T) {
+	b := newRequestBudget(20)
+	for i, want := range []int64{16, 4, 0} {
+		if got := b.take(budgetBatch); got != want {
+			t.Errorf("take %d = %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestUnlimitedBudget(t *testing.T) {
+	b := newRequestBudget(0)
+	if b != nil {
+		t.Fatalf("newRequestBudget(0) = %v, want nil", b)
+	}
+	a := allowance{budget: b}
+	for range 1000 {
+		if !a.next() {
+			t.Fatal("unlimited allowance refused a request")
+		}
+	}
+	if b.spent() {
+		t.Error("unlimited budget reported spent")
+	}
+}
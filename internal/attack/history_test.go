@@ -0,0 +1,85 @@
+package attack
+
+import (
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2025, 3, 1, 12, 30, 45, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2025-03-01T12:30:45Z", want, false},
+		{"2025-03-01T13:30:45+01:00", want, false},
+		{"2025-03-01T12:30:45.250Z", want.Add(250 * time.Millisecond), false},
+		{"2025-03-01T12:30:45", want, false},
+		{"2025-03-01 12:30:45", want, false},
+		{"2025-03-01 12:30:45.5", want.Add(500 * time.Millisecond), false},
+		{"2025-03-01 12:30:45+00:00", want, false},
+		{" 2025-03-01T12:30:45Z\n", want, false},
+		{"1740832245", want, false},
+		{"1740832245250", want.Add(250 * time.Millisecond), false},
+		{"", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+		{"2025-03-01", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimestamp(tt.in)
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("parseTimestamp(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFresh(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		age  time.Duration
+		want bool
+	}{
+		{0, true},
+		{time.Minute, true},
+		{2 * time.Minute, true},
+		{2*time.Minute + time.Second, false},
+		{time.Hour, false},
+		{-5 * time.Second, true}, // clock skew
+	}
+	for _, tt := range tests {
+		if got := fresh(now.Add(-tt.age), now, 2*time.Minute); got != tt.want {
+			t.Errorf("fresh with age %v = %v, want %v", tt.age, got, tt.want)
+		}
+	}
+}
+
+func TestMostRecentGame(t *testing.T) {
+	game := func(id, ts string) httpapi.PlayerGame {
+		return httpapi.PlayerGame{Game: httpapi.PlayerGameDetail{GameID: id, Timestamp: ts}}
+	}
+	tests := []struct {
+		name   string
+		games  []httpapi.PlayerGame
+		wantID string
+		wantOK bool
+	}{
+		{"none", nil, "", false},
+		{"newest first", []httpapi.PlayerGame{game("b", "2025-03-01T12:00:00Z"), game("a", "2025-03-01T11:00:00Z")}, "b", true},
+		{"newest last", []httpapi.PlayerGame{game("a", "2025-03-01T11:00:00Z"), game("b", "2025-03-01T12:00:00Z")}, "b", true},
+		{"mixed formats", []httpapi.PlayerGame{game("a", "2025-03-01 11:00:00"), game("b", "1740830400")}, "b", true},
+		{"unparsable skipped", []httpapi.PlayerGame{game("a", "2025-03-01T11:00:00Z"), game("b", "soon")}, "a", true},
+		{"no game ID skipped", []httpapi.PlayerGame{game("a", "2025-03-01T11:00:00Z"), game("", "2025-03-01T12:00:00Z")}, "a", true},
+		{"nothing usable", []httpapi.PlayerGame{game("a", ""), game("", "2025-03-01T12:00:00Z")}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, _, ok := mostRecentGame(tt.games)
+			if id != tt.wantID || ok != tt.wantOK {
+				t.Errorf("mostRecentGame = %q, %v; want %q, %v", id, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package attack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/metrics"
+)
+
+// TestRequestsIdentifyUs checks that the discovery, attack and probe
+// requests all carry the User-Agent and identification headers.
+func TestRequestsIdentifyUs(t *testing.T) {
+	defer func(ua string, h http.Header) { httpapi.UserAgent, httpapi.Headers = ua, h }(httpapi.UserAgent, httpapi.Headers)
+	httpapi.UserAgent = "aijam-tools/test run/r1"
+	httpapi.Headers = http.Header{"X-Team": {"ourteam"}}
+	defer func(b *requestBudget) { budget = b }(budget)
+	budget = newRequestBudget(2)
+
+	var mu sync.Mutex
+	seen := make(map[string][]http.Header)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.URL.Path] = append(seen[r.URL.Path], r.Header.Clone())
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v0/games" {
+			w.Write([]byte(`[{"game_id":"g0","game_state":{"players":[{"player_id":"other"}]}}]`))
+			return
+		}
+		w.Write([]byte(`{"games":[]}`))
+	}))
+	defer srv.Close()
+
+	api := httpapi.New(srv.URL, time.Second)
+	if _, err := findTargetPlayerGameIDInCurrentList(api, srv.URL, "p1", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := findTargetPlayerGameIDInHistory(api, srv.URL, "p1", time.Minute, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	client := &http.Client{Timeout: time.Second, Transport: httpapi.Transport(nil)}
+	attackWorker(context.Background(), client, api.GameURL("g-1"), newEndpointStats(metrics.New(), endpointMetricNames), make(chan struct{}), &wg)
+	newProber(api.APIURL("/leaderboard"), time.Second).probe(newEndpointStats(metrics.New(), endpointMetricNames))
+
+	for _, path := range []string{"/api/v0/games", "/api/v0/players/p1/games", "/games/g-1", "/api/v0/leaderboard"} {
+		headers := seen[path]
+		if len(headers) == 0 {
+			t.Errorf("no request to %s", path)
+		}
+		for _, h := range headers {
+			if got := h.Get("User-Agent"); got != httpapi.UserAgent {
+				t.Errorf("%s: User-Agent = %q, want %q", path, got, httpapi.UserAgent)
+			}
+			if got := h.Get("X-Team"); got != "ourteam" {
+				t.Errorf("%s: X-Team = %q, want ourteam", path, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package attack
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/metrics"
+	"elastic-ai-jam-2025/internal/netaddr"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// baselineGap is the pause between the probes taken before the attack.
+const baselineGap = 250 * time.Millisecond
+
+// collateralSection names the report sub-section of the prober.
+const collateralSection = "collateral impact"
+
+// prober measures a control endpoint unrelated to the attacked game, before
+// and during the attack, to tell whether the whole API degrades or only the
+// attacked endpoint. It has its own transport, so its requests are never
+// queued behind the attackers' connections, and its failures only show in
+// its own stats.
+type prober struct {
+	url    string
+	client *http.Client
+	before *endpointStats
+	during *endpointStats
+}
+
+func newProber(url string, timeout time.Duration) *prober {
+	return &prober{
+		url: url,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: httpapi.Transport(netaddr.Transport()),
+		},
+		before: newEndpointStats(metrics.New(), endpointMetricNames),
+		during: newEndpointStats(metrics.New(), endpointMetricNames),
+	}
+}
+
+// probe sends one request to the control URL and records it in s.
+func (p *prober) probe(s *endpointStats) {
+	start := time.Now()
+	resp, err := p.client.Get(p.url)
+	if err != nil {
+		s.observe(start, 0, 0, err)
+		return
+	}
+	n, _ := io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	s.observe(start, resp.StatusCode, n, nil)
+}
+
+// baseline takes n probes, baselineGap apart, before the attack starts.
+func (p *prober) baseline(ctx context.Context, n int) {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(baselineGap):
+			case <-ctx.Done():
+				return
+			}
+		}
+		p.probe(p.before)
+	}
+}
+
+// run probes every interval until stop is closed.
+func (p *prober) run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.probe(p.during)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// print writes the control endpoint's latency before and during the attack.
+func (p *prober) print() {
+	fmt.Printf("Control endpoint %s:\n", p.url)
+	fmt.Printf("  before the attack: %s (%d failed)\n", p.before.latency.Summary(), p.before.failed.Load())
+	fmt.Printf("  during the attack: %s (%d failed)\n", p.during.latency.Summary(), p.during.failed.Load())
+	if ratio, ok := p.p50Ratio(); ok {
+		fmt.Printf("  p50 during/before: %.2fx\n", ratio)
+	}
+}
+
+// p50Ratio compares the median latency during the attack with the one
+// before; it is false when either phase has no response.
+func (p *prober) p50Ratio() (float64, bool) {
+	before, during := p.before.latency.Summary(), p.during.latency.Summary()
+	if before.Count == 0 || during.Count == 0 || before.P50Ms == 0 {
+		return 0, false
+	}
+	return during.P50Ms / before.P50Ms, true
+}
+
+// fill writes the collateral impact section: per phase, the requests, the
+// failures and the latency of the control endpoint. Its errors are those of
+// the probes during the attack.
+func (p *prober) fill(rep *report.Report) {
+	sec := rep.SubSection(collateralSection)
+	for phase, s := range map[string]*endpointStats{"before": p.before, "during": p.during} {
+		sec.Counters[phase+"_requests"] = s.requests.Load()
+		sec.Counters[phase+"_failed"] = s.failed.Load()
+		if s.latency.Count() > 0 {
+			sec.Latencies[phase] = s.latency.Summary()
+		}
+	}
+	sec.SetErrors(p.during.failures.Snapshot())
+	rep.Details["control_url"] = p.url
+	if ratio, ok := p.p50Ratio(); ok {
+		rep.Details["control_p50_ratio"] = fmt.Sprintf("%.2f", ratio)
+	}
+}
@@ -0,0 +1,51 @@
+package attack
+
+import "time"
+
+// discoveryObservation is what one discovery attempt learned about the
+// target player.
+type discoveryObservation struct {
+	// At is when the attempt's responses were received, on the server's
+	// clock.
+	At time.Time
+	// LastSeen is the most recent time the responses placed the player at a
+	// table, such as the start of its latest game in its history; zero if
+	// they did not show the player at all.
+	LastSeen time.Time
+}
+
+// discoverySchedule decides how long to wait before the next discovery
+// attempt. A player seen recently is probably between two games, so the
+// games list is polled every Fast until the next one shows up. Otherwise the
+// delay starts at Base and doubles with every attempt that does not see the
+// player, up to Max; any sighting resets it.
+type discoverySchedule struct {
+	Fast time.Duration
+	Base time.Duration
+	Max  time.Duration
+	// Recent is how old a sighting may be, at the time of the attempt, to
+	// count as recent.
+	Recent time.Duration
+}
+
+// seen reports whether o saw the player recently.
+func (s discoverySchedule) seen(o discoveryObservation) bool {
+	return !o.LastSeen.IsZero() && o.At.Sub(o.LastSeen) <= s.Recent
+}
+
+// next returns the delay before the attempt following history, which lists
+// the attempts made so far, oldest first.
+func (s discoverySchedule) next(history []discoveryObservation) time.Duration {
+	misses := 0
+	for i := len(history) - 1; i >= 0 && !s.seen(history[i]); i-- {
+		misses++
+	}
+	if len(history) > 0 && misses == 0 {
+		return s.Fast
+	}
+	d := s.Base
+	for i := 1; i < misses && d < s.Max; i++ {
+		d *= 2
+	}
+	return min(d, s.Max)
+}
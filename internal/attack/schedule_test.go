@@ -0,0 +1,38 @@
+package attack
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiscoveryScheduleNext(t *testing.T) {
+	s := discoverySchedule{Fast: time.Second, Base: 5 * time.Second, Max: 30 * time.Second, Recent: time.Minute}
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	fresh := discoveryObservation{At: now, LastSeen: now.Add(-30 * time.Second)}
+	stale := discoveryObservation{At: now, LastSeen: now.Add(-2 * time.Minute)}
+	unseen := discoveryObservation{At: now}
+	tests := []struct {
+		name    string
+		history []discoveryObservation
+		want    time.Duration
+	}{
+		{"empty", nil, 5 * time.Second},
+		{"fresh", []discoveryObservation{fresh}, time.Second},
+		{"seen exactly Recent ago", []discoveryObservation{{At: now, LastSeen: now.Add(-time.Minute)}}, time.Second},
+		{"fresh after misses", []discoveryObservation{unseen, stale, fresh}, time.Second},
+		{"one stale", []discoveryObservation{stale}, 5 * time.Second},
+		{"one unseen", []discoveryObservation{unseen}, 5 * time.Second},
+		{"two stale", []discoveryObservation{stale, unseen}, 10 * time.Second},
+		{"three stale", []discoveryObservation{stale, unseen, stale}, 20 * time.Second},
+		{"four stale, capped", []discoveryObservation{stale, stale, stale, stale}, 30 * time.Second},
+		{"many stale, capped", make([]discoveryObservation, 100), 30 * time.Second},
+		{"stale after fresh", []discoveryObservation{fresh, stale, stale}, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.next(tt.history); got != tt.want {
+				t.Errorf("next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package attack
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"elastic-ai-jam-2025/internal/endpoint"
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/latency"
+	"elastic-ai-jam-2025/internal/metrics"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// endpointStats counts the requests made to one endpoint. It is safe for
+// concurrent use; a nil *endpointStats records nothing.
+type endpointStats struct {
+	requests   *metrics.Counter
+	successful *metrics.Counter
+	failed     *metrics.Counter
+	bytes      *metrics.Counter
+	latency    *latency.Histogram
+
+	statuses sync.Map // status code -> *int64
+	failures errclass.Counter
+}
+
+// metricNames names the metrics an endpointStats registers.
+type metricNames struct {
+	requests, successful, failed, bytes, latency string
+}
+
+// endpointMetricNames are the names used in the per-endpoint sub-reports.
+var endpointMetricNames = metricNames{"requests", "successful_requests", "failed_requests", "bytes_received", "request"}
+
+// newEndpointStats registers the endpoint's totals in reg.
+func newEndpointStats(reg *metrics.Registry, names metricNames) *endpointStats {
+	return &endpointStats{
+		requests:   reg.Counter(names.requests, "Requests sent."),
+		successful: reg.Counter(names.successful, "Requests answered with 200 OK."),
+		failed:     reg.Counter(names.failed, "Requests that failed or got another status."),
+		bytes:      reg.Counter(names.bytes, "Response body bytes received."),
+		latency:    reg.Histogram(names.latency, "Time until the response body was read."),
+	}
+}
+
+// observe records one request that started at start. status is 0 when no
+// response was received, in which case err says why; n is the number of body
+// bytes read. A 200 with an error, such as a proxy's error page that is not
+// JSON, is a failure.
+func (s *endpointStats) observe(start time.Time, status int, n int64, err error) {
+	if s == nil {
+		return
+	}
+	s.requests.Inc()
+	if status == 0 {
+		s.failed.Inc()
+		s.failures.AddErr(err)
+		return
+	}
+	s.received(start, status, n)
+	switch {
+	case status != http.StatusOK:
+		s.failed.Inc()
+		s.failures.Add(errclass.HTTPStatus)
+	case err != nil:
+		s.failed.Inc()
+		s.failures.AddErr(err)
+	default:
+		s.successful.Inc()
+	}
+}
+
+// received records the response of a request that started at start.
+func (s *endpointStats) received(start time.Time, status int, n int64) {
+	s.latency.Since(start)
+	s.bytes.Add(n)
+	c, ok := s.statuses.Load(status)
+	if !ok {
+		c, _ = s.statuses.LoadOrStore(status, new(int64))
+	}
+	atomic.AddInt64(c.(*int64), 1)
+}
+
+// observeAPI records one httpapi call. The client does not expose the body
+// size of its successful calls, so no bytes are counted for them.
+func (s *endpointStats) observeAPI(start time.Time, err error) {
+	if s == nil {
+		return
+	}
+	var statusErr *httpapi.StatusError
+	var typeErr *httpapi.ContentTypeError
+	switch {
+	case err == nil:
+		s.observe(start, http.StatusOK, 0, nil)
+	case errors.As(err, &statusErr):
+		s.observe(start, statusErr.StatusCode, int64(len(statusErr.Body)), err)
+	case errors.As(err, &typeErr):
+		s.observe(start, typeErr.StatusCode, int64(typeErr.Size), err)
+	default:
+		// Decode errors still got a response, but the status is not
+		// known here, so they count as failures without one.
+		s.observe(start, 0, 0, err)
+	}
+}
+
+// notJSON returns the number of answers that were not JSON.
+func (s *endpointStats) notJSON() int64 {
+	return s.failures.Snapshot()[errclass.NotJSON]
+}
+
+// statusCounts returns the number of responses per status code.
+func (s *endpointStats) statusCounts() map[int]int64 {
+	out := make(map[int]int64)
+	s.statuses.Range(func(k, v any) bool {
+		out[k.(int)] = atomic.LoadInt64(v.(*int64))
+		return true
+	})
+	return out
+}
+
+// fill writes the endpoint's measurements to sec.
+func (s *endpointStats) fill(sec *report.Section) {
+	sec.Counters[endpointMetricNames.requests] = s.requests.Load()
+	sec.Counters[endpointMetricNames.successful] = s.successful.Load()
+	sec.Counters[endpointMetricNames.failed] = s.failed.Load()
+	if n := s.bytes.Load(); n > 0 {
+		sec.Counters[endpointMetricNames.bytes] = n
+	}
+	for code, n := range s.statusCounts() {
+		sec.Counters["status_"+strconv.Itoa(code)] = n
+	}
+	sec.SetErrors(s.failures.Snapshot())
+	if s.latency.Count() > 0 {
+		sec.Latencies[endpointMetricNames.latency] = s.latency.Summary()
+	}
+}
+
+// byBaseURL holds the stats of one endpoint per -base-url replica. A nil
+// byBaseURL, used with a single replica, holds nothing.
+type byBaseURL map[string]*endpointStats
+
+// newByBaseURL returns the stats of the replicas of l, or nil when there is
+// only one, whose stats are the totals.
+func newByBaseURL(l endpoint.List) byBaseURL {
+	if l.Len() < 2 {
+		return nil
+	}
+	b := make(byBaseURL, l.Len())
+	for _, e := range l.Endpoints() {
+		b[e.Addr] = newEndpointStats(metrics.New(), endpointMetricNames)
+	}
+	return b
+}
+
+// fill adds a "<name> @ <base URL>" sub-report per replica that got
+// requests.
+func (b byBaseURL) fill(rep *report.Report, name string) {
+	for addr, s := range b {
+		if s.requests.Load() > 0 {
+			s.fill(rep.SubSection(name + " @ " + addr))
+		}
+	}
+}
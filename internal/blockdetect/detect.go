@@ -0,0 +1,202 @@
+// Package blockdetect recognizes, from per-second connection counters, the
+// signs that the infrastructure started black-holing or rate-limiting our
+// source address, or that the server is down, as during its maintenance
+// restarts, and pauses the launching of new connections until a probe gets
+// through again. Without it, a block only shows up as a wall of generic
+// timeouts, and an outage as thousands of failures.
+package blockdetect
+
+import (
+	"fmt"
+	"strings"
+
+	"elastic-ai-jam-2025/internal/errclass"
+)
+
+// Reasons a detector trips.
+const (
+	// ReasonSudden is a switch from mostly successful connections to
+	// connections that all fail at the network level.
+	ReasonSudden = "sudden switch to network failures"
+	// ReasonRefused is every connection refused or reset, whatever came
+	// before.
+	ReasonRefused = "connections consistently refused or reset"
+	// ReasonOutage is every attempt failing, whatever the failure, for
+	// OutageWindow.
+	ReasonOutage = "suspected outage"
+)
+
+// Totals are cumulative connection counters, as a command keeps them.
+type Totals struct {
+	Succeeded int64
+	Failed    map[errclass.Class]int64
+}
+
+// tick is what changed in one observation.
+type tick struct {
+	succeeded int64
+	failed    int64
+	network   int64 // failures a block produces: dial timeouts, refusals, resets
+	refused   int64 // failures refused or reset
+	byClass   map[errclass.Class]int64
+}
+
+// Config tunes a Detector. Spans are in observations, one per second when a
+// Guard drives the detector.
+type Config struct {
+	// Window is the span of recent observations examined: the trailing
+	// ones without a success, up to Window of them. ReasonSudden needs all
+	// of Window; ReasonRefused only two. Zero disables both.
+	Window int
+	// OutageWindow is the span of trailing observations without a success
+	// that makes ReasonOutage; zero disables it. It is checked before the
+	// other reasons, which only look at network failures and usually trip
+	// sooner on those.
+	OutageWindow int
+	// Baseline is the span before the window that must have been mostly
+	// successful for ReasonSudden.
+	Baseline int
+	// MinAttempts is the fewest failed attempts the window, and attempts
+	// the baseline for ReasonSudden, must hold to judge them.
+	MinAttempts int64
+	// MinSuccessRate is the share of Baseline attempts that must have
+	// succeeded for it to count as mostly successful.
+	MinSuccessRate float64
+}
+
+// DefaultConfig looks at the last 10 observations after a baseline of 30,
+// and suspects an outage after 30 observations without a success.
+func DefaultConfig() Config {
+	return Config{Window: 10, OutageWindow: 30, Baseline: 30, MinAttempts: 20, MinSuccessRate: 0.8}
+}
+
+// Detector turns a sequence of cumulative totals into a verdict. It is not
+// safe for concurrent use.
+type Detector struct {
+	cfg   Config
+	prev  Totals
+	ticks []tick // oldest first, at most Baseline+max(Window, OutageWindow)
+}
+
+// NewDetector returns a detector with cfg.
+func NewDetector(cfg Config) *Detector {
+	return &Detector{cfg: cfg}
+}
+
+// Verdict is why a detector tripped.
+type Verdict struct {
+	Reason string
+	// Evidence describes the counters that tripped it.
+	Evidence string
+	// Span is the number of trailing observations judged, all without a
+	// success.
+	Span int
+}
+
+// Observe records the totals of one observation and reports whether the
+// window now looks like a block.
+func (d *Detector) Observe(t Totals) (Verdict, bool) {
+	cur := tick{succeeded: t.Succeeded - d.prev.Succeeded, byClass: make(map[errclass.Class]int64)}
+	for c, n := range t.Failed {
+		delta := n - d.prev.Failed[c]
+		if delta <= 0 {
+			continue
+		}
+		cur.byClass[c] = delta
+		cur.failed += delta
+		switch c {
+		case errclass.DialTimeout:
+			cur.network += delta
+		case errclass.Refused, errclass.Reset:
+			cur.network += delta
+			cur.refused += delta
+		}
+	}
+	d.prev = Totals{Succeeded: t.Succeeded, Failed: copyCounts(t.Failed)}
+	d.ticks = append(d.ticks, cur)
+	if n := len(d.ticks) - d.cfg.Baseline - max(d.cfg.Window, d.cfg.OutageWindow); n > 0 {
+		d.ticks = d.ticks[n:]
+	}
+	return d.judge()
+}
+
+// Reset forgets the observations so far and takes t as the totals to count
+// the next observation from, so a new baseline is built from there on.
+func (d *Detector) Reset(t Totals) {
+	d.prev = Totals{Succeeded: t.Succeeded, Failed: copyCounts(t.Failed)}
+	d.ticks = nil
+}
+
+// minRefusedTicks is the fewest observations ReasonRefused needs, so a
+// single burst of refusals does not trip it.
+const minRefusedTicks = 2
+
+// judge looks at the trailing observations without a success: at most
+// OutageWindow of them for an outage, and at most Window of them, against
+// the ones before them, for a block.
+func (d *Detector) judge() (Verdict, bool) {
+	split := len(d.ticks)
+	for split > 0 && len(d.ticks)-split < max(d.cfg.Window, d.cfg.OutageWindow) && d.ticks[split-1].succeeded == 0 {
+		split--
+	}
+	span := len(d.ticks) - split
+	if d.cfg.OutageWindow > 0 && span >= d.cfg.OutageWindow {
+		if win := sum(d.ticks[split:]); win.failed >= d.cfg.MinAttempts {
+			return Verdict{ReasonOutage, fmt.Sprintf("last %ds: %s", span, describe(win)), span}, true
+		}
+	}
+	if d.cfg.Window <= 0 {
+		return Verdict{}, false
+	}
+	span = min(span, d.cfg.Window)
+	split = len(d.ticks) - span
+	win := sum(d.ticks[split:])
+	if win.failed < d.cfg.MinAttempts {
+		return Verdict{}, false
+	}
+	if span >= minRefusedTicks && win.refused == win.failed {
+		return Verdict{ReasonRefused, fmt.Sprintf("last %ds: %s", span, describe(win)), span}, true
+	}
+	from := max(split-d.cfg.Baseline, 0)
+	base := sum(d.ticks[from:split])
+	baseAttempts := base.succeeded + base.failed
+	if span == d.cfg.Window && win.network == win.failed && baseAttempts >= d.cfg.MinAttempts &&
+		float64(base.succeeded) >= d.cfg.MinSuccessRate*float64(baseAttempts) {
+		return Verdict{ReasonSudden, fmt.Sprintf("last %ds: %s; the %ds before: %d of %d succeeded",
+			span, describe(win), split-from, base.succeeded, baseAttempts), span}, true
+	}
+	return Verdict{}, false
+}
+
+func sum(ticks []tick) tick {
+	var s tick
+	s.byClass = make(map[errclass.Class]int64)
+	for _, t := range ticks {
+		s.succeeded += t.succeeded
+		s.failed += t.failed
+		s.network += t.network
+		s.refused += t.refused
+		for c, n := range t.byClass {
+			s.byClass[c] += n
+		}
+	}
+	return s
+}
+
+// describe formats the attempts of t, as in "0 of 57 succeeded (57
+// dial_timeout)".
+func describe(t tick) string {
+	var parts []string
+	for _, c := range errclass.Sorted(t.byClass) {
+		parts = append(parts, fmt.Sprintf("%d %s", t.byClass[c], c))
+	}
+	return fmt.Sprintf("%d of %d succeeded (%s)", t.succeeded, t.succeeded+t.failed, strings.Join(parts, ", "))
+}
+
+func copyCounts(m map[errclass.Class]int64) map[errclass.Class]int64 {
+	out := make(map[errclass.Class]int64, len(m))
+	for c, n := range m {
+		out[c] = n
+	}
+	return out
+}
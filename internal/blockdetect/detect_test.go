@@ -0,0 +1,108 @@
+package blockdetect
+
+import (
+	"testing"
+
+	"elastic-ai-jam-2025/internal/errclass"
+)
+
+// second is what happened during one observation.
+type second struct {
+	ok     int64
+	failed map[errclass.Class]int64
+}
+
+// repeat returns n copies of s.
+func repeat(n int, s second) []second {
+	out := make([]second, n)
+	for i := range out {
+		out[i] = s
+	}
+	return out
+}
+
+func seq(parts ...[]second) []second {
+	var out []second
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+var (
+	healthy      = second{ok: 10}
+	dialTimeouts = second{failed: map[errclass.Class]int64{errclass.DialTimeout: 5}}
+	refused      = second{failed: map[errclass.Class]int64{errclass.Refused: 4, errclass.Reset: 2}}
+	rejected     = second{failed: map[errclass.Class]int64{errclass.Rejected: 5}}
+	trickle      = second{failed: map[errclass.Class]int64{errclass.DialTimeout: 1}}
+)
+
+// observe feeds the seconds to d as cumulative totals and returns the index
+// of the first observation that tripped it, or -1.
+func observe(d *Detector, seconds []second) (int, Verdict) {
+	totals := Totals{Failed: make(map[errclass.Class]int64)}
+	for i, s := range seconds {
+		totals.Succeeded += s.ok
+		for c, n := range s.failed {
+			totals.Failed[c] += n
+		}
+		if v, ok := d.Observe(totals); ok {
+			return i, v
+		}
+	}
+	return -1, Verdict{}
+}
+
+var testConfig = Config{Window: 3, OutageWindow: 6, Baseline: 5, MinAttempts: 10, MinSuccessRate: 0.8}
+
+func TestDetector(t *testing.T) {
+	tests := []struct {
+		name    string
+		seconds []second
+		at      int
+		reason  string
+	}{
+		{"healthy", repeat(30, healthy), -1, ""},
+		{"sudden dial timeouts", seq(repeat(5, healthy), repeat(3, dialTimeouts)), 7, ReasonSudden},
+		{"refused from the start", repeat(3, refused), 1, ReasonRefused},
+		{"refused after a baseline", seq(repeat(5, healthy), repeat(3, refused)), 6, ReasonRefused},
+		{"dial timeouts without a baseline", repeat(10, dialTimeouts), 5, ReasonOutage},
+		{"rejections are an outage, not a block", seq(repeat(5, healthy), repeat(10, rejected)), 10, ReasonOutage},
+		{"too few attempts", seq(repeat(5, healthy), repeat(20, trickle)), -1, ""},
+		{"a success in between", seq(repeat(5, healthy), repeat(2, dialTimeouts), repeat(1, healthy), repeat(2, dialTimeouts)), -1, ""},
+		{"a mostly failing baseline", seq(repeat(5, second{ok: 1, failed: map[errclass.Class]int64{errclass.Rejected: 9}}), repeat(3, dialTimeouts)), -1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, v := observe(NewDetector(testConfig), tt.seconds)
+			if at != tt.at || v.Reason != tt.reason {
+				t.Errorf("tripped at %d with %q (%s), want at %d with %q", at, v.Reason, v.Evidence, tt.at, tt.reason)
+			}
+		})
+	}
+}
+
+func TestDetectorEvidence(t *testing.T) {
+	_, v := observe(NewDetector(testConfig), seq(repeat(5, healthy), repeat(3, dialTimeouts)))
+	want := "last 3s: 0 of 15 succeeded (15 dial_timeout); the 5s before: 50 of 50 succeeded"
+	if v.Evidence != want || v.Span != 3 {
+		t.Errorf("verdict %+v, want evidence %q over 3 observations", v, want)
+	}
+}
+
+func TestDetectorReset(t *testing.T) {
+	d := NewDetector(testConfig)
+	if at, _ := observe(d, seq(repeat(5, healthy), repeat(3, dialTimeouts))); at < 0 {
+		t.Fatal("the block was not detected")
+	}
+	d.Reset(Totals{Succeeded: 50, Failed: map[errclass.Class]int64{errclass.DialTimeout: 15}})
+	// Counted from the reset, the same totals are no new attempt; the
+	// dial timeouts after it have no baseline to be sudden against.
+	totals := Totals{Succeeded: 50, Failed: map[errclass.Class]int64{errclass.DialTimeout: 15}}
+	for i := range 5 {
+		if v, ok := d.Observe(totals); ok {
+			t.Fatalf("tripped %d observations after the reset: %+v", i+1, v)
+		}
+		totals.Failed = map[errclass.Class]int64{errclass.DialTimeout: totals.Failed[errclass.DialTimeout] + 5}
+	}
+}
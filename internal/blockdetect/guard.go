@@ -0,0 +1,249 @@
+package blockdetect
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/clock"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Event is one detected block or outage and the pause that followed it.
+type Event struct {
+	At time.Time
+	// Since is when the failures that tripped the detector began, the
+	// start of the span the verdict judged.
+	Since time.Time
+	Verdict
+	// Paused is how long launching was paused.
+	Paused time.Duration
+	// Probes is the number of single connections tried before resuming.
+	Probes int
+}
+
+// Outage reports whether e is a suspected outage rather than a block.
+func (e Event) Outage() bool { return e.Reason == ReasonOutage }
+
+// Interval is how long the outage or block lasted as far as we saw: from
+// the first failure judged to the probe that got through.
+func (e Event) Interval() time.Duration { return e.At.Add(e.Paused).Sub(e.Since) }
+
+func (e Event) String() string {
+	if e.Outage() {
+		return fmt.Sprintf("%s to %s (%s): %s: %s; %d probes", e.Since.UTC().Format(time.RFC3339), e.At.Add(e.Paused).UTC().Format(time.RFC3339),
+			e.Interval().Round(time.Second), e.Reason, e.Evidence, e.Probes)
+	}
+	return fmt.Sprintf("%s: %s: %s; paused %s, %d probes", e.At.UTC().Format(time.RFC3339), e.Reason, e.Evidence, e.Paused.Round(time.Second), e.Probes)
+}
+
+// Guard observes a command's totals every second and, when its detector
+// trips, pauses launching for Cooldown, then probes with a single
+// connection, pausing again for as long as the probes fail. A suspected
+// outage is probed every OutageProbeInterval instead. A nil *Guard never
+// pauses, so commands can call it unconditionally.
+type Guard struct {
+	// Cooldown is the pause after a detection and after each failed probe.
+	Cooldown time.Duration
+	// OutageProbeInterval replaces Cooldown for suspected outages, when
+	// positive.
+	OutageProbeInterval time.Duration
+	// Probe tries a single connection.
+	Probe func() error
+	// Totals returns the command's cumulative counters.
+	Totals func() Totals
+	// Out receives the detection banners.
+	Out io.Writer
+	// Clock paces the observations, pauses and probes.
+	Clock clock.Clock
+
+	det    *Detector
+	mu     sync.Mutex
+	resume chan struct{} // non-nil while paused; closed on resuming
+	events []Event
+}
+
+// NewGuard returns a guard judging with cfg.
+func NewGuard(cfg Config, cooldown time.Duration, probe func() error, totals func() Totals, out io.Writer) *Guard {
+	return &Guard{Cooldown: cooldown, Probe: probe, Totals: totals, Out: out, Clock: clock.Real, det: NewDetector(cfg)}
+}
+
+// Start observes the totals every second until ctx is done or the returned
+// function is called.
+func (g *Guard) Start(ctx context.Context) (stop func()) {
+	if g == nil {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := g.Clock.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+			case <-ctx.Done():
+				return
+			}
+			if v, ok := g.det.Observe(g.Totals()); ok {
+				g.pause(ctx, v)
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
+
+// Wait blocks while launching is paused. It returns ctx.Err() if ctx is
+// done first.
+func (g *Guard) Wait(ctx context.Context) error {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	resume := g.resume
+	g.mu.Unlock()
+	if resume == nil {
+		return nil
+	}
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pause holds launching until a probe succeeds or ctx is done, and records
+// the event.
+func (g *Guard) pause(ctx context.Context, v Verdict) {
+	now := g.Clock.Now()
+	ev := Event{At: now, Since: now.Add(-time.Duration(v.Span) * time.Second), Verdict: v}
+	g.mu.Lock()
+	g.resume = make(chan struct{})
+	g.mu.Unlock()
+	wait := g.Cooldown
+	fmt.Fprintln(g.Out, "!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!")
+	if ev.Outage() {
+		if g.OutageProbeInterval > 0 {
+			wait = g.OutageProbeInterval
+		}
+		fmt.Fprintf(g.Out, "!!! Suspected outage of the server detected at %s\n", ev.At.Format(time.RFC3339))
+	} else {
+		fmt.Fprintf(g.Out, "!!! Possible block/rate-limit detected at %s\n", ev.At.Format(time.RFC3339))
+	}
+	wait = max(wait, time.Second) // an outage guard may have no Cooldown
+	fmt.Fprintf(g.Out, "!!! %s: %s\n", v.Reason, v.Evidence)
+	fmt.Fprintf(g.Out, "!!! Pausing new connections for %s, then probing with a single connection.\n", wait)
+	fmt.Fprintln(g.Out, "!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!")
+	if ev.Outage() {
+		slog.Warn("suspected outage", "evidence", v.Evidence, "probe_interval", wait)
+	} else {
+		slog.Warn("possible block or rate limit", "reason", v.Reason, "evidence", v.Evidence, "cooldown", wait)
+	}
+
+	for ctx.Err() == nil {
+		select {
+		case <-g.Clock.After(wait):
+		case <-ctx.Done():
+			continue
+		}
+		ev.Probes++
+		err := g.Probe()
+		if err == nil {
+			fmt.Fprintf(g.Out, "Probe connection succeeded after a pause of %s: resuming.\n", g.Clock.Now().Sub(ev.At).Round(time.Second))
+			break
+		}
+		fmt.Fprintf(g.Out, "Probe connection failed (%v): pausing another %s.\n", err, wait)
+	}
+
+	ev.Paused = g.Clock.Now().Sub(ev.At)
+	g.det.Reset(g.Totals())
+	g.mu.Lock()
+	close(g.resume)
+	g.resume = nil
+	g.events = append(g.events, ev)
+	g.mu.Unlock()
+}
+
+// Events returns the blocks detected so far.
+func (g *Guard) Events() []Event {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Event(nil), g.events...)
+}
+
+// split returns the blocks and the outages detected so far.
+func (g *Guard) split() (blocks, outages []Event) {
+	for _, e := range g.Events() {
+		if e.Outage() {
+			outages = append(outages, e)
+		} else {
+			blocks = append(blocks, e)
+		}
+	}
+	return blocks, outages
+}
+
+// OutageTime returns the total interval of the suspected outages so far.
+func (g *Guard) OutageTime() time.Duration {
+	_, outages := g.split()
+	var total time.Duration
+	for _, e := range outages {
+		total += e.Interval()
+	}
+	return total
+}
+
+// PrintSummary prints the blocks and outages detected, if any.
+func (g *Guard) PrintSummary(w io.Writer) {
+	blocks, outages := g.split()
+	if len(blocks) > 0 {
+		var paused time.Duration
+		for _, e := range blocks {
+			paused += e.Paused
+		}
+		fmt.Fprintf(w, "Possible blocks/rate-limits detected: %d, launching paused for %s in total\n", len(blocks), paused.Round(time.Second))
+		for _, e := range blocks {
+			fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+	if len(outages) > 0 {
+		fmt.Fprintf(w, "Suspected outages: %d, %s in total\n", len(outages), g.OutageTime().Round(time.Second))
+		for _, e := range outages {
+			fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+}
+
+// Fill puts the detected blocks and outages in rep: the block_events and
+// block_pause_ms counters and a block_<n> detail per block, and the
+// outage_events and outage_ms counters and an outage_<n> detail per outage.
+func (g *Guard) Fill(rep *report.Report) {
+	blocks, outages := g.split()
+	if len(blocks) > 0 {
+		var paused time.Duration
+		for i, e := range blocks {
+			paused += e.Paused
+			rep.Details[fmt.Sprintf("block_%d", i+1)] = e.String()
+		}
+		rep.Counters["block_events"] = int64(len(blocks))
+		rep.Counters["block_pause_ms"] = paused.Milliseconds()
+	}
+	if len(outages) > 0 {
+		for i, e := range outages {
+			rep.Details[fmt.Sprintf("outage_%d", i+1)] = e.String()
+		}
+		rep.Counters["outage_events"] = int64(len(outages))
+		rep.Counters["outage_ms"] = g.OutageTime().Milliseconds()
+	}
+}
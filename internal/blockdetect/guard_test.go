@@ -0,0 +1,158 @@
+package blockdetect
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/clock"
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// waitFor polls cond until it holds, failing the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for start := time.Now(); !cond(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestGuardPausesOnTheClock(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1_700_000_000, 0))
+	var observed, probes atomic.Int64
+	totals := func() Totals {
+		n := observed.Add(1)
+		return Totals{Failed: map[errclass.Class]int64{errclass.Refused: 10 * n}}
+	}
+	probe := func() error {
+		if probes.Add(1) == 1 {
+			return errors.New("refused")
+		}
+		return nil
+	}
+	g := NewGuard(Config{Window: 2, MinAttempts: 10}, 20*time.Second, probe, totals, io.Discard)
+	g.Clock = fake
+	stop := g.Start(context.Background())
+	defer stop()
+
+	// Two seconds of refusals trip the guard.
+	waitFor(t, "the guard's ticker", func() bool { return fake.Waiters() == 1 })
+	fake.Advance(time.Second)
+	waitFor(t, "the first observation", func() bool { return observed.Load() == 1 })
+	fake.Advance(time.Second)
+	waitFor(t, "the pause", func() bool { return fake.Waiters() == 2 })
+
+	resumed := make(chan error, 1)
+	go func() { resumed <- g.Wait(context.Background()) }()
+
+	// The first probe fails, pausing another cooldown; the second gets
+	// through.
+	fake.Advance(20 * time.Second)
+	waitFor(t, "the first probe", func() bool { return probes.Load() == 1 && fake.Waiters() == 2 })
+	select {
+	case <-resumed:
+		t.Fatal("launching resumed after a failed probe")
+	default:
+	}
+	fake.Advance(20 * time.Second)
+	select {
+	case err := <-resumed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("launching did not resume after a successful probe")
+	}
+
+	waitFor(t, "the event", func() bool { return len(g.Events()) == 1 })
+	ev := g.Events()[0]
+	if ev.Reason != ReasonRefused || ev.Probes != 2 || ev.Paused != 40*time.Second {
+		t.Errorf("event = %s after %d probes and %s paused, want %s after 2 probes and 40s paused", ev.Reason, ev.Probes, ev.Paused, ReasonRefused)
+	}
+}
+
+func TestGuardSuspectsOutage(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1_700_000_000, 0))
+	var observed, probes atomic.Int64
+	totals := func() Totals {
+		n := observed.Add(1)
+		return Totals{Failed: map[errclass.Class]int64{errclass.Rejected: 10 * n}}
+	}
+	probe := func() error {
+		if probes.Add(1) <= 2 {
+			return errors.New("rejected")
+		}
+		return nil
+	}
+	g := NewGuard(Config{OutageWindow: 3, MinAttempts: 10}, 20*time.Second, probe, totals, io.Discard)
+	g.OutageProbeInterval = time.Minute
+	g.Clock = fake
+	stop := g.Start(context.Background())
+	defer stop()
+
+	// Three seconds of nothing but rejections suspect an outage.
+	waitFor(t, "the guard's ticker", func() bool { return fake.Waiters() == 1 })
+	for i := range 3 {
+		fake.Advance(time.Second)
+		waitFor(t, "an observation", func() bool { return observed.Load() == int64(i+1) })
+	}
+	waitFor(t, "the pause", func() bool { return fake.Waiters() == 2 })
+
+	// Probed every minute, not every cooldown: two fail, the third gets
+	// through.
+	for i := range 3 {
+		fake.Advance(20 * time.Second)
+		if n := probes.Load(); n != int64(i) {
+			t.Fatalf("%d probes 20s into a probe interval, want %d", n, i)
+		}
+		fake.Advance(40 * time.Second)
+		waitFor(t, "a probe", func() bool { return probes.Load() == int64(i+1) })
+		if i < 2 {
+			waitFor(t, "the next pause", func() bool { return fake.Waiters() == 2 })
+		}
+	}
+	if err := g.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "the event", func() bool { return len(g.Events()) == 1 })
+	ev := g.Events()[0]
+	if !ev.Outage() || ev.Probes != 3 || ev.Paused != 3*time.Minute {
+		t.Errorf("event = %s after %d probes and %s paused, want an outage after 3 probes and 3m paused", ev.Reason, ev.Probes, ev.Paused)
+	}
+	if ev.Interval() != 3*time.Minute+3*time.Second {
+		t.Errorf("outage interval = %s, want the 3s judged and the 3m paused", ev.Interval())
+	}
+
+	rep := report.New("flood", nil)
+	g.Fill(rep)
+	if rep.Counters["outage_events"] != 1 || rep.Counters["outage_ms"] != 183_000 || rep.Details["outage_1"] == "" {
+		t.Errorf("report counters %v, details %v", rep.Counters, rep.Details)
+	}
+	if _, ok := rep.Counters["block_events"]; ok {
+		t.Error("the outage was reported as a block")
+	}
+	var summary strings.Builder
+	g.PrintSummary(&summary)
+	if !strings.Contains(summary.String(), "Suspected outages: 1, 3m3s in total") {
+		t.Errorf("summary = %q", summary.String())
+	}
+}
+
+func TestNilGuard(t *testing.T) {
+	var g *Guard
+	g.Start(context.Background())()
+	if err := g.Wait(context.Background()); err != nil {
+		t.Errorf("Wait on a nil guard = %v", err)
+	}
+	if g.Events() != nil || g.OutageTime() != 0 {
+		t.Error("a nil guard detected something")
+	}
+}
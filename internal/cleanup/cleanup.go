@@ -0,0 +1,475 @@
+// Package cleanup implements the "cleanup" command: it takes the accounts a
+// flood or play run created out of circulation once the run is over.
+//
+// The server has no documented way to delete an account, and the TCP
+// protocol has no leave action. When the organizers provide a deletion
+// endpoint, -delete-url removes each account through it. Otherwise each
+// account is parked: logged in over TCP and disconnected without joining.
+// The server seats a connection only after it sent join, so a parked account
+// stays out of games until something logs in with it and joins again.
+package cleanup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/credentials"
+	"elastic-ai-jam-2025/internal/endpoint"
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/latency"
+	"elastic-ai-jam-2025/internal/netaddr"
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/report"
+	"elastic-ai-jam-2025/internal/sink"
+)
+
+// Paths an account can be cleaned up by.
+const (
+	pathDelete = "delete"
+	pathPark   = "park"
+)
+
+// Outcomes of an account's cleanup.
+const (
+	outcomeRemoved  = "removed" // deleted through -delete-url
+	outcomeParked   = "parked"  // logged in and disconnected without joining
+	outcomeNotFound = "not_found"
+	outcomeFailed   = "failed"
+)
+
+// resultsQueueSize bounds the outcomes waiting for the -results-out file.
+const resultsQueueSize = 4096
+
+// outcomes lists every outcome, in the order the summary prints them.
+var outcomes = []string{outcomeRemoved, outcomeParked, outcomeNotFound, outcomeFailed}
+
+// Config is the configuration of a cleanup run.
+type Config struct {
+	cli.Common
+
+	// Credentials, when set, is the file of the accounts to clean up, one
+	// per line: "username:password", a username alone with
+	// -password-secret, or a play -results-out record, whose password is
+	// the one Passwords gives the player. Without it, the accounts are
+	// UsernamePrefix followed by FirstIndex .. FirstIndex+NumPlayers-1, as
+	// flood and play name them, with the passwords of Passwords.
+	Credentials    string
+	UsernamePrefix string
+	FirstIndex     int
+	NumPlayers     int
+
+	MaxConcurrent int
+
+	// DeleteURL, when set, is the account deletion endpoint, with {player}
+	// standing for the username. Each account is removed with a DELETE
+	// request carrying its credentials as JSON; 404 means it did not exist.
+	// Without it, accounts are parked.
+	DeleteURL string
+
+	// LeaderboardLimit bounds the leaderboard fetched before parking. An
+	// account missing from it is counted not found and left alone, since
+	// logging in with it would create it.
+	LeaderboardLimit int
+
+	// ResultsOut, when set, is the NDJSON file of per-account outcomes.
+	ResultsOut string
+
+	// DryRun lists the accounts and the path that would be used, without
+	// contacting the server.
+	DryRun bool
+}
+
+// DefaultConfig returns the cleanup defaults, which match flood's accounts.
+func DefaultConfig() Config {
+	return Config{
+		Common:           cli.DefaultCommon(),
+		UsernamePrefix:   "over",
+		NumPlayers:       100,
+		MaxConcurrent:    20,
+		LeaderboardLimit: 100000,
+	}
+}
+
+// RegisterFlags adds the cleanup flags to fs.
+func (cfg *Config) RegisterFlags(fs *flag.FlagSet) {
+	cfg.Common.Register(fs)
+	cfg.Common.RegisterDialLimitFlag(fs)
+	cfg.Common.RegisterSeatbeltFlags(fs)
+	fs.StringVar(&cfg.Credentials, "credentials", cfg.Credentials, "file of the accounts to clean up: username:password lines, usernames alone with -password-secret, or a play -results-out file (default: the -username-prefix range)")
+	fs.StringVar(&cfg.UsernamePrefix, "username-prefix", cfg.UsernamePrefix, "prefix of the usernames to clean up, without -credentials")
+	cfg.Common.RegisterPasswordFlags(fs, "prefix of the passwords, followed by the player index (also for -credentials results files)")
+	fs.IntVar(&cfg.FirstIndex, "first-index", cfg.FirstIndex, "index of the first account, without -credentials")
+	fs.IntVar(&cfg.NumPlayers, "players", cfg.NumPlayers, "number of accounts, without -credentials")
+	fs.IntVar(&cfg.MaxConcurrent, "concurrency", cfg.MaxConcurrent, "number of accounts cleaned up in parallel")
+	fs.StringVar(&cfg.DeleteURL, "delete-url", cfg.DeleteURL, "account deletion endpoint, {player} standing for the username, e.g. http://host/api/v0/players/{player} (default: park the accounts, as the server has no known delete)")
+	fs.IntVar(&cfg.LeaderboardLimit, "leaderboard-limit", cfg.LeaderboardLimit, "leaderboard entries fetched to tell which accounts exist before parking them")
+	fs.StringVar(&cfg.ResultsOut, "results-out", cfg.ResultsOut, "write per-account outcomes to this NDJSON file")
+	fs.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "list the accounts and how they would be cleaned up, without contacting the server")
+}
+
+func newFlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("cleanup", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	return fs
+}
+
+// Flags returns the cleanup flag set with default values.
+func Flags() *flag.FlagSet {
+	cfg := DefaultConfig()
+	return newFlagSet(&cfg)
+}
+
+// path returns the path the accounts are cleaned up by.
+func (cfg *Config) path() string {
+	if cfg.DeleteURL != "" {
+		return pathDelete
+	}
+	return pathPark
+}
+
+// describePath says how the accounts are cleaned up, and why.
+func (cfg *Config) describePath() string {
+	if cfg.path() == pathDelete {
+		return fmt.Sprintf("delete (DELETE %s)", cfg.DeleteURL)
+	}
+	return fmt.Sprintf("park (no -delete-url, and the protocol has no leave action: log in on %s without joining, then disconnect)", cfg.TCPServer)
+}
+
+// targets returns the endpoints the run loads, for the seatbelt, and
+// normalizes the host of -delete-url on the way.
+func (cfg *Config) targets() (endpoint.List, error) {
+	if cfg.path() == pathPark {
+		return cfg.TCPServer, nil
+	}
+	deleteURL, err := netaddr.BaseURL(cfg.DeleteURL, "")
+	if err != nil || !strings.Contains(cfg.DeleteURL, "{player}") {
+		return endpoint.List{}, fmt.Errorf("-delete-url must be an absolute URL containing {player}, got %q", cfg.DeleteURL)
+	}
+	cfg.DeleteURL = deleteURL
+	u, err := url.Parse(deleteURL)
+	if err != nil {
+		return endpoint.List{}, fmt.Errorf("-delete-url: %w", err)
+	}
+	return endpoint.Parse(u.Host)
+}
+
+// accounts returns the accounts to clean up.
+func (cfg *Config) accounts() ([]credentials.Account, error) {
+	if err := cfg.Passwords.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Credentials != "" {
+		return credentials.Read(cfg.Credentials, cfg.Passwords)
+	}
+	if cfg.NumPlayers <= 0 {
+		return nil, errors.New("-players must be positive")
+	}
+	return credentials.Range(cfg.UsernamePrefix, cfg.Passwords, cfg.FirstIndex, cfg.NumPlayers), nil
+}
+
+// result is the outcome of one account, as written to -results-out.
+type result struct {
+	Player     string         `json:"player"`
+	Path       string         `json:"path"`
+	Outcome    string         `json:"outcome"`
+	ErrorClass errclass.Class `json:"error_class,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// tally counts the outcomes of a run and queues them for -results-out. It
+// is safe for concurrent use.
+type tally struct {
+	mu       sync.Mutex
+	counts   map[string]int64
+	failures errclass.Counter
+	latency  latency.Histogram
+	out      *sink.Sink
+}
+
+func (t *tally) record(r result, took time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counts[r.Outcome]++
+	if r.Outcome != outcomeFailed && took > 0 {
+		t.latency.Record(took)
+	}
+	if t.out != nil {
+		line, _ := json.Marshal(r)
+		t.out.Put(append(line, '\n'))
+	}
+}
+
+// Run is the entry point of the cleanup command. It exits 1 when any
+// account could not be cleaned up.
+func Run(args []string) int {
+	cfg := DefaultConfig()
+	fs := newFlagSet(&cfg)
+	if code, stop := cli.Parse(fs, args); stop {
+		return code
+	}
+	closeLog, err := cfg.SetupLogging()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer closeLog()
+	if cfg.MaxConcurrent <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: -concurrency must be positive")
+		return 2
+	}
+	targets, err := cfg.targets()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	accounts, err := cfg.accounts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	for _, a := range accounts {
+		if err := cfg.CheckIdent(a.Username); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+
+	if cfg.DryRun {
+		dryRun(os.Stdout, &cfg, accounts)
+		return 0
+	}
+	volume := fmt.Sprintf("%d account deletions, %d at a time", len(accounts), cfg.MaxConcurrent)
+	if cfg.path() == pathPark {
+		volume = fmt.Sprintf("%d logins, one TCP connection each, %d at a time", len(accounts), cfg.MaxConcurrent)
+	}
+	if err := cfg.ConfirmDestructive("cleanup", targets, volume); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	pokerclient.DialLimit = pokerclient.NewDialLimiter(cfg.MaxConcurrentDials)
+
+	rep := report.New("cleanup", cli.Effective(fs))
+	rep.Details["cleanup_path"] = cfg.path()
+	t := &tally{counts: make(map[string]int64)}
+	if cfg.ResultsOut != "" {
+		f, err := os.Create(cfg.ResultsOut)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		defer f.Close()
+		t.out = sink.New("results", resultsQueueSize, sink.Buffered(f))
+	}
+
+	fmt.Printf("--- Cleaning up %d accounts ---\n", len(accounts))
+	fmt.Printf("Path: %s\n", cfg.describePath())
+	if cfg.path() == pathPark {
+		known, err := existingAccounts(&cfg, accounts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			rep.Finish(report.StatusFailed, err.Error())
+			cfg.WriteReport(rep)
+			return 1
+		}
+		var kept []credentials.Account
+		for _, a := range accounts {
+			if known[a.Username] {
+				kept = append(kept, a)
+			} else {
+				t.record(result{Player: a.Username, Path: pathPark, Outcome: outcomeNotFound}, 0)
+			}
+		}
+		accounts = kept
+	}
+
+	ctx, stop := cli.InterruptContext()
+	defer stop()
+	start := time.Now()
+	launched := runCleanup(ctx, &cfg, accounts, t)
+	elapsed := time.Since(start)
+
+	outErr := t.out.Close()
+	printSummary(os.Stdout, &cfg, t, elapsed)
+	fillReport(rep, t)
+	if outErr != nil {
+		fmt.Fprintf(os.Stderr, "Error writing results: %v\n", outErr)
+	} else if cfg.ResultsOut != "" {
+		fmt.Printf("Account outcomes written to %s\n", cfg.ResultsOut)
+	}
+
+	code, status, reason := 0, "", ""
+	switch {
+	case ctx.Err() != nil:
+		code, status, reason = 1, report.StatusInterrupted, fmt.Sprintf("interrupted after launching %d of %d accounts", launched, len(accounts))
+	case t.counts[outcomeFailed] > 0:
+		code, status, reason = 1, report.StatusFailed, fmt.Sprintf("%d accounts failed", t.counts[outcomeFailed])
+	}
+	rep.Finish(status, reason)
+	cfg.WriteReport(rep)
+	return code
+}
+
+// dryRun lists the accounts and how they would be cleaned up.
+func dryRun(w io.Writer, cfg *Config, accounts []credentials.Account) {
+	fmt.Fprintln(w, "--- Dry run: cleanup ---")
+	fmt.Fprintf(w, "Path: %s\n", cfg.describePath())
+	if cfg.path() == pathPark {
+		fmt.Fprintf(w, "Accounts missing from the first %d leaderboard entries would be skipped as not found.\n", cfg.LeaderboardLimit)
+	}
+	fmt.Fprintf(w, "Would clean up %d accounts, %d at a time:\n", len(accounts), cfg.MaxConcurrent)
+	for _, a := range accounts {
+		fmt.Fprintf(w, "  %s\n", a.Username)
+	}
+}
+
+// existingAccounts returns which of accounts are on the leaderboard. It
+// warns when the leaderboard was cut at -leaderboard-limit, as accounts
+// missing from it may then exist all the same.
+func existingAccounts(cfg *Config, accounts []credentials.Account) (map[string]bool, error) {
+	api := httpapi.New(cfg.BaseURL.First(), cfg.RequestTimeout)
+	lb, err := api.Leaderboard(cfg.LeaderboardLimit)
+	if err != nil {
+		return nil, fmt.Errorf("fetching the leaderboard to tell which accounts exist: %w", err)
+	}
+	if len(lb.Entries) >= cfg.LeaderboardLimit {
+		fmt.Fprintf(os.Stderr, "WARNING: the leaderboard was cut at -leaderboard-limit %d entries; accounts missing from it are skipped as not found, but may exist\n", cfg.LeaderboardLimit)
+	}
+	wanted := make(map[string]bool, len(accounts))
+	for _, a := range accounts {
+		wanted[a.Username] = true
+	}
+	known := make(map[string]bool)
+	for _, e := range lb.Entries {
+		if wanted[e.PlayerID] {
+			known[e.PlayerID] = true
+		}
+	}
+	return known, nil
+}
+
+// runCleanup cleans up the accounts, cfg.MaxConcurrent at a time. It stops
+// launching when ctx is cancelled, and returns the number launched.
+func runCleanup(ctx context.Context, cfg *Config, accounts []credentials.Account, t *tally) int {
+	client := &http.Client{Timeout: cfg.RequestTimeout, Transport: httpapi.Transport(nil)}
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, cfg.MaxConcurrent)
+	launched := 0
+	for i, a := range accounts {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		launched++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			r := result{Player: a.Username, Path: cfg.path()}
+			start := time.Now()
+			var err error
+			if r.Path == pathDelete {
+				r.Outcome, err = deleteAccount(client, cfg.DeleteURL, a)
+			} else {
+				r.Outcome, err = parkAccount(cfg, cfg.TCPServer.For(i), a)
+			}
+			if err != nil {
+				t.failures.AddErr(err)
+				r.Outcome, r.ErrorClass, r.Error = outcomeFailed, errclass.Classify(err), err.Error()
+				slog.Warn("Cleanup failed", "player", a.Username, "path", r.Path, "error", err)
+			} else {
+				slog.Debug("Cleaned up", "player", a.Username, "outcome", r.Outcome)
+			}
+			t.record(r, time.Since(start))
+		}()
+	}
+	wg.Wait()
+	return launched
+}
+
+// deleteAccount removes a through the deletion endpoint.
+func deleteAccount(client *http.Client, deleteURL string, a credentials.Account) (string, error) {
+	target := strings.ReplaceAll(deleteURL, "{player}", url.PathEscape(a.Username))
+	body, err := json.Marshal(map[string]string{"username": a.Username, "password": a.Password})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodDelete, target, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("error creating request for %s: %w", target, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error making DELETE request to %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return outcomeNotFound, nil
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return outcomeRemoved, nil
+	default:
+		return "", &httpapi.StatusError{URL: target, StatusCode: resp.StatusCode, Status: resp.Status, Body: string(respBody)}
+	}
+}
+
+// parkAccount logs in with a on addr and disconnects without joining.
+func parkAccount(cfg *Config, addr string, a credentials.Account) (string, error) {
+	conn, err := pokerclient.Dial(addr, cfg.ConnectTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	cfg.ApplyTimeouts(conn)
+	if _, err := conn.Register(a.Username, a.Password); err != nil {
+		return "", err
+	}
+	return outcomeParked, nil
+}
+
+// printSummary writes the counts of each outcome and the failures by class.
+func printSummary(w io.Writer, cfg *Config, t *tally, elapsed time.Duration) {
+	fmt.Fprintln(w, "-----------------------------------------")
+	fmt.Fprintf(w, "Path: %s\n", cfg.path())
+	for _, o := range outcomes {
+		fmt.Fprintf(w, "%-10s %d\n", strings.ReplaceAll(o, "_", " ")+":", t.counts[o])
+	}
+	if failures := t.failures.Snapshot(); len(failures) > 0 {
+		fmt.Fprintln(w, "Failures by class:")
+		errclass.PrintCounts(w, failures)
+	}
+	fmt.Fprintf(w, "Latency: %s\n", t.latency.Summary())
+	fmt.Fprintf(w, "Elapsed: %s\n", elapsed.Round(time.Millisecond))
+	pokerclient.DialLimit.Print(w)
+	t.out.Print(w)
+}
+
+// fillReport records the outcome counts, failures and latency in rep.
+func fillReport(rep *report.Report, t *tally) {
+	for _, o := range outcomes {
+		rep.Counters[o] = t.counts[o]
+	}
+	rep.SetErrors(t.failures.Snapshot())
+	t.out.Fill(rep)
+	if t.latency.Count() > 0 {
+		rep.Latencies["cleanup"] = t.latency.Summary()
+	}
+}
@@ -0,0 +1,95 @@
+// Package cli holds the pieces shared by every aijam subcommand: the command
+// table and dispatcher, the common flag set, and logging setup.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Command is a single aijam subcommand.
+type Command struct {
+	Name    string
+	Aliases []string
+	Summary string
+	// Run executes the command with the arguments following the command name
+	// and returns the process exit code.
+	Run func(args []string) int
+	// Flags, when set, returns the command's flag set with default values.
+	// It is used to resolve the configuration without running the command.
+	Flags func() *flag.FlagSet
+}
+
+// registry holds the commands passed to Dispatch.
+var registry []Command
+
+// Dispatch looks up the command named by args[0] and runs it with the
+// remaining arguments. A bare invocation, "help", -h or --help prints usage.
+func Dispatch(prog string, commands []Command, args []string) int {
+	registry = commands
+	if len(args) == 0 {
+		Usage(os.Stderr, prog, commands)
+		return 2
+	}
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		Usage(os.Stdout, prog, commands)
+		return 0
+	}
+	for _, cmd := range commands {
+		if cmd.Name == name || contains(cmd.Aliases, name) {
+			return cmd.Run(args[1:])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%s: unknown command %q\n\n", prog, name)
+	Usage(os.Stderr, prog, commands)
+	return 2
+}
+
+// Usage prints the list of available commands.
+func Usage(w io.Writer, prog string, commands []Command) {
+	fmt.Fprintf(w, "Usage: %s <command> [flags]\n\nCommands:\n", prog)
+	for _, cmd := range commands {
+		name := cmd.Name
+		if len(cmd.Aliases) > 0 {
+			name += " (" + strings.Join(cmd.Aliases, ", ") + ")"
+		}
+		fmt.Fprintf(w, "  %-24s %s\n", name, cmd.Summary)
+	}
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for the flags of a command.\n", prog)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse parses args into fs and fills in the flags not given on the command
+// line from the environment and the -config file. When stop is true the
+// command should return exit straight away, either because help was printed
+// or the flags were bad.
+func Parse(fs *flag.FlagSet, args []string) (exit int, stop bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0, true
+		}
+		return 2, true
+	}
+	if _, err := applyConfig(fs); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2, true
+	}
+	for _, hook := range flagHooks {
+		hook.parsed(fs)
+	}
+	return 0, false
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDispatch(t *testing.T) {
+	defer func(saved []Command) { registry = saved }(registry)
+	var ran string
+	var got []string
+	cmd := func(name string) func([]string) int {
+		return func(args []string) int {
+			ran, got = name, args
+			return 7
+		}
+	}
+	commands := []Command{
+		{Name: "play", Aliases: []string{"create-and-play"}, Run: cmd("play")},
+		{Name: "flood", Run: cmd("flood")},
+	}
+	tests := []struct {
+		args     []string
+		code     int
+		ran      string
+		withArgs []string
+	}{
+		{[]string{"play", "-players", "3"}, 7, "play", []string{"-players", "3"}},
+		{[]string{"create-and-play"}, 7, "play", []string{}},
+		{[]string{"flood", "x"}, 7, "flood", []string{"x"}},
+		{[]string{"attack"}, 2, "", nil},
+		{nil, 2, "", nil},
+		{[]string{"help"}, 0, "", nil},
+		{[]string{"-h"}, 0, "", nil},
+		{[]string{"--help", "play"}, 0, "", nil},
+	}
+	for _, tt := range tests {
+		ran, got = "", nil
+		if code := Dispatch("aijam", commands, tt.args); code != tt.code {
+			t.Errorf("Dispatch(%q) = %d, want %d", tt.args, code, tt.code)
+		}
+		if ran != tt.ran || tt.ran != "" && !reflect.DeepEqual(got, tt.withArgs) {
+			t.Errorf("Dispatch(%q) ran %q with %q, want %q with %q", tt.args, ran, got, tt.ran, tt.withArgs)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	var buf bytes.Buffer
+	Usage(&buf, "aijam", []Command{
+		{Name: "play", Aliases: []string{"create-and-play"}, Summary: "play games"},
+		{Name: "flood", Summary: "register players"},
+	})
+	out := buf.String()
+	for _, want := range []string{"Usage: aijam <command> [flags]", "play (create-and-play)", "play games", "flood", "register players", "aijam <command> -h"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("usage lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestParseStops(t *testing.T) {
+	newFS := func() *flag.FlagSet {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(&bytes.Buffer{})
+		fs.Int("players", 1, "")
+		return fs
+	}
+	tests := []struct {
+		args []string
+		code int
+		stop bool
+	}{
+		{[]string{"-players", "3"}, 0, false},
+		{[]string{"-h"}, 0, true},
+		{[]string{"-players", "many"}, 2, true},
+		{[]string{"-unknown"}, 2, true},
+	}
+	for _, tt := range tests {
+		code, stop := Parse(newFS(), tt.args)
+		if code != tt.code || stop != tt.stop {
+			t.Errorf("Parse(%q) = %d, %v; want %d, %v", tt.args, code, stop, tt.code, tt.stop)
+		}
+	}
+}
+
+func TestTimeoutDefaults(t *testing.T) {
+	c := DefaultCommon()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c.Register(fs)
+	tests := []struct {
+		flag string
+		want string
+	}{
+		{"connect-timeout", "10s"},
+		{"register-timeout", "30s"},
+		{"read-timeout", "10s"},
+		{"write-timeout", "10s"},
+	}
+	for _, tt := range tests {
+		if got := fs.Lookup(tt.flag).DefValue; got != tt.want {
+			t.Errorf("-%s defaults to %s, want %s", tt.flag, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,525 @@
+package cli
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"elastic-ai-jam-2025/internal/blockdetect"
+	"elastic-ai-jam-2025/internal/endpoint"
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/latency"
+	"elastic-ai-jam-2025/internal/netaddr"
+	"elastic-ai-jam-2025/internal/passwords"
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/report"
+	"elastic-ai-jam-2025/internal/resusage"
+	"elastic-ai-jam-2025/internal/rng"
+	"elastic-ai-jam-2025/internal/servertime"
+)
+
+// Default endpoints of the jam environment.
+const (
+	DefaultTCPServer = "eah-2025-ai-jam.dev.elastic.cloud:8083"
+	DefaultBaseURL   = "http://eah-2025-ai-jam.dev.elastic.cloud:8082"
+)
+
+// Ports -server and -base-url addresses take when given without one.
+const (
+	defaultTCPPort  = "8083"
+	defaultHTTPPort = "8082"
+)
+
+// TCPAddr normalizes a -server address: a host, IPv6 literals with or
+// without brackets, with port 8083 when it has none.
+func TCPAddr(addr string) (string, error) {
+	return netaddr.HostPort(addr, defaultTCPPort)
+}
+
+// BaseURLAddr normalizes a -base-url address: an http or https URL, or a
+// host taken as http on port 8082.
+func BaseURLAddr(addr string) (string, error) {
+	return netaddr.BaseURL(addr, defaultHTTPPort)
+}
+
+// Common holds the flags every subcommand accepts. A command fills in its own
+// defaults before calling Register, so each keeps the values its standalone
+// binary used to hard-code.
+type Common struct {
+	// TCPServer is the host:port of the TCP game server, and BaseURL the
+	// root of the HTTP API (without the /api/v0 prefix). Either may list
+	// several replicas, which the commands running many workers spread
+	// them over; the others use the first. See package endpoint.
+	TCPServer endpoint.List
+	BaseURL   endpoint.List
+
+	// PreferIPv4 and PreferIPv6 dial the addresses of that family first
+	// when a host name resolves to both; see netaddr.Prefer.
+	PreferIPv4 bool
+	PreferIPv6 bool
+
+	// ConnectTimeout bounds dialing a TCP connection.
+	ConnectTimeout time.Duration
+	// RegisterTimeout bounds waiting for the answer to a registration.
+	RegisterTimeout time.Duration
+	// ReadTimeout bounds individual in-game reads on a TCP connection, and
+	// WriteTimeout individual writes.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// RequestTimeout bounds a single HTTP request.
+	RequestTimeout time.Duration
+
+	LogLevel string
+	LogFile  string
+
+	// ConfigFile is the JSON config file the other flags default to.
+	ConfigFile string
+
+	// ReportOut is the path of the JSON end-of-run report; empty disables it.
+	ReportOut string
+
+	// RunID identifies the run in its artifacts, log lines and HTTP
+	// requests. Empty picks a random one; see ResolveRunID.
+	RunID string
+
+	// UserAgent is sent with every HTTP request; empty sends
+	// "aijam-tools/<version> run/<run-id>". IdentHeaders are "Name: value"
+	// headers sent with every HTTP request as well.
+	UserAgent    string
+	IdentHeaders headerList
+	// CaptureHeaders are the comma-separated response headers recorded in
+	// the report; see httpapi.HeaderRecorder.
+	CaptureHeaders string
+	// Team is the team identifier; with EnforceIdent, the commands that
+	// register players require their username prefix to start with it.
+	Team         string
+	EnforceIdent bool
+
+	// Seed feeds every random decision of the run. Zero picks a time-based
+	// seed; see ResolveSeed.
+	Seed int64
+
+	// MetricsAddr is where the live metrics are served; empty disables it.
+	// Only commands that call RegisterMetricsFlag have it.
+	MetricsAddr string
+
+	// BlockCooldown is how long launching pauses when our source address
+	// looks blocked or rate-limited; zero disables the detection.
+	// BlockWindow is the span of recent connections judged. Only commands
+	// that call RegisterBlockFlags have them.
+	BlockCooldown time.Duration
+	BlockWindow   time.Duration
+
+	// OutageWindow is how long every attempt must fail, whatever the error,
+	// before an outage of the server is suspected and launching paused,
+	// probing every OutageProbeInterval; zero disables the detection.
+	// ExcludeOutages measures the rates without the outages. Only commands
+	// that call RegisterOutageFlags have them.
+	OutageWindow        time.Duration
+	OutageProbeInterval time.Duration
+	ExcludeOutages      bool
+
+	// MaxConcurrentDials bounds the TCP handshakes in flight, apart from
+	// the sessions' concurrency; zero does not limit them. Only commands
+	// that call RegisterDialLimitFlag have it; see pokerclient.DialLimiter.
+	MaxConcurrentDials int
+
+	// MaxClockSkew is how far the local clock may be off the server's, as
+	// estimated from the Date header of its HTTP responses, before
+	// CheckClockSkew warns. Only commands that call RegisterClockSkewFlag
+	// have it.
+	MaxClockSkew time.Duration
+
+	// FailFast lets a panic in one worker crash the whole run instead of
+	// being recovered and reported. Only commands that call
+	// RegisterFailFastFlag have it.
+	FailFast bool
+
+	// ResourceInterval is how often the process samples its own resource
+	// usage; zero disables the sampling. WarnGoroutines and WarnFDs are
+	// the counts past which steadily growing goroutines and open file
+	// descriptors warn. Only commands that call RegisterResourceFlags have
+	// them; see package resusage.
+	ResourceInterval time.Duration
+	WarnGoroutines   int64
+	WarnFDs          int64
+
+	// RecordDir saves every HTTP response as a fixture in this directory,
+	// and ReplayDir serves the HTTP requests from such a directory instead
+	// of the API. Only commands that call RegisterFixtureFlags have them;
+	// see httpapi.FixtureStore.
+	RecordDir string
+	ReplayDir string
+
+	// SlowestRequests is how many of the slowest HTTP requests the run
+	// keeps for the summary and report, and TraceRequests splits their
+	// latency into phases. Only commands that call RegisterSlowestFlags
+	// have them; see httpapi.Slowest.
+	SlowestRequests int
+	TraceRequests   bool
+
+	// GamesCacheTTL is how long the games list is shared between the
+	// callers of this process. Only commands that call
+	// RegisterGamesCacheFlag have it; see httpapi.SharedGames.
+	GamesCacheTTL time.Duration
+
+	// Passwords tells the password of each account the command logs in
+	// with. Only commands that call RegisterPasswordFlags have it; see
+	// package passwords.
+	Passwords passwords.Policy
+
+	// Yes skips the confirmation of destructive runs, and AllowHosts are
+	// the comma-separated hosts they may target. Only commands that call
+	// RegisterSeatbeltFlags have them; see ConfirmDestructive.
+	Yes        bool
+	AllowHosts string
+
+	// Notices is where the run ID and the report's path are printed; nil
+	// means stdout. Commands writing data to stdout, such as track's
+	// NDJSON, point it at stderr.
+	Notices io.Writer
+
+	// skewChecked is set once CheckClockSkew printed the skew.
+	skewChecked bool
+}
+
+// DefaultCommon returns the common settings shared by all commands.
+func DefaultCommon() Common {
+	return Common{
+		TCPServer:           endpoint.MustParse(DefaultTCPServer, TCPAddr),
+		BaseURL:             endpoint.MustParse(DefaultBaseURL, BaseURLAddr),
+		ConnectTimeout:      10 * time.Second,
+		RegisterTimeout:     30 * time.Second,
+		ReadTimeout:         10 * time.Second,
+		WriteTimeout:        10 * time.Second,
+		RequestTimeout:      30 * time.Second,
+		LogLevel:            "info",
+		BlockCooldown:       time.Minute,
+		BlockWindow:         10 * time.Second,
+		OutageWindow:        30 * time.Second,
+		OutageProbeInterval: 10 * time.Second,
+		AllowHosts:          DefaultAllowHosts,
+		CaptureHeaders:      "X-RateLimit-Remaining,X-Request-Id",
+		MaxClockSkew:        5 * time.Second,
+		ResourceInterval:    5 * time.Second,
+		WarnGoroutines:      100000,
+		WarnFDs:             10000,
+		SlowestRequests:     10,
+		GamesCacheTTL:       time.Second,
+		Passwords:           passwords.Default("password"),
+	}
+}
+
+// flagHook adds flags that only exist in special builds, such as the
+// fault-injection flags, and acts on them once they are parsed.
+type flagHook struct {
+	register func(fs *flag.FlagSet)
+	parsed   func(fs *flag.FlagSet)
+}
+
+var flagHooks []flagHook
+
+// AddFlagHook makes Register also call register, and Parse call parsed once
+// the flags, config file and environment were applied. It must be called
+// from an init function, before any flag set is built.
+func AddFlagHook(register, parsed func(fs *flag.FlagSet)) {
+	flagHooks = append(flagHooks, flagHook{register: register, parsed: parsed})
+}
+
+// Register adds the common flags to fs, using the current values of c as
+// defaults.
+func (c *Common) Register(fs *flag.FlagSet) {
+	fs.Var(&c.TCPServer, "server", "TCP game server address (host:port, [ipv6]:port, or a host or IPv6 literal alone for port "+defaultTCPPort+"); a comma-separated list spreads the players over replicas, weighted with addr=n")
+	fs.Var(&c.BaseURL, "base-url", "HTTP API base URL (a host or IPv6 literal alone is http on port "+defaultHTTPPort+"); a comma-separated list spreads attack's workers over replicas, weighted with url=n (other commands use the first)")
+	fs.BoolVar(&c.PreferIPv4, "prefer-ipv4", c.PreferIPv4, "dial the IPv4 addresses of a host name first when it also has IPv6 ones")
+	fs.BoolVar(&c.PreferIPv6, "prefer-ipv6", c.PreferIPv6, "dial the IPv6 addresses of a host name first when it also has IPv4 ones")
+	fs.DurationVar(&c.ConnectTimeout, "connect-timeout", c.ConnectTimeout, "timeout for dialing a TCP connection")
+	fs.DurationVar(&c.RegisterTimeout, "register-timeout", c.RegisterTimeout, "timeout for the server's answer to a registration")
+	fs.DurationVar(&c.ReadTimeout, "read-timeout", c.ReadTimeout, "timeout for individual in-game TCP reads")
+	fs.DurationVar(&c.WriteTimeout, "write-timeout", c.WriteTimeout, "timeout for individual TCP writes")
+	fs.Func("io-timeout", "deprecated: sets both -read-timeout and -write-timeout", func(s string) error {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		c.ReadTimeout, c.WriteTimeout = d, d
+		return nil
+	})
+	fs.DurationVar(&c.RequestTimeout, "request-timeout", c.RequestTimeout, "timeout for a single HTTP request")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "log level: debug, info, warn or error")
+	fs.StringVar(&c.LogFile, "log-file", c.LogFile, "write logs to this file instead of stderr")
+	fs.StringVar(&c.ReportOut, "report-out", c.ReportOut, "write a JSON end-of-run report to this path")
+	fs.StringVar(&c.RunID, "run-id", c.RunID, "identify the run in its artifacts, logs and HTTP User-Agent, e.g. to share one across machines (default: random, printed at startup)")
+	fs.StringVar(&c.UserAgent, "user-agent", c.UserAgent, "User-Agent of every HTTP request (default \"aijam-tools/<version> run/<run-id>\")")
+	fs.Var(&c.IdentHeaders, "ident-header", "add this \"Name: value\" header, e.g. \"X-Team: ourteam\", to every HTTP request (repeatable)")
+	fs.StringVar(&c.CaptureHeaders, "capture-headers", c.CaptureHeaders, "comma-separated HTTP response headers summarized in the report: numeric ones by their min and when they hit zero, others sampled per status class (empty disables)")
+	fs.StringVar(&c.Team, "team", c.Team, "team identifier, checked against the username prefix by -enforce-ident")
+	fs.BoolVar(&c.EnforceIdent, "enforce-ident", c.EnforceIdent, "refuse to register players whose username prefix does not start with -team")
+	fs.Int64Var(&c.Seed, "seed", c.Seed, "seed for all randomized behaviour (default: time-based, printed at startup)")
+	fs.StringVar(&c.ConfigFile, "config", c.ConfigFile, "JSON config file; values are overridden by "+EnvPrefix+"* variables and flags")
+	for _, hook := range flagHooks {
+		hook.register(fs)
+	}
+}
+
+// ApplyTimeouts sets the registration, read and write timeouts of conn.
+func (c *Common) ApplyTimeouts(conn *pokerclient.Conn) {
+	conn.RegisterTimeout = c.RegisterTimeout
+	conn.ReadTimeout = c.ReadTimeout
+	conn.WriteTimeout = c.WriteTimeout
+}
+
+// RegisterMetricsFlag adds -metrics-addr to fs, for the commands that
+// generate load and can be watched while they run.
+func (c *Common) RegisterMetricsFlag(fs *flag.FlagSet) {
+	fs.StringVar(&c.MetricsAddr, "metrics-addr", c.MetricsAddr, "serve live metrics on this address, host:port, [ipv6]:port or :port (/metrics for Prometheus, /stats as JSON)")
+}
+
+// RegisterBlockFlags adds -block-cooldown and -block-window to fs, for the
+// commands that open connections in bulk.
+func (c *Common) RegisterBlockFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&c.BlockCooldown, "block-cooldown", c.BlockCooldown, "when connections suddenly all time out or get reset, as when the server blocks or rate-limits us, pause launching this long before probing with a single connection (0 disables the detection)")
+	fs.DurationVar(&c.BlockWindow, "block-window", c.BlockWindow, "span of recent connections judged by the block detection")
+}
+
+// RegisterOutageFlags adds -outage-window, -outage-probe-interval and
+// -exclude-outages to fs, for the commands that load the server long enough
+// to see it restart.
+func (c *Common) RegisterOutageFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&c.OutageWindow, "outage-window", c.OutageWindow, "when every attempt fails, whatever the error, for this long, suspect an outage of the server and pause launching until a single probe gets through (0 disables the detection)")
+	fs.DurationVar(&c.OutageProbeInterval, "outage-probe-interval", c.OutageProbeInterval, "delay between the probes of a suspected outage")
+	fs.BoolVar(&c.ExcludeOutages, "exclude-outages", c.ExcludeOutages, "measure the rates without the time of the suspected outages")
+}
+
+// RegisterDialLimitFlag adds -max-concurrent-dials to fs, for the commands
+// that open connections in bulk.
+func (c *Common) RegisterDialLimitFlag(fs *flag.FlagSet) {
+	fs.IntVar(&c.MaxConcurrentDials, "max-concurrent-dials", c.MaxConcurrentDials, "TCP handshakes in flight at once, however many sessions are active; the wait for a slot is reported apart and left out of the registration latency (0 does not limit)")
+}
+
+// RegisterClockSkewFlag adds -max-clock-skew to fs, for the commands that
+// compare server timestamps with the local time.
+func (c *Common) RegisterClockSkewFlag(fs *flag.FlagSet) {
+	fs.DurationVar(&c.MaxClockSkew, "max-clock-skew", c.MaxClockSkew, "warn when the local clock is off the server's, estimated from the Date header of its HTTP responses, by more than this (0 disables the warning; freshness checks are corrected either way)")
+}
+
+// CheckClockSkew prints the skew of the local clock estimated so far, with
+// a warning on stderr when it exceeds -max-clock-skew. It prints nothing
+// before any response had a Date header, and only once after.
+func (c *Common) CheckClockSkew() {
+	skew, ok := httpapi.ServerClock.Skew()
+	if !ok || c.skewChecked {
+		return
+	}
+	c.skewChecked = true
+	fmt.Printf("Clock skew: %s\n", describeSkew(skew))
+	if servertime.Exceeds(skew, c.MaxClockSkew) {
+		fmt.Fprintf(os.Stderr, "WARNING: %s, over -max-clock-skew %s; freshness checks are corrected for it, but fix the clock\n", describeSkew(skew), c.MaxClockSkew)
+	}
+}
+
+// describeSkew says which way the local clock is off.
+func describeSkew(skew time.Duration) string {
+	skew = skew.Round(time.Millisecond)
+	switch {
+	case skew > 0:
+		return fmt.Sprintf("local clock %s ahead of the server", skew)
+	case skew < 0:
+		return fmt.Sprintf("local clock %s behind the server", -skew)
+	default:
+		return "local clock in sync with the server"
+	}
+}
+
+// RegisterResourceFlags adds -resource-interval, -warn-goroutines and
+// -warn-fds to fs, for the commands heavy enough to load the client
+// machine.
+func (c *Common) RegisterResourceFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&c.ResourceInterval, "resource-interval", c.ResourceInterval, "sample the client's goroutines, heap, GC, open files and CPU time this often, for the summary, report and -timeseries-out (0 disables)")
+	fs.Int64Var(&c.WarnGoroutines, "warn-goroutines", c.WarnGoroutines, "warn when the goroutines grow steadily past this many (0 disables)")
+	fs.Int64Var(&c.WarnFDs, "warn-fds", c.WarnFDs, "warn when the open file descriptors grow steadily past this many (0 disables)")
+}
+
+// StartResources starts sampling the process per -resource-interval,
+// warning on stderr; nil when disabled.
+func (c *Common) StartResources() *resusage.Sampler {
+	return resusage.Start(resusage.Config{Interval: c.ResourceInterval, WarnGoroutines: c.WarnGoroutines, WarnFDs: c.WarnFDs}, os.Stderr)
+}
+
+// RegisterFixtureFlags adds -record-dir and -replay-dir to fs, for the
+// commands that only read the HTTP API.
+func (c *Common) RegisterFixtureFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.RecordDir, "record-dir", c.RecordDir, "save every HTTP API response to this directory, for -replay-dir")
+	fs.StringVar(&c.ReplayDir, "replay-dir", c.ReplayDir, "answer every HTTP API request from the responses -record-dir saved in this directory, failing on any not recorded, instead of the API")
+}
+
+// SetupFixtures sets httpapi.Fixtures from -record-dir or -replay-dir.
+func (c *Common) SetupFixtures() error {
+	var err error
+	switch {
+	case c.RecordDir != "" && c.ReplayDir != "":
+		return fmt.Errorf("-record-dir and -replay-dir are exclusive")
+	case c.RecordDir != "":
+		httpapi.Fixtures, err = httpapi.NewFixtureStore(c.RecordDir, httpapi.FixtureRecord)
+	case c.ReplayDir != "":
+		httpapi.Fixtures, err = httpapi.NewFixtureStore(c.ReplayDir, httpapi.FixtureReplay)
+	}
+	return err
+}
+
+// RegisterSlowestFlags adds -slowest-requests and -trace-requests to fs,
+// for the commands making many HTTP requests.
+func (c *Common) RegisterSlowestFlags(fs *flag.FlagSet) {
+	fs.IntVar(&c.SlowestRequests, "slowest-requests", c.SlowestRequests, "list this many of the slowest HTTP requests, with their URL, status and time, in the summary and report (0 disables)")
+	fs.BoolVar(&c.TraceRequests, "trace-requests", c.TraceRequests, "trace every HTTP request to split the latency of the slowest into DNS, connect, TLS, send, wait and body phases")
+}
+
+// SetupSlowest sets httpapi.Slowest from -slowest-requests.
+func (c *Common) SetupSlowest() {
+	httpapi.Slowest = latency.NewSlowest(c.SlowestRequests)
+	httpapi.TracePhases = c.TraceRequests
+}
+
+// RegisterGamesCacheFlag adds -games-cache-ttl to fs, for the commands
+// polling the games list.
+func (c *Common) RegisterGamesCacheFlag(fs *flag.FlagSet) {
+	fs.DurationVar(&c.GamesCacheTTL, "games-cache-ttl", c.GamesCacheTTL, "share a games list fetched by any caller of this process for this long, and make concurrent callers wait for a single request (0 disables)")
+}
+
+// SetupGamesCache sets httpapi.SharedGames from -games-cache-ttl. A cache
+// already set keeps its lists, so runs sharing the process share it.
+func (c *Common) SetupGamesCache() {
+	switch {
+	case c.GamesCacheTTL <= 0:
+		httpapi.SharedGames = nil
+	case httpapi.SharedGames == nil:
+		httpapi.SharedGames = httpapi.NewGamesCache(c.GamesCacheTTL)
+	default:
+		httpapi.SharedGames.SetTTL(c.GamesCacheTTL)
+	}
+}
+
+// RegisterPasswordFlags adds -password-prefix, described by prefixUsage,
+// and the flags deriving the passwords from a secret to fs, for the
+// commands logging in with generated accounts.
+func (c *Common) RegisterPasswordFlags(fs *flag.FlagSet, prefixUsage string) {
+	fs.StringVar(&c.Passwords.Prefix, "password-prefix", c.Passwords.Prefix, prefixUsage)
+	fs.StringVar(&c.Passwords.Secret, "password-secret", c.Passwords.Secret, "derive each password from this secret and the username (HMAC-SHA256) instead of -password-prefix, so they are unguessable yet reproducible, and credentials files may list usernames alone; best set through "+EnvName("password-secret")+")")
+	fs.IntVar(&c.Passwords.Length, "password-length", c.Passwords.Length, "length of the passwords derived from -password-secret")
+	fs.Var(&c.Passwords.Classes, "password-classes", "comma-separated character classes the passwords derived from -password-secret contain at least one of each: "+(*passwords.Classes)(&passwords.AllClasses).String())
+}
+
+// RegisterFailFastFlag adds -fail-fast to fs, for the commands that run
+// many workers.
+func (c *Common) RegisterFailFastFlag(fs *flag.FlagSet) {
+	fs.BoolVar(&c.FailFast, "fail-fast", c.FailFast, "crash on a panic in any worker, for development, instead of recovering it and failing only that worker")
+}
+
+// BlockGuard returns a guard over totals that probes TCPServer, or nil when
+// both -block-cooldown and -outage-window are zero.
+func (c *Common) BlockGuard(totals func() blockdetect.Totals) *blockdetect.Guard {
+	probe := func() error {
+		conn, err := pokerclient.DialFunc(c.TCPServer.First(), c.ConnectTimeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	return c.guard(totals, probe, c.BlockCooldown > 0)
+}
+
+// OutageGuard returns a guard over totals that only suspects outages,
+// probing with probe, or nil when -outage-window is zero. It serves the
+// commands without block detection, such as those of the HTTP API.
+func (c *Common) OutageGuard(totals func() blockdetect.Totals, probe func() error) *blockdetect.Guard {
+	return c.guard(totals, probe, false)
+}
+
+// guard returns a guard suspecting outages per -outage-window, and blocks
+// per -block-window when blocks is set; nil when it would detect nothing.
+func (c *Common) guard(totals func() blockdetect.Totals, probe func() error, blocks bool) *blockdetect.Guard {
+	outage := int(c.OutageWindow / time.Second)
+	if !blocks && outage <= 0 {
+		return nil
+	}
+	cfg := blockdetect.DefaultConfig()
+	cfg.OutageWindow = outage
+	if !blocks {
+		cfg.Window = 0
+	} else if w := int(c.BlockWindow / time.Second); w > 0 {
+		cfg.Window = w
+	}
+	g := blockdetect.NewGuard(cfg, c.BlockCooldown, probe, totals, os.Stdout)
+	g.OutageProbeInterval = c.OutageProbeInterval
+	return g
+}
+
+// RateSpan returns elapsed, less the suspected outages of g when
+// -exclude-outages is set: the span to measure the rates of a run over.
+func (c *Common) RateSpan(elapsed time.Duration, g *blockdetect.Guard) time.Duration {
+	if !c.ExcludeOutages {
+		return elapsed
+	}
+	return max(elapsed-g.OutageTime(), 0)
+}
+
+// ResolveSeed picks a time-based seed unless -seed was given, and prints the
+// seed so the run can be reproduced.
+func (c *Common) ResolveSeed() int64 {
+	if c.Seed == 0 {
+		c.Seed = rng.NewSeed()
+	}
+	fmt.Printf("Seed: %d (re-run with -seed=%d to reproduce)\n", c.Seed, c.Seed)
+	return c.Seed
+}
+
+// ResolveRunID picks a random run ID unless -run-id was given, prints it,
+// and sets the User-Agent and identification headers of the HTTP clients.
+func (c *Common) ResolveRunID() string {
+	if c.RunID == "" {
+		c.RunID = newRunID()
+	}
+	c.identify()
+	fmt.Fprintf(c.notices(), "Run ID: %s\n", c.RunID)
+	return c.RunID
+}
+
+// notices returns Notices, or stdout when it is not set.
+func (c *Common) notices() io.Writer {
+	if c.Notices == nil {
+		return os.Stdout
+	}
+	return c.Notices
+}
+
+func newRunID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WriteReport stamps r with the run ID and writes it to -report-out, if set.
+func (c *Common) WriteReport(r *report.Report) {
+	r.RunID = c.RunID
+	httpapi.Capture.Fill(r)
+	httpapi.Fixtures.Fill(r)
+	httpapi.SharedGames.Fill(r)
+	r.SlowestRequests = httpapi.Slowest.List()
+	pokerclient.DialLimit.Fill(r)
+	pokerclient.Decoding.Fill(r)
+	if skew, ok := httpapi.ServerClock.Skew(); ok {
+		r.Details["clock_skew"] = skew.Round(time.Millisecond).String()
+	}
+	if c.ReportOut == "" {
+		return
+	}
+	if err := r.WriteFile(c.ReportOut); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		return
+	}
+	fmt.Fprintf(c.notices(), "Report written to %s\n", c.ReportOut)
+}
@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvPrefix prefixes the environment variable of every flag: -read-timeout
+// is read from AIJAM_READ_TIMEOUT.
+const EnvPrefix = "AIJAM_"
+
+// secretFlags are redacted when the effective configuration is printed.
+var secretFlags = map[string]bool{
+	"password-prefix": true,
+	"password":        true,
+	"password-secret": true,
+}
+
+// A config file is a JSON object whose keys are flag names. Top-level scalar
+// keys apply to every command (typically the common flags: server, base-url,
+// timeouts), and an object keyed by a command name holds the settings of that
+// command only, overriding the top level:
+//
+//	{
+//	  "server": "localhost:8083",
+//	  "read-timeout": "5s",
+//	  "play": {"players": 10, "concurrency": 5, "username-prefix": "dev-"}
+//	}
+//
+// Values are applied with the precedence config file < environment < flags.
+
+// EnvName returns the environment variable consulted for flag name.
+func EnvName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// LoadConfig reads a config file and returns the flag values that apply to
+// section, keyed by flag name. Warnings name every key that does not match a
+// flag in fs.
+func LoadConfig(path, section string, fs *flag.FlagSet) (values map[string]string, warnings []string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading config file: %w", err)
+	}
+	return ParseConfig(bytes.NewReader(data), section, fs)
+}
+
+// ParseConfig is LoadConfig on an already opened config document.
+func ParseConfig(r io.Reader, section string, fs *flag.FlagSet) (values map[string]string, warnings []string, err error) {
+	var doc map[string]json.RawMessage
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("parsing config file: %w", err)
+	}
+
+	values = make(map[string]string)
+	var sectionDoc map[string]json.RawMessage
+	for _, key := range sortedKeys(doc) {
+		raw := doc[key]
+		if isObject(raw) {
+			// Sections of other commands are none of our business.
+			if key == section {
+				if err := json.Unmarshal(raw, &sectionDoc); err != nil {
+					return nil, nil, fmt.Errorf("config section %q: %w", key, err)
+				}
+			}
+			continue
+		}
+		if fs.Lookup(key) == nil {
+			// A top-level key may belong to another command.
+			if !knownFlag(key) {
+				warnings = append(warnings, fmt.Sprintf("unknown config key %q", key))
+			}
+			continue
+		}
+		v, err := scalarString(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("config key %q: %w", key, err)
+		}
+		values[key] = v
+	}
+	for _, key := range sortedKeys(sectionDoc) {
+		if fs.Lookup(key) == nil {
+			warnings = append(warnings, fmt.Sprintf("unknown config key %q in section %q", key, section))
+			continue
+		}
+		v, err := scalarString(sectionDoc[key])
+		if err != nil {
+			return nil, nil, fmt.Errorf("config key %s.%s: %w", section, key, err)
+		}
+		values[key] = v
+	}
+	return values, warnings, nil
+}
+
+// Resolve fills in every flag of fs that was not set on the command line,
+// from the environment first and then from config. It returns the source of
+// each flag's final value: "flag", "env", "config" or "default".
+func Resolve(fs *flag.FlagSet, config map[string]string, getenv func(string) string) (map[string]string, error) {
+	sources := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) { sources[f.Name] = "flag" })
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || sources[f.Name] != "" {
+			return
+		}
+		if v := getenv(EnvName(f.Name)); v != "" {
+			if setErr := fs.Set(f.Name, v); setErr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %w", v, EnvName(f.Name), setErr)
+				return
+			}
+			sources[f.Name] = "env"
+			return
+		}
+		if v, ok := config[f.Name]; ok {
+			if setErr := fs.Set(f.Name, v); setErr != nil {
+				err = fmt.Errorf("invalid config value %q for %s: %w", v, f.Name, setErr)
+				return
+			}
+			sources[f.Name] = "config"
+			return
+		}
+		sources[f.Name] = "default"
+	})
+	return sources, err
+}
+
+// Effective returns the current value of every flag in fs, with secrets
+// redacted.
+func Effective(fs *flag.FlagSet) map[string]string {
+	out := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		v := f.Value.String()
+		if secretFlags[f.Name] && v != "" {
+			v = "<redacted>"
+		}
+		out[f.Name] = v
+	})
+	return out
+}
+
+// configPath returns the config file named by -config or its environment
+// variable, honouring the same precedence as every other flag.
+func configPath(fs *flag.FlagSet, getenv func(string) string) string {
+	f := fs.Lookup("config")
+	if f == nil {
+		return ""
+	}
+	explicit := false
+	fs.Visit(func(v *flag.Flag) {
+		if v.Name == "config" {
+			explicit = true
+		}
+	})
+	if !explicit {
+		if v := getenv(EnvName("config")); v != "" {
+			return v
+		}
+	}
+	return f.Value.String()
+}
+
+// applyConfig loads the config file named by fs's -config flag, if any, and
+// resolves every flag not set on the command line.
+func applyConfig(fs *flag.FlagSet) (map[string]string, error) {
+	var values map[string]string
+	if path := configPath(fs, os.Getenv); path != "" {
+		var warnings []string
+		var err error
+		values, warnings, err = LoadConfig(path, fs.Name(), fs)
+		if err != nil {
+			return nil, err
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", path, w)
+		}
+	}
+	return Resolve(fs, values, os.Getenv)
+}
+
+// knownFlag reports whether name is a flag of any registered command.
+func knownFlag(name string) bool {
+	for _, cmd := range registry {
+		if cmd.Flags != nil && cmd.Flags().Lookup(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isObject(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+func scalarString(raw json.RawMessage) (string, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, p := range v {
+			parts[i] = fmt.Sprint(p)
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %s", string(raw))
+	}
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// configFlags returns a flag set named play with a few flags of each kind.
+func configFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("play", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.String("server", "localhost:5000", "")
+	fs.Duration("read-timeout", time.Minute, "")
+	fs.Int("players", 1, "")
+	fs.Bool("verbose", false, "")
+	fs.String("strategy", "allin", "")
+	fs.String("password", "", "")
+	fs.String("config", "", "")
+	return fs
+}
+
+func TestParseConfig(t *testing.T) {
+	doc := `{
+		"server": "top:5000",
+		"read-timeout": "5s",
+		"players": 3,
+		"no-such-flag": true,
+		"play": {"players": 10, "verbose": true, "strategy": ["allin", "minbet"], "typo": 1},
+		"flood": {"players": 99}
+	}`
+	values, warnings, err := ParseConfig(strings.NewReader(doc), "play", configFlags())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"server":       "top:5000",
+		"read-timeout": "5s",
+		"players":      "10", // the section overrides the top level
+		"verbose":      "true",
+		"strategy":     "allin,minbet",
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %v, want %v", values, want)
+	}
+	wantWarnings := []string{`unknown config key "no-such-flag"`, `unknown config key "typo" in section "play"`}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("warnings = %q, want %q", warnings, wantWarnings)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for _, doc := range []string{
+		`not json`,
+		`{"server": {"nested": 1}, "play": {"server": {"nested": 1}}}`,
+		`{"players": null}`,
+	} {
+		if _, _, err := ParseConfig(strings.NewReader(doc), "play", configFlags()); err == nil {
+			t.Errorf("ParseConfig(%s) succeeded", doc)
+		}
+	}
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	fs := configFlags()
+	if err := fs.Parse([]string{"-players", "7"}); err != nil {
+		t.Fatal(err)
+	}
+	config := map[string]string{"players": "3", "server": "config:5000", "read-timeout": "5s", "verbose": "true"}
+	env := map[string]string{"AIJAM_PLAYERS": "5", "AIJAM_READ_TIMEOUT": "9s"}
+	sources, err := Resolve(fs, config, func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		flag, value, source string
+	}{
+		{"players", "7", "flag"},      // flags beat the environment
+		{"read-timeout", "9s", "env"}, // the environment beats the config
+		{"server", "config:5000", "config"},
+		{"verbose", "true", "config"},
+		{"strategy", "allin", "default"},
+	}
+	for _, tt := range tests {
+		if v := fs.Lookup(tt.flag).Value.String(); v != tt.value || sources[tt.flag] != tt.source {
+			t.Errorf("-%s = %s from %s, want %s from %s", tt.flag, v, sources[tt.flag], tt.value, tt.source)
+		}
+	}
+}
+
+func TestResolveInvalidValues(t *testing.T) {
+	if _, err := Resolve(configFlags(), nil, func(k string) string {
+		if k == "AIJAM_PLAYERS" {
+			return "many"
+		}
+		return ""
+	}); err == nil || !strings.Contains(err.Error(), "AIJAM_PLAYERS") {
+		t.Errorf("an invalid environment value gave %v", err)
+	}
+	if _, err := Resolve(configFlags(), map[string]string{"read-timeout": "soon"}, func(string) string { return "" }); err == nil {
+		t.Error("an invalid config value was accepted")
+	}
+}
+
+func TestConfigPath(t *testing.T) {
+	env := map[string]string{"AIJAM_CONFIG": "env.json"}
+	getenv := func(k string) string { return env[k] }
+	fs := configFlags()
+	if p := configPath(fs, getenv); p != "env.json" {
+		t.Errorf("configPath from the environment = %q", p)
+	}
+	fs.Parse([]string{"-config", "flag.json"})
+	if p := configPath(fs, getenv); p != "flag.json" {
+		t.Errorf("configPath with -config = %q, want the flag to win", p)
+	}
+}
+
+func TestLoadConfigAndParse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aijam.json")
+	if err := os.WriteFile(path, []byte(`{"server": "file:5000", "play": {"players": 4}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AIJAM_SERVER", "")
+	t.Setenv("AIJAM_PLAYERS", "")
+	fs := configFlags()
+	if code, stop := Parse(fs, []string{"-config", path, "-verbose"}); stop {
+		t.Fatalf("Parse stopped with %d", code)
+	}
+	for name, want := range map[string]string{"server": "file:5000", "players": "4", "verbose": "true"} {
+		if v := fs.Lookup(name).Value.String(); v != want {
+			t.Errorf("-%s = %s, want %s", name, v, want)
+		}
+	}
+	if _, _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"), "play", configFlags()); err == nil {
+		t.Error("LoadConfig of a missing file succeeded")
+	}
+}
+
+func TestEffectiveRedactsSecrets(t *testing.T) {
+	fs := configFlags()
+	fs.Parse([]string{"-password", "hunter2", "-players", "2"})
+	eff := Effective(fs)
+	if eff["password"] != "<redacted>" || eff["players"] != "2" {
+		t.Errorf("Effective = %v", eff)
+	}
+	if EnvName("read-timeout") != "AIJAM_READ_TIMEOUT" {
+		t.Errorf("EnvName(read-timeout) = %s", EnvName("read-timeout"))
+	}
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ConfigCommand returns the "config" command. "config print <command>
+// [flags]" resolves the configuration of a command exactly as running it
+// would, and prints the effective values with secrets redacted.
+func ConfigCommand() Command {
+	return Command{
+		Name:    "config",
+		Summary: "print the effective configuration of a command (config print <command> [flags])",
+		Run:     runConfig,
+	}
+}
+
+func runConfig(args []string) int {
+	if len(args) < 2 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "Usage: config print <command> [flags]")
+		return 2
+	}
+	name := args[1]
+	var cmd *Command
+	for i := range registry {
+		if registry[i].Name == name || contains(registry[i].Aliases, name) {
+			cmd = &registry[i]
+			break
+		}
+	}
+	if cmd == nil || cmd.Flags == nil {
+		fmt.Fprintf(os.Stderr, "config print: no configurable command %q\n", name)
+		return 2
+	}
+
+	fs := cmd.Flags()
+	if err := fs.Parse(args[2:]); err != nil {
+		return 2
+	}
+	sources, err := applyConfig(fs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	out := struct {
+		Command string            `json:"command"`
+		Config  map[string]string `json:"config"`
+		Sources map[string]string `json:"sources"`
+	}{cmd.Name, Effective(fs), sources}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(out); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
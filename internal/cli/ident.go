@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"runtime/debug"
+	"strings"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+)
+
+// The organizers ask teams to identify their traffic. HTTP requests carry
+// the User-Agent and the -ident-header headers; the TCP protocol has no
+// headers, so there the username prefix identifies the team, which
+// -enforce-ident checks against -team.
+
+// Version returns the module version of the binary, or its VCS revision
+// when built from a checkout, or "dev".
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && len(s.Value) >= 7 {
+			return s.Value[:7]
+		}
+	}
+	return "dev"
+}
+
+// headerList is a repeatable flag of "Name: value" headers.
+type headerList []string
+
+func (h *headerList) String() string {
+	if h == nil {
+		return ""
+	}
+	return strings.Join(*h, "; ")
+}
+
+func (h *headerList) Set(s string) error {
+	if _, _, err := parseHeader(s); err != nil {
+		return err
+	}
+	*h = append(*h, s)
+	return nil
+}
+
+func parseHeader(s string) (name, value string, err error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("header %q is not of the form \"Name: value\"", s)
+	}
+	return textproto.CanonicalMIMEHeaderKey(name), strings.TrimSpace(value), nil
+}
+
+// identify sets the User-Agent and identification headers of every HTTP
+// request, and the response headers captured. It needs the run ID.
+func (c *Common) identify() {
+	httpapi.UserAgent = c.UserAgent
+	if httpapi.UserAgent == "" {
+		httpapi.UserAgent = fmt.Sprintf("aijam-tools/%s run/%s", Version(), c.RunID)
+	}
+	httpapi.Headers = make(http.Header)
+	for _, s := range c.IdentHeaders {
+		name, value, _ := parseHeader(s) // checked by Set
+		httpapi.Headers.Add(name, value)
+	}
+	httpapi.Capture = httpapi.NewHeaderRecorder(strings.Split(c.CaptureHeaders, ","))
+}
+
+// CheckIdent fails, when -enforce-ident is set, if usernamePrefix does not
+// start with the -team identifier, so TCP traffic is identifiable too.
+func (c *Common) CheckIdent(usernamePrefix string) error {
+	if !c.EnforceIdent {
+		return nil
+	}
+	if c.Team == "" {
+		return fmt.Errorf("-enforce-ident needs -team")
+	}
+	if !strings.HasPrefix(usernamePrefix, c.Team) {
+		return fmt.Errorf("username prefix %q does not start with the team identifier %q (-team); -enforce-ident requires it", usernamePrefix, c.Team)
+	}
+	return nil
+}
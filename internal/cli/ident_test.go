@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+)
+
+func TestHeaderList(t *testing.T) {
+	tests := []struct {
+		in        string
+		name, val string
+		wantErr   bool
+	}{
+		{"X-Team: ourteam", "X-Team", "ourteam", false},
+		{"x-team:ourteam", "X-Team", "ourteam", false},
+		{"X-Contact: ops@example.com: 24/7", "X-Contact", "ops@example.com: 24/7", false},
+		{"X-Empty:", "X-Empty", "", false},
+		{"X-Team ourteam", "", "", true},
+		{": ourteam", "", "", true},
+		{"X Team: ourteam", "", "", true},
+	}
+	for _, tt := range tests {
+		var h headerList
+		err := h.Set(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Set(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		name, val, _ := parseHeader(h[0])
+		if name != tt.name || val != tt.val {
+			t.Errorf("Set(%q) gives %q: %q, want %q: %q", tt.in, name, val, tt.name, tt.val)
+		}
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	defer func(ua string, h http.Header, c *httpapi.HeaderRecorder) {
+		httpapi.UserAgent, httpapi.Headers, httpapi.Capture = ua, h, c
+	}(httpapi.UserAgent, httpapi.Headers, httpapi.Capture)
+
+	c := DefaultCommon()
+	c.RunID = "r1"
+	c.IdentHeaders = headerList{"X-Team: ourteam", "x-team: also", "X-Contact: ops"}
+	c.identify()
+	if want := "aijam-tools/" + Version() + " run/r1"; httpapi.UserAgent != want {
+		t.Errorf("default User-Agent = %q, want %q", httpapi.UserAgent, want)
+	}
+	want := http.Header{"X-Team": {"ourteam", "also"}, "X-Contact": {"ops"}}
+	if !reflect.DeepEqual(httpapi.Headers, want) {
+		t.Errorf("headers = %v, want %v", httpapi.Headers, want)
+	}
+
+	c.UserAgent = "custom/1"
+	c.identify()
+	if httpapi.UserAgent != "custom/1" {
+		t.Errorf("-user-agent gives User-Agent %q, want custom/1", httpapi.UserAgent)
+	}
+}
+
+func TestCheckIdent(t *testing.T) {
+	tests := []struct {
+		enforce bool
+		team    string
+		prefix  string
+		wantErr string
+	}{
+		{false, "", "anyone-", ""},
+		{true, "ourteam", "ourteam-bot-", ""},
+		{true, "ourteam", "bot-", "does not start with the team identifier"},
+		{true, "", "ourteam-", "needs -team"},
+	}
+	for _, tt := range tests {
+		c := Common{EnforceIdent: tt.enforce, Team: tt.team}
+		err := c.CheckIdent(tt.prefix)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("CheckIdent(%q) with -team %q = %v, want %q", tt.prefix, tt.team, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"elastic-ai-jam-2025/internal/netaddr"
+)
+
+// SetupLogging resolves the run ID and installs the default slog logger
+// according to the common flags, with the run ID on every line. It also sets
+// the dial preference of -prefer-ipv4 and -prefer-ipv6, which every command
+// needs before dialing. The returned function closes the log file, if one
+// was opened.
+func (c *Common) SetupLogging() (func(), error) {
+	switch {
+	case c.PreferIPv4 && c.PreferIPv6:
+		return nil, errors.New("-prefer-ipv4 cannot be combined with -prefer-ipv6")
+	case c.PreferIPv4:
+		netaddr.Prefer = netaddr.IPv4
+	case c.PreferIPv6:
+		netaddr.Prefer = netaddr.IPv6
+	default:
+		netaddr.Prefer = ""
+	}
+
+	var level slog.Level
+	switch strings.ToLower(c.LogLevel) {
+	case "debug":
+		level = slog.LevelDebug
+	case "info", "":
+		level = slog.LevelInfo
+	case "warn", "warning":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	default:
+		return nil, fmt.Errorf("unknown log level %q", c.LogLevel)
+	}
+
+	var out io.Writer = os.Stderr
+	closeFn := func() {}
+	if c.LogFile != "" {
+		f, err := os.OpenFile(c.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening log file: %w", err)
+		}
+		out = f
+		closeFn = func() { f.Close() }
+	}
+
+	c.ResolveRunID()
+	slog.SetDefault(slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: level})).With("run_id", c.RunID))
+	return closeFn, nil
+}
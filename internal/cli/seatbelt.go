@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"elastic-ai-jam-2025/internal/endpoint"
+	"elastic-ai-jam-2025/internal/netaddr"
+)
+
+// The commands that can take a server down, attack and flood beyond a few
+// players, are a flag away from any other command. Before loading a server
+// they check that its host is on -allow-hosts, and ask for confirmation
+// unless -yes was given.
+
+// DefaultAllowHosts are the hosts destructive commands may target by
+// default: the jam environment and this machine.
+const DefaultAllowHosts = "eah-2025-ai-jam.dev.elastic.cloud,localhost,127.0.0.1,::1"
+
+// RegisterSeatbeltFlags adds -yes and -allow-hosts to fs, for the commands
+// that can take a server down.
+func (c *Common) RegisterSeatbeltFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.Yes, "yes", c.Yes, "run without asking for confirmation, e.g. in CI")
+	fs.StringVar(&c.AllowHosts, "allow-hosts", c.AllowHosts, "comma-separated hosts this command may load; it refuses any other (\"*\" allows all)")
+}
+
+// ConfirmDestructive returns an error unless the hosts of targets, addresses
+// or URLs, are on -allow-hosts and either -yes is set or the user confirms,
+// on the terminal, the load described by volume, e.g. "5000 attackers for
+// 30s, rate: unlimited". Without -yes and without a terminal to ask on, it
+// refuses.
+func (c *Common) ConfirmDestructive(command string, targets endpoint.List, volume string) error {
+	var hosts []string
+	for _, e := range targets.Endpoints() {
+		host := HostOf(e.Addr)
+		if !HostAllowed(c.AllowHosts, host) {
+			return fmt.Errorf("%s refuses to target %s: it is not on -allow-hosts (%s)", command, host, c.AllowHosts)
+		}
+		hosts = append(hosts, host)
+	}
+	host := strings.Join(hosts, ", ")
+	if c.Yes {
+		return nil
+	}
+	noTerminal := fmt.Errorf("%s against %s needs confirmation: pass -yes to run it without a terminal", command, host)
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return noTerminal
+	}
+	fmt.Fprintf(os.Stderr, "%s is about to load %s with %s.\nType yes to continue: ", command, host, volume)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		// Stdin is a device that is not a terminal, such as /dev/null.
+		fmt.Fprintln(os.Stderr)
+		return noTerminal
+	}
+	if strings.TrimSpace(answer) != "yes" {
+		return fmt.Errorf("%s aborted: not confirmed", command)
+	}
+	return nil
+}
+
+// HostAllowed reports whether host is on the comma-separated list allow,
+// ignoring case and the brackets of IPv6 literals; "*" allows any host.
+func HostAllowed(allow, host string) bool {
+	host = netaddr.Host(host)
+	for _, h := range strings.Split(allow, ",") {
+		h = strings.TrimSpace(h)
+		if h == "*" || netaddr.Host(h) == host {
+			return true
+		}
+	}
+	return false
+}
+
+// HostOf returns the host of a host:port address or of a URL, without the
+// port, and an IPv6 literal without its brackets.
+func HostOf(addr string) string {
+	if u, err := url.Parse(addr); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return netaddr.Host(addr)
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/endpoint"
+)
+
+func TestHostOf(t *testing.T) {
+	tests := []struct{ addr, want string }{
+		{"example.com:8083", "example.com"},
+		{"http://example.com:8082", "example.com"},
+		{"https://example.com", "example.com"},
+		{"10.0.0.1:8083", "10.0.0.1"},
+		{"[::1]:8083", "::1"},
+		{"http://[2001:db8::1]:8082/api", "2001:db8::1"},
+		{"example.com", "example.com"},
+	}
+	for _, tt := range tests {
+		if got := HostOf(tt.addr); got != tt.want {
+			t.Errorf("HostOf(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	tests := []struct {
+		allow, host string
+		want        bool
+	}{
+		{DefaultAllowHosts, "localhost", true},
+		{DefaultAllowHosts, "LOCALHOST", true},
+		{DefaultAllowHosts, "::1", true},
+		{DefaultAllowHosts, "[::1]", true},
+		{DefaultAllowHosts, "eah-2025-ai-jam.dev.elastic.cloud", true},
+		{DefaultAllowHosts, "example.com", false},
+		{DefaultAllowHosts, "127.0.0.2", false},
+		{"example.com, other.org", "other.org", true},
+		{"*", "anything.example", true},
+		{"", "localhost", false},
+	}
+	for _, tt := range tests {
+		if got := HostAllowed(tt.allow, tt.host); got != tt.want {
+			t.Errorf("HostAllowed(%q, %q) = %v, want %v", tt.allow, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestConfirmDestructiveRefusesHosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets endpoint.List
+		refused string // the host refused, or "" when allowed
+	}{
+		{"allowed address", endpoint.MustParse("localhost:8083", TCPAddr), ""},
+		{"allowed URL", endpoint.MustParse("http://127.0.0.1:8082", BaseURLAddr), ""},
+		{"other address", endpoint.MustParse("example.com:8083", TCPAddr), "example.com"},
+		{"other URL", endpoint.MustParse("http://example.com:8082", BaseURLAddr), "example.com"},
+		{"one replica not allowed", endpoint.MustParse("localhost:8083,10.1.2.3:8083", TCPAddr), "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		for _, yes := range []bool{false, true} {
+			c := Common{AllowHosts: DefaultAllowHosts, Yes: yes}
+			err := c.ConfirmDestructive("attack", tt.targets, "1 attacker")
+			if tt.refused == "" {
+				// Without -yes the answer depends on the test's stdin.
+				if yes && err != nil {
+					t.Errorf("%s with -yes: %v", tt.name, err)
+				}
+				continue
+			}
+			if err == nil || !strings.Contains(err.Error(), "refuses to target "+tt.refused) {
+				t.Errorf("%s (-yes %v): error %v, want %s refused", tt.name, yes, err, tt.refused)
+			}
+		}
+	}
+}
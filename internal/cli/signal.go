@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// InterruptContext returns a context that is cancelled on the first Ctrl+C
+// (or SIGTERM), giving the command a chance to drain and write its report.
+// The default signal behaviour is restored afterwards, so a second Ctrl+C
+// terminates the process straight away.
+func InterruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
@@ -0,0 +1,102 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+// fired reports whether c holds a value, taking it.
+func fired(c <-chan time.Time) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestFakeFiresInDeadlineOrder(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	f := NewFake(start)
+	late := f.NewTimer(3 * time.Second)
+	early := f.After(time.Second)
+	ticker := f.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	f.Advance(999 * time.Millisecond)
+	if fired(early) || fired(late.C()) || fired(ticker.C()) {
+		t.Fatal("something fired before its deadline")
+	}
+	f.Advance(time.Millisecond)
+	if at := <-early; !at.Equal(start.Add(time.Second)) {
+		t.Errorf("After fired at %s, want its deadline", at)
+	}
+	f.Advance(2 * time.Second)
+	if !fired(ticker.C()) || !fired(late.C()) {
+		t.Error("the ticker and the timer did not fire at 3s")
+	}
+	if got := f.Now(); !got.Equal(start.Add(3 * time.Second)) {
+		t.Errorf("Now = %s, want 3s after the start", got)
+	}
+	if n := f.Waiters(); n != 1 {
+		t.Errorf("%d waiters left, want only the ticker", n)
+	}
+}
+
+func TestFakeTickerDropsMissedTicks(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	ticker := f.NewTicker(time.Second)
+	f.Advance(10 * time.Second)
+	if at := <-ticker.C(); !at.Equal(time.Unix(1, 0)) {
+		t.Errorf("the tick held is from %s, want the first one", at)
+	}
+	if fired(ticker.C()) {
+		t.Error("the ticker held more than one tick")
+	}
+	ticker.Stop()
+	f.Advance(10 * time.Second)
+	if fired(ticker.C()) {
+		t.Error("a stopped ticker fired")
+	}
+}
+
+func TestFakeTimerStopAndReset(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	timer := f.NewTimer(time.Second)
+	if !timer.Stop() {
+		t.Error("Stop of a pending timer returned false")
+	}
+	if timer.Stop() {
+		t.Error("Stop of a stopped timer returned true")
+	}
+	if timer.Reset(2 * time.Second) {
+		t.Error("Reset of a stopped timer returned true")
+	}
+	f.Advance(time.Second)
+	if fired(timer.C()) {
+		t.Error("the timer fired at its old deadline")
+	}
+	f.Advance(time.Second)
+	if !fired(timer.C()) {
+		t.Error("the timer did not fire at its new deadline")
+	}
+}
+
+func TestFakeSleep(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	woke := make(chan struct{})
+	go func() {
+		f.Sleep(time.Minute)
+		close(woke)
+	}()
+	for f.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	f.Advance(time.Minute)
+	select {
+	case <-woke:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Sleep did not return once the clock passed its end")
+	}
+	f.Sleep(0) // does not block
+}
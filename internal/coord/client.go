@@ -0,0 +1,79 @@
+package coord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+)
+
+// Client talks to a controller on behalf of one worker.
+type Client struct {
+	// BaseURL is the controller's root, e.g. "http://host:9090".
+	BaseURL string
+	HTTP    *http.Client
+	// WorkerID is set by Register.
+	WorkerID string
+}
+
+// NewClient returns a client for the controller at baseURL whose requests
+// time out after timeout. A bare host:port is taken as http.
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		HTTP:    &http.Client{Timeout: timeout, Transport: httpapi.Transport(nil)},
+	}
+}
+
+// Register announces the worker and returns its assignment.
+func (c *Client) Register(ctx context.Context, req RegisterRequest) (RegisterResponse, error) {
+	var resp RegisterResponse
+	if err := c.post(ctx, PathRegister, req, &resp); err != nil {
+		return resp, fmt.Errorf("registering with the controller: %w", err)
+	}
+	c.WorkerID = resp.WorkerID
+	return resp, nil
+}
+
+// Push sends the worker's counters so far.
+func (c *Client) Push(ctx context.Context, p Push) error {
+	p.WorkerID = c.WorkerID
+	if err := c.post(ctx, PathPush, p, nil); err != nil {
+		return fmt.Errorf("pushing to the controller: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) post(ctx context.Context, path string, body, target any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s: %s", path, resp.Status, bytes.TrimSpace(msg))
+	}
+	if target == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
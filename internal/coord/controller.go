@@ -0,0 +1,155 @@
+package coord
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/latency"
+)
+
+// Controller hands out player index blocks and merges the workers' pushes.
+// It is safe for concurrent use.
+type Controller struct {
+	// RunID is sent to workers as the controller's run ID.
+	RunID string
+	// LostAfter is how long a running worker may go without pushing before
+	// it is considered lost.
+	LostAfter time.Duration
+
+	mu        sync.Mutex
+	nextIndex int
+	workers   []*worker
+	byID      map[string]*worker
+}
+
+type worker struct {
+	RegisterRequest
+	id         string
+	firstIndex int
+	lastPush   time.Time
+	last       Push
+}
+
+// NewController returns a controller whose workers are lost after lostAfter
+// without a push.
+func NewController(runID string, lostAfter time.Duration) *Controller {
+	return &Controller{RunID: runID, LostAfter: lostAfter, byID: make(map[string]*worker)}
+}
+
+// Register adds a worker and assigns it the next free block of indexes.
+func (c *Controller) Register(req RegisterRequest) (RegisterResponse, error) {
+	if req.Players <= 0 {
+		return RegisterResponse{}, fmt.Errorf("players must be positive, got %d", req.Players)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &worker{
+		RegisterRequest: req,
+		id:              fmt.Sprintf("w%d", len(c.workers)+1),
+		firstIndex:      c.nextIndex,
+		lastPush:        time.Now(),
+	}
+	c.nextIndex += req.Players
+	c.workers = append(c.workers, w)
+	c.byID[w.id] = w
+	return RegisterResponse{WorkerID: w.id, FirstIndex: w.firstIndex, RunID: c.RunID}, nil
+}
+
+// Accept records a push. It returns false for an unknown worker.
+func (c *Controller) Accept(p Push) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w, ok := c.byID[p.WorkerID]
+	if !ok {
+		return false
+	}
+	w.last, w.lastPush = p, time.Now()
+	return true
+}
+
+// Summary merges the last push of every worker.
+func (c *Controller) Summary() Summary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := Summary{
+		Workers:   make([]WorkerSummary, 0, len(c.workers)),
+		Counters:  make(map[string]int64),
+		Errors:    make(map[string]int64),
+		Latencies: make(map[string]latency.Summary),
+	}
+	hists := make(map[string]*latency.Histogram)
+	now := time.Now()
+	for _, w := range c.workers {
+		state := StateRunning
+		switch {
+		case w.last.Done:
+			state = StateDone
+		case c.LostAfter > 0 && now.Sub(w.lastPush) > c.LostAfter:
+			state = StateLost
+		}
+		s.Workers = append(s.Workers, WorkerSummary{
+			WorkerID: w.id, Name: w.Name, RunID: w.RunID, FirstIndex: w.firstIndex, Players: w.Players,
+			State: state, LastPush: w.lastPush, Counters: w.last.Counters, Errors: w.last.Errors,
+		})
+		for k, n := range w.last.Counters {
+			s.Counters[k] += n
+		}
+		for k, n := range w.last.Errors {
+			s.Errors[k] += n
+		}
+		for k, counts := range w.last.Latencies {
+			if hists[k] == nil {
+				hists[k] = &latency.Histogram{}
+			}
+			hists[k].Merge(counts)
+		}
+	}
+	for k, h := range hists {
+		s.Latencies[k] = h.Summary()
+	}
+	return s
+}
+
+// Handler serves the protocol.
+func (c *Controller) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+PathRegister, func(w http.ResponseWriter, r *http.Request) {
+		var req RegisterRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp, err := c.Register(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, resp)
+	})
+	mux.HandleFunc("POST "+PathPush, func(w http.ResponseWriter, r *http.Request) {
+		var p Push
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !c.Accept(p) {
+			http.Error(w, "unknown worker "+p.WorkerID, http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET "+PathSummary, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, c.Summary())
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,97 @@
+// Package coord coordinates a load run spread over several machines. One
+// instance runs a Controller; the others are workers that register with it
+// through a Client, get a block of player indexes of their own, so no two
+// machines register the same username, and push their counters while they
+// run. The controller merges them into one live summary and one final
+// report.
+//
+// The protocol is JSON over HTTP:
+//
+//	POST /v1/register  RegisterRequest  -> RegisterResponse
+//	POST /v1/push      Push             -> 204, or 404 for an unknown worker
+//	GET  /v1/summary                    -> Summary
+//
+// Pushes carry cumulative values, so a lost push is repaired by the next
+// one, and a worker keeps running on its own if the controller goes away.
+package coord
+
+import (
+	"time"
+
+	"elastic-ai-jam-2025/internal/latency"
+)
+
+// Endpoint paths.
+const (
+	PathRegister = "/v1/register"
+	PathPush     = "/v1/push"
+	PathSummary  = "/v1/summary"
+)
+
+// RegisterRequest announces a worker.
+type RegisterRequest struct {
+	// Name identifies the worker to people, usually its host name.
+	Name  string `json:"name"`
+	RunID string `json:"run_id,omitempty"`
+	// Players is the number of player indexes the worker needs.
+	Players int `json:"players"`
+}
+
+// RegisterResponse assigns a worker its ID and its first player index; the
+// worker uses indexes FirstIndex to FirstIndex+Players-1.
+type RegisterResponse struct {
+	WorkerID   string `json:"worker_id"`
+	FirstIndex int    `json:"first_index"`
+	// RunID is the controller's run ID.
+	RunID string `json:"run_id,omitempty"`
+}
+
+// Push reports a worker's counters so far.
+type Push struct {
+	WorkerID  string                    `json:"worker_id"`
+	Counters  map[string]int64          `json:"counters,omitempty"`
+	Errors    map[string]int64          `json:"errors,omitempty"`
+	Latencies map[string]latency.Counts `json:"latencies,omitempty"`
+	// Done is set on the last push of a worker.
+	Done bool `json:"done,omitempty"`
+}
+
+// Worker states in a Summary.
+const (
+	StateRunning = "running"
+	StateDone    = "done"
+	// StateLost is a worker that stopped pushing before it was done.
+	StateLost = "lost"
+)
+
+// WorkerSummary is one worker as the controller last heard from it.
+type WorkerSummary struct {
+	WorkerID   string           `json:"worker_id"`
+	Name       string           `json:"name"`
+	RunID      string           `json:"run_id,omitempty"`
+	FirstIndex int              `json:"first_index"`
+	Players    int              `json:"players"`
+	State      string           `json:"state"`
+	LastPush   time.Time        `json:"last_push"`
+	Counters   map[string]int64 `json:"counters,omitempty"`
+	Errors     map[string]int64 `json:"errors,omitempty"`
+}
+
+// Summary merges the counters of every worker.
+type Summary struct {
+	Workers   []WorkerSummary            `json:"workers"`
+	Counters  map[string]int64           `json:"counters"`
+	Errors    map[string]int64           `json:"errors"`
+	Latencies map[string]latency.Summary `json:"latencies"`
+}
+
+// Count returns how many workers are in state.
+func (s *Summary) Count(state string) int {
+	n := 0
+	for _, w := range s.Workers {
+		if w.State == state {
+			n++
+		}
+	}
+	return n
+}
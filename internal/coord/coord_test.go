@@ -0,0 +1,131 @@
+package coord
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/latency"
+)
+
+// startController serves a controller for the test.
+func startController(t *testing.T, lostAfter time.Duration) (*Controller, *httptest.Server) {
+	t.Helper()
+	c := NewController("ctl-run", lostAfter)
+	srv := httptest.NewServer(c.Handler())
+	t.Cleanup(srv.Close)
+	return c, srv
+}
+
+func TestRegisterAssignsDisjointBlocks(t *testing.T) {
+	_, srv := startController(t, 0)
+	ctx := context.Background()
+	tests := []struct {
+		players   int
+		wantID    string
+		wantFirst int
+	}{
+		{100, "w1", 0},
+		{50, "w2", 100},
+		{1, "w3", 150},
+	}
+	for _, tt := range tests {
+		client := NewClient(srv.URL, time.Second)
+		resp, err := client.Register(ctx, RegisterRequest{Name: "host", Players: tt.players})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.WorkerID != tt.wantID || resp.FirstIndex != tt.wantFirst || resp.RunID != "ctl-run" {
+			t.Errorf("registering %d players = %+v, want %s from index %d", tt.players, resp, tt.wantID, tt.wantFirst)
+		}
+		if client.WorkerID != tt.wantID {
+			t.Errorf("client worker ID = %q, want %q", client.WorkerID, tt.wantID)
+		}
+	}
+	for _, players := range []int{0, -1} {
+		if _, err := NewClient(srv.URL, time.Second).Register(ctx, RegisterRequest{Players: players}); err == nil {
+			t.Errorf("registering %d players succeeded", players)
+		}
+	}
+}
+
+func TestSummaryMergesPushes(t *testing.T) {
+	c, srv := startController(t, 0)
+	ctx := context.Background()
+	histogram := func(ds ...time.Duration) latency.Counts {
+		var h latency.Histogram
+		for _, d := range ds {
+			h.Record(d)
+		}
+		return h.Counts()
+	}
+	a, b := NewClient(srv.URL, time.Second), NewClient(srv.URL, time.Second)
+	for _, cl := range []*Client{a, b} {
+		if _, err := cl.Register(ctx, RegisterRequest{Name: "host", Players: 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pushes := []struct {
+		client *Client
+		push   Push
+	}{
+		{a, Push{Counters: map[string]int64{"registrations": 1}}},
+		// Pushes are cumulative: this one replaces the first.
+		{a, Push{Counters: map[string]int64{"registrations": 4}, Errors: map[string]int64{"timeout": 1},
+			Latencies: map[string]latency.Counts{"register": histogram(time.Millisecond, time.Millisecond)}, Done: true}},
+		{b, Push{Counters: map[string]int64{"registrations": 3, "reconnects": 2}, Errors: map[string]int64{"timeout": 2, "reset": 1},
+			Latencies: map[string]latency.Counts{"register": histogram(time.Millisecond)}}},
+	}
+	for _, p := range pushes {
+		if err := p.client.Push(ctx, p.push); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := c.Summary()
+	wantCounters := map[string]int64{"registrations": 7, "reconnects": 2}
+	wantErrors := map[string]int64{"timeout": 3, "reset": 1}
+	for k, n := range wantCounters {
+		if s.Counters[k] != n {
+			t.Errorf("counter %s = %d, want %d", k, s.Counters[k], n)
+		}
+	}
+	for k, n := range wantErrors {
+		if s.Errors[k] != n {
+			t.Errorf("error %s = %d, want %d", k, s.Errors[k], n)
+		}
+	}
+	if got := s.Latencies["register"].Count; got != 3 {
+		t.Errorf("merged register latencies = %d, want 3", got)
+	}
+	if s.Count(StateDone) != 1 || s.Count(StateRunning) != 1 {
+		t.Errorf("workers %+v, want one done and one running", s.Workers)
+	}
+}
+
+func TestPushFailures(t *testing.T) {
+	c, srv := startController(t, time.Millisecond)
+	ctx := context.Background()
+
+	stranger := NewClient(srv.URL, time.Second)
+	stranger.WorkerID = "w9"
+	if err := stranger.Push(ctx, Push{}); err == nil {
+		t.Error("a push from an unknown worker succeeded")
+	}
+
+	client := NewClient(srv.URL, time.Second)
+	if _, err := client.Register(ctx, RegisterRequest{Players: 1}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if s := c.Summary(); s.Count(StateLost) != 1 {
+		t.Errorf("workers %+v, want the silent one lost", s.Workers)
+	}
+
+	// The worker only sees an error once the controller is gone.
+	srv.Close()
+	if err := client.Push(ctx, Push{Done: true}); err == nil {
+		t.Error("a push to a stopped controller succeeded")
+	}
+}
@@ -0,0 +1,93 @@
+// Package credentials reads the accounts commands log in with: ranges of
+// generated usernames and passwords, and credentials files.
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"elastic-ai-jam-2025/internal/passwords"
+)
+
+// Account is the username and password of one account.
+type Account struct {
+	Username string
+	Password string
+}
+
+// Range returns the accounts flood and play create: the username prefix
+// followed by first .. first+n-1, with the passwords of policy.
+func Range(usernamePrefix string, policy passwords.Policy, first, n int) []Account {
+	accounts := make([]Account, 0, n)
+	for i := first; i < first+n; i++ {
+		username := usernamePrefix + strconv.Itoa(i)
+		accounts = append(accounts, Account{username, policy.Password(username, i)})
+	}
+	return accounts
+}
+
+// Read reads the accounts of a credentials file. Each non-empty line
+// is either "username:password" or a play -results-out record, whose
+// password is the one policy gives the player and its index. When policy
+// derives the passwords from a secret, a line may also hold a username
+// alone. Lines starting with "#" are comments, and an account listed twice
+// is kept once.
+func Read(path string, policy passwords.Policy) ([]Account, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading credentials: %w", err)
+	}
+	defer f.Close()
+	var accounts []Account
+	seen := make(map[string]bool)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 16<<20)
+	for line := 1; sc.Scan(); line++ {
+		text := bytes.TrimSpace(sc.Bytes())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+		a, err := parse(text, policy)
+		if err != nil {
+			return nil, fmt.Errorf("reading credentials: %s:%d: %w", path, line, err)
+		}
+		if !seen[a.Username] {
+			seen[a.Username] = true
+			accounts = append(accounts, a)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading credentials: %w", err)
+	}
+	return accounts, nil
+}
+
+// parse parses one line of a credentials file.
+func parse(line []byte, policy passwords.Policy) (Account, error) {
+	if line[0] == '{' {
+		var r struct {
+			Player string `json:"player"`
+			Index  *int   `json:"index"`
+		}
+		if err := json.Unmarshal(line, &r); err != nil {
+			return Account{}, err
+		}
+		if r.Player == "" || r.Index == nil {
+			return Account{}, errors.New("not a play -results-out record: no player or index")
+		}
+		return Account{r.Player, policy.Password(r.Player, *r.Index)}, nil
+	}
+	username, password, ok := bytes.Cut(line, []byte(":"))
+	if !ok && policy.Deterministic() {
+		return Account{string(line), policy.Password(string(line), 0)}, nil
+	}
+	if !ok || len(username) == 0 {
+		return Account{}, errors.New("expected username:password, or a username alone with -password-secret")
+	}
+	return Account{string(username), string(password)}, nil
+}
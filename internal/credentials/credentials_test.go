@@ -0,0 +1,82 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/passwords"
+)
+
+func TestRead(t *testing.T) {
+	prefix := passwords.Default("pw")
+	derived := passwords.Default("pw")
+	derived.Secret = "jam-night"
+	tests := []struct {
+		name    string
+		content string
+		policy  passwords.Policy
+		want    []Account
+		wantErr bool
+	}{
+		{
+			name:    "pairs",
+			content: "# team\nalice:a1\n\nbob:b:2\nalice:other\n",
+			policy:  prefix,
+			want:    []Account{{Username: "alice", Password: "a1"}, {Username: "bob", Password: "b:2"}},
+		},
+		{
+			name:    "results records with prefix passwords",
+			content: `{"player":"player3","index":3,"outcome":"ok"}` + "\n",
+			policy:  prefix,
+			want:    []Account{{Username: "player3", Password: "pw3"}},
+		},
+		{
+			name:    "results records with derived passwords",
+			content: `{"player":"player3","index":3}` + "\n",
+			policy:  derived,
+			want:    []Account{{Username: "player3", Password: derived.Password("player3", 3)}},
+		},
+		{
+			name:    "usernames alone with a secret",
+			content: "alice\nbob:given\n",
+			policy:  derived,
+			want:    []Account{{Username: "alice", Password: derived.Password("alice", 0)}, {Username: "bob", Password: "given"}},
+		},
+		{name: "usernames alone without a secret", content: "alice\n", policy: prefix, wantErr: true},
+		{name: "no username", content: ":secret\n", policy: derived, wantErr: true},
+		{name: "record without index", content: `{"player":"player3"}` + "\n", policy: prefix, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "accounts.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Read(path, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("accounts = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRange(t *testing.T) {
+	derived := passwords.Default("pw")
+	derived.Secret = "jam-night"
+	got := Range("player", derived, 4, 2)
+	want := []Account{
+		{Username: "player4", Password: derived.Password("player4", 4)},
+		{Username: "player5", Password: derived.Password("player5", 5)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Range = %+v, want %+v", got, want)
+	}
+	if got := Range("player", passwords.Default("pw"), 0, 1); got[0].Password != "pw0" {
+		t.Errorf("prefix password = %q, want pw0", got[0].Password)
+	}
+}
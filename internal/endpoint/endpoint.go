@@ -0,0 +1,169 @@
+// Package endpoint spreads a run's workers over several replicas of a
+// server. -server and -base-url take a comma-separated list of addresses,
+// each optionally weighted with "=n":
+//
+//	-server lb-a.example:8083,lb-b.example:8083=3
+//
+// sends a quarter of the workers to lb-a and the rest to lb-b. A worker keeps
+// its endpoint for the whole run, so per-endpoint latencies compare the
+// replicas rather than the mix.
+//
+// A list parsed with a normalizer, as those of -server and -base-url are,
+// holds each address in the normalizer's canonical form, so IPv6 literals
+// may be given with or without brackets and a missing port takes a default.
+package endpoint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxWeight bounds a weight, which bounds the length of a List's schedule.
+const maxWeight = 1000
+
+// Endpoint is one address of a List.
+type Endpoint struct {
+	Addr   string
+	Weight int
+}
+
+// List is a weighted list of endpoints. It implements flag.Value; the zero
+// value is empty.
+type List struct {
+	raw string
+	// normalize, when set, checks each address and returns its canonical
+	// form. Set keeps it, so a flag keeps the normalizer of its default.
+	normalize func(addr string) (string, error)
+	endpoints []Endpoint
+	// schedule is a smooth weighted round-robin over endpoints: indexes
+	// into it, one per weight unit, interleaved.
+	schedule []int
+}
+
+// Parse parses a comma-separated list of addresses with optional "=weight"
+// suffixes, keeping the addresses as given.
+func Parse(s string) (List, error) {
+	return ParseFunc(s, nil)
+}
+
+// ParseFunc is Parse with each address passed through normalize, such as
+// netaddr.HostPort with a default port, when it is not nil.
+func ParseFunc(s string, normalize func(addr string) (string, error)) (List, error) {
+	l := List{raw: s, normalize: normalize}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return List{}, fmt.Errorf("empty endpoint in %q", s)
+		}
+		e := Endpoint{Addr: item, Weight: 1}
+		if i := strings.LastIndex(item, "="); i >= 0 {
+			w, err := strconv.Atoi(item[i+1:])
+			if err != nil || w < 1 || w > maxWeight {
+				return List{}, fmt.Errorf("endpoint %q: the weight must be a number from 1 to %d", item, maxWeight)
+			}
+			e.Addr, e.Weight = strings.TrimSpace(item[:i]), w
+		}
+		if normalize != nil {
+			addr, err := normalize(e.Addr)
+			if err != nil {
+				return List{}, err
+			}
+			e.Addr = addr
+		}
+		for _, other := range l.endpoints {
+			if other.Addr == e.Addr {
+				return List{}, fmt.Errorf("endpoint %q is listed twice", e.Addr)
+			}
+		}
+		l.endpoints = append(l.endpoints, e)
+	}
+	l.schedule = schedule(l.endpoints)
+	return l, nil
+}
+
+// MustParse is ParseFunc for lists known to be valid, such as defaults.
+func MustParse(s string, normalize func(addr string) (string, error)) List {
+	l, err := ParseFunc(s, normalize)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// schedule interleaves the endpoints by weight the way smooth weighted
+// round-robin does: each turn every endpoint gains its weight, and the one
+// ahead is picked and loses the total.
+func schedule(endpoints []Endpoint) []int {
+	total := 0
+	for _, e := range endpoints {
+		total += e.Weight
+	}
+	current := make([]int, len(endpoints))
+	out := make([]int, 0, total)
+	for range total {
+		best := 0
+		for i, e := range endpoints {
+			current[i] += e.Weight
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		out = append(out, best)
+	}
+	return out
+}
+
+// String returns the list as given or, for a list with a normalizer, with
+// the addresses in canonical form.
+func (l List) String() string {
+	if l.normalize == nil {
+		return l.raw
+	}
+	items := make([]string, len(l.endpoints))
+	for i, e := range l.endpoints {
+		items[i] = e.Addr
+		if e.Weight != 1 {
+			items[i] += "=" + strconv.Itoa(e.Weight)
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+// Set implements flag.Value.
+func (l *List) Set(s string) error {
+	parsed, err := ParseFunc(s, l.normalize)
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+// Len returns the number of endpoints.
+func (l List) Len() int { return len(l.endpoints) }
+
+// Endpoints returns the endpoints in the order given.
+func (l List) Endpoints() []Endpoint { return l.endpoints }
+
+// First returns the first address, used by the commands and steps that talk
+// to a single endpoint, or "" for an empty list.
+func (l List) First() string {
+	if len(l.endpoints) == 0 {
+		return ""
+	}
+	return l.endpoints[0].Addr
+}
+
+// For returns the address of worker n, which is the same for every call
+// with n.
+func (l List) For(n int) string {
+	if len(l.endpoints) <= 1 {
+		return l.First()
+	}
+	if n < 0 {
+		n = -n
+	}
+	return l.endpoints[l.schedule[n%len(l.schedule)]].Addr
+}
@@ -0,0 +1,141 @@
+package endpoint
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/latency"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Stats breaks a run's counters, latencies and failures down by endpoint,
+// next to its usual totals. It is safe for concurrent use; a nil *Stats
+// records nothing.
+type Stats struct {
+	addrs  []string
+	byAddr map[string]*stats
+}
+
+type stats struct {
+	mu        sync.Mutex
+	counters  map[string]int64
+	latencies map[string]*latency.Histogram
+	failures  errclass.Counter
+}
+
+// NewStats returns the stats of the endpoints of l, or nil when there is
+// only one, whose stats are the run's.
+func NewStats(l List) *Stats {
+	if l.Len() < 2 {
+		return nil
+	}
+	s := &Stats{byAddr: make(map[string]*stats)}
+	for _, e := range l.Endpoints() {
+		s.addrs = append(s.addrs, e.Addr)
+		s.byAddr[e.Addr] = &stats{counters: make(map[string]int64), latencies: make(map[string]*latency.Histogram)}
+	}
+	return s
+}
+
+func (s *Stats) of(addr string) *stats {
+	if s == nil {
+		return nil
+	}
+	return s.byAddr[addr]
+}
+
+// Add adds n to the counter name of addr.
+func (s *Stats) Add(addr, name string, n int64) {
+	st := s.of(addr)
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	st.counters[name] += n
+	st.mu.Unlock()
+}
+
+// Record adds d to the latency histogram name of addr.
+func (s *Stats) Record(addr, name string, d time.Duration) {
+	st := s.of(addr)
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	h := st.latencies[name]
+	if h == nil {
+		h = &latency.Histogram{}
+		st.latencies[name] = h
+	}
+	st.mu.Unlock()
+	h.Record(d)
+}
+
+// Failed counts err by class against addr.
+func (s *Stats) Failed(addr string, err error) {
+	if st := s.of(addr); st != nil {
+		st.failures.AddErr(err)
+	}
+}
+
+// Print writes a line per endpoint with its counters, latencies and
+// failures.
+func (s *Stats) Print(w io.Writer) {
+	if s == nil {
+		return
+	}
+	fmt.Fprintln(w, "Per endpoint:")
+	for _, addr := range s.addrs {
+		st := s.byAddr[addr]
+		st.mu.Lock()
+		var parts []string
+		for _, name := range sortedKeys(st.counters) {
+			parts = append(parts, fmt.Sprintf("%s %d", name, st.counters[name]))
+		}
+		for _, name := range sortedKeys(st.latencies) {
+			parts = append(parts, fmt.Sprintf("%s latency %s", name, st.latencies[name].Summary()))
+		}
+		st.mu.Unlock()
+		fmt.Fprintf(w, "  %s: %s\n", addr, strings.Join(parts, ", "))
+		counts := st.failures.Snapshot()
+		for _, c := range errclass.Sorted(counts) {
+			fmt.Fprintf(w, "    %s: %d\n", c, counts[c])
+		}
+	}
+}
+
+// Fill adds an "endpoint <addr>" sub-section per endpoint to rep.
+func (s *Stats) Fill(rep *report.Report) {
+	if s == nil {
+		return
+	}
+	for _, addr := range s.addrs {
+		st := s.byAddr[addr]
+		sec := rep.SubSection("endpoint " + addr)
+		st.mu.Lock()
+		for name, n := range st.counters {
+			sec.Counters[name] = n
+		}
+		for name, h := range st.latencies {
+			if h.Count() > 0 {
+				sec.Latencies[name] = h.Summary()
+			}
+		}
+		st.mu.Unlock()
+		sec.SetErrors(st.failures.Snapshot())
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,202 @@
+// Package errclass sorts the errors of network operations into a small set
+// of failure classes, so every command reports failures the same way.
+package errclass
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// Class is a failure category.
+type Class string
+
+const (
+	DNS         Class = "dns"
+	DialTimeout Class = "dial_timeout"
+	Refused     Class = "connection_refused"
+	Reset       Class = "connection_reset"
+	Timeout     Class = "timeout"
+	// RegisterTimeout and WriteTimeout are timeouts of a registration
+	// answer and of a write; Timeout is left for other reads.
+	RegisterTimeout Class = "register_timeout"
+	WriteTimeout    Class = "write_timeout"
+	EOF             Class = "eof"
+	Decode          Class = "decode"
+	Rejected        Class = "rejected"
+	// PasswordPolicy is a registration rejected because its password does
+	// not meet the server's rules, told apart from other rejections so a
+	// policy change shows at once.
+	PasswordPolicy Class = "password_policy"
+	Unexpected     Class = "unexpected_response"
+	HTTPStatus     Class = "http_status"
+	// NotJSON is an HTTP answer that is not the JSON asked for, usually a
+	// proxy's error page while the backend is down.
+	NotJSON Class = "not_json"
+	Other   Class = "other"
+)
+
+// All lists every class, in a stable order for column-oriented output.
+var All = []Class{DNS, DialTimeout, Refused, Reset, Timeout, RegisterTimeout, WriteTimeout, EOF, Decode, Rejected, PasswordPolicy, Unexpected, HTTPStatus, NotJSON, Other}
+
+// Classifier is implemented by errors that know their own class, such as a
+// server rejecting a registration.
+type Classifier interface {
+	FailureClass() Class
+}
+
+// Classify returns the failure class of err.
+func Classify(err error) Class {
+	if err == nil {
+		return ""
+	}
+	var c Classifier
+	if errors.As(err, &c) {
+		return c.FailureClass()
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return DNS
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return Decode
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return Refused
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return Reset
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return EOF
+	}
+
+	timeout := errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, context.DeadlineExceeded)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		timeout = true
+	}
+	if timeout {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) {
+			switch opErr.Op {
+			case "dial":
+				return DialTimeout
+			case "write":
+				return WriteTimeout
+			}
+		}
+		return Timeout
+	}
+	return Other
+}
+
+// phaseError attributes the read timeouts of err to a phase.
+type phaseError struct {
+	timeout Class
+	err     error
+}
+
+func (e *phaseError) Error() string { return e.err.Error() }
+func (e *phaseError) Unwrap() error { return e.err }
+
+// FailureClass implements Classifier.
+func (e *phaseError) FailureClass() Class {
+	if c := Classify(e.err); c != Timeout {
+		return c
+	}
+	return e.timeout
+}
+
+// TimeoutIn wraps err so that, if it is a read timeout, it is classified
+// as timeout instead of Timeout. Other errors keep their class. A nil err
+// stays nil.
+func TimeoutIn(timeout Class, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &phaseError{timeout: timeout, err: err}
+}
+
+// Counter counts failures by class. It is safe for concurrent use.
+type Counter struct {
+	mu     sync.Mutex
+	counts map[Class]int64
+}
+
+// Add counts one failure of class c.
+func (fc *Counter) Add(c Class) {
+	fc.mu.Lock()
+	if fc.counts == nil {
+		fc.counts = make(map[Class]int64)
+	}
+	fc.counts[c]++
+	fc.mu.Unlock()
+}
+
+// AddErr counts err under its class.
+func (fc *Counter) AddErr(err error) {
+	fc.Add(Classify(err))
+}
+
+// Snapshot returns a copy of the counts.
+func (fc *Counter) Snapshot() map[Class]int64 {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	out := make(map[Class]int64, len(fc.counts))
+	for k, v := range fc.counts {
+		out[k] = v
+	}
+	return out
+}
+
+// Sorted returns the classes of counts ordered by descending count.
+func Sorted(counts map[Class]int64) []Class {
+	classes := make([]Class, 0, len(counts))
+	for c := range counts {
+		classes = append(classes, c)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if counts[classes[i]] != counts[classes[j]] {
+			return counts[classes[i]] > counts[classes[j]]
+		}
+		return classes[i] < classes[j]
+	})
+	return classes
+}
+
+// PrintCounts writes one indented line per class with its share of the
+// failures, most frequent first.
+func PrintCounts(w io.Writer, counts map[Class]int64) {
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	for _, c := range Sorted(counts) {
+		fmt.Fprintf(w, "  %s: %d (%.1f%%)\n", c, counts[c], 100*float64(counts[c])/float64(total))
+	}
+}
+
+type classified struct {
+	class Class
+	msg   string
+}
+
+func (e *classified) Error() string       { return e.msg }
+func (e *classified) FailureClass() Class { return e.class }
+
+// Errorf returns an error of class c formatted like fmt.Errorf (without %w
+// support).
+func Errorf(c Class, format string, args ...interface{}) error {
+	return &classified{class: c, msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,105 @@
+// Package errlog reports worker failures on stderr without flooding it.
+// Failures are counted per error class and written as one line per class at
+// most once per interval, so a dead server costs a few lines a second
+// instead of one per failure; each failure in full goes to the debug log.
+package errlog
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/errclass"
+)
+
+// Reporter aggregates failures. It is safe for concurrent use; a nil
+// *Reporter ignores everything.
+type Reporter struct {
+	w        io.Writer
+	interval time.Duration
+
+	mu sync.Mutex
+	// counts are the failures per class since the last flush, and example
+	// the first of each, prefixed with the worker it happened in.
+	counts  map[errclass.Class]int64
+	example map[errclass.Class]string
+	since   time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Start returns a reporter writing to w every interval, until Stop.
+func Start(w io.Writer, interval time.Duration) *Reporter {
+	r := &Reporter{
+		w:        w,
+		interval: interval,
+		counts:   make(map[errclass.Class]int64),
+		example:  make(map[errclass.Class]string),
+		since:    time.Now(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go r.loop()
+	return r
+}
+
+func (r *Reporter) loop() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.flush()
+		case <-r.stop:
+			r.flush()
+			return
+		}
+	}
+}
+
+// Add records the failure err of the worker who.
+func (r *Reporter) Add(who string, err error) {
+	if r == nil {
+		return
+	}
+	slog.Debug("failure", "who", who, "error", err)
+	c := errclass.Classify(err)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.counts[c] == 0 {
+		r.example[c] = fmt.Sprintf("[%s] %v", who, err)
+	}
+	r.counts[c]++
+}
+
+// flush writes a line per class failed since the last flush, as
+// "connection_refused ×1243 in last 1s, e.g. [over12] dial tcp ...".
+func (r *Reporter) flush() {
+	r.mu.Lock()
+	counts, example, since := r.counts, r.example, r.since
+	now := time.Now()
+	r.counts, r.example, r.since = make(map[errclass.Class]int64), make(map[errclass.Class]string), now
+	r.mu.Unlock()
+	window := now.Sub(since)
+	if window >= time.Second {
+		window = window.Round(100 * time.Millisecond)
+	} else {
+		window = window.Round(time.Millisecond)
+	}
+	for _, c := range errclass.Sorted(counts) {
+		fmt.Fprintf(r.w, "%s ×%d in last %s, e.g. %s\n", c, counts[c], window, example[c])
+	}
+}
+
+// Stop writes the failures not reported yet and stops the reporter.
+func (r *Reporter) Stop() {
+	if r == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+}
@@ -0,0 +1,116 @@
+package errlog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// buffer is a bytes.Buffer the reporter can write to while the test reads.
+type buffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+// lines returns the lines written since the last call, with their windows
+// left out: "eof ×2, e.g. [w1] EOF".
+func (b *buffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := []string{}
+	for _, l := range strings.Split(strings.TrimSuffix(b.b.String(), "\n"), "\n") {
+		if l != "" {
+			out = append(out, window.ReplaceAllString(l, ","))
+		}
+	}
+	b.b.Reset()
+	return out
+}
+
+var window = regexp.MustCompile(` in last [0-9.]+[µnm]?s,`)
+
+// fail adds n failures err of the workers w1, w2, ...
+func fail(r *Reporter, n int, err error) {
+	for i := range n {
+		r.Add(fmt.Sprintf("w%d", i+1), err)
+	}
+}
+
+func TestLinePerClassPerInterval(t *testing.T) {
+	var out buffer
+	r := Start(&out, time.Hour) // the test flushes
+	refused := fmt.Errorf("dial tcp: %w", syscall.ECONNREFUSED)
+	intervals := []struct {
+		name string
+		add  func()
+		want []string
+	}{
+		{"three classes", func() {
+			fail(r, 3, refused)
+			fail(r, 1, errors.New("boom"))
+			fail(r, 2, io.EOF)
+		}, []string{
+			"connection_refused ×3, e.g. [w1] dial tcp: connection refused",
+			"eof ×2, e.g. [w1] EOF",
+			"other ×1, e.g. [w1] boom",
+		}},
+		{"counts start over", func() {
+			fail(r, 1, io.EOF)
+			r.Add("w9", fmt.Errorf("reading: %w", io.EOF))
+		}, []string{"eof ×2, e.g. [w1] EOF"}},
+		{"no failures", func() {}, []string{}},
+	}
+	for _, iv := range intervals {
+		iv.add()
+		r.flush()
+		if got := out.lines(); strings.Join(got, "\n") != strings.Join(iv.want, "\n") {
+			t.Errorf("%s: wrote %q, want %q", iv.name, got, iv.want)
+		}
+	}
+
+	// Failures after the last tick are written on Stop.
+	fail(r, 4, refused)
+	r.Stop()
+	if got, want := out.lines(), []string{"connection_refused ×4, e.g. [w1] dial tcp: connection refused"}; strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Stop wrote %q, want %q", got, want)
+	}
+}
+
+func TestTicks(t *testing.T) {
+	var out buffer
+	r := Start(&out, 10*time.Millisecond)
+	defer r.Stop()
+	fail(r, 5, io.EOF)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if got := out.lines(); len(got) > 0 {
+			if len(got) != 1 || got[0] != "eof ×5, e.g. [w1] EOF" {
+				t.Errorf("a tick wrote %q", got)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no tick wrote the failures")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestNilReporter(t *testing.T) {
+	var r *Reporter
+	r.Add("w1", io.EOF)
+	r.Stop()
+}
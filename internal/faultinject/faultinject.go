@@ -0,0 +1,153 @@
+// Package faultinject simulates network failures on the TCP connections of
+// pokerclient, to exercise retry and reconnect logic deterministically.
+//
+// It is only linked into binaries built with the faultinject tag, which add
+// the -fault-* flags to every command:
+//
+//	go build -tags faultinject ./cmd/aijam
+package faultinject
+
+import (
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/rng"
+)
+
+// Config selects the faults to inject. The zero value injects nothing.
+type Config struct {
+	// FailDials makes the first FailDials dial attempts fail with
+	// "connection refused".
+	FailDials int
+	// DropAfterBytes closes each connection once it has read that many
+	// bytes.
+	DropAfterBytes int64
+	// ReadDelay is added before every read.
+	ReadDelay time.Duration
+	// CorruptPercent is the share of inbound lines whose first byte is
+	// replaced, which makes them invalid JSON.
+	CorruptPercent float64
+	// Seed drives the choice of corrupted lines.
+	Seed int64
+}
+
+// Enabled reports whether any fault is configured.
+func (c Config) Enabled() bool {
+	return c.FailDials > 0 || c.DropAfterBytes > 0 || c.ReadDelay > 0 || c.CorruptPercent > 0
+}
+
+// Dialer wraps a dial function with the faults of cfg. It is safe for
+// concurrent use.
+type Dialer struct {
+	cfg   Config
+	next  func(addr string, timeout time.Duration) (net.Conn, error)
+	dials atomic.Int64
+}
+
+// NewDialer returns a Dialer calling next for the dials it lets through.
+func NewDialer(cfg Config, next func(addr string, timeout time.Duration) (net.Conn, error)) *Dialer {
+	return &Dialer{cfg: cfg, next: next}
+}
+
+// Dial has the signature of pokerclient.DialFunc.
+func (d *Dialer) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	n := d.dials.Add(1)
+	if n <= int64(d.cfg.FailDials) {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	}
+	c, err := d.next(addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return Wrap(c, d.cfg, int(n)), nil
+}
+
+// Conn is a net.Conn that injects read faults.
+type Conn struct {
+	net.Conn
+	cfg Config
+
+	mu          sync.Mutex
+	rng         *rand.Rand
+	read        int64
+	atLineStart bool
+}
+
+// Wrap returns c with the read faults of cfg. index selects the random
+// stream, so each connection corrupts different lines.
+func Wrap(c net.Conn, cfg Config, index int) *Conn {
+	return &Conn{Conn: c, cfg: cfg, rng: rng.ForWorker(cfg.Seed, index), atLineStart: true}
+}
+
+func (c *Conn) Read(p []byte) (int, error) {
+	if c.cfg.ReadDelay > 0 {
+		time.Sleep(c.cfg.ReadDelay)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cfg.DropAfterBytes > 0 {
+		left := c.cfg.DropAfterBytes - c.read
+		if left <= 0 {
+			c.Conn.Close()
+			return 0, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+		}
+		if int64(len(p)) > left {
+			p = p[:left]
+		}
+	}
+	n, err := c.Conn.Read(p)
+	c.read += int64(n)
+	if c.cfg.CorruptPercent > 0 {
+		for i := 0; i < n; i++ {
+			if c.atLineStart && c.rng.Float64()*100 < c.cfg.CorruptPercent {
+				p[i] = '!'
+			}
+			c.atLineStart = p[i] == '\n'
+		}
+	}
+	return n, err
+}
+
+// Install wraps pokerclient.DialFunc with the faults of cfg.
+func Install(cfg Config) {
+	pokerclient.DialFunc = NewDialer(cfg, pokerclient.DialFunc).Dial
+}
+
+// RegisterFlags adds the -fault-* flags to fs. Use it with cli.AddFlagHook,
+// together with InstallFromFlags.
+func RegisterFlags(fs *flag.FlagSet) {
+	fs.Int("fault-fail-dials", 0, "fault injection: fail the first N dials")
+	fs.Int64("fault-drop-after", 0, "fault injection: drop each connection after reading N bytes")
+	fs.Duration("fault-read-delay", 0, "fault injection: delay every read")
+	fs.Float64("fault-corrupt-percent", 0, "fault injection: corrupt this percentage of inbound lines")
+	fs.Int64("fault-seed", 1, "fault injection: seed of the corrupted line choice")
+}
+
+// InstallFromFlags installs the faults selected by the parsed -fault-* flags
+// of fs, if any, and says so on stdout. Flag sets without the common flags
+// are ignored.
+func InstallFromFlags(fs *flag.FlagSet) {
+	if fs.Lookup("fault-fail-dials") == nil {
+		return
+	}
+	get := func(name string) any { return fs.Lookup(name).Value.(flag.Getter).Get() }
+	cfg := Config{
+		FailDials:      get("fault-fail-dials").(int),
+		DropAfterBytes: get("fault-drop-after").(int64),
+		ReadDelay:      get("fault-read-delay").(time.Duration),
+		CorruptPercent: get("fault-corrupt-percent").(float64),
+		Seed:           get("fault-seed").(int64),
+	}
+	if !cfg.Enabled() {
+		return
+	}
+	fmt.Printf("FAULT INJECTION ACTIVE: %+v\n", cfg)
+	Install(cfg)
+}
@@ -0,0 +1,72 @@
+package faultinject
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// serve returns the client end of a connection whose server end writes
+// data and closes.
+func serve(t *testing.T, data string) net.Conn {
+	t.Helper()
+	client, server := net.Pipe()
+	go func() {
+		server.Write([]byte(data))
+		server.Close()
+	}()
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestDialerFailsFirstDials(t *testing.T) {
+	dialed := 0
+	d := NewDialer(Config{FailDials: 2}, func(string, time.Duration) (net.Conn, error) {
+		dialed++
+		client, _ := net.Pipe()
+		return client, nil
+	})
+	for i, wantErr := range []bool{true, true, false, false} {
+		c, err := d.Dial("host:5000", time.Second)
+		if wantErr != (err != nil) {
+			t.Fatalf("dial %d: error %v, want error %v", i+1, err, wantErr)
+		}
+		if err != nil && !errors.Is(err, syscall.ECONNREFUSED) {
+			t.Errorf("dial %d: %v, want connection refused", i+1, err)
+		}
+		if c != nil {
+			c.Close()
+		}
+	}
+	if dialed != 2 {
+		t.Errorf("%d dials went through, want 2", dialed)
+	}
+}
+
+func TestConnFaults(t *testing.T) {
+	const lines = "line one\nline two\nline three\n"
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    string
+		wantErr error
+	}{
+		{"no faults", Config{}, lines, nil},
+		{"drop after bytes", Config{DropAfterBytes: 12}, "line one\nlin", syscall.ECONNRESET},
+		{"corrupt every line", Config{CorruptPercent: 100}, "!ine one\n!ine two\n!ine three\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(Wrap(serve(t, lines), tt.cfg, 0))
+			if string(got) != tt.want {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
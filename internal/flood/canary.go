@@ -0,0 +1,94 @@
+package flood
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/latency"
+	"elastic-ai-jam-2025/internal/passwords"
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// canaryRun is the outcome of the canary registrations made before the
+// flood. Its counts are kept apart from the flood's.
+type canaryRun struct {
+	attempted, succeeded int
+	latency              latency.Histogram
+	failures             errclass.Counter
+}
+
+// canaryPrefix returns the username prefix of the canaries: -canary-prefix,
+// or the flood's prefix followed by "canary-", outside the numbered range
+// either way. The run ID keeps the canaries of different runs apart.
+func (cfg *Config) canaryPrefix() string {
+	prefix := cfg.CanaryPrefix
+	if prefix == "" {
+		prefix = cfg.BaseUsername + "canary-"
+	}
+	return prefix + cfg.RunID + "-"
+}
+
+// runCanaries registers cfg.Canaries throwaway players one after the
+// other, each on the endpoint a worker of its index would use, and prints a
+// line per canary to w.
+func runCanaries(w io.Writer, cfg *Config) *canaryRun {
+	c := &canaryRun{}
+	prefix := cfg.canaryPrefix()
+	fmt.Fprintf(w, "Preflight: %d canary registrations as %s0 .. %s%d\n", cfg.Canaries, prefix, prefix, cfg.Canaries-1)
+	for i := range cfg.Canaries {
+		username := prefix + strconv.Itoa(i)
+		addr := cfg.TCPServer.For(i)
+		start := time.Now()
+		resp, err := registerCanary(cfg, addr, username, cfg.Passwords.Password(username, i))
+		took := time.Since(start)
+		c.attempted++
+		if err != nil {
+			c.failures.AddErr(err)
+			passwords.NoteRejection(err)
+			fmt.Fprintf(w, "  [FAIL] %-28s %8s  %s: %v\n", username, took.Round(time.Millisecond), errclass.Classify(err), err)
+			continue
+		}
+		c.succeeded++
+		c.latency.Record(took)
+		fmt.Fprintf(w, "  [ OK ] %-28s %8s  %s from %s\n", username, took.Round(time.Millisecond), resp.Type, addr)
+	}
+	fmt.Fprintf(w, "Preflight: %d of %d canaries registered, latency %s\n", c.succeeded, c.attempted, c.latency.Summary())
+	return c
+}
+
+// registerCanary registers one canary the way registerPlayer registers a
+// player, and checks the answer is a leaderboard entry start.
+func registerCanary(cfg *Config, addr, username, password string) (*pokerclient.ServerResponse, error) {
+	conn, err := pokerclient.Dial(addr, cfg.ConnectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	cfg.ApplyTimeouts(conn)
+	return conn.Register(username, password)
+}
+
+// failed reports whether more than half of the canaries failed, which
+// aborts the run.
+func (c *canaryRun) failed() bool {
+	return c != nil && 2*(c.attempted-c.succeeded) > c.attempted
+}
+
+// fill records the canaries in the "preflight" sub-report of rep.
+func (c *canaryRun) fill(rep *report.Report) {
+	if c == nil {
+		return
+	}
+	sec := rep.SubSection("preflight")
+	sec.Counters["canary_registrations"] = int64(c.attempted)
+	sec.Counters["successful_canary_registrations"] = int64(c.succeeded)
+	sec.Counters["failed_canary_registrations"] = int64(c.attempted - c.succeeded)
+	if c.latency.Count() > 0 {
+		sec.Latencies["canary_registration"] = c.latency.Summary()
+	}
+	sec.SetErrors(c.failures.Snapshot())
+}
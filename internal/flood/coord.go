@@ -0,0 +1,207 @@
+package flood
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/coord"
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// finalPushTimeout bounds the last push of a worker, so a dead controller
+// does not hold up its exit.
+const finalPushTimeout = 5 * time.Second
+
+// joinController registers this run as a worker of cfg.Controller and sets
+// its first player index to the block the controller assigned.
+func joinController(cfg *Config) (*coord.Client, error) {
+	client := coord.NewClient(cfg.Controller, cfg.RequestTimeout)
+	name, _ := os.Hostname()
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.RequestTimeout)
+	defer cancel()
+	resp, err := client.Register(ctx, coord.RegisterRequest{Name: name, RunID: cfg.RunID, Players: cfg.NumPlayers})
+	if err != nil {
+		return nil, err
+	}
+	cfg.FirstIndex = resp.FirstIndex
+	fmt.Printf("Worker %s of controller %s (run %s): players %d to %d\n", resp.WorkerID, client.BaseURL, resp.RunID,
+		cfg.FirstIndex, cfg.FirstIndex+cfg.NumPlayers-1)
+	return client, nil
+}
+
+// pushCounters pushes the counters to the controller every interval until
+// finish, which sends the last push. Pushes that fail are logged once until
+// one succeeds again; the run carries on either way, with its local report.
+func pushCounters(client *coord.Client, interval time.Duration) (finish func()) {
+	unreachable := false
+	push := func(ctx context.Context, done bool) {
+		err := client.Push(ctx, counters(done))
+		switch {
+		case err != nil && !unreachable:
+			slog.Warn("controller unreachable, carrying on with the local report only", "error", err)
+			unreachable = true
+		case err == nil && unreachable:
+			slog.Info("controller reachable again")
+			unreachable = false
+		}
+	}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				push(context.Background(), false)
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+		ctx, cancel := context.WithTimeout(context.Background(), finalPushTimeout)
+		defer cancel()
+		push(ctx, true)
+	}
+}
+
+// counters is what a worker pushes: its counters so far.
+func counters(done bool) coord.Push {
+	p := coord.Push{
+		Counters:  registry.Snapshot().Counters,
+		Errors:    make(map[string]int64),
+		Latencies: registry.HistogramCounts(),
+		Done:      done,
+	}
+	for c, n := range failuresByClass.Snapshot() {
+		p.Errors[string(c)] = n
+	}
+	return p
+}
+
+// runController serves the coordination endpoints on cfg.Listen, prints the
+// merged summary every cfg.CoordInterval, and once every worker finished or
+// was lost, or the run is interrupted, prints and reports the merged totals.
+func runController(ctx context.Context, cfg *Config, fs *flag.FlagSet) int {
+	ctl := coord.NewController(cfg.RunID, 3*cfg.CoordInterval)
+	ln, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	srv := &http.Server{Handler: ctl.Handler()}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("controller server failed", "error", err)
+		}
+	}()
+	defer srv.Close()
+
+	fmt.Println("--- Flood controller ---")
+	fmt.Printf("Listening on %s; start workers with -controller=%s\n", ln.Addr(), ln.Addr())
+	summary := url.URL{Scheme: "http", Host: ln.Addr().String(), Path: coord.PathSummary}
+	fmt.Printf("Merged summary: %s\n", &summary)
+	fmt.Println("Press Ctrl+C to stop.")
+	rep := report.New("flood", cli.Effective(fs))
+	start := time.Now()
+	ticker := time.NewTicker(cfg.CoordInterval)
+	defer ticker.Stop()
+wait:
+	for {
+		select {
+		case <-ticker.C:
+			s := ctl.Summary()
+			fmt.Printf("[controller %s] %s\n", time.Since(start).Round(time.Second), progressLine(&s))
+			if len(s.Workers) > 0 && s.Count(coord.StateRunning) == 0 {
+				break wait
+			}
+		case <-ctx.Done():
+			break wait
+		}
+	}
+
+	s := ctl.Summary()
+	fmt.Println("-----------------------------------------")
+	fmt.Printf("Workers: %d (%d done, %d lost, %d still running)\n", len(s.Workers), s.Count(coord.StateDone), s.Count(coord.StateLost), s.Count(coord.StateRunning))
+	for _, w := range s.Workers {
+		fmt.Printf("  %-4s %-20s players %d to %d: %s, successful %d, failed %d\n", w.WorkerID, w.Name,
+			w.FirstIndex, w.FirstIndex+w.Players-1, w.State, w.Counters["successful_registrations"], w.Counters["failed_registrations"])
+	}
+	fmt.Printf("Successful registrations: %d\n", s.Counters["successful_registrations"])
+	fmt.Printf("Failed registrations: %d\n", s.Counters["failed_registrations"])
+	errs := make(map[errclass.Class]int64, len(s.Errors))
+	for c, n := range s.Errors {
+		errs[errclass.Class(c)] = n
+	}
+	errclass.PrintCounts(os.Stdout, errs)
+	fmt.Printf("Registration latency: %s\n", s.Latencies["registration"])
+
+	fillControllerReport(rep, &s)
+	status, reason := "", ""
+	switch {
+	case ctx.Err() != nil:
+		status, reason = report.StatusInterrupted, fmt.Sprintf("interrupted with %d of %d workers running", s.Count(coord.StateRunning), len(s.Workers))
+	case s.Count(coord.StateLost) > 0:
+		reason = fmt.Sprintf("%d of %d workers lost", s.Count(coord.StateLost), len(s.Workers))
+	}
+	rep.Finish(status, reason)
+	cfg.WriteReport(rep)
+	return 0
+}
+
+// progressLine formats the merged counters for the live summary.
+func progressLine(s *coord.Summary) string {
+	return fmt.Sprintf("workers: %d running, %d done, %d lost; launched %d, successful %d, failed %d; registration p50 %.1fms p95 %.1fms",
+		s.Count(coord.StateRunning), s.Count(coord.StateDone), s.Count(coord.StateLost),
+		s.Counters["registrations_launched"], s.Counters["successful_registrations"], s.Counters["failed_registrations"],
+		s.Latencies["registration"].P50Ms, s.Latencies["registration"].P95Ms)
+}
+
+// fillControllerReport puts the merged totals in rep, and a "worker <id>"
+// sub-report per worker.
+func fillControllerReport(rep *report.Report, s *coord.Summary) {
+	for k, n := range s.Counters {
+		rep.Counters[k] = n
+	}
+	for k, n := range s.Errors {
+		rep.Errors[k] = n
+	}
+	for k, l := range s.Latencies {
+		if l.Count > 0 {
+			rep.Latencies[k] = l
+		}
+	}
+	var runIDs []string
+	for _, w := range s.Workers {
+		sec := rep.SubSection("worker " + w.WorkerID)
+		for k, n := range w.Counters {
+			sec.Counters[k] = n
+		}
+		for k, n := range w.Errors {
+			sec.Errors[k] = n
+		}
+		rep.Details["worker_"+w.WorkerID] = fmt.Sprintf("%s, players %d to %d, %s", w.Name, w.FirstIndex, w.FirstIndex+w.Players-1, w.State)
+		if w.RunID != "" {
+			runIDs = append(runIDs, w.RunID)
+		}
+	}
+	rep.Details["workers"] = fmt.Sprint(len(s.Workers))
+	if len(runIDs) > 0 {
+		rep.Details["worker_run_ids"] = strings.Join(runIDs, ",")
+	}
+}
@@ -0,0 +1,106 @@
+package flood
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// drainLog counts the events the server sent after registration answers,
+// by type. It is safe for concurrent use.
+type drainLog struct {
+	mu     sync.Mutex
+	byType map[string]int64
+}
+
+// drainEvents reads and discards what the server sends on conn after the
+// registration answer, for cfg.DrainEvents or until cfg.DrainMaxEvents
+// events, so the connection is not closed under the follow-up events the
+// server sends some players, such as a queue placement. It stops early on
+// a read error other than the end of the grace period.
+func drainEvents(cfg *Config, conn *pokerclient.Conn) {
+	deadline := time.Now().Add(cfg.DrainEvents)
+	n := 0
+	for cfg.DrainMaxEvents == 0 || n < cfg.DrainMaxEvents {
+		left := time.Until(deadline)
+		if left <= 0 {
+			break
+		}
+		conn.ReadTimeout = left
+		resp, err := conn.ReadMessage()
+		if err != nil {
+			var ne net.Error
+			if !errors.As(err, &ne) || !ne.Timeout() {
+				drainErrors.Inc()
+			}
+			break
+		}
+		n++
+		drainedEvents.Inc()
+		drained.add(resp.Type)
+	}
+	if n > 0 {
+		unsolicitedConnections.Inc()
+	}
+}
+
+func (l *drainLog) add(typ string) {
+	if typ == "" {
+		typ = "unknown"
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.byType == nil {
+		l.byType = make(map[string]int64)
+	}
+	l.byType[typ]++
+}
+
+// snapshot returns the counts by type.
+func (l *drainLog) snapshot() map[string]int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[string]int64, len(l.byType))
+	for typ, n := range l.byType {
+		out[typ] = n
+	}
+	return out
+}
+
+// print writes the events drained, by type, if -drain-events is set.
+func (l *drainLog) print(w io.Writer, cfg *Config) {
+	if cfg.DrainEvents <= 0 {
+		return
+	}
+	fmt.Fprintf(w, "Connections with events after the registration answer: %d of %d (%d events drained",
+		unsolicitedConnections.Load(), successfulRegistrations.Load(), drainedEvents.Load())
+	if n := drainErrors.Load(); n > 0 {
+		fmt.Fprintf(w, ", %d drains cut short by an error", n)
+	}
+	fmt.Fprintln(w, ")")
+	byType := l.snapshot()
+	types := make([]string, 0, len(byType))
+	for typ := range byType {
+		types = append(types, typ)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return byType[types[i]] > byType[types[j]] || byType[types[i]] == byType[types[j]] && types[i] < types[j]
+	})
+	for _, typ := range types {
+		fmt.Fprintf(w, "  %-32s %d\n", typ, byType[typ])
+	}
+}
+
+// fill records the events drained by type as "drained_<type>" counters.
+func (l *drainLog) fill(rep *report.Report) {
+	for typ, n := range l.snapshot() {
+		rep.Counters["drained_"+typ] = n
+	}
+}
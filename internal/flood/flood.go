@@ -0,0 +1,523 @@
+// Package flood implements the "flood" command: mass registration of players
+// over the TCP protocol.
+package flood
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/blockdetect"
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/endpoint"
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/errlog"
+	"elastic-ai-jam-2025/internal/metrics"
+	"elastic-ai-jam-2025/internal/netaddr"
+	"elastic-ai-jam-2025/internal/panics"
+	"elastic-ai-jam-2025/internal/passwords"
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/preflight"
+	"elastic-ai-jam-2025/internal/rejectlog"
+	"elastic-ai-jam-2025/internal/report"
+	"elastic-ai-jam-2025/internal/resusage"
+	"elastic-ai-jam-2025/internal/rng"
+	"elastic-ai-jam-2025/internal/timeseries"
+)
+
+// Config is the configuration of a flood run.
+type Config struct {
+	cli.Common
+
+	// Number of players to attempt to create.
+	// WARNING: Setting this to 1,000,000 will take a very long time and put extreme load on the server.
+	// Start with a small number like 100 for testing.
+	NumPlayers int
+	// MaxConcurrent controls how many registration attempts run in parallel.
+	MaxConcurrent int
+
+	BaseUsername string // Usernames will be like over0, over1, ...
+	// Shuffle registers the players in an order permuted by the seed rather
+	// than by index, so no username is always created first.
+	Shuffle bool
+	// RejectionSamples is how many distinct rejection messages are kept
+	// per error code for the summary and the report.
+	RejectionSamples int
+	// ConfirmAbove is the number of players above which the run is
+	// destructive: it needs -yes or a confirmation, and a host on
+	// -allow-hosts.
+	ConfirmAbove int
+	// FirstIndex is the index of the first player, so machines flooding
+	// together do not register the same usernames. -controller sets it.
+	FirstIndex int
+
+	// Controller, when set, is the address of a flood -listen instance to
+	// register with as a worker: it assigns the block of player indexes and
+	// receives the counters every CoordInterval. Listen, when set, makes
+	// this instance that controller instead: it floods nothing, prints the
+	// merged counters of its workers every CoordInterval and reports them
+	// once every worker is done, or lost after 3 intervals without a push.
+	// See package coord.
+	Controller    string
+	Listen        string
+	CoordInterval time.Duration
+
+	// StartDelay is a brief pause for the user to read the warning.
+	StartDelay time.Duration
+
+	// Canaries is the number of registrations made one after the other
+	// before the flood, under CanaryPrefix (default: BaseUsername followed
+	// by "canary-") and the run ID; the run is aborted if more than half of
+	// them fail. SkipPreflight skips them.
+	Canaries      int
+	CanaryPrefix  string
+	SkipPreflight bool
+
+	// DryRun checks configuration and connectivity, then exits without flooding.
+	DryRun bool
+
+	// TimeseriesOut, when set, is the file of per-second counters, in
+	// TimeseriesFormat.
+	TimeseriesOut    string
+	TimeseriesFormat string
+
+	// DrainEvents, when set, is how long a registered player's connection
+	// keeps reading, and discarding, what the server sends after the
+	// registration answer before it is closed; DrainMaxEvents closes it
+	// after that many events, zero not limiting them. See drainEvents.
+	DrainEvents    time.Duration
+	DrainMaxEvents int
+}
+
+// DefaultConfig returns the defaults the standalone flood-players binary used.
+func DefaultConfig() Config {
+	common := cli.DefaultCommon()
+	common.RegisterTimeout = 10 * time.Second
+	return Config{
+		Common:           common,
+		NumPlayers:       100000000,
+		MaxConcurrent:    100,
+		BaseUsername:     "over",
+		RejectionSamples: 5,
+		ConfirmAbove:     1000,
+		StartDelay:       5 * time.Second,
+		Canaries:         3,
+		CoordInterval:    5 * time.Second,
+		TimeseriesFormat: timeseries.FormatCSV,
+	}
+}
+
+// RegisterFlags adds the flood flags to fs.
+func (cfg *Config) RegisterFlags(fs *flag.FlagSet) {
+	cfg.Common.Register(fs)
+	cfg.Common.RegisterMetricsFlag(fs)
+	cfg.Common.RegisterBlockFlags(fs)
+	cfg.Common.RegisterOutageFlags(fs)
+	cfg.Common.RegisterDialLimitFlag(fs)
+	cfg.Common.RegisterFailFastFlag(fs)
+	cfg.Common.RegisterSeatbeltFlags(fs)
+	cfg.Common.RegisterResourceFlags(fs)
+	fs.IntVar(&cfg.NumPlayers, "players", cfg.NumPlayers, "number of players to register")
+	fs.IntVar(&cfg.MaxConcurrent, "concurrency", cfg.MaxConcurrent, "number of registrations running in parallel")
+	fs.StringVar(&cfg.BaseUsername, "username-prefix", cfg.BaseUsername, "prefix of generated usernames")
+	cfg.Common.RegisterPasswordFlags(fs, "prefix of generated passwords, followed by the player index")
+	fs.BoolVar(&cfg.Shuffle, "shuffle", cfg.Shuffle, "register the players in an order permuted by the seed instead of by index")
+	fs.IntVar(&cfg.ConfirmAbove, "confirm-above", cfg.ConfirmAbove, "registering more players than this needs -yes or a confirmation, and a host on -allow-hosts")
+	fs.IntVar(&cfg.RejectionSamples, "rejection-samples", cfg.RejectionSamples, "distinct server rejection messages shown per error code in the summary and report (0 disables)")
+	fs.IntVar(&cfg.FirstIndex, "first-index", cfg.FirstIndex, "index of the first player, to split the usernames between machines (set by -controller)")
+	fs.StringVar(&cfg.Controller, "controller", cfg.Controller, "register as a worker of the flood -listen instance at this address (host:port, [ipv6]:port or URL), which assigns the players and merges the counters")
+	fs.StringVar(&cfg.Listen, "listen", cfg.Listen, "coordinate -controller workers on this address (host:port, [ipv6]:port or :port) instead of flooding")
+	fs.DurationVar(&cfg.CoordInterval, "coord-interval", cfg.CoordInterval, "how often workers push their counters and the controller prints them")
+	fs.DurationVar(&cfg.StartDelay, "start-delay", cfg.StartDelay, "pause after the warning banner before starting")
+	fs.IntVar(&cfg.Canaries, "canaries", cfg.Canaries, "canary registrations made one after the other before the flood; more than half failing aborts the run")
+	fs.StringVar(&cfg.CanaryPrefix, "canary-prefix", cfg.CanaryPrefix, "username prefix of the canaries, followed by the run ID (default: -username-prefix followed by \"canary-\")")
+	fs.BoolVar(&cfg.SkipPreflight, "skip-preflight", cfg.SkipPreflight, "skip the canary registrations")
+	fs.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "check configuration and connectivity, print the plan and exit")
+	fs.StringVar(&cfg.TimeseriesOut, "timeseries-out", cfg.TimeseriesOut, "write per-second counters to this file as the run progresses")
+	fs.StringVar(&cfg.TimeseriesFormat, "timeseries-format", cfg.TimeseriesFormat, "format of -timeseries-out: "+strings.Join(timeseries.Formats, " or ")+" (json is written at the end of the run)")
+	fs.DurationVar(&cfg.DrainEvents, "drain-events", cfg.DrainEvents, "after a registration, keep reading and discarding the server's events this long before closing, counting them by type; each registration holds its -concurrency slot meanwhile (0: close right after the answer)")
+	fs.IntVar(&cfg.DrainMaxEvents, "drain-max-events", cfg.DrainMaxEvents, "stop -drain-events after this many events (0: no limit)")
+}
+
+// --- Global Counters (registered in registry, safe for concurrent use) ---
+var (
+	registry = metrics.New()
+
+	registrationsLaunched   = registry.Counter("registrations_launched", "Registrations started.")
+	successfulRegistrations = registry.Counter("successful_registrations", "Registrations the server accepted.")
+	failedRegistrations     = registry.Counter("failed_registrations", "Registrations that failed for any reason.")
+	registrationLatency     = registry.Histogram("registration", "Time from dialing to the registration reply, without the wait for a dial slot.")
+
+	// What -drain-events read after the registration answers.
+	unsolicitedConnections = registry.Counter("post_registration_traffic_connections", "Registered connections the server sent events to after the registration answer.")
+	drainedEvents          = registry.Counter("drained_events", "Events read and discarded after registration answers.")
+	drainErrors            = registry.Counter("drain_errors", "Drains cut short by a read error other than the end of the grace period.")
+	drained                drainLog
+
+	failuresByClass errclass.Counter
+	// rejections keeps the server's rejection messages; nil when
+	// -rejection-samples is zero.
+	rejections *rejectlog.Log
+	// failures reports the failed registrations on stderr, aggregated per
+	// class every second.
+	failures *errlog.Reporter
+	// byEndpoint breaks the registrations down per -server endpoint; nil
+	// with a single one.
+	byEndpoint *endpoint.Stats
+	// canaries are the preflight registrations, nil when skipped.
+	canaries *canaryRun
+
+	startTime time.Time
+
+	// series records per-second counters; nil unless -timeseries-out is set.
+	series *timeseries.Series
+
+	// resources samples the client's own resource usage; nil when
+	// -resource-interval is zero.
+	resources *resusage.Sampler
+
+	// guard pauses launching while the server looks like it blocks us; nil
+	// when -block-cooldown is zero.
+	guard *blockdetect.Guard
+
+	// workerPanics recovers the panics of registrations, unless -fail-fast.
+	workerPanics panics.Recorder
+)
+
+func newFlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("flood", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	return fs
+}
+
+// Flags returns the flood flag set with default values.
+func Flags() *flag.FlagSet {
+	cfg := DefaultConfig()
+	return newFlagSet(&cfg)
+}
+
+// Run is the entry point of the flood command.
+func Run(args []string) int {
+	cfg := DefaultConfig()
+	fs := newFlagSet(&cfg)
+	if code, stop := cli.Parse(fs, args); stop {
+		return code
+	}
+	closeLog, err := cfg.SetupLogging()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer closeLog()
+	if err := cfg.CheckIdent(cfg.BaseUsername); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if err := cfg.CheckIdent(cfg.canaryPrefix()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if err := cfg.Passwords.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if !cfg.SkipPreflight && cfg.Canaries <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: -canaries must be positive; use -skip-preflight to skip them")
+		return 2
+	}
+
+	if cfg.Listen != "" && cfg.Controller != "" {
+		fmt.Fprintln(os.Stderr, "Error: -listen and -controller are exclusive")
+		return 2
+	}
+	if (cfg.Listen != "" || cfg.Controller != "") && cfg.CoordInterval <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: -coord-interval must be positive")
+		return 2
+	}
+	if cfg.Listen != "" {
+		if cfg.Listen, err = netaddr.ListenAddr(cfg.Listen); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -listen: %v\n", err)
+			return 2
+		}
+	}
+	if cfg.Controller != "" {
+		if cfg.Controller, err = netaddr.BaseURL(cfg.Controller, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -controller: %v\n", err)
+			return 2
+		}
+	}
+	if cfg.Listen != "" {
+		ctx, stop := cli.InterruptContext()
+		defer stop()
+		return runController(ctx, &cfg, fs)
+	}
+
+	if cfg.DryRun {
+		return dryRun(&cfg)
+	}
+	if cfg.NumPlayers > cfg.ConfirmAbove {
+		volume := fmt.Sprintf("%d registrations, one TCP connection each, %d at a time", cfg.NumPlayers, cfg.MaxConcurrent)
+		if err := cfg.ConfirmDestructive("flood", cfg.TCPServer, volume); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+	rejections = rejectlog.New(cfg.RejectionSamples)
+	byEndpoint = endpoint.NewStats(cfg.TCPServer)
+	workerPanics.FailFast = cfg.FailFast
+	pokerclient.DialLimit = pokerclient.NewDialLimiter(cfg.MaxConcurrentDials)
+	// The canaries run before joining a controller, so a worker that
+	// cannot register does not claim a block of players.
+	rep := report.New("flood", cli.Effective(fs))
+	if !cfg.SkipPreflight {
+		canaries = runCanaries(os.Stdout, &cfg)
+		if canaries.failed() {
+			reason := fmt.Sprintf("%d of %d canary registrations failed", canaries.attempted-canaries.succeeded, canaries.attempted)
+			fmt.Fprintf(os.Stderr, "Error: preflight failed, %s; not flooding (-skip-preflight skips the canaries)\n", reason)
+			canaries.fill(rep)
+			rep.Finish(report.StatusAborted, "preflight: "+reason)
+			cfg.WriteReport(rep)
+			return 1
+		}
+	}
+	var finishPushing func()
+	if cfg.Controller != "" {
+		worker, err := joinController(&cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		finishPushing = pushCounters(worker, cfg.CoordInterval)
+	}
+	stopMetrics, err := registry.Serve(cfg.MetricsAddr, "aijam_flood")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer stopMetrics()
+
+	ctx, stop := cli.InterruptContext()
+	defer stop()
+	launched := runFlood(ctx, &cfg)
+	elapsed := runDuration()
+	if finishPushing != nil {
+		finishPushing()
+	}
+	printSummary(&cfg, launched, elapsed)
+
+	status, reason := "", ""
+	if ctx.Err() != nil {
+		status, reason = report.StatusInterrupted, fmt.Sprintf("interrupted after launching %d of %d registrations", launched, cfg.NumPlayers)
+	}
+	fillReport(rep, cfg.RateSpan(elapsed, guard))
+	rep.Config = cli.Effective(fs) // picks up the resolved seed
+	rep.Finish(status, reason)
+	cfg.WriteReport(rep)
+	return 0
+}
+
+// dryRun prints what a real run would do and checks that the server accepts
+// a registration, using the credentials of the run's first player.
+func dryRun(cfg *Config) int {
+	fmt.Println("--- Dry run: flood ---")
+	fmt.Printf("Would register %d players (%s%d .. %s%d) on %s\n", cfg.NumPlayers, cfg.BaseUsername, cfg.FirstIndex, cfg.BaseUsername, cfg.FirstIndex+cfg.NumPlayers-1, cfg.TCPServer)
+	fmt.Printf("Concurrency: %d registrations, rate: unlimited\n", cfg.MaxConcurrent)
+	fmt.Println("Checks:")
+	steps := preflight.TCPEndpoints(cfg.TCPServer, cfg.ConnectTimeout)
+	username := cfg.BaseUsername + strconv.Itoa(cfg.FirstIndex)
+	steps = append(steps, preflight.Registration(cfg.TCPServer.First(), cfg.ConnectTimeout, cfg.RegisterTimeout, username, cfg.Passwords.Password(username, cfg.FirstIndex)))
+	ok := preflight.Run(os.Stdout, steps)
+	if !ok {
+		fmt.Println("Dry run FAILED.")
+		return 1
+	}
+	fmt.Println("Dry run passed.")
+	return 0
+}
+
+// runFlood launches the registrations and waits for them to finish. It stops
+// launching when ctx is cancelled, and returns the number launched.
+func runFlood(ctx context.Context, cfg *Config) int {
+	fmt.Printf("--- TCP Player Creator ---\n")
+	fmt.Printf("WARNING: This script will attempt to create %d players.\n", cfg.NumPlayers)
+	fmt.Printf("Target TCP Server: %s\n", cfg.TCPServer)
+	fmt.Printf("Concurrency Level: %d\n", cfg.MaxConcurrent)
+	fmt.Printf("Passwords: %s\n", cfg.Passwords.Describe())
+	cfg.ResolveSeed()
+	order := func(i int) int { return i }
+	if cfg.Shuffle {
+		order = rng.NewPermutation(cfg.Seed, cfg.NumPlayers).At
+		fmt.Println("Player order: shuffled by the seed")
+	}
+	fmt.Println("Consider starting with a much smaller number of players for initial testing.")
+	fmt.Println("Press Ctrl+C to interrupt at any time (though players already registered will remain).")
+	fmt.Println("-----------------------------------------")
+	// Started before the pause, so that every return stops it.
+	failures = errlog.Start(os.Stderr, time.Second)
+	defer failures.Stop()
+	// Brief pause for the user to read the warning
+	select {
+	case <-time.After(cfg.StartDelay):
+	case <-ctx.Done():
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	// Semaphore to limit concurrency
+	semaphore := make(chan struct{}, cfg.MaxConcurrent)
+
+	startTime = time.Now()
+	resources = cfg.StartResources()
+	if cfg.TimeseriesOut != "" {
+		var err error
+		opts := timeseries.Options{Format: cfg.TimeseriesFormat, Labels: map[string]string{"run_id": cfg.RunID}, Extra: resources.Columns()}
+		if series, err = timeseries.Create(cfg.TimeseriesOut, startTime, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}
+
+	guard = cfg.BlockGuard(blockTotals)
+	stopGuard := guard.Start(ctx)
+
+	launched := 0
+launch:
+	for i := 0; i < cfg.NumPlayers; i++ {
+		guard.Wait(ctx)
+		select {
+		case semaphore <- struct{}{}: // Acquire a slot in the semaphore
+		case <-ctx.Done():
+			fmt.Println("\nInterrupted: no new registrations will be started, waiting for the running ones...")
+			break launch
+		}
+		wg.Add(1)
+		launched++
+		registrationsLaunched.Inc()
+
+		go registerPlayer(cfg, cfg.FirstIndex+order(i), &wg, semaphore)
+
+		// Optional: print progress periodically
+		if (i+1)%100 == 0 {
+			fmt.Printf("Launched registration for player %d...\n", i+1)
+		}
+	}
+
+	wg.Wait() // Wait for all goroutines to finish
+	stopGuard()
+	resources.Stop()
+	close(semaphore)
+	if err := series.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing time series: %v\n", err)
+	}
+	return launched
+}
+
+// runDuration is how long the registrations ran, zero if none started.
+func runDuration() time.Duration {
+	if startTime.IsZero() {
+		return 0
+	}
+	return time.Since(startTime)
+}
+
+func printSummary(cfg *Config, launched int, elapsed time.Duration) {
+	fmt.Println("-----------------------------------------")
+	fmt.Println("All registration attempts completed.")
+	fmt.Printf("Duration: %s\n", elapsed)
+	fmt.Printf("Successful registrations: %d\n", successfulRegistrations.Load())
+	fmt.Printf("Failed registrations: %d\n", failedRegistrations.Load())
+	errclass.PrintCounts(os.Stdout, failuresByClass.Snapshot())
+	rejections.Print(os.Stdout, "Registration rejections")
+	fmt.Printf("Registration latency: %s\n", registrationLatency.Summary())
+	pokerclient.DialLimit.Print(os.Stdout)
+	pokerclient.Decoding.Print(os.Stdout)
+	fmt.Printf("Total attempted: %d of %d\n", launched, cfg.NumPlayers)
+	report.DeriveRates(registry.Snapshot().Counters, nil, cfg.RateSpan(elapsed, guard)).Print(os.Stdout, "registrations_launched", "successful_registrations")
+	byEndpoint.Print(os.Stdout)
+	guard.PrintSummary(os.Stdout)
+	workerPanics.Print(os.Stdout)
+	drained.print(os.Stdout, cfg)
+	resources.Print(os.Stdout)
+	if best, worst, ok := series.BestWorst(); ok {
+		fmt.Printf("Per-second counters written to %s\n", cfg.TimeseriesOut)
+		fmt.Printf("  Best second:  +%ds, %d successful, %d failed, mean latency %.1fms\n", best.Second, best.Succeeded, best.Failed, best.MeanLatencyMs)
+		fmt.Printf("  Worst second: +%ds, %d successful, %d failed, mean latency %.1fms\n", worst.Second, worst.Succeeded, worst.Failed, worst.MeanLatencyMs)
+	}
+}
+
+// fillReport records the run, with its rates measured over elapsed.
+func fillReport(rep *report.Report, elapsed time.Duration) {
+	registry.Snapshot().Fill(&rep.Section)
+	rep.SetErrors(failuresByClass.Snapshot())
+	rep.Rejections = rejections.Snapshot()
+	rep.Rates = report.DeriveRates(rep.Counters, rep.Errors, elapsed)
+	guard.Fill(rep)
+	workerPanics.Fill(rep)
+	byEndpoint.Fill(rep)
+	canaries.fill(rep)
+	drained.fill(rep)
+	rep.Resources = resources.Snapshot()
+}
+
+// blockTotals are the counters the block detection judges.
+func blockTotals() blockdetect.Totals {
+	return blockdetect.Totals{Succeeded: successfulRegistrations.Load(), Failed: failuresByClass.Snapshot()}
+}
+
+// registerPlayer attempts to register a single player.
+func registerPlayer(cfg *Config, id int, wg *sync.WaitGroup, semaphore chan struct{}) {
+	defer wg.Done()
+	defer func() { <-semaphore }() // Release slot in semaphore
+
+	username := cfg.BaseUsername + strconv.Itoa(id)
+	defer workerPanics.Recover("registration of "+username, nil)
+	password := cfg.Passwords.Password(username, id)
+
+	// 1. Establish TCP connection
+	addr := cfg.TCPServer.For(id)
+	start := time.Now()
+	series.Started()
+	conn, err := pokerclient.Dial(addr, cfg.ConnectTimeout)
+	if err != nil {
+		failures.Add(username, err)
+		failedRegistrations.Inc()
+		failuresByClass.AddErr(err)
+		series.Failed(err)
+		byEndpoint.Add(addr, "failed_registrations", 1)
+		byEndpoint.Failed(addr, err)
+		return
+	}
+	defer conn.Close()
+
+	// 2. Bound the write and the wait for the answer
+	cfg.ApplyTimeouts(conn)
+
+	// 3. Send registration message and check the response.
+	if _, err := conn.Register(username, password); err != nil {
+		failures.Add(username, err)
+		failedRegistrations.Inc()
+		failuresByClass.AddErr(err)
+		rejections.AddErr(err, username)
+		passwords.NoteRejection(err)
+		series.Failed(err)
+		byEndpoint.Add(addr, "failed_registrations", 1)
+		byEndpoint.Failed(addr, err)
+		return
+	}
+	took := time.Since(start) - conn.DialWait
+	registrationLatency.Record(took)
+	series.Succeeded(took)
+	successfulRegistrations.Inc()
+	byEndpoint.Add(addr, "successful_registrations", 1)
+	byEndpoint.Record(addr, "registration", took)
+
+	// The server may send more events after the answer, such as a queue
+	// placement; without -drain-events they hit a closed connection.
+	if cfg.DrainEvents > 0 {
+		drainEvents(cfg, conn)
+	}
+}
@@ -0,0 +1,355 @@
+// Package httpapi is a small client for the jam's REST API.
+package httpapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/netaddr"
+	"elastic-ai-jam-2025/internal/servertime"
+)
+
+// APIPrefix is the path prefix of every REST endpoint.
+const APIPrefix = "/api/v0"
+
+// UserAgent and Headers are sent with every request of the clients built by
+// New and of those using Transport, whose responses Capture observes. cli
+// sets them from the identification flags.
+var (
+	UserAgent = "aijam"
+	Headers   http.Header
+)
+
+// ServerClock estimates the skew of the local clock from the Date header of
+// every response to those requests.
+var ServerClock = &servertime.Estimator{}
+
+// defaultTransport is http.DefaultTransport dialing in the order of
+// netaddr.Prefer, shared by every client so they share its connections.
+var defaultTransport = netaddr.Transport()
+
+// Transport returns next, or defaultTransport if nil, with every request
+// carrying UserAgent and Headers, recorded or replayed by Fixtures when set,
+// and offered to Slowest when set. Replayed responses are not observed by
+// ServerClock, Capture and Slowest, their headers and timings being those of
+// another time.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = defaultTransport
+	}
+	return identTransport{next}
+}
+
+type identTransport struct{ next http.RoundTripper }
+
+func (t identTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context()) // a RoundTripper must not modify the request
+	req.Header.Set("User-Agent", UserAgent)
+	for name, values := range Headers {
+		req.Header[name] = values
+	}
+	if Fixtures.Replaying() {
+		return Fixtures.replay(req)
+	}
+	req, trace := traced(req)
+	sent := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err == nil {
+		ServerClock.Observe(resp.Header.Get("Date"), sent, time.Now())
+		Capture.Observe(resp)
+		if Fixtures != nil {
+			resp, err = Fixtures.record(req, resp)
+		}
+	}
+	return timed(req, sent, trace, resp, err), err
+}
+
+// Client fetches JSON documents from the REST API.
+type Client struct {
+	// BaseURL is the server root, e.g. "http://host:8082".
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// New returns a client for baseURL whose requests time out after timeout.
+func New(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		BaseURL: baseURL,
+		HTTP:    &http.Client{Timeout: timeout, Transport: Transport(nil)},
+	}
+}
+
+// APIURL returns the absolute URL of an API path such as "/leaderboard".
+func (c *Client) APIURL(path string) string {
+	return c.BaseURL + APIPrefix + path
+}
+
+// GameURL returns the URL of the game detail page for gameID.
+func (c *Client) GameURL(gameID string) string {
+	return c.BaseURL + "/games/" + url.PathEscape(gameID)
+}
+
+// NewRequest returns a GET request for url that asks for JSON, as every
+// request of the client does.
+func NewRequest(url string) (*http.Request, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request for %s: %w", url, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+// Drain reads and closes the body of resp, the answer to a NewRequest for
+// url, and returns its size. A 200 answer that is not JSON gives a
+// *ContentTypeError, as GetJSON does; other statuses are left to the
+// caller.
+func Drain(url string, resp *http.Response) (int64, error) {
+	defer resp.Body.Close()
+	contentType := resp.Header.Get("Content-Type")
+	if resp.StatusCode != http.StatusOK || contentType != "" && isJSON(contentType, nil) {
+		return io.Copy(io.Discard, resp.Body)
+	}
+	// Without a Content-Type, or with a wrong one, the body tells.
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return int64(len(bodyBytes)), err
+	}
+	if !isJSON(contentType, bodyBytes) {
+		return int64(len(bodyBytes)), newContentTypeError(url, resp.StatusCode, contentType, bodyBytes)
+	}
+	return int64(len(bodyBytes)), nil
+}
+
+// GetJSON makes an HTTP GET request to url and unmarshals the JSON response
+// into target. A 200 answer that is not JSON, such as a proxy's error page,
+// gives a *ContentTypeError instead of a decode error.
+func (c *Client) GetJSON(url string, target interface{}) error {
+	slog.Debug("Requesting URL", "url", url)
+
+	req, err := NewRequest(url)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making GET request to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	slog.Debug("Received response", "url", url, "status", resp.StatusCode)
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body from %s: %w", url, err)
+	}
+	slog.Debug("Raw response body", "url", url, "body", string(bodyBytes))
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status, Body: string(bodyBytes)}
+	}
+	if contentType := resp.Header.Get("Content-Type"); !isJSON(contentType, bodyBytes) {
+		return newContentTypeError(url, resp.StatusCode, contentType, bodyBytes)
+	}
+
+	if err := json.Unmarshal(bodyBytes, target); err != nil {
+		return fmt.Errorf("error decoding JSON from %s (status %d): %w. Body: %s", url, resp.StatusCode, err, string(bodyBytes))
+	}
+	return nil
+}
+
+// StatusError is returned when the API answers with a non-200 status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("received non-200 status code from %s: %d %s. Body: %s", e.URL, e.StatusCode, e.Status, e.Body)
+}
+
+// FailureClass implements errclass.Classifier.
+func (e *StatusError) FailureClass() errclass.Class {
+	return errclass.HTTPStatus
+}
+
+// ContentTypeError is returned when the API answers with something other than
+// JSON. Body holds the start of the answer, which is usually enough to tell a
+// proxy's error page.
+type ContentTypeError struct {
+	URL         string
+	StatusCode  int
+	ContentType string
+	Body        string
+	// Size is the length of the whole body.
+	Size int
+}
+
+// maxErrorBody is the most of a non-JSON body a ContentTypeError keeps.
+const maxErrorBody = 200
+
+func newContentTypeError(url string, status int, contentType string, body []byte) *ContentTypeError {
+	e := &ContentTypeError{URL: url, StatusCode: status, ContentType: contentType, Size: len(body)}
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody]
+	}
+	e.Body = string(body)
+	return e
+}
+
+func (e *ContentTypeError) Error() string {
+	contentType := e.ContentType
+	if contentType == "" {
+		contentType = "no Content-Type"
+	}
+	return fmt.Sprintf("expected JSON from %s but got %s (status %d, %d bytes); a proxy or error page probably answered for the backend. Body starts: %q", e.URL, contentType, e.StatusCode, e.Size, e.Body)
+}
+
+// FailureClass implements errclass.Classifier.
+func (e *ContentTypeError) FailureClass() errclass.Class {
+	return errclass.NotJSON
+}
+
+// isJSON reports whether a response with contentType and body is JSON. A
+// response without a Content-Type is taken as JSON unless it looks like
+// markup.
+func isJSON(contentType string, body []byte) bool {
+	if contentType == "" {
+		return !bytes.HasPrefix(bytes.TrimSpace(body), []byte("<"))
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// Leaderboard fetches up to limit leaderboard entries.
+func (c *Client) Leaderboard(limit int) (*LeaderboardResponse, error) {
+	var data LeaderboardResponse
+	u := fmt.Sprintf("%s?limit=%d", c.APIURL("/leaderboard"), limit)
+	if err := c.GetJSON(u, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// PlayerGames fetches up to limit of the most recent games of playerID.
+func (c *Client) PlayerGames(playerID string, limit int) (*PlayerGamesResponse, error) {
+	var data PlayerGamesResponse
+	u := fmt.Sprintf("%s?limit=%d", c.APIURL("/players/"+url.PathEscape(playerID)+"/games"), limit)
+	if err := c.GetJSON(u, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// Games fetches the current list of games.
+func (c *Client) Games() ([]ListedGame, error) {
+	var games []ListedGame // API returns a JSON array of games
+	if err := c.GetJSON(c.APIURL("/games"), &games); err != nil {
+		return nil, err
+	}
+	return games, nil
+}
+
+// EachGame streams the current list of games, calling fn with each game in
+// the order listed until fn returns true. It decodes one game at a time, and
+// closes the response, unread, as soon as fn is done with the list, so a
+// large list costs the memory of its largest game rather than of the whole
+// list. It returns the number of games decoded; a JSON null list has none.
+// An entry that does not decode as a game ends the walk with an error, as it
+// does for Games, unless fn was done with the list before it.
+func (c *Client) EachGame(fn func(ListedGame) (done bool)) (int, error) {
+	u := c.APIURL("/games")
+	body, err := c.open(u)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	dec := json.NewDecoder(body)
+	tok, err := dec.Token()
+	if err != nil {
+		return 0, fmt.Errorf("error decoding JSON from %s: %w", u, err)
+	}
+	if tok == nil {
+		return 0, nil
+	}
+	if tok != json.Delim('[') {
+		return 0, fmt.Errorf("error decoding JSON from %s: expected an array of games, got %v", u, tok)
+	}
+	n := 0
+	for dec.More() {
+		var game ListedGame
+		if err := dec.Decode(&game); err != nil {
+			return n, fmt.Errorf("error decoding JSON from %s: game %d: %w", u, n+1, err)
+		}
+		n++
+		if fn(game) {
+			return n, nil
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return n, fmt.Errorf("error decoding JSON from %s: %w", u, err)
+	}
+	return n, nil
+}
+
+// open makes a GET request to url and returns the body of a 200 JSON
+// answer for the caller to stream and close. Other answers give the errors
+// of GetJSON.
+func (c *Client) open(url string) (io.ReadCloser, error) {
+	slog.Debug("Requesting URL", "url", url)
+
+	req, err := NewRequest(url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making GET request to %s: %w", url, err)
+	}
+	slog.Debug("Received response", "url", url, "status", resp.StatusCode)
+
+	body := bufio.NewReader(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(body)
+		return nil, &StatusError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status, Body: string(bodyBytes)}
+	}
+	// isJSON only looks at the start of a body without a Content-Type.
+	start, _ := body.Peek(512)
+	if contentType := resp.Header.Get("Content-Type"); !isJSON(contentType, start) {
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(body)
+		return nil, newContentTypeError(url, resp.StatusCode, contentType, bodyBytes)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{body, resp.Body}, nil
+}
+
+// Game fetches the details of a single game.
+func (c *Client) Game(gameID string) (*GameDetail, error) {
+	var data GameDetail
+	if err := c.GetJSON(c.APIURL("/games/"+url.PathEscape(gameID)), &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
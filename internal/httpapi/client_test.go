@@ -0,0 +1,197 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEachGame(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		find    int
+		listed  int
+		wantErr bool
+	}{
+		{"found first", gamesJSON(5), 1, 1, false},
+		{"found last", gamesJSON(5), 5, 5, false},
+		{"not found", gamesJSON(5), 42, 5, false},
+		{"empty list", `[]`, 1, 0, false},
+		{"null list", `null`, 1, 0, false},
+		{"malformed entry after the game found", `[{"game_id":"g1"},{"game_id":2}]`, 1, 1, false},
+		{"malformed entry before the game", `[{"game_id":"g1"},{"game_id":2},{"game_id":"g3"}]`, 3, 1, true},
+		{"truncated list", `[{"game_id":"g1"},{"game_id":"g2"`, 42, 1, true},
+		{"not an array", `{"game_id":"g1"}`, 1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newGamesServer(t, tt.body, nil)
+			listed, err := New(srv.URL, 5*time.Second).EachGame(findGame(tt.find))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if listed != tt.listed {
+				t.Errorf("listed %d games, want %d", listed, tt.listed)
+			}
+		})
+	}
+}
+
+// largeGamesJSON returns a games list of n games numbered from 1, each
+// with players seated players.
+func largeGamesJSON(n, players int) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i := 1; i <= n; i++ {
+		if i > 1 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"game_id":"g%d","timestamp":"2025-05-15T10:00:00Z","game_state":{"game_id":"g%d","players":[`, i, i)
+		for p := 0; p < players; p++ {
+			if p > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, `{"player_id":"team-%d-%d","chips":%d}`, i, p, 1000+p)
+		}
+		b.WriteString(`]}}`)
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// BenchmarkEachGame looks a game up in a list of 500 games of 8 players,
+// streaming with EachGame or decoding the whole list with Games.
+func BenchmarkEachGame(b *testing.B) {
+	const n = 500
+	srv := newGamesServer(b, largeGamesJSON(n, 8), nil)
+	c := New(srv.URL, 5*time.Second)
+	for _, at := range []int{50, n} {
+		b.Run(fmt.Sprintf("EachGame/game %d", at), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if listed, err := c.EachGame(findGame(at)); err != nil || listed != at {
+					b.Fatalf("EachGame = %d, %v", listed, err)
+				}
+			}
+		})
+	}
+	b.Run("Games", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			games, err := c.Games()
+			if err != nil || len(games) != n {
+				b.Fatalf("Games = %d games, %v", len(games), err)
+			}
+		}
+	})
+}
+
+func TestGameURLEscapesTheID(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+	c := New(srv.URL, time.Second)
+	tests := []struct{ gameID, wantPath string }{
+		{"g-1", "/games/g-1"},
+		{"a/b", "/games/a%2Fb"},
+		{"a b?c", "/games/a%20b%3Fc"},
+	}
+	for _, tt := range tests {
+		if got, want := c.GameURL(tt.gameID), srv.URL+tt.wantPath; got != want {
+			t.Errorf("GameURL(%q) = %q, want %q", tt.gameID, got, want)
+		}
+		// The API's detail URL escapes the ID the same way.
+		paths = nil
+		if _, err := c.Game(tt.gameID); err != nil {
+			t.Fatal(err)
+		}
+		if want := APIPrefix + tt.wantPath; len(paths) != 1 || paths[0] != want {
+			t.Errorf("Game(%q) requested %q, want %q", tt.gameID, paths, want)
+		}
+	}
+}
+
+func TestNotJSONAnswers(t *testing.T) {
+	page := "<!DOCTYPE html><html><body><h1>502 Bad Gateway</h1></body></html>"
+	long := "<html><body>" + strings.Repeat("upstream unavailable. ", 40) + "</body></html>"
+	tests := []struct {
+		name        string
+		contentType string // none if empty
+		body        string
+		wantErr     bool
+	}{
+		{"html page", "text/html; charset=utf-8", page, true},
+		{"html page without a content type", "", "\n  " + page, true},
+		{"long html page", "text/html", long, true},
+		{"long html page without a content type", "", long, true},
+		{"json without a content type", "", `[{"game_id":"g1"}]`, false},
+		{"json with a parameter", "application/json; charset=utf-8", `[{"game_id":"g1"}]`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType == "" {
+					w.Header()["Content-Type"] = nil // not sniffed
+				} else {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+			c := New(srv.URL, 5*time.Second)
+			u := c.APIURL("/games")
+
+			check := func(call string, err error) {
+				t.Helper()
+				if !tt.wantErr {
+					if err != nil {
+						t.Errorf("%s: %v", call, err)
+					}
+					return
+				}
+				var cte *ContentTypeError
+				if !errors.As(err, &cte) {
+					t.Fatalf("%s error = %v, want a *ContentTypeError", call, err)
+				}
+				want := tt.body
+				if len(want) > maxErrorBody {
+					want = want[:maxErrorBody]
+				}
+				if cte.URL != u || cte.StatusCode != http.StatusOK || cte.ContentType != tt.contentType {
+					t.Errorf("%s: error about %s, status %d, %q", call, cte.URL, cte.StatusCode, cte.ContentType)
+				}
+				if cte.Body != want || cte.Size != len(tt.body) {
+					t.Errorf("%s: error keeps %q of %d bytes, want %q of %d", call, cte.Body, cte.Size, want, len(tt.body))
+				}
+			}
+
+			var games []ListedGame
+			check("GetJSON", c.GetJSON(u, &games))
+			_, err := c.EachGame(func(ListedGame) bool { return false })
+			check("EachGame", err)
+
+			req, err := NewRequest(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.HTTP.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			size, err := Drain(u, resp)
+			check("Drain", err)
+			if size != int64(len(tt.body)) {
+				t.Errorf("Drain read %d bytes, want %d", size, len(tt.body))
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package httpapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Fixtures, when set, records the responses to the requests made through
+// Transport, or serves them from an earlier recording instead of the
+// network. cli sets it from -record-dir and -replay-dir.
+var Fixtures *FixtureStore
+
+// Modes of a FixtureStore.
+const (
+	FixtureRecord = "record"
+	FixtureReplay = "replay"
+)
+
+// scrubbedHeaders are left out of the recorded metadata: they may carry
+// credentials, and fixtures end up committed.
+var scrubbedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// FixtureStore is a directory of recorded responses, one per normalized
+// request: <name>.body holds the body as received, and <name>.meta.json
+// the request and the status and headers of the response. The name starts
+// with the request's path, readable in a listing, and ends with a hash of
+// the normalized request. A nil *FixtureStore neither records nor replays.
+type FixtureStore struct {
+	Dir  string
+	Mode string
+
+	recorded, replayed atomic.Int64
+}
+
+// fixtureMeta is the content of a .meta.json file.
+type fixtureMeta struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Key    string      `json:"key"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	// RequestHeader are the headers the request was sent with, scrubbed
+	// like the response's.
+	RequestHeader http.Header `json:"request_header,omitempty"`
+}
+
+// NewFixtureStore returns a store of dir in mode. Recording creates dir;
+// replaying needs it to exist.
+func NewFixtureStore(dir, mode string) (*FixtureStore, error) {
+	switch mode {
+	case FixtureRecord:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("creating the fixtures directory: %w", err)
+		}
+	case FixtureReplay:
+		if fi, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("reading the fixtures directory: %w", err)
+		} else if !fi.IsDir() {
+			return nil, fmt.Errorf("fixtures directory %s is not a directory", dir)
+		}
+	default:
+		return nil, fmt.Errorf("unknown fixtures mode %q", mode)
+	}
+	return &FixtureStore{Dir: dir, Mode: mode}, nil
+}
+
+// Replaying reports whether requests are served from the fixtures.
+func (s *FixtureStore) Replaying() bool {
+	return s != nil && s.Mode == FixtureReplay
+}
+
+// FixtureKey normalizes a request to the key of its fixture: the method,
+// the path and the query sorted by name. The scheme and host are left out,
+// so a recording replays whatever -base-url the later run is given.
+func FixtureKey(method string, u *url.URL) string {
+	key := method + " " + u.EscapedPath()
+	if q := u.Query(); len(q) > 0 {
+		key += "?" + q.Encode() // Encode sorts by name
+	}
+	return key
+}
+
+// fixtureName is the file name, without extension, of the fixture of key.
+func fixtureName(key string, u *url.URL) string {
+	sum := sha256.Sum256([]byte(key))
+	path := strings.Trim(strings.TrimPrefix(u.Path, APIPrefix), "/")
+	path = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, path)
+	if len(path) > 80 {
+		path = path[:80]
+	}
+	if path == "" {
+		path = "root"
+	}
+	return path + "-" + hex.EncodeToString(sum[:6])
+}
+
+// MissError is returned, in place of a response, by a replaying store that
+// has no fixture for a request.
+type MissError struct {
+	Key string
+	Dir string
+}
+
+func (e *MissError) Error() string {
+	return fmt.Sprintf("no recorded fixture for %s in %s (-replay-dir); record it with -record-dir against the live API", e.Key, e.Dir)
+}
+
+// replay returns the recorded response to req.
+func (s *FixtureStore) replay(req *http.Request) (*http.Response, error) {
+	key := FixtureKey(req.Method, req.URL)
+	base := filepath.Join(s.Dir, fixtureName(key, req.URL))
+	raw, err := os.ReadFile(base + ".meta.json")
+	if os.IsNotExist(err) {
+		return nil, &MissError{Key: key, Dir: s.Dir}
+	} else if err != nil {
+		return nil, fmt.Errorf("reading fixture: %w", err)
+	}
+	var meta fixtureMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("reading fixture %s.meta.json: %w", base, err)
+	}
+	if meta.Key != key {
+		return nil, fmt.Errorf("fixture %s.meta.json is of %s, not %s", base, meta.Key, key)
+	}
+	body, err := os.ReadFile(base + ".body")
+	if err != nil {
+		return nil, fmt.Errorf("reading fixture: %w", err)
+	}
+	s.replayed.Add(1)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", meta.Status, http.StatusText(meta.Status)),
+		StatusCode:    meta.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        meta.Header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// record saves resp, the response to req, and returns it with a body that
+// reads again from the start.
+func (s *FixtureStore) record(req *http.Request, resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	key := FixtureKey(req.Method, req.URL)
+	base := filepath.Join(s.Dir, fixtureName(key, req.URL))
+	meta := fixtureMeta{
+		Method:        req.Method,
+		URL:           req.URL.Redacted(),
+		Key:           key,
+		Status:        resp.StatusCode,
+		Header:        scrub(resp.Header),
+		RequestHeader: scrub(req.Header),
+	}
+	raw, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(base+".body", body, 0o644); err != nil {
+		return nil, fmt.Errorf("recording fixture: %w", err)
+	}
+	if err := os.WriteFile(base+".meta.json", append(raw, '\n'), 0o644); err != nil {
+		return nil, fmt.Errorf("recording fixture: %w", err)
+	}
+	s.recorded.Add(1)
+	return resp, nil
+}
+
+// scrub returns a copy of h without the headers that may carry credentials.
+func scrub(h http.Header) http.Header {
+	c := h.Clone()
+	for _, name := range scrubbedHeaders {
+		c.Del(name)
+	}
+	if len(c) == 0 {
+		return nil
+	}
+	return c
+}
+
+// Print writes how many responses were recorded or replayed.
+func (s *FixtureStore) Print(w io.Writer) {
+	if s == nil {
+		return
+	}
+	if s.Mode == FixtureRecord {
+		fmt.Fprintf(w, "HTTP fixtures recorded to %s: %d\n", s.Dir, s.recorded.Load())
+	} else {
+		fmt.Fprintf(w, "HTTP fixtures replayed from %s: %d\n", s.Dir, s.replayed.Load())
+	}
+}
+
+// Fill records the fixtures directory and the responses recorded or
+// replayed in rep.
+func (s *FixtureStore) Fill(rep *report.Report) {
+	if s == nil {
+		return
+	}
+	rep.Details["http_fixtures_"+s.Mode] = s.Dir
+	rep.Counters["http_fixtures_recorded"] = s.recorded.Load()
+	rep.Counters["http_fixtures_replayed"] = s.replayed.Load()
+}
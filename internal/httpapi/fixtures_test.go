@@ -0,0 +1,82 @@
+package httpapi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFixtureRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewFixtureStore(dir, FixtureRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("GET", "http://live.example:8080/api/v0/leaderboard?limit=5&epoch=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("Accept", "application/json")
+	resp := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": {"application/json"}, "Set-Cookie": {"session=secret-cookie"}},
+		Body:       io.NopCloser(strings.NewReader(`{"entries":[]}`)),
+	}
+	resp, err = rec.record(req, resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != `{"entries":[]}` {
+		t.Errorf("the recorded response reads %q", body)
+	}
+
+	metas, _ := filepath.Glob(filepath.Join(dir, "*.meta.json"))
+	if len(metas) != 1 {
+		t.Fatalf("recorded %d metadata files, want 1", len(metas))
+	}
+	meta, err := os.ReadFile(metas[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-token", "secret-cookie", "Authorization", "Set-Cookie"} {
+		if strings.Contains(string(meta), secret) {
+			t.Errorf("the recorded metadata holds %q:\n%s", secret, meta)
+		}
+	}
+
+	play, err := NewFixtureStore(dir, FixtureReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same request to another host, with its query reordered.
+	again, _ := http.NewRequest("GET", "http://replay.invalid/api/v0/leaderboard?epoch=2&limit=5", nil)
+	replayed, err := play.replay(again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(replayed.Body)
+	if replayed.StatusCode != 200 || string(body) != `{"entries":[]}` || replayed.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("replayed %d %v %q", replayed.StatusCode, replayed.Header, body)
+	}
+
+	other, _ := http.NewRequest("GET", "http://replay.invalid/api/v0/leaderboard?limit=6", nil)
+	_, err = play.replay(other)
+	var miss *MissError
+	if !errors.As(err, &miss) || miss.Key != "GET /api/v0/leaderboard?limit=6" {
+		t.Errorf("replaying an unrecorded request = %v, want a *MissError for its key", err)
+	}
+}
+
+func TestNewFixtureStoreErrors(t *testing.T) {
+	if _, err := NewFixtureStore(filepath.Join(t.TempDir(), "missing"), FixtureReplay); err == nil {
+		t.Error("replaying from a missing directory succeeded")
+	}
+	if _, err := NewFixtureStore(t.TempDir(), "rewind"); err == nil {
+		t.Error("an unknown mode was accepted")
+	}
+}
@@ -0,0 +1,237 @@
+package httpapi
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"elastic-ai-jam-2025/internal/clock"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// SharedGames, when set, shares the games list between the callers of
+// Client.SharedGames in this process, so tools polling it together send one
+// request instead of one each. cli sets it from -games-cache-ttl.
+var SharedGames *GamesCache
+
+// GamesList is a games list fetched through a GamesCache. Its Games are
+// shared with the other callers and must not be modified.
+type GamesList struct {
+	Games []ListedGame
+	// Fetched is when the list was received.
+	Fetched time.Time
+	// Shared is set when the list was fetched for another caller: taken
+	// from the cache, or from a request in flight the caller joined.
+	Shared bool
+}
+
+// GamesCache keeps the last games list of each base URL for TTL, and makes
+// the callers asking for a list while it is being fetched wait for that
+// request rather than send their own. A fetch streams the list like
+// Client.EachGame: it stops where its caller found what it wanted, unless
+// other callers wait for it, and the part read is kept for the callers that
+// find what they want in it. Failed fetches are shared with the callers
+// waiting for them, but not cached. It is safe for concurrent use.
+type GamesCache struct {
+	// Clock tells the age of the lists; NewGamesCache sets it to
+	// clock.Real.
+	Clock clock.Clock
+
+	mu    sync.Mutex
+	ttl   time.Duration
+	lists map[string]*gamesFetch
+
+	fetches   atomic.Int64
+	hits      atomic.Int64
+	coalesced atomic.Int64
+}
+
+// gamesFetch is a games list request, in flight until done is closed.
+// complete is set when games is the whole list. waiters counts the callers
+// waiting for it, under the cache's lock.
+type gamesFetch struct {
+	done     chan struct{}
+	games    []ListedGame
+	complete bool
+	fetched  time.Time
+	err      error
+	waiters  int
+}
+
+// errFetchPanicked is what the callers waiting for a fetch get when it
+// panicked.
+var errFetchPanicked = errors.New("fetching the games list panicked")
+
+// NewGamesCache returns a cache keeping lists for ttl, or nil when ttl is
+// not positive.
+func NewGamesCache(ttl time.Duration) *GamesCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &GamesCache{Clock: clock.Real, ttl: ttl, lists: make(map[string]*gamesFetch)}
+}
+
+// SetTTL changes how long the lists are kept.
+func (g *GamesCache) SetTTL(ttl time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.ttl = ttl
+}
+
+// Get returns the whole games list of c, from the cache when it is at most
+// maxAge old, or the cache's TTL when maxAge is not positive or longer. A
+// list being fetched is waited for whatever maxAge, being as fresh as any.
+func (g *GamesCache) Get(c *Client, maxAge time.Duration) (GamesList, error) {
+	f, _, shared, err := g.each(c, maxAge, func(ListedGame) bool { return false })
+	if err != nil {
+		return GamesList{}, err
+	}
+	return GamesList{Games: f.games, Fetched: f.fetched, Shared: shared}, nil
+}
+
+// Each calls fn with the games of c's list in the order listed until fn
+// returns true, like Client.EachGame, taking the list from the cache as Get
+// does. It returns the number of games fn was called with, and whether the
+// list came from another caller's request. A list another caller cut short
+// that does not hold what fn wants is fetched again, whole, so fn may see
+// its first games twice.
+func (g *GamesCache) Each(c *Client, maxAge time.Duration, fn func(ListedGame) (done bool)) (listed int, shared bool, err error) {
+	_, listed, shared, err = g.each(c, maxAge, fn)
+	return listed, shared, err
+}
+
+func (g *GamesCache) each(c *Client, maxAge time.Duration, fn func(ListedGame) bool) (*gamesFetch, int, bool, error) {
+	key := c.APIURL("/games")
+	whole := false
+	for {
+		g.mu.Lock()
+		if maxAge <= 0 || maxAge > g.ttl {
+			maxAge = g.ttl
+		}
+		f := g.lists[key]
+		if f == nil {
+			break
+		}
+		select {
+		case <-f.done:
+			if g.Clock.Now().Sub(f.fetched) > maxAge || whole && !f.complete {
+				break
+			}
+			g.mu.Unlock()
+			if n, ok := walk(f.games, fn); ok || f.complete {
+				g.hits.Add(1)
+				return f, n, true, nil
+			}
+			whole = true
+			continue
+		default:
+			f.waiters++
+			g.mu.Unlock()
+			g.coalesced.Add(1)
+			<-f.done
+			n, ok := walk(f.games, fn)
+			switch {
+			case ok:
+				return f, n, true, nil
+			case f.complete || f.err != nil:
+				return f, n, true, f.err
+			}
+			whole = true
+			continue
+		}
+		break
+	}
+	f := &gamesFetch{done: make(chan struct{})}
+	g.lists[key] = f
+	g.mu.Unlock()
+	g.fetches.Add(1)
+	n, err := g.fetch(c, key, f, fn, whole)
+	return f, n, false, err
+}
+
+// fetch streams the list of c into f, calling fn with each game until it
+// returns true. It reads on once fn is done when whole is set or callers
+// wait for f. An error past the game fn was done at is f's, not fn's.
+func (g *GamesCache) fetch(c *Client, key string, f *gamesFetch, fn func(ListedGame) bool, whole bool) (listed int, err error) {
+	finished := false
+	defer func() {
+		if !finished {
+			f.err = errFetchPanicked
+		}
+		if f.err != nil {
+			g.mu.Lock()
+			if g.lists[key] == f {
+				delete(g.lists, key)
+			}
+			g.mu.Unlock()
+		}
+		close(f.done)
+	}()
+	done, stopped := false, false
+	_, f.err = c.EachGame(func(game ListedGame) bool {
+		f.games = append(f.games, game)
+		if !done {
+			listed++
+			done = fn(game)
+		}
+		if done && !whole {
+			g.mu.Lock()
+			stopped = f.waiters == 0
+			g.mu.Unlock()
+		}
+		return stopped
+	})
+	f.fetched = g.Clock.Now()
+	f.complete = f.err == nil && !stopped
+	finished = true
+	if done {
+		return listed, nil
+	}
+	return listed, f.err
+}
+
+// walk calls fn with games until it returns true, and returns the number of
+// games it was called with and whether it did.
+func walk(games []ListedGame, fn func(ListedGame) bool) (int, bool) {
+	for i, game := range games {
+		if fn(game) {
+			return i + 1, true
+		}
+	}
+	return len(games), false
+}
+
+// Fill adds the games_cache_fetches, games_cache_hits and
+// games_cache_coalesced counters to rep, once the cache was used.
+func (g *GamesCache) Fill(rep *report.Report) {
+	if g == nil || g.fetches.Load() == 0 {
+		return
+	}
+	rep.Counters["games_cache_fetches"] = g.fetches.Load()
+	rep.Counters["games_cache_hits"] = g.hits.Load()
+	rep.Counters["games_cache_coalesced"] = g.coalesced.Load()
+}
+
+// SharedGames returns the whole games list through SharedGames, at most
+// maxAge old as GamesCache.Get tells, or fetches it when SharedGames is not
+// set.
+func (c *Client) SharedGames(maxAge time.Duration) (GamesList, error) {
+	if SharedGames == nil {
+		games, err := c.Games()
+		return GamesList{Games: games, Fetched: time.Now()}, err
+	}
+	return SharedGames.Get(c, maxAge)
+}
+
+// EachSharedGame walks the games list through SharedGames, as
+// GamesCache.Each does, or streams it with EachGame when SharedGames is not
+// set. requested reports whether the call sent a request of its own.
+func (c *Client) EachSharedGame(maxAge time.Duration, fn func(ListedGame) (done bool)) (listed int, requested bool, err error) {
+	if SharedGames == nil {
+		listed, err = c.EachGame(fn)
+		return listed, true, err
+	}
+	listed, shared, err := SharedGames.Each(c, maxAge, fn)
+	return listed, !shared, err
+}
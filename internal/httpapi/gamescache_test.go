@@ -0,0 +1,239 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/clock"
+)
+
+// gamesServer serves body as the games list, counting the requests. Each
+// answer waits for release when it is set.
+type gamesServer struct {
+	*httptest.Server
+	hits    atomic.Int64
+	release chan struct{}
+}
+
+func newGamesServer(t testing.TB, body string, release chan struct{}) *gamesServer {
+	t.Helper()
+	s := &gamesServer{release: release}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != APIPrefix+"/games" {
+			http.NotFound(w, r)
+			return
+		}
+		s.hits.Add(1)
+		if s.release != nil {
+			<-s.release
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// gamesJSON returns a games list of n games numbered from 1.
+func gamesJSON(n int) string {
+	entries := make([]string, n)
+	for i := range entries {
+		entries[i] = fmt.Sprintf(`{"game_id":"g%d","game_state":{"players":[]}}`, i+1)
+	}
+	return "[" + strings.Join(entries, ",") + "]"
+}
+
+// findGame returns an Each callback done at game gn.
+func findGame(n int) func(ListedGame) bool {
+	id := fmt.Sprintf("g%d", n)
+	return func(g ListedGame) bool { return g.GameID == id }
+}
+
+func TestGamesCacheCoalescesConcurrentCallers(t *testing.T) {
+	release := make(chan struct{})
+	srv := newGamesServer(t, gamesJSON(5), release)
+	cache := NewGamesCache(time.Minute)
+	c := New(srv.URL, 5*time.Second)
+
+	const callers = 20
+	var wg sync.WaitGroup
+	lists := make([]GamesList, callers)
+	errs := make([]error, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lists[i], errs[i] = cache.Get(c, 0)
+		}()
+	}
+	// Hold the answer until every caller either sent the request or waits
+	// for it.
+	for cache.fetches.Load()+cache.coalesced.Load() < callers {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := srv.hits.Load(); n != 1 {
+		t.Errorf("%d callers sent %d requests, want 1", callers, n)
+	}
+	shared := 0
+	for i := range callers {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: %v", i, errs[i])
+		}
+		if len(lists[i].Games) != 5 {
+			t.Errorf("caller %d got %d games, want 5", i, len(lists[i].Games))
+		}
+		if lists[i].Shared {
+			shared++
+		}
+	}
+	if shared != callers-1 {
+		t.Errorf("%d lists were shared, want %d", shared, callers-1)
+	}
+
+	if _, err := cache.Get(c, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.hits.Load(); n != 1 {
+		t.Errorf("a cached list was fetched again: %d requests", n)
+	}
+	if n := cache.hits.Load(); n != 1 {
+		t.Errorf("hits = %d, want 1", n)
+	}
+}
+
+func TestGamesCacheExpires(t *testing.T) {
+	srv := newGamesServer(t, gamesJSON(2), nil)
+	fake := clock.NewFake(time.Unix(0, 0))
+	cache := NewGamesCache(time.Minute)
+	cache.Clock = fake
+	c := New(srv.URL, 5*time.Second)
+
+	get := func() GamesList {
+		t.Helper()
+		list, err := cache.Get(c, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return list
+	}
+	get()
+	fake.Advance(time.Minute)
+	if list := get(); !list.Shared || srv.hits.Load() != 1 {
+		t.Errorf("a list as old as the TTL was fetched again")
+	}
+	fake.Advance(time.Second)
+	if list := get(); list.Shared || srv.hits.Load() != 2 {
+		t.Errorf("a list older than the TTL was not fetched again")
+	}
+	fake.Advance(10 * time.Second)
+	if _, err := cache.Get(c, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.hits.Load(); n != 3 {
+		t.Errorf("a list older than maxAge was taken from the cache: %d requests, want 3", n)
+	}
+}
+
+func TestGamesCacheEachStopsEarly(t *testing.T) {
+	srv := newGamesServer(t, gamesJSON(5), nil)
+	cache := NewGamesCache(time.Minute)
+	c := New(srv.URL, 5*time.Second)
+
+	listed, shared, err := cache.Each(c, 0, findGame(2))
+	if err != nil || listed != 2 || shared {
+		t.Fatalf("Each = %d, %v, %v; want 2, false, nil", listed, shared, err)
+	}
+
+	// The first two games were kept: finding one of them takes no request.
+	listed, shared, err = cache.Each(c, 0, findGame(1))
+	if err != nil || listed != 1 || !shared {
+		t.Fatalf("Each = %d, %v, %v; want 1, true, nil", listed, shared, err)
+	}
+	if n := srv.hits.Load(); n != 1 {
+		t.Fatalf("a game of the partial list took a request: %d requests", n)
+	}
+
+	// A game past them takes the whole list, which is then kept.
+	if _, _, err := cache.Each(c, 0, findGame(5)); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.hits.Load(); n != 2 {
+		t.Fatalf("%d requests for a game past the partial list, want 2", n)
+	}
+	listed, shared, err = cache.Each(c, 0, findGame(42))
+	if err != nil || listed != 5 || !shared {
+		t.Errorf("Each for a missing game = %d, %v, %v; want 5, true, nil", listed, shared, err)
+	}
+	if n := srv.hits.Load(); n != 2 {
+		t.Errorf("a missing game of the whole list took a request: %d requests", n)
+	}
+}
+
+func TestGamesCacheMalformedEntryAfterFoundGame(t *testing.T) {
+	srv := newGamesServer(t, `[{"game_id":"g1","game_state":{"players":[]}},{"game_id":2}]`, nil)
+	cache := NewGamesCache(time.Minute)
+	c := New(srv.URL, 5*time.Second)
+
+	listed, _, err := cache.Each(c, 0, findGame(1))
+	if err != nil || listed != 1 {
+		t.Fatalf("Each = %d, %v; want 1, nil", listed, err)
+	}
+	if _, _, err := cache.Each(c, 0, findGame(3)); err == nil {
+		t.Error("walking past the malformed entry succeeded")
+	}
+	if _, err := cache.Get(c, 0); err == nil {
+		t.Error("Get succeeded on a list with a malformed entry")
+	}
+}
+
+func TestGamesCachePanickingFetchReleasesWaiters(t *testing.T) {
+	release := make(chan struct{})
+	srv := newGamesServer(t, gamesJSON(3), release)
+	cache := NewGamesCache(time.Minute)
+	c := New(srv.URL, 5*time.Second)
+
+	panicked := make(chan any)
+	go func() {
+		defer func() { panicked <- recover() }()
+		cache.Each(c, 0, func(ListedGame) bool { panic("boom") })
+	}()
+	for srv.hits.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	waited := make(chan error)
+	go func() {
+		_, err := cache.Get(c, 0)
+		waited <- err
+	}()
+	for cache.coalesced.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	if r := <-panicked; r == nil {
+		t.Fatal("the callback's panic was swallowed")
+	}
+	select {
+	case err := <-waited:
+		if !errors.Is(err, errFetchPanicked) {
+			t.Errorf("the waiter got %v, want %v", err, errFetchPanicked)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the waiter is still blocked on the panicked fetch")
+	}
+
+	// The failed fetch was not cached.
+	if list, err := cache.Get(c, 0); err != nil || len(list.Games) != 3 {
+		t.Errorf("Get after the panic = %d games, %v; want 3, nil", len(list.Games), err)
+	}
+}
@@ -0,0 +1,136 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Capture, when set, records the configured headers of every response to a
+// request made through Transport. cli sets it from -capture-headers.
+var Capture *HeaderRecorder
+
+// headerSamples is how many distinct values of a non-numeric header are
+// kept per status class.
+const headerSamples = 3
+
+// HeaderRecorder aggregates a few response headers, such as rate-limit
+// counters and request IDs, that are worth reporting to the organizers.
+// Numeric values are summarized by their minimum, maximum and last value
+// and when they first reached zero; other values are sampled per status
+// class. It is safe for concurrent use; a nil *HeaderRecorder records
+// nothing.
+type HeaderRecorder struct {
+	// keys are the canonical keys of the captured headers, and names the
+	// names they were configured and are reported with.
+	keys  []string
+	names []string
+
+	mu      sync.Mutex
+	signals map[string]*report.HeaderSignal
+}
+
+// NewHeaderRecorder returns a recorder of the headers names, or nil if
+// there are none.
+func NewHeaderRecorder(names []string) *HeaderRecorder {
+	r := &HeaderRecorder{signals: make(map[string]*report.HeaderSignal)}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if key := textproto.CanonicalMIMEHeaderKey(name); key != "" && !slices.Contains(r.keys, key) {
+			r.keys = append(r.keys, key)
+			r.names = append(r.names, name)
+		}
+	}
+	if len(r.keys) == 0 {
+		return nil
+	}
+	return r
+}
+
+// Observe records the captured headers of resp. Only the configured keys
+// are looked up.
+func (r *HeaderRecorder) Observe(resp *http.Response) {
+	if r == nil {
+		return
+	}
+	for i, key := range r.keys {
+		values := resp.Header[key]
+		if len(values) == 0 {
+			continue
+		}
+		name := r.names[i]
+		value := values[0]
+		if slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+			slog.Debug("response header", "header", name, "value", value, "status", resp.StatusCode, "url", resp.Request.URL.String())
+		}
+		r.record(name, value, resp.StatusCode)
+	}
+}
+
+func (r *HeaderRecorder) record(name, value string, status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.signals[name]
+	if s == nil {
+		s = &report.HeaderSignal{}
+		r.signals[name] = s
+	}
+	s.Seen++
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		if s.Numeric == 0 {
+			s.Min, s.Max, s.Last = ptr(n), ptr(n), ptr(n)
+		} else {
+			*s.Min, *s.Max, *s.Last = min(*s.Min, n), max(*s.Max, n), n
+		}
+		s.Numeric++
+		if n <= 0 {
+			s.AtZero++
+			if s.FirstZero == "" {
+				s.FirstZero = time.Now().UTC().Format(time.RFC3339Nano)
+			}
+		}
+		return
+	}
+	class := fmt.Sprintf("%dxx", status/100)
+	if len(s.Samples[class]) < headerSamples && !slices.Contains(s.Samples[class], value) {
+		if s.Samples == nil {
+			s.Samples = make(map[string][]string)
+		}
+		s.Samples[class] = append(s.Samples[class], value)
+	}
+}
+
+// Fill records the headers seen in rep.
+func (r *HeaderRecorder) Fill(rep *report.Report) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.signals) == 0 {
+		return
+	}
+	rep.ResponseHeaders = make(map[string]report.HeaderSignal, len(r.signals))
+	for name, s := range r.signals {
+		c := *s
+		if s.Numeric > 0 {
+			c.Min, c.Max, c.Last = ptr(*s.Min), ptr(*s.Max), ptr(*s.Last)
+		}
+		c.Samples = make(map[string][]string, len(s.Samples))
+		for class, values := range s.Samples {
+			c.Samples[class] = slices.Clone(values)
+		}
+		rep.ResponseHeaders[name] = c
+	}
+}
+
+func ptr(f float64) *float64 { return &f }
@@ -0,0 +1,120 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/report"
+)
+
+func TestNewHeaderRecorder(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  []string
+	}{
+		{nil, nil},
+		{[]string{"", " "}, nil},
+		{[]string{"x-request-id"}, []string{"x-request-id"}},
+		{[]string{" X-RateLimit-Remaining ", "x-ratelimit-remaining", "X-Request-Id"}, []string{"X-RateLimit-Remaining", "X-Request-Id"}},
+	}
+	for _, tt := range tests {
+		r := NewHeaderRecorder(tt.names)
+		if tt.want == nil {
+			if r != nil {
+				t.Errorf("NewHeaderRecorder(%q) = %q, want nil", tt.names, r.names)
+			}
+			continue
+		}
+		if r == nil || !reflect.DeepEqual(r.names, tt.want) {
+			t.Errorf("NewHeaderRecorder(%q) records %v, want %q", tt.names, r, tt.want)
+		}
+	}
+}
+
+// TestCaptureHeaders counts a rate limit down from 3 on a server that
+// answers every third request with a 503, and checks what the report gets.
+func TestCaptureHeaders(t *testing.T) {
+	var n atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := n.Add(1)
+		w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(3-i))
+		w.Header().Set("X-Request-Id", fmt.Sprintf("req-%d", i))
+		w.Header().Set("X-Not-Captured", "1")
+		if i%3 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	defer func(c *HeaderRecorder) { Capture = c }(Capture)
+	Capture = NewHeaderRecorder([]string{"x-ratelimit-remaining", "X-Request-Id", "X-Absent"})
+
+	client := &http.Client{Transport: Transport(nil), Timeout: 5 * time.Second}
+	for range 6 {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	var rep report.Report
+	Capture.Fill(&rep)
+
+	if len(rep.ResponseHeaders) != 2 {
+		t.Fatalf("headers reported: %+v, want the two the server sent", rep.ResponseHeaders)
+	}
+	limit := rep.ResponseHeaders["x-ratelimit-remaining"]
+	if limit.FirstZero == "" {
+		t.Error("the first time the limit reached zero is missing")
+	}
+	limit.FirstZero = ""
+	want := report.HeaderSignal{Seen: 6, Numeric: 6, Min: ptr(-3), Max: ptr(2), Last: ptr(-3), AtZero: 4, Samples: map[string][]string{}}
+	if !reflect.DeepEqual(limit, want) {
+		t.Errorf("rate limit = %s, want %s", signal(limit), signal(want))
+	}
+	ids := rep.ResponseHeaders["X-Request-Id"]
+	want = report.HeaderSignal{Seen: 6, Samples: map[string][]string{
+		"2xx": {"req-1", "req-2", "req-4"},
+		"5xx": {"req-3", "req-6"},
+	}}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("request IDs = %s, want %s", signal(ids), signal(want))
+	}
+
+	// The report holds copies: later responses do not change it.
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if *rep.ResponseHeaders["x-ratelimit-remaining"].Last != -3 {
+		t.Error("a response after Fill changed the report")
+	}
+}
+
+func TestCaptureDisabled(t *testing.T) {
+	var r *HeaderRecorder
+	r.Observe(&http.Response{Header: http.Header{"X-Request-Id": {"req-1"}}})
+	var rep report.Report
+	r.Fill(&rep)
+	NewHeaderRecorder([]string{"X-Request-Id"}).Fill(&rep)
+	if rep.ResponseHeaders != nil {
+		t.Errorf("headers reported without a capture: %+v", rep.ResponseHeaders)
+	}
+}
+
+// signal formats s with the values of its pointers.
+func signal(s report.HeaderSignal) string {
+	f := func(p *float64) string {
+		if p == nil {
+			return "nil"
+		}
+		return fmt.Sprint(*p)
+	}
+	return fmt.Sprintf("{seen %d numeric %d min %s max %s last %s at zero %d samples %v}",
+		s.Seen, s.Numeric, f(s.Min), f(s.Max), f(s.Last), s.AtZero, s.Samples)
+}
@@ -0,0 +1,119 @@
+package httpapi
+
+import (
+	"crypto/tls"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/latency"
+)
+
+// Slowest, when set, keeps the slowest requests of the clients using
+// Transport, each timed from sending it to closing its response body.
+// TracePhases splits their latency into phases, at the cost of tracing
+// every request.
+var (
+	Slowest     *latency.Slowest
+	TracePhases bool
+)
+
+// phaseTrace records when a traced request reached each phase.
+type phaseTrace struct {
+	mu                   sync.Mutex
+	dnsStart, dnsDone    time.Time
+	connStart, connDone  time.Time
+	tlsStart, tlsDone    time.Time
+	gotConn, wrote, resp time.Time
+}
+
+// traced returns req traced when Slowest is set and TracePhases asks for
+// phases.
+func traced(req *http.Request) (*http.Request, *phaseTrace) {
+	if Slowest == nil || !TracePhases {
+		return req, nil
+	}
+	p := &phaseTrace{}
+	at := func(t *time.Time) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if t.IsZero() {
+			*t = time.Now()
+		}
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { at(&p.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { at(&p.dnsDone) },
+		ConnectStart:         func(string, string) { at(&p.connStart) },
+		ConnectDone:          func(string, string, error) { at(&p.connDone) },
+		TLSHandshakeStart:    func() { at(&p.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { at(&p.tlsDone) },
+		GotConn:              func(httptrace.GotConnInfo) { at(&p.gotConn) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { at(&p.wrote) },
+		GotFirstResponseByte: func() { at(&p.resp) },
+	})), p
+}
+
+// phases splits a request that ended at end into the phases it went
+// through.
+func (p *phaseTrace) phases(end time.Time) map[string]float64 {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make(map[string]float64)
+	span := func(name string, from, to time.Time) {
+		if !from.IsZero() && !to.IsZero() && to.After(from) {
+			out[name] = math.Round(float64(to.Sub(from))/float64(time.Microsecond)) / 1000
+		}
+	}
+	span("dns", p.dnsStart, p.dnsDone)
+	span("connect", p.connStart, p.connDone)
+	span("tls", p.tlsStart, p.tlsDone)
+	span("send", p.gotConn, p.wrote)
+	span("wait", p.wrote, p.resp)
+	span("body", p.resp, end)
+	return out
+}
+
+// timed offers the request sent at sent to Slowest: at once when it got
+// no response, and otherwise when resp's body is closed. It returns resp,
+// its body wrapped as needed.
+func timed(req *http.Request, sent time.Time, trace *phaseTrace, resp *http.Response, err error) *http.Response {
+	if Slowest == nil {
+		return resp
+	}
+	offer := func(status int, err error) {
+		end := time.Now()
+		Slowest.Offer(end.Sub(sent), func() latency.Request {
+			r := latency.Request{At: sent, URL: req.URL.Redacted(), Status: status, PhasesMs: trace.phases(end)}
+			if err != nil {
+				r.Error = err.Error()
+			}
+			return r
+		})
+	}
+	if err != nil {
+		offer(0, err)
+		return resp
+	}
+	resp.Body = &timedBody{ReadCloser: resp.Body, done: func() { offer(resp.StatusCode, nil) }}
+	return resp
+}
+
+// timedBody calls done once, when the body is first closed.
+type timedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *timedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
@@ -0,0 +1,137 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// --- Structs for /api/v0/leaderboard ---
+
+type LeaderboardEntry struct {
+	PlayerID  string `json:"player_id"`
+	Chips     int    `json:"chips"`
+	MaxChips  int    `json:"max_chips"`
+	Epoch     int    `json:"epoch"`
+	GameCount int    `json:"game_count"`
+}
+
+type LeaderboardResponse struct {
+	Entries []LeaderboardEntry `json:"entries"`
+}
+
+// --- Structs for /api/v0/players/{playerID}/games ---
+
+type PlayerGameUser struct {
+	Username   string `json:"username"`
+	GameID     string `json:"game_id"`
+	ChipsDelta int    `json:"chips_delta"`
+}
+
+type PlayerGameDetail struct {
+	GameID    string                 `json:"game_id"`
+	Type      string                 `json:"type"`
+	Timestamp string                 `json:"timestamp"`
+	GameState map[string]interface{} `json:"game_state"`
+}
+
+type PlayerGame struct {
+	User PlayerGameUser   `json:"user"`
+	Game PlayerGameDetail `json:"game"`
+}
+
+type PlayerGamesResponse struct {
+	Games []PlayerGame `json:"games"`
+}
+
+// --- Structs for /api/v0/games ---
+
+type ListedPlayer struct {
+	PlayerID string `json:"player_id"`
+	Chips    int    `json:"chips"`
+}
+
+type ListedGameState struct {
+	GameID  string         `json:"game_id"` // game_id is often duplicated here
+	Players []ListedPlayer `json:"players"`
+}
+
+type ListedGame struct {
+	GameID    string          `json:"game_id"`
+	GameState ListedGameState `json:"game_state"`
+	Timestamp string          `json:"timestamp"`
+}
+
+// --- Structs for /api/v0/games/{gameID} ---
+
+// GameDetailState is the part of a game's state the tools use. Pots and
+// winners are kept raw, since only their presence is relied upon.
+type GameDetailState struct {
+	GameID  string          `json:"game_id"`
+	Players []ListedPlayer  `json:"players"`
+	Pots    json.RawMessage `json:"pots,omitempty"`
+	Winners json.RawMessage `json:"winners,omitempty"`
+}
+
+type GameDetail struct {
+	GameID    string          `json:"game_id"`
+	GameState GameDetailState `json:"game_state"`
+	Timestamp string          `json:"timestamp"`
+}
+
+// --- Typed parts of a game_state ---
+
+// HandState is the part of a game_state describing the cards: the players,
+// with their hole cards when they were shown, and the community cards.
+// Games do not always carry the cards.
+type HandState struct {
+	Players []HandPlayer `json:"players"`
+	// Table holds the community cards.
+	Table   []string        `json:"table,omitempty"`
+	Pots    json.RawMessage `json:"pots,omitempty"`
+	Winners json.RawMessage `json:"winners,omitempty"`
+}
+
+// HandPlayer is a player of a HandState. Hand holds its hole cards, only
+// present when they were shown.
+type HandPlayer struct {
+	PlayerID string   `json:"player_id"`
+	Chips    int      `json:"chips"`
+	Hand     []string `json:"hand,omitempty"`
+}
+
+// HandState decodes the typed part of the game's state.
+func (g *PlayerGameDetail) HandState() (*HandState, error) {
+	data, err := json.Marshal(g.GameState)
+	if err != nil {
+		return nil, err
+	}
+	var s HandState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("decoding game_state of game %s: %w", g.GameID, err)
+	}
+	return &s, nil
+}
+
+// WinnerIDs returns the players named in a winners field. The field is not
+// typed by the API docs, so both a list of player IDs and a list of objects
+// with a player_id are accepted; any other shape gives nil.
+func WinnerIDs(winners json.RawMessage) []string {
+	var ids []string
+	if json.Unmarshal(winners, &ids) == nil {
+		return ids
+	}
+	// A failed decoding leaves what it could of the list in ids.
+	ids = nil
+	var objs []struct {
+		PlayerID string `json:"player_id"`
+	}
+	if json.Unmarshal(winners, &objs) != nil {
+		return nil
+	}
+	for _, o := range objs {
+		if o.PlayerID != "" {
+			ids = append(ids, o.PlayerID)
+		}
+	}
+	return ids
+}
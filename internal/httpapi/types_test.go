@@ -0,0 +1,28 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestWinnerIDs(t *testing.T) {
+	tests := []struct {
+		winners string
+		want    []string
+	}{
+		{`["a","b"]`, []string{"a", "b"}},
+		{`[{"player_id":"a"},{"player_id":"b"}]`, []string{"a", "b"}},
+		{`[{"player_id":"a"},{"chips":10}]`, []string{"a"}},
+		{`[]`, []string{}},
+		{`null`, nil},
+		{``, nil},
+		{`"a"`, nil},
+		{`{"player_id":"a"}`, nil},
+	}
+	for _, tt := range tests {
+		if got := WinnerIDs(json.RawMessage(tt.winners)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("WinnerIDs(%s) = %q, want %q", tt.winners, got, tt.want)
+		}
+	}
+}
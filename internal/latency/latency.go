@@ -0,0 +1,198 @@
+// Package latency records durations into a fixed log-scale histogram.
+//
+// Recording is a single atomic increment, so workers can share a Histogram at
+// any request rate; percentiles are approximate (within ~5%).
+package latency
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	minLatency  = 100 * time.Microsecond
+	growth      = 1.05
+	bucketCount = 320 // covers up to ~100 minutes
+)
+
+// bucketBounds[i] is the upper bound of bucket i.
+var bucketBounds = func() [bucketCount]time.Duration {
+	var b [bucketCount]time.Duration
+	v := float64(minLatency)
+	for i := range b {
+		b[i] = time.Duration(v)
+		v *= growth
+	}
+	return b
+}()
+
+// Histogram is a concurrency-safe latency histogram. The zero value is ready
+// to use.
+type Histogram struct {
+	buckets [bucketCount + 1]atomic.Int64 // last bucket is overflow
+	count   atomic.Int64
+	sum     atomic.Int64
+	max     atomic.Int64
+}
+
+func bucketFor(d time.Duration) int {
+	if d <= minLatency {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(float64(d)/float64(minLatency)) / math.Log(growth)))
+	if i >= bucketCount {
+		return bucketCount
+	}
+	// Guard against floating point rounding at bucket edges.
+	for i > 0 && d <= bucketBounds[i-1] {
+		i--
+	}
+	for i < bucketCount && d > bucketBounds[i] {
+		i++
+	}
+	return i
+}
+
+// Record adds one observation.
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.buckets[bucketFor(d)].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(d))
+	for {
+		cur := h.max.Load()
+		if int64(d) <= cur || h.max.CompareAndSwap(cur, int64(d)) {
+			break
+		}
+	}
+}
+
+// Since records the time elapsed since start.
+func (h *Histogram) Since(start time.Time) {
+	h.Record(time.Since(start))
+}
+
+// Reset empties h, for reuse. Observations recorded during the reset may
+// be kept or lost.
+func (h *Histogram) Reset() {
+	for i := range h.buckets {
+		h.buckets[i].Store(0)
+	}
+	h.count.Store(0)
+	h.sum.Store(0)
+	h.max.Store(0)
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() int64 {
+	return h.count.Load()
+}
+
+// Quantile returns the approximate q-quantile (0 < q <= 1), or zero when
+// nothing was recorded.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	total := h.count.Load()
+	if total == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i := 0; i <= bucketCount; i++ {
+		seen += h.buckets[i].Load()
+		if seen >= rank {
+			if i == bucketCount {
+				return time.Duration(h.max.Load())
+			}
+			// Never report more than the largest observation.
+			return min(bucketBounds[i], time.Duration(h.max.Load()))
+		}
+	}
+	return time.Duration(h.max.Load())
+}
+
+// Summary is a point-in-time view of a Histogram, in milliseconds.
+type Summary struct {
+	Count  int64   `json:"count"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P90Ms  float64 `json:"p90_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// Summary returns the current count, mean and percentiles.
+func (h *Histogram) Summary() Summary {
+	n := h.count.Load()
+	if n == 0 {
+		return Summary{}
+	}
+	return Summary{
+		Count:  n,
+		MeanMs: ms(time.Duration(h.sum.Load() / n)),
+		P50Ms:  ms(h.Quantile(0.50)),
+		P90Ms:  ms(h.Quantile(0.90)),
+		P95Ms:  ms(h.Quantile(0.95)),
+		P99Ms:  ms(h.Quantile(0.99)),
+		MaxMs:  ms(time.Duration(h.max.Load())),
+	}
+}
+
+func ms(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*100) / 100
+}
+
+// String formats the summary for the end-of-run printout.
+func (s Summary) String() string {
+	if s.Count == 0 {
+		return "no samples"
+	}
+	return fmt.Sprintf("n=%d mean=%.1fms p50=%.1fms p90=%.1fms p95=%.1fms p99=%.1fms max=%.1fms",
+		s.Count, s.MeanMs, s.P50Ms, s.P90Ms, s.P95Ms, s.P99Ms, s.MaxMs)
+}
+
+// Counts is the content of a Histogram in a form another process can merge
+// exactly, unlike a Summary; see Merge.
+type Counts struct {
+	// Buckets maps the index of every non-empty bucket to its count.
+	Buckets map[int]int64 `json:"buckets,omitempty"`
+	SumNs   int64         `json:"sum_ns"`
+	MaxNs   int64         `json:"max_ns"`
+}
+
+// Counts copies the current content of h.
+func (h *Histogram) Counts() Counts {
+	c := Counts{Buckets: make(map[int]int64), SumNs: h.sum.Load(), MaxNs: h.max.Load()}
+	for i := range h.buckets {
+		if n := h.buckets[i].Load(); n > 0 {
+			c.Buckets[i] = n
+		}
+	}
+	return c
+}
+
+// Merge adds the observations of c to h. Buckets outside the histogram's
+// range are counted in the overflow bucket.
+func (h *Histogram) Merge(c Counts) {
+	for i, n := range c.Buckets {
+		if i < 0 || i > bucketCount {
+			i = bucketCount
+		}
+		h.buckets[i].Add(n)
+		h.count.Add(n)
+	}
+	h.sum.Add(c.SumNs)
+	for {
+		cur := h.max.Load()
+		if c.MaxNs <= cur || h.max.CompareAndSwap(cur, c.MaxNs) {
+			break
+		}
+	}
+}
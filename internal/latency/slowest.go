@@ -0,0 +1,135 @@
+package latency
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Request describes one completed request kept by Slowest.
+type Request struct {
+	At        time.Time `json:"at"`
+	URL       string    `json:"url"`
+	Status    int       `json:"status,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs float64   `json:"latency_ms"`
+	// PhasesMs splits the latency into the phases of the request, such as
+	// "dns", "connect" and "wait", when it was traced.
+	PhasesMs map[string]float64 `json:"phases_ms,omitempty"`
+}
+
+// Slowest keeps the N slowest requests offered to it. It is safe for
+// concurrent use. Once it holds N requests, an offer no slower than the
+// fastest of them costs a single atomic load, so workers can offer every
+// request at any rate. A nil *Slowest keeps nothing.
+type Slowest struct {
+	n int
+	// floor is the latency an offer must exceed: the fastest request kept
+	// once N are, zero before.
+	floor atomic.Int64
+
+	mu   sync.Mutex
+	kept slowHeap
+}
+
+// NewSlowest returns a Slowest keeping n requests, or nil when n is not
+// positive.
+func NewSlowest(n int) *Slowest {
+	if n <= 0 {
+		return nil
+	}
+	return &Slowest{n: n}
+}
+
+// Offer keeps the request that took d, described by describe, if it is
+// among the N slowest so far. describe is only called for requests kept.
+func (s *Slowest) Offer(d time.Duration, describe func() Request) {
+	if s == nil || int64(d) <= s.floor.Load() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.kept) == s.n {
+		if d <= s.kept[0].d {
+			return // another offer raised the floor meanwhile
+		}
+		heap.Pop(&s.kept)
+	}
+	heap.Push(&s.kept, slowEntry{d: d, req: describe()})
+	if len(s.kept) == s.n {
+		s.floor.Store(int64(s.kept[0].d))
+	}
+}
+
+// List returns the requests kept, slowest first.
+func (s *Slowest) List() []Request {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	entries := append(slowHeap(nil), s.kept...)
+	s.mu.Unlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].d > entries[j].d })
+	out := make([]Request, len(entries))
+	for i, e := range entries {
+		out[i] = e.req
+		out[i].LatencyMs = ms(e.d)
+	}
+	return out
+}
+
+// Print writes the requests kept, slowest first, if any.
+func (s *Slowest) Print(w io.Writer) {
+	list := s.List()
+	if len(list) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Slowest %d requests:\n", len(list))
+	for _, r := range list {
+		outcome := fmt.Sprint(r.Status)
+		if r.Error != "" {
+			outcome = r.Error
+		}
+		fmt.Fprintf(w, "  %9.1fms  %s  %s  %s", r.LatencyMs, r.At.Format("15:04:05.000"), r.URL, outcome)
+		if len(r.PhasesMs) > 0 {
+			names := make([]string, 0, len(r.PhasesMs))
+			for name := range r.PhasesMs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fmt.Fprint(w, " (")
+			for i, name := range names {
+				if i > 0 {
+					fmt.Fprint(w, " ")
+				}
+				fmt.Fprintf(w, "%s %.1fms", name, r.PhasesMs[name])
+			}
+			fmt.Fprint(w, ")")
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+type slowEntry struct {
+	d   time.Duration
+	req Request
+}
+
+// slowHeap is a min-heap by latency: the fastest request kept is on top,
+// to be replaced first.
+type slowHeap []slowEntry
+
+func (h slowHeap) Len() int           { return len(h) }
+func (h slowHeap) Less(i, j int) bool { return h[i].d < h[j].d }
+func (h slowHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *slowHeap) Push(x any)        { *h = append(*h, x.(slowEntry)) }
+func (h *slowHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
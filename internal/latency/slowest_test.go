@@ -0,0 +1,153 @@
+package latency
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// offer offers a request of d milliseconds to s, named after its latency,
+// and reports whether it was described.
+func offer(s *Slowest, d int) (described bool) {
+	s.Offer(time.Duration(d)*time.Millisecond, func() Request {
+		described = true
+		return Request{URL: fmt.Sprintf("/r/%d", d), Status: 200}
+	})
+	return described
+}
+
+// urls returns the URLs of list.
+func urls(list []Request) []string {
+	var out []string
+	for _, r := range list {
+		out = append(out, r.URL)
+	}
+	return out
+}
+
+func TestSlowestKeepsTheSlowest(t *testing.T) {
+	tests := []struct {
+		name   string
+		n      int
+		offers []int
+		want   []string
+	}{
+		{"fewer offers than kept", 5, []int{3, 1, 2}, []string{"/r/3", "/r/2", "/r/1"}},
+		{"ascending", 3, []int{1, 2, 3, 4, 5}, []string{"/r/5", "/r/4", "/r/3"}},
+		{"descending", 3, []int{5, 4, 3, 2, 1}, []string{"/r/5", "/r/4", "/r/3"}},
+		{"one kept", 1, []int{4, 9, 2, 7}, []string{"/r/9"}},
+		{"ties at the floor not kept", 2, []int{5, 3, 3, 4}, []string{"/r/5", "/r/4"}},
+		{"zero latency never kept", 3, []int{0, 0}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSlowest(tt.n)
+			for _, d := range tt.offers {
+				offer(s, d)
+			}
+			got := s.List()
+			if strings.Join(urls(got), " ") != strings.Join(tt.want, " ") {
+				t.Errorf("kept %q, want %q", urls(got), tt.want)
+			}
+			for _, r := range got {
+				if want := fmt.Sprintf("/r/%g", r.LatencyMs); r.URL != want {
+					t.Errorf("%s has a latency of %gms", r.URL, r.LatencyMs)
+				}
+			}
+		})
+	}
+}
+
+func TestSlowestShuffled(t *testing.T) {
+	const kept = 10
+	s := NewSlowest(kept)
+	r := rand.New(rand.NewPCG(1, 2))
+	described := 0
+	for _, d := range r.Perm(1000) {
+		if offer(s, d+1) {
+			described++
+		}
+	}
+	var want []string
+	for d := 1000; d > 1000-kept; d-- {
+		want = append(want, fmt.Sprintf("/r/%d", d))
+	}
+	if got := urls(s.List()); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("kept %q, want %q", got, want)
+	}
+	// Only offers above the floor are described, far fewer than all.
+	if described >= 200 {
+		t.Errorf("%d of 1000 offers described", described)
+	}
+	if got := time.Duration(s.floor.Load()); got != (1000-kept+1)*time.Millisecond {
+		t.Errorf("floor = %s, want the fastest kept", got)
+	}
+}
+
+func TestSlowestConcurrent(t *testing.T) {
+	const workers, each, kept = 8, 1000, 5
+	s := NewSlowest(kept)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range each {
+				offer(s, i*workers+w+1)
+				if i%100 == 0 {
+					s.List()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	got := s.List()
+	if len(got) != kept || got[0].LatencyMs != workers*each {
+		t.Errorf("kept %q, want the %d slowest of %d", urls(got), kept, workers*each)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].LatencyMs != got[i-1].LatencyMs-1 {
+			t.Errorf("kept %q, want consecutive latencies", urls(got))
+			break
+		}
+	}
+}
+
+func TestSlowestDisabled(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		if s := NewSlowest(n); s != nil {
+			t.Errorf("NewSlowest(%d) keeps requests", n)
+		}
+	}
+	var s *Slowest
+	if offer(s, 10) {
+		t.Error("a nil Slowest described a request")
+	}
+	var b strings.Builder
+	s.Print(&b)
+	if s.List() != nil || b.Len() != 0 {
+		t.Errorf("a nil Slowest kept %v and printed %q", s.List(), b.String())
+	}
+}
+
+func TestSlowestPrint(t *testing.T) {
+	s := NewSlowest(2)
+	at := time.Date(2025, 5, 15, 10, 0, 0, 0, time.Local)
+	s.Offer(1500*time.Millisecond, func() Request {
+		return Request{At: at, URL: "http://jam/api/v0/games", Status: 503, PhasesMs: map[string]float64{"wait": 1400, "connect": 100}}
+	})
+	s.Offer(250*time.Millisecond, func() Request {
+		return Request{At: at, URL: "http://jam/api/v0/games/g1", Error: "context deadline exceeded"}
+	})
+	var b strings.Builder
+	s.Print(&b)
+	want := "Slowest 2 requests:\n" +
+		"     1500.0ms  10:00:00.000  http://jam/api/v0/games  503 (connect 100.0ms wait 1400.0ms)\n" +
+		"      250.0ms  10:00:00.000  http://jam/api/v0/games/g1  context deadline exceeded\n"
+	if b.String() != want {
+		t.Errorf("printed:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
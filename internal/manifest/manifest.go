@@ -0,0 +1,151 @@
+// Package manifest defines the games manifest: the games the sessions of a
+// play run took part in, which of our players were in each, and what was
+// observed of them. play -games-manifest writes it and analyze
+// -games-manifest reads it, so both sides share this format.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// Version is the format version written; Read rejects any other.
+const Version = 1
+
+// Manifest is the content of a manifest file.
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	// RunID is the run that wrote the manifest.
+	RunID string `json:"run_id,omitempty"`
+	// Games is sorted by GameID.
+	Games []Game `json:"games"`
+}
+
+// Game is one game our players were seen at.
+type Game struct {
+	GameID string `json:"game_id"`
+	// Players are our usernames seen at the game, sorted.
+	Players []string `json:"players"`
+	// FirstEvent and LastEvent are when the first and last message about
+	// the game were received, by any of our players.
+	FirstEvent time.Time `json:"first_event"`
+	LastEvent  time.Time `json:"last_event"`
+	// TerminalEvent is the type of the last message ending the game that
+	// was received, such as event_game_over; empty if none was.
+	TerminalEvent string `json:"terminal_event,omitempty"`
+}
+
+// GameIDs returns the IDs of the games, in manifest order.
+func (m *Manifest) GameIDs() []string {
+	ids := make([]string, len(m.Games))
+	for i, g := range m.Games {
+		ids[i] = g.GameID
+	}
+	return ids
+}
+
+// terminalTypes are the message types that end a game.
+var terminalTypes = map[string]bool{
+	pokerclient.TypeGameOver:            true,
+	pokerclient.TypeLeaderboardEntryEnd: true,
+}
+
+// Builder collects a manifest from the messages of many sessions. It is safe
+// for concurrent use; the zero value is ready to use.
+type Builder struct {
+	mu    sync.Mutex
+	games map[string]*builderGame
+}
+
+type builderGame struct {
+	Game
+	players map[string]bool
+}
+
+// Observe records that player received a message of type msgType about
+// gameID at time at.
+func (b *Builder) Observe(gameID, player, msgType string, at time.Time) {
+	if gameID == "" {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.games == nil {
+		b.games = make(map[string]*builderGame)
+	}
+	g := b.games[gameID]
+	if g == nil {
+		g = &builderGame{Game: Game{GameID: gameID, FirstEvent: at}, players: make(map[string]bool)}
+		b.games[gameID] = g
+	}
+	g.players[player] = true
+	if at.Before(g.FirstEvent) {
+		g.FirstEvent = at
+	}
+	if at.After(g.LastEvent) {
+		g.LastEvent = at
+	}
+	if terminalTypes[msgType] {
+		g.TerminalEvent = msgType
+	}
+}
+
+// Len returns the number of games observed.
+func (b *Builder) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.games)
+}
+
+// Manifest returns the games observed so far.
+func (b *Builder) Manifest() *Manifest {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m := &Manifest{Version: Version, CreatedAt: time.Now().UTC(), Games: make([]Game, 0, len(b.games))}
+	for _, g := range b.games {
+		game := g.Game
+		game.Players = make([]string, 0, len(g.players))
+		for p := range g.players {
+			game.Players = append(game.Players, p)
+		}
+		sort.Strings(game.Players)
+		m.Games = append(m.Games, game)
+	}
+	sort.Slice(m.Games, func(i, j int) bool { return m.Games[i].GameID < m.Games[j].GameID })
+	return m
+}
+
+// Write writes m to path as indented JSON.
+func Write(path string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing games manifest: %w", err)
+	}
+	return nil
+}
+
+// Read loads the manifest at path.
+func Read(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading games manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing games manifest %s: %w", path, err)
+	}
+	if m.Version != Version {
+		return nil, fmt.Errorf("games manifest %s has version %d, expected %d", path, m.Version, Version)
+	}
+	return &m, nil
+}
@@ -0,0 +1,101 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+func TestRoundTrip(t *testing.T) {
+	at := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	var b Builder
+	b.Observe("g2", "bot-b", "event_player_action", at.Add(3*time.Second))
+	b.Observe("g1", "bot-b", "action_player_bet", at.Add(time.Second))
+	b.Observe("g1", "bot-a", "event_player_action", at)
+	b.Observe("g1", "bot-a", pokerclient.TypeGameOver, at.Add(5*time.Second))
+	b.Observe("g1", "bot-b", "event_player_action", at.Add(2*time.Second))
+	b.Observe("", "bot-a", "event_player_action", at) // not about a game
+	m := b.Manifest()
+	m.RunID = "4f2a9c"
+
+	want := []Game{
+		{GameID: "g1", Players: []string{"bot-a", "bot-b"}, FirstEvent: at, LastEvent: at.Add(5 * time.Second), TerminalEvent: pokerclient.TypeGameOver},
+		{GameID: "g2", Players: []string{"bot-b"}, FirstEvent: at.Add(3 * time.Second), LastEvent: at.Add(3 * time.Second)},
+	}
+	if !reflect.DeepEqual(m.Games, want) {
+		t.Fatalf("games = %+v, want %+v", m.Games, want)
+	}
+
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := Write(path, m); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(m.CreatedAt) {
+		t.Errorf("created at %s, written %s", got.CreatedAt, m.CreatedAt)
+	}
+	got.CreatedAt = m.CreatedAt
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("read %+v, wrote %+v", got, m)
+	}
+	if ids := got.GameIDs(); !reflect.DeepEqual(ids, []string{"g1", "g2"}) {
+		t.Errorf("game IDs = %q", ids)
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"newer version", `{"version":2,"games":[]}`, "has version 2, expected 1"},
+		{"no version", `{"games":[{"game_id":"g1"}]}`, "has version 0, expected 1"},
+		{"not JSON", `game_id,players`, "parsing games manifest"},
+		{"mistyped games", `{"version":1,"games":{"g1":["bot-a"]}}`, "parsing games manifest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "manifest.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			m, err := Read(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Read = %+v, %v; want an error saying %q", m, err, tt.want)
+			}
+		})
+	}
+	if _, err := Read(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("reading a missing manifest did not fail")
+	}
+}
+
+func TestBuilderConcurrent(t *testing.T) {
+	var b Builder
+	at := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				b.Observe("g1", "bot", "event_player_action", at.Add(time.Duration(i*100+j)*time.Millisecond))
+			}
+		}()
+	}
+	wg.Wait()
+	g := b.Manifest().Games
+	if b.Len() != 1 || !g[0].FirstEvent.Equal(at) || !g[0].LastEvent.Equal(at.Add(799*time.Millisecond)) {
+		t.Errorf("games = %+v", g)
+	}
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"elastic-ai-jam-2025/internal/latency"
+	"elastic-ai-jam-2025/internal/netaddr"
+)
+
+// summaryQuantiles are the quantiles exported for each histogram.
+var summaryQuantiles = []struct {
+	label string
+	ms    func(s latency.Summary) float64
+}{
+	{"0.5", func(s latency.Summary) float64 { return s.P50Ms }},
+	{"0.9", func(s latency.Summary) float64 { return s.P90Ms }},
+	{"0.95", func(s latency.Summary) float64 { return s.P95Ms }},
+	{"0.99", func(s latency.Summary) float64 { return s.P99Ms }},
+}
+
+// WritePrometheus writes the registry in the Prometheus text exposition
+// format. Every name is prefixed with namespace and an underscore; counters
+// get a _total suffix and histograms are exported as summaries in seconds.
+func (r *Registry) WritePrometheus(w io.Writer, namespace string) error {
+	var b strings.Builder
+	for _, m := range r.list() {
+		name := namespace + "_" + m.name
+		switch m.kind {
+		case kindCounter:
+			name += "_total"
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, m.help, name, name, m.counter.Load())
+		case kindGauge:
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", name, m.help, name, name, m.gauge.Load())
+		case kindHistogram:
+			name += "_seconds"
+			s := m.histogram.Summary()
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s summary\n", name, m.help, name)
+			for _, q := range summaryQuantiles {
+				fmt.Fprintf(&b, "%s{quantile=%q} %.6g\n", name, q.label, q.ms(s)/1000)
+			}
+			fmt.Fprintf(&b, "%s_sum %.6g\n%s_count %d\n", name, s.MeanMs*float64(s.Count)/1000, name, s.Count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Handler serves the registry: /metrics in the Prometheus text format and
+// /stats as a JSON Snapshot.
+func (r *Registry) Handler(namespace string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		r.WritePrometheus(w, namespace)
+	})
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(r.Snapshot())
+	})
+	return mux
+}
+
+// Serve starts serving the registry on addr, host:port, [ipv6]:port or
+// :port, in the background and returns a function that stops the server. An
+// empty addr serves nothing.
+func (r *Registry) Serve(addr, namespace string) (stop func(), err error) {
+	if addr == "" {
+		return func() {}, nil
+	}
+	addr, err = netaddr.ListenAddr(addr)
+	if err != nil {
+		return nil, fmt.Errorf("serving metrics: %w", err)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("serving metrics: %w", err)
+	}
+	srv := &http.Server{Handler: r.Handler(namespace), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			slog.Warn("metrics server stopped", "error", err)
+		}
+	}()
+	slog.Info("Serving metrics", "addr", ln.Addr().String(), "paths", "/metrics /stats")
+	return func() { srv.Close() }, nil
+}
@@ -0,0 +1,175 @@
+// Package metrics is a registry of named counters, gauges and latency
+// histograms. A command registers its metrics once, at package level, and
+// updates them from any goroutine; the end-of-run summary, the JSON report
+// and the /metrics endpoint all render the same registry.
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"elastic-ai-jam-2025/internal/latency"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Counter is a monotonically increasing count.
+type Counter struct {
+	v atomic.Int64
+}
+
+// Inc adds one.
+func (c *Counter) Inc() { c.v.Add(1) }
+
+// Add adds n.
+func (c *Counter) Add(n int64) { c.v.Add(n) }
+
+// Load returns the current count.
+func (c *Counter) Load() int64 { return c.v.Load() }
+
+// Gauge is a value that goes up and down, such as the number of sessions in
+// a given state.
+type Gauge struct {
+	v atomic.Int64
+}
+
+// Inc adds one.
+func (g *Gauge) Inc() { g.v.Add(1) }
+
+// Dec subtracts one.
+func (g *Gauge) Dec() { g.v.Add(-1) }
+
+// Set replaces the value.
+func (g *Gauge) Set(n int64) { g.v.Store(n) }
+
+// Load returns the current value.
+func (g *Gauge) Load() int64 { return g.v.Load() }
+
+type kind int
+
+const (
+	kindCounter kind = iota
+	kindGauge
+	kindHistogram
+)
+
+func (k kind) String() string {
+	return [...]string{"counter", "gauge", "histogram"}[k]
+}
+
+type metric struct {
+	name      string
+	help      string
+	kind      kind
+	counter   *Counter
+	gauge     *Gauge
+	histogram *latency.Histogram
+}
+
+// Registry holds the metrics of a command. Registering takes a lock;
+// updating a registered metric never does.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []*metric
+	byName  map[string]*metric
+}
+
+// New returns an empty registry.
+func New() *Registry {
+	return &Registry{byName: make(map[string]*metric)}
+}
+
+// lookup returns the metric registered as name, creating it with create if
+// needed. Registering a name twice with different kinds is a programming
+// error and panics.
+func (r *Registry) lookup(name, help string, k kind, create func(m *metric)) *metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.byName[name]; ok {
+		if m.kind != k {
+			panic(fmt.Sprintf("metrics: %s registered as %s and %s", name, m.kind, k))
+		}
+		return m
+	}
+	m := &metric{name: name, help: help, kind: k}
+	create(m)
+	r.metrics = append(r.metrics, m)
+	r.byName[name] = m
+	return m
+}
+
+// Counter returns the counter registered as name, registering it first if
+// needed. The name is also its key in the JSON report.
+func (r *Registry) Counter(name, help string) *Counter {
+	return r.lookup(name, help, kindCounter, func(m *metric) { m.counter = &Counter{} }).counter
+}
+
+// Gauge returns the gauge registered as name, registering it first if
+// needed.
+func (r *Registry) Gauge(name, help string) *Gauge {
+	return r.lookup(name, help, kindGauge, func(m *metric) { m.gauge = &Gauge{} }).gauge
+}
+
+// Histogram returns the latency histogram registered as name, registering it
+// first if needed.
+func (r *Registry) Histogram(name, help string) *latency.Histogram {
+	return r.lookup(name, help, kindHistogram, func(m *metric) { m.histogram = &latency.Histogram{} }).histogram
+}
+
+// Snapshot is a point-in-time copy of a registry.
+type Snapshot struct {
+	Counters  map[string]int64           `json:"counters"`
+	Gauges    map[string]int64           `json:"gauges"`
+	Latencies map[string]latency.Summary `json:"latencies"`
+}
+
+// Snapshot copies the current values. Writers keep updating while it runs,
+// so the values are not taken at a single instant, but each one is exact.
+func (r *Registry) Snapshot() Snapshot {
+	s := Snapshot{
+		Counters:  make(map[string]int64),
+		Gauges:    make(map[string]int64),
+		Latencies: make(map[string]latency.Summary),
+	}
+	for _, m := range r.list() {
+		switch m.kind {
+		case kindCounter:
+			s.Counters[m.name] = m.counter.Load()
+		case kindGauge:
+			s.Gauges[m.name] = m.gauge.Load()
+		case kindHistogram:
+			s.Latencies[m.name] = m.histogram.Summary()
+		}
+	}
+	return s
+}
+
+// HistogramCounts copies the content of every histogram, for merging with
+// those of other processes.
+func (r *Registry) HistogramCounts() map[string]latency.Counts {
+	out := make(map[string]latency.Counts)
+	for _, m := range r.list() {
+		if m.kind == kindHistogram {
+			out[m.name] = m.histogram.Counts()
+		}
+	}
+	return out
+}
+
+// list returns the registered metrics in registration order.
+func (r *Registry) list() []*metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*metric(nil), r.metrics...)
+}
+
+// Fill copies the counters and latencies into a report section. Gauges only
+// describe the moment they are read, so they are left out of reports.
+func (s Snapshot) Fill(sec *report.Section) {
+	for name, n := range s.Counters {
+		sec.Counters[name] = n
+	}
+	for name, l := range s.Latencies {
+		sec.Latencies[name] = l
+	}
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	r := New()
+	r.Counter("requests", "Requests sent.").Add(3)
+	r.Gauge("sessions", "Sessions running.").Set(-2)
+	h := r.Histogram("request", "Time until the response was read.")
+	for range 4 {
+		h.Record(50 * time.Millisecond)
+	}
+	r.Histogram("idle", "Never observed.")
+
+	var b strings.Builder
+	if err := r.WritePrometheus(&b, "aijam"); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP aijam_requests_total Requests sent.
+# TYPE aijam_requests_total counter
+aijam_requests_total 3
+# HELP aijam_sessions Sessions running.
+# TYPE aijam_sessions gauge
+aijam_sessions -2
+# HELP aijam_request_seconds Time until the response was read.
+# TYPE aijam_request_seconds summary
+aijam_request_seconds{quantile="0.5"} 0.05
+aijam_request_seconds{quantile="0.9"} 0.05
+aijam_request_seconds{quantile="0.95"} 0.05
+aijam_request_seconds{quantile="0.99"} 0.05
+aijam_request_seconds_sum 0.2
+aijam_request_seconds_count 4
+# HELP aijam_idle_seconds Never observed.
+# TYPE aijam_idle_seconds summary
+aijam_idle_seconds{quantile="0.5"} 0
+aijam_idle_seconds{quantile="0.9"} 0
+aijam_idle_seconds{quantile="0.95"} 0
+aijam_idle_seconds{quantile="0.99"} 0
+aijam_idle_seconds_sum 0
+aijam_idle_seconds_count 0
+`
+	if got := b.String(); got != want {
+		t.Errorf("exposition:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestRegistryConcurrent registers and updates the same metrics from many
+// goroutines while others render the registry; run it with -race.
+func TestRegistryConcurrent(t *testing.T) {
+	const workers, each = 8, 1000
+	r := New()
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for range 2 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				r.WritePrometheus(io.Discard, "aijam")
+				r.Snapshot()
+				r.HistogramCounts()
+			}
+		}()
+	}
+	var writers sync.WaitGroup
+	for range workers {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for range each {
+				r.Counter("requests", "Requests sent.").Add(2)
+				r.Gauge("sessions", "Sessions running.").Inc()
+				r.Histogram("request", "Request latency.").Record(time.Millisecond)
+			}
+		}()
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+
+	s := r.Snapshot()
+	if got, want := s.Counters["requests"], int64(2*workers*each); got != want {
+		t.Errorf("requests = %d, want %d", got, want)
+	}
+	if got, want := s.Gauges["sessions"], int64(workers*each); got != want {
+		t.Errorf("sessions = %d, want %d", got, want)
+	}
+	if got, want := s.Latencies["request"].Count, int64(workers*each); got != want {
+		t.Errorf("request count = %d, want %d", got, want)
+	}
+}
+
+func TestRegisterTwiceWithAnotherKind(t *testing.T) {
+	r := New()
+	r.Counter("requests", "")
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a counter's name as a gauge did not panic")
+		}
+	}()
+	r.Gauge("requests", "")
+}
@@ -0,0 +1,461 @@
+// Package mockserver is an in-process stand-in for the jam's TCP game
+// server, so the client can be run end to end without a network. It speaks
+// the part of the protocol pokerclient implements; the dealing is scripted
+// rather than real poker: each connection plays alone against a few bots
+// whose moves follow Config.Dealer.
+package mockserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/rng"
+)
+
+// Dealer scripts: how the bots at every table move.
+const (
+	DealerFold   = "fold"   // bots fold to everything
+	DealerCall   = "call"   // bots call everything
+	DealerRandom = "random" // bots fold, call or raise at random
+)
+
+// Dealers lists the scripts Config.Dealer may name.
+var Dealers = []string{DealerFold, DealerCall, DealerRandom}
+
+// TypePlayerAction carries a bot's move. The real server's name for this
+// event is not known; play accepts moves from any message type, see
+// pokerclient.PlayerActionOf.
+const TypePlayerAction = "event_player_action"
+
+// retryWindow is how long the server waits for another bet after rejecting
+// one before it folds the player.
+const retryWindow = 200 * time.Millisecond
+
+// Config describes the games the server deals.
+type Config struct {
+	// HandsPerGame is the number of hands before event_game_over.
+	HandsPerGame int
+	// StartChips is the stack of a newly registered player; chips are kept
+	// per username across connections.
+	StartChips int
+	// MinimumBet is the pre-flop minimum bet.
+	MinimumBet int
+	// Bots is the number of opponents at each table.
+	Bots int
+	// Dealer is one of the Dealer* scripts.
+	Dealer string
+	// FailPercent is the share of bets rejected with an error, and a tenth
+	// of it the share of hands that start by dropping the connection.
+	FailPercent float64
+	// DropPercent is the share of the player's moves the server ignores,
+	// prompting again as if they never arrived.
+	DropPercent float64
+	// MinPasswordLength, when positive, rejects the logins with shorter
+	// passwords, as a server enforcing a password policy would.
+	MinPasswordLength int
+	// Seed drives the bots and the injected failures, along with the
+	// username of each connection and how many connections it made
+	// before, so a run replays the same games whatever order the
+	// connections arrive in.
+	Seed int64
+}
+
+// Stats counts what the server did.
+type Stats struct {
+	Connections  int64
+	Registered   int64
+	Games        int64
+	Hands        int64
+	RejectedBets int64
+	DroppedMoves int64
+	Drops        int64
+}
+
+// Server is a running mock server.
+type Server struct {
+	cfg Config
+	ln  net.Listener
+	wg  sync.WaitGroup
+
+	mu    sync.Mutex
+	users map[string]*user
+	conns map[net.Conn]struct{}
+
+	nextGame atomic.Int64
+	stats    struct {
+		connections, registered, games, hands, rejectedBets, droppedMoves, drops atomic.Int64
+	}
+}
+
+type user struct {
+	password string
+	chips    int
+	// logins is the number of connections that registered as the user.
+	logins int
+}
+
+// Start listens on addr, such as "127.0.0.1:0", and serves in the
+// background until Close.
+func Start(addr string, cfg Config) (*Server, error) {
+	switch cfg.Dealer {
+	case DealerFold, DealerCall, DealerRandom:
+	default:
+		return nil, fmt.Errorf("unknown dealer %q", cfg.Dealer)
+	}
+	if cfg.HandsPerGame <= 0 || cfg.StartChips <= 0 || cfg.MinimumBet <= 0 {
+		return nil, errors.New("hands per game, start chips and minimum bet must be positive")
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{cfg: cfg, ln: ln, users: make(map[string]*user), conns: make(map[net.Conn]struct{})}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// Addr is the address the server listens on.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close stops accepting, closes every connection and waits for their
+// handlers to return.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// Stats returns the counts so far.
+func (s *Server) Stats() Stats {
+	return Stats{
+		Connections:  s.stats.connections.Load(),
+		Registered:   s.stats.registered.Load(),
+		Games:        s.stats.games.Load(),
+		Hands:        s.stats.hands.Load(),
+		RejectedBets: s.stats.rejectedBets.Load(),
+		DroppedMoves: s.stats.droppedMoves.Load(),
+		Drops:        s.stats.drops.Load(),
+	}
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+		s.stats.connections.Add(1)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, c)
+				s.mu.Unlock()
+				c.Close()
+			}()
+			sc := &session{srv: s, conn: c, r: bufio.NewReader(c)}
+			sc.serve()
+		}()
+	}
+}
+
+// session is the server side of one connection.
+type session struct {
+	srv  *Server
+	conn net.Conn
+	r    *bufio.Reader
+	// rng is set at registration, from the username.
+	rng  *rand.Rand
+	user *user
+	name string
+}
+
+// message is what the server sends; the fields mirror
+// pokerclient.ServerResponse.
+type message struct {
+	Type       string       `json:"type,omitempty"`
+	Event      any          `json:"event,omitempty"`
+	Code       int          `json:"code,omitempty"`
+	Message    string       `json:"message,omitempty"`
+	GameID     string       `json:"game_id,omitempty"`
+	Stage      string       `json:"stage,omitempty"`
+	State      *promptState `json:"state,omitempty"`
+	MinimumBet int          `json:"minimum_bet,omitempty"`
+}
+
+type promptState struct {
+	Player pokerclient.PlayerStateForBet `json:"player"`
+}
+
+func (sc *session) send(m message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = sc.conn.Write(append(b, '\n'))
+	return err
+}
+
+func (sc *session) fail(code int, text string) error {
+	return sc.send(message{Code: code, Message: text})
+}
+
+// read decodes the next line into v. A zero deadline waits forever.
+func (sc *session) read(v any, deadline time.Time) error {
+	if err := sc.conn.SetReadDeadline(deadline); err != nil {
+		return err
+	}
+	line, err := sc.r.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(line, v)
+}
+
+func (sc *session) serve() {
+	var reg pokerclient.RegistrationMsg
+	if err := sc.read(&reg, time.Time{}); err != nil || reg.Username == "" {
+		sc.fail(400, "invalid registration")
+		return
+	}
+	if n := sc.srv.cfg.MinPasswordLength; len(reg.Password) < n {
+		sc.fail(400, fmt.Sprintf("password does not meet the policy: at least %d characters", n))
+		return
+	}
+	if !sc.login(reg) {
+		sc.fail(401, "invalid password")
+		return
+	}
+	sc.srv.stats.registered.Add(1)
+	if sc.send(message{Type: pokerclient.TypeLeaderboardEntryStart, Event: map[string]any{"player_id": sc.name, "chips": sc.chips()}}) != nil {
+		return
+	}
+	for {
+		var act pokerclient.ActionMsg
+		if err := sc.read(&act, time.Time{}); err != nil {
+			return
+		}
+		switch act.Action {
+		case pokerclient.ActionJoin:
+			if sc.chips() <= 0 {
+				if sc.fail(400, "not enough chips to join a game") != nil {
+					return
+				}
+				continue
+			}
+			if !sc.play() {
+				return
+			}
+		case pokerclient.ActionBet:
+			if sc.fail(400, "not your turn") != nil {
+				return
+			}
+		default:
+			// Keepalives and unknown actions are ignored, as the real
+			// server is assumed to do.
+		}
+	}
+}
+
+// login registers reg, or checks its password if the user exists.
+func (sc *session) login(reg pokerclient.RegistrationMsg) bool {
+	sc.srv.mu.Lock()
+	defer sc.srv.mu.Unlock()
+	u, ok := sc.srv.users[reg.Username]
+	if !ok {
+		u = &user{password: reg.Password, chips: sc.srv.cfg.StartChips}
+		sc.srv.users[reg.Username] = u
+	} else if u.password != reg.Password {
+		return false
+	}
+	sc.user, sc.name = u, reg.Username
+	sc.rng = rng.ForName(sc.srv.cfg.Seed, reg.Username, u.logins)
+	u.logins++
+	return true
+}
+
+func (sc *session) chips() int {
+	sc.srv.mu.Lock()
+	defer sc.srv.mu.Unlock()
+	return sc.user.chips
+}
+
+func (sc *session) addChips(n int) int {
+	sc.srv.mu.Lock()
+	defer sc.srv.mu.Unlock()
+	sc.user.chips += n
+	return sc.user.chips
+}
+
+// play deals one game. It returns false once the connection is unusable.
+func (sc *session) play() bool {
+	cfg := sc.srv.cfg
+	gameID := fmt.Sprintf("mock-%d", sc.srv.nextGame.Add(1))
+	sc.srv.stats.games.Add(1)
+	for hand := 0; hand < cfg.HandsPerGame && sc.chips() > 0; hand++ {
+		if sc.rng.Float64()*100 < cfg.FailPercent/10 {
+			sc.srv.stats.drops.Add(1)
+			return false
+		}
+		sc.srv.stats.hands.Add(1)
+		if !sc.hand(gameID) {
+			return false
+		}
+	}
+	return sc.send(message{Type: pokerclient.TypeGameOver, GameID: gameID, Event: map[string]any{
+		"game_id": gameID,
+		"players": []map[string]any{{"player_id": sc.name, "chips": sc.chips()}},
+	}}) == nil
+}
+
+// hand deals one hand: at each stage the bots move, then the player is
+// prompted. The player wins against bots that all folded, and otherwise at
+// even odds at showdown.
+func (sc *session) hand(gameID string) bool {
+	cfg := sc.srv.cfg
+	committed, calling := 0, cfg.Bots
+	for _, stage := range pokerclient.KnownStages {
+		minimum := 0
+		if stage == pokerclient.StagePreFlop {
+			minimum = cfg.MinimumBet
+		}
+		for bot := 0; bot < cfg.Bots; bot++ {
+			move, amount := sc.botMove(minimum)
+			if move == pokerclient.MoveFold {
+				calling--
+			} else if amount > minimum {
+				minimum = amount
+			}
+			ev := map[string]any{"player_id": fmt.Sprintf("bot-%d", bot), "action": move, "amount": amount}
+			if sc.send(message{Type: TypePlayerAction, GameID: gameID, Event: ev}) != nil {
+				return false
+			}
+		}
+		chips := sc.chips() - committed
+		if chips <= 0 {
+			break // all-in: nothing left to decide
+		}
+		amount, ok := sc.prompt(gameID, stage, chips, minimum)
+		if !ok {
+			return false
+		}
+		if amount < 0 {
+			sc.addChips(-committed)
+			return sc.potWon(gameID, "bot-0", committed)
+		}
+		committed += amount
+		if calling <= 0 {
+			break
+		}
+	}
+	if calling <= 0 || sc.rng.IntN(2) == 0 {
+		won := committed * max(calling, 1)
+		sc.addChips(won)
+		return sc.potWon(gameID, sc.name, committed+won)
+	}
+	sc.addChips(-committed)
+	return sc.potWon(gameID, "bot-0", committed*(calling+1))
+}
+
+// botMove returns the move of a bot facing minimum.
+func (sc *session) botMove(minimum int) (string, int) {
+	switch sc.srv.cfg.Dealer {
+	case DealerFold:
+		return pokerclient.MoveFold, 0
+	case DealerCall:
+		return pokerclient.MoveCall, minimum
+	}
+	switch sc.rng.IntN(3) {
+	case 0:
+		return pokerclient.MoveFold, 0
+	case 1:
+		return pokerclient.MoveCall, minimum
+	default:
+		return pokerclient.MoveRaise, max(minimum, sc.srv.cfg.MinimumBet) * 2
+	}
+}
+
+// prompt asks the player to bet and returns the accepted amount, negative
+// for a fold. An invalid or randomly rejected bet is answered with an error
+// and the player gets retryWindow to send another one before it folds;
+// keepalives sent meanwhile do not extend it. A randomly dropped move is
+// ignored and the prompt sent again, once.
+func (sc *session) prompt(gameID, stage string, chips, minimum int) (amount int, ok bool) {
+	ask := message{
+		Type: pokerclient.TypeActionPlayerBet, GameID: gameID, Stage: stage, MinimumBet: minimum,
+		State: &promptState{Player: pokerclient.PlayerStateForBet{PlayerID: sc.name, Chips: chips}},
+	}
+	if sc.send(ask) != nil {
+		return 0, false
+	}
+	var deadline time.Time
+	dropped, retry := false, false
+	for {
+		var act pokerclient.ActionMsg
+		if err := sc.read(&act, deadline); err != nil {
+			var netErr net.Error
+			if retry && errors.As(err, &netErr) && netErr.Timeout() {
+				return -1, true
+			}
+			return 0, false
+		}
+		if act.Action != pokerclient.ActionBet || act.Amount == nil {
+			continue
+		}
+		if !dropped && !retry && sc.rng.Float64()*100 < sc.srv.cfg.DropPercent {
+			dropped = true
+			sc.srv.stats.droppedMoves.Add(1)
+			if sc.send(ask) != nil {
+				return 0, false
+			}
+			continue
+		}
+		amount := *act.Amount
+		var err error
+		switch {
+		case amount < 0:
+			return amount, true
+		case amount > chips:
+			err = sc.fail(400, "not enough chips for this bet")
+		case amount < minimum && amount != chips:
+			err = sc.fail(400, fmt.Sprintf("bet below the minimum of %d", minimum))
+		case !retry && sc.rng.Float64()*100 < sc.srv.cfg.FailPercent:
+			err = sc.fail(400, "invalid bet")
+		default:
+			return amount, true
+		}
+		sc.srv.stats.rejectedBets.Add(1)
+		if err != nil {
+			return 0, false
+		}
+		if retry {
+			return -1, true
+		}
+		retry, deadline = true, time.Now().Add(retryWindow)
+	}
+}
+
+func (sc *session) potWon(gameID, winner string, pot int) bool {
+	return sc.send(message{Type: pokerclient.TypePotWon, GameID: gameID, Event: map[string]any{"player_id": winner, "amount": pot}}) == nil
+}
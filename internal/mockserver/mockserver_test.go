@@ -0,0 +1,101 @@
+package mockserver
+
+import (
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// readUntil reads messages until one of type typ.
+func readUntil(t *testing.T, c *pokerclient.Conn, typ string) *pokerclient.ServerResponse {
+	t.Helper()
+	for {
+		resp, err := c.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", typ, err)
+		}
+		if resp.Type == typ {
+			return resp
+		}
+	}
+}
+
+func TestRetryWindowIgnoresKeepalives(t *testing.T) {
+	srv, err := Start("127.0.0.1:0", Config{HandsPerGame: 1, StartChips: 100, MinimumBet: 10, Bots: 1, Dealer: DealerCall, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	c, err := pokerclient.Dial(srv.Addr(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.ReadTimeout = 2 * time.Second
+	if _, err := c.Register("p", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Join(); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, c, pokerclient.TypeActionPlayerBet)
+
+	// A bet above the stack is rejected; the pings that follow must not
+	// keep the server waiting for another one.
+	allIn, _ := pokerclient.AllIn(1000)
+	if err := c.SendJSON(allIn.Msg()); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ping := pokerclient.ActionMsg{Action: pokerclient.ActionPing}
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(retryWindow / 4):
+				c.SendQuiet(ping)
+			}
+		}
+	}()
+	resp, err := c.ReadMessage()
+	if err != nil || resp.Code == 0 {
+		t.Fatalf("want the rejection of the bet, got %+v, %v", resp, err)
+	}
+	readUntil(t, c, pokerclient.TypePotWon)
+	if waited := time.Since(start); waited > 4*retryWindow {
+		t.Errorf("folded after %s, want about %s", waited, retryWindow)
+	}
+}
+
+func TestMinPasswordLength(t *testing.T) {
+	srv, err := Start("127.0.0.1:0", Config{HandsPerGame: 1, StartChips: 100, MinimumBet: 10, MinPasswordLength: 12, Dealer: DealerFold, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	tests := []struct {
+		username, password string
+		want               errclass.Class
+	}{
+		{"short", "secret", errclass.PasswordPolicy},
+		{"long", "a-long-enough-secret", ""},
+		{"long", "another-long-secret", errclass.Rejected},
+	}
+	for _, tt := range tests {
+		c, err := pokerclient.Dial(srv.Addr(), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.ReadTimeout = 2 * time.Second
+		_, err = c.Register(tt.username, tt.password)
+		c.Close()
+		if got := errclass.Classify(err); got != tt.want {
+			t.Errorf("%s with %q: %v classified %q, want %q", tt.username, tt.password, err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// Package netaddr normalizes the addresses given on the command line and
+// dials them. Hosts may be names, IPv4 literals or IPv6 literals, the
+// latter with or without brackets:
+//
+//	eah.example:8083  192.0.2.7:8083  [2001:db8::1]:8083  2001:db8::1
+//
+// An IPv6 literal followed by a port needs the brackets, or the port would
+// read as the last group of the address. Addresses are split and joined with
+// net.SplitHostPort and net.JoinHostPort, and URLs built with url.URL, never
+// by formatting strings, so literals keep their brackets where they need
+// them.
+package netaddr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// splitHostPort splits addr into its host, without brackets, and its port,
+// empty when addr has none. An address with several colons and no brackets
+// is an IPv6 literal without a port.
+func splitHostPort(addr string) (host, port string, err error) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return "", "", errors.New("empty address")
+	}
+	if h, p, err := net.SplitHostPort(addr); err == nil {
+		if err := checkPort(p); err != nil {
+			return "", "", fmt.Errorf("address %q: %w", addr, err)
+		}
+		return h, p, nil
+	}
+	host = addr
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	if strings.Contains(host, ":") {
+		if _, err := netip.ParseAddr(host); err != nil {
+			return "", "", fmt.Errorf("address %q is neither host:port nor an IPv6 literal", addr)
+		}
+	} else if strings.ContainsAny(host, "[]") {
+		return "", "", fmt.Errorf("address %q has unbalanced brackets", addr)
+	}
+	return host, "", nil
+}
+
+// checkPort checks that port is a port number, 0 included, which listening
+// takes for any free port.
+func checkPort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("port %q is not a number from 0 to 65535", port)
+	}
+	return nil
+}
+
+// HostPort returns addr as host:port, bracketing an IPv6 host, with
+// defaultPort when addr has no port. Without a defaultPort, a port is
+// required.
+func HostPort(addr, defaultPort string) (string, error) {
+	host, port, err := splitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		return "", fmt.Errorf("address %q has no host", addr)
+	}
+	switch port {
+	case "":
+		if defaultPort == "" {
+			return "", fmt.Errorf("address %q has no port", addr)
+		}
+		port = defaultPort
+	case "0":
+		return "", fmt.Errorf("address %q: port 0 can only be listened on", addr)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// ListenAddr returns addr as an address to listen on: host:port, or :port
+// for every interface. A port is required, 0 for any free one.
+func ListenAddr(addr string) (string, error) {
+	host, port, err := splitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if port == "" {
+		return "", fmt.Errorf("listen address %q has no port", addr)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// BaseURL returns raw as an absolute http or https URL without a trailing
+// slash, its host normalized and its path and query as given. A raw without
+// a scheme is taken as http, and given defaultPort if it has no port either;
+// a URL with a scheme keeps the scheme's port. Unlike url.Parse, it accepts
+// an IPv6 literal without brackets when it has no port.
+func BaseURL(raw, defaultPort string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	scheme, rest, ok := strings.Cut(raw, "://")
+	if !ok {
+		scheme, rest = "http", raw
+	} else {
+		defaultPort = ""
+	}
+	scheme = strings.ToLower(scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", fmt.Errorf("URL %q: the scheme must be http or https", raw)
+	}
+	authority, path := rest, ""
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		authority, path = rest[:i], rest[i:]
+	}
+	if authority == "" {
+		return "", fmt.Errorf("URL %q has no host", raw)
+	}
+	if strings.Contains(authority, "@") {
+		return "", fmt.Errorf("URL %q: credentials in the URL are not supported", raw)
+	}
+	host, port, err := splitHostPort(authority)
+	if err != nil {
+		return "", fmt.Errorf("URL %q: %w", raw, err)
+	}
+	if host == "" {
+		return "", fmt.Errorf("URL %q has no host", raw)
+	}
+	switch port {
+	case "":
+		port = defaultPort
+	case "0":
+		return "", fmt.Errorf("URL %q: port 0 can only be listened on", raw)
+	}
+	u := &url.URL{Scheme: scheme, Host: host}
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	}
+	// The path is kept as given, so placeholders such as {player} survive.
+	if _, err := url.Parse(path); err != nil {
+		return "", fmt.Errorf("URL %q: %w", raw, err)
+	}
+	return strings.TrimSuffix(u.String()+path, "/"), nil
+}
+
+// Host returns a host as the allow lists compare it: without brackets, in
+// lower case.
+func Host(host string) string {
+	host = strings.TrimSpace(host)
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	return strings.ToLower(host)
+}
+
+// Families a host name's addresses may be dialed in the preference of.
+const (
+	IPv4 = "ipv4"
+	IPv6 = "ipv6"
+)
+
+// Prefer, when IPv4 or IPv6, makes Dial and DialContext try the addresses of
+// that family first when a host name resolves to both; the others are
+// tried after them. Empty leaves the order to the resolver. cli sets it from
+// -prefer-ipv4 and -prefer-ipv6.
+var Prefer string
+
+// minAttempt is the least time an address is given when a dial spreads
+// its timeout over several addresses, as the net package does.
+const minAttempt = 2 * time.Second
+
+// Dial connects to the TCP address addr within timeout, zero meaning none.
+func Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return DialContext(ctx, "tcp", addr)
+}
+
+// DialContext connects to addr on network like net.Dialer.DialContext,
+// honoring Prefer. With a preference, a host name is resolved first and its
+// addresses dialed one after the other, each given a share of the time left,
+// so a family that does not answer leaves time for the other.
+func DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	var d net.Dialer
+	host, port, err := net.SplitHostPort(addr)
+	if Prefer == "" || err != nil {
+		return d.DialContext(ctx, network, addr)
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return d.DialContext(ctx, network, addr)
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	ips = Order(ips, Prefer)
+	var first error
+	for i, ip := range ips {
+		attempt := ctx
+		if deadline, ok := ctx.Deadline(); ok && i < len(ips)-1 {
+			share := time.Until(deadline) / time.Duration(len(ips)-i)
+			var cancel context.CancelFunc
+			attempt, cancel = context.WithTimeout(ctx, max(share, minAttempt))
+			defer cancel()
+		}
+		c, err := d.DialContext(attempt, network, net.JoinHostPort(ip.Unmap().String(), port))
+		if err == nil {
+			return c, nil
+		}
+		if first == nil {
+			first = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, first
+}
+
+// Order returns ips with those of family first, each group in its original
+// order.
+func Order(ips []netip.Addr, family string) []netip.Addr {
+	out := slices.Clone(ips)
+	rank := func(ip netip.Addr) int {
+		if ip.Unmap().Is4() == (family == IPv4) {
+			return 0
+		}
+		return 1
+	}
+	slices.SortStableFunc(out, func(a, b netip.Addr) int { return rank(a) - rank(b) })
+	return out
+}
+
+// Transport returns a clone of http.DefaultTransport dialing through
+// DialContext.
+func Transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = DialContext
+	return t
+}
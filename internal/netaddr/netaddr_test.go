@@ -0,0 +1,123 @@
+package netaddr
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+// TestHostPort covers -server, which defaults the port.
+func TestHostPort(t *testing.T) {
+	tests := []struct {
+		addr, defaultPort string
+		want              string
+		wantErr           bool
+	}{
+		{"eah.example:8083", "8083", "eah.example:8083", false},
+		{"eah.example", "8083", "eah.example:8083", false},
+		{"192.0.2.7:8083", "8083", "192.0.2.7:8083", false},
+		{"192.0.2.7", "8083", "192.0.2.7:8083", false},
+		{"[2001:db8::1]:8083", "8083", "[2001:db8::1]:8083", false},
+		{"[2001:db8::1]", "8083", "[2001:db8::1]:8083", false},
+		{"2001:db8::1", "8083", "[2001:db8::1]:8083", false},
+		{" eah.example:8083 ", "8083", "eah.example:8083", false},
+		{"eah.example", "", "", true},
+		{"eah.example:0", "8083", "", true},
+		{"eah.example:99999", "8083", "", true},
+		{":8083", "8083", "", true},
+		{"", "8083", "", true},
+		{"[2001:db8::1", "8083", "", true},
+		{"2001:db8::zz", "8083", "", true},
+	}
+	for _, tt := range tests {
+		got, err := HostPort(tt.addr, tt.defaultPort)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("HostPort(%q, %q) = %q, %v; want %q, error %v", tt.addr, tt.defaultPort, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestListenAddr covers -listen and -metrics-addr.
+func TestListenAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		want    string
+		wantErr bool
+	}{
+		{":9090", ":9090", false},
+		{"localhost:9090", "localhost:9090", false},
+		{"127.0.0.1:0", "127.0.0.1:0", false},
+		{"[::1]:9090", "[::1]:9090", false},
+		{"localhost", "", true},
+		{"::1", "", true},
+		{"[::1]", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ListenAddr(tt.addr)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ListenAddr(%q) = %q, %v; want %q, error %v", tt.addr, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestBaseURL covers -base-url, which defaults the port of a URL without a
+// scheme, and -controller, -probe-url and -delete-url, which do not.
+func TestBaseURL(t *testing.T) {
+	tests := []struct {
+		raw, defaultPort string
+		want             string
+		wantErr          bool
+	}{
+		{"eah.example", "8082", "http://eah.example:8082", false},
+		{"eah.example:9000", "8082", "http://eah.example:9000", false},
+		{"http://eah.example", "8082", "http://eah.example", false},
+		{"HTTPS://eah.example/", "8082", "https://eah.example", false},
+		{"192.0.2.7", "8082", "http://192.0.2.7:8082", false},
+		{"http://192.0.2.7:9000/api", "", "http://192.0.2.7:9000/api", false},
+		{"[2001:db8::1]:9000", "8082", "http://[2001:db8::1]:9000", false},
+		{"[2001:db8::1]", "8082", "http://[2001:db8::1]:8082", false},
+		{"2001:db8::1", "8082", "http://[2001:db8::1]:8082", false},
+		{"http://2001:db8::1", "", "http://[2001:db8::1]", false},
+		{"http://[2001:db8::1]/players/{player}", "", "http://[2001:db8::1]/players/{player}", false},
+		{"http://eah.example/delete?user={player}", "", "http://eah.example/delete?user={player}", false},
+		{"ftp://eah.example", "", "", true},
+		{"http://", "", "", true},
+		{"http://user:pw@eah.example", "", "", true},
+		{"http://eah.example:0", "", "", true},
+		{"http://[2001:db8::1", "", "", true},
+	}
+	for _, tt := range tests {
+		got, err := BaseURL(tt.raw, tt.defaultPort)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("BaseURL(%q, %q) = %q, %v; want %q, error %v", tt.raw, tt.defaultPort, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestHost covers the hosts of the -allow-hosts list.
+func TestHost(t *testing.T) {
+	tests := []struct{ host, want string }{
+		{"EAH.example", "eah.example"},
+		{"192.0.2.7", "192.0.2.7"},
+		{"[2001:DB8::1]", "2001:db8::1"},
+		{" 2001:db8::1 ", "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		if got := Host(tt.host); got != tt.want {
+			t.Errorf("Host(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	v4a, v4b := netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("192.0.2.2")
+	v6a, v6b := netip.MustParseAddr("2001:db8::1"), netip.MustParseAddr("2001:db8::2")
+	mapped := netip.MustParseAddr("::ffff:192.0.2.3")
+	ips := []netip.Addr{v6a, v4a, mapped, v6b, v4b}
+	if got, want := Order(ips, IPv4), []netip.Addr{v4a, mapped, v4b, v6a, v6b}; !slices.Equal(got, want) {
+		t.Errorf("Order(IPv4) = %v, want %v", got, want)
+	}
+	if got, want := Order(ips, IPv6), []netip.Addr{v6a, v6b, v4a, mapped, v4b}; !slices.Equal(got, want) {
+		t.Errorf("Order(IPv6) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,86 @@
+// Package panics recovers the panics of worker goroutines, so a bug hit by
+// one session of a multi-hour run is reported instead of ending the run.
+package panics
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Recorder counts the panics it recovered and keeps the first one. The zero
+// value is ready to use; it is safe for concurrent use.
+type Recorder struct {
+	// FailFast leaves panics alone, so they crash the process as usual.
+	FailFast bool
+
+	count atomic.Int64
+	mu    sync.Mutex
+	// first is the first panic recovered, with its stack, and firstWho
+	// the worker it happened in.
+	first    string
+	firstWho string
+}
+
+// Recover ends a panicking goroutine normally. It must be deferred directly
+// by the goroutine's function. The panic is written to stderr with its
+// stack, counted, and passed to onPanic, if not nil. With FailFast, Recover
+// does nothing.
+func (r *Recorder) Recover(who string, onPanic func(v any)) {
+	if r.FailFast {
+		return
+	}
+	v := recover()
+	if v == nil {
+		return
+	}
+	trace := fmt.Sprintf("panic: %v\n\n%s", v, debug.Stack())
+	fmt.Fprintf(os.Stderr, "Recovered from a panic in %s (-fail-fast crashes instead):\n%s\n", who, trace)
+	if r.count.Add(1) == 1 {
+		r.mu.Lock()
+		r.first, r.firstWho = trace, who
+		r.mu.Unlock()
+	}
+	if onPanic != nil {
+		onPanic(v)
+	}
+}
+
+// Count returns the number of panics recovered.
+func (r *Recorder) Count() int64 {
+	return r.count.Load()
+}
+
+// First returns the first panic recovered with its stack, and the worker
+// it happened in, or empty strings if there was none.
+func (r *Recorder) First() (trace, who string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.first, r.firstWho
+}
+
+// Print writes a line about the panics recovered, if any.
+func (r *Recorder) Print(w io.Writer) {
+	n := r.Count()
+	if n == 0 {
+		return
+	}
+	_, who := r.First()
+	fmt.Fprintf(w, "Recovered panics: %d, the first in %s; this is a bug, see the stack traces on stderr\n", n, who)
+}
+
+// Fill records the panics recovered in rep: the "panics" counter, and the
+// first one's worker and stack trace as details.
+func (r *Recorder) Fill(rep *report.Report) {
+	n := r.Count()
+	if n == 0 {
+		return
+	}
+	rep.Counters["panics"] = n
+	rep.Details["first_panic"], rep.Details["first_panic_in"] = r.First()
+}
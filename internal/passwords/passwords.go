@@ -0,0 +1,218 @@
+// Package passwords gives the passwords of the accounts the commands create.
+// By default a password is a prefix followed by the player's index, as the
+// first tools did; with a secret it is derived from the secret and the
+// username, so it is reproducible from the secret alone, differs for every
+// username, and follows a policy of length and character classes.
+package passwords
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"elastic-ai-jam-2025/internal/errclass"
+)
+
+// Class is a class of characters a derived password contains.
+type Class string
+
+const (
+	Lower  Class = "lower"
+	Upper  Class = "upper"
+	Digit  Class = "digit"
+	Symbol Class = "symbol"
+)
+
+// AllClasses lists every class.
+var AllClasses = []Class{Lower, Upper, Digit, Symbol}
+
+// chars returns the characters of c. The symbols leave out quotes,
+// backslashes, colons and spaces, which credentials files, shells and JSON
+// would need escaped.
+func (c Class) chars() string {
+	switch c {
+	case Lower:
+		return "abcdefghijklmnopqrstuvwxyz"
+	case Upper:
+		return "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	case Digit:
+		return "0123456789"
+	case Symbol:
+		return "!#%+-.=?@_~"
+	}
+	return ""
+}
+
+// Classes is a set of classes, given on the command line as a
+// comma-separated list. It implements flag.Value.
+type Classes []Class
+
+func (cs *Classes) String() string {
+	if cs == nil {
+		return ""
+	}
+	names := make([]string, len(*cs))
+	for i, c := range *cs {
+		names[i] = string(c)
+	}
+	return strings.Join(names, ",")
+}
+
+// Set parses a comma-separated list of classes, each kept once.
+func (cs *Classes) Set(s string) error {
+	var out Classes
+	for _, name := range strings.Split(s, ",") {
+		c := Class(strings.TrimSpace(name))
+		if c == "" || slices.Contains(out, c) {
+			continue
+		}
+		if !slices.Contains(AllClasses, c) {
+			return fmt.Errorf("unknown character class %q (want %s)", c, (*Classes)(&AllClasses))
+		}
+		out = append(out, c)
+	}
+	*cs = out
+	return nil
+}
+
+// Policy tells the password of each account.
+type Policy struct {
+	// Prefix, followed by the player's index, is the password of every
+	// account while Secret is empty.
+	Prefix string
+	// Secret, when set, derives the passwords from HMAC-SHA256 of the
+	// username keyed with it instead.
+	Secret string
+	// Length and Classes shape the derived passwords: Length characters,
+	// drawn from Classes, with at least one of each.
+	Length  int
+	Classes Classes
+}
+
+// Default returns the policy of the commands: prefix passwords, and 20
+// characters of every class once a secret is given.
+func Default(prefix string) Policy {
+	return Policy{Prefix: prefix, Length: 20, Classes: slices.Clone(Classes(AllClasses))}
+}
+
+// Deterministic reports whether the passwords are derived from a secret,
+// so a credentials file may list the usernames alone.
+func (p Policy) Deterministic() bool {
+	return p.Secret != ""
+}
+
+// Validate checks that the policy can derive passwords.
+func (p Policy) Validate() error {
+	if !p.Deterministic() {
+		return nil
+	}
+	if len(p.Classes) == 0 {
+		return errors.New("-password-classes needs at least one class")
+	}
+	if p.Length < len(p.Classes) {
+		return fmt.Errorf("-password-length %d is too short to hold the %d classes of -password-classes", p.Length, len(p.Classes))
+	}
+	return nil
+}
+
+// Password returns the password of the account username, the index-th
+// player: derived from the secret if there is one, else the prefix followed
+// by index.
+func (p Policy) Password(username string, index int) string {
+	if !p.Deterministic() {
+		return p.Prefix + strconv.Itoa(index)
+	}
+	return p.derive(username)
+}
+
+// Describe says how the passwords are made, without revealing them.
+func (p Policy) Describe() string {
+	if !p.Deterministic() {
+		return "prefix followed by the player index"
+	}
+	return fmt.Sprintf("derived from -password-secret and the username: %d characters of %s", p.Length, &p.Classes)
+}
+
+// derive draws the password of username from a stream keyed by the secret:
+// one character of each class, then characters of any class, shuffled so
+// the classes do not sit at fixed positions.
+func (p Policy) derive(username string) string {
+	s := newStream(p.Secret, username)
+	var all string
+	for _, c := range p.Classes {
+		all += c.chars()
+	}
+	out := make([]byte, p.Length)
+	for i := range out {
+		set := all
+		if i < len(p.Classes) {
+			set = p.Classes[i].chars()
+		}
+		out[i] = set[s.intn(len(set))]
+	}
+	for i := len(out) - 1; i > 0; i-- {
+		j := s.intn(i + 1)
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// stream is a deterministic byte stream: the blocks HMAC-SHA256(secret,
+// username, counter) one after the other.
+type stream struct {
+	mac      hash.Hash
+	username string
+	counter  uint32
+	block    []byte
+}
+
+func newStream(secret, username string) *stream {
+	return &stream{mac: hmac.New(sha256.New, []byte(secret)), username: username}
+}
+
+func (s *stream) byte() byte {
+	if len(s.block) == 0 {
+		s.mac.Reset()
+		s.mac.Write([]byte(s.username))
+		s.mac.Write([]byte{0})
+		s.mac.Write(binary.BigEndian.AppendUint32(nil, s.counter))
+		s.block = s.mac.Sum(nil)
+		s.counter++
+	}
+	b := s.block[0]
+	s.block = s.block[1:]
+	return b
+}
+
+// intn returns a uniform number in [0, n), n at most 256, rejecting the
+// bytes past the largest multiple of n so no character is favored.
+func (s *stream) intn(n int) int {
+	limit := 256 - 256%n
+	for {
+		if b := int(s.byte()); b < limit {
+			return b % n
+		}
+	}
+}
+
+// warned is set once a password policy rejection was warned about.
+var warned atomic.Bool
+
+// NoteRejection warns on stderr, once per process, when err is a
+// registration rejected for its password, so a policy the server starts
+// enforcing shows at the first failure rather than in the summary.
+func NoteRejection(err error) {
+	if errclass.Classify(err) != errclass.PasswordPolicy || !warned.CompareAndSwap(false, true) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "WARNING: the server rejected a password by its policy: %v\n", err)
+	fmt.Fprintln(os.Stderr, "WARNING: every registration may fail the same way; derive passwords that meet it with -password-secret, -password-length and -password-classes")
+}
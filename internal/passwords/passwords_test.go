@@ -0,0 +1,137 @@
+package passwords
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// secretPolicy is the default policy with a secret.
+func secretPolicy(secret string) Policy {
+	p := Default("pw")
+	p.Secret = secret
+	return p
+}
+
+func TestDerivedPasswordsAreDeterministic(t *testing.T) {
+	p := secretPolicy("jam-night")
+	// Pinned: a change to the derivation would lock every account out.
+	pinned := map[string]string{"team-1": "ak?znX#QdIdfH978+tE#", "team-2": "kL3fq8jT.yqF0+dPmRRQ"}
+	for username, want := range pinned {
+		if got := p.Password(username, 0); got != want {
+			t.Errorf("password of %s = %q, want %q", username, got, want)
+		}
+	}
+
+	seen := make(map[string]string)
+	for i := range 500 {
+		username := fmt.Sprintf("team-%d", i)
+		pw := p.Password(username, i)
+		if again := secretPolicy("jam-night").Password(username, i+7); again != pw {
+			t.Fatalf("%s got %q, then %q", username, pw, again)
+		}
+		if other, ok := seen[pw]; ok {
+			t.Fatalf("%s and %s share the password %q", other, username, pw)
+		}
+		seen[pw] = username
+		if secretPolicy("jam-day").Password(username, i) == pw {
+			t.Errorf("%s has the same password under another secret", username)
+		}
+	}
+}
+
+func TestDerivedPasswordsMeetThePolicy(t *testing.T) {
+	tests := []struct {
+		length  int
+		classes Classes
+	}{
+		{20, Classes(AllClasses)},
+		{4, Classes(AllClasses)},
+		{8, Classes{Digit}},
+		{12, Classes{Lower, Upper}},
+		{64, Classes{Symbol, Digit}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d %s", tt.length, &tt.classes), func(t *testing.T) {
+			p := Policy{Secret: "s", Length: tt.length, Classes: tt.classes}
+			if err := p.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			var allowed string
+			for _, c := range tt.classes {
+				allowed += c.chars()
+			}
+			for i := range 300 {
+				pw := p.Password(fmt.Sprintf("player%d", i), i)
+				if len(pw) != tt.length {
+					t.Fatalf("%q has %d characters, want %d", pw, len(pw), tt.length)
+				}
+				if strings.Trim(pw, allowed) != "" {
+					t.Fatalf("%q has characters outside %s", pw, &tt.classes)
+				}
+				for _, c := range tt.classes {
+					if !strings.ContainsAny(pw, c.chars()) {
+						t.Fatalf("%q has no %s character", pw, c)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPrefixPasswords(t *testing.T) {
+	p := Default("secret")
+	if p.Deterministic() {
+		t.Error("a policy without a secret is deterministic")
+	}
+	if got := p.Password("player7", 7); got != "secret7" {
+		t.Errorf("password = %q, want secret7", got)
+	}
+	if err := (Policy{Prefix: "p"}).Validate(); err != nil {
+		t.Errorf("prefix passwords need no classes: %v", err)
+	}
+	if strings.Contains(secretPolicy("jam-night").Describe(), "jam-night") {
+		t.Error("the description reveals the secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       Policy
+		wantErr bool
+	}{
+		{"default", secretPolicy("s"), false},
+		{"one character per class", Policy{Secret: "s", Length: 2, Classes: Classes{Lower, Digit}}, false},
+		{"too short for the classes", Policy{Secret: "s", Length: 3, Classes: Classes(AllClasses)}, true},
+		{"no classes", Policy{Secret: "s", Length: 20}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.p.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestClassesSet(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"lower,digit", "lower,digit", false},
+		{" upper , lower,upper,", "upper,lower", false},
+		{"lower,emoji", "", true},
+	}
+	for _, tt := range tests {
+		var cs Classes
+		err := cs.Set(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Set(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && cs.String() != tt.want {
+			t.Errorf("Set(%q) = %s, want %s", tt.in, &cs, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package play
+
+import "elastic-ai-jam-2025/internal/pokerclient"
+
+// chipTracker follows the session's stack between the prompts that report
+// it: the moves sent take chips out, the pots won and the game over stack
+// put them back. A prompt's chips are the server's word and replace the
+// tracked ones; a difference over the tolerance counts as a correction,
+// the sign of an event we missed or misread. The stack never goes below
+// zero.
+type chipTracker struct {
+	self      string
+	tolerance int
+	// chips is the tracked stack, -1 until the server first reported it.
+	chips int
+}
+
+func newChipTracker(self string, tolerance int) chipTracker {
+	return chipTracker{self: self, tolerance: tolerance, chips: -1}
+}
+
+// Observe updates the stack with a server message. playerID is the ID the
+// server assigned the session, if any: messages naming it or the username
+// are about the session's stack.
+func (c *chipTracker) Observe(resp *pokerclient.ServerResponse, playerID string) {
+	mine := func(id string) bool {
+		return pokerclient.SamePlayer(id, c.self) || playerID != "" && pokerclient.SamePlayer(id, playerID)
+	}
+	switch resp.Type {
+	case pokerclient.TypeActionPlayerBet:
+		if !mine(resp.State.Player.PlayerID) {
+			return
+		}
+		c.report(resp.State.Player.Chips)
+	case pokerclient.TypePotWon:
+		var ev struct {
+			PlayerID string  `json:"player_id"`
+			Amount   float64 `json:"amount"`
+		}
+		if resp.DecodeEvent(&ev) == nil && mine(ev.PlayerID) {
+			c.add(int(ev.Amount))
+		}
+	case pokerclient.TypeGameOver:
+		var ev struct {
+			Players []struct {
+				PlayerID string  `json:"player_id"`
+				Chips    float64 `json:"chips"`
+			} `json:"players"`
+		}
+		resp.DecodeEvent(&ev)
+		for _, p := range ev.Players {
+			if mine(p.PlayerID) {
+				c.report(int(p.Chips))
+			}
+		}
+	}
+}
+
+// Moved takes the chips of a move sent out of the stack.
+func (c *chipTracker) Moved(m pokerclient.Move) {
+	c.add(-m.Amount())
+}
+
+// report replaces the stack with the one the server reported.
+func (c *chipTracker) report(chips int) {
+	if chips < 0 {
+		chipsClamped.Inc()
+		chips = 0
+	}
+	if c.chips >= 0 && abs(c.chips-chips) > c.tolerance {
+		chipCorrections.Inc()
+	}
+	c.chips = chips
+}
+
+// add changes the stack by n chips, once it is known.
+func (c *chipTracker) add(n int) {
+	if c.chips < 0 {
+		return
+	}
+	c.chips += n
+	if c.chips < 0 {
+		chipsClamped.Inc()
+		c.chips = 0
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// withinStack keeps a move answering the prompt t within the stack: a bet
+// of more chips than the stack goes all-in instead, and a bet of less than
+// one chip checks when the minimum bet allows it and folds otherwise.
+// Moves it had to change count as invalid bets prevented.
+func withinStack(m pokerclient.Move, t pokerclient.Turn) pokerclient.Move {
+	if m.Folds() || m.Amount() == 0 && !m.IsAllIn() {
+		return m // a fold or a check
+	}
+	chips := max(t.Chips, 0)
+	switch {
+	case m.Amount() < 1 || chips < 1:
+		invalidBetsPrevented.Inc()
+		if t.MinimumBet <= 0 {
+			return pokerclient.Check()
+		}
+		return pokerclient.Fold()
+	case m.Amount() > chips:
+		invalidBetsPrevented.Inc()
+		m, _ = pokerclient.AllIn(chips)
+		return m
+	}
+	return m
+}
@@ -0,0 +1,153 @@
+package play
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+func TestChipTracker(t *testing.T) {
+	bet, _ := pokerclient.Bet(300)
+	allIn, _ := pokerclient.AllIn(2000)
+	steps := []struct {
+		name string
+		// resp is observed, or else move is sent.
+		resp                 *pokerclient.ServerResponse
+		move                 pokerclient.Move
+		chips                int
+		clamped, corrections int64
+	}{
+		{"unknown before a prompt", nil, bet, -1, 0, 0},
+		{"a pot won before a prompt", potWon("g1", "me", 50), pokerclient.Move{}, -1, 0, 0},
+		{"first prompt", betPrompt("g1", "me", 1000), pokerclient.Move{}, 1000, 0, 0},
+		{"another player's prompt", betPrompt("g1", "other", 5), pokerclient.Move{}, 1000, 0, 0},
+		{"bet", nil, bet, 700, 0, 0},
+		{"pot won", potWon("g1", "me", 600), pokerclient.Move{}, 1300, 0, 0},
+		{"another player's pot", potWon("g1", "other", 600), pokerclient.Move{}, 1300, 0, 0},
+		{"prompt agreeing", betPrompt("g1", "Me", 1300), pokerclient.Move{}, 1300, 0, 0},
+		{"prompt disagreeing", betPrompt("g1", "me", 1250), pokerclient.Move{}, 1250, 0, 1},
+		{"all-in over the stack", nil, allIn, 0, 1, 1},
+		{"negative pot", potWon("g1", "me", -40), pokerclient.Move{}, 0, 2, 1},
+		{"negative prompt", betPrompt("g1", "me", -50), pokerclient.Move{}, 0, 3, 1},
+		{"fold", nil, pokerclient.Fold(), 0, 3, 1},
+		{"game over", gameOver("g1", 90), pokerclient.Move{}, 90, 3, 2},
+	}
+	c := newChipTracker("me", 0)
+	clamped, corrections := chipsClamped.Load(), chipCorrections.Load()
+	for _, s := range steps {
+		if s.resp != nil {
+			c.Observe(s.resp, "")
+		} else {
+			c.Moved(s.move)
+		}
+		if c.chips != s.chips {
+			t.Errorf("%s: chips %d, want %d", s.name, c.chips, s.chips)
+		}
+		if got := chipsClamped.Load() - clamped; got != s.clamped {
+			t.Errorf("%s: %d clamped, want %d", s.name, got, s.clamped)
+		}
+		if got := chipCorrections.Load() - corrections; got != s.corrections {
+			t.Errorf("%s: %d corrections, want %d", s.name, got, s.corrections)
+		}
+	}
+}
+
+func TestChipTrackerTolerance(t *testing.T) {
+	c := newChipTracker("me", 20)
+	corrections := chipCorrections.Load()
+	c.Observe(betPrompt("g1", "me", 1000), "")
+	c.Observe(betPrompt("g1", "me", 980), "") // a blind posted without an event
+	if got := chipCorrections.Load() - corrections; got != 0 {
+		t.Errorf("%d corrections within the tolerance, want 0", got)
+	}
+	c.Observe(betPrompt("g1", "me", 950), "")
+	if got := chipCorrections.Load() - corrections; got != 1 {
+		t.Errorf("%d corrections past the tolerance, want 1", got)
+	}
+}
+
+func TestChipTrackerAssignedID(t *testing.T) {
+	c := newChipTracker("me", 0)
+	corrections := chipCorrections.Load()
+	bet, _ := pokerclient.Bet(300)
+	over := &pokerclient.ServerResponse{
+		Type:  pokerclient.TypeGameOver,
+		Event: []byte(`{"players":[{"player_id":"me-2","chips":5},{"player_id":"p-42","chips":1700}]}`),
+	}
+	steps := []struct {
+		resp  *pokerclient.ServerResponse
+		chips int
+	}{
+		{betPrompt("g1", "p-42", 1000), 1000},
+		{betPrompt("g1", "p-7", 5), 1000},
+		{nil, 700},
+		{potWon("g1", " P-42 ", 1000), 1700},
+		{betPrompt("g1", "me", 1700), 1700},
+		{over, 1700},
+	}
+	for i, s := range steps {
+		if s.resp != nil {
+			c.Observe(s.resp, "p-42")
+		} else {
+			c.Moved(bet)
+		}
+		if c.chips != s.chips {
+			t.Errorf("step %d: chips %d, want %d", i, c.chips, s.chips)
+		}
+	}
+	if got := chipCorrections.Load() - corrections; got != 0 {
+		t.Errorf("%d corrections, want none", got)
+	}
+	c = newChipTracker("me", 0)
+	c.Observe(betPrompt("g1", "p-42", 1000), "")
+	if c.chips != -1 {
+		t.Errorf("a prompt for an ID before one was assigned set the stack to %d", c.chips)
+	}
+}
+
+// overBet bets more than its stack: stale chips, as a strategy would with a
+// count that went wrong.
+type overBet struct{ retryMinimum }
+
+func (overBet) Bet(t Turn) pokerclient.Move {
+	m, _ := pokerclient.Bet(t.Chips + 500)
+	return m
+}
+
+// TestNoInvalidBets drives a session through prompts and pots that do not
+// add up, and checks that every move sent was a fold, a check, or a bet of
+// at least one chip and at most the stack of its prompt.
+func TestNoInvalidBets(t *testing.T) {
+	prompt := func(chips, minimum int, stage string) string {
+		return fmt.Sprintf(`{"type":"action_player_bet","game_id":"g1","stage":%q,"state":{"player":{"player_id":"me","chips":%d}},"minimum_bet":%d}`, stage, chips, minimum)
+	}
+	lines := []string{
+		prompt(1000, 10, pokerclient.StagePreFlop),
+		// After an all-in of 1000, a negative pot and a stack from nowhere.
+		`{"type":"event_pot_won","game_id":"g1","event":{"player_id":"me","amount":-300}}`,
+		prompt(200, 10, pokerclient.StagePreFlop),
+		prompt(80, 0, pokerclient.StageFlop),
+		prompt(-50, 10, pokerclient.StageTurn),
+		`{"type":"event_game_over","game_id":"g1","event":{}}`,
+	}
+	stacks := []int{1000, 200, 80, 0}
+	addr, bets := scriptedServer(t, lines...)
+	prevented := invalidBetsPrevented.Load()
+	ps := playSession(t, testConfig(), addr, "me", overBet{})
+	ps.conn.Close()
+
+	got := <-bets
+	if want := []int{1000, 200, 80, -1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("bets sent %v, want %v", got, want)
+	}
+	for i, amount := range got {
+		if amount != -1 && amount != 0 && (amount < 1 || i < len(stacks) && amount > stacks[i]) {
+			t.Errorf("move %d: bet %d against a stack of %d", i, amount, stacks[i])
+		}
+	}
+	if n := invalidBetsPrevented.Load() - prevented; n != 3 {
+		t.Errorf("%d invalid bets prevented, want the 3 over the stack", n)
+	}
+}
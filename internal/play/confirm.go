@@ -0,0 +1,166 @@
+package play
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// confirmation is what the messages following a move told about it.
+type confirmation int
+
+const (
+	// confirmPending: nothing was told yet, or no move is watched.
+	confirmPending confirmation = iota
+	// confirmApplied: the move was confirmed within the window.
+	confirmApplied
+	// confirmLate: the move was confirmed, after the window.
+	confirmLate
+	// confirmDropped: the server prompted again as if the move never
+	// arrived.
+	confirmDropped
+)
+
+// actionConfirmer watches the messages following each move the session sent
+// for a sign that the server applied it. We used to assume every move took
+// effect, until prompts showed our stack unchanged after a bet.
+//
+// A move is confirmed by the server reporting it as our action, by a prompt
+// to another player, by a prompt to us at a later stage or with fewer chips,
+// or by the pot being won, all of which only happen once the server moved
+// past our turn. A prompt to us at the same stage with the same stack, or
+// for a check the same minimum bet, means the move was dropped. A
+// confirmation later than the window counts as unconfirmed, like a drop and
+// a move still watched when the session ends, but the rest of the session
+// does not depend on it. Error replies are rejections, counted elsewhere;
+// they end the watch of the move they answer.
+type actionConfirmer struct {
+	self   string
+	window time.Duration
+
+	// pending is set while move, sent at sentAt in answer to a prompt at
+	// stage with chips and minimumBet, is watched. resent is set when the
+	// move resends a dropped one.
+	pending    bool
+	move       pokerclient.Move
+	stage      string
+	chips      int
+	minimumBet int
+	sentAt     time.Time
+	resent     bool
+}
+
+// newActionConfirmer returns a confirmer for the moves of self, or one
+// watching nothing when window is not positive.
+func newActionConfirmer(self string, window time.Duration) actionConfirmer {
+	return actionConfirmer{self: self, window: window}
+}
+
+// sent starts watching m, sent at now in answer to the prompt t. resent
+// marks the resend of a dropped move.
+func (c *actionConfirmer) sent(m pokerclient.Move, t pokerclient.Turn, now time.Time, resent bool) {
+	if c.window <= 0 {
+		return
+	}
+	c.pending, c.move, c.stage, c.chips, c.minimumBet, c.sentAt, c.resent = true, m, t.Stage, t.Chips, t.MinimumBet, now, resent
+}
+
+// observe judges the move watched with a message received at now. A
+// verdict other than confirmPending ends the watch.
+func (c *actionConfirmer) observe(resp *pokerclient.ServerResponse, now time.Time) confirmation {
+	if !c.pending {
+		return confirmPending
+	}
+	verdict := confirmPending
+	switch {
+	case resp.Type == "" && resp.Code != 0:
+		c.pending = false
+		return confirmPending
+	case resp.Type == pokerclient.TypeActionPlayerBet:
+		switch p := resp.State.Player; {
+		case p.PlayerID == "":
+		case !pokerclient.SamePlayer(p.PlayerID, c.self), resp.Stage != c.stage, p.Chips < c.chips:
+			verdict = confirmApplied
+		case c.move.Amount() == 0 && !c.move.Folds() && !c.move.IsAllIn() && resp.MinimumBet != c.minimumBet:
+			verdict = confirmApplied // a check, and someone bet since
+		default:
+			verdict = confirmDropped
+		}
+	case resp.Type == pokerclient.TypePotWon, resp.Type == pokerclient.TypeGameOver:
+		verdict = confirmApplied
+	default:
+		if a, ok := pokerclient.PlayerActionOf(resp); ok && pokerclient.SamePlayer(a.PlayerID, c.self) {
+			verdict = confirmApplied
+		}
+	}
+	if verdict == confirmPending {
+		return verdict
+	}
+	c.pending = false
+	if verdict == confirmApplied && now.Sub(c.sentAt) > c.window {
+		verdict = confirmLate
+	}
+	return verdict
+}
+
+// finish ends the watch, when the session ends, and reports whether a move
+// was still watched.
+func (c *actionConfirmer) finish() bool {
+	pending := c.pending
+	c.pending = false
+	return pending
+}
+
+// confirmAction records the verdict on the move watched that resp tells.
+// For a dropped move, it decides how to answer the prompt repeating it: a
+// move not resent yet is resent when the strategy wants, and anything else
+// folds.
+func (ps *PlayerSessionState) confirmAction(resp *pokerclient.ServerResponse, now time.Time) {
+	resent, move := ps.confirm.resent, ps.confirm.move
+	switch ps.confirm.observe(resp, now) {
+	case confirmApplied:
+		movesConfirmed.Inc()
+	case confirmLate:
+		movesConfirmedLate.Inc()
+		ps.result.UnconfirmedMoves++
+	case confirmDropped:
+		movesDropped.Inc()
+		ps.result.UnconfirmedMoves++
+		t := pokerclient.Turn{GameID: resp.GameID, Stage: resp.Stage, Chips: resp.State.Player.Chips, MinimumBet: resp.MinimumBet}
+		redo := pokerclient.Fold()
+		if !resent && ps.strategy.OnActionUnconfirmed(ps.turn(t), move) {
+			redo = move
+			droppedResent.Inc()
+		} else {
+			droppedFolded.Inc()
+		}
+		ps.logVerbose("Move %s seems dropped: prompted again at %s with %d chips. Answering with %s.", move, resp.Stage, resp.State.Player.Chips, redo)
+		ps.redo = &redo
+	}
+}
+
+// finishConfirm counts a move still watched when the session ends.
+func (ps *PlayerSessionState) finishConfirm() {
+	if ps.confirm.finish() {
+		movesUnsettled.Inc()
+		ps.result.UnconfirmedMoves++
+	}
+}
+
+// printConfirmations writes how many moves were confirmed, once any was
+// watched.
+func printConfirmations(w io.Writer, window time.Duration) {
+	confirmed, late, dropped, unsettled := movesConfirmed.Load(), movesConfirmedLate.Load(), movesDropped.Load(), movesUnsettled.Load()
+	watched := confirmed + late + dropped + unsettled
+	if watched == 0 {
+		return
+	}
+	unconfirmed := late + dropped + unsettled
+	fmt.Fprintf(w, "Moves unconfirmed within %s: %d of %d (%.2f%%): %d confirmed late, %d dropped, %d unsettled at the end\n",
+		window, unconfirmed, watched, 100*float64(unconfirmed)/float64(watched), late, dropped, unsettled)
+	if dropped > 0 {
+		fmt.Fprintf(w, "Dropped moves resent: %d, folded: %d\n", droppedResent.Load(), droppedFolded.Load())
+	}
+}
@@ -0,0 +1,198 @@
+package play
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/mockserver"
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// stagePrompt is a bet prompt to player at stage, with chips left, asking
+// for at least minimum.
+func stagePrompt(player, stage string, chips, minimum int) *pokerclient.ServerResponse {
+	resp := minBetPrompt("g1", player, chips, minimum)
+	resp.Stage = stage
+	return resp
+}
+
+func TestActionConfirmer(t *testing.T) {
+	bet, _ := pokerclient.Bet(20)
+	allIn, _ := pokerclient.AllIn(1000)
+	preFlop := pokerclient.Turn{GameID: "g1", Stage: pokerclient.StagePreFlop, Chips: 1000, MinimumBet: 20}
+	flop := pokerclient.Turn{GameID: "g1", Stage: pokerclient.StageFlop, Chips: 980, MinimumBet: 0}
+	sec := time.Second
+	type message struct {
+		resp *pokerclient.ServerResponse
+		at   time.Duration // after the move
+		want confirmation
+	}
+	tests := []struct {
+		name     string
+		window   time.Duration
+		move     pokerclient.Move
+		turn     pokerclient.Turn
+		messages []message
+		// pending is whether the move is still watched at the end.
+		pending bool
+	}{
+		{"our action reported", 5 * sec, bet, preFlop, []message{
+			{moveEvent("g1", "Me", "bet"), sec, confirmApplied},
+		}, false},
+		{"another player prompted", 5 * sec, bet, preFlop, []message{
+			{stagePrompt("other", pokerclient.StagePreFlop, 1000, 20), sec, confirmApplied},
+		}, false},
+		{"prompted at a later stage", 5 * sec, bet, preFlop, []message{
+			{stagePrompt("me", pokerclient.StageFlop, 980, 0), sec, confirmApplied},
+		}, false},
+		{"prompted with fewer chips", 5 * sec, bet, preFlop, []message{
+			{stagePrompt("me", pokerclient.StagePreFlop, 980, 40), sec, confirmApplied},
+		}, false},
+		{"pot won", 5 * sec, pokerclient.Fold(), preFlop, []message{
+			{potWon("g1", "other", 40), sec, confirmApplied},
+		}, false},
+		{"game over", 5 * sec, allIn, preFlop, []message{
+			{gameOver("g1", 0), sec, confirmApplied},
+		}, false},
+		{"other messages wait", 5 * sec, bet, preFlop, []message{
+			{moveEvent("g1", "other", "call"), sec, confirmPending},
+			{stagePrompt("", pokerclient.StagePreFlop, 1000, 20), 2 * sec, confirmPending},
+			{moveEvent("g1", "me", "bet"), 3 * sec, confirmApplied},
+			{moveEvent("g1", "me", "bet"), 4 * sec, confirmPending},
+		}, false},
+		{"dropped", 5 * sec, bet, preFlop, []message{
+			{stagePrompt("me", pokerclient.StagePreFlop, 1000, 20), sec, confirmDropped},
+			{stagePrompt("me", pokerclient.StagePreFlop, 1000, 20), 2 * sec, confirmPending},
+		}, false},
+		{"dropped check", 5 * sec, pokerclient.Check(), flop, []message{
+			{stagePrompt("me", pokerclient.StageFlop, 980, 0), sec, confirmDropped},
+		}, false},
+		{"check followed by a bet", 5 * sec, pokerclient.Check(), flop, []message{
+			{stagePrompt("me", pokerclient.StageFlop, 980, 40), sec, confirmApplied},
+		}, false},
+		{"confirmed late", 5 * sec, bet, preFlop, []message{
+			{moveEvent("g1", "other", "call"), 4 * sec, confirmPending},
+			{stagePrompt("other", pokerclient.StagePreFlop, 1000, 20), 6 * sec, confirmLate},
+		}, false},
+		{"confirmed at the window", 5 * sec, bet, preFlop, []message{
+			{moveEvent("g1", "me", "bet"), 5 * sec, confirmApplied},
+		}, false},
+		{"dropped after the window", 5 * sec, bet, preFlop, []message{
+			{stagePrompt("me", pokerclient.StagePreFlop, 1000, 20), 10 * sec, confirmDropped},
+		}, false},
+		{"error reply ends the watch", 5 * sec, bet, preFlop, []message{
+			{&pokerclient.ServerResponse{Code: 400, Message: "invalid bet"}, sec, confirmPending},
+			{stagePrompt("me", pokerclient.StagePreFlop, 1000, 20), 2 * sec, confirmPending},
+		}, false},
+		{"never told", 5 * sec, bet, preFlop, []message{
+			{moveEvent("g1", "other", "call"), sec, confirmPending},
+		}, true},
+		{"disabled", 0, bet, preFlop, []message{
+			{stagePrompt("me", pokerclient.StagePreFlop, 1000, 20), sec, confirmPending},
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newActionConfirmer("me", tt.window)
+			sent := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+			c.sent(tt.move, tt.turn, sent, false)
+			for i, m := range tt.messages {
+				if got := c.observe(m.resp, sent.Add(m.at)); got != m.want {
+					t.Errorf("message %d: verdict %d, want %d", i, got, m.want)
+				}
+			}
+			if got := c.finish(); got != tt.pending {
+				t.Errorf("still watched at the end: %v, want %v", got, tt.pending)
+			}
+			if c.finish() {
+				t.Error("watched after finishing")
+			}
+		})
+	}
+}
+
+// dropFolder folds rather than resend a dropped move.
+type dropFolder struct{ acceptRejection }
+
+func (dropFolder) Bet(t Turn) pokerclient.Move { return minBet{}.Bet(t) }
+
+// TestDroppedMoves plays hands whose prompts come again as if our moves
+// never arrived, and checks how the session answers the repeats.
+func TestDroppedMoves(t *testing.T) {
+	const (
+		prompt   = `{"type":"action_player_bet","game_id":"g1","stage":"pre_flop","state":{"player":{"player_id":"me","chips":1000}},"minimum_bet":10}`
+		applied  = `{"type":"event_player_action","game_id":"g1","event":{"player_id":"me","action":"bet","amount":10}}`
+		won      = `{"type":"event_pot_won","game_id":"g1","event":{"player_id":"other","amount":20}}`
+		gameOver = `{"type":"event_game_over","game_id":"g1","event":{}}`
+	)
+	tests := []struct {
+		name      string
+		strategy  Strategy
+		lines     []string
+		bets      []int
+		confirmed int64
+		dropped   int64
+		resent    int64
+		folded    int64
+		// unconfirmed are the session's unconfirmed moves.
+		unconfirmed int
+	}{
+		{"confirmed", minBet{}, []string{prompt, applied, won, gameOver}, []int{10}, 1, 0, 0, 0, 0},
+		{"dropped and resent", minBet{}, []string{prompt, prompt, applied, won, gameOver}, []int{10, 10}, 1, 1, 1, 0, 1},
+		{"resent and dropped again", minBet{}, []string{prompt, prompt, prompt, won, gameOver}, []int{10, 10, -1}, 1, 2, 1, 1, 2},
+		{"dropped and folded", dropFolder{}, []string{prompt, prompt, won, gameOver}, []int{10, -1}, 1, 1, 0, 1, 1},
+		{"unsettled at the end", minBet{}, []string{prompt}, []int{10}, 0, 0, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confirmed, dropped := movesConfirmed.Load(), movesDropped.Load()
+			resent, folded := droppedResent.Load(), droppedFolded.Load()
+			addr, bets := scriptedServer(t, tt.lines...)
+			cfg := testConfig()
+			cfg.ReadTimeout = 200 * time.Millisecond // ends the unsettled hand
+			ps := playSession(t, cfg, addr, "me", tt.strategy)
+			ps.conn.Close()
+			if got := <-bets; !reflect.DeepEqual(got, tt.bets) {
+				t.Errorf("bets sent %v, want %v", got, tt.bets)
+			}
+			for _, c := range []struct {
+				name      string
+				got, want int64
+			}{
+				{"confirmed", movesConfirmed.Load() - confirmed, tt.confirmed},
+				{"dropped", movesDropped.Load() - dropped, tt.dropped},
+				{"resent", droppedResent.Load() - resent, tt.resent},
+				{"folded", droppedFolded.Load() - folded, tt.folded},
+			} {
+				if c.got != c.want {
+					t.Errorf("%d moves %s, want %d", c.got, c.name, c.want)
+				}
+			}
+			if ps.result.UnconfirmedMoves != tt.unconfirmed {
+				t.Errorf("%d unconfirmed moves in the result, want %d", ps.result.UnconfirmedMoves, tt.unconfirmed)
+			}
+		})
+	}
+}
+
+// TestDroppedMovesAgainstMock plays against a mock server that ignores a
+// share of the moves, and checks the session saw every drop the server made.
+func TestDroppedMovesAgainstMock(t *testing.T) {
+	srv := startMock(t, mockserver.Config{Dealer: mockserver.DealerCall, HandsPerGame: 20, DropPercent: 30, Seed: 7})
+	dropped, resent := movesDropped.Load(), droppedResent.Load()
+	ps := playSession(t, testConfig(), srv.Addr(), "me", minBet{})
+	serverDropped := srv.Stats().DroppedMoves
+	if serverDropped == 0 {
+		t.Fatal("the mock server dropped no moves; pick another seed")
+	}
+	if got := movesDropped.Load() - dropped; got != serverDropped {
+		t.Errorf("%d moves seen dropped, the server dropped %d", got, serverDropped)
+	}
+	if got := droppedResent.Load() - resent; got != serverDropped {
+		t.Errorf("%d dropped moves resent, want all %d", got, serverDropped)
+	}
+	if int64(ps.result.UnconfirmedMoves) < serverDropped {
+		t.Errorf("%d unconfirmed moves in the result, fewer than the %d dropped", ps.result.UnconfirmedMoves, serverDropped)
+	}
+}
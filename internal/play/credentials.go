@@ -0,0 +1,151 @@
+package play
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+
+	"elastic-ai-jam-2025/internal/credentials"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// credentialPlan is how -players and the lines of a -credentials-file were
+// reconciled: the number of sessions to run and which account each plays.
+type credentialPlan struct {
+	accounts []credentials.Account
+	// Sessions is the number of sessions the run launches, Cycles the
+	// passes over the file they take.
+	Sessions int
+	Cycles   int
+	// Suffix is set unless -allow-duplicate-logins: the accounts reused on
+	// the second and later passes get "-r<pass>" appended to their
+	// username, so no two sessions log in as the same player at once.
+	Suffix bool
+	// Decision describes the resolution, and Warnings what the run does
+	// differently from what the flags asked.
+	Decision string
+	Warnings []string
+}
+
+// planCredentials reconciles players, the -players flag (playersSet when it
+// was given), with the lines of a credentials file. Without -players there
+// is one session per line. With it, players caps the lines used; a file
+// with fewer lines runs them all and warns, unless repeat cycles the file to
+// reach players, suffixing the usernames of the later cycles unless allowDup.
+func planCredentials(accounts []credentials.Account, players int, playersSet, repeat, allowDup bool) (*credentialPlan, error) {
+	lines := len(accounts)
+	if lines == 0 {
+		return nil, errors.New("the credentials file has no accounts")
+	}
+	if players < 1 {
+		return nil, fmt.Errorf("-players must be positive, got %d", players)
+	}
+	p := &credentialPlan{accounts: accounts, Cycles: 1, Suffix: !allowDup}
+	switch {
+	case !playersSet:
+		if repeat {
+			return nil, errors.New("-repeat-credentials needs -players, the number of sessions to reach")
+		}
+		p.Sessions = lines
+		p.Decision = fmt.Sprintf("-players not set: %d sessions, one per line of the credentials file", lines)
+	case players <= lines:
+		p.Sessions = players
+		p.Decision = fmt.Sprintf("-players %d: using the first %d of %d lines of the credentials file", players, players, lines)
+		if repeat {
+			p.Decision += "; -repeat-credentials not needed"
+		}
+	case !repeat:
+		p.Sessions = lines
+		p.Decision = fmt.Sprintf("-players %d: running %d sessions, one per line of the credentials file", players, lines)
+		p.Warnings = append(p.Warnings, fmt.Sprintf("-players %d exceeds the %d lines of the credentials file; add -repeat-credentials to cycle it", players, lines))
+	default:
+		p.Sessions = players
+		p.Cycles = (players + lines - 1) / lines
+		p.Decision = fmt.Sprintf("-players %d: cycling the %d lines of the credentials file %d times", players, lines, p.Cycles)
+		if p.Suffix {
+			p.Decision += `, suffixing the usernames of cycle 2 and later with "-r<cycle>"`
+		} else {
+			p.Decision += ", reusing the usernames (-allow-duplicate-logins)"
+		}
+	}
+	if allowDup && p.Cycles == 1 {
+		p.Decision += "; -allow-duplicate-logins applies only to sessions reusing a line"
+	}
+	return p, nil
+}
+
+// account returns the account of player id. Ids past the plan's sessions,
+// such as -replace-busted replacements and -soak sessions, keep cycling the
+// file the same way.
+func (p *credentialPlan) account(id int) credentials.Account {
+	a := p.accounts[id%len(p.accounts)]
+	if cycle := id/len(p.accounts) + 1; cycle > 1 && p.Suffix {
+		a.Username += "-r" + strconv.Itoa(cycle)
+	}
+	return a
+}
+
+// Print writes the resolution, and its warnings to warn.
+func (p *credentialPlan) Print(w, warn io.Writer) {
+	if p == nil {
+		return
+	}
+	fmt.Fprintf(w, "Credentials: %s\n", p.Decision)
+	for _, s := range p.Warnings {
+		fmt.Fprintf(warn, "Warning: %s\n", s)
+	}
+}
+
+// Fill records the resolution in rep.
+func (p *credentialPlan) Fill(rep *report.Report) {
+	if p == nil {
+		return
+	}
+	rep.Details["credentials"] = p.Decision
+	for i, s := range p.Warnings {
+		rep.Details["credentials_warning_"+strconv.Itoa(i+1)] = s
+	}
+	rep.Counters["credentials_lines"] = int64(len(p.accounts))
+	rep.Counters["credentials_cycles"] = int64(p.Cycles)
+}
+
+// account returns the account of player id: from the credentials file, or
+// the username prefix followed by id, with the password of -password-prefix
+// or -password-secret.
+func (cfg *Config) account(id int) credentials.Account {
+	if cfg.logins != nil {
+		return cfg.logins.account(id)
+	}
+	username := cfg.BaseUsername + strconv.Itoa(id)
+	return credentials.Account{Username: username, Password: cfg.Passwords.Password(username, id)}
+}
+
+// resolveCredentials reads -credentials-file, if set, and sets NumPlayers to
+// the sessions it resolves to with -players, parsed into fs.
+func (cfg *Config) resolveCredentials(fs *flag.FlagSet, waves []int) error {
+	if cfg.CredentialsFile == "" {
+		if cfg.RepeatCredentials {
+			return errors.New("-repeat-credentials needs -credentials-file")
+		}
+		if cfg.AllowDuplicateLogins {
+			return errors.New("-allow-duplicate-logins needs -credentials-file")
+		}
+		return nil
+	}
+	if waves != nil {
+		return errors.New("-waves gives each wave its own -username-prefix range and cannot use -credentials-file")
+	}
+	accounts, err := credentials.Read(cfg.CredentialsFile, cfg.Passwords)
+	if err != nil {
+		return err
+	}
+	playersSet := false
+	fs.Visit(func(f *flag.Flag) { playersSet = playersSet || f.Name == "players" })
+	if cfg.logins, err = planCredentials(accounts, cfg.NumPlayers, playersSet, cfg.RepeatCredentials, cfg.AllowDuplicateLogins); err != nil {
+		return err
+	}
+	cfg.NumPlayers = cfg.logins.Sessions
+	return nil
+}
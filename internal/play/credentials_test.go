@@ -0,0 +1,179 @@
+package play
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/credentials"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// accounts returns the lines of a credentials file of usernames, each with
+// the password "p" followed by its username.
+func accounts(usernames ...string) []credentials.Account {
+	var out []credentials.Account
+	for _, u := range usernames {
+		out = append(out, credentials.Account{Username: u, Password: "p" + u})
+	}
+	return out
+}
+
+func TestPlanCredentials(t *testing.T) {
+	lines := accounts("a", "b", "c")
+	tests := []struct {
+		name             string
+		players          int
+		playersSet       bool
+		repeat, allowDup bool
+		wantUsernames    []string
+		wantCycles       int
+		wantWarning      bool
+		wantDecision     string
+		wantErr          bool
+	}{
+		// -players not set: one session per line.
+		{"file alone", 10, false, false, false, []string{"a", "b", "c"}, 1, false, "one per line", false},
+		{"repeat without -players", 10, false, true, false, nil, 0, false, "", true},
+		{"duplicates without -players", 10, false, false, true, []string{"a", "b", "c"}, 1, false, "applies only to sessions reusing a line", false},
+		{"repeat and duplicates without -players", 10, false, true, true, nil, 0, false, "", true},
+		// -players below the lines: it caps them.
+		{"fewer players", 2, true, false, false, []string{"a", "b"}, 1, false, "first 2 of 3 lines", false},
+		{"fewer players, repeat", 2, true, true, false, []string{"a", "b"}, 1, false, "-repeat-credentials not needed", false},
+		{"fewer players, duplicates", 2, true, false, true, []string{"a", "b"}, 1, false, "applies only", false},
+		{"fewer players, repeat and duplicates", 2, true, true, true, []string{"a", "b"}, 1, false, "not needed; -allow-duplicate-logins applies only", false},
+		{"as many players as lines", 3, true, false, false, []string{"a", "b", "c"}, 1, false, "first 3 of 3 lines", false},
+		// -players above the lines.
+		{"more players", 5, true, false, false, []string{"a", "b", "c"}, 1, true, "running 3 sessions", false},
+		{"more players, duplicates", 5, true, false, true, []string{"a", "b", "c"}, 1, true, "applies only", false},
+		{"more players, repeat", 5, true, true, false, []string{"a", "b", "c", "a-r2", "b-r2"}, 2, false, `suffixing the usernames of cycle 2 and later with "-r<cycle>"`, false},
+		{"more players, repeat and duplicates", 5, true, true, true, []string{"a", "b", "c", "a", "b"}, 2, false, "reusing the usernames", false},
+		{"three cycles", 7, true, true, false, []string{"a", "b", "c", "a-r2", "b-r2", "c-r2", "a-r3"}, 3, false, "3 times", false},
+		{"no players", 0, true, false, false, nil, 0, false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := planCredentials(lines, tt.players, tt.playersSet, tt.repeat, tt.allowDup)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var usernames []string
+			for id := range p.Sessions {
+				a := p.account(id)
+				usernames = append(usernames, a.Username)
+				if want := lines[id%len(lines)].Password; a.Password != want {
+					t.Errorf("session %d has the password %q, want %q", id, a.Password, want)
+				}
+			}
+			if !reflect.DeepEqual(usernames, tt.wantUsernames) {
+				t.Errorf("usernames = %q, want %q", usernames, tt.wantUsernames)
+			}
+			if p.Cycles != tt.wantCycles {
+				t.Errorf("cycles = %d, want %d", p.Cycles, tt.wantCycles)
+			}
+			if warned := len(p.Warnings) > 0; warned != tt.wantWarning {
+				t.Errorf("warnings = %q, want a warning %v", p.Warnings, tt.wantWarning)
+			}
+			if !strings.Contains(p.Decision, tt.wantDecision) {
+				t.Errorf("decision %q does not say %q", p.Decision, tt.wantDecision)
+			}
+		})
+	}
+	if _, err := planCredentials(nil, 3, true, false, false); err == nil {
+		t.Error("planning an empty credentials file did not fail")
+	}
+}
+
+func TestCredentialPlanReplacementsKeepCycling(t *testing.T) {
+	p, err := planCredentials(accounts("a", "b"), 2, true, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A replacement session after the planned ones gets a suffix too.
+	if got := p.account(5).Username; got != "b-r3" {
+		t.Errorf("account of session 5 = %q, want b-r3", got)
+	}
+}
+
+func TestCredentialPlanReport(t *testing.T) {
+	lines := accounts("a", "b")
+	p, err := planCredentials(lines, 5, true, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out, warn strings.Builder
+	p.Print(&out, &warn)
+	if !strings.HasPrefix(out.String(), "Credentials: -players 5") || !strings.Contains(warn.String(), "Warning: -players 5 exceeds") {
+		t.Errorf("printed %q, warned %q", out.String(), warn.String())
+	}
+	rep := report.New("play", nil)
+	p.Fill(rep)
+	if rep.Details["credentials"] != p.Decision || rep.Details["credentials_warning_1"] == "" {
+		t.Errorf("report details = %v", rep.Details)
+	}
+	if rep.Counters["credentials_lines"] != 2 || rep.Counters["credentials_cycles"] != 1 {
+		t.Errorf("report counters = %v", rep.Counters)
+	}
+
+	var none *credentialPlan
+	none.Print(io.Discard, io.Discard)
+	rep = report.New("play", nil)
+	none.Fill(rep)
+	if len(rep.Details) != 0 {
+		t.Errorf("a run without a credentials file reported %v", rep.Details)
+	}
+}
+
+func TestResolveCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.txt")
+	if err := os.WriteFile(path, []byte("# team accounts\na:pa\nb:pb\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		args      []string
+		waves     []int
+		wantPlays []string
+		wantErr   bool
+	}{
+		{"no file", []string{"-players", "2"}, nil, []string{"player0", "player1"}, false},
+		{"repeat without a file", []string{"-repeat-credentials"}, nil, nil, true},
+		{"duplicates without a file", []string{"-allow-duplicate-logins"}, nil, nil, true},
+		{"file alone", []string{"-credentials-file", path}, nil, []string{"a", "b"}, false},
+		{"file cycled", []string{"-credentials-file", path, "-players", "3", "-repeat-credentials"}, nil, []string{"a", "b", "a-r2"}, false},
+		{"file with waves", []string{"-credentials-file", path}, []int{1, 2}, nil, true},
+		{"missing file", []string{"-credentials-file", path + ".missing"}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.BaseUsername = "player"
+			fs := flag.NewFlagSet("play", flag.ContinueOnError)
+			cfg.RegisterFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			err := cfg.resolveCredentials(fs, tt.waves)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var plays []string
+			for id := range cfg.NumPlayers {
+				plays = append(plays, cfg.account(id).Username)
+			}
+			if !reflect.DeepEqual(plays, tt.wantPlays) {
+				t.Errorf("sessions play %q, want %q", plays, tt.wantPlays)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package play
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"elastic-ai-jam-2025/internal/metrics"
+)
+
+// Where the deadline of a turn came from.
+const (
+	// DeadlinePrompt is a deadline the bet prompt carried.
+	DeadlinePrompt = "prompt"
+	// DeadlineConfigured is -turn-timeout after the prompt was received.
+	DeadlineConfigured = "configured"
+	// DeadlineUnknown is a turn with neither.
+	DeadlineUnknown = "unknown"
+)
+
+// promptDeadlineFields are the fields a prompt may carry its deadline in,
+// at its top level or in its state: an absolute time, as RFC 3339 or Unix
+// seconds or milliseconds.
+var promptDeadlineFields = []string{"deadline", "turn_deadline", "expires_at", "action_deadline"}
+
+// promptTimeoutFields are the fields a prompt may carry the time left to
+// act in, relative to its receipt: milliseconds for the _ms ones, seconds
+// for the others.
+var promptTimeoutFields = []string{"turn_timeout_ms", "time_left_ms", "timeout_ms", "turn_timeout", "time_left", "timeout"}
+
+// turnClock times the session's answer to one bet prompt against the
+// deadline of the turn.
+type turnClock struct {
+	// received is when the prompt came, deadline when the turn ends, zero
+	// when unknown, and source where that deadline came from.
+	received time.Time
+	deadline time.Time
+	source   string
+}
+
+// startTurn times a prompt received at now, with its deadline read from
+// raw, or -turn-timeout after now.
+func (cfg *Config) startTurn(raw json.RawMessage, now time.Time) turnClock {
+	if d, ok := promptDeadline(raw, now); ok {
+		return turnClock{received: now, deadline: d, source: DeadlinePrompt}
+	}
+	if cfg.TurnTimeout > 0 {
+		return turnClock{received: now, deadline: now.Add(cfg.TurnTimeout), source: DeadlineConfigured}
+	}
+	return turnClock{received: now, source: DeadlineUnknown}
+}
+
+// promptDeadline reads the deadline of a prompt received at now from the
+// first of promptDeadlineFields or promptTimeoutFields it has.
+func promptDeadline(raw json.RawMessage, now time.Time) (time.Time, bool) {
+	var p struct {
+		State map[string]json.RawMessage `json:"state"`
+	}
+	var top map[string]json.RawMessage
+	if json.Unmarshal(raw, &top) != nil {
+		return time.Time{}, false
+	}
+	json.Unmarshal(raw, &p)
+	for _, fields := range []map[string]json.RawMessage{top, p.State} {
+		for _, name := range promptDeadlineFields {
+			if v, ok := fields[name]; ok {
+				if t, ok := parseInstant(v); ok {
+					return t, true
+				}
+			}
+		}
+		for _, name := range promptTimeoutFields {
+			v, ok := fields[name]
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseFloat(string(v), 64)
+			if err != nil || n <= 0 {
+				continue
+			}
+			unit := time.Second
+			if len(name) > 3 && name[len(name)-3:] == "_ms" {
+				unit = time.Millisecond
+			}
+			return now.Add(time.Duration(n * float64(unit))), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseInstant parses an absolute time: an RFC 3339 string, or a Unix time
+// in seconds or, past the year 2286 in seconds, milliseconds.
+func parseInstant(v json.RawMessage) (time.Time, bool) {
+	var s string
+	if json.Unmarshal(v, &s) == nil {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		return t, err == nil
+	}
+	n, err := strconv.ParseFloat(string(v), 64)
+	if err != nil || n <= 0 {
+		return time.Time{}, false
+	}
+	if n > 1e10 {
+		return time.UnixMilli(int64(n)), true
+	}
+	return time.Unix(0, int64(n*float64(time.Second))), true
+}
+
+// budget is how much of the turn, from now, the session may still spend
+// before sending its move: what is left of -turn-budget of the turn, or
+// false when the deadline is unknown.
+func (c turnClock) budget(cfg *Config, now time.Time) (time.Duration, bool) {
+	if c.deadline.IsZero() {
+		return 0, false
+	}
+	return max(time.Duration(cfg.TurnBudget*float64(c.deadline.Sub(c.received)))-now.Sub(c.received), 0), true
+}
+
+// think returns how long to wait before sending the move decided at now:
+// a random time up to -think-time, cut to what is left of the budget. A
+// strategy that alone used up the budget counts as an overrun.
+func (c turnClock) think(cfg *Config, r *rand.Rand, now time.Time) time.Duration {
+	left, known := c.budget(cfg, now)
+	if known && left == 0 {
+		turnBudgetOverruns.Inc()
+	}
+	if cfg.ThinkTime <= 0 {
+		return 0
+	}
+	wait := time.Duration(r.Int64N(int64(cfg.ThinkTime) + 1))
+	if known && wait > left {
+		wait = left
+		thinkTimeCut.Inc()
+	}
+	return wait
+}
+
+// sent records the move sent at now: how long the turn took, and how long
+// before its deadline the move went out.
+func (c turnClock) sent(now time.Time) {
+	if c.received.IsZero() {
+		return
+	}
+	turnResponse.Record(now.Sub(c.received))
+	turnDeadlineSources[c.source].Inc()
+	if c.deadline.IsZero() {
+		return
+	}
+	if margin := c.deadline.Sub(now); margin >= 0 {
+		turnMargin.Record(margin)
+	} else {
+		turnsPastDeadline.Inc()
+	}
+}
+
+// Turn timing, across sessions.
+var (
+	turnResponse       = registry.Histogram("turn_response", "Time from a bet prompt to the move answering it.")
+	turnMargin         = registry.Histogram("turn_margin", "Time left before the turn's deadline when the move was sent.")
+	turnsPastDeadline  = registry.Counter("turns_past_deadline", "Moves sent after the turn's deadline.")
+	turnBudgetOverruns = registry.Counter("turn_budget_overruns", "Turns whose strategy alone used up the -turn-budget share of the turn.")
+	thinkTimeCut       = registry.Counter("think_time_cut", "Think times cut short to stay within -turn-budget.")
+
+	turnDeadlineSources = map[string]*metrics.Counter{
+		DeadlinePrompt:     registry.Counter("turn_deadlines_prompt", "Turns whose deadline the prompt gave."),
+		DeadlineConfigured: registry.Counter("turn_deadlines_configured", "Turns timed against -turn-timeout."),
+		DeadlineUnknown:    registry.Counter("turn_deadlines_unknown", "Turns with no deadline known."),
+	}
+)
+
+// printTurnTiming writes how fast the sessions answered their prompts and
+// the headroom they had before the deadlines.
+func printTurnTiming(w io.Writer) {
+	if turnResponse.Count() == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Turn response time: %s\n", turnResponse.Summary())
+	fmt.Fprintf(w, "  deadlines: from the prompt %d, configured %d, unknown %d\n",
+		turnDeadlineSources[DeadlinePrompt].Load(), turnDeadlineSources[DeadlineConfigured].Load(), turnDeadlineSources[DeadlineUnknown].Load())
+	if turnMargin.Count()+turnsPastDeadline.Load() > 0 {
+		fmt.Fprintf(w, "  margin before the deadline: %s, past it: %d\n", turnMargin.Summary(), turnsPastDeadline.Load())
+	}
+	if n, m := turnBudgetOverruns.Load(), thinkTimeCut.Load(); n+m > 0 {
+		fmt.Fprintf(w, "  strategy over the turn budget: %d, think times cut to fit it: %d\n", n, m)
+	}
+}
@@ -0,0 +1,199 @@
+package play
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// handTally counts hands of one kind: how many, how many were won, and the
+// stack change summed over those whose stacks are known.
+type handTally struct {
+	hands, won, measured int
+	chipsDelta           int64
+}
+
+func (t *handTally) add(h HandResult) {
+	t.hands++
+	if h.Won {
+		t.won++
+	}
+	if h.ChipsStart >= 0 && h.ChipsEnd >= 0 {
+		t.measured++
+		t.chipsDelta += int64(h.ChipsEnd - h.ChipsStart)
+	}
+}
+
+func (t handTally) merge(o handTally) handTally {
+	return handTally{t.hands + o.hands, t.won + o.won, t.measured + o.measured, t.chipsDelta + o.chipsDelta}
+}
+
+// effectTally is the data the strategy effectiveness section is computed
+// from, summed over sessions.
+type effectTally struct {
+	allIn, folded handTally
+	// sessions and sessionsDelta are the sessions whose chips are known
+	// and their summed chips delta; baseline and baselineDelta the same for
+	// the sessions that folded every prompt.
+	sessions, baseline           int
+	sessionsDelta, baselineDelta int64
+}
+
+// tallyHands splits hands into those in which the session went all-in and
+// those it folded without going all-in. Hands in which it only checked or
+// bet, or did not move, are in neither.
+func tallyHands(hands []HandResult) (allIn, folded handTally) {
+	for _, h := range hands {
+		switch handMove(h) {
+		case "all-in":
+			allIn.add(h)
+		case "fold":
+			folded.add(h)
+		}
+	}
+	return allIn, folded
+}
+
+// handMove returns "all-in" if the session went all-in in h, otherwise
+// "fold" if it folded, otherwise "".
+func handMove(h HandResult) string {
+	folded := false
+	for _, m := range h.Moves {
+		if strings.HasPrefix(m, "all-in") {
+			return "all-in"
+		}
+		folded = folded || m == "fold"
+	}
+	if folded {
+		return "fold"
+	}
+	return ""
+}
+
+// alwaysFolded reports whether r folded at every prompt it answered, as
+// spectating sessions do.
+func alwaysFolded(r *SessionResult) bool {
+	return r.Folds > 0 && r.Bets == 0 && r.AllIns == 0
+}
+
+// strategyEffectiveness computes the strategy effectiveness section from t,
+// or returns nil when t has neither hands nor sessions.
+func strategyEffectiveness(t effectTally) *report.StrategyEffectiveness {
+	if t.allIn.hands+t.folded.hands == 0 && t.sessions == 0 && t.baseline == 0 {
+		return nil
+	}
+	e := &report.StrategyEffectiveness{
+		AllIn:            t.allIn.outcomes(),
+		Folded:           t.folded.outcomes(),
+		Sessions:         t.sessions,
+		BaselineSessions: t.baseline,
+	}
+	if t.sessions > 0 {
+		e.SessionEV = float64(t.sessionsDelta) / float64(t.sessions)
+	}
+	if t.baseline > 0 {
+		ev := float64(t.baselineDelta) / float64(t.baseline)
+		e.BaselineEV = &ev
+	}
+	return e
+}
+
+func (t handTally) outcomes() report.HandOutcomes {
+	o := report.HandOutcomes{Hands: t.hands, Won: t.won, Measured: t.measured}
+	if t.hands > 0 {
+		o.WinRate = 100 * float64(t.won) / float64(t.hands)
+	}
+	if t.measured > 0 {
+		o.MeanChipsDelta = float64(t.chipsDelta) / float64(t.measured)
+	}
+	return o
+}
+
+// effectLog collects the effectiveness data of the finished sessions. It is
+// safe for concurrent use.
+type effectLog struct {
+	mu sync.Mutex
+	t  effectTally
+}
+
+// add records a finished session and its hands.
+func (l *effectLog) add(r *SessionResult, hands []HandResult) {
+	allIn, folded := tallyHands(hands)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.t.allIn = l.t.allIn.merge(allIn)
+	l.t.folded = l.t.folded.merge(folded)
+	if r.FinalChips < 0 {
+		return
+	}
+	l.t.sessions++
+	l.t.sessionsDelta += int64(r.ChipsDelta)
+	l.addBaseline(r)
+}
+
+// addBaseline counts r in the always-fold baseline if it folded every
+// prompt. l.mu must be held.
+func (l *effectLog) addBaseline(r *SessionResult) {
+	if r.FinalChips >= 0 && alwaysFolded(r) {
+		l.t.baseline++
+		l.t.baselineDelta += int64(r.ChipsDelta)
+	}
+}
+
+// loadBaseline adds the sessions of a -results-out file, typically of a
+// spectate run, that folded every prompt to the baseline. It returns how
+// many it added.
+func (l *effectLog) loadBaseline(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("reading baseline results: %w", err)
+	}
+	defer f.Close()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	before := l.t.baseline
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		var r SessionResult
+		if json.Unmarshal(sc.Bytes(), &r) == nil && r.Player != "" {
+			l.addBaseline(&r)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return 0, fmt.Errorf("reading baseline results: %w", err)
+	}
+	return l.t.baseline - before, nil
+}
+
+// snapshot returns the section of the sessions recorded so far.
+func (l *effectLog) snapshot() *report.StrategyEffectiveness {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strategyEffectiveness(l.t)
+}
+
+// printStrategyEffectiveness writes the strategy effectiveness section.
+func printStrategyEffectiveness(w io.Writer, e *report.StrategyEffectiveness) {
+	if e == nil {
+		return
+	}
+	fmt.Fprintln(w, "Strategy effectiveness:")
+	fmt.Fprintf(w, "  All-in hands: %d, won %d (%.1f%%), mean chips delta %+.1f over %d measured\n",
+		e.AllIn.Hands, e.AllIn.Won, e.AllIn.WinRate, e.AllIn.MeanChipsDelta, e.AllIn.Measured)
+	fmt.Fprintf(w, "  Folded hands: %d, mean chips delta %+.1f over %d measured, the cost of folding\n",
+		e.Folded.Hands, e.Folded.MeanChipsDelta, e.Folded.Measured)
+	fmt.Fprintf(w, "  Mean chips delta per session: %+.1f over %d sessions\n", e.SessionEV, e.Sessions)
+	if e.BaselineEV != nil {
+		fmt.Fprintf(w, "  Always-fold baseline: %+.1f over %d sessions, the strategy's edge over it %+.1f per session\n",
+			*e.BaselineEV, e.BaselineSessions, e.SessionEV-*e.BaselineEV)
+	} else {
+		fmt.Fprintln(w, "  Always-fold baseline: no session folded every prompt (see -baseline-results)")
+	}
+}
@@ -0,0 +1,123 @@
+package play
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// hand is a hand with the moves of the session and its stack before and
+// after, -1 if unknown.
+func hand(start, end int, won bool, moves ...string) HandResult {
+	return HandResult{GameID: "g1", Hand: 1, Moves: moves, ChipsStart: start, ChipsEnd: end, Won: won}
+}
+
+func TestTallyHands(t *testing.T) {
+	hands := []HandResult{
+		hand(1000, 2000, true, "all-in 1000"),
+		hand(500, 0, false, "bet 10", "all-in 490"),
+		hand(800, -1, true, "fold", "all-in 800"), // a rejected fold, retried as all-in
+		hand(1000, 990, false, "bet 10", "fold"),
+		hand(990, 990, false, "fold"),
+		hand(-1, 980, false, "fold"),
+		hand(1000, 1020, true, "check", "bet 10"),
+		hand(1000, 1000, false),
+	}
+	allIn, folded := tallyHands(hands)
+	if want := (handTally{hands: 3, won: 2, measured: 2, chipsDelta: 500}); allIn != want {
+		t.Errorf("all-in tally = %+v, want %+v", allIn, want)
+	}
+	if want := (handTally{hands: 3, won: 0, measured: 2, chipsDelta: -10}); folded != want {
+		t.Errorf("fold tally = %+v, want %+v", folded, want)
+	}
+}
+
+func TestStrategyEffectiveness(t *testing.T) {
+	ev := func(f float64) *float64 { return &f }
+	tests := []struct {
+		name string
+		t    effectTally
+		want *report.StrategyEffectiveness
+	}{
+		{"nothing recorded", effectTally{}, nil},
+		{
+			name: "no baseline",
+			t: effectTally{
+				allIn:         handTally{hands: 4, won: 1, measured: 4, chipsDelta: -1000},
+				folded:        handTally{hands: 2, measured: 2, chipsDelta: -20},
+				sessions:      4,
+				sessionsDelta: -1000,
+			},
+			want: &report.StrategyEffectiveness{
+				AllIn:     report.HandOutcomes{Hands: 4, Won: 1, Measured: 4, WinRate: 25, MeanChipsDelta: -250},
+				Folded:    report.HandOutcomes{Hands: 2, Measured: 2, MeanChipsDelta: -10},
+				Sessions:  4,
+				SessionEV: -250,
+			},
+		},
+		{
+			name: "baseline sessions alone",
+			t:    effectTally{sessions: 2, sessionsDelta: -30, baseline: 2, baselineDelta: -30},
+			want: &report.StrategyEffectiveness{Sessions: 2, SessionEV: -15, BaselineSessions: 2, BaselineEV: ev(-15)},
+		},
+		{
+			name: "hands without known stacks",
+			t:    effectTally{allIn: handTally{hands: 2, won: 2}},
+			want: &report.StrategyEffectiveness{AllIn: report.HandOutcomes{Hands: 2, Won: 2, WinRate: 100}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strategyEffectiveness(tt.t); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("strategyEffectiveness(%+v) = %+v, want %+v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEffectLog(t *testing.T) {
+	var l effectLog
+	l.add(&SessionResult{Player: "a", FinalChips: 2000, ChipsDelta: 1000, AllIns: 1},
+		[]HandResult{hand(1000, 2000, true, "all-in 1000")})
+	l.add(&SessionResult{Player: "b", FinalChips: 990, ChipsDelta: -10, Folds: 1},
+		[]HandResult{hand(1000, 990, false, "fold")})
+	// Chips unknown: its hands count, the session does not.
+	l.add(&SessionResult{Player: "c", FinalChips: -1, Folds: 1},
+		[]HandResult{hand(1000, 980, false, "fold")})
+
+	path := filepath.Join(t.TempDir(), "baseline.ndjson")
+	lines := `{"player":"s-0","final_chips":980,"chips_delta":-20,"folds":3}
+not a result
+{"player":"s-1","final_chips":1010,"chips_delta":10,"folds":2,"bets":1}
+{"player":"s-2","final_chips":960,"chips_delta":-40,"folds":1}
+`
+	if err := os.WriteFile(path, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	n, err := l.loadBaseline(path)
+	if err != nil || n != 2 {
+		t.Fatalf("loadBaseline = %d, %v; want the 2 sessions that folded every prompt", n, err)
+	}
+	if _, err := l.loadBaseline(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("loading a missing baseline did not fail")
+	}
+
+	want := &report.StrategyEffectiveness{
+		AllIn:            report.HandOutcomes{Hands: 1, Won: 1, Measured: 1, WinRate: 100, MeanChipsDelta: 1000},
+		Folded:           report.HandOutcomes{Hands: 2, Measured: 2, MeanChipsDelta: -15},
+		Sessions:         2,
+		SessionEV:        495,
+		BaselineSessions: 3,
+	}
+	got := l.snapshot()
+	if got == nil || got.BaselineEV == nil || *got.BaselineEV != -70.0/3 {
+		t.Fatalf("baseline of %+v, want a mean of %v", got, -70.0/3)
+	}
+	got.BaselineEV = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,292 @@
+package play
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Built-in averages of a session, used without -calibrate-from. They are
+// rough figures of the jam server; the report of an earlier run against the
+// same server gives better ones.
+const (
+	defaultMessagesPerGame = 150
+	defaultMovesPerGame    = 8
+	defaultSessionLifetime = time.Minute
+)
+
+// What a run holds besides its sessions. sessionMemory is the heap and
+// stack of a session with its connection, baseMemory the rest of the
+// process, and baseFDs the descriptors of stdio, the output files and the
+// metrics listener.
+const (
+	sessionMemory = 48 << 10
+	baseMemory    = 32 << 20
+	baseFDs       = 16
+)
+
+// runPlan is what a run is set to do, as far as its cost goes.
+type runPlan struct {
+	// Basis describes the plan, e.g. "1000 players".
+	Basis string
+	// Sessions are launched Concurrency at a time, at most JoinRate per
+	// second when positive, and for at most Span when positive.
+	Sessions    int64
+	Concurrency int64
+	JoinRate    float64
+	Span        time.Duration
+	// Waves, when set, replaces the above: the sessions run in waves of
+	// these sizes, each draining before the next.
+	Waves []int
+	// Soak, when positive, keeps that many sessions running for Span
+	// instead.
+	Soak int64
+
+	PoolSize int64
+	// LogFiles is the most per-session log files open at once.
+	LogFiles int64
+	// MaxHands caps the moves of a session, when positive.
+	MaxHands int
+	// HTTPRequests are the requests made besides those per game, and
+	// Enrich adds one per game joined.
+	HTTPRequests int64
+	Enrich       bool
+}
+
+// runAverages are the per-session and per-game figures an estimate
+// assumes, a game being one the session joined.
+type runAverages struct {
+	// Source is the report they were measured in, "" for the built-in
+	// ones.
+	Source          string
+	GamesPerSession float64
+	MessagesPerGame float64
+	MovesPerGame    float64
+	SessionLifetime time.Duration
+}
+
+// plan returns the plan of cfg, with waves as parsed from -waves. A soak
+// without -duration is estimated over one hour.
+func (cfg *Config) plan(waves []int) runPlan {
+	p := runPlan{
+		Sessions:    int64(cfg.NumPlayers),
+		Concurrency: int64(cfg.MaxConcurrent),
+		JoinRate:    cfg.JoinRate,
+		Span:        cfg.Duration,
+		PoolSize:    int64(cfg.PoolSize),
+		MaxHands:    cfg.MaxHands,
+		Enrich:      cfg.Enrich,
+	}
+	switch {
+	case cfg.Soak > 0:
+		p.Soak = int64(cfg.Soak)
+		if p.Span <= 0 {
+			p.Span = time.Hour
+		}
+		p.Basis = fmt.Sprintf("soak of %d sessions for %s", cfg.Soak, p.Span)
+	case len(waves) > 0:
+		p.Waves = waves
+		p.Basis = "waves " + cfg.Waves
+	default:
+		p.Basis = fmt.Sprintf("%d players", cfg.NumPlayers)
+		if cfg.Duration > 0 {
+			p.Basis += fmt.Sprintf(" over at most %s", cfg.Duration)
+		}
+	}
+	if cfg.LogDir != "" {
+		p.LogFiles = int64(cfg.LogDirMaxFiles)
+	}
+	if cfg.VerifyChips {
+		p.HTTPRequests += int64(cfg.VerifyChipsRetries) + 1
+	}
+	return p
+}
+
+// defaultAverages returns the built-in averages for cfg. A spectating
+// session joins once and watches -spectate-games games, whose messages and
+// moves count as those of the one game joined.
+func defaultAverages(cfg *Config) runAverages {
+	a := runAverages{
+		GamesPerSession: 1,
+		MessagesPerGame: defaultMessagesPerGame,
+		MovesPerGame:    defaultMovesPerGame,
+		SessionLifetime: defaultSessionLifetime,
+	}
+	if cfg.Strategy == strategySpectate {
+		n := float64(cfg.SpectateGames)
+		a.MessagesPerGame *= n
+		a.MovesPerGame *= n
+		a.SessionLifetime *= time.Duration(cfg.SpectateGames)
+	}
+	return a
+}
+
+// calibrate replaces the averages of a with those measured in rep, the
+// report of an earlier play run read from path. Averages rep did not
+// measure are kept.
+func calibrate(a runAverages, rep *report.Report, path string) (runAverages, error) {
+	if rep.Command != "play" {
+		return a, fmt.Errorf("-calibrate-from %s is a %s report, not a play one", path, rep.Command)
+	}
+	c := rep.Counters
+	a.Source = path
+	if sessions, games := c["sessions_launched"], c["games_joined"]; sessions > 0 && games > 0 {
+		a.GamesPerSession = float64(games) / float64(sessions)
+	}
+	if games := c["games_joined"]; games > 0 {
+		if n := c["messages_received"]; n > 0 {
+			a.MessagesPerGame = float64(n) / float64(games)
+		} else if observed := c["games_observed"]; observed > 0 && c["events_captured"] > 0 {
+			a.MessagesPerGame = float64(c["events_captured"]) / float64(observed)
+		}
+		if moves := c["bets"] + c["all_ins"] + c["folds"]; moves > 0 {
+			a.MovesPerGame = float64(moves) / float64(games)
+		}
+	}
+	if l := rep.Latencies["session_lifetime"]; l.Count > 0 && l.MeanMs > 0 {
+		a.SessionLifetime = time.Duration(l.MeanMs * float64(time.Millisecond))
+	}
+	return a, nil
+}
+
+// estimate returns the estimate of cfg's run, with waves as parsed from
+// -waves, calibrated from -calibrate-from when set.
+func (cfg *Config) estimate(waves []int) (*report.RunEstimate, error) {
+	a := defaultAverages(cfg)
+	if cfg.CalibrateFrom != "" {
+		rep, err := report.ReadFile(cfg.CalibrateFrom)
+		if err != nil {
+			return nil, err
+		}
+		if a, err = calibrate(a, rep, cfg.CalibrateFrom); err != nil {
+			return nil, err
+		}
+	}
+	e := estimateRun(cfg.plan(waves), a)
+	return &e, nil
+}
+
+// estimateRun returns the expected cost of running p, assuming a.
+func estimateRun(p runPlan, a runAverages) report.RunEstimate {
+	lifetime := max(a.SessionLifetime.Seconds(), 0.001)
+	e := report.RunEstimate{
+		Basis:                  p.Basis,
+		CalibratedFrom:         a.Source,
+		GamesPerSession:        a.GamesPerSession,
+		MessagesPerGame:        a.MessagesPerGame,
+		MovesPerGame:           a.MovesPerGame,
+		SessionLifetimeSeconds: lifetime,
+	}
+	switch {
+	case p.Soak > 0:
+		// Every session that ends is replaced.
+		e.PeakSessions = p.Soak
+		e.Sessions = p.Soak + int64(float64(p.Soak)*p.Span.Seconds()/lifetime)
+		e.DurationSeconds = p.Span.Seconds()
+	case len(p.Waves) > 0:
+		for _, n := range p.Waves {
+			e.Sessions += int64(n)
+			e.PeakSessions = max(e.PeakSessions, int64(n))
+			e.DurationSeconds += lifetime
+		}
+	case p.Sessions > 0:
+		concurrency := float64(max(min(p.Concurrency, p.Sessions), 1))
+		// Without a rate, the first sessions start at once and each that
+		// ends is replaced; a lower rate spreads the launches instead.
+		throughput := concurrency / lifetime
+		limited := p.JoinRate > 0 && p.JoinRate < throughput
+		if limited {
+			throughput = p.JoinRate
+		}
+		peak := concurrency
+		if limited {
+			peak = math.Min(concurrency, math.Ceil(throughput*lifetime))
+		}
+		sessions := float64(p.Sessions)
+		if p.Span > 0 {
+			sessions = math.Min(sessions, peak+throughput*p.Span.Seconds())
+		}
+		e.Sessions = int64(sessions)
+		e.PeakSessions = int64(math.Min(peak, sessions))
+		if limited {
+			e.DurationSeconds = sessions/throughput + lifetime
+		} else {
+			e.DurationSeconds = math.Ceil(sessions/concurrency) * lifetime
+		}
+	}
+	e.Connections = e.Sessions
+	e.Registrations = e.Sessions
+	e.GamesJoined = int64(math.Round(float64(e.Sessions) * a.GamesPerSession))
+	movesPerSession := a.GamesPerSession * a.MovesPerGame
+	if p.MaxHands > 0 {
+		movesPerSession = math.Min(movesPerSession, float64(p.MaxHands))
+	}
+	e.Moves = int64(math.Round(float64(e.Sessions) * movesPerSession))
+	e.MessagesReceived = int64(math.Round(float64(e.GamesJoined) * a.MessagesPerGame))
+	e.HTTPRequests = p.HTTPRequests
+	if p.Enrich {
+		e.HTTPRequests += e.GamesJoined
+	}
+	e.PeakFDs = e.PeakSessions + p.PoolSize + min(p.LogFiles, e.PeakSessions) + baseFDs
+	e.PeakMemoryMB = math.Round(float64(baseMemory+(e.PeakSessions+p.PoolSize)*sessionMemory)/(1<<20)*10) / 10
+	return e
+}
+
+// estimateDuration returns the estimated duration of e, to the second.
+func estimateDuration(e *report.RunEstimate) time.Duration {
+	return time.Duration(e.DurationSeconds * float64(time.Second)).Round(time.Second)
+}
+
+// printEstimate writes the estimate before the run starts.
+func printEstimate(w io.Writer, e *report.RunEstimate) {
+	source := "built-in averages; -calibrate-from a report of an earlier run gives better ones"
+	if e.CalibratedFrom != "" {
+		source = "averages measured in " + e.CalibratedFrom
+	}
+	fmt.Fprintf(w, "Estimate (%s):\n", source)
+	fmt.Fprintf(w, "  %s: %d connections and registrations, ~%d games joined\n", e.Basis, e.Connections, e.GamesJoined)
+	fmt.Fprintf(w, "  ~%d moves sent, ~%d messages received, ~%d HTTP requests\n", e.Moves, e.MessagesReceived, e.HTTPRequests)
+	lifetime := time.Duration(e.SessionLifetimeSeconds * float64(time.Second)).Round(time.Millisecond)
+	fmt.Fprintf(w, "  ~%s with up to %d sessions at once (%s per session, %.1f games, %.0f messages and %.1f moves per game)\n",
+		estimateDuration(e), e.PeakSessions, lifetime, e.GamesPerSession, e.MessagesPerGame, e.MovesPerGame)
+	fmt.Fprintf(w, "  peak ~%d file descriptors, ~%.0f MiB of memory\n", e.PeakFDs, e.PeakMemoryMB)
+}
+
+// exceededCeilings returns a description of each -max-est-* ceiling e
+// exceeds.
+func (cfg *Config) exceededCeilings(e *report.RunEstimate) []string {
+	var over []string
+	if cfg.MaxEstConnections > 0 && e.Connections > cfg.MaxEstConnections {
+		over = append(over, fmt.Sprintf("%d connections (-max-est-connections %d)", e.Connections, cfg.MaxEstConnections))
+	}
+	if cfg.MaxEstFDs > 0 && e.PeakFDs > cfg.MaxEstFDs {
+		over = append(over, fmt.Sprintf("%d file descriptors (-max-est-fds %d)", e.PeakFDs, cfg.MaxEstFDs))
+	}
+	if cfg.MaxEstMemoryMB > 0 && e.PeakMemoryMB > float64(cfg.MaxEstMemoryMB) {
+		over = append(over, fmt.Sprintf("%.0f MiB of memory (-max-est-memory-mb %d)", e.PeakMemoryMB, cfg.MaxEstMemoryMB))
+	}
+	if cfg.MaxEstDuration > 0 && estimateDuration(e) > cfg.MaxEstDuration {
+		over = append(over, fmt.Sprintf("a duration of %s (-max-est-duration %s)", estimateDuration(e), cfg.MaxEstDuration))
+	}
+	return over
+}
+
+// printEstimateComparison writes the estimate next to what the run did.
+func printEstimateComparison(w io.Writer, e *report.RunEstimate, elapsed time.Duration) {
+	if e == nil {
+		return
+	}
+	actual := registry.Snapshot().Counters
+	pairs := []string{
+		fmt.Sprintf("sessions %d/%d", e.Sessions, actual["sessions_launched"]),
+		fmt.Sprintf("games joined %d/%d", e.GamesJoined, actual["games_joined"]),
+		fmt.Sprintf("moves %d/%d", e.Moves, actual["bets"]+actual["all_ins"]+actual["folds"]),
+		fmt.Sprintf("messages %d/%d", e.MessagesReceived, actual["messages_received"]),
+		fmt.Sprintf("duration %s/%s", estimateDuration(e), elapsed.Round(time.Second)),
+	}
+	fmt.Fprintf(w, "Estimate vs actual: %s\n", strings.Join(pairs, ", "))
+}
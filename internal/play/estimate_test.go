@@ -0,0 +1,178 @@
+package play
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/latency"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+func TestEstimateRun(t *testing.T) {
+	averages := runAverages{GamesPerSession: 1, MessagesPerGame: 150, MovesPerGame: 8, SessionLifetime: time.Minute}
+	// A lifetime whose throughputs are exact in binary.
+	quick := averages
+	quick.SessionLifetime = 64 * time.Second
+
+	// cost is the part of an estimate that depends on the plan.
+	type cost struct {
+		sessions, peak     int64
+		duration           float64
+		games, moves, msgs int64
+		http, fds          int64
+		memoryMB           float64
+	}
+	tests := []struct {
+		name string
+		plan runPlan
+		a    runAverages
+		want cost
+	}{
+		{
+			name: "players at full concurrency",
+			plan: runPlan{Sessions: 100, Concurrency: 10},
+			a:    averages,
+			want: cost{100, 10, 600, 100, 800, 15000, 0, 26, 32.5},
+		},
+		{
+			name: "concurrency above the players",
+			plan: runPlan{Sessions: 4, Concurrency: 100},
+			a:    averages,
+			want: cost{4, 4, 60, 4, 32, 600, 0, 20, 32.2},
+		},
+		{
+			name: "join rate below the throughput",
+			plan: runPlan{Sessions: 100, Concurrency: 50, JoinRate: 0.5},
+			a:    averages,
+			want: cost{100, 30, 260, 100, 800, 15000, 0, 46, 33.4},
+		},
+		{
+			name: "join rate above the throughput",
+			plan: runPlan{Sessions: 100, Concurrency: 10, JoinRate: 100},
+			a:    averages,
+			want: cost{100, 10, 600, 100, 800, 15000, 0, 26, 32.5},
+		},
+		{
+			name: "cut short by the duration",
+			plan: runPlan{Sessions: 1000, Concurrency: 16, Span: 128 * time.Second},
+			a:    quick,
+			want: cost{48, 16, 192, 48, 384, 7200, 0, 32, 32.8},
+		},
+		{
+			name: "soak replaces the sessions that end",
+			plan: runPlan{Soak: 20, Span: time.Hour},
+			a:    averages,
+			want: cost{1220, 20, 3600, 1220, 9760, 183000, 0, 36, 32.9},
+		},
+		{
+			name: "waves drain one after the other",
+			plan: runPlan{Waves: []int{10, 50, 20}, Sessions: 999, Concurrency: 999},
+			a:    averages,
+			want: cost{80, 50, 180, 80, 640, 12000, 0, 66, 34.3},
+		},
+		{
+			name: "max hands caps the moves",
+			plan: runPlan{Sessions: 10, Concurrency: 10, MaxHands: 3},
+			a:    averages,
+			want: cost{10, 10, 60, 10, 30, 1500, 0, 26, 32.5},
+		},
+		{
+			name: "pool, log files and HTTP requests",
+			plan: runPlan{Sessions: 10, Concurrency: 10, PoolSize: 5, LogFiles: 100, HTTPRequests: 2, Enrich: true},
+			a:    averages,
+			want: cost{10, 10, 60, 10, 80, 1500, 12, 41, 32.7},
+		},
+		{
+			name: "nothing planned",
+			a:    averages,
+			want: cost{0, 0, 0, 0, 0, 0, 0, 16, 32},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := estimateRun(tt.plan, tt.a)
+			got := cost{e.Sessions, e.PeakSessions, e.DurationSeconds, e.GamesJoined, e.Moves, e.MessagesReceived, e.HTTPRequests, e.PeakFDs, e.PeakMemoryMB}
+			if got != tt.want {
+				t.Errorf("estimate = %+v, want %+v", got, tt.want)
+			}
+			if e.Connections != e.Sessions || e.Registrations != e.Sessions {
+				t.Errorf("%d connections and %d registrations for %d sessions", e.Connections, e.Registrations, e.Sessions)
+			}
+		})
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	defaults := runAverages{GamesPerSession: 1, MessagesPerGame: 150, MovesPerGame: 8, SessionLifetime: time.Minute}
+	rep := report.New("play", nil)
+	rep.Counters["sessions_launched"] = 10
+	rep.Counters["games_joined"] = 20
+	rep.Counters["messages_received"] = 4000
+	rep.Counters["bets"] = 30
+	rep.Counters["all_ins"] = 10
+	rep.Counters["folds"] = 20
+	rep.Latencies["session_lifetime"] = latency.Summary{Count: 10, MeanMs: 90_000}
+
+	got, err := calibrate(defaults, rep, "run.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := runAverages{Source: "run.json", GamesPerSession: 2, MessagesPerGame: 200, MovesPerGame: 3, SessionLifetime: 90 * time.Second}
+	if got != want {
+		t.Errorf("calibrated = %+v, want %+v", got, want)
+	}
+
+	// A spectate run counts the events it captured instead of messages.
+	spectate := report.New("play", nil)
+	spectate.Counters["sessions_launched"] = 5
+	spectate.Counters["games_joined"] = 5
+	spectate.Counters["games_observed"] = 10
+	spectate.Counters["events_captured"] = 500
+	got, err = calibrate(defaults, spectate, "spectate.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = runAverages{Source: "spectate.json", GamesPerSession: 1, MessagesPerGame: 50, MovesPerGame: 8, SessionLifetime: time.Minute}
+	if got != want {
+		t.Errorf("calibrated from a spectate run = %+v, want %+v", got, want)
+	}
+
+	// A report that measured nothing keeps the defaults.
+	got, err = calibrate(defaults, report.New("play", nil), "empty.json")
+	if want := defaults; err != nil || got.Source != "empty.json" || got.MessagesPerGame != want.MessagesPerGame || got.SessionLifetime != want.SessionLifetime {
+		t.Errorf("calibrated from an empty report = %+v, %v", got, err)
+	}
+
+	if _, err := calibrate(defaults, report.New("attack", nil), "attack.json"); err == nil {
+		t.Error("calibrating from an attack report did not fail")
+	}
+}
+
+func TestExceededCeilings(t *testing.T) {
+	e := &report.RunEstimate{Connections: 1000, PeakFDs: 1100, PeakMemoryMB: 80, DurationSeconds: 3600}
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{"no ceilings", Config{}, nil},
+		{"all under", Config{MaxEstConnections: 1000, MaxEstFDs: 1100, MaxEstMemoryMB: 80, MaxEstDuration: time.Hour}, nil},
+		{"connections", Config{MaxEstConnections: 999}, []string{"-max-est-connections"}},
+		{"all over", Config{MaxEstConnections: 10, MaxEstFDs: 1024, MaxEstMemoryMB: 64, MaxEstDuration: time.Minute},
+			[]string{"-max-est-connections", "-max-est-fds", "-max-est-memory-mb", "-max-est-duration"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cfg.exceededCeilings(e)
+			if len(got) != len(tt.want) {
+				t.Fatalf("exceeded %q, want %q", got, tt.want)
+			}
+			for i, flag := range tt.want {
+				if !strings.Contains(got[i], flag) {
+					t.Errorf("exceeded %q, want %q", got[i], flag)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+//go:build faultinject
+
+package play
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/faultinject"
+	"elastic-ai-jam-2025/internal/mockserver"
+	"elastic-ai-jam-2025/internal/netaddr"
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// injectFaults makes the test's dials go through a faultinject.Dialer
+// with cfg, until the test ends or injectFaults is called again.
+func injectFaults(t *testing.T, cfg faultinject.Config) {
+	t.Helper()
+	previous := pokerclient.DialFunc
+	pokerclient.DialFunc = faultinject.NewDialer(cfg, netaddr.Dial).Dial
+	t.Cleanup(func() { pokerclient.DialFunc = previous })
+}
+
+func TestFaultDropMidHand(t *testing.T) {
+	srv := startMock(t, mockserver.Config{HandsPerGame: 20, Bots: 3, Seed: 1})
+	cfg := testConfig()
+	// Past the registration answer and into the first hands.
+	injectFaults(t, faultinject.Config{DropAfterBytes: 2000})
+
+	ps := playSession(t, cfg, srv.Addr(), "drop-0", minBet{})
+
+	if ps.result.Outcome != outcomeDisconnected {
+		t.Errorf("outcome = %s, want %s", ps.result.Outcome, outcomeDisconnected)
+	}
+	if ps.result.Hands == 0 {
+		t.Error("the connection dropped before the first prompt; raise DropAfterBytes")
+	}
+}
+
+func TestFaultSlowReads(t *testing.T) {
+	tests := []struct {
+		name      string
+		readDelay time.Duration
+		want      Outcome
+	}{
+		{"below the read timeout", 20 * time.Millisecond, outcomeCompleted},
+		{"past the read timeout", 300 * time.Millisecond, outcomeStalled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startMock(t, mockserver.Config{Seed: 1})
+			cfg := testConfig()
+			cfg.ReadTimeout = 200 * time.Millisecond
+			cfg.RegisterTimeout = time.Second
+			cfg.SeatTimeout = 0
+			// The registration waits for longer than the delay.
+			injectFaults(t, faultinject.Config{ReadDelay: tt.readDelay})
+			ps := registeredSession(t, cfg, srv.Addr(), "slow-0", minBet{})
+
+			ps.result.Outcome = ps.play(context.Background())
+
+			if ps.result.Outcome != tt.want {
+				t.Errorf("outcome = %s, want %s", ps.result.Outcome, tt.want)
+			}
+		})
+	}
+}
+
+func TestFaultParkReconnectsThroughFailedDials(t *testing.T) {
+	srv := startMock(t, mockserver.Config{Seed: 1})
+	cfg := testConfig()
+	cfg.Park = true
+	cfg.ParkFor = 500 * time.Millisecond
+	cfg.ParkReconnectDelay = 10 * time.Millisecond
+	cfg.ParkReconnectMaxDelay = 40 * time.Millisecond
+
+	// The first connection drops right after the registration answer,
+	// whose size is measured on another account of the same length.
+	probe, err := pokerclient.Dial(srv.Addr(), cfg.ConnectTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := probe.Register("park-x", "secret")
+	probe.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	injectFaults(t, faultinject.Config{DropAfterBytes: int64(len(resp.Raw) + 1)})
+	ps := registeredSession(t, cfg, srv.Addr(), "park-0", minBet{})
+	// Then the first two reconnection dials fail.
+	injectFaults(t, faultinject.Config{FailDials: 2})
+	failures := parkReconnectFailures.Load()
+
+	outcome := ps.park(context.Background(), "secret")
+
+	if outcome != outcomeCompleted {
+		t.Errorf("outcome = %s, want %s", outcome, outcomeCompleted)
+	}
+	if n := parkReconnectFailures.Load() - failures; n != 2 {
+		t.Errorf("%d failed reconnection attempts, want 2", n)
+	}
+	if ps.result.Reconnects != 1 {
+		t.Errorf("reconnects = %d, want 1", ps.result.Reconnects)
+	}
+}
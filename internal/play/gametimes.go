@@ -0,0 +1,259 @@
+package play
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/latency"
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// gameTiming is how long a session saw a game last, from the first message
+// about it to its game over.
+type gameTiming struct {
+	GameID   string
+	Duration time.Duration
+	// TableSize is the number of players at the table: those listed in the
+	// game's details, in which case exact is set, or else the distinct
+	// players named in its prompts, moves and game over, a lower bound for
+	// a game the session left early. Zero if none was named.
+	TableSize int
+	exact     bool
+	// Censored is set when the session left the game before its game over,
+	// by ending or by moving to another game. Duration then runs to the
+	// last message about the game, a lower bound of how long it lasted.
+	Censored bool
+}
+
+// better reports whether t says more about its game's duration than o: a
+// complete observation beats a censored one, and of two of the same kind
+// the longer wins, as it started from an earlier message.
+func (t gameTiming) better(o gameTiming) bool {
+	if t.Censored != o.Censored {
+		return !t.Censored
+	}
+	return t.Duration > o.Duration
+}
+
+// mergeTiming combines two observations of the same game, by different
+// sessions: the better duration, and the exact table size if either has it,
+// else the larger.
+func mergeTiming(a, b gameTiming) gameTiming {
+	m := a
+	if b.better(a) {
+		m = b
+	}
+	switch {
+	case a.exact:
+		m.TableSize, m.exact = a.TableSize, true
+	case b.exact:
+		m.TableSize, m.exact = b.TableSize, true
+	default:
+		m.TableSize = max(a.TableSize, b.TableSize)
+	}
+	return m
+}
+
+// gameTimer times the games of one session.
+type gameTimer struct {
+	// cur is the game in progress, nil between games; start and last are
+	// when its first and last messages arrived, and seen the players named
+	// in its messages.
+	cur         *gameTiming
+	start, last time.Time
+	seen        map[string]bool
+	// over is the game that ended last, whose late messages start nothing.
+	over string
+	done []gameTiming
+}
+
+// observe updates the timer with a message received at at.
+func (t *gameTimer) observe(resp *pokerclient.ServerResponse, at time.Time) {
+	if id := resp.GameID; id != "" && id != t.over && (t.cur == nil || id != t.cur.GameID) {
+		t.end(true)
+		t.cur = &gameTiming{GameID: id}
+		t.start, t.seen = at, make(map[string]bool)
+	}
+	if t.cur == nil {
+		return
+	}
+	t.last = at
+	switch resp.Type {
+	case pokerclient.TypeActionPlayerBet:
+		if id := resp.State.Player.PlayerID; id != "" {
+			t.seen[id] = true
+		}
+	case pokerclient.TypeGameOver:
+		var ev struct {
+			Players []struct {
+				PlayerID string `json:"player_id"`
+			} `json:"players"`
+		}
+		resp.DecodeEvent(&ev)
+		for _, p := range ev.Players {
+			if p.PlayerID != "" {
+				t.seen[p.PlayerID] = true
+			}
+		}
+		t.over = t.cur.GameID
+		t.end(false)
+	default:
+		if a, ok := pokerclient.PlayerActionOf(resp); ok {
+			t.seen[a.PlayerID] = true
+		}
+	}
+}
+
+// elapsed returns the time from the first message about the game in
+// progress to at, 0 between games.
+func (t *gameTimer) elapsed(at time.Time) time.Duration {
+	if t.cur == nil {
+		return 0
+	}
+	return at.Sub(t.start)
+}
+
+// end records the game in progress, if any.
+func (t *gameTimer) end(censored bool) {
+	if t.cur == nil {
+		return
+	}
+	g := *t.cur
+	g.TableSize = len(t.seen)
+	g.Duration, g.Censored = t.last.Sub(t.start), censored
+	t.done = append(t.done, g)
+	t.cur, t.seen = nil, nil
+}
+
+// finish ends the game in progress, censored, and returns every game timed.
+func (t *gameTimer) finish() []gameTiming {
+	t.end(true)
+	return t.done
+}
+
+// attachTimings copies the timings to the session's games.
+func attachTimings(games []GameResult, timings []gameTiming) {
+	byID := make(map[string]gameTiming, len(timings))
+	for _, g := range timings {
+		byID[g.GameID] = g
+	}
+	for i := range games {
+		if g, ok := byID[games[i].GameID]; ok {
+			games[i].DurationMs = g.Duration.Milliseconds()
+			games[i].TableSize = g.TableSize
+			games[i].Censored = g.Censored
+		}
+	}
+}
+
+// groupDurations groups the timings by table size, ascending with the
+// unknown size last. Censored games are counted apart and kept out of the
+// duration percentiles.
+func groupDurations(timings []gameTiming) []report.GameDurations {
+	type group struct {
+		games, censored int
+		duration, at    latency.Histogram
+	}
+	groups := make(map[int]*group)
+	for _, g := range timings {
+		gr := groups[g.TableSize]
+		if gr == nil {
+			gr = &group{}
+			groups[g.TableSize] = gr
+		}
+		if g.Censored {
+			gr.censored++
+			gr.at.Record(g.Duration)
+		} else {
+			gr.games++
+			gr.duration.Record(g.Duration)
+		}
+	}
+	sizes := make([]int, 0, len(groups))
+	for n := range groups {
+		sizes = append(sizes, n)
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		if (sizes[i] == 0) != (sizes[j] == 0) {
+			return sizes[j] == 0
+		}
+		return sizes[i] < sizes[j]
+	})
+	out := make([]report.GameDurations, 0, len(sizes))
+	for _, n := range sizes {
+		gr := groups[n]
+		out = append(out, report.GameDurations{
+			TableSize:  n,
+			Games:      gr.games,
+			Duration:   gr.duration.Summary(),
+			Censored:   gr.censored,
+			CensoredAt: gr.at.Summary(),
+		})
+	}
+	return out
+}
+
+// durationLog collects the games timed by the sessions, one observation per
+// game. It is safe for concurrent use.
+type durationLog struct {
+	mu    sync.Mutex
+	games map[string]gameTiming
+}
+
+// add records the games a session timed.
+func (l *durationLog) add(timings []gameTiming) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.games == nil {
+		l.games = make(map[string]gameTiming)
+	}
+	for _, g := range timings {
+		if prev, ok := l.games[g.GameID]; ok {
+			g = mergeTiming(prev, g)
+		}
+		l.games[g.GameID] = g
+	}
+}
+
+// setTableSize records the table size of gameID found in its details.
+func (l *durationLog) setTableSize(gameID string, n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if g, ok := l.games[gameID]; ok && n > 0 {
+		g.TableSize, g.exact = n, true
+		l.games[gameID] = g
+	}
+}
+
+// snapshot returns the durations of the games timed so far, by table size.
+func (l *durationLog) snapshot() []report.GameDurations {
+	l.mu.Lock()
+	timings := make([]gameTiming, 0, len(l.games))
+	for _, g := range l.games {
+		timings = append(timings, g)
+	}
+	l.mu.Unlock()
+	return groupDurations(timings)
+}
+
+// printGameDurations writes the game durations by table size.
+func printGameDurations(w io.Writer, groups []report.GameDurations) {
+	if len(groups) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Game duration by table size (first message to game over; censored: left before it, lasted at least):")
+	for _, g := range groups {
+		label := "unknown"
+		if g.TableSize > 0 {
+			label = fmt.Sprintf("%d players", g.TableSize)
+		}
+		fmt.Fprintf(w, "  %-11s %s\n", label+":", g.Duration)
+		if g.Censored > 0 {
+			fmt.Fprintf(w, "  %-11s censored %d, %s\n", "", g.Censored, g.CensoredAt)
+		}
+	}
+}
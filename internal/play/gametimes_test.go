@@ -0,0 +1,195 @@
+package play
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+func TestGameTimer(t *testing.T) {
+	t0 := time.Unix(1_700_000_000, 0)
+	at := func(s int) time.Time { return t0.Add(time.Duration(s) * time.Second) }
+	type message struct {
+		resp *pokerclient.ServerResponse
+		at   int
+	}
+	tests := []struct {
+		name     string
+		messages []message
+		want     []gameTiming
+	}{
+		{
+			name: "game played to its game over",
+			messages: []message{
+				{betPrompt("g1", "a", 1000), 0},
+				{moveEvent("g1", "b", "call"), 4},
+				{betPrompt("g1", "me", 1000), 7},
+				{gameOver("g1", 1000), 30},
+				// Late messages about the game start nothing.
+				{moveEvent("g1", "c", "fold"), 31},
+			},
+			want: []gameTiming{{GameID: "g1", Duration: 30 * time.Second, TableSize: 4}},
+		},
+		{
+			name: "left for another game",
+			messages: []message{
+				{moveEvent("g1", "a", "call"), 0},
+				{betPrompt("g1", "me", 1000), 10},
+				{betPrompt("g2", "me", 1000), 25},
+				{gameOver("g2", 1200), 45},
+			},
+			want: []gameTiming{
+				{GameID: "g1", Duration: 10 * time.Second, TableSize: 2, Censored: true},
+				{GameID: "g2", Duration: 20 * time.Second, TableSize: 2},
+			},
+		},
+		{
+			name: "session ended mid-game",
+			messages: []message{
+				{betPrompt("g1", "me", 1000), 0},
+				{moveEvent("g1", "a", "raise"), 12},
+			},
+			want: []gameTiming{{GameID: "g1", Duration: 12 * time.Second, TableSize: 2, Censored: true}},
+		},
+		{
+			name: "messages outside a game ignored",
+			messages: []message{
+				{&pokerclient.ServerResponse{Type: "event_player_joined"}, 0},
+				{betPrompt("g1", "", 1000), 3},
+				{gameOver("g1", 990), 9},
+				{&pokerclient.ServerResponse{Type: "event_player_joined"}, 60},
+			},
+			want: []gameTiming{{GameID: "g1", Duration: 6 * time.Second, TableSize: 2}},
+		},
+		{
+			name: "no game",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gt gameTimer
+			for _, m := range tt.messages {
+				gt.observe(m.resp, at(m.at))
+			}
+			if got := gt.finish(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("timings = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGameTimerElapsed(t *testing.T) {
+	t0 := time.Unix(1_700_000_000, 0)
+	var gt gameTimer
+	if d := gt.elapsed(t0); d != 0 {
+		t.Errorf("elapsed before a game = %s, want 0", d)
+	}
+	gt.observe(betPrompt("g1", "me", 1000), t0)
+	if d := gt.elapsed(t0.Add(time.Minute)); d != time.Minute {
+		t.Errorf("elapsed a minute into the game = %s", d)
+	}
+	gt.observe(gameOver("g1", 1000), t0.Add(2*time.Minute))
+	if d := gt.elapsed(t0.Add(3 * time.Minute)); d != 0 {
+		t.Errorf("elapsed after the game over = %s, want 0", d)
+	}
+}
+
+func TestMergeTiming(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b gameTiming
+		want gameTiming
+	}{
+		{
+			"complete beats censored",
+			gameTiming{Duration: time.Minute, TableSize: 3, Censored: true},
+			gameTiming{Duration: 30 * time.Second, TableSize: 2},
+			gameTiming{Duration: 30 * time.Second, TableSize: 3},
+		},
+		{
+			"longer of two complete",
+			gameTiming{Duration: 30 * time.Second, TableSize: 2},
+			gameTiming{Duration: 40 * time.Second, TableSize: 2},
+			gameTiming{Duration: 40 * time.Second, TableSize: 2},
+		},
+		{
+			"longer of two censored",
+			gameTiming{Duration: 50 * time.Second, Censored: true},
+			gameTiming{Duration: 20 * time.Second, TableSize: 5, Censored: true},
+			gameTiming{Duration: 50 * time.Second, TableSize: 5, Censored: true},
+		},
+		{
+			"exact size beats a larger count",
+			gameTiming{Duration: time.Minute, TableSize: 6},
+			gameTiming{Duration: time.Second, TableSize: 4, exact: true},
+			gameTiming{Duration: time.Minute, TableSize: 4, exact: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, got := range []gameTiming{mergeTiming(tt.a, tt.b), mergeTiming(tt.b, tt.a)} {
+				if got != tt.want {
+					t.Errorf("merged = %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupDurations(t *testing.T) {
+	timings := []gameTiming{
+		{GameID: "g1", Duration: 20 * time.Second, TableSize: 6},
+		{GameID: "g2", Duration: 10 * time.Second, TableSize: 2},
+		{GameID: "g3", Duration: 90 * time.Second, TableSize: 6, Censored: true},
+		{GameID: "g4", Duration: 30 * time.Second},
+		{GameID: "g5", Duration: 12 * time.Second, TableSize: 2},
+		{GameID: "g6", Duration: 40 * time.Second, TableSize: 6},
+	}
+	groups := groupDurations(timings)
+	type group struct {
+		size, games, censored int
+		maxMs, censoredMaxMs  float64
+	}
+	var got []group
+	for _, g := range groups {
+		got = append(got, group{g.TableSize, g.Games, g.Censored, g.Duration.MaxMs, g.CensoredAt.MaxMs})
+	}
+	want := []group{
+		{2, 2, 0, 12000, 0},
+		// The censored game is kept out of the durations.
+		{6, 2, 1, 40000, 90000},
+		// The unknown size comes last.
+		{0, 1, 0, 30000, 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %+v, want %+v", got, want)
+	}
+	if groups := groupDurations(nil); len(groups) != 0 {
+		t.Errorf("groups of no games = %+v", groups)
+	}
+}
+
+func TestDurationLog(t *testing.T) {
+	var l durationLog
+	l.add([]gameTiming{{GameID: "g1", Duration: time.Minute, TableSize: 2, Censored: true}})
+	l.add([]gameTiming{
+		{GameID: "g1", Duration: 30 * time.Second, TableSize: 3},
+		{GameID: "g2", Duration: 20 * time.Second, TableSize: 2},
+	})
+	l.setTableSize("g2", 4)
+	l.setTableSize("g3", 4) // not timed
+	l.setTableSize("g1", 0) // no details
+
+	groups := l.snapshot()
+	if len(groups) != 2 {
+		t.Fatalf("groups = %+v, want tables of 3 and 4", groups)
+	}
+	if g := groups[0]; g.TableSize != 3 || g.Games != 1 || g.Censored != 0 || g.Duration.MaxMs != 30000 {
+		t.Errorf("table of 3 = %+v, want g1 complete in 30s", g)
+	}
+	if g := groups[1]; g.TableSize != 4 || g.Games != 1 || g.Duration.MaxMs != 20000 {
+		t.Errorf("table of 4 = %+v, want g2 in 20s", g)
+	}
+}
@@ -0,0 +1,231 @@
+package play
+
+import "elastic-ai-jam-2025/internal/pokerclient"
+
+// HandResult is a hand of a game the session was at, as recorded in the
+// hand_log of its -results-out line with -record-hands.
+type HandResult struct {
+	GameID string `json:"game_id"`
+	// Hand is the hand's number in its game, from 1.
+	Hand int `json:"hand"`
+	// Moves are the moves the session sent in the hand, retries included.
+	Moves []string `json:"moves,omitempty"`
+	// ChipsStart is the stack at the first prompt to the session in the
+	// hand, and ChipsEnd the stack at the first report after the hand: the
+	// next prompt to the session or the game over. Either is -1 if the
+	// server never reported it.
+	ChipsStart int `json:"chips_start"`
+	ChipsEnd   int `json:"chips_end"`
+	// Won is set when a pot of the hand went to the session, and PotsWon
+	// is what those pots were worth.
+	Won     bool `json:"won"`
+	PotsWon int  `json:"pots_won,omitempty"`
+}
+
+// HandTracker splits the messages of a session's games into hands. The
+// protocol has no hand start event: a hand starts at the first bet prompt,
+// to anyone, of a game or after a pot was won, so the pots of a hand split
+// into side pots, won back to back, count once. A game over or a new game
+// ends the current hand.
+type HandTracker struct {
+	self   string
+	gameID string
+	// hand is the current hand's number in gameID, 0 before the first.
+	hand int
+	// cur is the hand in progress, nil between hands; potWon is set once
+	// one of its pots was won, so the next prompt starts a new hand.
+	cur    *HandResult
+	potWon bool
+	// ended is the hand ended last, kept until a report of the session's
+	// stack gives its ChipsEnd.
+	ended *HandResult
+	done  []HandResult
+	// history are the moves reported in the current hand; see History.
+	// historyEnded is set once the hand ended, so that the next move or
+	// prompt starts the history of the next hand.
+	history      HandHistory
+	historyEnded bool
+}
+
+// maxHandHistory caps the moves a hand's history keeps; later ones are
+// dropped and counted, so a server that never ends a hand cannot grow it
+// without bound.
+const maxHandHistory = 256
+
+// HandAction is a move the server reported in the current hand.
+type HandAction struct {
+	Player string
+	// Action is the lower-case move, such as pokerclient.MoveRaise, and
+	// Amount the chips it put in, when the event says.
+	Action string
+	Amount int
+}
+
+// HandHistory is the sequence of moves reported in the current hand,
+// oldest first.
+type HandHistory []HandAction
+
+// Committed returns the chips player put in during the hand, as far as the
+// moves reported tell.
+func (h HandHistory) Committed(player string) int {
+	n := 0
+	for _, a := range h {
+		if a.Player == player && a.Action != pokerclient.MoveFold {
+			n += a.Amount
+		}
+	}
+	return n
+}
+
+// Players returns the players with a move in the history, in the order of
+// their first move.
+func (h HandHistory) Players() []string {
+	var players []string
+	seen := make(map[string]bool)
+	for _, a := range h {
+		if !seen[a.Player] {
+			seen[a.Player] = true
+			players = append(players, a.Player)
+		}
+	}
+	return players
+}
+
+// NewHandTracker returns a tracker of the hands of the player self.
+func NewHandTracker(self string) *HandTracker {
+	return &HandTracker{self: self}
+}
+
+// Hand returns the number of the current hand in its game, from 1, or 0
+// before the first prompt of a game.
+func (h *HandTracker) Hand() int { return h.hand }
+
+// Observe updates the tracker with a server message.
+func (h *HandTracker) Observe(resp *pokerclient.ServerResponse) {
+	if resp.GameID != "" && resp.GameID != h.gameID {
+		if h.gameID != "" {
+			h.endGame(-1)
+		}
+		h.gameID = resp.GameID
+	}
+	switch resp.Type {
+	case pokerclient.TypeActionPlayerBet:
+		player := resp.State.Player.PlayerID
+		if player == "" {
+			return // a malformed prompt says nothing about the hand
+		}
+		if h.cur == nil || h.potWon {
+			h.endHand()
+			h.startHistory()
+			h.hand++
+			h.cur = &HandResult{GameID: h.gameID, Hand: h.hand, ChipsStart: -1, ChipsEnd: -1}
+		}
+		if !pokerclient.SamePlayer(player, h.self) {
+			return
+		}
+		chips := resp.State.Player.Chips
+		h.reportChips(chips)
+		if h.cur.ChipsStart < 0 {
+			h.cur.ChipsStart = chips
+		}
+	case pokerclient.TypePotWon:
+		var ev struct {
+			PlayerID string  `json:"player_id"`
+			Amount   float64 `json:"amount"`
+		}
+		resp.DecodeEvent(&ev) // a mistyped field is left empty, like a missing one
+		h.historyEnded = true
+		if h.cur == nil {
+			return
+		}
+		h.potWon = true
+		if pokerclient.SamePlayer(ev.PlayerID, h.self) {
+			h.cur.Won = true
+			h.cur.PotsWon += int(ev.Amount)
+		}
+	case pokerclient.TypeGameOver:
+		var ev struct {
+			Players []struct {
+				PlayerID string  `json:"player_id"`
+				Chips    float64 `json:"chips"`
+			} `json:"players"`
+		}
+		resp.DecodeEvent(&ev)
+		chips := -1
+		for _, p := range ev.Players {
+			if pokerclient.SamePlayer(p.PlayerID, h.self) {
+				chips = int(p.Chips)
+			}
+		}
+		h.endGame(chips)
+		h.gameID = ""
+	default:
+		if a, ok := pokerclient.PlayerActionOf(resp); ok {
+			h.startHistory()
+			if len(h.history) == maxHandHistory {
+				handHistoryDropped.Inc()
+				return
+			}
+			h.history = append(h.history, HandAction{Player: a.PlayerID, Action: a.Action, Amount: a.Amount})
+		}
+	}
+}
+
+// History returns the moves reported so far in the current hand. Moves
+// reported before the hand's first prompt count in it. The history is
+// shared: it must not be modified.
+func (h *HandTracker) History() HandHistory {
+	if h.historyEnded {
+		return nil
+	}
+	return h.history
+}
+
+// startHistory forgets the history of the hand that ended, if it did.
+// Clearing allocates anew, so histories handed out are never overwritten.
+func (h *HandTracker) startHistory() {
+	if h.historyEnded {
+		h.history, h.historyEnded = nil, false
+	}
+}
+
+// Moved records a move the session sent in the current hand.
+func (h *HandTracker) Moved(m pokerclient.Move) {
+	if h.cur != nil {
+		h.cur.Moves = append(h.cur.Moves, m.String())
+	}
+}
+
+// Finish ends the current hand and returns every hand recorded.
+func (h *HandTracker) Finish() []HandResult {
+	h.endGame(-1)
+	return h.done
+}
+
+// endHand ends the hand in progress, if any.
+func (h *HandTracker) endHand() {
+	if h.cur == nil {
+		return
+	}
+	h.reportChips(-1) // a report after the previous hand did not come
+	h.ended, h.cur, h.potWon = h.cur, nil, false
+}
+
+// endGame ends the hand in progress with the stack chips, -1 if unknown, and
+// starts counting hands anew.
+func (h *HandTracker) endGame(chips int) {
+	h.endHand()
+	h.reportChips(chips)
+	h.hand = 0
+	h.historyEnded = true
+}
+
+// reportChips gives the hand ended last its ChipsEnd and records it.
+func (h *HandTracker) reportChips(chips int) {
+	if h.ended == nil {
+		return
+	}
+	h.ended.ChipsEnd = chips
+	h.done = append(h.done, *h.ended)
+	h.ended = nil
+}
@@ -0,0 +1,287 @@
+package play
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/mockserver"
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// betPrompt is a bet prompt to player, with chips left, in game.
+func betPrompt(game, player string, chips int) *pokerclient.ServerResponse {
+	resp := &pokerclient.ServerResponse{Type: pokerclient.TypeActionPlayerBet, GameID: game}
+	resp.State.Player.PlayerID = player
+	resp.State.Player.Chips = chips
+	return resp
+}
+
+// potWon is a server message reporting that player won a pot of amount.
+func potWon(game, player string, amount int) *pokerclient.ServerResponse {
+	return &pokerclient.ServerResponse{
+		Type:   pokerclient.TypePotWon,
+		GameID: game,
+		Event:  []byte(fmt.Sprintf(`{"player_id":%q,"amount":%d}`, player, amount)),
+	}
+}
+
+// gameOver is a game over reporting the stack of "me".
+func gameOver(game string, chips int) *pokerclient.ServerResponse {
+	return &pokerclient.ServerResponse{
+		Type:   pokerclient.TypeGameOver,
+		GameID: game,
+		Event:  []byte(fmt.Sprintf(`{"players":[{"player_id":"other","chips":0},{"player_id":"me","chips":%d}]}`, chips)),
+	}
+}
+
+func TestHandTracker(t *testing.T) {
+	bet, _ := pokerclient.Bet(20)
+	tests := []struct {
+		name string
+		feed func(h *HandTracker)
+		want []HandResult
+	}{
+		{
+			name: "one hand won",
+			feed: func(h *HandTracker) {
+				h.Observe(betPrompt("g1", "other", 1000))
+				h.Observe(betPrompt("g1", "me", 1000))
+				h.Moved(bet)
+				h.Observe(betPrompt("g1", "me", 980))
+				h.Moved(pokerclient.Check())
+				h.Observe(potWon("g1", "me", 60))
+				h.Observe(gameOver("g1", 1040))
+			},
+			want: []HandResult{
+				{GameID: "g1", Hand: 1, Moves: []string{"bet 20", "check"}, ChipsStart: 1000, ChipsEnd: 1040, Won: true, PotsWon: 60},
+			},
+		},
+		{
+			name: "back-to-back pots count once",
+			feed: func(h *HandTracker) {
+				h.Observe(betPrompt("g1", "me", 1000))
+				h.Moved(bet)
+				h.Observe(potWon("g1", "other", 100))
+				h.Observe(potWon("g1", "me", 40))
+				h.Observe(betPrompt("g1", "other", 500))
+				h.Observe(betPrompt("g1", "me", 940))
+				h.Moved(pokerclient.Fold())
+				h.Observe(potWon("g1", "other", 30))
+				h.Observe(potWon("g1", "other", 10))
+			},
+			want: []HandResult{
+				{GameID: "g1", Hand: 1, Moves: []string{"bet 20"}, ChipsStart: 1000, ChipsEnd: 940, Won: true, PotsWon: 40},
+				{GameID: "g1", Hand: 2, Moves: []string{"fold"}, ChipsStart: 940, ChipsEnd: -1},
+			},
+		},
+		{
+			name: "hands without a prompt to us",
+			feed: func(h *HandTracker) {
+				h.Observe(betPrompt("g1", "other", 1000))
+				h.Observe(potWon("g1", "other", 20))
+				h.Observe(betPrompt("g1", "other", 1010))
+				h.Observe(potWon("g1", "other", 20))
+				h.Observe(betPrompt("g1", "me", 990))
+				h.Observe(gameOver("g1", 990))
+			},
+			want: []HandResult{
+				{GameID: "g1", Hand: 1, ChipsStart: -1, ChipsEnd: -1},
+				{GameID: "g1", Hand: 2, ChipsStart: -1, ChipsEnd: 990},
+				{GameID: "g1", Hand: 3, ChipsStart: 990, ChipsEnd: 990},
+			},
+		},
+		{
+			name: "a new game ends the hand and numbers hands anew",
+			feed: func(h *HandTracker) {
+				h.Observe(betPrompt("g1", "me", 1000))
+				h.Observe(potWon("g1", "me", 20))
+				h.Observe(betPrompt("g1", "me", 1020))
+				h.Observe(betPrompt("g2", "me", 1000))
+			},
+			want: []HandResult{
+				{GameID: "g1", Hand: 1, ChipsStart: 1000, ChipsEnd: 1020, Won: true, PotsWon: 20},
+				{GameID: "g1", Hand: 2, ChipsStart: 1020, ChipsEnd: -1},
+				{GameID: "g2", Hand: 1, ChipsStart: 1000, ChipsEnd: -1},
+			},
+		},
+		{
+			name: "pots and malformed prompts outside a hand ignored",
+			feed: func(h *HandTracker) {
+				h.Observe(potWon("g1", "me", 20))
+				h.Observe(betPrompt("g1", "", 1000))
+				h.Observe(betPrompt("g1", "me", 1000))
+				h.Observe(betPrompt("g1", "", 1000))
+				h.Observe(gameOver("g1", 1000))
+				h.Observe(potWon("", "me", 20))
+			},
+			want: []HandResult{
+				{GameID: "g1", Hand: 1, ChipsStart: 1000, ChipsEnd: 1000},
+			},
+		},
+		{
+			name: "moves between hands dropped",
+			feed: func(h *HandTracker) {
+				h.Moved(bet)
+				h.Observe(betPrompt("g1", "me", 1000))
+				h.Observe(gameOver("g1", 900))
+				h.Moved(bet)
+			},
+			want: []HandResult{
+				{GameID: "g1", Hand: 1, ChipsStart: 1000, ChipsEnd: 900},
+			},
+		},
+		{
+			name: "no hands",
+			feed: func(h *HandTracker) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandTracker("me")
+			tt.feed(h)
+			if got := h.Finish(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hands:\n%+v\nwant:\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandNumber(t *testing.T) {
+	h := NewHandTracker("me")
+	steps := []struct {
+		resp *pokerclient.ServerResponse
+		want int
+	}{
+		{moveEvent("g1", "other", "call"), 0},
+		{betPrompt("g1", "other", 1000), 1},
+		{potWon("g1", "other", 20), 1},
+		{potWon("g1", "me", 20), 1},
+		{betPrompt("g1", "me", 1000), 2},
+		{betPrompt("g1", "other", 980), 2},
+		{gameOver("g1", 1000), 0},
+		{betPrompt("g2", "other", 1000), 1},
+	}
+	for i, s := range steps {
+		h.Observe(s.resp)
+		if got := h.Hand(); got != s.want {
+			t.Errorf("step %d (%s): hand %d, want %d", i, s.resp.Type, got, s.want)
+		}
+	}
+}
+
+func TestHandHistory(t *testing.T) {
+	h := NewHandTracker("me")
+	h.Observe(moveEvent("g1", "a", "raise"))
+	h.Observe(betPrompt("g1", "me", 1000))
+	h.Observe(moveEvent("g1", "b", "call"))
+	h.Observe(moveEvent("g1", "a", "fold"))
+	h.Observe(moveEvent("g1", "b", "raise"))
+	hist := h.History()
+	if got, want := hist.Players(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("players = %q, want %q", got, want)
+	}
+	if got := hist.Committed("a"); got != 10 {
+		t.Errorf("a committed %d, want 10: the fold counts nothing", got)
+	}
+	if got := hist.Committed("b"); got != 20 {
+		t.Errorf("b committed %d, want 20", got)
+	}
+
+	h.Observe(potWon("g1", "b", 50))
+	if got := h.History(); got != nil {
+		t.Errorf("history after the pot was won = %+v, want none", got)
+	}
+	h.Observe(potWon("g1", "a", 10))
+	h.Observe(moveEvent("g1", "a", "call"))
+	if got := h.History(); len(got) != 1 || got[0].Player != "a" {
+		t.Errorf("history of the next hand = %+v, want a's call alone", got)
+	}
+	if len(hist) != 4 {
+		t.Errorf("the history handed out changed to %+v", hist)
+	}
+}
+
+// recordedGame is a two-hand heads-up game as the server sent it to "me",
+// the second hand split into a main and a side pot.
+const recordedGame = `
+{"type":"event_game_start","game_id":"g-7"}
+{"type":"action_player_bet","game_id":"g-7","stage":"pre_flop","minimum_bet":10,"state":{"player":{"player_id":"other","chips":1000}}}
+{"type":"event_player_action","game_id":"g-7","event":{"player_id":"other","action":"bet","amount":10}}
+{"type":"action_player_bet","game_id":"g-7","stage":"pre_flop","minimum_bet":10,"state":{"player":{"player_id":"me","chips":1000}}}
+{"type":"event_player_action","game_id":"g-7","event":{"player_id":"me","action":"bet","amount":10}}
+{"type":"action_player_bet","game_id":"g-7","stage":"flop","minimum_bet":10,"state":{"player":{"player_id":"me","chips":990}}}
+{"type":"event_player_action","game_id":"g-7","event":{"player_id":"me","action":"fold","amount":0}}
+{"type":"event_pot_won","game_id":"g-7","event":{"player_id":"other","amount":20}}
+{"type":"action_player_bet","game_id":"g-7","stage":"pre_flop","minimum_bet":10,"state":{"player":{"player_id":"me","chips":990}}}
+{"type":"event_player_action","game_id":"g-7","event":{"player_id":"me","action":"all_in","amount":990}}
+{"type":"action_player_bet","game_id":"g-7","stage":"pre_flop","minimum_bet":10,"state":{"player":{"player_id":"other","chips":1010}}}
+{"type":"event_player_action","game_id":"g-7","event":{"player_id":"other","action":"all_in","amount":1010}}
+{"type":"event_pot_won","game_id":"g-7","event":{"player_id":"me","amount":1980}}
+{"type":"event_pot_won","game_id":"g-7","event":{"player_id":"other","amount":20}}
+{"type":"event_game_over","game_id":"g-7","event":{"players":[{"player_id":"me","chips":1980},{"player_id":"other","chips":20}]}}
+`
+
+func TestHandTrackerRecorded(t *testing.T) {
+	bet, _ := pokerclient.Bet(10)
+	allIn, _ := pokerclient.AllIn(990)
+	ours := map[int]pokerclient.Move{4: bet, 6: pokerclient.Fold(), 9: allIn}
+	h := NewHandTracker("me")
+	for i, line := range strings.Split(strings.TrimSpace(recordedGame), "\n") {
+		var resp pokerclient.ServerResponse
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		h.Observe(&resp)
+		if m, ok := ours[i]; ok {
+			h.Moved(m)
+		}
+	}
+	want := []HandResult{
+		{GameID: "g-7", Hand: 1, Moves: []string{"bet 10", "fold"}, ChipsStart: 1000, ChipsEnd: 990},
+		{GameID: "g-7", Hand: 2, Moves: []string{"all-in 990"}, ChipsStart: 990, ChipsEnd: 1980, Won: true, PotsWon: 1980},
+	}
+	if got := h.Finish(); !reflect.DeepEqual(got, want) {
+		t.Errorf("hands:\n%+v\nwant:\n%+v", got, want)
+	}
+}
+
+func TestHandLogAgainstMock(t *testing.T) {
+	srv := startMock(t, mockserver.Config{HandsPerGame: 3, Seed: 1})
+	cfg := testConfig()
+	cfg.RecordHands = true
+
+	ps := playSession(t, cfg, srv.Addr(), "hands-0", minBet{})
+	hands := ps.hands.Finish()
+
+	if len(hands) != 3 {
+		t.Fatalf("recorded %d hands, want 3: %+v", len(hands), hands)
+	}
+	for i, hr := range hands {
+		if hr.Hand != i+1 || hr.GameID != hands[0].GameID {
+			t.Errorf("hand %d is hand %d of %s", i, hr.Hand, hr.GameID)
+		}
+	}
+}
+
+func TestHandHistoryCapped(t *testing.T) {
+	h := NewHandTracker("me")
+	dropped := handHistoryDropped.Load()
+	h.Observe(betPrompt("g1", "me", 1000))
+	for range maxHandHistory + 10 {
+		h.Observe(moveEvent("g1", "a", "call"))
+	}
+	if got := len(h.History()); got != maxHandHistory {
+		t.Errorf("history of %d moves, want the cap of %d", got, maxHandHistory)
+	}
+	if n := handHistoryDropped.Load() - dropped; n != 10 {
+		t.Errorf("%d moves dropped, want 10", n)
+	}
+	h.Observe(potWon("g1", "a", 100))
+	h.Observe(moveEvent("g1", "a", "call"))
+	if got := len(h.History()); got != 1 {
+		t.Errorf("history of the next hand has %d moves, want 1", got)
+	}
+}
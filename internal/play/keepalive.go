@@ -0,0 +1,94 @@
+package play
+
+import (
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// Keepalive modes of -keepalive.
+const (
+	keepaliveOff   = ""
+	keepaliveTCP   = "tcp"   // TCP keep-alive probes, invisible to the game server
+	keepaliveEmpty = "empty" // an empty JSON object
+	keepalivePing  = "ping"  // a {"action":"ping"} message
+)
+
+// keepaliveMessage returns what a protocol keepalive of mode sends.
+func keepaliveMessage(mode string) (interface{}, error) {
+	switch mode {
+	case keepaliveEmpty:
+		return struct{}{}, nil
+	case keepalivePing:
+		return pokerclient.ActionMsg{Action: pokerclient.ActionPing}, nil
+	case keepaliveOff, keepaliveTCP:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown keepalive mode %q (available: tcp, empty, ping)", mode)
+}
+
+// startKeepalive keeps the connection from looking idle to middleboxes,
+// according to cfg.Keepalive. Protocol keepalives are sent by a single
+// timer whenever nothing was written for cfg.KeepaliveIdle; the server's
+// reaction is judged from the next message, see keepaliveReaction. The
+// returned function stops the timer. The keepalives go on the connection
+// the session had when it started them.
+func (ps *PlayerSessionState) startKeepalive() (stop func()) {
+	conn := ps.conn
+	switch ps.cfg.Keepalive {
+	case keepaliveOff:
+		return func() {}
+	case keepaliveTCP:
+		if err := conn.SetTCPKeepAlive(ps.cfg.KeepaliveIdle); err != nil {
+			ps.logVerbose("Error enabling TCP keep-alive: %v", err)
+		}
+		return func() {}
+	}
+	msg, _ := keepaliveMessage(ps.cfg.Keepalive) // validated in Run
+	idle := ps.cfg.KeepaliveIdle
+	var timer *time.Timer
+	timer = time.AfterFunc(idle, func() {
+		if since := conn.SinceLastWrite(); since < idle {
+			timer.Reset(idle - since)
+			return
+		}
+		if err := conn.SendQuiet(msg); err != nil {
+			keepaliveDisconnects.Inc()
+			return
+		}
+		keepalivesSent.Inc()
+		atomic.StoreInt32(&ps.keepalivePending, 1)
+		timer.Reset(idle)
+	})
+	return func() { timer.Stop() }
+}
+
+// keepaliveReaction classifies the message (or read error) that followed a
+// keepalive, and reports whether it was an error answering the keepalive.
+// The next message may be an unrelated game event, in which case the
+// keepalive counts as ignored. An error arriving while a join or move awaits
+// its answer is taken as that action's, so a rejected bet is still retried;
+// the keepalive then stays pending.
+func (ps *PlayerSessionState) keepaliveReaction(resp *pokerclient.ServerResponse, err error) (rejected bool) {
+	if resp != nil && resp.Type == "" && resp.Code != 0 && ps.answerDue {
+		return false
+	}
+	if !atomic.CompareAndSwapInt32(&ps.keepalivePending, 1, 0) {
+		return false
+	}
+	switch {
+	case err != nil:
+		keepaliveDisconnects.Inc()
+		slog.Warn("connection lost after a keepalive", "player", ps.username, "mode", ps.cfg.Keepalive, "error", err)
+	case resp.Type == "" && resp.Code != 0:
+		keepaliveErrors.Inc()
+		slog.Warn("server answered a keepalive with an error", "player", ps.username, "mode", ps.cfg.Keepalive, "code", resp.Code, "message", resp.Message)
+		return true
+	default:
+		keepalivesIgnored.Inc()
+	}
+	return false
+}
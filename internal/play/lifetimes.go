@@ -0,0 +1,103 @@
+package play
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/latency"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// lifetimeStats breaks session lifetimes down by outcome, and completed
+// sessions by the number of games they played. It is safe for concurrent
+// use.
+type lifetimeStats struct {
+	mu             sync.Mutex
+	byOutcome      map[Outcome]*latency.Histogram
+	completedGames map[int]int64
+}
+
+var lifetimes lifetimeStats
+
+// record adds a session that lived d and ended as r says.
+func (l *lifetimeStats) record(r *SessionResult, d time.Duration) {
+	sessionLifetime.Record(d)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.byOutcome == nil {
+		l.byOutcome = make(map[Outcome]*latency.Histogram)
+		l.completedGames = make(map[int]int64)
+	}
+	h := l.byOutcome[r.Outcome]
+	if h == nil {
+		h = &latency.Histogram{}
+		l.byOutcome[r.Outcome] = h
+	}
+	h.Record(d)
+	if r.Outcome == outcomeCompleted {
+		l.completedGames[len(r.Games)]++
+	}
+}
+
+// snapshot returns the histogram of each outcome seen, in the order of
+// outcomes, and the completed sessions per number of games.
+func (l *lifetimeStats) snapshot() (seen []Outcome, byOutcome map[Outcome]*latency.Histogram, games map[int]int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	byOutcome = make(map[Outcome]*latency.Histogram, len(l.byOutcome))
+	for _, o := range outcomes {
+		if h := l.byOutcome[o]; h != nil {
+			seen = append(seen, o)
+			byOutcome[o] = h
+		}
+	}
+	games = make(map[int]int64, len(l.completedGames))
+	for n, c := range l.completedGames {
+		games[n] = c
+	}
+	return seen, byOutcome, games
+}
+
+// print writes the lifetime percentiles per outcome.
+func (l *lifetimeStats) print(w io.Writer) {
+	seen, byOutcome, games := l.snapshot()
+	if len(seen) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Session lifetime: %s\n", sessionLifetime.Summary())
+	fmt.Fprintln(w, "Sessions by end cause:")
+	for _, o := range seen {
+		fmt.Fprintf(w, "  %-20s %s\n", o, byOutcome[o].Summary())
+	}
+	if len(games) > 0 {
+		counts := make([]int, 0, len(games))
+		for n := range games {
+			counts = append(counts, n)
+		}
+		sort.Ints(counts)
+		fmt.Fprint(w, "Completed sessions by games played:")
+		for _, n := range counts {
+			fmt.Fprintf(w, " %d: %d", n, games[n])
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// fill adds, per outcome, a sessions_ended_<outcome> counter and a
+// session_lifetime_<outcome> latency to rep, and a completed_games_<n>
+// counter per number of games played by completed sessions.
+func (l *lifetimeStats) fill(rep *report.Report) {
+	seen, byOutcome, games := l.snapshot()
+	for _, o := range seen {
+		h := byOutcome[o]
+		rep.Counters["sessions_ended_"+string(o)] = h.Count()
+		rep.Latencies["session_lifetime_"+string(o)] = h.Summary()
+	}
+	for n, c := range games {
+		rep.Counters["completed_games_"+strconv.Itoa(n)] = c
+	}
+}
@@ -0,0 +1,190 @@
+package play
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// maxPrintedHands bounds the rows of the minimum bet table in the summary;
+// the report has them all.
+const maxPrintedHands = 20
+
+// minimumBetSample is the minimum bet of a bet prompt a session saw, to any
+// player.
+type minimumBetSample struct {
+	// Hand is the prompt's hand number in its game, from 1, and Elapsed the
+	// time since the first message about the game.
+	Hand       int
+	Elapsed    time.Duration
+	MinimumBet int
+}
+
+// MinimumBetPoint is a hand of a game in the minimum_bets series of a
+// -results-out game.
+type MinimumBetPoint struct {
+	Hand int `json:"hand"`
+	// ElapsedMs is when the hand's first prompt came, since the first
+	// message about the game.
+	ElapsedMs int64 `json:"elapsed_ms"`
+	// Opening is the minimum bet of the hand's first prompt the session
+	// saw: the blind, or the bet to call by then. Max is the largest of the
+	// hand.
+	Opening int `json:"opening"`
+	Max     int `json:"max"`
+}
+
+// gameMinimumBets are the bet prompts a session saw in one game.
+type gameMinimumBets struct {
+	GameID  string
+	Samples []minimumBetSample
+	// Forced is set when a prompt asked the session for more than its
+	// chips, forcing it all in or out.
+	Forced bool
+}
+
+// minimumBetTracker records the minimum bets of the games of one session.
+type minimumBetTracker struct {
+	games []gameMinimumBets
+}
+
+// observe records resp, a bet prompt of hand in gameID received elapsed
+// after the game's first message, to the session self.
+func (t *minimumBetTracker) observe(resp *pokerclient.ServerResponse, self, gameID string, hand int, elapsed time.Duration) {
+	if resp.Type != pokerclient.TypeActionPlayerBet || gameID == "" || hand == 0 {
+		return
+	}
+	if n := len(t.games); n == 0 || t.games[n-1].GameID != gameID {
+		t.games = append(t.games, gameMinimumBets{GameID: gameID})
+	}
+	g := &t.games[len(t.games)-1]
+	g.Samples = append(g.Samples, minimumBetSample{Hand: hand, Elapsed: elapsed, MinimumBet: resp.MinimumBet})
+	if p := resp.State.Player; pokerclient.SamePlayer(p.PlayerID, self) && resp.MinimumBet > p.Chips && !g.Forced {
+		g.Forced = true
+		forcedAllInGames.Inc()
+	}
+}
+
+// minimumBetSeries reduces the samples of a game to one point per hand, in
+// hand order.
+func minimumBetSeries(samples []minimumBetSample) []MinimumBetPoint {
+	var series []MinimumBetPoint
+	for _, s := range samples {
+		if n := len(series); n > 0 && series[n-1].Hand == s.Hand {
+			series[n-1].Max = max(series[n-1].Max, s.MinimumBet)
+			continue
+		}
+		series = append(series, MinimumBetPoint{Hand: s.Hand, ElapsedMs: s.Elapsed.Milliseconds(), Opening: s.MinimumBet, Max: s.MinimumBet})
+	}
+	sort.SliceStable(series, func(i, j int) bool { return series[i].Hand < series[j].Hand })
+	return series
+}
+
+// minimumBetsByHand aggregates the opening minimum bet of each hand number
+// over the series of several games.
+func minimumBetsByHand(games [][]MinimumBetPoint) []report.MinimumBetByHand {
+	type acc struct {
+		games, min, max int
+		sum, elapsed    int64
+	}
+	byHand := make(map[int]*acc)
+	for _, series := range games {
+		for _, p := range series {
+			a := byHand[p.Hand]
+			if a == nil {
+				a = &acc{min: p.Opening, max: p.Opening}
+				byHand[p.Hand] = a
+			}
+			a.games++
+			a.min, a.max = min(a.min, p.Opening), max(a.max, p.Opening)
+			a.sum += int64(p.Opening)
+			a.elapsed += p.ElapsedMs
+		}
+	}
+	out := make([]report.MinimumBetByHand, 0, len(byHand))
+	for hand, a := range byHand {
+		out = append(out, report.MinimumBetByHand{
+			Hand:          hand,
+			Games:         a.games,
+			Min:           a.min,
+			Mean:          float64(a.sum) / float64(a.games),
+			Max:           a.max,
+			MeanElapsedMs: a.elapsed / int64(a.games),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Hand < out[j].Hand })
+	return out
+}
+
+// attachMinimumBets copies the series of the session's games to its
+// results.
+func attachMinimumBets(games []GameResult, seen []gameMinimumBets) {
+	byID := make(map[string]gameMinimumBets, len(seen))
+	for _, g := range seen {
+		byID[g.GameID] = g
+	}
+	for i := range games {
+		if g, ok := byID[games[i].GameID]; ok {
+			games[i].MinimumBets = minimumBetSeries(g.Samples)
+			games[i].ForcedAllIn = g.Forced
+		}
+	}
+}
+
+// minimumBetLog collects the series of the games the sessions saw, one per
+// game: of two sessions at the same table, the one that saw more hands. It
+// is safe for concurrent use.
+type minimumBetLog struct {
+	mu    sync.Mutex
+	games map[string][]MinimumBetPoint
+}
+
+// add records the games a session saw.
+func (l *minimumBetLog) add(seen []gameMinimumBets) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.games == nil {
+		l.games = make(map[string][]MinimumBetPoint)
+	}
+	for _, g := range seen {
+		series := minimumBetSeries(g.Samples)
+		if len(series) > len(l.games[g.GameID]) {
+			l.games[g.GameID] = series
+		}
+	}
+}
+
+// snapshot returns the minimum bet by hand number over the games so far.
+func (l *minimumBetLog) snapshot() []report.MinimumBetByHand {
+	l.mu.Lock()
+	games := make([][]MinimumBetPoint, 0, len(l.games))
+	for _, series := range l.games {
+		games = append(games, series)
+	}
+	l.mu.Unlock()
+	return minimumBetsByHand(games)
+}
+
+// printMinimumBets writes the minimum bet by hand number.
+func printMinimumBets(w io.Writer, byHand []report.MinimumBetByHand) {
+	if len(byHand) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Minimum bet by hand number (first prompt of the hand):")
+	for i, h := range byHand {
+		if i == maxPrintedHands {
+			fmt.Fprintf(w, "  ... %d more hands in the report\n", len(byHand)-i)
+			break
+		}
+		fmt.Fprintf(w, "  hand %-4d games %-6d min %-6d mean %-9.1f max %-6d at %s\n",
+			h.Hand, h.Games, h.Min, h.Mean, h.Max, time.Duration(h.MeanElapsedMs)*time.Millisecond)
+	}
+	if n := forcedAllInGames.Load(); n > 0 {
+		fmt.Fprintf(w, "Games where the minimum bet exceeded the session's chips: %d\n", n)
+	}
+}
@@ -0,0 +1,136 @@
+package play
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// minBetPrompt is a bet prompt to player, with chips left, asking for at
+// least minimum.
+func minBetPrompt(game, player string, chips, minimum int) *pokerclient.ServerResponse {
+	resp := betPrompt(game, player, chips)
+	resp.MinimumBet = minimum
+	return resp
+}
+
+func TestMinimumBetTracker(t *testing.T) {
+	type prompt struct {
+		resp    *pokerclient.ServerResponse
+		game    string
+		hand    int
+		elapsed time.Duration
+	}
+	sec := time.Second
+	prompts := []prompt{
+		{minBetPrompt("g1", "other", 1000, 10), "g1", 1, 0},
+		{minBetPrompt("g1", "me", 1000, 20), "g1", 1, 2 * sec},
+		// Not a prompt, or outside a hand: ignored.
+		{moveEvent("g1", "other", "call"), "g1", 1, 3 * sec},
+		{minBetPrompt("g1", "me", 1000, 10), "", 1, 3 * sec},
+		{minBetPrompt("g1", "me", 1000, 10), "g1", 0, 3 * sec},
+		{minBetPrompt("g1", "me", 980, 20), "g1", 2, 10 * sec},
+		// More than the session's chips, twice: one forced game.
+		{minBetPrompt("g1", "me", 15, 40), "g1", 3, 20 * sec},
+		{minBetPrompt("g1", "me", 15, 40), "g1", 3, 21 * sec},
+		// More than another player's chips: not forcing the session.
+		{minBetPrompt("g2", "other", 5, 10), "g2", 1, 0},
+	}
+	var tr minimumBetTracker
+	before := forcedAllInGames.Load()
+	for _, p := range prompts {
+		tr.observe(p.resp, "me", p.game, p.hand, p.elapsed)
+	}
+	want := []gameMinimumBets{
+		{GameID: "g1", Forced: true, Samples: []minimumBetSample{
+			{1, 0, 10}, {1, 2 * sec, 20}, {2, 10 * sec, 20}, {3, 20 * sec, 40}, {3, 21 * sec, 40},
+		}},
+		{GameID: "g2", Samples: []minimumBetSample{{1, 0, 10}}},
+	}
+	if !reflect.DeepEqual(tr.games, want) {
+		t.Errorf("games = %+v, want %+v", tr.games, want)
+	}
+	if n := forcedAllInGames.Load() - before; n != 1 {
+		t.Errorf("forced all-in games counted %d, want 1", n)
+	}
+}
+
+func TestMinimumBetSeries(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name    string
+		samples []minimumBetSample
+		want    []MinimumBetPoint
+	}{
+		{"none", nil, nil},
+		{
+			name: "one point per hand, opening and max",
+			samples: []minimumBetSample{
+				{1, 0, 10}, {1, 500 * ms, 40}, {1, 900 * ms, 20},
+				{2, 3000 * ms, 20},
+				{3, 7000 * ms, 40}, {3, 7500 * ms, 80},
+			},
+			want: []MinimumBetPoint{
+				{Hand: 1, ElapsedMs: 0, Opening: 10, Max: 40},
+				{Hand: 2, ElapsedMs: 3000, Opening: 20, Max: 20},
+				{Hand: 3, ElapsedMs: 7000, Opening: 40, Max: 80},
+			},
+		},
+		{
+			name:    "hands out of order sorted",
+			samples: []minimumBetSample{{2, 3000 * ms, 20}, {1, 0, 10}},
+			want: []MinimumBetPoint{
+				{Hand: 1, ElapsedMs: 0, Opening: 10, Max: 10},
+				{Hand: 2, ElapsedMs: 3000, Opening: 20, Max: 20},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := minimumBetSeries(tt.samples); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("series = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinimumBetsByHand(t *testing.T) {
+	games := [][]MinimumBetPoint{
+		{{Hand: 1, ElapsedMs: 0, Opening: 10}, {Hand: 2, ElapsedMs: 4000, Opening: 20}, {Hand: 3, ElapsedMs: 9000, Opening: 40}},
+		{{Hand: 1, ElapsedMs: 1000, Opening: 10}, {Hand: 2, ElapsedMs: 6000, Opening: 30}},
+		{{Hand: 1, ElapsedMs: 500, Opening: 20}},
+	}
+	want := []report.MinimumBetByHand{
+		{Hand: 1, Games: 3, Min: 10, Mean: 40.0 / 3, Max: 20, MeanElapsedMs: 500},
+		{Hand: 2, Games: 2, Min: 20, Mean: 25, Max: 30, MeanElapsedMs: 5000},
+		{Hand: 3, Games: 1, Min: 40, Mean: 40, Max: 40, MeanElapsedMs: 9000},
+	}
+	if got := minimumBetsByHand(games); !reflect.DeepEqual(got, want) {
+		t.Errorf("by hand = %+v, want %+v", got, want)
+	}
+	if got := minimumBetsByHand(nil); len(got) != 0 {
+		t.Errorf("by hand of no games = %+v", got)
+	}
+}
+
+func TestMinimumBetLog(t *testing.T) {
+	var l minimumBetLog
+	// Two sessions at g1: the one that saw more hands is kept.
+	l.add([]gameMinimumBets{
+		{GameID: "g1", Samples: []minimumBetSample{{1, 0, 10}}},
+		{GameID: "g2", Samples: []minimumBetSample{{1, 0, 20}}},
+	})
+	l.add([]gameMinimumBets{{GameID: "g1", Samples: []minimumBetSample{{1, 0, 10}, {2, time.Second, 40}}}})
+	l.add([]gameMinimumBets{{GameID: "g1", Samples: []minimumBetSample{{1, 0, 999}}}})
+
+	want := []report.MinimumBetByHand{
+		{Hand: 1, Games: 2, Min: 10, Mean: 15, Max: 20},
+		{Hand: 2, Games: 1, Min: 40, Mean: 40, Max: 40, MeanElapsedMs: 1000},
+	}
+	if got := l.snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package play
+
+import "elastic-ai-jam-2025/internal/pokerclient"
+
+// OpponentStats counts the moves of one opponent. Checks count as calls and
+// bets and all-ins as raises.
+type OpponentStats struct {
+	Folds  int
+	Calls  int
+	Raises int
+}
+
+// Observations is the number of moves seen.
+func (s OpponentStats) Observations() int { return s.Folds + s.Calls + s.Raises }
+
+// FoldRate is the share of observed moves that were folds.
+func (s OpponentStats) FoldRate() float64 {
+	if n := s.Observations(); n > 0 {
+		return float64(s.Folds) / float64(n)
+	}
+	return 0
+}
+
+// OpponentModel tracks how the other players at the current game act. It
+// is reset when a game ends, since seats and opponents change between games.
+type OpponentModel struct {
+	self    string
+	gameID  string
+	players map[string]*OpponentStats
+}
+
+// NewOpponentModel returns an empty model that ignores self's own moves.
+func NewOpponentModel(self string) *OpponentModel {
+	return &OpponentModel{self: self, players: make(map[string]*OpponentStats)}
+}
+
+// Reset forgets every opponent.
+func (m *OpponentModel) Reset() {
+	m.gameID = ""
+	clear(m.players)
+}
+
+// Observe updates the model with a server message: game boundaries reset
+// it and player moves are counted.
+func (m *OpponentModel) Observe(resp *pokerclient.ServerResponse) {
+	if resp.Type == pokerclient.TypeGameOver {
+		m.Reset()
+		return
+	}
+	if resp.GameID != "" && resp.GameID != m.gameID {
+		if m.gameID != "" {
+			m.Reset()
+		}
+		m.gameID = resp.GameID
+	}
+	a, ok := pokerclient.PlayerActionOf(resp)
+	if !ok || pokerclient.SamePlayer(a.PlayerID, m.self) {
+		return
+	}
+	s, ok := m.players[a.PlayerID]
+	if !ok {
+		s = &OpponentStats{}
+		m.players[a.PlayerID] = s
+	}
+	switch a.Action {
+	case pokerclient.MoveFold:
+		s.Folds++
+		opponentFolds.Inc()
+	case pokerclient.MoveCheck, pokerclient.MoveCall:
+		s.Calls++
+		opponentCalls.Inc()
+	case pokerclient.MoveBet, pokerclient.MoveRaise, pokerclient.MoveAllIn:
+		s.Raises++
+		opponentRaises.Inc()
+	}
+}
+
+// Table aggregates the opponents with at least minObservations moves. ok is
+// false when there is no such opponent yet.
+func (m *OpponentModel) Table(minObservations int) (stats OpponentStats, ok bool) {
+	for _, s := range m.players {
+		if s.Observations() < minObservations {
+			continue
+		}
+		stats.Folds += s.Folds
+		stats.Calls += s.Calls
+		stats.Raises += s.Raises
+		ok = true
+	}
+	return stats, ok
+}
@@ -0,0 +1,164 @@
+package play
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// moveEvent is a server message reporting that player made action in game.
+func moveEvent(game, player, action string) *pokerclient.ServerResponse {
+	return &pokerclient.ServerResponse{
+		Type:   "event_player_action",
+		GameID: game,
+		Event:  []byte(fmt.Sprintf(`{"player_id":%q,"action":%q,"amount":10}`, player, action)),
+	}
+}
+
+// observeAll feeds events to a model of player "me".
+func observeAll(events ...*pokerclient.ServerResponse) *OpponentModel {
+	m := NewOpponentModel("me")
+	for _, e := range events {
+		m.Observe(e)
+	}
+	return m
+}
+
+func TestOpponentModel(t *testing.T) {
+	gameOver := &pokerclient.ServerResponse{Type: pokerclient.TypeGameOver, GameID: "g1", Event: []byte(`{}`)}
+	tests := []struct {
+		name   string
+		events []*pokerclient.ServerResponse
+		want   map[string]OpponentStats
+	}{
+		{
+			name: "moves counted per opponent",
+			events: []*pokerclient.ServerResponse{
+				moveEvent("g1", "a", "fold"),
+				moveEvent("g1", "a", "check"),
+				moveEvent("g1", "a", "call"),
+				moveEvent("g1", "b", "bet"),
+				moveEvent("g1", "b", "RAISE"),
+				moveEvent("g1", "b", "all_in"),
+			},
+			want: map[string]OpponentStats{
+				"a": {Folds: 1, Calls: 2},
+				"b": {Raises: 3},
+			},
+		},
+		{
+			name: "own moves ignored",
+			events: []*pokerclient.ServerResponse{
+				moveEvent("g1", "me", "fold"),
+				moveEvent("g1", "a", "fold"),
+			},
+			want: map[string]OpponentStats{"a": {Folds: 1}},
+		},
+		{
+			name: "messages without a move ignored",
+			events: []*pokerclient.ServerResponse{
+				{Type: pokerclient.TypePotWon, GameID: "g1", Event: []byte(`{"player_id":"a","amount":10}`)},
+				{Type: pokerclient.TypeActionPlayerBet, GameID: "g1"},
+			},
+			want: map[string]OpponentStats{},
+		},
+		{
+			name: "reset by game over",
+			events: []*pokerclient.ServerResponse{
+				moveEvent("g1", "a", "fold"),
+				gameOver,
+				moveEvent("g2", "b", "call"),
+			},
+			want: map[string]OpponentStats{"b": {Calls: 1}},
+		},
+		{
+			name: "reset by a new game ID",
+			events: []*pokerclient.ServerResponse{
+				moveEvent("g1", "a", "fold"),
+				moveEvent("g2", "a", "raise"),
+			},
+			want: map[string]OpponentStats{"a": {Raises: 1}},
+		},
+		{
+			name: "messages without a game ID keep the game",
+			events: []*pokerclient.ServerResponse{
+				moveEvent("g1", "a", "fold"),
+				moveEvent("", "a", "fold"),
+			},
+			want: map[string]OpponentStats{"a": {Folds: 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := observeAll(tt.events...)
+			got := make(map[string]OpponentStats)
+			for id, s := range m.players {
+				got[id] = *s
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stats = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpponentModelTable(t *testing.T) {
+	m := observeAll(
+		moveEvent("g1", "a", "fold"),
+		moveEvent("g1", "a", "fold"),
+		moveEvent("g1", "a", "call"),
+		moveEvent("g1", "b", "raise"),
+		moveEvent("g1", "b", "fold"),
+		moveEvent("g1", "c", "fold"),
+	)
+	tests := []struct {
+		min    int
+		want   OpponentStats
+		wantOK bool
+	}{
+		{1, OpponentStats{Folds: 4, Calls: 1, Raises: 1}, true},
+		{2, OpponentStats{Folds: 3, Calls: 1, Raises: 1}, true},
+		{3, OpponentStats{Folds: 2, Calls: 1}, true},
+		{4, OpponentStats{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := m.Table(tt.min)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Table(%d) = %+v, %v; want %+v, %v", tt.min, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestExploitBet(t *testing.T) {
+	// repeat returns n moves of player a.
+	repeat := func(action string, n int) []*pokerclient.ServerResponse {
+		out := make([]*pokerclient.ServerResponse, n)
+		for i := range out {
+			out[i] = moveEvent("g1", "a", action)
+		}
+		return out
+	}
+	tests := []struct {
+		name    string
+		events  []*pokerclient.ServerResponse
+		minimum int
+		want    string
+	}{
+		{"too few observations", repeat("fold", 2), 10, "all-in 1000"},
+		{"folding table", repeat("fold", 3), 10, "all-in 1000"},
+		{"calling station", repeat("call", 3), 10, "fold"},
+		{"calling station, free check", repeat("call", 3), 0, "check"},
+		{"in between", append(repeat("fold", 2), repeat("call", 2)...), 10, "all-in 1000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &exploit{minObservations: 3}
+			turn := Turn{Player: "me", Chips: 1000, MinimumBet: tt.minimum, Opponents: observeAll(tt.events...)}
+			if got := s.Bet(turn); got.String() != tt.want {
+				t.Errorf("Bet = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package play
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// parkPoll is the longest a parked session waits on a read before touching
+// the watchdog; reads are shorter when -reap-after asks for it.
+const parkPoll = time.Minute
+
+// checkPark validates a -park run. Parked sessions never end on their own,
+// so nothing waiting for sessions to end can go with it. -keepalive
+// defaults to TCP keep-alive probes, which the game server never sees.
+func (cfg *Config) checkPark() error {
+	if cfg.ParkReconnectDelay <= 0 || cfg.ParkReconnectMaxDelay < cfg.ParkReconnectDelay {
+		return errors.New("-park-reconnect-delay must be positive and at most -park-reconnect-max-delay")
+	}
+	for _, c := range []struct {
+		flag string
+		set  bool
+	}{
+		{"-waves", cfg.Waves != ""},
+		{"-script", cfg.Script != ""},
+		{"-soak", cfg.Soak > 0},
+		{"-replace-busted", cfg.ReplaceBusted},
+	} {
+		if c.set {
+			return fmt.Errorf("-park cannot be combined with %s", c.flag)
+		}
+	}
+	if cfg.Keepalive == keepaliveOff {
+		cfg.Keepalive = keepaliveTCP
+	}
+	return nil
+}
+
+// park keeps the registered session connected without ever joining a
+// game, for -park-for or until the run is interrupted. Every message the
+// server sends meanwhile is unsolicited, and counted by kind. A dropped
+// connection is dialed and logged into again after -park-reconnect-delay,
+// doubling up to -park-reconnect-max-delay while the attempts fail;
+// reconnections count as registrations like the first one.
+func (ps *PlayerSessionState) park(ctx context.Context, password string) Outcome {
+	if ps.cfg.ParkFor > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ps.cfg.ParkFor)
+		defer cancel()
+	}
+	account := &parkedAccount{Player: ps.username}
+	defer parked.add(account)
+	defer func() {
+		ps.result.UptimeMs = account.Uptime.Milliseconds()
+		ps.result.Reconnects = account.Reconnects
+	}()
+
+	first := ps.conn
+	delay := ps.cfg.ParkReconnectDelay
+	for {
+		connected := time.Now()
+		err := ps.idle(ctx)
+		account.Uptime += time.Since(connected)
+		if ps.conn != first {
+			ps.conn.Close()
+		}
+		switch {
+		case ctx.Err() != nil:
+			return outcomeCompleted
+		case ps.tracked.reaped.Load():
+			return outcomeReaped
+		}
+		account.Drops++
+		parkDrops.Inc()
+		ps.logVerbose("Parked connection dropped after %s: %v", time.Since(connected).Round(time.Second), err)
+		for {
+			select {
+			case <-ps.cfg.clock.After(delay):
+			case <-ctx.Done():
+				return outcomeCompleted
+			}
+			if ps.reconnect(ctx, password) {
+				break
+			}
+			parkReconnectFailures.Inc()
+			delay = min(2*delay, ps.cfg.ParkReconnectMaxDelay)
+		}
+		account.Reconnects++
+		parkReconnects.Inc()
+		delay = ps.cfg.ParkReconnectDelay
+	}
+}
+
+// idle reads from the parked connection until it fails or ctx is done,
+// classifying every message received. A read timeout only touches the
+// watchdog.
+func (ps *PlayerSessionState) idle(ctx context.Context) error {
+	conn := ps.conn
+	stopClose := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stopClose()
+	stopKeepalive := ps.startKeepalive()
+	defer stopKeepalive()
+	conn.ReadTimeout = parkPoll
+	if r := ps.cfg.ReapAfter / 2; r > 0 && r < parkPoll {
+		conn.ReadTimeout = r
+	}
+	for {
+		ps.tracked.touch(stateParked)
+		resp, err := conn.ReadMessage()
+		var decodeErr *pokerclient.DecodeError
+		switch {
+		case err == nil:
+		case errors.As(err, &decodeErr):
+			parked.event("undecodable")
+			continue
+		case ctx.Err() == nil && errclass.Classify(err) == errclass.Timeout:
+			continue
+		default:
+			if ctx.Err() == nil {
+				ps.keepaliveReaction(nil, err)
+			}
+			return err
+		}
+		if ps.keepaliveReaction(resp, nil) {
+			rejections.add(actionKeepalive, resp.Code)
+			continue // the answer to our keepalive, not unsolicited
+		}
+		messagesReceived.Inc()
+		noteUnknownKeys(resp)
+		transcriptOut.Write(ps.username, resp.GameID, resp.Raw)
+		kind := resp.Type
+		switch {
+		case kind == "" && resp.Code != 0:
+			kind = "error_" + strconv.Itoa(resp.Code)
+		case kind == "":
+			kind = "untyped"
+		}
+		parked.event(kind)
+		ps.logVerbose("Unsolicited %s while parked.", kind)
+	}
+}
+
+// reconnect dials the session's server again and logs in, making the new
+// connection the session's.
+func (ps *PlayerSessionState) reconnect(ctx context.Context, password string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	series.Started()
+	start := time.Now()
+	conn, err := pokerclient.Dial(ps.addr, ps.cfg.ConnectTimeout)
+	if err != nil {
+		ps.logVerbose("Error dialing TCP server to reconnect: %v", err)
+		recordRegistrationFailure(err, ps.username, ps.addr)
+		return false
+	}
+	ps.cfg.ApplyTimeouts(conn)
+	if ps.verbose() {
+		conn.Logf = ps.logVerbose
+	}
+	previous := ps.conn
+	ps.conn = conn
+	ps.tracked.conn.Store(conn)
+	// Closes the connection if the run is interrupted during the login.
+	stopClose := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stopClose()
+	if !ps.register(password) {
+		conn.Close()
+		ps.conn = previous
+		return false
+	}
+	recordRegistration(time.Since(start)-conn.DialWait, ps.addr)
+	ps.logVerbose("Reconnected and logged in again.")
+	return true
+}
+
+// Parked connections, across sessions.
+var (
+	parkDrops             = registry.Counter("park_drops", "Parked connections that dropped.")
+	parkReconnects        = registry.Counter("park_reconnects", "Parked sessions connected and logged in again after a drop.")
+	parkReconnectFailures = registry.Counter("park_reconnect_failures", "Failed attempts to reconnect a parked session.")
+)
+
+// parkedAccount is how a parked session fared: how long it was connected,
+// and how often it dropped and came back.
+type parkedAccount struct {
+	Player     string
+	Uptime     time.Duration
+	Drops      int
+	Reconnects int
+}
+
+// parkStats gathers the parked sessions and the unsolicited messages they
+// received, by kind: the message type, or error_<code> for an error. It is
+// safe for concurrent use.
+type parkStats struct {
+	mu       sync.Mutex
+	accounts []*parkedAccount
+	events   map[string]int64
+}
+
+var parked parkStats
+
+func (p *parkStats) add(a *parkedAccount) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.accounts = append(p.accounts, a)
+}
+
+func (p *parkStats) event(kind string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.events == nil {
+		p.events = make(map[string]int64)
+	}
+	p.events[kind]++
+}
+
+// snapshot returns the accounts, least uptime first, and the event kinds,
+// most frequent first, with their counts.
+func (p *parkStats) snapshot() (accounts []parkedAccount, kinds []string, events map[string]int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, a := range p.accounts {
+		accounts = append(accounts, *a)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Uptime < accounts[j].Uptime })
+	events = make(map[string]int64, len(p.events))
+	for k, n := range p.events {
+		kinds = append(kinds, k)
+		events[k] = n
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		if events[kinds[i]] != events[kinds[j]] {
+			return events[kinds[i]] > events[kinds[j]]
+		}
+		return kinds[i] < kinds[j]
+	})
+	return accounts, kinds, events
+}
+
+// maxParkedListed is how many parked accounts print summarizes one by one;
+// past it, only the ones with the least uptime are listed.
+const maxParkedListed = 20
+
+// print writes the uptime of the parked accounts and the breakdown of the
+// unsolicited messages they received.
+func (p *parkStats) print(w io.Writer) {
+	accounts, kinds, events := p.snapshot()
+	if len(accounts) == 0 {
+		return
+	}
+	var total time.Duration
+	for _, a := range accounts {
+		total += a.Uptime
+	}
+	fmt.Fprintf(w, "Parked accounts: %d, uptime min %s, mean %s, max %s; drops %d, reconnects %d (%d failed attempts)\n",
+		len(accounts), accounts[0].Uptime.Round(time.Second), (total / time.Duration(len(accounts))).Round(time.Second),
+		accounts[len(accounts)-1].Uptime.Round(time.Second), parkDrops.Load(), parkReconnects.Load(), parkReconnectFailures.Load())
+	listed := accounts
+	if len(accounts) > maxParkedListed {
+		listed = accounts[:maxParkedListed/2]
+		fmt.Fprintf(w, "  the %d with the least uptime:\n", len(listed))
+	}
+	for _, a := range listed {
+		fmt.Fprintf(w, "  %-24s uptime %-10s drops %d, reconnects %d\n", a.Player, a.Uptime.Round(time.Second), a.Drops, a.Reconnects)
+	}
+	if len(kinds) == 0 {
+		fmt.Fprintln(w, "Unsolicited messages to parked connections: none")
+		return
+	}
+	fmt.Fprintln(w, "Unsolicited messages to parked connections:")
+	for _, k := range kinds {
+		fmt.Fprintf(w, "  %-24s %d\n", k, events[k])
+	}
+}
+
+// fill adds the parked accounts' uptime and a park_event_<kind> counter per
+// kind of unsolicited message to rep.
+func (p *parkStats) fill(rep *report.Report) {
+	accounts, _, events := p.snapshot()
+	if len(accounts) == 0 {
+		return
+	}
+	var total time.Duration
+	for _, a := range accounts {
+		total += a.Uptime
+	}
+	rep.Counters["parked_accounts"] = int64(len(accounts))
+	rep.Counters["park_uptime_min_ms"] = accounts[0].Uptime.Milliseconds()
+	rep.Counters["park_uptime_mean_ms"] = (total / time.Duration(len(accounts))).Milliseconds()
+	rep.Counters["park_uptime_max_ms"] = accounts[len(accounts)-1].Uptime.Milliseconds()
+	for k, n := range events {
+		rep.Counters["park_event_"+k] = n
+	}
+}
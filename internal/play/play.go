@@ -0,0 +1,1291 @@
+// Package play implements the "play" command: it registers players over the
+// TCP protocol, joins them to games and plays them with a betting strategy.
+package play
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"elastic-ai-jam-2025/internal/blockdetect"
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/clock"
+	"elastic-ai-jam-2025/internal/endpoint"
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/manifest"
+	"elastic-ai-jam-2025/internal/metrics"
+	"elastic-ai-jam-2025/internal/panics"
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/preflight"
+	"elastic-ai-jam-2025/internal/rejectlog"
+	"elastic-ai-jam-2025/internal/report"
+	"elastic-ai-jam-2025/internal/resusage"
+	"elastic-ai-jam-2025/internal/rng"
+	"elastic-ai-jam-2025/internal/script"
+	"elastic-ai-jam-2025/internal/timeseries"
+	"elastic-ai-jam-2025/internal/transcript"
+)
+
+// Config is the configuration of a play run.
+type Config struct {
+	cli.Common
+
+	// Number of players to attempt to create and have play.
+	// WARNING: Start with 1 for testing the game logic.
+	NumPlayers int
+
+	// Duration, when positive, stops launching sessions once it elapsed;
+	// the running ones are then drained. NumPlayers still bounds the
+	// number of sessions, and an interrupt still stops launching earlier:
+	// whichever stop condition comes first wins.
+	Duration time.Duration
+
+	// Waves, when set, is a comma-separated list of increasing wave sizes.
+	// Each wave runs that many sessions at once and drains before the next
+	// starts; escalation stops at the first wave whose failure rate exceeds
+	// WaveMaxFailureRate or whose time to seat p95 exceeds WaveMaxSeatP95
+	// (0 disables that ceiling). NumPlayers and MaxConcurrent are ignored,
+	// and Duration applies to each wave.
+	Waves              string
+	WaveMaxFailureRate float64
+	WaveMaxSeatP95     time.Duration
+
+	// Soak, when positive, runs until interrupted with that many sessions
+	// active, starting a new player for every session that ends. Every
+	// SoakRollover, aligned on the clock, the results, time series and
+	// report of the window are rolled over into timestamped files; see
+	// soak.go. NumPlayers and MaxConcurrent are ignored, and Duration still
+	// ends the run.
+	Soak         int
+	SoakRollover time.Duration
+
+	// MaxConcurrent controls how many sessions run in parallel.
+	MaxConcurrent int
+	// JoinRate, when positive, caps the sessions launched per second.
+	JoinRate float64
+
+	// PoolSize, when positive, keeps up to that many players registered
+	// ahead of their session, refilled by PoolRefill workers, so sessions
+	// join without waiting for registration. A pooled connection idle for
+	// longer than PoolMaxIdle is dropped and its player registered again.
+	PoolSize    int
+	PoolRefill  int
+	PoolMaxIdle time.Duration
+
+	BaseUsername string // Usernames will be like over-0, over-1, ...
+	// Shuffle launches the players in an order permuted by the seed rather
+	// than by index, so no username is always created first.
+	Shuffle bool
+	// RejectionSamples is how many distinct rejection messages are kept
+	// per error code for the summary and the report.
+	RejectionSamples int
+
+	// GameActivityTimeout is the max time to wait for any game activity before assuming stall.
+	GameActivityTimeout time.Duration
+	// SeatTimeout is how long a session waits, after joining, for the first
+	// message showing it was seated; zero waits up to GameActivityTimeout.
+	SeatTimeout time.Duration
+	// StallWarning is how long a session may go without a message before a
+	// warning is logged. Unlike GameActivityTimeout it does not end the session.
+	StallWarning time.Duration
+
+	// Keepalive is "", "tcp", "empty" or "ping"; see keepalive.go. Protocol
+	// keepalives are sent after KeepaliveIdle without a write.
+	Keepalive     string
+	KeepaliveIdle time.Duration
+
+	Verbose bool // Set to true to see detailed logs for player sessions
+	// LogDir, when set, gets one file per verbose session, named after the
+	// player, instead of interleaving them on stdout. At most
+	// LogDirMaxFiles are created; later sessions log to stdout.
+	LogDir         string
+	LogDirMaxFiles int
+
+	// ReapAfter, when positive, is how long a session may go without any
+	// activity before the watchdog closes its connection. It backs up the
+	// per-read timeouts, for sessions stuck where no timeout applies.
+	ReapAfter time.Duration
+
+	// MaxHands, when positive, is the number of bet prompts a session
+	// answers; it then folds until the hand ends and leaves.
+	MaxHands int
+
+	// ReplaceBusted starts a new player, under the next unused index, for
+	// every session that runs out of chips, keeping concurrency constant.
+	ReplaceBusted bool
+
+	// Strategy names the betting strategy of every session.
+	Strategy string
+	// ExploitMinObservations is how many moves of an opponent the exploit
+	// strategy needs before it deviates from its fallback.
+	ExploitMinObservations int
+	// AggressionShare is the share of its stack an opponent may put in a
+	// hand before the fold-to-aggression strategy folds.
+	AggressionShare float64
+
+	// ThinkTime is the most a session waits, at random, before sending the
+	// move its strategy chose. TurnTimeout is the time to act the server
+	// allows when its prompts do not say; see deadline.go. The strategy and
+	// the wait together take at most TurnBudget of a turn whose deadline is
+	// known.
+	ThinkTime   time.Duration
+	TurnTimeout time.Duration
+	TurnBudget  float64
+
+	// ConfirmWindow is how soon the messages following a move must show
+	// the server applied it; see confirm.go. Zero disables the watch.
+	ConfirmWindow time.Duration
+
+	// ProtocolCheckWindow is how many of the first bet prompts and
+	// registration replies of the run are checked for signs of a protocol
+	// change, each kind needing ProtocolMinShare of them as expected; see
+	// protocheck.go. ProtocolAbort ends the run once it is suspect.
+	ProtocolCheckWindow int
+	ProtocolMinShare    float64
+	ProtocolAbort       bool
+
+	// Park logs the sessions in and keeps them connected, without ever
+	// joining a game, for ParkFor or until interrupted; see park.go. A
+	// dropped connection is reconnected after ParkReconnectDelay, doubling
+	// up to ParkReconnectMaxDelay while the attempts fail.
+	Park                  bool
+	ParkFor               time.Duration
+	ParkReconnectDelay    time.Duration
+	ParkReconnectMaxDelay time.Duration
+
+	// ChipsTolerance is how far the stack the session tracked may be from
+	// the one a prompt reports before the server's counts as a correction.
+	ChipsTolerance int
+
+	// SpectateGames is how many games a spectate session observes, and
+	// SpectateMinChips the stack below which it stops.
+	SpectateGames    int
+	SpectateMinChips int
+
+	// TranscriptOut, when set, is the NDJSON file every received message is
+	// recorded to.
+	TranscriptOut string
+	// GamesManifest, when set, is the file the games the sessions took part
+	// in are written to once the run ends; see package manifest.
+	GamesManifest string
+
+	// ResultsOut, when set, is the NDJSON file of per-session results,
+	// appended to as sessions finish. ResumeResults loads it first and skips
+	// the usernames it records as completed.
+	ResultsOut    string
+	ResumeResults bool
+	// RecordHands adds the hands of each session to its results; see
+	// HandTracker.
+	RecordHands bool
+	// BaselineResults, when set, is a -results-out file, typically of a
+	// spectate run, whose sessions that folded every prompt give the
+	// always-fold baseline of the strategy effectiveness section.
+	BaselineResults string
+	// TimeseriesOut, when set, is the file of per-second registrations and
+	// moves, in TimeseriesFormat.
+	TimeseriesOut    string
+	TimeseriesFormat string
+	// ProgressInterval is the period of the rolling summary; 0 disables it.
+	ProgressInterval time.Duration
+	// Enrich fetches the HTTP details of every game played once the run
+	// ends, with at most EnrichConcurrency requests in flight.
+	Enrich            bool
+	EnrichConcurrency int
+
+	// VerifyChips compares, once the run ends, the chips of a sample of
+	// VerifyChipsSample sessions with the first VerifyLeaderboardLimit
+	// leaderboard entries. Missing players are retried VerifyChipsRetries
+	// times, VerifyChipsDelay apart.
+	VerifyChips            bool
+	VerifyChipsSample      int
+	VerifyChipsRetries     int
+	VerifyChipsDelay       time.Duration
+	VerifyLeaderboardLimit int
+
+	// Script, when set, replaces the players with one session, the first
+	// player, playing this script file; see package script. Each wait and
+	// expect step may take up to ScriptTimeout.
+	Script        string
+	ScriptTimeout time.Duration
+
+	// CalibrateFrom, when set, is the report of an earlier play run whose
+	// averages the estimate printed before the run uses instead of the
+	// built-in ones; see estimate.go. A run whose estimate exceeds one of
+	// the MaxEst ceilings needs confirmation; zero disables a ceiling.
+	CalibrateFrom     string
+	MaxEstConnections int64
+	MaxEstFDs         int64
+	MaxEstMemoryMB    int64
+	MaxEstDuration    time.Duration
+
+	// CredentialsFile, when set, holds the accounts the sessions play
+	// instead of the -username-prefix range; see credentials.go for how it
+	// reconciles with NumPlayers, RepeatCredentials and
+	// AllowDuplicateLogins.
+	CredentialsFile      string
+	RepeatCredentials    bool
+	AllowDuplicateLogins bool
+
+	// DryRun checks configuration and connectivity, then exits without playing.
+	DryRun bool
+
+	// logins is the resolved -credentials-file; nil without one.
+	logins *credentialPlan
+	// clock paces the launches, the parked sessions' reconnections and the
+	// think time, and times the seat and game activity timeouts and the
+	// turns.
+	clock clock.Clock
+}
+
+// DefaultConfig returns the defaults the standalone create-and-play binary used.
+func DefaultConfig() Config {
+	common := cli.DefaultCommon()
+	common.ReadTimeout = 60 * time.Second // waiting between hands is normal
+	return Config{
+		Common:              common,
+		clock:               clock.Real,
+		NumPlayers:          1000000,
+		MaxConcurrent:       1000,
+		PoolRefill:          8,
+		PoolMaxIdle:         30 * time.Second,
+		BaseUsername:        "over-",
+		RejectionSamples:    5,
+		GameActivityTimeout: 60 * time.Second,
+		SeatTimeout:         60 * time.Second,
+		WaveMaxFailureRate:  0.05,
+		TimeseriesFormat:    timeseries.FormatCSV,
+		WaveMaxSeatP95:      10 * time.Second,
+		SoakRollover:        time.Hour,
+		StallWarning:        20 * time.Second,
+		ReapAfter:           5 * time.Minute,
+		KeepaliveIdle:       20 * time.Second,
+		Verbose:             true,
+		LogDirMaxFiles:      100,
+		Strategy:            "allin-once",
+
+		ExploitMinObservations: 10,
+		AggressionShare:        0.25,
+		TurnBudget:             0.5,
+		ConfirmWindow:          5 * time.Second,
+		ProtocolCheckWindow:    20,
+		ProtocolMinShare:       0.9,
+		ParkReconnectDelay:     time.Second,
+		ParkReconnectMaxDelay:  time.Minute,
+		SpectateGames:          10,
+		SpectateMinChips:       100,
+		EnrichConcurrency:      8,
+		ProgressInterval:       time.Minute,
+		VerifyChipsSample:      100,
+		VerifyChipsRetries:     2,
+		VerifyChipsDelay:       5 * time.Second,
+		VerifyLeaderboardLimit: 10000,
+		ScriptTimeout:          time.Minute,
+		MaxEstFDs:              openFileLimit(),
+	}
+}
+
+// RegisterFlags adds the play flags to fs.
+func (cfg *Config) RegisterFlags(fs *flag.FlagSet) {
+	cfg.Common.Register(fs)
+	cfg.Common.RegisterMetricsFlag(fs)
+	cfg.Common.RegisterBlockFlags(fs)
+	cfg.Common.RegisterOutageFlags(fs)
+	cfg.Common.RegisterDialLimitFlag(fs)
+	cfg.Common.RegisterFailFastFlag(fs)
+	cfg.Common.RegisterSeatbeltFlags(fs)
+	cfg.Common.RegisterResourceFlags(fs)
+	fs.IntVar(&cfg.NumPlayers, "players", cfg.NumPlayers, "number of players to create and have play (an upper bound with -duration)")
+	fs.DurationVar(&cfg.Duration, "duration", cfg.Duration, "keep launching sessions for this long, then wait for the running ones (0: launch all -players)")
+	fs.StringVar(&cfg.Waves, "waves", cfg.Waves, "run waves of this many concurrent sessions, e.g. 50,100,200,400, each draining before the next")
+	fs.Float64Var(&cfg.WaveMaxFailureRate, "wave-max-failure-rate", cfg.WaveMaxFailureRate, "stop -waves after a wave whose share of failed registrations and unseated sessions exceeds this")
+	fs.DurationVar(&cfg.WaveMaxSeatP95, "wave-max-seat-p95", cfg.WaveMaxSeatP95, "stop -waves after a wave whose time to seat p95 exceeds this (0 disables)")
+	fs.IntVar(&cfg.Soak, "soak", cfg.Soak, "run until interrupted with this many sessions active, replacing every session that ends")
+	fs.DurationVar(&cfg.SoakRollover, "soak-rollover", cfg.SoakRollover, "roll -soak's results, time series and report over into timestamped files this often")
+	fs.IntVar(&cfg.MaxConcurrent, "concurrency", cfg.MaxConcurrent, "number of sessions running in parallel")
+	fs.Float64Var(&cfg.JoinRate, "join-rate", cfg.JoinRate, "max sessions launched per second (0: unlimited)")
+	fs.IntVar(&cfg.PoolSize, "pool-size", cfg.PoolSize, "keep this many players registered ahead of their session (0 disables the pool)")
+	fs.IntVar(&cfg.PoolRefill, "pool-refill-concurrency", cfg.PoolRefill, "registrations the pool runs in parallel")
+	fs.DurationVar(&cfg.PoolMaxIdle, "pool-max-idle", cfg.PoolMaxIdle, "register a pooled player again if it waited longer than this (0: never)")
+	fs.StringVar(&cfg.BaseUsername, "username-prefix", cfg.BaseUsername, "prefix of generated usernames")
+	cfg.Common.RegisterPasswordFlags(fs, "prefix of generated passwords, followed by the player index")
+	fs.BoolVar(&cfg.Shuffle, "shuffle", cfg.Shuffle, "launch the players in an order permuted by the seed instead of by index")
+	fs.IntVar(&cfg.RejectionSamples, "rejection-samples", cfg.RejectionSamples, "distinct server rejection messages shown per error code in the summary and report (0 disables)")
+	fs.DurationVar(&cfg.GameActivityTimeout, "game-timeout", cfg.GameActivityTimeout, "max time to wait for game activity before assuming a stall")
+	fs.DurationVar(&cfg.SeatTimeout, "seat-timeout", cfg.SeatTimeout, "max time to wait after joining for the first message showing the session was seated (0: use -game-timeout)")
+	fs.DurationVar(&cfg.ReapAfter, "reap-after", cfg.ReapAfter, "close sessions with no activity for this long, ending them as reaped (0 disables)")
+	fs.DurationVar(&cfg.StallWarning, "stall-warning", cfg.StallWarning, "warn when a session receives nothing for this long (0 disables)")
+	fs.StringVar(&cfg.Keepalive, "keepalive", cfg.Keepalive, "keep idle connections alive: tcp, empty or ping (default off)")
+	fs.DurationVar(&cfg.KeepaliveIdle, "keepalive-idle", cfg.KeepaliveIdle, "idle time after which a keepalive is sent")
+	fs.IntVar(&cfg.ProtocolCheckWindow, "protocol-check-window", cfg.ProtocolCheckWindow, "first bet prompts and registration replies checked for signs of a protocol change (0 disables)")
+	fs.Float64Var(&cfg.ProtocolMinShare, "protocol-min-share", cfg.ProtocolMinShare, "share of the checked prompts and registration replies that must look as expected, in (0, 1]")
+	fs.BoolVar(&cfg.ProtocolAbort, "protocol-abort", cfg.ProtocolAbort, "end the run as soon as the protocol check finds it suspect")
+	fs.BoolVar(&cfg.Park, "park", cfg.Park, "log the players in and keep them connected without joining, counting what the server sends them (keepalive defaults to tcp)")
+	fs.DurationVar(&cfg.ParkFor, "park-for", cfg.ParkFor, "how long -park keeps the players connected (0: until interrupted)")
+	fs.DurationVar(&cfg.ParkReconnectDelay, "park-reconnect-delay", cfg.ParkReconnectDelay, "wait before reconnecting a dropped -park connection, doubled after each failed attempt")
+	fs.DurationVar(&cfg.ParkReconnectMaxDelay, "park-reconnect-max-delay", cfg.ParkReconnectMaxDelay, "max wait between attempts to reconnect a -park connection")
+	fs.IntVar(&cfg.MaxHands, "max-hands", cfg.MaxHands, "bet prompts each session answers before leaving at the end of the hand (0: no limit)")
+	fs.BoolVar(&cfg.ReplaceBusted, "replace-busted", cfg.ReplaceBusted, "start a new player for every session that runs out of chips, until the run is interrupted")
+	fs.BoolVar(&cfg.Verbose, "verbose", cfg.Verbose, "log every session's messages")
+	fs.StringVar(&cfg.LogDir, "log-dir", cfg.LogDir, "write each verbose session's log to <player>.log in this directory instead of stdout")
+	fs.IntVar(&cfg.LogDirMaxFiles, "log-dir-max-files", cfg.LogDirMaxFiles, "max per-player files -log-dir creates; later sessions log to stdout")
+	fs.StringVar(&cfg.Strategy, "strategy", cfg.Strategy, "betting strategy: "+strings.Join(strategyNames(), ", "))
+	fs.IntVar(&cfg.SpectateGames, "spectate-games", cfg.SpectateGames, "games each session observes with -strategy=spectate")
+	fs.IntVar(&cfg.SpectateMinChips, "spectate-min-chips", cfg.SpectateMinChips, "stop spectating when the session's chips drop below this")
+	fs.StringVar(&cfg.TranscriptOut, "transcript-out", cfg.TranscriptOut, "record every received message to this NDJSON file")
+	fs.StringVar(&cfg.GamesManifest, "games-manifest", cfg.GamesManifest, "write the games the sessions took part in to this JSON file, for analyze -games-manifest")
+	fs.StringVar(&cfg.ResultsOut, "results-out", cfg.ResultsOut, "write per-session results to this NDJSON file")
+	fs.StringVar(&cfg.TimeseriesOut, "timeseries-out", cfg.TimeseriesOut, "write per-second registrations and moves to this file as the run progresses")
+	fs.StringVar(&cfg.TimeseriesFormat, "timeseries-format", cfg.TimeseriesFormat, "format of -timeseries-out: "+strings.Join(timeseries.Formats, " or ")+" (json is written at the end of the run)")
+	fs.BoolVar(&cfg.RecordHands, "record-hands", cfg.RecordHands, "record each hand, with our moves, chips and pots won, in the -results-out line of its session")
+	fs.StringVar(&cfg.BaselineResults, "baseline-results", cfg.BaselineResults, "compare the strategy with the sessions that folded every prompt in this -results-out file, e.g. of a spectate run")
+	fs.BoolVar(&cfg.ResumeResults, "resume-results", cfg.ResumeResults, "skip the players -results-out already records as completed, and append to it")
+	fs.DurationVar(&cfg.ProgressInterval, "progress-interval", cfg.ProgressInterval, "print a rolling summary this often (0 disables)")
+	fs.BoolVar(&cfg.Enrich, "enrich", cfg.Enrich, "after the run, fetch the HTTP details of every game played")
+	fs.IntVar(&cfg.EnrichConcurrency, "enrich-concurrency", cfg.EnrichConcurrency, "max concurrent game detail requests of -enrich")
+	fs.BoolVar(&cfg.VerifyChips, "verify-chips", cfg.VerifyChips, "after the run, compare the sessions' chips with the leaderboard")
+	fs.IntVar(&cfg.VerifyChipsSample, "verify-chips-sample", cfg.VerifyChipsSample, "number of players -verify-chips checks")
+	fs.IntVar(&cfg.VerifyChipsRetries, "verify-chips-retries", cfg.VerifyChipsRetries, "lookups retried for players not yet on the leaderboard")
+	fs.DurationVar(&cfg.VerifyChipsDelay, "verify-chips-delay", cfg.VerifyChipsDelay, "delay between -verify-chips lookups")
+	fs.IntVar(&cfg.VerifyLeaderboardLimit, "verify-leaderboard-limit", cfg.VerifyLeaderboardLimit, "leaderboard entries fetched by -verify-chips")
+	fs.IntVar(&cfg.ExploitMinObservations, "exploit-min-observations", cfg.ExploitMinObservations, "opponent moves the exploit strategy needs before it adapts")
+	fs.Float64Var(&cfg.AggressionShare, "aggression-share", cfg.AggressionShare, "share of its stack an opponent may put in a hand before the fold-to-aggression strategy folds")
+	fs.DurationVar(&cfg.ThinkTime, "think-time", cfg.ThinkTime, "wait up to this long, at random, before sending each move (0 disables)")
+	fs.DurationVar(&cfg.TurnTimeout, "turn-timeout", cfg.TurnTimeout, "time the server allows to act, for prompts that do not carry a deadline (0: unknown)")
+	fs.Float64Var(&cfg.TurnBudget, "turn-budget", cfg.TurnBudget, "share of a turn with a known deadline the strategy and -think-time may use, in (0, 1]")
+	fs.DurationVar(&cfg.ConfirmWindow, "confirm-window", cfg.ConfirmWindow, "count a move as unconfirmed unless the following messages show the server applied it within this long; a move prompted again as if dropped is resent once or folded, as the strategy decides (0 disables)")
+	fs.IntVar(&cfg.ChipsTolerance, "chips-tolerance", cfg.ChipsTolerance, "chips the tracked stack may differ from a prompt's before counting a correction (blinds posted without an event differ)")
+	fs.StringVar(&cfg.Script, "script", cfg.Script, "play this script of actions and expectations as the first player, instead of running sessions")
+	fs.DurationVar(&cfg.ScriptTimeout, "script-timeout", cfg.ScriptTimeout, "max duration of each wait and expect step of -script")
+	fs.StringVar(&cfg.CalibrateFrom, "calibrate-from", cfg.CalibrateFrom, "estimate the run with the averages of this earlier play -report-out instead of built-in ones")
+	fs.Int64Var(&cfg.MaxEstConnections, "max-est-connections", cfg.MaxEstConnections, "ask for confirmation when the run is estimated to open more connections (0 disables)")
+	fs.Int64Var(&cfg.MaxEstFDs, "max-est-fds", cfg.MaxEstFDs, "ask for confirmation when the run is estimated to hold more file descriptors (default: the open files limit; 0 disables)")
+	fs.Int64Var(&cfg.MaxEstMemoryMB, "max-est-memory-mb", cfg.MaxEstMemoryMB, "ask for confirmation when the run is estimated to use more MiB of memory (0 disables)")
+	fs.DurationVar(&cfg.MaxEstDuration, "max-est-duration", cfg.MaxEstDuration, "ask for confirmation when the run is estimated to last longer (0 disables)")
+	fs.StringVar(&cfg.CredentialsFile, "credentials-file", cfg.CredentialsFile, "play the accounts of this file, username:password lines, usernames alone with -password-secret, or a play -results-out file, instead of the -username-prefix range")
+	fs.BoolVar(&cfg.RepeatCredentials, "repeat-credentials", cfg.RepeatCredentials, "cycle -credentials-file until -players sessions are reached")
+	fs.BoolVar(&cfg.AllowDuplicateLogins, "allow-duplicate-logins", cfg.AllowDuplicateLogins, "with -repeat-credentials, reuse the usernames as they are instead of suffixing them with -r<cycle>")
+	fs.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "check configuration and connectivity, print the plan and exit")
+}
+
+// --- Global Counters (registered in registry, safe for concurrent use) ---
+var (
+	registry = metrics.New()
+
+	sessionsLaunched        = registry.Counter("sessions_launched", "Player sessions started.")
+	successfulRegistrations = registry.Counter("successful_registrations", "Registrations the server accepted.")
+	failedRegistrations     = registry.Counter("failed_registrations", "Registrations or joins that failed.")
+	registrationLatency     = registry.Histogram("registration", "Time from dialing to the registration reply, without the wait for a dial slot.")
+	gamesJoined             = registry.Counter("games_joined", "Join requests sent.")
+	sessionLifetime         = registry.Histogram("session_lifetime", "Time from dialing to the end of a session.")
+	timeToSeat              = registry.Histogram("time_to_seat", "Time from a join request to the first message showing the session was seated.")
+	sessionsNeverSeated     = registry.Counter("sessions_never_seated", "Sessions that timed out waiting for their first seat.")
+	allInsMade              = registry.Counter("all_ins", "All-in bets sent.")
+	betsMade                = registry.Counter("bets", "Bets other than all-ins sent.")
+	foldsMade               = registry.Counter("folds", "Folds sent.")
+
+	// promptsMatchedLoosely counts the bet prompts addressed to us that an
+	// exact comparison of their player_id with the username would have
+	// missed, and idMismatchWarned is set once that was warned about.
+	promptsMatchedLoosely = registry.Counter("prompts_matched_loosely", "Bet prompts matched despite a player_id differing from the username.")
+	idMismatchWarned      atomic.Bool
+	betRetries            = registry.Counter("bet_retries", "Bets resent after the server rejected them.")
+
+	// Verdicts on the moves sent; see actionConfirmer.
+	movesConfirmed     = registry.Counter("moves_confirmed", "Moves the following messages showed applied within -confirm-window.")
+	movesConfirmedLate = registry.Counter("moves_confirmed_late", "Moves the following messages showed applied after -confirm-window.")
+	movesDropped       = registry.Counter("moves_dropped", "Moves followed by the same prompt again, as if the server never got them.")
+	movesUnsettled     = registry.Counter("moves_unsettled", "Moves neither confirmed nor dropped when their session ended.")
+	droppedResent      = registry.Counter("dropped_moves_resent", "Dropped moves the strategy chose to resend.")
+	droppedFolded      = registry.Counter("dropped_moves_folded", "Dropped moves answered with a fold.")
+
+	// Decisions of the exploit strategy that departed from its fallback.
+	exploitShoves     = registry.Counter("exploit_shoves", "Exploit strategy shoves against folding tables.")
+	exploitTightFolds = registry.Counter("exploit_tight_folds", "Exploit strategy folds against calling tables.")
+
+	// Folds of the fold-to-aggression strategy, and moves left out of a
+	// hand's history past maxHandHistory.
+	aggressionFolds    = registry.Counter("aggression_folds", "Fold-to-aggression strategy folds to an opponent's commitment.")
+	handHistoryDropped = registry.Counter("hand_history_dropped", "Moves left out of a hand's history, past its cap.")
+
+	// stalledSessions is the number of sessions currently quiet for longer
+	// than -stall-warning; everStalled counts those that ever were.
+	stalledSessions = registry.Gauge("sessions_stalled", "Sessions currently quiet for longer than -stall-warning.")
+	everStalled     = registry.Counter("stalled_sessions", "Sessions that were ever quiet for longer than -stall-warning.")
+
+	// Protocol keepalives sent and how the server reacted to them.
+	keepalivesSent       = registry.Counter("keepalives_sent", "Keepalives sent.")
+	keepalivesIgnored    = registry.Counter("keepalives_ignored", "Keepalives the server did not answer.")
+	keepaliveErrors      = registry.Counter("keepalive_errors", "Keepalives answered with an error.")
+	keepaliveDisconnects = registry.Counter("keepalive_disconnects", "Connections lost right after a keepalive.")
+
+	// Opponent moves seen by every session's OpponentModel.
+	opponentFolds  = registry.Counter("opponent_folds", "Opponent folds observed.")
+	opponentCalls  = registry.Counter("opponent_calls", "Opponent calls and checks observed.")
+	opponentRaises = registry.Counter("opponent_raises", "Opponent bets and raises observed.")
+
+	// Sessions that ran out of chips, the games they finished before that,
+	// and the players started to replace them.
+	playersBusted        = registry.Counter("players_busted", "Sessions that ran out of chips.")
+	bustedGamesSurvived  = registry.Counter("busted_games_survived", "Games busted sessions finished before the one they went broke in.")
+	replacementsLaunched = registry.Counter("replacement_sessions", "Sessions started to replace busted ones.")
+	// nextPlayerIndex is the index of the next replacement player.
+	nextPlayerIndex atomic.Int64
+
+	// Warm-up pool: pooled players launched (hits), launches that found the
+	// pool empty (misses), pooled connections dropped as stale or never
+	// used, and how long pooled players waited.
+	poolHits     = registry.Counter("pool_hits", "Sessions launched with a pooled registration.")
+	poolMisses   = registry.Counter("pool_misses", "Sessions launched while the pool was empty.")
+	poolStale    = registry.Counter("pool_stale", "Pooled registrations dropped for waiting past -pool-max-idle.")
+	poolUnused   = registry.Counter("pool_unused", "Pooled registrations never used.")
+	poolFailures = registry.Counter("pool_registration_failures", "Pool registrations that failed.")
+	poolIdle     = registry.Histogram("pool_idle", "Time a pooled registration waited for its session.")
+
+	// sessionsReaped counts the sessions the watchdog closed for being idle
+	// past -reap-after.
+	sessionsReaped = registry.Counter("sessions_reaped", "Sessions closed by the watchdog for being idle too long.")
+
+	// sessionsActive is the number of sessions running, as tracked by
+	// activeSessions. Once launchesStopped is set, not even replacements
+	// are started; launchStopReason says why and inFlightAtStop how many
+	// sessions were still running then.
+	sessionsActive   = registry.Gauge("sessions_active", "Player sessions running.")
+	launchesStopped  atomic.Bool
+	launchStopReason string
+	inFlightAtStop   int64
+
+	// Games watched to the end and the messages received during them.
+	gamesObserved  = registry.Counter("games_observed", "Games watched to the end.")
+	eventsCaptured = registry.Counter("events_captured", "Messages received during observed games.")
+	// messagesReceived counts every server message, for -calibrate-from.
+	messagesReceived = registry.Counter("messages_received", "Server messages received by the sessions.")
+
+	playerGames gameLog
+	// gamesSeen collects the -games-manifest.
+	gamesSeen manifest.Builder
+
+	// results streams and totals the finished sessions, and keeps them in
+	// memory when collectResults is true.
+	results       resultLog
+	gamesEnriched = registry.Counter("games_enriched", "Games whose details were fetched after the run.")
+	gamesNotFound = registry.Counter("games_not_enriched", "Games whose details could not be fetched.")
+
+	// series records per-second registrations; nil unless -timeseries-out
+	// is set.
+	series *timeseries.Series
+
+	// resources samples the client's own resource usage; nil when
+	// -resource-interval is zero.
+	resources *resusage.Sampler
+
+	// guard pauses launching while the server looks like it blocks us; nil
+	// when -block-cooldown is zero.
+	guard *blockdetect.Guard
+
+	// workerPanics recovers the panics of sessions and pool registrations,
+	// unless -fail-fast.
+	workerPanics panics.Recorder
+
+	// transcriptOut records received messages; nil unless -transcript-out is set.
+	transcriptOut *transcript.Writer
+
+	// messagesWithUnknownKeys counts server messages carrying top-level
+	// fields ServerResponse does not bind; unknownKeys records their names.
+	messagesWithUnknownKeys = registry.Counter("messages_with_unknown_keys", "Server messages with fields we do not bind.")
+	unknownKeys             sync.Map
+
+	// streamAnomalies counts the anomalies found by the stream checkers,
+	// by kind.
+	streamAnomalies = map[string]*metrics.Counter{
+		anomalyDuplicate:      registry.Counter("stream_duplicates", "Server messages identical to the one before them."),
+		anomalyTimeRegression: registry.Counter("stream_time_regressions", "Server messages timestamped before an earlier one."),
+		anomalyRepeatedPrompt: registry.Counter("stream_repeated_prompts", "Bet prompts repeating the one just answered."),
+	}
+
+	// positionSources counts the hands by where the session's position in
+	// them came from; see PositionTracker.
+	positionSources = map[string]*metrics.Counter{
+		PositionKnown:    registry.Counter("positions_known", "Hands whose position the prompt gave."),
+		PositionInferred: registry.Counter("positions_inferred", "Hands whose position was inferred from the order of action."),
+		PositionUnknown:  registry.Counter("positions_unknown", "Hands whose position could not be told."),
+	}
+
+	// Chip tracking anomalies; see chipTracker and withinStack.
+	chipsClamped         = registry.Counter("chips_clamped", "Chip counts that went negative and were clamped to zero.")
+	chipCorrections      = registry.Counter("chip_corrections", "Prompts whose chips differed from the tracked stack by more than -chips-tolerance.")
+	invalidBetsPrevented = registry.Counter("invalid_bets_prevented", "Moves of more chips than the stack or less than one chip, changed before sending.")
+
+	// malformedPrompts counts the bet prompts missing the player or its
+	// chips; loggedPrompts how many of them were logged.
+	malformedPrompts = registry.Counter("malformed_prompts", "Bet prompts missing the player or its chips.")
+	loggedPrompts    atomic.Int64
+
+	registrationFailures errclass.Counter
+	// effects collects the hands and chips of the sessions for the
+	// strategy effectiveness section.
+	effects effectLog
+	// gameDurations collects the games the sessions timed, for the game
+	// duration section.
+	gameDurations durationLog
+	// minimumBets collects the minimum bets of the games the sessions saw,
+	// and forcedAllInGames counts those that asked a session for more than
+	// its chips.
+	minimumBets      minimumBetLog
+	forcedAllInGames = registry.Counter("forced_all_in_games", "Games in which a bet prompt asked the session for more than its chips.")
+	// registrationRejections keeps the server's rejection messages; nil
+	// when -rejection-samples is zero.
+	registrationRejections *rejectlog.Log
+	// byEndpoint breaks the registrations, seats and session outcomes down
+	// per -server endpoint; nil with a single one.
+	byEndpoint *endpoint.Stats
+
+	// runEstimate is the estimate printed before the run, for comparison
+	// with what it did; nil for a -script run.
+	runEstimate *report.RunEstimate
+
+	startTime time.Time
+)
+
+// collectResults reports whether finished sessions are kept for after the run.
+func (cfg *Config) collectResults() bool {
+	return cfg.Enrich || cfg.VerifyChips || cfg.NumPlayers == 1
+}
+
+func newFlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("play", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	return fs
+}
+
+// Flags returns the play flag set with default values.
+func Flags() *flag.FlagSet {
+	cfg := DefaultConfig()
+	return newFlagSet(&cfg)
+}
+
+// Run is the entry point of the play command.
+func Run(args []string) int {
+	cfg := DefaultConfig()
+	fs := newFlagSet(&cfg)
+	if code, stop := cli.Parse(fs, args); stop {
+		if code != 0 {
+			return exitUsage
+		}
+		return code
+	}
+	closeLog, err := cfg.SetupLogging()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	defer closeLog()
+	if err := cfg.CheckIdent(cfg.BaseUsername); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	if err := cfg.Passwords.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	registrationRejections = rejectlog.New(cfg.RejectionSamples)
+	byEndpoint = endpoint.NewStats(cfg.TCPServer)
+	workerPanics.FailFast = cfg.FailFast
+	pokerclient.DialLimit = pokerclient.NewDialLimiter(cfg.MaxConcurrentDials)
+	if _, ok := strategies[cfg.Strategy]; !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown strategy %q (available: %s)\n", cfg.Strategy, strings.Join(strategyNames(), ", "))
+		return exitUsage
+	}
+	if _, err := keepaliveMessage(cfg.Keepalive); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	if cfg.ProtocolMinShare <= 0 || cfg.ProtocolMinShare > 1 {
+		fmt.Fprintln(os.Stderr, "Error: -protocol-min-share must be in (0, 1]")
+		return exitUsage
+	}
+	if cfg.AggressionShare <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: -aggression-share must be positive")
+		return exitUsage
+	}
+	if cfg.TurnBudget <= 0 || cfg.TurnBudget > 1 {
+		fmt.Fprintln(os.Stderr, "Error: -turn-budget must be in (0, 1]")
+		return exitUsage
+	}
+
+	var steps []script.Step
+	if cfg.Script != "" {
+		if steps, err = script.ParseFile(cfg.Script); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	var waves []int
+	if cfg.Waves != "" {
+		if waves, err = parseWaves(cfg.Waves); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	if cfg.Park {
+		if err := cfg.checkPark(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	if cfg.Soak > 0 {
+		if err := cfg.checkSoak(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	if err := cfg.resolveCredentials(fs, waves); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	cfg.logins.Print(os.Stdout, os.Stderr)
+
+	if steps == nil {
+		est, err := cfg.estimate(waves)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+		printEstimate(os.Stdout, est)
+		if over := cfg.exceededCeilings(est); len(over) > 0 {
+			volume := "an estimated " + strings.Join(over, ", ")
+			if cfg.DryRun {
+				fmt.Printf("Would ask for confirmation of %s\n", volume)
+			} else if err := cfg.ConfirmDestructive("play", cfg.TCPServer, volume); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitUsage
+			}
+		}
+		runEstimate = est
+	}
+
+	if cfg.DryRun {
+		return dryRun(&cfg)
+	}
+
+	if cfg.LogDir != "" {
+		if err := os.MkdirAll(cfg.LogDir, 0o755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	if cfg.TranscriptOut != "" {
+		if transcriptOut, err = transcript.Create(cfg.TranscriptOut); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+		transcriptOut.RunID = cfg.RunID
+	}
+
+	if steps != nil {
+		code := runScript(&cfg, steps)
+		if err := transcriptOut.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing transcript: %v\n", err)
+		}
+		return code
+	}
+
+	results.keep = cfg.collectResults()
+	var completed map[string]bool
+	if cfg.ResultsOut != "" {
+		if completed, err = results.open(cfg.ResultsOut, cfg.ResumeResults); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+	} else if cfg.ResumeResults {
+		fmt.Fprintln(os.Stderr, "Error: -resume-results needs -results-out")
+		return exitUsage
+	} else if cfg.RecordHands {
+		fmt.Fprintln(os.Stderr, "Error: -record-hands needs -results-out")
+		return exitUsage
+	}
+	if cfg.BaselineResults != "" {
+		n, err := effects.loadBaseline(cfg.BaselineResults)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+		fmt.Printf("Always-fold baseline: %d sessions from %s\n", n, cfg.BaselineResults)
+	}
+	resources = cfg.StartResources()
+	defer resources.Stop()
+	if cfg.TimeseriesOut != "" {
+		extra := append([]timeseries.Column{
+			timeseries.Rate("bets", func() int64 { return betsMade.Load() + allInsMade.Load() }),
+			timeseries.Rate("folds", foldsMade.Load),
+		}, resources.Columns()...)
+		opts := timeseries.Options{Format: cfg.TimeseriesFormat, Labels: map[string]string{"run_id": cfg.RunID}, Extra: extra}
+		if series, err = timeseries.Create(cfg.TimeseriesOut, time.Now(), opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+	}
+	stopMetrics, err := registry.Serve(cfg.MetricsAddr, "aijam_play")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	defer stopMetrics()
+
+	ctx, stop := cli.InterruptContext()
+	defer stop()
+	ctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	protocol = newProtocolCheck(cfg.ProtocolCheckWindow, cfg.ProtocolMinShare, func(string) {
+		if cfg.ProtocolAbort {
+			fmt.Fprintln(os.Stderr, "Aborting the run (-protocol-abort).")
+			abort(errProtocolSuspect)
+		}
+	})
+	rep := report.New("play", cli.Effective(fs))
+	var soak *soakWindows
+	if cfg.Soak > 0 {
+		soak = startSoak(&cfg, cli.Effective(fs))
+	}
+	guard = cfg.BlockGuard(blockTotals)
+	stopGuard := guard.Start(ctx)
+	var launched int
+	if waves != nil {
+		launched = runWaves(ctx, &cfg, waves, completed)
+	} else {
+		launched = runPlayers(ctx, &cfg, completed)
+	}
+	elapsed := runDuration()
+	stopGuard()
+	resources.Stop()
+	status, reason := "", ""
+	if ctx.Err() != nil {
+		status, reason = report.StatusInterrupted, fmt.Sprintf("interrupted after launching %d of %d sessions", launched, cfg.NumPlayers)
+		if soak != nil {
+			reason = fmt.Sprintf("interrupted after launching %d sessions", launched)
+		}
+		if errors.Is(context.Cause(ctx), errProtocolSuspect) {
+			status, reason = report.StatusAborted, "protocol suspect: "+protocol.suspect()
+		}
+	}
+	if soak != nil {
+		soak.finish(status, reason)
+	} else if err := series.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing time series: %v\n", err)
+	} else if cfg.TimeseriesOut != "" {
+		fmt.Printf("Per-second registrations written to %s\n", cfg.TimeseriesOut)
+	}
+	if err := transcriptOut.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing transcript: %v\n", err)
+	}
+	if cfg.GamesManifest != "" {
+		m := gamesSeen.Manifest()
+		m.RunID = cfg.RunID
+		if err := manifest.Write(cfg.GamesManifest, m); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		} else {
+			fmt.Printf("Games manifest written to %s (%d games)\n", cfg.GamesManifest, gamesSeen.Len())
+		}
+	}
+	if cfg.Enrich && ctx.Err() == nil {
+		fmt.Println("Fetching the details of the games played...")
+		enriched, notFound := results.enrich(httpapi.New(cfg.BaseURL.First(), cfg.RequestTimeout), cfg.EnrichConcurrency)
+		gamesEnriched.Add(int64(enriched))
+		gamesNotFound.Add(int64(notFound))
+	}
+	var chips *report.ChipReconciliation
+	if cfg.VerifyChips && ctx.Err() == nil {
+		fmt.Println("Verifying chips against the leaderboard...")
+		chips = verifyChips(ctx, &cfg, httpapi.New(cfg.BaseURL.First(), cfg.RequestTimeout))
+	}
+	if err := results.close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	} else if cfg.ResultsOut != "" && soak == nil {
+		if cfg.Enrich && gamesEnriched.Load()+gamesNotFound.Load() > 0 {
+			err = results.rewrite(cfg.ResultsOut)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		} else {
+			fmt.Printf("Session results written to %s\n", cfg.ResultsOut)
+		}
+	}
+	printSummary(&cfg, launched, elapsed)
+	if chips != nil {
+		printChipReconciliation(os.Stdout, chips)
+	}
+
+	fillReport(rep, cfg.RateSpan(elapsed, guard))
+	cfg.logins.Fill(rep)
+	rep.ChipReconciliation = chips
+	rep.Config = cli.Effective(fs) // picks up the resolved seed
+	rep.Finish(status, reason)
+	cfg.WriteReport(rep)
+	return exitCode(&cfg, launched)
+}
+
+// replaceBusted starts a session under the next unused player index. It
+// inherits the semaphore slot of the busted session it replaces.
+func replaceBusted(ctx context.Context, cfg *Config, wg *sync.WaitGroup, semaphore chan struct{}) {
+	replacementsLaunched.Inc()
+	startSession(ctx, cfg, int(nextPlayerIndex.Add(1)-1), nil, wg, semaphore)
+}
+
+// startSession tracks player id as active and runs its session. The
+// session releases its semaphore slot and calls wg.Done when it ends.
+func startSession(ctx context.Context, cfg *Config, id int, pc *pooledConn, wg *sync.WaitGroup, semaphore chan struct{}) {
+	wg.Add(1)
+	sessionsLaunched.Inc()
+	tracked := activeSessions.add(id, cfg.account(id).Username)
+	go managePlayerSession(ctx, cfg, tracked, pc, wg, semaphore)
+}
+
+// exitCode of a run. A single-player run reports how its session went and
+// prints the session result as a final JSON line; larger runs only fail
+// when no session registered at all.
+func exitCode(cfg *Config, launched int) int {
+	if sessions := results.all(); cfg.NumPlayers == 1 && len(sessions) == 1 {
+		line, _ := json.Marshal(sessions[0])
+		fmt.Println(string(line))
+		return outcomeExitCode(sessions[0].Outcome)
+	}
+	if launched > 0 && successfulRegistrations.Load() == 0 {
+		return exitFailed
+	}
+	return exitCompleted
+}
+
+// joinRate describes the cap -join-rate puts on the sessions launched.
+func (cfg *Config) joinRate() string {
+	if cfg.JoinRate <= 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%g sessions/s", cfg.JoinRate)
+}
+
+// dryRun prints what a real run would do and checks that the server accepts
+// a registration, using the credentials of the run's first player.
+func dryRun(cfg *Config) int {
+	fmt.Println("--- Dry run: play ---")
+	if cfg.Soak > 0 {
+		fmt.Printf("Would keep %d sessions active on %s until interrupted, rolling the artifacts over every %s\n", cfg.Soak, cfg.TCPServer, cfg.SoakRollover)
+	} else {
+		fmt.Printf("Would create %d players (%s .. %s) on %s\n", cfg.NumPlayers, cfg.account(0).Username, cfg.account(cfg.NumPlayers-1).Username, cfg.TCPServer)
+	}
+	fmt.Printf("Concurrency: %d sessions, rate: %s, game activity timeout: %s\n", cfg.MaxConcurrent, cfg.joinRate(), cfg.GameActivityTimeout)
+	fmt.Println("Checks:")
+	steps := preflight.TCPEndpoints(cfg.TCPServer, cfg.ConnectTimeout)
+	first := cfg.account(0)
+	steps = append(steps, preflight.Registration(cfg.TCPServer.First(), cfg.ConnectTimeout, cfg.RegisterTimeout, first.Username, first.Password))
+	ok := preflight.Run(os.Stdout, steps)
+	if !ok {
+		fmt.Println("Dry run FAILED.")
+		return 1
+	}
+	fmt.Println("Dry run passed.")
+	return 0
+}
+
+// runPlayers launches the player sessions and waits for them to finish. It
+// skips the usernames in completed, stops launching when ctx is cancelled,
+// and returns the number of sessions launched.
+func runPlayers(ctx context.Context, cfg *Config, completed map[string]bool) int {
+	fmt.Printf("--- TCP Player Creator & Game Player ---\n")
+	if cfg.Soak > 0 {
+		fmt.Printf("Soak: keeping %d sessions active until interrupted, rolling the artifacts over every %s.\n", cfg.Soak, cfg.SoakRollover)
+	} else if cfg.Park {
+		fmt.Printf("Park: keeping %d players connected without joining, with %s keepalives.\n", cfg.NumPlayers, cfg.Keepalive)
+	} else {
+		fmt.Printf("WARNING: This script will attempt to create %d players and have them play.\n", cfg.NumPlayers)
+	}
+	fmt.Printf("Target TCP Server: %s\n", cfg.TCPServer)
+	fmt.Printf("Concurrency Level: %d\n", cfg.MaxConcurrent)
+	fmt.Printf("Strategy: %s\n", cfg.Strategy)
+	fmt.Printf("Passwords: %s\n", cfg.Passwords.Describe())
+	if cfg.Shuffle {
+		fmt.Println("Player order: shuffled by the seed")
+	}
+	if cfg.Verbose && cfg.NumPlayers > 1 && cfg.LogDir == "" {
+		fmt.Println("Verbose logging is ON, but numPlayersToCreate > 1. Logs might be interleaved and hard to read.")
+		fmt.Println("Consider setting -players=1 when -verbose is true for easier debugging, or -log-dir.")
+	} else if cfg.Verbose && cfg.LogDir != "" {
+		fmt.Printf("Session logs: %s (at most %d files)\n", cfg.LogDir, cfg.LogDirMaxFiles)
+	}
+	cfg.ResolveSeed()
+	fmt.Println("Press Ctrl+C to interrupt.")
+	fmt.Println("-----------------------------------------")
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, cfg.MaxConcurrent)
+	startTime = time.Now()
+	stopProgress := printProgress(cfg.ProgressInterval)
+	defer stopProgress()
+	stopWatchdog := activeSessions.watch(cfg.ReapAfter)
+	defer stopWatchdog()
+
+	var deadline <-chan time.Time
+	if cfg.Duration > 0 {
+		deadline = cfg.clock.After(cfg.Duration)
+	}
+	var tick <-chan time.Time
+	if cfg.JoinRate > 0 {
+		ticker := cfg.clock.NewTicker(time.Duration(float64(time.Second) / cfg.JoinRate))
+		defer ticker.Stop()
+		tick = ticker.C()
+	}
+	next, skippedCount, closePool := playerSource(ctx, cfg, completed)
+	launched := 0
+	nextPlayerIndex.Store(int64(cfg.NumPlayers))
+	launchesStopped.Store(false)
+	stopLaunching(launchStopAllLaunched, false)
+launch:
+	for {
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+			case <-deadline:
+			}
+		}
+		guard.Wait(ctx)
+		// The deadline wins over a free slot, so no session starts late.
+		select {
+		case <-deadline:
+			stopLaunching(launchStopDuration, true)
+			break launch
+		default:
+		}
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			what := "Interrupted"
+			if errors.Is(context.Cause(ctx), errProtocolSuspect) {
+				what = "Protocol suspect"
+			}
+			fmt.Printf("\n%s: no new sessions will be started, closing the running ones...\n", what)
+			stopLaunching(launchStopInterrupted, true)
+			break launch
+		case <-deadline:
+			stopLaunching(launchStopDuration, true)
+			break launch
+		}
+		id, pc, ok := next()
+		if !ok {
+			<-semaphore
+			break
+		}
+		launched++
+		startSession(ctx, cfg, id, pc, &wg, semaphore)
+	}
+	closePool()
+	if launchStopReason == launchStopDuration {
+		fmt.Printf("\nDuration of %s reached: no new sessions will be started, waiting for the %d running ones...\n", cfg.Duration, inFlightAtStop)
+	}
+
+	if skipped := skippedCount(); skipped > 0 {
+		fmt.Printf("Skipped %d players already completed in a previous run.\n", skipped)
+	}
+	wg.Wait()
+	launchesStopped.Store(true)
+	close(semaphore)
+	return launched
+}
+
+// playerSource returns how runPlayers gets the players to launch: next
+// returns the next one, with its pooled connection if it has one, and false
+// once there are none left; skipped counts the completed players passed
+// over. closePool releases the warm-up pool, if cfg enables it.
+func playerSource(ctx context.Context, cfg *Config, completed map[string]bool) (next func() (int, *pooledConn, bool), skipped func() int64, closePool func()) {
+	order := playerOrder(cfg)
+	if cfg.PoolSize > 0 {
+		pool := newAccountPool(ctx, cfg, completed, order)
+		return pool.take, pool.skipped.Load, pool.close
+	}
+	var i int
+	var n int64
+	next = func() (int, *pooledConn, bool) {
+		for ; i < cfg.NumPlayers; i++ {
+			id := order(i)
+			if completed[cfg.account(id).Username] {
+				n++
+				continue
+			}
+			i++
+			return id, nil, true
+		}
+		return 0, nil, false
+	}
+	return next, func() int64 { return n }, func() {}
+}
+
+// playerOrder maps a launch position to the index of the player launched
+// there: the identity, or a permutation by the seed with -shuffle. Resuming
+// needs no more than that: completed players are skipped by username,
+// wherever they fall in the order.
+func playerOrder(cfg *Config) func(int) int {
+	if !cfg.Shuffle {
+		return func(i int) int { return i }
+	}
+	return rng.NewPermutation(cfg.Seed, cfg.NumPlayers).At
+}
+
+// Why runPlayers stopped launching sessions.
+const (
+	launchStopAllLaunched = "all players launched"
+	launchStopDuration    = "duration reached"
+	launchStopInterrupted = "interrupted"
+)
+
+// stopLaunching records why launching stopped. With final unset it only
+// records the default reason, used when the loop runs out of players.
+func stopLaunching(reason string, final bool) {
+	launchStopReason = reason
+	if final {
+		launchesStopped.Store(true)
+		inFlightAtStop = sessionsActive.Load()
+	}
+}
+
+// printProgress prints the rolling summary every interval until the
+// returned function is called.
+func printProgress(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fmt.Println(results.progress(time.Since(startTime)))
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// runDuration is how long the sessions ran, zero if none started.
+func runDuration() time.Duration {
+	if startTime.IsZero() {
+		return 0
+	}
+	return time.Since(startTime)
+}
+
+func printSummary(cfg *Config, launched int, elapsed time.Duration) {
+	fmt.Println("-----------------------------------------")
+	fmt.Println("All player session attempts completed.")
+	fmt.Printf("Duration: %s\n", elapsed)
+	fmt.Printf("Successful registrations: %d\n", successfulRegistrations.Load())
+	fmt.Printf("Failed registrations: %d\n", failedRegistrations.Load())
+	errclass.PrintCounts(os.Stdout, registrationFailures.Snapshot())
+	registrationRejections.Print(os.Stdout, "Registration rejections")
+	fmt.Printf("Registration latency: %s\n", registrationLatency.Summary())
+	pokerclient.DialLimit.Print(os.Stdout)
+	pokerclient.Decoding.Print(os.Stdout)
+	fmt.Printf("Games Joined by players: %d\n", gamesJoined.Load())
+	fmt.Printf("Time to seat: %s\n", timeToSeat.Summary())
+	fmt.Printf("Sessions never seated within %s: %d\n", cfg.SeatTimeout, sessionsNeverSeated.Load())
+	fmt.Printf("All-In Bets Made: %d\n", allInsMade.Load())
+	fmt.Printf("Other Bets Made: %d\n", betsMade.Load())
+	fmt.Printf("Folds Made: %d\n", foldsMade.Load())
+	if n := promptsMatchedLoosely.Load(); n > 0 {
+		fmt.Printf("Bet prompts matched despite a player_id differing from the username: %d\n", n)
+	}
+	fmt.Printf("Sessions that went quiet for over %s: %d\n", cfg.StallWarning, everStalled.Load())
+	if n := keepalivesSent.Load(); n > 0 {
+		fmt.Printf("Keepalives sent: %d (ignored %d, error replies %d, disconnects %d)\n", n,
+			keepalivesIgnored.Load(), keepaliveErrors.Load(), keepaliveDisconnects.Load())
+	}
+	printStageStats(os.Stdout)
+	rejections.print(os.Stdout)
+	if cfg.PoolSize > 0 {
+		fmt.Printf("Warm-up pool: hits %d, misses %d, stale %d, unused %d, failed registrations %d\n",
+			poolHits.Load(), poolMisses.Load(), poolStale.Load(), poolUnused.Load(), poolFailures.Load())
+		fmt.Printf("Pooled players' wait before joining: %s\n", poolIdle.Summary())
+	}
+	lifetimes.print(os.Stdout)
+	if n := betRetries.Load(); n > 0 {
+		fmt.Printf("Rejected bets retried: %d\n", n)
+	}
+	printConfirmations(os.Stdout, cfg.ConfirmWindow)
+	if cfg.Enrich {
+		fmt.Printf("Games enriched: %d, not enriched: %d\n", gamesEnriched.Load(), gamesNotFound.Load())
+	}
+	if games := gamesObserved.Load(); games > 0 {
+		events := eventsCaptured.Load()
+		fmt.Printf("Games observed: %d, events captured: %d (%.1f per game)\n", games, events, float64(events)/float64(games))
+	}
+	if cfg.Strategy == "exploit" {
+		fmt.Printf("Exploit shoves: %d, tight folds: %d\n", exploitShoves.Load(), exploitTightFolds.Load())
+	}
+	if cfg.Strategy == strategyFoldToAggression {
+		fmt.Printf("Folds to aggression: %d\n", aggressionFolds.Load())
+	}
+	if n := handHistoryDropped.Load(); n > 0 {
+		fmt.Printf("Moves left out of hand histories past %d per hand: %d\n", maxHandHistory, n)
+	}
+	known, inferred, unknown := positionSources[PositionKnown].Load(), positionSources[PositionInferred].Load(), positionSources[PositionUnknown].Load()
+	if known+inferred+unknown > 0 {
+		fmt.Printf("Position in the hands played: known %d, inferred %d, unknown %d\n", known, inferred, unknown)
+	}
+	printTurnTiming(os.Stdout)
+	parked.print(os.Stdout)
+	if a, b, c := chipsClamped.Load(), chipCorrections.Load(), invalidBetsPrevented.Load(); a+b+c > 0 {
+		fmt.Printf("Chip tracking: %d negative counts clamped, %d corrected from prompts, %d invalid bets prevented\n", a, b, c)
+	}
+	folds, calls, raises := opponentFolds.Load(), opponentCalls.Load(), opponentRaises.Load()
+	if n := folds + calls + raises; n > 0 {
+		fmt.Printf("Opponent moves observed: %d (fold %.1f%%, call %.1f%%, raise %.1f%%)\n", n,
+			100*float64(folds)/float64(n), 100*float64(calls)/float64(n), 100*float64(raises)/float64(n))
+	}
+	if n := playersBusted.Load(); n > 0 {
+		fmt.Printf("Players busted: %d (%.1f games survived on average)\n", n, float64(bustedGamesSurvived.Load())/float64(n))
+		if r := replacementsLaunched.Load(); r > 0 {
+			fmt.Printf("Replacement players started: %d\n", r)
+		}
+	}
+	if n := messagesWithUnknownKeys.Load(); n > 0 {
+		fmt.Printf("Messages with unknown fields: %d (%s)\n", n, strings.Join(unknownKeyNames(), ", "))
+	}
+	printStreamAnomalies(os.Stdout)
+	protocol.print(os.Stdout)
+	printStrategyEffectiveness(os.Stdout, effects.snapshot())
+	printGameDurations(os.Stdout, gameDurations.snapshot())
+	printMinimumBets(os.Stdout, minimumBets.snapshot())
+	if n := malformedPrompts.Load(); n > 0 {
+		fmt.Printf("Malformed bet prompts: %d (the first %d logged)\n", n, min(n, maxLoggedPrompts))
+	}
+	byEndpoint.Print(os.Stdout)
+	results.sink.Print(os.Stdout)
+	transcriptOut.Sink().Print(os.Stdout)
+	guard.PrintSummary(os.Stdout)
+	workerPanics.Print(os.Stdout)
+	resources.Print(os.Stdout)
+	if cfg.Waves != "" {
+		fmt.Printf("Total player sessions attempted: %d\n", launched)
+		printWaves(os.Stdout)
+	} else if cfg.Soak > 0 {
+		fmt.Printf("Total player sessions attempted: %d\n", launched)
+	} else {
+		fmt.Printf("Total player sessions attempted: %d of %d\n", launched, cfg.NumPlayers)
+	}
+	report.DeriveRates(registry.Snapshot().Counters, nil, cfg.RateSpan(elapsed, guard)).Print(os.Stdout, "sessions_launched", "successful_registrations", "bets", "all_ins")
+	printEstimateComparison(os.Stdout, runEstimate, elapsed)
+	if cfg.Duration > 0 {
+		attempted := sessionsLaunched.Load()
+		fmt.Printf("Stopped launching: %s. Sessions attempted: %d, completed: %d, still in flight at the stop: %d\n",
+			launchStopReason, attempted, attempted-inFlightAtStop, inFlightAtStop)
+	}
+}
+
+// fillReport records the run, with its rates measured over elapsed.
+func fillReport(rep *report.Report, elapsed time.Duration) {
+	registry.Snapshot().Fill(&rep.Section)
+	rejections.fill(rep)
+	lifetimes.fill(rep)
+	parked.fill(rep)
+	protocol.fill(rep)
+	fillWaves(rep)
+	rep.Counters["sessions_in_flight_at_stop"] = inFlightAtStop
+	rep.Details["launch_stop_reason"] = launchStopReason
+	if names := unknownKeyNames(); len(names) > 0 {
+		rep.Details["unknown_keys"] = strings.Join(names, ",")
+	}
+	rep.PlayerGames = playerGames.snapshot()
+	rep.StrategyEffectiveness = effects.snapshot()
+	rep.GameDurations = gameDurations.snapshot()
+	rep.MinimumBetByHand = minimumBets.snapshot()
+	rep.SetErrors(registrationFailures.Snapshot())
+	rep.Rejections = registrationRejections.Snapshot()
+	rep.Rates = report.DeriveRates(rep.Counters, rep.Errors, elapsed)
+	byEndpoint.Fill(rep)
+	results.sink.Fill(rep)
+	transcriptOut.Sink().Fill(rep)
+	guard.Fill(rep)
+	workerPanics.Fill(rep)
+	rep.Estimate = runEstimate
+	rep.Resources = resources.Snapshot()
+}
+
+// blockTotals are the counters the block detection judges.
+func blockTotals() blockdetect.Totals {
+	return blockdetect.Totals{Succeeded: successfulRegistrations.Load(), Failed: registrationFailures.Snapshot()}
+}
+
+// noteUnknownKeys counts a message carrying fields we do not bind and warns
+// the first time each such field is seen, so protocol drift shows up early.
+func noteUnknownKeys(resp *pokerclient.ServerResponse) {
+	if len(resp.Extra) == 0 {
+		return
+	}
+	messagesWithUnknownKeys.Inc()
+	for k := range resp.Extra {
+		if _, seen := unknownKeys.LoadOrStore(k, true); !seen {
+			slog.Warn("server sent an unknown field", "field", k, "type", resp.Type, "raw", string(resp.Raw))
+		}
+	}
+}
+
+// warnIDMismatch warns, once per run, that the server knows a player by an
+// ID other than its username, which an exact match would have ignored.
+func warnIDMismatch(username, playerID string) {
+	if idMismatchWarned.CompareAndSwap(false, true) {
+		slog.Warn("the server's player_id differs from the username; matching prompts ignoring case and spaces, and against the assigned ID", "username", username, "player_id", playerID)
+	}
+}
+
+func unknownKeyNames() []string {
+	var names []string
+	unknownKeys.Range(func(k, _ any) bool {
+		names = append(names, k.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// gameLog records the games each player was seated at. It is safe for
+// concurrent use.
+type gameLog struct {
+	mu    sync.Mutex
+	games map[string][]string
+}
+
+func (g *gameLog) add(player, gameID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.games == nil {
+		g.games = make(map[string][]string)
+	}
+	g.games[player] = append(g.games[player], gameID)
+}
+
+func (g *gameLog) snapshot() map[string][]string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make(map[string][]string, len(g.games))
+	for p, ids := range g.games {
+		out[p] = slices.Clone(ids)
+	}
+	return out
+}
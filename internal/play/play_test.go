@@ -0,0 +1,248 @@
+package play
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/clock"
+	"elastic-ai-jam-2025/internal/endpoint"
+	"elastic-ai-jam-2025/internal/mockserver"
+	"elastic-ai-jam-2025/internal/report"
+	"elastic-ai-jam-2025/internal/rng"
+)
+
+// silentServer accepts connections and never answers them. Its accepted
+// connections are sent on the returned channel.
+func silentServer(t *testing.T) (addr string, accepted <-chan net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	conns := make(chan net.Conn, 16)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { c.Close() })
+			conns <- c
+		}
+	}()
+	return ln.Addr().String(), conns
+}
+
+// TestLaunchStopConditions runs two sessions against a server that keeps
+// them registering, then ends the launches one way or another: the first
+// stop condition wins.
+func TestLaunchStopConditions(t *testing.T) {
+	tests := []struct {
+		name       string
+		players    int
+		stop       string // "duration", "interrupt" or "" to let the sessions end
+		wantReason string
+		// wantFlight is -1 when it races: an interrupt also closes the
+		// sessions' connections.
+		wantFlight int64
+	}{
+		{"duration reached first", 10, "duration", launchStopDuration, 2},
+		{"interrupted first", 10, "interrupt", launchStopInterrupted, -1},
+		{"players launched first", 2, "", launchStopAllLaunched, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, accepted := silentServer(t)
+			cfg := testConfig()
+			cfg.TCPServer = endpoint.MustParse(addr, cli.TCPAddr)
+			cfg.RegisterTimeout = time.Minute
+			cfg.NumPlayers = tt.players
+			cfg.MaxConcurrent = 2
+			cfg.Duration = time.Hour
+			cfg.ProgressInterval = 0
+			fake := clock.NewFake(time.Unix(1_700_000_000, 0))
+			cfg.clock = fake
+			inFlightAtStop = 0
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			launched := make(chan int, 1)
+			go func() { launched <- runPlayers(ctx, cfg, nil) }()
+
+			var conns []net.Conn
+			for len(conns) < 2 {
+				select {
+				case c := <-accepted:
+					conns = append(conns, c)
+				case <-time.After(5 * time.Second):
+					t.Fatalf("%d sessions connected, want 2", len(conns))
+				}
+			}
+			switch tt.stop {
+			case "duration":
+				fake.Advance(cfg.Duration)
+				for start := time.Now(); !launchesStopped.Load(); time.Sleep(time.Millisecond) {
+					if time.Since(start) > 5*time.Second {
+						t.Fatal("launching did not stop once the duration passed")
+					}
+				}
+			case "interrupt":
+				cancel()
+			}
+			// Ending the sessions frees their slots, and the launches
+			// go on if nothing stopped them.
+			for _, c := range conns {
+				c.Close()
+			}
+
+			select {
+			case n := <-launched:
+				if n != 2 {
+					t.Errorf("launched %d sessions, want 2", n)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("runPlayers did not return")
+			}
+			if launchStopReason != tt.wantReason {
+				t.Errorf("launch stop reason = %q, want %q", launchStopReason, tt.wantReason)
+			}
+			if tt.wantFlight >= 0 && inFlightAtStop != tt.wantFlight {
+				t.Errorf("sessions in flight at the stop = %d, want %d", inFlightAtStop, tt.wantFlight)
+			}
+		})
+	}
+}
+
+func TestRunIDPropagates(t *testing.T) {
+	srv := startMock(t, mockserver.Config{Bots: 1, Seed: 1})
+	dir := t.TempDir()
+	resultsOut := filepath.Join(dir, "results.ndjson")
+	reportOut := filepath.Join(dir, "report.json")
+	code := Run([]string{"-server", srv.Addr(), "-players", "2", "-run-id", "run-under-test",
+		"-results-out", resultsOut, "-report-out", reportOut, "-log-level", "error"})
+	if code != 0 {
+		t.Fatalf("play exited with %d", code)
+	}
+
+	rep, err := report.ReadFile(reportOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.RunID != "run-under-test" {
+		t.Errorf("report run ID = %q, want run-under-test", rep.RunID)
+	}
+	raw, err := os.ReadFile(resultsOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%d results, want 2", len(lines))
+	}
+	for _, line := range lines {
+		var r SessionResult
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.RunID != "run-under-test" {
+			t.Errorf("result of %s has run ID %q, want run-under-test", r.Player, r.RunID)
+		}
+	}
+}
+
+// readFile returns the content of path.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(raw)
+}
+
+// readResults returns the results recorded in path, in file order.
+func readResults(t *testing.T, path string) []SessionResult {
+	t.Helper()
+	var out []SessionResult
+	for _, line := range strings.Split(strings.TrimSpace(readFile(t, path)), "\n") {
+		var r SessionResult
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// TestShuffledResume interrupts a shuffled run after three of its players,
+// by truncating its results, and resumes it: the other players must be
+// played once each, in the shuffled order, under their original index.
+func TestShuffledResume(t *testing.T) {
+	const players = 8
+	srv := startMock(t, mockserver.Config{Bots: 1, Seed: 1})
+	resultsOut := filepath.Join(t.TempDir(), "results.ndjson")
+	args := []string{"-server", srv.Addr(), "-players", strconv.Itoa(players), "-concurrency", "1",
+		"-shuffle", "-seed", "7", "-results-out", resultsOut, "-log-level", "error"}
+	if code := Run(args); code != 0 {
+		t.Fatalf("play exited with %d", code)
+	}
+	perm := rng.NewPermutation(7, players)
+	var order []int
+	for i := range players {
+		order = append(order, perm.At(i))
+	}
+	first := readResults(t, resultsOut)
+	var got []int
+	for _, r := range first {
+		got = append(got, r.Index)
+		if r.Player != "over-"+strconv.Itoa(r.Index) {
+			t.Errorf("result of %s has index %d", r.Player, r.Index)
+		}
+	}
+	if !slices.Equal(got, order) {
+		t.Fatalf("played indexes %v, want the shuffled order %v", got, order)
+	}
+
+	// Keep the first three results, as a run stopped there would have.
+	lines := strings.SplitAfter(readFile(t, resultsOut), "\n")
+	if err := os.WriteFile(resultsOut, []byte(strings.Join(lines[:3], "")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code := Run(append(args, "-resume-results")); code != 0 {
+		t.Fatalf("resumed play exited with %d", code)
+	}
+	got = got[:0]
+	for _, r := range readResults(t, resultsOut) {
+		got = append(got, r.Index)
+	}
+	if !slices.Equal(got, order) {
+		t.Errorf("indexes after resuming %v, want each player once in the shuffled order %v", got, order)
+	}
+}
+
+func TestJoinRateDescription(t *testing.T) {
+	tests := []struct {
+		rate float64
+		want string
+	}{
+		{0, "unlimited"},
+		{5, "5 sessions/s"},
+		{0.5, "0.5 sessions/s"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{JoinRate: tt.rate}
+		if got := cfg.joinRate(); got != tt.want {
+			t.Errorf("-join-rate %g described as %q, want %q", tt.rate, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package play
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// pooledConn is a connection registered ahead of its session.
+type pooledConn struct {
+	id           int
+	conn         *pokerclient.Conn
+	dialedAt     time.Time
+	registeredAt time.Time
+}
+
+// accountPool registers players ahead of the launcher: it keeps up to
+// cfg.PoolSize connections registered but not yet joined, refilled by
+// cfg.PoolRefill workers, so the launcher can add table load faster than
+// registration alone allows.
+//
+// The pool hands out every player index below cfg.NumPlayers exactly once,
+// skipping the completed ones. When it is empty the launcher claims the next
+// index itself and the session registers inline (a miss).
+type accountPool struct {
+	cfg       *Config
+	completed map[string]bool
+	// order maps a claim to its player index; see playerOrder.
+	order func(int) int
+
+	// slots bounds the connections registered or being registered; ready
+	// holds the registered ones and is closed once the workers stop.
+	slots chan struct{}
+	ready chan *pooledConn
+	next  atomic.Int64
+	stop  context.CancelFunc
+
+	skipped atomic.Int64
+}
+
+// newAccountPool starts the refill workers. They stop when ctx is done,
+// every index was claimed or close is called.
+func newAccountPool(ctx context.Context, cfg *Config, completed map[string]bool, order func(int) int) *accountPool {
+	ctx, stop := context.WithCancel(ctx)
+	p := &accountPool{
+		stop:      stop,
+		cfg:       cfg,
+		completed: completed,
+		order:     order,
+		slots:     make(chan struct{}, cfg.PoolSize),
+		ready:     make(chan *pooledConn, cfg.PoolSize),
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.PoolRefill; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.refill(ctx)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(p.ready)
+	}()
+	return p
+}
+
+// claim returns the next index to play, or false once all were claimed.
+func (p *accountPool) claim() (int, bool) {
+	for {
+		pos := int(p.next.Add(1) - 1)
+		if pos >= p.cfg.NumPlayers {
+			return 0, false
+		}
+		id := p.order(pos)
+		if p.completed[p.cfg.account(id).Username] {
+			p.skipped.Add(1)
+			continue
+		}
+		return id, true
+	}
+}
+
+func (p *accountPool) refill(ctx context.Context) {
+	for {
+		guard.Wait(ctx)
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		id, ok := p.claim()
+		if !ok {
+			<-p.slots
+			return
+		}
+		pc := p.register(id)
+		if pc == nil {
+			<-p.slots
+			continue
+		}
+		p.ready <- pc
+	}
+}
+
+// register dials and registers player id, counting the registration like a
+// session would. It returns nil if that failed.
+func (p *accountPool) register(id int) *pooledConn {
+	account := p.cfg.account(id)
+	username := account.Username
+	defer workerPanics.Recover("pool registration of "+username, nil)
+	addr := p.cfg.TCPServer.For(id)
+	start := time.Now()
+	series.Started()
+	conn, err := pokerclient.Dial(addr, p.cfg.ConnectTimeout)
+	if err != nil {
+		recordRegistrationFailure(err, username, addr)
+		poolFailures.Inc()
+		slog.Debug("pool registration failed", "player", username, "error", err)
+		return nil
+	}
+	p.cfg.ApplyTimeouts(conn)
+	resp, err := conn.Register(username, account.Password)
+	if resp != nil {
+		noteUnknownKeys(resp)
+		transcriptOut.Write(username, "", resp.Raw)
+	}
+	protocol.registration(err)
+	if err != nil {
+		conn.Close()
+		recordRegistrationFailure(err, username, addr)
+		poolFailures.Inc()
+		slog.Debug("pool registration failed", "player", username, "error", err)
+		return nil
+	}
+	recordRegistration(time.Since(start)-conn.DialWait, addr)
+	return &pooledConn{id: id, conn: conn, dialedAt: start, registeredAt: time.Now()}
+}
+
+// take returns the next player to launch: a pooled connection when one is
+// ready (a hit), otherwise a claimed index with a nil connection (a miss).
+// A pooled connection idle for longer than cfg.PoolMaxIdle is closed and
+// its player registered again by the session. take returns false once
+// every player was handed out.
+func (p *accountPool) take() (id int, pc *pooledConn, ok bool) {
+	select {
+	case pc, ok := <-p.ready:
+		if ok {
+			return p.hit(pc)
+		}
+	default:
+	}
+	if id, ok := p.claim(); ok {
+		poolMisses.Inc()
+		return id, nil, true
+	}
+	// Every index is claimed; wait for those still being registered.
+	pc, ok = <-p.ready
+	if !ok {
+		return 0, nil, false
+	}
+	return p.hit(pc)
+}
+
+func (p *accountPool) hit(pc *pooledConn) (int, *pooledConn, bool) {
+	<-p.slots
+	idle := time.Since(pc.registeredAt)
+	if p.cfg.PoolMaxIdle > 0 && idle > p.cfg.PoolMaxIdle {
+		pc.conn.Close()
+		poolStale.Inc()
+		return pc.id, nil, true
+	}
+	poolHits.Inc()
+	poolIdle.Record(idle)
+	return pc.id, pc, true
+}
+
+// close stops the workers and closes the connections never handed out.
+func (p *accountPool) close() {
+	p.stop()
+	for pc := range p.ready {
+		pc.conn.Close()
+		<-p.slots
+		poolUnused.Inc()
+	}
+}
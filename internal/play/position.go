@@ -0,0 +1,181 @@
+package play
+
+import (
+	"encoding/json"
+	"slices"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// Sources of a Position.
+const (
+	// PositionKnown is a position the prompt gave: the player's seat and
+	// the dealer button's.
+	PositionKnown = "known"
+	// PositionInferred is a position inferred from the order the players
+	// acted in, once a hand of the game was seen to its end.
+	PositionInferred = "inferred"
+	// PositionUnknown is the position of the first hand of a game, whose
+	// table size is not known yet, or of a session not prompted yet.
+	PositionUnknown = "unknown"
+)
+
+// Position is where the session acts in the order of action of a hand.
+type Position struct {
+	Source string
+	// Index is the number of players acting before the session, and
+	// Players the players at the table, the session included.
+	Index   int
+	Players int
+	// Order is the order of action of the last hand seen to its end, the
+	// session included; nil until then.
+	Order []string
+}
+
+// Late reports whether the session acts in the last third of a known or
+// inferred order, and at least last but one.
+func (p Position) Late() bool {
+	if p.Source == PositionUnknown || p.Source == "" || p.Players < 2 {
+		return false
+	}
+	return p.Index >= p.Players-max(1, p.Players/3)
+}
+
+// PositionTracker finds the session's position in each hand. The protocol
+// has no seats: a prompt carrying state.player.seat, state.dealer (or
+// state.button) and state.players gives it outright, as a future server
+// might; otherwise the players acting before the session's first prompt of
+// a hand, in moves and prompts, give its index, and the players seen in the
+// game the table size. A hand ends when a pot is won; a game over or a new
+// game starts over.
+type PositionTracker struct {
+	self   string
+	gameID string
+	// seen are the players of the game, acted the players of the current
+	// hand in order of action, and handsSeen the hands of the game seen
+	// to their end.
+	seen      map[string]bool
+	acted     []string
+	order     []string
+	handsSeen int
+	// cur is the position in the current hand, set at the session's first
+	// prompt in it.
+	cur      Position
+	prompted bool
+}
+
+// NewPositionTracker returns a tracker of the positions of the player self.
+func NewPositionTracker(self string) *PositionTracker {
+	return &PositionTracker{self: self, seen: make(map[string]bool)}
+}
+
+// Position returns the session's position in the current hand; its Source
+// is PositionUnknown before the session's first prompt in it.
+func (t *PositionTracker) Position() Position {
+	if !t.prompted {
+		return Position{Source: PositionUnknown, Order: t.order}
+	}
+	return t.cur
+}
+
+// Observe updates the tracker with a server message.
+func (t *PositionTracker) Observe(resp *pokerclient.ServerResponse) {
+	if resp.GameID != "" && resp.GameID != t.gameID {
+		t.reset()
+		t.gameID = resp.GameID
+	}
+	switch resp.Type {
+	case pokerclient.TypeGameOver:
+		t.reset()
+	case pokerclient.TypePotWon:
+		if len(t.acted) > 0 {
+			t.order, t.handsSeen = t.acted, t.handsSeen+1
+		}
+		t.acted, t.prompted, t.cur = nil, false, Position{}
+	case pokerclient.TypeActionPlayerBet:
+		player := resp.State.Player.PlayerID
+		if player == "" {
+			return
+		}
+		if pokerclient.SamePlayer(player, t.self) && !t.prompted {
+			t.prompted = true
+			t.cur = t.locate(resp)
+			positionSources[t.cur.Source].Inc()
+		}
+		t.act(player)
+	default:
+		if a, ok := pokerclient.PlayerActionOf(resp); ok {
+			t.act(a.PlayerID)
+		}
+	}
+}
+
+// act records that player acted in the current hand. The session's own
+// moves are recorded under self, however the server spells it.
+func (t *PositionTracker) act(player string) {
+	if pokerclient.SamePlayer(player, t.self) {
+		player = t.self
+	}
+	t.seen[player] = true
+	if !slices.Contains(t.acted, player) {
+		t.acted = append(t.acted, player)
+	}
+}
+
+// locate returns the position of the session's first prompt in a hand.
+func (t *PositionTracker) locate(resp *pokerclient.ServerResponse) Position {
+	if p, ok := seatPosition(resp.Raw); ok {
+		p.Order = t.order
+		return p
+	}
+	before := 0
+	for _, player := range t.acted {
+		if player != t.self {
+			before++
+		}
+	}
+	players := len(t.seen)
+	if !t.seen[t.self] {
+		players++
+	}
+	p := Position{Source: PositionInferred, Index: before, Players: max(players, before+1), Order: t.order}
+	if t.handsSeen == 0 {
+		p.Source = PositionUnknown
+	}
+	return p
+}
+
+// reset forgets the game.
+func (t *PositionTracker) reset() {
+	t.gameID = ""
+	clear(t.seen)
+	t.acted, t.order, t.handsSeen = nil, nil, 0
+	t.prompted, t.cur = false, Position{}
+}
+
+// seatPosition reads a position from the seats of a prompt, if it has
+// them. The seat after the button acts first, and the button last.
+func seatPosition(raw json.RawMessage) (Position, bool) {
+	var p struct {
+		State struct {
+			Player struct {
+				Seat *int `json:"seat"`
+			} `json:"player"`
+			Dealer  *int              `json:"dealer"`
+			Button  *int              `json:"button"`
+			Players []json.RawMessage `json:"players"`
+		} `json:"state"`
+	}
+	if json.Unmarshal(raw, &p) != nil {
+		return Position{}, false
+	}
+	button, n := p.State.Dealer, len(p.State.Players)
+	if button == nil {
+		button = p.State.Button
+	}
+	seat := p.State.Player.Seat
+	if seat == nil || button == nil || n < 2 || *seat < 0 || *seat >= n {
+		return Position{}, false
+	}
+	return Position{Source: PositionKnown, Index: ((*seat-*button-1)%n + n) % n, Players: n}, true
+}
@@ -0,0 +1,201 @@
+package play
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// recordedHands are two games as the server sent them to "me": a table of
+// three whose first hand sets the order of action, and a second game
+// starting over.
+const recordedHands = `
+{"type":"action_player_bet","game_id":"g-1","minimum_bet":10,"state":{"player":{"player_id":"a","chips":1000}}}
+{"type":"event_player_action","game_id":"g-1","event":{"player_id":"a","action":"bet","amount":10}}
+{"type":"action_player_bet","game_id":"g-1","minimum_bet":10,"state":{"player":{"player_id":"me","chips":1000}}}
+{"type":"event_player_action","game_id":"g-1","event":{"player_id":"me","action":"bet","amount":10}}
+{"type":"action_player_bet","game_id":"g-1","minimum_bet":10,"state":{"player":{"player_id":"b","chips":1000}}}
+{"type":"event_player_action","game_id":"g-1","event":{"player_id":"b","action":"bet","amount":10}}
+{"type":"action_player_bet","game_id":"g-1","minimum_bet":0,"state":{"player":{"player_id":"me","chips":990}}}
+{"type":"event_pot_won","game_id":"g-1","event":{"player_id":"b","amount":30}}
+{"type":"action_player_bet","game_id":"g-1","minimum_bet":10,"state":{"player":{"player_id":"b","chips":1020}}}
+{"type":"event_player_action","game_id":"g-1","event":{"player_id":"b","action":"bet","amount":10}}
+{"type":"action_player_bet","game_id":"g-1","minimum_bet":10,"state":{"player":{"player_id":"a","chips":990}}}
+{"type":"event_player_action","game_id":"g-1","event":{"player_id":"a","action":"fold","amount":0}}
+{"type":"action_player_bet","game_id":"g-1","minimum_bet":10,"state":{"player":{"player_id":"Me","chips":990}}}
+{"type":"event_player_action","game_id":"g-1","event":{"player_id":"me","action":"all_in","amount":990}}
+{"type":"event_pot_won","game_id":"g-1","event":{"player_id":"me","amount":1000}}
+{"type":"action_player_bet","game_id":"g-1","minimum_bet":10,"state":{"player":{"player_id":"me","chips":2000}}}
+{"type":"event_game_over","game_id":"g-1","event":{"players":[{"player_id":"me","chips":2000}]}}
+{"type":"action_player_bet","game_id":"g-2","minimum_bet":10,"state":{"player":{"player_id":"c","chips":1000}}}
+{"type":"action_player_bet","game_id":"g-2","minimum_bet":10,"state":{"player":{"player_id":"me","chips":1000}}}
+{"type":"action_player_bet","game_id":"g-2","minimum_bet":10,"state":{"player":{"player_id":"me","chips":990},"dealer":2,"players":[{},{},{},{}]}}
+{"type":"action_player_bet","game_id":"g-2","minimum_bet":10,"state":{"player":{"player_id":"me","chips":990,"seat":0},"dealer":2,"players":[{},{},{},{}]}}
+`
+
+// replayPositions feeds a transcript to a tracker of "me" and returns its
+// position after each message.
+func replayPositions(t *testing.T, transcript string) []Position {
+	t.Helper()
+	tr := NewPositionTracker("me")
+	var out []Position
+	for i, line := range strings.Split(strings.TrimSpace(transcript), "\n") {
+		var resp pokerclient.ServerResponse
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		resp.Raw = json.RawMessage(line)
+		tr.Observe(&resp)
+		out = append(out, tr.Position())
+	}
+	return out
+}
+
+func TestPositionInferred(t *testing.T) {
+	order := []string{"a", "me", "b"}
+	unknown := Position{Source: PositionUnknown}
+	before := map[string]int64{}
+	for source, c := range positionSources {
+		before[source] = c.Load()
+	}
+	got := replayPositions(t, recordedHands)
+	want := []Position{
+		unknown,
+		unknown,
+		// The first hand of a game: a's move puts the session second of
+		// the two players seen so far.
+		{Source: PositionUnknown, Index: 1, Players: 2},
+		{Source: PositionUnknown, Index: 1, Players: 2},
+		{Source: PositionUnknown, Index: 1, Players: 2},
+		{Source: PositionUnknown, Index: 1, Players: 2},
+		// A later prompt in the same hand keeps the position.
+		{Source: PositionUnknown, Index: 1, Players: 2},
+		// The pot won sets the order of action.
+		{Source: PositionUnknown, Order: order},
+		{Source: PositionUnknown, Order: order},
+		{Source: PositionUnknown, Order: order},
+		{Source: PositionUnknown, Order: order},
+		{Source: PositionUnknown, Order: order},
+		// Prompted last, under another spelling of its name.
+		{Source: PositionInferred, Index: 2, Players: 3, Order: order},
+		{Source: PositionInferred, Index: 2, Players: 3, Order: order},
+		{Source: PositionUnknown, Order: []string{"b", "a", "me"}},
+		// Prompted first.
+		{Source: PositionInferred, Index: 0, Players: 3, Order: []string{"b", "a", "me"}},
+		// The game over starts over.
+		unknown,
+		unknown,
+		{Source: PositionUnknown, Index: 1, Players: 2},
+		// Seats in a later prompt of the hand do not change its position.
+		{Source: PositionUnknown, Index: 1, Players: 2},
+		{Source: PositionUnknown, Index: 1, Players: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%d positions, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("after message %d: position %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	wantCounts := map[string]int64{PositionKnown: 0, PositionInferred: 2, PositionUnknown: 2}
+	for source, n := range wantCounts {
+		if got := positionSources[source].Load() - before[source]; got != n {
+			t.Errorf("%s positions counted %d, want %d", source, got, n)
+		}
+	}
+}
+
+func TestSeatPosition(t *testing.T) {
+	tests := []struct {
+		name   string
+		state  string
+		want   Position
+		wantOK bool
+	}{
+		{"first after the button", `{"player":{"seat":3},"dealer":2,"players":[{},{},{},{}]}`, Position{Source: PositionKnown, Index: 0, Players: 4}, true},
+		{"on the button", `{"player":{"seat":2},"dealer":2,"players":[{},{},{},{}]}`, Position{Source: PositionKnown, Index: 3, Players: 4}, true},
+		{"wrapping around", `{"player":{"seat":0},"dealer":2,"players":[{},{},{},{}]}`, Position{Source: PositionKnown, Index: 1, Players: 4}, true},
+		{"button instead of dealer", `{"player":{"seat":1},"button":0,"players":[{},{}]}`, Position{Source: PositionKnown, Index: 0, Players: 2}, true},
+		{"seat zero with dealer zero", `{"player":{"seat":0},"dealer":0,"players":[{},{},{}]}`, Position{Source: PositionKnown, Index: 2, Players: 3}, true},
+		{"no seat", `{"player":{},"dealer":0,"players":[{},{}]}`, Position{}, false},
+		{"no button", `{"player":{"seat":0},"players":[{},{}]}`, Position{}, false},
+		{"seat off the table", `{"player":{"seat":2},"dealer":0,"players":[{},{}]}`, Position{}, false},
+		{"alone at the table", `{"player":{"seat":0},"dealer":0,"players":[{}]}`, Position{}, false},
+		{"mistyped seat", `{"player":{"seat":"1"},"dealer":0,"players":[{},{}]}`, Position{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := seatPosition(json.RawMessage(`{"type":"action_player_bet","state":` + tt.state + `}`))
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("seatPosition = %+v, %v; want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPositionKnownFromSeats(t *testing.T) {
+	tr := NewPositionTracker("me")
+	line := `{"type":"action_player_bet","game_id":"g-1","minimum_bet":10,"state":{"player":{"player_id":"me","chips":1000,"seat":1},"dealer":0,"players":[{},{},{}]}}`
+	var resp pokerclient.ServerResponse
+	if err := json.Unmarshal([]byte(line), &resp); err != nil {
+		t.Fatal(err)
+	}
+	resp.Raw = json.RawMessage(line)
+	tr.Observe(moveEvent("g-1", "a", "call"))
+	tr.Observe(&resp)
+	if got, want := tr.Position(), (Position{Source: PositionKnown, Index: 0, Players: 3}); !reflect.DeepEqual(got, want) {
+		t.Errorf("position = %+v, want %+v: the seats win over the order seen", got, want)
+	}
+}
+
+func TestPositionLate(t *testing.T) {
+	tests := []struct {
+		p    Position
+		want bool
+	}{
+		{Position{Source: PositionInferred, Index: 1, Players: 2}, true},
+		{Position{Source: PositionInferred, Index: 0, Players: 2}, false},
+		{Position{Source: PositionKnown, Index: 4, Players: 6}, true},
+		{Position{Source: PositionKnown, Index: 3, Players: 6}, false},
+		{Position{Source: PositionKnown, Index: 6, Players: 9}, true},
+		{Position{Source: PositionKnown, Index: 5, Players: 9}, false},
+		{Position{Source: PositionUnknown, Index: 1, Players: 2}, false},
+		{Position{Source: PositionKnown, Index: 0, Players: 1}, false},
+		{Position{}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Late(); got != tt.want {
+			t.Errorf("%+v.Late() = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestPositional(t *testing.T) {
+	late := Position{Source: PositionInferred, Index: 2, Players: 3}
+	early := Position{Source: PositionInferred, Index: 0, Players: 3}
+	tests := []struct {
+		name     string
+		position Position
+		chips    int
+		minimum  int
+		want     string
+	}{
+		{"late shoves", late, 500, 10, "all-in 500"},
+		{"late without chips", late, 0, 0, "check"},
+		{"early checks for free", early, 500, 0, "check"},
+		{"early folds", early, 500, 10, "fold"},
+		{"unknown folds", Position{Source: PositionUnknown, Index: 2, Players: 3}, 500, 10, "fold"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := positional{}.Bet(Turn{Chips: tt.chips, MinimumBet: tt.minimum, Position: tt.position})
+			if m.String() != tt.want {
+				t.Errorf("move = %s, want %s", m, tt.want)
+			}
+		})
+	}
+}
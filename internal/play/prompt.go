@@ -0,0 +1,43 @@
+package play
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// Bounds on the malformed prompts logged, so a server sending nothing else
+// does not flood the log.
+const (
+	maxLoggedPrompts   = 20
+	maxLoggedPromptLen = 1024
+)
+
+// malformedPrompt counts a bet prompt the session cannot act on, and logs
+// it raw if it is among the first maxLoggedPrompts of the run.
+func (ps *PlayerSessionState) malformedPrompt(resp *pokerclient.ServerResponse, problem string) {
+	malformedPrompts.Inc()
+	ps.logVerbose("Ignoring malformed bet prompt (%s): %s", problem, resp.Raw)
+	if loggedPrompts.Add(1) > maxLoggedPrompts {
+		return
+	}
+	raw := string(resp.Raw)
+	if len(raw) > maxLoggedPromptLen {
+		raw = raw[:maxLoggedPromptLen] + "..."
+	}
+	slog.Warn("malformed bet prompt", "player", ps.username, "game_id", ps.gameID, "problem", problem, "raw", raw)
+}
+
+// promptHasChips reports whether the prompt raw states the player's chips,
+// as opposed to leaving them out, which decodes to zero as well.
+func promptHasChips(raw []byte) bool {
+	var prompt struct {
+		State struct {
+			Player struct {
+				Chips *json.RawMessage `json:"chips"`
+			} `json:"player"`
+		} `json:"state"`
+	}
+	return json.Unmarshal(raw, &prompt) == nil && prompt.State.Player.Chips != nil
+}
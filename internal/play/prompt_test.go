@@ -0,0 +1,107 @@
+package play
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// scriptedServer accepts one connection, answers its registration and
+// join, then sends lines and closes. It returns the amounts of the bets
+// the client sent, read until the client closed the connection.
+func scriptedServer(t *testing.T, lines ...string) (addr string, bets <-chan []int) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	out := make(chan []int, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		sc := bufio.NewScanner(conn)
+		sc.Scan() // registration
+		conn.Write([]byte(`{"type":"` + pokerclient.TypeLeaderboardEntryStart + `"}` + "\n"))
+		sc.Scan() // join
+		for _, l := range lines {
+			conn.Write([]byte(l + "\n"))
+		}
+		got := []int{}
+		for sc.Scan() {
+			var msg pokerclient.ActionMsg
+			if json.Unmarshal(sc.Bytes(), &msg) == nil && msg.Action == pokerclient.ActionBet && msg.Amount != nil {
+				got = append(got, *msg.Amount)
+			}
+		}
+		out <- got
+	}()
+	return ln.Addr().String(), out
+}
+
+func TestMalformedPrompts(t *testing.T) {
+	const gameOver = `{"type":"event_game_over","game_id":"g1","event":{}}`
+	tests := []struct {
+		name      string
+		prompt    string
+		bets      []int
+		malformed int64
+	}{
+		{"no state", `{"type":"action_player_bet","game_id":"g1","stage":"pre_flop","minimum_bet":10}`, []int{}, 1},
+		{"zero-value state", `{"type":"action_player_bet","game_id":"g1","stage":"pre_flop","state":{"player":{}},"minimum_bet":10}`, []int{}, 1},
+		{"no player_id", `{"type":"action_player_bet","game_id":"g1","stage":"pre_flop","state":{"player":{"chips":1000}},"minimum_bet":10}`, []int{}, 1},
+		{"no chips, a bet to cover", `{"type":"action_player_bet","game_id":"g1","stage":"pre_flop","state":{"player":{"player_id":"me"}},"minimum_bet":10}`, []int{-1}, 1},
+		{"no chips, nothing to cover", `{"type":"action_player_bet","game_id":"g1","stage":"flop","state":{"player":{"player_id":"me"}}}`, []int{}, 1},
+		{"zero chips", `{"type":"action_player_bet","game_id":"g1","stage":"pre_flop","state":{"player":{"player_id":"me","chips":0}},"minimum_bet":10}`, []int{-1}, 0},
+		{"someone else's", `{"type":"action_player_bet","game_id":"g1","stage":"pre_flop","state":{"player":{"player_id":"other","chips":1000}},"minimum_bet":10}`, []int{}, 0},
+		{"well formed", `{"type":"action_player_bet","game_id":"g1","stage":"pre_flop","state":{"player":{"player_id":"me","chips":1000}},"minimum_bet":10}`, []int{10}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, bets := scriptedServer(t, tt.prompt, gameOver)
+			before := malformedPrompts.Load()
+			ps := playSession(t, testConfig(), addr, "me", minBet{})
+			ps.conn.Close()
+			if got := <-bets; !reflect.DeepEqual(got, tt.bets) {
+				t.Errorf("bets sent %v, want %v", got, tt.bets)
+			}
+			if got := malformedPrompts.Load() - before; got != tt.malformed {
+				t.Errorf("%d malformed prompts counted, want %d", got, tt.malformed)
+			}
+		})
+	}
+}
+
+// TestFoldToAggressionHands plays scripted hands, an opponent raising big
+// in the first, and checks the strategy folds that one alone.
+func TestFoldToAggressionHands(t *testing.T) {
+	const prompt = `{"type":"action_player_bet","game_id":"g1","stage":"pre_flop","state":{"player":{"player_id":"me","chips":1000}},"minimum_bet":10}`
+	move := func(player, action string, amount int) string {
+		return fmt.Sprintf(`{"type":"event_player_action","game_id":"g1","event":{"player_id":%q,"action":%q,"amount":%d}}`, player, action, amount)
+	}
+	won := func(player string) string {
+		return fmt.Sprintf(`{"type":"event_pot_won","game_id":"g1","event":{"player_id":%q,"amount":100}}`, player)
+	}
+	addr, bets := scriptedServer(t,
+		// An opponent raised 300 of our 1000 chips: fold.
+		move("a", "bet", 10), move("b", "raise", 300), prompt, won("b"),
+		// The raise is forgotten with the hand: call.
+		move("a", "bet", 10), move("b", "call", 10), prompt, won("a"),
+		// Our own chips in the pot do not count: call.
+		move("me", "bet", 900), move("a", "call", 200), prompt, won("me"),
+		`{"type":"event_game_over","game_id":"g1","event":{}}`,
+	)
+	ps := playSession(t, testConfig(), addr, "me", foldToAggression{share: 0.25})
+	ps.conn.Close()
+	if got, want := <-bets, []int{-1, 10, 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("bets sent %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,149 @@
+package play
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"sync"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// errProtocolSuspect is the cause of a run -protocol-abort ended.
+var errProtocolSuspect = errors.New("protocol suspect")
+
+// protocolCheck watches the first bet prompts and registration replies of a
+// run for signs that the server changed its protocol under us: prompts
+// whose player ID no longer decodes, and successful registrations answered
+// with another message type than pokerclient.TypeLeaderboardEntryStart.
+// After the server renamed fields once, the bots folded every hand for an
+// hour without a single error; this makes that loud within seconds.
+//
+// Each signal is judged over its first window samples: it turns suspect as
+// soon as more of them missed than minShare allows, so a broken protocol
+// is caught at the first few misses rather than at the end of the window.
+// Rejected and failed registrations say nothing about the protocol and are
+// not judged. A nil *protocolCheck checks nothing. It is safe for
+// concurrent use.
+type protocolCheck struct {
+	window   int
+	minShare float64
+	// maxMissed is how many samples of a window may miss, rounded so
+	// that, say, 0.9 of 20 allows 2 despite floating point.
+	maxMissed int
+	// onSuspect, when set, is called once, when the run turns suspect.
+	onSuspect func(reason string)
+
+	mu            sync.Mutex
+	prompts       protocolSignal
+	registrations protocolSignal
+	// reason is why the run is suspect, empty while it is not.
+	reason string
+}
+
+// protocolSignal counts the samples of a signal judged, and those that
+// missed.
+type protocolSignal struct {
+	judged, missed int
+}
+
+// protocol checks the current run; see Run.
+var protocol *protocolCheck
+
+// newProtocolCheck returns a check over the first window samples of each
+// signal, or nil when window is not positive.
+func newProtocolCheck(window int, minShare float64, onSuspect func(string)) *protocolCheck {
+	if window <= 0 {
+		return nil
+	}
+	maxMissed := int(math.Floor((1-minShare)*float64(window) + 1e-9))
+	return &protocolCheck{window: window, minShare: minShare, maxMissed: maxMissed, onSuspect: onSuspect}
+}
+
+// prompt judges a bet prompt, to anyone.
+func (c *protocolCheck) prompt(resp *pokerclient.ServerResponse) {
+	if c == nil {
+		return
+	}
+	c.judge(&c.prompts, resp.State.Player.PlayerID != "", "bet prompts with a player_id")
+}
+
+// registration judges a registration that ended with err.
+func (c *protocolCheck) registration(err error) {
+	if c == nil {
+		return
+	}
+	var regErr *pokerclient.RegistrationError
+	switch {
+	case err == nil:
+		c.judge(&c.registrations, true, "")
+	case errors.As(err, &regErr) && regErr.Code == 0:
+		c.judge(&c.registrations, false, fmt.Sprintf("registration replies of type %s (last: %q)", pokerclient.TypeLeaderboardEntryStart, regErr.Type))
+	}
+}
+
+// judge adds a sample of s, which hit or missed; what describes the hits,
+// for the warning.
+func (c *protocolCheck) judge(s *protocolSignal, hit bool, what string) {
+	c.mu.Lock()
+	if s.judged == c.window {
+		c.mu.Unlock()
+		return
+	}
+	s.judged++
+	if hit {
+		c.mu.Unlock()
+		return
+	}
+	s.missed++
+	if c.reason != "" || s.missed <= c.maxMissed {
+		c.mu.Unlock()
+		return
+	}
+	c.reason = fmt.Sprintf("only %d of the first %d %s", s.judged-s.missed, s.judged, what)
+	reason := c.reason
+	c.mu.Unlock()
+
+	slog.Error("the server protocol may have changed", "reason", reason)
+	fmt.Fprintf(os.Stderr, "\n*** WARNING: PROTOCOL SUSPECT: %s, below %.0f%%. The server may have changed its protocol; check a transcript before trusting this run. ***\n\n", reason, 100*c.minShare)
+	if c.onSuspect != nil {
+		c.onSuspect(reason)
+	}
+}
+
+// suspect returns why the run is protocol-suspect, or "" if it is not.
+func (c *protocolCheck) suspect() string {
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reason
+}
+
+// print writes the verdict, if the run is suspect.
+func (c *protocolCheck) print(w io.Writer) {
+	if reason := c.suspect(); reason != "" {
+		fmt.Fprintf(w, "PROTOCOL SUSPECT: %s\n", reason)
+	}
+}
+
+// fill marks a suspect run in rep, with the samples judged.
+func (c *protocolCheck) fill(rep *report.Report) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rep.Counters["protocol_prompts_judged"] = int64(c.prompts.judged)
+	rep.Counters["protocol_prompts_missed"] = int64(c.prompts.missed)
+	rep.Counters["protocol_registrations_judged"] = int64(c.registrations.judged)
+	rep.Counters["protocol_registrations_missed"] = int64(c.registrations.missed)
+	if c.reason != "" {
+		rep.Details["protocol_suspect"] = c.reason
+	}
+}
@@ -0,0 +1,137 @@
+package play
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// feedProtocol feeds c a mix of samples, one per letter: p a bet prompt
+// with a player ID and P one without; r a registration that succeeded, R
+// one answered with a renamed type, x one the server rejected and e one
+// that failed on the connection.
+func feedProtocol(t *testing.T, c *protocolCheck, mix string) {
+	t.Helper()
+	for _, s := range mix {
+		switch s {
+		case 'p', 'P':
+			resp := betPrompt("g1", "me", 1000)
+			if s == 'P' {
+				resp.State.Player.PlayerID = ""
+			}
+			c.prompt(resp)
+		case 'r':
+			c.registration(nil)
+		case 'R':
+			c.registration(&pokerclient.RegistrationError{Type: "event_player_registered"})
+		case 'x':
+			c.registration(&pokerclient.RegistrationError{Type: "error", Code: 409, Message: "already registered"})
+		case 'e':
+			c.registration(io.ErrUnexpectedEOF)
+		default:
+			t.Fatalf("unknown sample %q", s)
+		}
+	}
+}
+
+func TestProtocolCheck(t *testing.T) {
+	renamed := `registration replies of type event_player_leaderboard_entry_start (last: "event_player_registered")`
+	tests := []struct {
+		name     string
+		window   int
+		minShare float64
+		mix      string
+		// want is the reason the run is suspect, "" if it is not.
+		want                         string
+		promptsJudged, promptsMissed int64
+		regsJudged, regsMissed       int64
+	}{
+		{"all as expected", 20, 0.9, strings.Repeat("pr", 30), "", 20, 0, 20, 0},
+		{"misses within the share", 20, 0.9, "PP" + strings.Repeat("p", 18), "", 20, 2, 0, 0},
+		{"prompts without a player ID", 20, 0.9, "pPPP" + strings.Repeat("p", 20), "only 1 of the first 4 bet prompts with a player_id", 20, 3, 0, 0},
+		{"misses after the window", 20, 0.9, strings.Repeat("p", 20) + strings.Repeat("P", 20), "", 20, 0, 0, 0},
+		{"renamed registration replies", 20, 0.9, "RRR", "only 0 of the first 3 " + renamed, 0, 0, 3, 3},
+		{"rejections and failures not judged", 20, 0.9, "xxxxeeeerrR", "", 0, 0, 3, 1},
+		{"signals judged apart", 20, 0.9, "PPRR" + strings.Repeat("pr", 18), "", 20, 2, 20, 2},
+		{"every sample required", 10, 1, "pppP", "only 3 of the first 4 bet prompts with a player_id", 4, 1, 0, 0},
+		{"a short window", 5, 0.5, "PpPP", "only 1 of the first 4 bet prompts with a player_id", 4, 3, 0, 0},
+		{"the first signal to miss wins", 20, 0.9, "RRRPPP", "only 0 of the first 3 " + renamed, 3, 3, 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reasons []string
+			c := newProtocolCheck(tt.window, tt.minShare, func(reason string) { reasons = append(reasons, reason) })
+			feedProtocol(t, c, tt.mix)
+			if got := c.suspect(); got != tt.want {
+				t.Errorf("suspect = %q, want %q", got, tt.want)
+			}
+			if tt.want == "" && len(reasons) != 0 || tt.want != "" && len(reasons) != 1 {
+				t.Errorf("onSuspect called with %q", reasons)
+			}
+			rep := report.New("play", nil)
+			c.fill(rep)
+			for name, want := range map[string]int64{
+				"protocol_prompts_judged":       tt.promptsJudged,
+				"protocol_prompts_missed":       tt.promptsMissed,
+				"protocol_registrations_judged": tt.regsJudged,
+				"protocol_registrations_missed": tt.regsMissed,
+			} {
+				if got := rep.Counters[name]; got != want {
+					t.Errorf("%s = %d, want %d", name, got, want)
+				}
+			}
+			if got := rep.Details["protocol_suspect"]; got != tt.want {
+				t.Errorf("protocol_suspect detail = %q, want %q", got, tt.want)
+			}
+			var b strings.Builder
+			c.print(&b)
+			if printed := b.Len() > 0; printed != (tt.want != "") {
+				t.Errorf("printed %q", b.String())
+			}
+		})
+	}
+}
+
+func TestProtocolCheckDisabled(t *testing.T) {
+	c := newProtocolCheck(0, 0.9, func(string) { t.Error("a disabled check turned suspect") })
+	if c != nil {
+		t.Fatal("a window of 0 checks")
+	}
+	feedProtocol(t, c, "PPPRRR")
+	var b strings.Builder
+	c.print(&b)
+	rep := report.New("play", nil)
+	c.fill(rep)
+	if c.suspect() != "" || b.Len() != 0 || len(rep.Counters) != 0 || len(rep.Details) != 0 {
+		t.Errorf("a disabled check reported %q, %v, %v", b.String(), rep.Counters, rep.Details)
+	}
+}
+
+// TestProtocolCheckInSession plays a session against a server whose
+// prompts lost their player ID, and checks the run turns suspect, as
+// -protocol-abort needs, while registration still looks as expected.
+func TestProtocolCheckInSession(t *testing.T) {
+	const prompt = `{"type":"action_player_bet","game_id":"g1","stage":"pre_flop","state":{"player":{"chips":1000}},"minimum_bet":10}`
+	addr, bets := scriptedServer(t, prompt, prompt, prompt, `{"type":"event_game_over","game_id":"g1","event":{}}`)
+	saved := protocol
+	defer func() { protocol = saved }()
+	var reasons []string
+	protocol = newProtocolCheck(3, 0.9, func(reason string) { reasons = append(reasons, reason) })
+	ps := playSession(t, testConfig(), addr, "me", minBet{})
+	ps.conn.Close()
+	if got := <-bets; len(got) != 0 {
+		t.Errorf("bets sent %v to prompts for nobody", got)
+	}
+	want := "only 0 of the first 1 bet prompts with a player_id"
+	if len(reasons) != 1 || reasons[0] != want {
+		t.Errorf("turned suspect for %q, want once for %q", reasons, want)
+	}
+	rep := report.New("play", nil)
+	protocol.fill(rep)
+	if rep.Counters["protocol_prompts_judged"] != 3 || rep.Counters["protocol_registrations_judged"] != 1 || rep.Counters["protocol_registrations_missed"] != 0 {
+		t.Errorf("report counters = %v", rep.Counters)
+	}
+}
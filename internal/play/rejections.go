@@ -0,0 +1,96 @@
+package play
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Outbound actions an in-game server error is attributed to.
+const (
+	actionJoin      = "join"
+	actionBet       = "bet"
+	actionFold      = "fold"
+	actionKeepalive = "keepalive"
+	actionNone      = "none"
+)
+
+// rejectionMatrix counts in-game server errors by the action the session
+// sent last and the error code. It is safe for concurrent use.
+type rejectionMatrix struct {
+	mu     sync.Mutex
+	counts map[string]map[int]int64
+}
+
+var rejections rejectionMatrix
+
+func (m *rejectionMatrix) add(action string, code int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counts == nil {
+		m.counts = make(map[string]map[int]int64)
+	}
+	if m.counts[action] == nil {
+		m.counts[action] = make(map[int]int64)
+	}
+	m.counts[action][code]++
+}
+
+// snapshot returns a copy of the counts, and the actions and codes seen,
+// sorted.
+func (m *rejectionMatrix) snapshot() (counts map[string]map[int]int64, actions []string, codes []int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts = make(map[string]map[int]int64, len(m.counts))
+	seen := make(map[int]bool)
+	for action, byCode := range m.counts {
+		actions = append(actions, action)
+		counts[action] = make(map[int]int64, len(byCode))
+		for code, n := range byCode {
+			counts[action][code] = n
+			if !seen[code] {
+				seen[code] = true
+				codes = append(codes, code)
+			}
+		}
+	}
+	sort.Strings(actions)
+	sort.Ints(codes)
+	return counts, actions, codes
+}
+
+// print writes the matrix with one row per action and one column per code.
+func (m *rejectionMatrix) print(w io.Writer) {
+	counts, actions, codes := m.snapshot()
+	if len(actions) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Server errors by last action and code:")
+	header := make([]string, len(codes))
+	for i, code := range codes {
+		header[i] = fmt.Sprintf("%6d", code)
+	}
+	fmt.Fprintf(w, "  %-10s%s\n", "", strings.Join(header, " "))
+	for _, action := range actions {
+		row := make([]string, len(codes))
+		for i, code := range codes {
+			row[i] = fmt.Sprintf("%6d", counts[action][code])
+		}
+		fmt.Fprintf(w, "  %-10s%s\n", action, strings.Join(row, " "))
+	}
+}
+
+// fill adds a server_errors_<action>_<code> counter per cell to rep.
+func (m *rejectionMatrix) fill(rep *report.Report) {
+	counts, _, _ := m.snapshot()
+	for action, byCode := range counts {
+		for code, n := range byCode {
+			rep.Counters["server_errors_"+action+"_"+strconv.Itoa(code)] = n
+		}
+	}
+}
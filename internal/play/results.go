@@ -0,0 +1,445 @@
+package play
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/rotate"
+	"elastic-ai-jam-2025/internal/sink"
+)
+
+// Outcome is why a session ended. Every session ends with exactly one.
+type Outcome string
+
+// Outcomes of a session.
+const (
+	outcomeCompleted          Outcome = "completed" // saw event_game_over
+	outcomeLeft               Outcome = "left"      // answered -max-hands prompts, left before the game ended
+	outcomeRegistrationFailed Outcome = "registration_failed"
+	outcomeStalled            Outcome = "stalled" // timed out waiting for game activity
+	outcomeProtocolError      Outcome = "protocol_error"
+	outcomeDisconnected       Outcome = "disconnected" // the server closed the connection
+	outcomeInterrupted        Outcome = "interrupted"
+	outcomeBusted             Outcome = "busted"
+	outcomeNeverSeated        Outcome = "never_seated"
+	outcomeReaped             Outcome = "reaped" // closed by the idle watchdog
+	outcomePanic              Outcome = "panic"  // the session's goroutine panicked; a bug
+)
+
+// outcomes lists every Outcome, in the order summaries print them.
+var outcomes = []Outcome{
+	outcomeCompleted, outcomeLeft, outcomeBusted, outcomeStalled, outcomeNeverSeated,
+	outcomeDisconnected, outcomeProtocolError, outcomeReaped, outcomePanic, outcomeRegistrationFailed, outcomeInterrupted,
+}
+
+// Exit codes of play. A single-player run exits with the code of its
+// session's outcome; larger runs exit exitFailed only when no session
+// registered. Flag and setup errors exit exitUsage in every run.
+const (
+	exitCompleted          = 0 // the session saw event_game_over
+	exitFailed             = 1
+	exitRegistrationFailed = 2
+	exitStalled            = 3 // no game activity within the timeouts
+	exitProtocolError      = 4 // and every other outcome without a code
+	exitBusted             = 5
+	exitNeverSeated        = 6
+	exitLeft               = 7 // -max-hands done before the game ended
+	exitUsage              = 64
+	exitInterrupted        = 130
+)
+
+// outcomeExitCode is the exit code of a single-player run that ended with
+// outcome.
+func outcomeExitCode(outcome Outcome) int {
+	switch outcome {
+	case outcomeCompleted:
+		return exitCompleted
+	case outcomeRegistrationFailed:
+		return exitRegistrationFailed
+	case outcomeStalled:
+		return exitStalled
+	case outcomeBusted:
+		return exitBusted
+	case outcomeNeverSeated:
+		return exitNeverSeated
+	case outcomeLeft:
+		return exitLeft
+	case outcomeInterrupted:
+		return exitInterrupted
+	default:
+		return exitProtocolError
+	}
+}
+
+// SessionResult is one line of the -results-out file: what a session did.
+type SessionResult struct {
+	RunID  string `json:"run_id,omitempty"`
+	Player string `json:"player"`
+	// Index is the player's index, whatever order -shuffle launched it in.
+	Index      int          `json:"index"`
+	Registered bool         `json:"registered"`
+	Outcome    Outcome      `json:"outcome"`
+	Games      []GameResult `json:"games,omitempty"`
+	Bets       int          `json:"bets"`
+	AllIns     int          `json:"all_ins"`
+	Folds      int          `json:"folds"`
+	// Hands is the number of bet prompts the session answered.
+	Hands int `json:"hands"`
+	// UnconfirmedMoves is the number of moves not confirmed within
+	// -confirm-window; see actionConfirmer.
+	UnconfirmedMoves int `json:"unconfirmed_moves,omitempty"`
+	// FinalChips is the last chip count the server reported to the session,
+	// or -1 if it never did. ChipsDelta is FinalChips minus the first chip
+	// count reported.
+	FinalChips int `json:"final_chips"`
+	ChipsDelta int `json:"chips_delta"`
+	// DurationMs is how long the session lived, from dialing to its end.
+	DurationMs int64 `json:"duration_ms"`
+	// UptimeMs is how long a -park session was connected, and Reconnects
+	// how often it came back after a drop.
+	UptimeMs   int64 `json:"uptime_ms,omitempty"`
+	Reconnects int   `json:"reconnects,omitempty"`
+	// LogFile is the session's -log-dir file, if it got one.
+	LogFile string `json:"log_file,omitempty"`
+	// HandLog are the hands of the session's games, with -record-hands.
+	HandLog []HandResult `json:"hand_log,omitempty"`
+}
+
+// GameResult is a game the session was seated at, with the official outcome
+// attached by -enrich.
+type GameResult struct {
+	GameID      string                 `json:"game_id"`
+	Enriched    bool                   `json:"enriched,omitempty"`
+	EnrichError string                 `json:"enrich_error,omitempty"`
+	Players     []httpapi.ListedPlayer `json:"players,omitempty"`
+	Pots        json.RawMessage        `json:"pots,omitempty"`
+	Winners     json.RawMessage        `json:"winners,omitempty"`
+	// DurationMs is how long the session saw the game last, and TableSize
+	// the players at the table; Censored is set when the session left
+	// before the game over. See gameTiming.
+	DurationMs int64 `json:"duration_ms,omitempty"`
+	TableSize  int   `json:"table_size,omitempty"`
+	Censored   bool  `json:"censored,omitempty"`
+	// MinimumBets is the minimum bet of each hand the session saw, and
+	// ForcedAllIn is set when a prompt asked it for more than its chips.
+	MinimumBets []MinimumBetPoint `json:"minimum_bets,omitempty"`
+	ForcedAllIn bool              `json:"forced_all_in,omitempty"`
+}
+
+// resultsSyncInterval bounds how much of the streamed results file a crash
+// of the machine can lose.
+const resultsSyncInterval = 5 * time.Second
+
+// resultsQueueSize bounds the results waiting for the disk; sessions end far
+// less often than they receive messages.
+const resultsQueueSize = 4096
+
+// resultLog collects the results of finished sessions. Each result is
+// appended to the results file as soon as its session ends, and running
+// totals feed the rolling summary. It is safe for concurrent use.
+type resultLog struct {
+	mu sync.Mutex
+	// keep makes finish retain results in memory, for the post-run steps.
+	keep    bool
+	results []*SessionResult
+
+	// path is the results file, set before any session runs; out is open
+	// on it, and sink writes to out so sessions never wait on the disk.
+	path string
+	out  *resultsFile
+	sink *sink.Sink
+
+	// Totals over the sessions finished in this run.
+	finished   int
+	completed  int
+	gamesTotal int
+	netChips   int64
+}
+
+// open starts streaming results to path. With resume, the records already
+// in path are loaded and the usernames of completed sessions returned, so
+// they are not played again; a partial last line left by a crash and any
+// other unreadable line are skipped.
+func (l *resultLog) open(path string, resume bool) (done map[string]bool, err error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		if done, err = l.load(path); err != nil {
+			return nil, err
+		}
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening results: %w", err)
+	}
+	if resume {
+		// Terminate a partial last line so the next record starts cleanly.
+		if info, err := f.Stat(); err == nil && info.Size() > 0 && !endsWithNewline(path) {
+			f.WriteString("\n")
+		}
+	}
+	l.path, l.out = path, &resultsFile{f: f, lastSync: time.Now()}
+	l.sink = sink.New("results", resultsQueueSize, l.out)
+	return done, nil
+}
+
+// resultsFile is the sink target of the results file. It syncs the file
+// every resultsSyncInterval.
+type resultsFile struct {
+	f        *os.File
+	lastSync time.Time
+}
+
+func (t *resultsFile) Write(record []byte) error {
+	if _, err := t.f.Write(record); err != nil {
+		return err
+	}
+	if time.Since(t.lastSync) < resultsSyncInterval {
+		return nil
+	}
+	t.lastSync = time.Now()
+	return t.f.Sync()
+}
+
+// Flush does nothing: records are written unbuffered, and synced by Write.
+func (t *resultsFile) Flush() error { return nil }
+
+func (l *resultLog) load(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading results: %w", err)
+	}
+	defer f.Close()
+	done := make(map[string]bool)
+	skipped := 0
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		var r SessionResult
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil || r.Player == "" {
+			if len(bytes.TrimSpace(sc.Bytes())) > 0 {
+				skipped++
+			}
+			continue
+		}
+		if r.Outcome == outcomeCompleted || r.Outcome == outcomeLeft {
+			done[r.Player] = true
+		}
+		if l.keep {
+			l.results = append(l.results, &r)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading results: %w", err)
+	}
+	fmt.Printf("Resuming from %s: %d sessions already completed", path, len(done))
+	if skipped > 0 {
+		fmt.Printf(", %d unreadable lines skipped", skipped)
+	}
+	fmt.Println()
+	return done, nil
+}
+
+func endsWithNewline(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+	last := make([]byte, 1)
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return true
+	}
+	if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+		return true
+	}
+	return last[0] == '\n'
+}
+
+// finish records the result of a session that ended.
+func (l *resultLog) finish(r *SessionResult) {
+	var line []byte
+	if l.path != "" {
+		line, _ = json.Marshal(r)
+		line = append(line, '\n')
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.finished++
+	if r.Outcome == outcomeCompleted {
+		l.completed++
+	}
+	l.gamesTotal += len(r.Games)
+	if r.FinalChips >= 0 {
+		l.netChips += int64(r.ChipsDelta)
+	}
+	if l.keep {
+		l.results = append(l.results, r)
+	}
+	l.sink.Put(line)
+}
+
+// rollover moves the results streamed so far to archive and continues in a
+// new results file; see rotate.Swap.
+func (l *resultLog) rollover(archive string) error {
+	if l.sink == nil {
+		return nil
+	}
+	// The swap runs on the sink's goroutine, after the results queued
+	// before it, so no write lands in the archive late.
+	var swapErr error
+	err := l.sink.Do(func() error {
+		f, err := rotate.Swap(l.out.f, l.path, archive, nil)
+		if f == nil {
+			return err
+		}
+		l.out.f, l.out.lastSync = f, time.Now()
+		swapErr = err
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return swapErr
+}
+
+// progress formats the rolling summary line.
+func (l *resultLog) progress(elapsed time.Duration) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rate := 0.0
+	if l.finished > 0 {
+		rate = 100 * float64(l.completed) / float64(l.finished)
+	}
+	return fmt.Sprintf("[progress %s] sessions finished: %d, completed: %.1f%%, games joined: %d, net chips: %+d",
+		elapsed.Round(time.Second), l.finished, rate, l.gamesTotal, l.netChips)
+}
+
+// close writes the results still queued, then syncs and closes the results
+// file.
+func (l *resultLog) close() error {
+	if l.out == nil {
+		return nil
+	}
+	err := l.sink.Close()
+	if err == nil {
+		err = l.out.f.Sync()
+	}
+	if cerr := l.out.f.Close(); err == nil {
+		err = cerr
+	}
+	l.out = nil
+	if err != nil {
+		return fmt.Errorf("writing results: %w", err)
+	}
+	return nil
+}
+
+// all returns the collected results.
+func (l *resultLog) all() []*SessionResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.results)
+}
+
+// gameIDs returns every game ID of the collected results, once.
+func (l *resultLog) gameIDs() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	seen := make(map[string]bool)
+	var ids []string
+	for _, r := range l.results {
+		for _, g := range r.Games {
+			if !seen[g.GameID] {
+				seen[g.GameID] = true
+				ids = append(ids, g.GameID)
+			}
+		}
+	}
+	return ids
+}
+
+// rewrite replaces path with the collected results, one session per line.
+// It is used once the results were enriched after the run.
+func (l *resultLog) rewrite(path string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	l.mu.Lock()
+	for _, r := range l.results {
+		enc.Encode(r)
+	}
+	l.mu.Unlock()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("writing results: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// enrich fetches the details of every game the sessions played, at most
+// concurrency at a time, and attaches them to the results. A game that
+// cannot be fetched stays unenriched with the error recorded; it never fails
+// the run. It returns the number of games enriched and not enriched.
+func (l *resultLog) enrich(api *httpapi.Client, concurrency int) (ok, failed int) {
+	ids := l.gameIDs()
+	details := make(map[string]GameResult, len(ids))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(concurrency, 1))
+	for _, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			g := GameResult{GameID: id}
+			if d, err := api.Game(id); err != nil {
+				g.EnrichError = err.Error()
+			} else {
+				g.Enriched = true
+				g.Players = d.GameState.Players
+				g.Pots = d.GameState.Pots
+				g.Winners = d.GameState.Winners
+			}
+			mu.Lock()
+			details[id] = g
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	for id, g := range details {
+		if g.Enriched {
+			ok++
+			gameDurations.setTableSize(id, len(g.Players))
+		} else {
+			failed++
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, r := range l.results {
+		for i, g := range r.Games {
+			d := details[g.GameID]
+			d.DurationMs, d.TableSize, d.Censored = g.DurationMs, g.TableSize, g.Censored
+			d.MinimumBets, d.ForcedAllIn = g.MinimumBets, g.ForcedAllIn
+			if n := len(d.Players); n > 0 {
+				d.TableSize = n
+			}
+			r.Games[i] = d
+		}
+	}
+	return ok, failed
+}
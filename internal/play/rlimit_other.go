@@ -0,0 +1,6 @@
+//go:build !unix
+
+package play
+
+// openFileLimit returns 0: the limit on open files is unknown here.
+func openFileLimit() int64 { return 0 }
@@ -0,0 +1,18 @@
+//go:build unix
+
+package play
+
+import (
+	"math"
+	"syscall"
+)
+
+// openFileLimit returns the soft limit on the files the process may open,
+// or 0 when it is unlimited or unknown.
+func openFileLimit() int64 {
+	var l syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &l); err != nil || l.Cur > math.MaxInt64 {
+		return 0
+	}
+	return int64(l.Cur)
+}
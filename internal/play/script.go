@@ -0,0 +1,71 @@
+package play
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/script"
+)
+
+// runScript registers the first player and plays steps with it. It returns
+// 1 when a step fails, printing the expectation diff if that is why.
+func runScript(cfg *Config, steps []script.Step) int {
+	account := cfg.account(0)
+	username := account.Username
+	fmt.Printf("Running script %s (%d steps) as %s on %s\n", cfg.Script, len(steps), username, cfg.TCPServer)
+
+	conn, err := pokerclient.Dial(cfg.TCPServer.First(), cfg.ConnectTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer conn.Close()
+	cfg.ApplyTimeouts(conn)
+	resp, err := conn.Register(username, account.Password)
+	if resp != nil {
+		transcriptOut.Write(username, "", resp.Raw)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := cli.InterruptContext()
+	defer stop()
+	stopClose := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stopClose()
+
+	var gameID string
+	r := &script.Runner{
+		Conn:    conn,
+		Player:  username,
+		Timeout: cfg.ScriptTimeout,
+		Out:     os.Stdout,
+		OnMessage: func(resp *pokerclient.ServerResponse) {
+			if resp.GameID != "" {
+				gameID = resp.GameID
+			}
+			transcriptOut.Write(username, gameID, resp.Raw)
+		},
+	}
+	err = r.Run(ctx, steps)
+	var expectErr *script.ExpectationError
+	switch {
+	case err == nil:
+		fmt.Println("Script passed.")
+		return 0
+	case ctx.Err() != nil:
+		fmt.Fprintln(os.Stderr, "Interrupted.")
+		return 1
+	case errors.As(err, &expectErr):
+		fmt.Fprintf(os.Stderr, "Expectation failed at %s:\n%s\n", expectErr.Step, expectErr.Diff)
+		return 1
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+}
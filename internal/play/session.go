@@ -0,0 +1,721 @@
+package play
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/passwords"
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/rng"
+)
+
+// PlayerSessionState holds the state for a single player's game session.
+type PlayerSessionState struct {
+	cfg       *Config
+	username  string
+	tracked   *trackedSession
+	conn      *pokerclient.Conn
+	logPrefix string
+
+	strategy  Strategy
+	opponents *OpponentModel
+	hands     *HandTracker
+	positions *PositionTracker
+	chips     chipTracker
+	timer     gameTimer
+	minBets   minimumBetTracker
+
+	// playerID is the ID the server assigned at registration, when it
+	// gave one; prompts addressed to it are ours too.
+	playerID string
+
+	// gameID is the game the player was last seen at, and gameEvents the
+	// number of messages received since that game started.
+	gameID      string
+	gameEvents  int64
+	gamesPlayed int
+
+	// stalled is 1 while the session is quiet past cfg.StallWarning; see
+	// watchStalls. heard is the function re-arming that watch.
+	stalled int32
+	heard   func()
+	// keepalivePending is 1 between a keepalive and the next message.
+	// answerDue is set from a join or move sent until the next message,
+	// which may be the server rejecting it.
+	keepalivePending int32
+	answerDue        bool
+
+	// result is what the session did, kept when results are collected.
+	// startChips is the first chip count the server reported, or -1.
+	result     SessionResult
+	startChips int
+	// leaving is set once cfg.MaxHands prompts were answered, and
+	// handOver once the hand then ended. outOfChips is set once a prompt
+	// showed no chips to cover its bet.
+	leaving    bool
+	handOver   bool
+	outOfChips bool
+	// awaitingSeat is set from a join until a message shows the session
+	// was seated; joinedAt is when that join was sent, and gameStart when
+	// the current game was joined.
+	awaitingSeat bool
+	joinedAt     time.Time
+	gameStart    time.Time
+
+	// lastMove is the last move sent.
+	lastMove pokerclient.Move
+	// confirm watches the moves sent for the server applying them. redo,
+	// when set, answers the next prompt, one repeating a dropped move, and
+	// resending is set from then until that answer is sent.
+	confirm   actionConfirmer
+	redo      *pokerclient.Move
+	resending bool
+	// clock times the answer to the prompt being answered.
+	clock turnClock
+
+	// logOut is where logVerbose writes, chosen by logWriter on first use;
+	// logFile is the -log-dir file behind it, if any.
+	logOut  io.Writer
+	logFile *os.File
+
+	// stream checks the received messages for anomalies.
+	stream streamChecker
+
+	// rng is this session's random source, derived from the run seed and the
+	// player index.
+	rng *rand.Rand
+
+	// addr is the -server endpoint of the session, chosen by its index.
+	addr string
+}
+
+// managePlayerSession handles the entire lifecycle for one player. With a
+// pooled connection, the player is already registered and only joins.
+func managePlayerSession(ctx context.Context, cfg *Config, tracked *trackedSession, pc *pooledConn, wg *sync.WaitGroup, semaphore chan struct{}) {
+	defer wg.Done()
+	defer activeSessions.remove(tracked)
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			<-semaphore
+		}
+	}()
+
+	id, username := tracked.id, tracked.username
+	playerState := &PlayerSessionState{
+		cfg:       cfg,
+		tracked:   tracked,
+		username:  username,
+		logPrefix: fmt.Sprintf("[%s] ", username),
+		opponents: NewOpponentModel(username),
+		hands:     NewHandTracker(username),
+		positions: NewPositionTracker(username),
+		chips:     newChipTracker(username, cfg.ChipsTolerance),
+		confirm:   newActionConfirmer(username, cfg.ConfirmWindow),
+		rng:       rng.ForWorker(cfg.Seed, id),
+		addr:      cfg.TCPServer.For(id),
+	}
+	playerState.result = SessionResult{RunID: cfg.RunID, Player: username, Index: id, FinalChips: -1, Outcome: outcomeRegistrationFailed}
+	playerState.startChips = -1
+	defer results.finish(&playerState.result)
+	defer func() {
+		playerState.finishConfirm()
+		hands := playerState.hands.Finish()
+		effects.add(&playerState.result, hands)
+		timings := playerState.timer.finish()
+		gameDurations.add(timings)
+		attachTimings(playerState.result.Games, timings)
+		minimumBets.add(playerState.minBets.games)
+		attachMinimumBets(playerState.result.Games, playerState.minBets.games)
+		if cfg.RecordHands {
+			playerState.result.HandLog = hands
+		}
+	}()
+	started := time.Now()
+	if pc != nil {
+		started = pc.dialedAt
+	}
+	defer func() {
+		if tracked.reaped.Load() {
+			playerState.result.Outcome = outcomeReaped
+		}
+		lived := time.Since(started)
+		playerState.result.DurationMs = lived.Milliseconds()
+		lifetimes.record(&playerState.result, lived)
+		byEndpoint.Add(playerState.addr, "outcome_"+string(playerState.result.Outcome), 1)
+	}()
+	defer playerState.closeLog()
+	// Deferred last so the session's result records the panic.
+	defer workerPanics.Recover("session of "+username, func(any) {
+		playerState.result.Outcome = outcomePanic
+	})
+	playerState.strategy = strategies[cfg.Strategy](cfg, playerState.rng)
+	password := cfg.account(id).Password
+
+	// 1. Establish TCP connection
+	var err error
+	regStart := time.Now()
+	if pc != nil {
+		playerState.conn = pc.conn
+	} else {
+		series.Started()
+		if playerState.conn, err = pokerclient.Dial(playerState.addr, cfg.ConnectTimeout); err != nil {
+			playerState.logVerbose("Error dialing TCP server: %v", err)
+			recordRegistrationFailure(err, playerState.username, playerState.addr)
+			return
+		}
+	}
+	defer playerState.conn.Close()
+	tracked.conn.Store(playerState.conn)
+	// Closing the connection unblocks any pending read when the run is interrupted.
+	conn := playerState.conn
+	stopClose := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stopClose()
+	cfg.ApplyTimeouts(playerState.conn)
+	if playerState.verbose() {
+		// Left nil otherwise, so the connection does not even format its log lines.
+		playerState.conn.Logf = playerState.logVerbose
+	}
+
+	// 2. Register, unless the pool already did
+	if pc != nil {
+		playerState.logVerbose("Registered by the pool %s ago.", time.Since(pc.registeredAt).Round(time.Millisecond))
+	} else {
+		if !playerState.register(password) {
+			return // Registration failed, error already logged and counter incremented
+		}
+		recordRegistration(time.Since(regStart)-playerState.conn.DialWait, playerState.addr)
+		playerState.logVerbose("Successfully registered.")
+	}
+	playerState.result.Registered = true
+
+	// 3. Join a game and play, or stay parked
+	if cfg.Park {
+		playerState.result.Outcome = playerState.park(ctx, password)
+	} else {
+		playerState.result.Outcome = playerState.play(ctx)
+	}
+	if ctx.Err() != nil {
+		playerState.result.Outcome = outcomeInterrupted
+	}
+	if playerState.startChips >= 0 {
+		playerState.result.ChipsDelta = playerState.result.FinalChips - playerState.startChips
+	}
+	if playerState.result.Outcome == outcomeBusted && cfg.ReplaceBusted && ctx.Err() == nil && !launchesStopped.Load() {
+		replaceBusted(ctx, cfg, wg, semaphore)
+		handedOff = true
+	}
+
+	playerState.logVerbose("Session ended.")
+}
+
+// recordRegistration counts a successful registration on addr that took d.
+func recordRegistration(d time.Duration, addr string) {
+	successfulRegistrations.Inc()
+	registrationLatency.Record(d)
+	byEndpoint.Add(addr, "successful_registrations", 1)
+	byEndpoint.Record(addr, "registration", d)
+	series.Succeeded(d)
+	if w := currentWave.Load(); w != nil {
+		w.registration.Record(d)
+	}
+}
+
+// recordRegistrationFailure counts a dial or registration of player on addr
+// that failed with err.
+func recordRegistrationFailure(err error, player, addr string) {
+	failedRegistrations.Inc()
+	byEndpoint.Add(addr, "failed_registrations", 1)
+	byEndpoint.Failed(addr, err)
+	registrationFailures.AddErr(err)
+	registrationRejections.AddErr(err, player)
+	passwords.NoteRejection(err)
+	series.Failed(err)
+}
+
+func (ps *PlayerSessionState) verbose() bool {
+	return ps.cfg.Verbose || ps.cfg.NumPlayers == 1 // Always log if only one player for easier debugging
+}
+
+func (ps *PlayerSessionState) logVerbose(format string, args ...interface{}) {
+	if ps.verbose() {
+		fmt.Fprintf(ps.logWriter(), ps.logPrefix+format+"\n", args...)
+	}
+}
+
+func (ps *PlayerSessionState) register(password string) bool {
+	resp, err := ps.conn.Register(ps.username, password)
+	if resp != nil {
+		noteUnknownKeys(resp)
+		transcriptOut.Write(ps.username, "", resp.Raw)
+	}
+	protocol.registration(err)
+	if err != nil {
+		if regErr, ok := err.(*pokerclient.RegistrationError); ok {
+			ps.logVerbose("%v", regErr)
+		}
+		recordRegistrationFailure(err, ps.username, ps.addr)
+		return false
+	}
+	ps.playerID = pokerclient.AssignedPlayerID(resp)
+	if ps.playerID != "" && ps.playerID != ps.username {
+		warnIDMismatch(ps.username, ps.playerID)
+	}
+	return true
+}
+
+// isMe reports whether a prompt for playerID is addressed to this session:
+// playerID names our username or the ID the server assigned us, ignoring
+// case and surrounding spaces. Prompts an exact comparison with the username
+// would have missed are counted.
+func (ps *PlayerSessionState) isMe(playerID string) bool {
+	if playerID == ps.username {
+		return true
+	}
+	if !pokerclient.SamePlayer(playerID, ps.username) && (ps.playerID == "" || !pokerclient.SamePlayer(playerID, ps.playerID)) {
+		return false
+	}
+	promptsMatchedLoosely.Inc()
+	warnIDMismatch(ps.username, playerID)
+	return true
+}
+
+// watchStalls arms a timer that warns when the session receives nothing for
+// cfg.StallWarning. heard must be called on every message; it re-arms the
+// timer and clears the stalled state. stop disarms it.
+func (ps *PlayerSessionState) watchStalls() (heard, stop func()) {
+	if ps.cfg.StallWarning <= 0 {
+		return func() {}, func() {}
+	}
+	var hasStalled int32
+	timer := time.AfterFunc(ps.cfg.StallWarning, func() {
+		if atomic.CompareAndSwapInt32(&ps.stalled, 0, 1) {
+			stalledSessions.Inc()
+			if atomic.CompareAndSwapInt32(&hasStalled, 0, 1) {
+				everStalled.Inc()
+			}
+			slog.Warn("session is quiet", "player", ps.username, "silent_for", ps.cfg.StallWarning)
+		}
+	})
+	heard = func() {
+		timer.Reset(ps.cfg.StallWarning)
+		if atomic.CompareAndSwapInt32(&ps.stalled, 1, 0) {
+			stalledSessions.Dec()
+			ps.logVerbose("Traffic resumed after a quiet period.")
+		}
+	}
+	stop = func() {
+		timer.Stop()
+		if atomic.CompareAndSwapInt32(&ps.stalled, 1, 0) {
+			stalledSessions.Dec()
+		}
+	}
+	return heard, stop
+}
+
+// enterGame records that the player is now seated at gameID and tags the
+// following log lines with it.
+func (ps *PlayerSessionState) enterGame(gameID string) {
+	previous := ps.gameID
+	ps.gameID = gameID
+	ps.seated()
+	ps.logPrefix = fmt.Sprintf("[%s game=%s] ", ps.username, gameID)
+	if previous != "" {
+		ps.logVerbose("Moved from game %s to game %s.", previous, gameID)
+	} else {
+		ps.logVerbose("Seated at game %s.", gameID)
+	}
+	playerGames.add(ps.username, gameID)
+	ps.result.Games = append(ps.result.Games, GameResult{GameID: gameID})
+}
+
+// seated ends the wait for a seat, if the session was waiting.
+func (ps *PlayerSessionState) seated() {
+	if !ps.awaitingSeat {
+		return
+	}
+	ps.awaitingSeat = false
+	waited := ps.cfg.clock.Now().Sub(ps.joinedAt)
+	timeToSeat.Record(waited)
+	byEndpoint.Record(ps.addr, "time_to_seat", waited)
+	if w := currentWave.Load(); w != nil {
+		w.seat.Record(waited)
+	}
+}
+
+// readTimeout bounds the next read. Waiting between hands is normal, so
+// reads may take up to the read timeout, capped by the game activity
+// timeout, and a quieter session is only reported by watchStalls; while
+// waiting for a seat, the read ends at the seat deadline instead. A
+// non-positive result means the deadline passed.
+func (ps *PlayerSessionState) readTimeout() time.Duration {
+	limit := ps.cfg.GameActivityTimeout
+	if ps.cfg.ReadTimeout > 0 {
+		limit = min(limit, ps.cfg.ReadTimeout)
+	}
+	if !ps.awaitingSeat || ps.cfg.SeatTimeout <= 0 {
+		return limit
+	}
+	return min(ps.joinedAt.Add(ps.cfg.SeatTimeout).Sub(ps.cfg.clock.Now()), limit)
+}
+
+// seatTimedOut ends a session that was not seated within cfg.SeatTimeout.
+// One that already played is counted as stalled rather than never seated.
+func (ps *PlayerSessionState) seatTimedOut() Outcome {
+	ps.logVerbose("Not seated within %s. Ending session.", ps.cfg.SeatTimeout)
+	if len(ps.result.Games) > 0 {
+		return outcomeStalled
+	}
+	sessionsNeverSeated.Inc()
+	return outcomeNeverSeated
+}
+
+// spectating reports whether the session only observes games.
+func (ps *PlayerSessionState) spectating() bool {
+	return ps.cfg.Strategy == strategySpectate
+}
+
+// endSession is returned by the hooks of pokerclient.Run to end the session
+// with outcome.
+type endSession struct{ outcome Outcome }
+
+func (e endSession) Error() string { return "session ended: " + string(e.outcome) }
+
+// play joins a game and plays until the session ends, through
+// pokerclient.Run, and returns the outcome.
+func (ps *PlayerSessionState) play(ctx context.Context) Outcome {
+	heard, stopWatch := ps.watchStalls()
+	defer stopWatch()
+	ps.heard = heard
+	stopKeepalive := ps.startKeepalive()
+	defer stopKeepalive()
+
+	games := 1
+	if ps.spectating() {
+		games = ps.cfg.SpectateGames
+	}
+	_, err := pokerclient.Run(ctx, pokerclient.Config{
+		Credentials: pokerclient.Credentials{Username: ps.username},
+		PlayerID:    ps.playerID,
+		Strategy:    sessionStrategy{ps},
+		Conn:        ps.conn,
+		Games:       games,
+		Hooks: pokerclient.Hooks{
+			BeforeRead: ps.beforeRead,
+			OnEvent:    ps.onEvent,
+			OnAction:   ps.onAction,
+			OnRejected: ps.onRejected,
+			OnGameEnd:  ps.onGameEnd,
+		},
+	})
+	return ps.outcomeOf(err)
+}
+
+// outcomeOf returns the outcome of a session pokerclient.Run ended with err.
+func (ps *PlayerSessionState) outcomeOf(err error) Outcome {
+	var end endSession
+	var sessErr *pokerclient.SessionError
+	switch {
+	case err == nil:
+		return outcomeCompleted
+	case errors.As(err, &end):
+		return end.outcome
+	case errors.As(err, &sessErr) && sessErr.Op == "read":
+		ps.keepaliveReaction(nil, sessErr.Err)
+		ps.logVerbose("Exiting game loop due to read error: %v", sessErr.Err)
+		switch errclass.Classify(sessErr.Err) {
+		case errclass.Timeout:
+			if ps.awaitingSeat && ps.cfg.SeatTimeout > 0 {
+				return ps.seatTimedOut()
+			}
+			return outcomeStalled
+		case errclass.EOF, errclass.Reset:
+			return outcomeDisconnected
+		}
+	default:
+		ps.logVerbose("Error sending: %v. Exiting.", err)
+	}
+	return outcomeProtocolError
+}
+
+// beforeRead ends the session once the game went quiet for too long, or
+// bounds the next read; see readTimeout.
+func (ps *PlayerSessionState) beforeRead() error {
+	if ps.cfg.clock.Now().Sub(ps.gameStart) > ps.cfg.GameActivityTimeout {
+		ps.logVerbose("Game activity timeout. Ending session.")
+		return endSession{outcomeStalled}
+	}
+	timeout := ps.readTimeout()
+	if timeout <= 0 {
+		return endSession{ps.seatTimedOut()}
+	}
+	ps.conn.ReadTimeout = timeout
+	return nil
+}
+
+// onEvent records every message received, and decides which prompts and
+// errors pokerclient.Run acts on.
+func (ps *PlayerSessionState) onEvent(resp *pokerclient.ServerResponse) error {
+	keepaliveRejected := ps.keepaliveReaction(resp, nil)
+	ps.answerDue = false
+	ps.heard()
+	if ps.awaitingSeat {
+		ps.tracked.touch(stateSeating)
+	} else {
+		ps.tracked.touch(statePlaying)
+	}
+	noteUnknownKeys(resp)
+	if resp.GameID != "" && resp.GameID != ps.gameID {
+		ps.enterGame(resp.GameID)
+	}
+	ps.gameEvents++
+	messagesReceived.Inc()
+	transcriptOut.Write(ps.username, ps.gameID, resp.Raw)
+	ps.checkStream(resp)
+	if ps.cfg.GamesManifest != "" {
+		gamesSeen.Observe(ps.gameID, ps.username, resp.Type, time.Now())
+	}
+	ps.opponents.Observe(resp)
+	ps.hands.Observe(resp)
+	ps.positions.Observe(resp)
+	ps.chips.Observe(resp, ps.playerID)
+	now := ps.cfg.clock.Now()
+	ps.confirmAction(resp, now)
+	ps.timer.observe(resp, now)
+	ps.minBets.observe(resp, ps.username, ps.gameID, ps.hands.Hand(), ps.timer.elapsed(now))
+
+	if ps.handOver && resp.Type != pokerclient.TypePotWon && resp.Type != pokerclient.TypeGameOver {
+		// The game goes on without us: the session did its hands but
+		// never saw the game end.
+		ps.logVerbose("Answered %d bet prompts and the hand ended. Leaving before the game ends.", ps.result.Hands)
+		return endSession{outcomeLeft}
+	}
+
+	switch resp.Type {
+	case pokerclient.TypeActionPlayerBet:
+		return ps.onPrompt(resp)
+	case pokerclient.TypeLeaderboardEntryEnd:
+		ps.logVerbose("Received terminal event: %s. Ending session.", resp.Type)
+	case pokerclient.TypePotWon:
+		// The event_pot_won structure needs to be parsed to find our player's chip count.
+		// For simplicity, we rely on action_player_bet or game_over for chip status.
+		// A last hand is followed by event_game_over, which completes
+		// the session; anything else makes it leave. A hand may award
+		// several pots.
+		ps.handOver = ps.leaving
+	case "": // Empty type might mean an error object that wasn't fully parsed as ServerResponse
+		if resp.Code == 0 {
+			ps.logVerbose("Received message with empty type and no error code. Raw: %+v", resp)
+			return nil
+		}
+		ps.logVerbose("Received error from server: Code %d, Message: %s", resp.Code, resp.Message)
+		if keepaliveRejected {
+			rejections.add(actionKeepalive, resp.Code)
+			if err := ps.refusedSeat(resp.Message); err != nil {
+				return err
+			}
+			return pokerclient.SkipEvent
+		}
+	}
+	return nil
+}
+
+// onPrompt checks a bet prompt before pokerclient.Run answers it.
+func (ps *PlayerSessionState) onPrompt(resp *pokerclient.ServerResponse) error {
+	protocol.prompt(resp)
+	if resp.State.Player.PlayerID == "" {
+		// A prompt for nobody: acting on it would answer for whoever
+		// the server meant.
+		ps.malformedPrompt(resp, "no player_id")
+		return pokerclient.SkipEvent
+	}
+	if !ps.isMe(resp.State.Player.PlayerID) {
+		return nil
+	}
+	ps.seated()
+	ps.clock = ps.cfg.startTurn(resp.Raw, ps.cfg.clock.Now())
+	ps.logVerbose("It's my turn to bet. Stage: %s, My Chips: %d", resp.Stage, resp.State.Player.Chips)
+	if resp.State.Player.Chips <= 0 {
+		// Run folds a prompt to cover a bet with no chips; one with no
+		// bet to cover is left unanswered.
+		if resp.MinimumBet > 0 {
+			ps.logVerbose("Cannot cover the minimum bet of %d with no chips.", resp.MinimumBet)
+		}
+		if !promptHasChips(resp.Raw) {
+			ps.malformedPrompt(resp, "no chips")
+			if resp.MinimumBet > 0 {
+				return nil
+			}
+			return pokerclient.SkipEvent
+		}
+		ps.result.FinalChips = resp.State.Player.Chips
+		if resp.MinimumBet > 0 {
+			ps.outOfChips = true // once the fold is sent; see onAction
+			return nil
+		}
+		return endSession{ps.bust("prompted with no chips")}
+	}
+	if ps.spectating() && resp.State.Player.Chips < ps.cfg.SpectateMinChips {
+		ps.logVerbose("Chips %d below the spectate floor of %d. Ending session.", resp.State.Player.Chips, ps.cfg.SpectateMinChips)
+		return endSession{outcomeCompleted}
+	}
+	return nil
+}
+
+// onAction counts the joins and moves pokerclient.Run sent.
+func (ps *PlayerSessionState) onAction(a pokerclient.Action) error {
+	ps.answerDue = true
+	if a.Join {
+		ps.awaitingSeat, ps.joinedAt = true, ps.cfg.clock.Now()
+		ps.gameStart = ps.joinedAt
+		ps.tracked.touch(stateSeating)
+		if ps.gamesPlayed == 0 {
+			gamesJoined.Inc()
+			byEndpoint.Add(ps.addr, "games_joined", 1)
+			ps.logVerbose("Successfully sent join action. Waiting for game events...")
+		}
+		return nil
+	}
+	if a.Retry {
+		betRetries.Inc()
+		ps.logVerbose("Move %s rejected, retried with %s.", ps.lastMove, a.Move)
+	}
+	now := ps.cfg.clock.Now()
+	ps.clock.sent(now)
+	ps.clock = turnClock{}
+	ps.confirm.sent(a.Move, a.Turn, now, ps.resending)
+	ps.redo, ps.resending = nil, false
+	ps.countMove(a.Turn, a.Move)
+	ps.hands.Moved(a.Move)
+	ps.chips.Moved(a.Move)
+	if ps.outOfChips {
+		return endSession{ps.bust("prompted with no chips")}
+	}
+	if !a.Retry && !ps.leaving && (a.Turn.Chips > 0 || a.Turn.MinimumBet <= 0) {
+		ps.result.Hands++
+		ps.leaving = ps.cfg.MaxHands > 0 && ps.result.Hands >= ps.cfg.MaxHands
+	}
+	return nil
+}
+
+// onRejected counts a server error against the action sent last.
+func (ps *PlayerSessionState) onRejected(last *pokerclient.Action, code int, message string) error {
+	action := actionNone
+	switch {
+	case last == nil:
+	case last.Join:
+		action = actionJoin
+	case last.Move.Folds():
+		action = actionFold
+	default:
+		action = actionBet
+	}
+	rejections.add(action, code)
+	return ps.refusedSeat(message)
+}
+
+// refusedSeat ends the session as busted when message refuses a seat for
+// lack of chips. The server does not say why it will not seat a player;
+// an error about chips right after rejoining is the best sign that we are
+// broke.
+func (ps *PlayerSessionState) refusedSeat(message string) error {
+	if ps.awaitingSeat && strings.Contains(strings.ToLower(message), "chip") {
+		return endSession{ps.bust("refused a seat: " + message)}
+	}
+	return nil
+}
+
+// onGameEnd ends the session after its game, unless it spectates more.
+func (ps *PlayerSessionState) onGameEnd(resp *pokerclient.ServerResponse) error {
+	if ps.spectating() {
+		gamesObserved.Inc()
+		eventsCaptured.Add(ps.gameEvents)
+		ps.gamesPlayed++
+		if ps.result.FinalChips == 0 {
+			return endSession{ps.bust("last seen with no chips")}
+		}
+		if ps.gamesPlayed < ps.cfg.SpectateGames {
+			ps.logVerbose("Observed game %d of %d. Joining the next one.", ps.gamesPlayed, ps.cfg.SpectateGames)
+			ps.gameEvents = 0
+			return nil
+		}
+	}
+	ps.logVerbose("Received terminal event: %s. Ending session.", resp.Type)
+	if ps.cfg.Verbose {
+		ps.logVerbose("Game Over Event Data: %s", resp.Event)
+	}
+	return nil
+}
+
+// bust counts the session as out of chips and returns its outcome. There is
+// no leave action in the protocol, so the session just ends.
+func (ps *PlayerSessionState) bust(reason string) Outcome {
+	ps.logVerbose("Out of chips (%s). Ending session.", reason)
+	playersBusted.Inc()
+	bustedGamesSurvived.Add(int64(max(len(ps.result.Games)-1, 0)))
+	return outcomeBusted
+}
+
+// sessionStrategy adapts the session's Strategy to pokerclient.Run: it
+// records the stacks, gives the strategy the opponent model and folds the
+// rest of the hand once the session is leaving. It waits the think time
+// after the strategy decides, and keeps its moves within the stack.
+type sessionStrategy struct{ ps *PlayerSessionState }
+
+func (s sessionStrategy) Bet(t pokerclient.Turn) pokerclient.Move {
+	s.ps.result.FinalChips = t.Chips
+	if s.ps.startChips < 0 {
+		s.ps.startChips = t.Chips
+	}
+	if s.ps.leaving {
+		return pokerclient.Fold()
+	}
+	if redo := s.ps.redo; redo != nil {
+		s.ps.redo, s.ps.resending = nil, true
+		return withinStack(*redo, t)
+	}
+	m := withinStack(s.ps.strategy.Bet(s.ps.turn(t)), t)
+	s.ps.cfg.clock.Sleep(s.ps.clock.think(s.ps.cfg, s.ps.rng, s.ps.cfg.clock.Now()))
+	return m
+}
+
+func (s sessionStrategy) OnActionRejected(t pokerclient.Turn, m pokerclient.Move, code int, message string) (pokerclient.Move, bool) {
+	if s.ps.leaving {
+		return pokerclient.Move{}, false
+	}
+	retry, ok := s.ps.strategy.OnActionRejected(s.ps.turn(t), m, code, message)
+	return withinStack(retry, t), ok
+}
+
+func (ps *PlayerSessionState) turn(t pokerclient.Turn) Turn {
+	return Turn{Player: ps.username, Stage: t.Stage, Chips: max(t.Chips, 0), MinimumBet: t.MinimumBet, Hand: ps.hands.Hand(), History: ps.hands.History(), Opponents: ps.opponents, Position: ps.positions.Position()}
+}
+
+// countMove counts m, sent in answer to the prompt t.
+func (ps *PlayerSessionState) countMove(t pokerclient.Turn, m pokerclient.Move) {
+	stage := stageStats[stageName(t.Stage)]
+	counter, stageCounter, sessionCounter, what := betsMade, stage.bets, &ps.result.Bets, fmt.Sprintf("Bet %d.", m.Amount())
+	switch {
+	case m.Folds():
+		counter, stageCounter, sessionCounter, what = foldsMade, stage.folds, &ps.result.Folds, "Folded."
+	case m.IsAllIn() || m.Amount() > 0 && m.Amount() >= t.Chips:
+		counter, stageCounter, sessionCounter, what = allInsMade, stage.allIns, &ps.result.AllIns, fmt.Sprintf("Went all-in with %d chips.", m.Amount())
+	case m.Amount() == 0:
+		what = "Checked."
+	}
+	ps.logVerbose("%s", what)
+	counter.Inc()
+	stageCounter.Inc()
+	*sessionCounter++
+	ps.lastMove = m
+}
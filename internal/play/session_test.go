@@ -0,0 +1,323 @@
+package play
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/clock"
+	"elastic-ai-jam-2025/internal/mockserver"
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/rng"
+)
+
+// testConfig returns a play configuration for sessions against a local mock
+// server: quiet, with short timeouts.
+func testConfig() *Config {
+	cfg := DefaultConfig()
+	cfg.Verbose = false
+	cfg.ConnectTimeout = 2 * time.Second
+	cfg.RegisterTimeout = 2 * time.Second
+	cfg.ReadTimeout = 5 * time.Second
+	cfg.GameActivityTimeout = 10 * time.Second
+	cfg.SeatTimeout = 5 * time.Second
+	cfg.StallWarning = 0
+	cfg.Seed = 1
+	return &cfg
+}
+
+// startMock starts a mock server for the test.
+func startMock(t *testing.T, mc mockserver.Config) *mockserver.Server {
+	t.Helper()
+	if mc.Dealer == "" {
+		mc.Dealer = mockserver.DealerFold
+	}
+	if mc.StartChips == 0 {
+		mc.StartChips = 1000
+	}
+	if mc.MinimumBet == 0 {
+		mc.MinimumBet = 10
+	}
+	if mc.HandsPerGame == 0 {
+		mc.HandsPerGame = 1
+	}
+	srv, err := mockserver.Start("127.0.0.1:0", mc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+// playSession registers username on addr and plays one session with
+// strategy, set up as managePlayerSession does, and returns it once it
+// ended.
+func playSession(t *testing.T, cfg *Config, addr, username string, strategy Strategy) *PlayerSessionState {
+	t.Helper()
+	ps := registeredSession(t, cfg, addr, username, strategy)
+	ps.result.Outcome = ps.play(context.Background())
+	ps.finishConfirm()
+	return ps
+}
+
+// registeredSession returns the session of username on addr, set up as
+// managePlayerSession does and registered with the password "secret".
+// Its connection is closed when the test ends.
+func registeredSession(t *testing.T, cfg *Config, addr, username string, strategy Strategy) *PlayerSessionState {
+	t.Helper()
+	tracked := activeSessions.add(0, username)
+	t.Cleanup(func() { activeSessions.remove(tracked) })
+	ps := &PlayerSessionState{
+		cfg:        cfg,
+		tracked:    tracked,
+		username:   username,
+		logPrefix:  "[" + username + "] ",
+		opponents:  NewOpponentModel(username),
+		hands:      NewHandTracker(username),
+		positions:  NewPositionTracker(username),
+		chips:      newChipTracker(username, cfg.ChipsTolerance),
+		confirm:    newActionConfirmer(username, cfg.ConfirmWindow),
+		rng:        rng.ForWorker(cfg.Seed, 0),
+		addr:       addr,
+		strategy:   strategy,
+		startChips: -1,
+		result:     SessionResult{Player: username, FinalChips: -1},
+	}
+	conn, err := pokerclient.Dial(addr, cfg.ConnectTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	ps.conn = conn
+	cfg.ApplyTimeouts(conn)
+	if !ps.register("secret") {
+		t.Fatalf("registering %s failed", username)
+	}
+	return ps
+}
+
+// slowAllIn goes all-in after waiting delay, long enough for keepalives to
+// go out meanwhile.
+type slowAllIn struct {
+	retryMinimum
+	delay time.Duration
+}
+
+func (s slowAllIn) Bet(t Turn) pokerclient.Move {
+	time.Sleep(s.delay)
+	m, _ := pokerclient.AllIn(t.Chips)
+	return m
+}
+
+func TestRejectedMoveWithKeepalivePending(t *testing.T) {
+	// Every first bet is rejected; the retry is accepted.
+	srv := startMock(t, mockserver.Config{FailPercent: 100, Seed: 3})
+	cfg := testConfig()
+	cfg.Keepalive = keepalivePing
+	cfg.KeepaliveIdle = 10 * time.Millisecond
+	sent, ignoredErrors, retries := keepalivesSent.Load(), keepaliveErrors.Load(), betRetries.Load()
+
+	ps := playSession(t, cfg, srv.Addr(), "keepalive-0", slowAllIn{delay: 100 * time.Millisecond})
+
+	if st := srv.Stats(); st.Drops > 0 {
+		t.Fatalf("the mock server dropped the connection; pick another seed")
+	}
+	if keepalivesSent.Load() == sent {
+		t.Fatal("no keepalive was sent while the strategy thought")
+	}
+	if n := keepaliveErrors.Load() - ignoredErrors; n != 0 {
+		t.Errorf("%d rejections were blamed on a keepalive, want 0", n)
+	}
+	if n := betRetries.Load() - retries; n != 1 {
+		t.Errorf("the rejected all-in was retried %d times, want 1", n)
+	}
+	if ps.result.Outcome != outcomeCompleted {
+		t.Errorf("outcome = %s, want %s", ps.result.Outcome, outcomeCompleted)
+	}
+}
+
+// fakeSession returns a session, not connected, whose configuration runs on
+// a fake clock.
+func fakeSession(t *testing.T, cfg *Config) (*PlayerSessionState, *clock.Fake) {
+	t.Helper()
+	fake := clock.NewFake(time.Unix(1_700_000_000, 0))
+	cfg.clock = fake
+	tracked := activeSessions.add(0, "fake-0")
+	t.Cleanup(func() { activeSessions.remove(tracked) })
+	ps := &PlayerSessionState{
+		cfg:        cfg,
+		tracked:    tracked,
+		username:   "fake-0",
+		opponents:  NewOpponentModel("fake-0"),
+		hands:      NewHandTracker("fake-0"),
+		positions:  NewPositionTracker("fake-0"),
+		chips:      newChipTracker("fake-0", cfg.ChipsTolerance),
+		confirm:    newActionConfirmer("fake-0", cfg.ConfirmWindow),
+		rng:        rng.ForWorker(cfg.Seed, 0),
+		conn:       &pokerclient.Conn{},
+		strategy:   minBet{},
+		startChips: -1,
+		result:     SessionResult{Player: "fake-0", FinalChips: -1},
+	}
+	return ps, fake
+}
+
+func TestSessionTimeoutsFollowTheClock(t *testing.T) {
+	cfg := testConfig()
+	cfg.SeatTimeout = 5 * time.Second
+	cfg.ReadTimeout = 30 * time.Second
+	cfg.GameActivityTimeout = time.Minute
+	ps, fake := fakeSession(t, cfg)
+
+	ps.onAction(pokerclient.Action{Join: true})
+	if err := ps.beforeRead(); err != nil {
+		t.Fatalf("beforeRead right after joining: %v", err)
+	}
+	if ps.conn.ReadTimeout != 5*time.Second {
+		t.Errorf("read timeout right after joining = %s, want the seat timeout", ps.conn.ReadTimeout)
+	}
+	fake.Advance(3 * time.Second)
+	if err := ps.beforeRead(); err != nil {
+		t.Fatalf("beforeRead 3s after joining: %v", err)
+	}
+	if ps.conn.ReadTimeout != 2*time.Second {
+		t.Errorf("read timeout 3s after joining = %s, want what is left of the seat timeout", ps.conn.ReadTimeout)
+	}
+
+	ps.seated()
+	if err := ps.beforeRead(); err != nil || ps.conn.ReadTimeout != 30*time.Second {
+		t.Fatalf("beforeRead once seated = %v with a %s timeout, want nil with the read timeout", err, ps.conn.ReadTimeout)
+	}
+	fake.Advance(57 * time.Second)
+	if err := ps.beforeRead(); err != nil {
+		t.Fatalf("beforeRead as old as the game activity timeout: %v", err)
+	}
+	fake.Advance(time.Millisecond)
+	if err := ps.beforeRead(); err != (endSession{outcomeStalled}) {
+		t.Errorf("beforeRead past the game activity timeout = %v, want the session stalled", err)
+	}
+}
+
+func TestSeatTimeoutFollowsTheClock(t *testing.T) {
+	cfg := testConfig()
+	cfg.SeatTimeout = 5 * time.Second
+	ps, fake := fakeSession(t, cfg)
+
+	ps.onAction(pokerclient.Action{Join: true})
+	fake.Advance(5 * time.Second)
+	if err := ps.beforeRead(); err != (endSession{outcomeNeverSeated}) {
+		t.Errorf("beforeRead at the seat deadline = %v, want the session never seated", err)
+	}
+}
+
+func TestThinkTimeSleepsOnTheClock(t *testing.T) {
+	cfg := testConfig()
+	cfg.ThinkTime = 10 * time.Second
+	ps, fake := fakeSession(t, cfg)
+
+	moved := make(chan pokerclient.Move, 1)
+	go func() {
+		moved <- sessionStrategy{ps}.Bet(pokerclient.Turn{Stage: pokerclient.StagePreFlop, Chips: 1000, MinimumBet: 10})
+	}()
+	for start := time.Now(); fake.Waiters() == 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the think time was not slept on the session's clock")
+		}
+	}
+	select {
+	case m := <-moved:
+		t.Fatalf("%s was sent before the think time passed", m)
+	case <-time.After(10 * time.Millisecond):
+	}
+	fake.Advance(cfg.ThinkTime)
+	select {
+	case <-moved:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the move was not sent once the think time passed")
+	}
+}
+
+func TestIsMe(t *testing.T) {
+	tests := []struct {
+		playerID string
+		assigned string
+		want     bool
+		// loose is set when an exact comparison with the username would
+		// have missed the prompt.
+		loose bool
+	}{
+		{"team-1", "", true, false},
+		{"Team-1", "", true, true},
+		{" team-1 ", "", true, true},
+		{"team-2", "", false, false},
+		{"p-42", "", false, false},
+		{"p-42", "p-42", true, true},
+		{"P-42 ", "p-42", true, true},
+		{"team-1", "p-42", true, false},
+		{"p-43", "p-42", false, false},
+	}
+	for _, tt := range tests {
+		ps := &PlayerSessionState{username: "team-1", playerID: tt.assigned}
+		before := promptsMatchedLoosely.Load()
+		if got := ps.isMe(tt.playerID); got != tt.want {
+			t.Errorf("isMe(%q) with the assigned ID %q = %v, want %v", tt.playerID, tt.assigned, got, tt.want)
+		}
+		if loose := promptsMatchedLoosely.Load() > before; loose != tt.loose {
+			t.Errorf("isMe(%q) with the assigned ID %q counted a loose match: %v, want %v", tt.playerID, tt.assigned, loose, tt.loose)
+		}
+	}
+}
+
+func TestMaxHandsOutcome(t *testing.T) {
+	tests := []struct {
+		name         string
+		handsPerGame int
+		want         Outcome
+		wantCode     int
+	}{
+		// The hand played was the game's last: game over follows.
+		{"game ends with the hand", 1, outcomeCompleted, exitCompleted},
+		// More hands follow: the session leaves without seeing game over.
+		{"game goes on", 3, outcomeLeft, exitLeft},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startMock(t, mockserver.Config{HandsPerGame: tt.handsPerGame, Seed: 1})
+			cfg := testConfig()
+			cfg.MaxHands = 1
+
+			ps := playSession(t, cfg, srv.Addr(), "max-hands-0", minBet{})
+
+			if ps.result.Outcome != tt.want {
+				t.Errorf("outcome = %s, want %s", ps.result.Outcome, tt.want)
+			}
+			if ps.result.Hands != 1 {
+				t.Errorf("hands = %d, want 1", ps.result.Hands)
+			}
+			if code := outcomeExitCode(ps.result.Outcome); code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestUsageExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"unknown flag", []string{"-no-such-flag"}},
+		{"unknown strategy", []string{"-strategy", "no-such-strategy"}},
+		{"bad turn budget", []string{"-turn-budget", "2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := Run(tt.args); code != exitUsage {
+				t.Errorf("Run(%q) = %d, want %d", tt.args, code, exitUsage)
+			}
+		})
+	}
+	if exitUsage == exitRegistrationFailed {
+		t.Error("usage errors share the exit code of failed registrations")
+	}
+}
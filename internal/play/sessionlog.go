@@ -0,0 +1,65 @@
+package play
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// sessionLogsOpened is the number of per-player files opened in -log-dir,
+// and sessionLogsCapped is set once -log-dir-max-files was reached.
+var (
+	sessionLogsOpened atomic.Int64
+	sessionLogsCapped atomic.Bool
+)
+
+// logWriter returns where the session's verbose lines go. With cfg.LogDir
+// set, the first call opens <username>.log there, unless cfg.LogDirMaxFiles
+// files were already opened or the file cannot be created; the session then
+// logs to stdout, as without -log-dir.
+func (ps *PlayerSessionState) logWriter() io.Writer {
+	if ps.logOut != nil {
+		return ps.logOut
+	}
+	ps.logOut = os.Stdout
+	if ps.cfg.LogDir == "" {
+		return ps.logOut
+	}
+	if sessionLogsOpened.Add(1) > int64(ps.cfg.LogDirMaxFiles) {
+		sessionLogsOpened.Add(-1)
+		if sessionLogsCapped.CompareAndSwap(false, true) {
+			slog.Warn("per-player log file limit reached, further sessions log to stdout", "max_files", ps.cfg.LogDirMaxFiles)
+		}
+		return ps.logOut
+	}
+	path := filepath.Join(ps.cfg.LogDir, ps.username+".log")
+	f, err := os.Create(path)
+	if err != nil {
+		sessionLogsOpened.Add(-1)
+		slog.Warn("cannot create per-player log file, logging to stdout", "path", path, "error", err)
+		return ps.logOut
+	}
+	ps.logFile = f
+	ps.logOut = bufio.NewWriter(f)
+	ps.result.LogFile = path
+	return ps.logOut
+}
+
+// closeLog flushes and closes the session's log file, if it has one.
+func (ps *PlayerSessionState) closeLog() {
+	if ps.logFile == nil {
+		return
+	}
+	if w, ok := ps.logOut.(*bufio.Writer); ok {
+		if err := w.Flush(); err != nil {
+			slog.Warn("writing per-player log file", "path", ps.logFile.Name(), "error", err)
+		}
+	}
+	if err := ps.logFile.Close(); err != nil {
+		slog.Warn("closing per-player log file", "path", ps.logFile.Name(), "error", err)
+	}
+	ps.logFile = nil
+}
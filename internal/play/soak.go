@@ -0,0 +1,184 @@
+package play
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/metrics"
+	"elastic-ai-jam-2025/internal/report"
+	"elastic-ai-jam-2025/internal/rotate"
+)
+
+// soakPlayers is the player count of a -soak run: more than it can ever
+// launch.
+const soakPlayers = math.MaxInt32
+
+// checkSoak rejects the options a -soak run cannot honour and sets the
+// player count and concurrency it runs with.
+func (cfg *Config) checkSoak() error {
+	if cfg.SoakRollover <= 0 {
+		return errors.New("-soak-rollover must be positive")
+	}
+	for _, c := range []struct {
+		flag string
+		set  bool
+	}{
+		{"-waves", cfg.Waves != ""},
+		{"-script", cfg.Script != ""},
+		{"-resume-results", cfg.ResumeResults},
+		// Both keep every result in memory, which a soak cannot afford.
+		{"-enrich", cfg.Enrich},
+		{"-verify-chips", cfg.VerifyChips},
+		// A soak already replaces every session that ends.
+		{"-replace-busted", cfg.ReplaceBusted},
+	} {
+		if c.set {
+			return fmt.Errorf("-soak cannot be combined with %s", c.flag)
+		}
+	}
+	cfg.NumPlayers, cfg.MaxConcurrent = soakPlayers, cfg.Soak
+	return nil
+}
+
+// soakWindows rolls a -soak run's artifacts over every cfg.SoakRollover.
+// Each window gets its own results and time series files and a report of
+// the counters it moved, named after the time it started; the files at the
+// configured paths always hold the current window. The registry keeps
+// counting over the whole run, so the window's counters are the difference
+// from the snapshot taken when it started.
+type soakWindows struct {
+	cfg    *Config
+	config map[string]string // the effective flags, for the reports
+
+	// The window running: its number, start, and the counters then.
+	n          int
+	start      time.Time
+	prev       metrics.Snapshot
+	prevErrors map[errclass.Class]int64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startSoak opens the first window and rolls over at every boundary until
+// finish.
+func startSoak(cfg *Config, config map[string]string) *soakWindows {
+	w := &soakWindows{
+		cfg:        cfg,
+		config:     config,
+		n:          1,
+		start:      time.Now(),
+		prev:       registry.Snapshot(),
+		prevErrors: registrationFailures.Snapshot(),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *soakWindows) run() {
+	defer close(w.done)
+	for {
+		// Windows end on multiples of the rollover, so hourly files cover
+		// clock hours; the first one is usually partial.
+		next := time.Now().Truncate(w.cfg.SoakRollover).Add(w.cfg.SoakRollover)
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-t.C:
+			w.rollover(false, "", "")
+		case <-w.stop:
+			t.Stop()
+			return
+		}
+	}
+}
+
+// finish ends the current window, however short, once the sessions have
+// stopped. status and reason are those of its report.
+func (w *soakWindows) finish(status, reason string) {
+	close(w.stop)
+	<-w.done
+	w.rollover(true, status, reason)
+}
+
+// rollover archives the window's files, writes its report and starts the
+// next window. The last window's files are closed and renamed instead. Only
+// one rollover runs at a time: run's, then finish's.
+func (w *soakWindows) rollover(last bool, status, reason string) {
+	cfg := w.cfg
+	end := time.Now()
+	snap, errs := registry.Snapshot(), registrationFailures.Snapshot()
+
+	rep := report.New("play", w.config)
+	rep.RunID = cfg.RunID
+	rep.StartedAt = w.start.UTC()
+	for name, n := range snap.Counters {
+		rep.Counters[name] = n - w.prev.Counters[name]
+	}
+	for c, n := range errs {
+		if d := n - w.prevErrors[c]; d > 0 {
+			rep.Errors[string(c)] = d
+		}
+	}
+	cumulative := rep.SubSection("cumulative")
+	snap.Fill(cumulative)
+	cumulative.SetErrors(errs)
+	rep.Details["soak_window"] = strconv.Itoa(w.n)
+
+	fmt.Printf("[soak window %d, %s to %s] sessions launched: %d, registrations: %d ok, %d failed, games joined: %d, sessions active: %d\n",
+		w.n, w.start.Format(time.TimeOnly), end.Format(time.TimeOnly), rep.Counters["sessions_launched"],
+		rep.Counters["successful_registrations"], rep.Counters["failed_registrations"], rep.Counters["games_joined"], sessionsActive.Load())
+
+	if cfg.ResultsOut != "" {
+		archive := rotate.Name(cfg.ResultsOut, w.start)
+		var err error
+		if last {
+			if err = results.close(); err == nil {
+				err = os.Rename(cfg.ResultsOut, archive)
+			}
+		} else {
+			err = results.rollover(archive)
+		}
+		archived(rep, "results_file", archive, err)
+	}
+	if cfg.TimeseriesOut != "" {
+		archive := rotate.Name(cfg.TimeseriesOut, w.start)
+		var err error
+		if last {
+			if err = series.Close(); err == nil {
+				err = os.Rename(cfg.TimeseriesOut, archive)
+			}
+		} else {
+			err = series.Rollover(archive)
+		}
+		archived(rep, "timeseries_file", archive, err)
+	}
+	rep.Finish(status, reason)
+	if cfg.ReportOut != "" {
+		path := rotate.Name(cfg.ReportOut, w.start)
+		if err := rep.WriteFile(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		} else {
+			fmt.Printf("  %s\n", path)
+		}
+	}
+
+	w.n++
+	w.start, w.prev, w.prevErrors = end, snap, errs
+}
+
+// archived reports where a window's file went, or why it could not.
+func archived(rep *report.Report, key, archive string, err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	rep.Details[key] = archive
+	fmt.Printf("  %s\n", archive)
+}
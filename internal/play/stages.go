@@ -0,0 +1,70 @@
+package play
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"elastic-ai-jam-2025/internal/metrics"
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// stageOther collects the stages pokerclient.KnownStages does not list.
+const stageOther = "other"
+
+// stageCounter counts our actions at one betting stage.
+type stageCounter struct {
+	bets   *metrics.Counter
+	allIns *metrics.Counter
+	folds  *metrics.Counter
+}
+
+var (
+	// stageStats is built once and only read afterwards, so sessions can
+	// look up their counter without locking.
+	stageStats    = newStageStats()
+	unknownStages sync.Map
+)
+
+func newStageStats() map[string]*stageCounter {
+	m := make(map[string]*stageCounter, len(pokerclient.KnownStages)+1)
+	for _, s := range stageOrder() {
+		m[s] = &stageCounter{
+			bets:   registry.Counter(s+"_bets", "Bets sent at the "+s+" stage."),
+			allIns: registry.Counter(s+"_all_ins", "All-ins sent at the "+s+" stage."),
+			folds:  registry.Counter(s+"_folds", "Folds sent at the "+s+" stage."),
+		}
+	}
+	return m
+}
+
+// stageName maps a stage sent by the server to a stageStats key, warning the
+// first time an unknown stage is seen.
+func stageName(stage string) string {
+	if slices.Contains(pokerclient.KnownStages, stage) {
+		return stage
+	}
+	if _, seen := unknownStages.LoadOrStore(stage, true); !seen {
+		slog.Warn("server sent an unknown betting stage", "stage", stage)
+	}
+	return stageOther
+}
+
+// stageOrder lists the stageStats keys in the order of a hand.
+func stageOrder() []string {
+	return append(slices.Clone(pokerclient.KnownStages), stageOther)
+}
+
+func printStageStats(w io.Writer) {
+	fmt.Fprintln(w, "Actions by stage:")
+	for _, name := range stageOrder() {
+		c := stageStats[name]
+		bets, allIns, folds := c.bets.Load(), c.allIns.Load(), c.folds.Load()
+		if bets+allIns+folds == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "  %s: bets %d, all-ins %d, folds %d\n", name, bets, allIns, folds)
+	}
+}
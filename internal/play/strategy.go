@@ -0,0 +1,213 @@
+package play
+
+import (
+	"math/rand/v2"
+	"sort"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// Turn is what a strategy knows when the server asks it to bet.
+type Turn struct {
+	// Player is the session's own player ID.
+	Player     string
+	Stage      string // as sent by the server, see pokerclient.KnownStages
+	Chips      int
+	MinimumBet int
+	// Hand is the number of the current hand in the game, from 1, and
+	// History the moves reported in it so far.
+	Hand      int
+	History   HandHistory
+	Opponents *OpponentModel
+	// Position is where the session acts in the hand.
+	Position Position
+}
+
+// Strategy decides how a session answers bet prompts. A strategy instance
+// belongs to a single session, so it may keep per-session state.
+type Strategy interface {
+	// Bet returns the move to make.
+	Bet(t Turn) pokerclient.Move
+	// OnActionRejected is called once per prompt when the server answers
+	// the move returned by Bet with an error. It returns the move to send
+	// instead, or ok false to let the rejection stand.
+	OnActionRejected(t Turn, m pokerclient.Move, code int, message string) (retry pokerclient.Move, ok bool)
+	// OnActionUnconfirmed is called when the server prompts again as if
+	// the move m it was sent never arrived, t being the new prompt. It
+	// returns true to send m again, once, and false to fold.
+	OnActionUnconfirmed(t Turn, m pokerclient.Move) (resend bool)
+}
+
+// callMinimum matches the minimum bet: a check when it is zero, all-in when
+// it takes the whole stack.
+func callMinimum(t Turn) pokerclient.Move {
+	if t.MinimumBet <= 0 {
+		return pokerclient.Check()
+	}
+	if t.MinimumBet >= t.Chips {
+		m, _ := pokerclient.AllIn(t.Chips) // a fold with no chips
+		return m
+	}
+	m, _ := pokerclient.Bet(t.MinimumBet)
+	return m
+}
+
+// retryMinimum retries a rejected bet with the minimum bet, when that is a
+// different amount the player can afford, and resends a dropped move.
+// Strategies embed it to get those reactions.
+type retryMinimum struct{}
+
+func (retryMinimum) OnActionRejected(t Turn, m pokerclient.Move, _ int, _ string) (pokerclient.Move, bool) {
+	if m.Folds() || m.Amount() == t.MinimumBet || t.MinimumBet > t.Chips {
+		return pokerclient.Move{}, false
+	}
+	return callMinimum(t), true
+}
+
+func (retryMinimum) OnActionUnconfirmed(Turn, pokerclient.Move) bool { return true }
+
+// acceptRejection lets every rejection stand, and folds rather than resend
+// a dropped move.
+type acceptRejection struct{}
+
+func (acceptRejection) OnActionRejected(Turn, pokerclient.Move, int, string) (pokerclient.Move, bool) {
+	return pokerclient.Move{}, false
+}
+
+func (acceptRejection) OnActionUnconfirmed(Turn, pokerclient.Move) bool { return false }
+
+// strategies maps the -strategy names to their constructors.
+var strategies = map[string]func(cfg *Config, r *rand.Rand) Strategy{
+	"allin-once":     func(*Config, *rand.Rand) Strategy { return &allInOnce{} },
+	"min-bet":        func(*Config, *rand.Rand) Strategy { return minBet{} },
+	strategySpectate: func(*Config, *rand.Rand) Strategy { return spectate{} },
+	"exploit": func(cfg *Config, _ *rand.Rand) Strategy {
+		return &exploit{minObservations: cfg.ExploitMinObservations}
+	},
+	"positional": func(*Config, *rand.Rand) Strategy { return positional{} },
+	strategyFoldToAggression: func(cfg *Config, _ *rand.Rand) Strategy {
+		return foldToAggression{share: cfg.AggressionShare}
+	},
+}
+
+// strategyNames returns the registered strategy names, sorted.
+func strategyNames() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// allInOnce goes all-in at the first prompt and folds at every later one.
+// A rejected all-in is retried with the minimum bet.
+type allInOnce struct {
+	retryMinimum
+	done bool
+}
+
+func (s *allInOnce) Bet(t Turn) pokerclient.Move {
+	if s.done || t.Chips <= 0 { // Cannot bet 0 or less, must be at least minimum or fold
+		return pokerclient.Fold()
+	}
+	s.done = true
+	m, _ := pokerclient.AllIn(t.Chips)
+	return m
+}
+
+// strategySpectate names the spectator strategy. Besides folding, it makes
+// sessions rejoin after each game; see PlayerSessionState.onGameEnd.
+const strategySpectate = "spectate"
+
+// spectate folds at every prompt, so observing a game costs only the blinds.
+type spectate struct{ acceptRejection }
+
+func (spectate) Bet(Turn) pokerclient.Move { return pokerclient.Fold() }
+
+// minBet checks or calls the minimum pre-flop, checks later streets when it
+// is free and folds to any post-flop bet.
+type minBet struct{ retryMinimum }
+
+func (minBet) Bet(t Turn) pokerclient.Move {
+	switch {
+	case t.Stage == pokerclient.StagePreFlop && t.MinimumBet <= t.Chips:
+		return callMinimum(t)
+	case t.Stage != pokerclient.StagePreFlop && t.MinimumBet == 0:
+		return pokerclient.Check()
+	default:
+		return pokerclient.Fold()
+	}
+}
+
+// Fold-rate bounds of the exploit strategy.
+const (
+	exploitShoveFoldRate = 0.6 // shove into tables that fold at least this often
+	exploitTightFoldRate = 0.3 // fold against tables that fold less often than this
+)
+
+// exploit shoves against opponents who fold a lot and stays out of pots
+// against calling stations. Until minObservations moves of an opponent were
+// seen it plays like allInOnce.
+type exploit struct {
+	retryMinimum
+	minObservations int
+	fallback        allInOnce
+}
+
+func (s *exploit) Bet(t Turn) pokerclient.Move {
+	table, ok := t.Opponents.Table(s.minObservations)
+	if !ok {
+		return s.fallback.Bet(t)
+	}
+	switch rate := table.FoldRate(); {
+	case rate >= exploitShoveFoldRate && t.Chips > 0:
+		exploitShoves.Inc()
+		m, _ := pokerclient.AllIn(t.Chips)
+		return m
+	case rate < exploitTightFoldRate:
+		exploitTightFolds.Inc()
+		if t.MinimumBet == 0 {
+			return pokerclient.Check() // check when it is free
+		}
+		return pokerclient.Fold()
+	default:
+		return s.fallback.Bet(t)
+	}
+}
+
+// positional shoves from late position, where the fewest players are left
+// to act behind it, and otherwise checks when it is free and folds. It
+// folds whenever its position is unknown.
+type positional struct{ retryMinimum }
+
+func (positional) Bet(t Turn) pokerclient.Move {
+	switch {
+	case t.Position.Late() && t.Chips > 0:
+		m, _ := pokerclient.AllIn(t.Chips)
+		return m
+	case t.MinimumBet == 0:
+		return pokerclient.Check()
+	default:
+		return pokerclient.Fold()
+	}
+}
+
+const strategyFoldToAggression = "fold-to-aggression"
+
+// foldToAggression folds as soon as an opponent put more than share of its
+// stack in the hand, and otherwise plays like minBet.
+type foldToAggression struct {
+	retryMinimum
+	share float64
+}
+
+func (s foldToAggression) Bet(t Turn) pokerclient.Move {
+	for _, p := range t.History.Players() {
+		if p != t.Player && float64(t.History.Committed(p)) > s.share*float64(t.Chips) {
+			aggressionFolds.Inc()
+			return pokerclient.Fold()
+		}
+	}
+	return minBet{}.Bet(t)
+}
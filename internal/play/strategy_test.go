@@ -0,0 +1,96 @@
+package play
+
+import (
+	"fmt"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+func TestCallMinimum(t *testing.T) {
+	tests := []struct {
+		chips, minimum int
+		want           string
+	}{
+		{1000, 0, "check"},
+		{1000, 10, "bet 10"},
+		{10, 10, "all-in 10"},
+		{5, 10, "all-in 5"},
+		// No chips to go all-in with: not a check, which would not cover
+		// the bet.
+		{0, 10, "fold"},
+		{-1, 10, "fold"},
+	}
+	for _, tt := range tests {
+		if got := callMinimum(Turn{Chips: tt.chips, MinimumBet: tt.minimum}); got.String() != tt.want {
+			t.Errorf("callMinimum with %d chips and a minimum of %d = %s, want %s", tt.chips, tt.minimum, got, tt.want)
+		}
+	}
+}
+
+func TestWithinStack(t *testing.T) {
+	bet, _ := pokerclient.Bet(500)
+	tests := []struct {
+		move           pokerclient.Move
+		chips, minimum int
+		want           string
+	}{
+		{bet, 1000, 10, "bet 500"},
+		{bet, 200, 10, "all-in 200"},
+		{bet, 0, 10, "fold"},
+		{bet, 0, 0, "check"},
+		{pokerclient.Check(), 0, 0, "check"},
+		{pokerclient.Fold(), 1000, 0, "fold"},
+	}
+	for _, tt := range tests {
+		got := withinStack(tt.move, pokerclient.Turn{Chips: tt.chips, MinimumBet: tt.minimum})
+		if got.String() != tt.want {
+			t.Errorf("withinStack(%s) with %d chips and a minimum of %d = %s, want %s", tt.move, tt.chips, tt.minimum, got, tt.want)
+		}
+	}
+}
+
+// moves returns a hand history of moves, each "player action amount".
+func moves(t *testing.T, specs ...string) HandHistory {
+	t.Helper()
+	var h HandHistory
+	for _, s := range specs {
+		var a HandAction
+		if _, err := fmt.Sscan(s, &a.Player, &a.Action, &a.Amount); err != nil {
+			t.Fatalf("move %q: %v", s, err)
+		}
+		h = append(h, a)
+	}
+	return h
+}
+
+func TestFoldToAggression(t *testing.T) {
+	tests := []struct {
+		name    string
+		history HandHistory
+		chips   int
+		want    string
+	}{
+		{"no history", nil, 1000, "bet 10"},
+		{"small bets", moves(t, "a bet 100", "b call 100", "a raise 100"), 1000, "bet 10"},
+		{"a quarter of the stack", moves(t, "a bet 250"), 1000, "bet 10"},
+		{"over a quarter", moves(t, "a bet 251"), 1000, "fold"},
+		{"over a quarter over several moves", moves(t, "a bet 100", "b call 100", "a raise 200"), 1000, "fold"},
+		{"folded chips count nothing", moves(t, "a fold 500"), 1000, "bet 10"},
+		{"our own chips count nothing", moves(t, "me bet 900"), 1000, "bet 10"},
+		{"against a short stack", moves(t, "a bet 30"), 100, "fold"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folds := aggressionFolds.Load()
+			turn := Turn{Player: "me", Stage: pokerclient.StagePreFlop, Chips: tt.chips, MinimumBet: 10, History: tt.history}
+			m := foldToAggression{share: 0.25}.Bet(turn)
+			if m.String() != tt.want {
+				t.Errorf("move = %s, want %s", m, tt.want)
+			}
+			if counted := aggressionFolds.Load() > folds; counted != m.Folds() {
+				t.Errorf("fold counted: %v, want %v", counted, m.Folds())
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package play
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// Kinds of stream anomaly, as named in the transcript.
+const (
+	anomalyDuplicate      = "duplicate"
+	anomalyTimeRegression = "time_regression"
+	anomalyRepeatedPrompt = "repeated_prompt"
+)
+
+// streamChecker looks for signs that the server dropped, duplicated or
+// reordered the messages of one session. The protocol has no sequence
+// numbers, so it relies on what the messages do carry:
+//
+//   - a message identical to the one just before it is a duplicate;
+//   - a timestamp, when the message has one, older than the latest seen is
+//     a time regression;
+//   - a bet prompt for the same stage, chips and minimum bet as the prompt
+//     just answered, with no message in between, is a repeated prompt: the
+//     server asked again for a hand we already acted on.
+//
+// It is only used by its session's goroutine.
+type streamChecker struct {
+	prev     []byte
+	lastTime time.Time
+
+	// prompt is the last prompt for us, and sincePrompt the messages
+	// received after it.
+	prompt      promptKey
+	hasPrompt   bool
+	sincePrompt int
+}
+
+type promptKey struct {
+	stage      string
+	chips      int
+	minimumBet int
+}
+
+// check returns the anomaly resp shows, or "" if none. player is the
+// session's username.
+func (c *streamChecker) check(resp *pokerclient.ServerResponse, player string) string {
+	duplicate := c.prev != nil && bytes.Equal(c.prev, resp.Raw)
+	c.prev = append(c.prev[:0], resp.Raw...)
+
+	regressed := false
+	if t, ok := messageTime(resp); ok {
+		regressed = t.Before(c.lastTime)
+		if !regressed {
+			c.lastTime = t
+		}
+	}
+
+	repeated := false
+	if resp.Type == pokerclient.TypeActionPlayerBet && pokerclient.SamePlayer(resp.State.Player.PlayerID, player) {
+		key := promptKey{resp.Stage, resp.State.Player.Chips, resp.MinimumBet}
+		repeated = c.hasPrompt && c.sincePrompt == 0 && key == c.prompt
+		c.prompt, c.hasPrompt, c.sincePrompt = key, true, 0
+	} else {
+		c.sincePrompt++
+	}
+
+	switch {
+	case duplicate:
+		return anomalyDuplicate
+	case regressed:
+		return anomalyTimeRegression
+	case repeated:
+		return anomalyRepeatedPrompt
+	}
+	return ""
+}
+
+// messageTime returns the timestamp of resp, taken from a top-level or
+// event "timestamp" field holding RFC 3339 text or Unix seconds or
+// milliseconds.
+func messageTime(resp *pokerclient.ServerResponse) (time.Time, bool) {
+	raw, ok := resp.Extra["timestamp"]
+	if !ok {
+		var event struct {
+			Timestamp json.RawMessage `json:"timestamp"`
+		}
+		if resp.DecodeEvent(&event) != nil || event.Timestamp == nil {
+			return time.Time{}, false
+		}
+		raw = event.Timestamp
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		return t, err == nil
+	}
+	n, err := strconv.ParseFloat(string(raw), 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	// Seconds stay below 1e11 until the year 5138.
+	if n >= 1e11 {
+		return time.UnixMilli(int64(n)), true
+	}
+	return time.Unix(0, int64(n*float64(time.Second))), true
+}
+
+// checkStream runs the session's stream checker on resp and counts and
+// records any anomaly.
+func (ps *PlayerSessionState) checkStream(resp *pokerclient.ServerResponse) {
+	kind := ps.stream.check(resp, ps.username)
+	if kind == "" {
+		return
+	}
+	streamAnomalies[kind].Inc()
+	transcriptOut.WriteAnomaly(ps.username, ps.gameID, kind, resp.Raw)
+	ps.logVerbose("Stream anomaly %s: %s", kind, resp.Raw)
+}
+
+// printStreamAnomalies writes the anomaly totals, if there were any.
+func printStreamAnomalies(w io.Writer) {
+	dup, back, rep := streamAnomalies[anomalyDuplicate].Load(), streamAnomalies[anomalyTimeRegression].Load(), streamAnomalies[anomalyRepeatedPrompt].Load()
+	if dup+back+rep == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Stream anomalies: %d duplicates, %d timestamps going backwards, %d repeated prompts\n", dup, back, rep)
+}
@@ -0,0 +1,120 @@
+package play
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"elastic-ai-jam-2025/internal/clock"
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// Session states, as tracked by the watchdog.
+const (
+	stateRegistering int32 = iota
+	stateSeating
+	statePlaying
+	stateParked
+)
+
+var stateNames = [...]string{"registering", "seating", "playing", "parked"}
+
+// trackedSession is the watchdog's view of a running session.
+type trackedSession struct {
+	id       int
+	username string
+	clock    clock.Clock
+
+	state        atomic.Int32
+	lastActivity atomic.Int64 // UnixNano
+	conn         atomic.Pointer[pokerclient.Conn]
+	// reaped is set when the watchdog closed the connection; the session
+	// then ends as outcomeReaped.
+	reaped atomic.Bool
+}
+
+// touch records activity in state.
+func (s *trackedSession) touch(state int32) {
+	s.state.Store(state)
+	s.lastActivity.Store(s.clock.Now().UnixNano())
+}
+
+// sessionTracker holds the running sessions. Its size is the
+// sessions_active gauge. It is safe for concurrent use.
+type sessionTracker struct {
+	// clock times the sessions' activity and paces the watchdog.
+	clock    clock.Clock
+	mu       sync.Mutex
+	sessions map[int]*trackedSession
+}
+
+var activeSessions = sessionTracker{clock: clock.Real, sessions: make(map[int]*trackedSession)}
+
+// add starts tracking session id.
+func (t *sessionTracker) add(id int, username string) *trackedSession {
+	s := &trackedSession{id: id, username: username, clock: t.clock}
+	s.touch(stateRegistering)
+	t.mu.Lock()
+	t.sessions[id] = s
+	t.mu.Unlock()
+	sessionsActive.Inc()
+	return s
+}
+
+// remove stops tracking s.
+func (t *sessionTracker) remove(s *trackedSession) {
+	t.mu.Lock()
+	delete(t.sessions, s.id)
+	t.mu.Unlock()
+	sessionsActive.Dec()
+}
+
+// reap closes the connection of every session with no activity for longer
+// than limit, and returns how many it reaped.
+func (t *sessionTracker) reap(limit time.Duration) int {
+	now := t.clock.Now()
+	cutoff := now.Add(-limit).UnixNano()
+	t.mu.Lock()
+	var idle []*trackedSession
+	for _, s := range t.sessions {
+		if s.lastActivity.Load() < cutoff && !s.reaped.Load() {
+			idle = append(idle, s)
+		}
+	}
+	t.mu.Unlock()
+	for _, s := range idle {
+		s.reaped.Store(true)
+		sessionsReaped.Inc()
+		slog.Warn("reaping idle session", "player", s.username, "state", stateNames[s.state.Load()],
+			"idle_for", now.Sub(time.Unix(0, s.lastActivity.Load())).Round(time.Second))
+		if c := s.conn.Load(); c != nil {
+			c.Close()
+		}
+	}
+	return len(idle)
+}
+
+// watch scans for idle sessions until the returned function is called. A
+// non-positive limit disables the watchdog.
+func (t *sessionTracker) watch(limit time.Duration) (stop func()) {
+	if limit <= 0 {
+		return func() {}
+	}
+	ticker := t.clock.NewTicker(min(limit/4, 30*time.Second))
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C():
+				t.reap(limit)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
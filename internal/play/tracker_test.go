@@ -0,0 +1,32 @@
+package play
+
+import (
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/clock"
+)
+
+func TestWatchdogReapsIdleSessions(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1_700_000_000, 0))
+	tracker := sessionTracker{clock: fake, sessions: make(map[int]*trackedSession)}
+	idle := tracker.add(1, "idle")
+	busy := tracker.add(2, "busy")
+	defer tracker.remove(idle)
+	defer tracker.remove(busy)
+
+	stop := tracker.watch(time.Minute)
+	defer stop()
+	fake.Advance(30 * time.Second)
+	busy.touch(statePlaying)
+	fake.Advance(45 * time.Second)
+
+	for start := time.Now(); !idle.reaped.Load(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the session idle for 75s of a 1m limit was not reaped")
+		}
+	}
+	if busy.reaped.Load() {
+		t.Error("the session active 45s ago was reaped")
+	}
+}
@@ -0,0 +1,102 @@
+package play
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/report"
+	"elastic-ai-jam-2025/internal/rng"
+)
+
+// verifyChips compares the final chips of a sample of sessions with the
+// leaderboard. Players missing from the leaderboard are looked up again
+// after cfg.VerifyChipsDelay, up to cfg.VerifyChipsRetries times, since the
+// leaderboard is indexed with some lag; after that they are unverifiable.
+func verifyChips(ctx context.Context, cfg *Config, api *httpapi.Client) *report.ChipReconciliation {
+	pending := make(map[string]int) // player -> session chips
+	for _, r := range results.sample(cfg.VerifyChipsSample, cfg.Seed) {
+		pending[r.Player] = r.FinalChips
+	}
+	rec := &report.ChipReconciliation{}
+lookup:
+	for attempt := 0; attempt <= cfg.VerifyChipsRetries && len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(cfg.VerifyChipsDelay):
+			case <-ctx.Done():
+				break lookup // the rest is unverifiable
+			}
+		}
+		lb, err := api.Leaderboard(cfg.VerifyLeaderboardLimit)
+		if err != nil {
+			fmt.Printf("Chip verification: fetching the leaderboard failed: %v\n", err)
+			continue
+		}
+		for _, e := range lb.Entries {
+			sessionChips, ok := pending[e.PlayerID]
+			if !ok {
+				continue
+			}
+			delete(pending, e.PlayerID)
+			check := report.ChipCheck{Player: e.PlayerID, SessionChips: sessionChips, APIChips: e.Chips, Status: report.ChipsMatch}
+			if sessionChips != e.Chips {
+				check.Status = report.ChipsMismatch
+			}
+			rec.Players = append(rec.Players, check)
+		}
+	}
+	for player, chips := range pending {
+		rec.Players = append(rec.Players, report.ChipCheck{Player: player, SessionChips: chips, Status: report.ChipsUnverifiable})
+	}
+	sort.Slice(rec.Players, func(i, j int) bool { return rec.Players[i].Player < rec.Players[j].Player })
+
+	for _, c := range rec.Players {
+		rec.Checked++
+		switch c.Status {
+		case report.ChipsMatch:
+			rec.Matched++
+		case report.ChipsMismatch:
+			rec.Mismatched++
+		default:
+			rec.Unverifiable++
+		}
+	}
+	if verifiable := rec.Matched + rec.Mismatched; verifiable > 0 {
+		rec.MatchRate = 100 * float64(rec.Matched) / float64(verifiable)
+	}
+	return rec
+}
+
+func printChipReconciliation(w io.Writer, rec *report.ChipReconciliation) {
+	fmt.Fprintf(w, "Chip verification: %d checked, %d match, %d mismatch, %d unverifiable (match rate %.1f%%)\n",
+		rec.Checked, rec.Matched, rec.Mismatched, rec.Unverifiable, rec.MatchRate)
+	for _, c := range rec.Players {
+		if c.Status == report.ChipsMismatch {
+			fmt.Fprintf(w, "  %s: session %d, API %d (%+d)\n", c.Player, c.SessionChips, c.APIChips, c.APIChips-c.SessionChips)
+		}
+	}
+}
+
+// sample returns up to n registered sessions that know their chips, chosen
+// reproducibly from seed.
+func (l *resultLog) sample(n int, seed int64) []*SessionResult {
+	l.mu.Lock()
+	var known []*SessionResult
+	for _, r := range l.results {
+		if r.Registered && r.FinalChips >= 0 {
+			known = append(known, r)
+		}
+	}
+	l.mu.Unlock()
+	sort.Slice(known, func(i, j int) bool { return known[i].Player < known[j].Player })
+	if len(known) <= n {
+		return known
+	}
+	r := rng.ForWorker(seed, -1)
+	r.Shuffle(len(known), func(i, j int) { known[i], known[j] = known[j], known[i] })
+	return known[:n]
+}
@@ -0,0 +1,153 @@
+package play
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"elastic-ai-jam-2025/internal/latency"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// waveStats measures one wave of a -waves run. Waves never overlap, so the
+// sessions of the running wave record into currentWave.
+type waveStats struct {
+	size         int
+	launched     int
+	failed       int64 // failed registrations and sessions never seated
+	elapsed      time.Duration
+	registration latency.Histogram
+	seat         latency.Histogram
+}
+
+// currentWave is the wave running, or nil outside a -waves run.
+var currentWave atomic.Pointer[waveStats]
+
+// waveResults are the waves run so far, and waveStopReason why escalation
+// stopped.
+var (
+	waveResults    []*waveStats
+	waveStopReason string
+)
+
+// failureRate is the share of the wave's sessions that failed to register
+// or were never seated.
+func (w *waveStats) failureRate() float64 {
+	if w.launched == 0 {
+		return 0
+	}
+	return float64(w.failed) / float64(w.launched)
+}
+
+// parseWaves parses a comma-separated list of increasing wave sizes.
+func parseWaves(s string) ([]int, error) {
+	var sizes []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid wave size %q in -waves", f)
+		}
+		if len(sizes) > 0 && n <= sizes[len(sizes)-1] {
+			return nil, fmt.Errorf("-waves sizes must increase, got %d after %d", n, sizes[len(sizes)-1])
+		}
+		sizes = append(sizes, n)
+	}
+	return sizes, nil
+}
+
+// runWaves runs one wave of sessions per size, each with as many sessions
+// as concurrent slots, and waits for each to drain before the next starts,
+// so a wave's latencies are not measured under the previous one's load. It
+// stops escalating once a wave exceeds cfg.WaveMaxFailureRate or
+// cfg.WaveMaxSeatP95, and returns the number of sessions launched.
+//
+// Each wave's players get their own username prefix, so no two waves share
+// a player.
+func runWaves(ctx context.Context, cfg *Config, sizes []int, completed map[string]bool) int {
+	runStart := time.Now()
+	total := 0
+	waveStopReason = "all waves run"
+	for i, size := range sizes {
+		wcfg := *cfg
+		wcfg.NumPlayers, wcfg.MaxConcurrent = size, size
+		wcfg.BaseUsername = fmt.Sprintf("%sw%d-", cfg.BaseUsername, i+1)
+		fmt.Printf("\n=== Wave %d of %d: %d players ===\n", i+1, len(sizes), size)
+
+		w := &waveStats{size: size}
+		failedBefore := failedRegistrations.Load() + sessionsNeverSeated.Load()
+		currentWave.Store(w)
+		start := time.Now()
+		w.launched = runPlayers(ctx, &wcfg, completed)
+		w.elapsed = time.Since(start)
+		currentWave.Store(nil)
+		w.failed = failedRegistrations.Load() + sessionsNeverSeated.Load() - failedBefore
+		waveResults = append(waveResults, w)
+		total += w.launched
+
+		if ctx.Err() != nil {
+			waveStopReason = "interrupted"
+			break
+		}
+		if reason := waveCeiling(cfg, w); reason != "" {
+			waveStopReason = fmt.Sprintf("wave %d (%d players): %s", i+1, size, reason)
+			fmt.Printf("Stopping escalation: %s\n", reason)
+			break
+		}
+	}
+	startTime = runStart
+	return total
+}
+
+// waveCeiling returns which ceiling w hit, if any.
+func waveCeiling(cfg *Config, w *waveStats) string {
+	if rate := w.failureRate(); rate > cfg.WaveMaxFailureRate {
+		return fmt.Sprintf("failure rate %.1f%% above %.1f%%", rate*100, cfg.WaveMaxFailureRate*100)
+	}
+	if cfg.WaveMaxSeatP95 > 0 && w.seat.Count() > 0 {
+		if p95 := w.seat.Quantile(0.95); p95 > cfg.WaveMaxSeatP95 {
+			return fmt.Sprintf("time to seat p95 %s above %s", p95.Round(time.Millisecond), cfg.WaveMaxSeatP95)
+		}
+	}
+	return ""
+}
+
+// printWaves writes one row per wave.
+func printWaves(w io.Writer) {
+	if len(waveResults) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Waves:")
+	fmt.Fprintf(w, "  %4s %8s %10s %10s %10s %10s %9s %10s\n", "wave", "players", "reg p50", "reg p95", "seat p50", "seat p95", "failures", "duration")
+	for i, ws := range waveResults {
+		fmt.Fprintf(w, "  %4d %8d %10s %10s %10s %10s %8.1f%% %10s\n", i+1, ws.launched,
+			ws.registration.Quantile(0.5).Round(time.Millisecond), ws.registration.Quantile(0.95).Round(time.Millisecond),
+			ws.seat.Quantile(0.5).Round(time.Millisecond), ws.seat.Quantile(0.95).Round(time.Millisecond),
+			ws.failureRate()*100, ws.elapsed.Round(time.Millisecond))
+	}
+	fmt.Fprintf(w, "Escalation stopped: %s\n", waveStopReason)
+}
+
+// fillWaves adds a "wave N" sub-report per wave to rep.
+func fillWaves(rep *report.Report) {
+	if len(waveResults) == 0 {
+		return
+	}
+	for i, ws := range waveResults {
+		sec := rep.SubSection(fmt.Sprintf("wave %d", i+1))
+		sec.Counters["players"] = int64(ws.size)
+		sec.Counters["sessions_launched"] = int64(ws.launched)
+		sec.Counters["failed_sessions"] = ws.failed
+		sec.Counters["duration_ms"] = ws.elapsed.Milliseconds()
+		if ws.registration.Count() > 0 {
+			sec.Latencies["registration"] = ws.registration.Summary()
+		}
+		if ws.seat.Count() > 0 {
+			sec.Latencies["time_to_seat"] = ws.seat.Summary()
+		}
+	}
+	rep.Details["wave_stop_reason"] = waveStopReason
+}
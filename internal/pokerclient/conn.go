@@ -0,0 +1,375 @@
+package pokerclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/netaddr"
+)
+
+// Conn is a connection to the TCP game server.
+type Conn struct {
+	conn net.Conn
+	// dec reads the stream of JSON objects. The server newline-delimits
+	// them, but the decoder does not depend on it: objects coalesced into
+	// one segment, split across reads or larger than any line buffer are
+	// all returned one by one. src is what it reads: the connection, after
+	// what remained buffered when a malformed line was skipped.
+	dec *json.Decoder
+	src io.Reader
+
+	// IOTimeout, when positive, is applied as a deadline to every read and
+	// write. Callers that prefer one overall deadline leave it at zero and
+	// call SetDeadline themselves.
+	IOTimeout time.Duration
+	// ReadTimeout, when positive, replaces IOTimeout for reads, for phases
+	// where long quiet periods are normal, such as waiting between hands.
+	ReadTimeout time.Duration
+	// WriteTimeout, when positive, replaces IOTimeout for writes.
+	WriteTimeout time.Duration
+	// RegisterTimeout, when positive, replaces the read timeout while
+	// Register waits for the answer, which a loaded server can be slow to
+	// send.
+	RegisterTimeout time.Duration
+
+	// DialWait is how long Dial queued for a DialLimit slot before
+	// dialing, which callers leave out of the latencies they measure.
+	DialWait time.Duration
+
+	// Logf, when set, receives a line for every message sent and received
+	// and for every I/O error.
+	Logf func(format string, args ...interface{})
+
+	// wmu serialises writes, which may come from a keepalive goroutine as
+	// well as from the session, and guards wbuf and enc, the encode buffer
+	// reused by every write. lastWrite is the UnixNano time of the last
+	// successful write.
+	wmu       sync.Mutex
+	wbuf      bytes.Buffer
+	enc       *json.Encoder
+	lastWrite int64
+
+	// raw and resp are reused by every ReadMessage call, so a long session
+	// does not allocate a new buffer and response per event. A buffer grown
+	// past maxKeptReadBuffer by a large message is dropped instead.
+	raw  json.RawMessage
+	resp ServerResponse
+	// lineOpen is set when more than the end of the line followed the last
+	// object decoded: a syntax error there is trailing garbage of a line
+	// that already gave a message, not a line without any.
+	lineOpen bool
+}
+
+// maxKeptReadBuffer is the largest read buffer a connection keeps between
+// messages, so one large game-over payload does not stay pinned for the
+// rest of a long session.
+const maxKeptReadBuffer = 16 << 10
+
+// DialFunc opens the TCP connections of Dial, in the order of
+// netaddr.Prefer. Fault-injection builds wrap it to simulate network
+// failures; nothing else should replace it.
+var DialFunc = netaddr.Dial
+
+// Dial connects to the game server at addr. The dial timeout starts once
+// DialLimit lets the dial through.
+func Dial(addr string, dialTimeout time.Duration) (*Conn, error) {
+	wait := DialLimit.acquire()
+	c, err := DialFunc(addr, dialTimeout)
+	DialLimit.release()
+	if err != nil {
+		return nil, err
+	}
+	conn := NewConn(c)
+	conn.DialWait = wait
+	return conn, nil
+}
+
+// NewConn wraps an established connection.
+func NewConn(c net.Conn) *Conn {
+	conn := &Conn{conn: c, src: c, dec: json.NewDecoder(c)}
+	conn.enc = json.NewEncoder(&conn.wbuf)
+	return conn
+}
+
+// Close closes the underlying connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// SetDeadline sets the read and write deadline of the underlying connection.
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+func (c *Conn) logf(format string, args ...interface{}) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+	}
+}
+
+// SendJSON writes data as a single JSON line.
+func (c *Conn) SendJSON(data interface{}) error {
+	return c.send(data, true)
+}
+
+// SendQuiet writes data like SendJSON but never calls Logf, so it is safe to
+// use from a goroutine other than the one owning the session's logger.
+func (c *Conn) SendQuiet(data interface{}) error {
+	return c.send(data, false)
+}
+
+func (c *Conn) send(data interface{}, log bool) error {
+	log = log && c.Logf != nil
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.wbuf.Reset()
+	// Encode appends the newline that terminates a message.
+	if err := c.enc.Encode(data); err != nil {
+		if log {
+			c.logf("Error marshalling JSON for sending: %v", err)
+		}
+		return err
+	}
+	if log {
+		c.logf("Sending: %s", bytes.TrimSuffix(c.wbuf.Bytes(), []byte("\n")))
+	}
+	timeout := c.IOTimeout
+	if c.WriteTimeout > 0 {
+		timeout = c.WriteTimeout
+	}
+	if timeout > 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+			if log {
+				c.logf("Error setting write deadline: %v", err)
+			}
+			return err
+		}
+	}
+	if _, err := c.conn.Write(c.wbuf.Bytes()); err != nil {
+		if log {
+			c.logf("Error sending data: %v", err)
+		}
+		return err
+	}
+	atomic.StoreInt64(&c.lastWrite, time.Now().UnixNano())
+	return nil
+}
+
+// SinceLastWrite returns how long ago the last successful write finished,
+// or zero if nothing was written yet.
+func (c *Conn) SinceLastWrite() time.Duration {
+	last := atomic.LoadInt64(&c.lastWrite)
+	if last == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, last))
+}
+
+// SetTCPKeepAlive enables TCP keep-alive probes every period on the
+// underlying connection. It is a no-op on connections that are not TCP.
+func (c *Conn) SetTCPKeepAlive(period time.Duration) error {
+	tc, ok := c.conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+	if err := tc.SetKeepAlive(true); err != nil {
+		return err
+	}
+	return tc.SetKeepAlivePeriod(period)
+}
+
+// ReadMessage reads and decodes the next message from the server. It
+// returns io.EOF when the server closed the connection cleanly after a
+// complete message, and io.ErrUnexpectedEOF when it closed it mid-message.
+//
+// The returned response is owned by the Conn and is overwritten by the next
+// call; callers that keep a message across reads must copy it.
+func (c *Conn) ReadMessage() (*ServerResponse, error) {
+	timeout := c.IOTimeout
+	if c.ReadTimeout > 0 {
+		timeout = c.ReadTimeout
+	}
+	if timeout > 0 {
+		if err := c.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			c.logf("Error setting read deadline: %v", err)
+			return nil, err
+		}
+	}
+	if cap(c.raw) > maxKeptReadBuffer {
+		c.raw = nil
+	}
+	for {
+		err := c.dec.Decode(&c.raw)
+		var syntaxErr *json.SyntaxError
+		switch {
+		case err == nil && len(c.raw) > 0 && c.raw[0] == '{':
+			c.lineOpen = !c.lineEnds()
+		case err == nil:
+			// A number or string, say, is garbage. Only what follows it on
+			// its line is skipped: when the value ended the line, the next
+			// line is left to the decoder.
+			line := string(c.raw)
+			if !c.lineEnds() {
+				line += " " + c.skipLine()
+			}
+			if c.lineOpen {
+				c.lineOpen = false
+				Decoding.salvaged.Add(1)
+				Decoding.sample(line)
+				c.logf("Skipped trailing garbage after a server message: %q", line)
+				continue
+			}
+			err = fmt.Errorf("a JSON %s is not a message", jsonKind(c.raw))
+			return nil, c.undecodable(line, err)
+		case !errors.As(err, &syntaxErr):
+			c.logf("Error reading server response: %v", err)
+			return nil, err
+		case c.lineOpen:
+			// Trailing garbage after the line's object: skip it.
+			line := c.skipLine()
+			c.lineOpen = false
+			Decoding.salvaged.Add(1)
+			Decoding.sample(line)
+			c.logf("Skipped trailing garbage after a server message: %q", line)
+			continue
+		default:
+			return nil, c.undecodable(c.skipLine(), err)
+		}
+		break
+	}
+	if c.Logf != nil {
+		c.logf("Received: %s", c.raw)
+	}
+
+	c.resp = ServerResponse{}
+	if err := json.Unmarshal(c.raw, &c.resp); err != nil {
+		c.logf("Error unmarshalling server response '%s': %v", c.raw, err)
+		Decoding.failed.Add(1)
+		Decoding.sample(string(c.raw))
+		return nil, err
+	}
+	extra, err := unknownFields(c.raw)
+	if err != nil {
+		c.logf("Error unmarshalling server response '%s': %v", c.raw, err)
+		Decoding.failed.Add(1)
+		Decoding.sample(string(c.raw))
+		return nil, err
+	}
+	c.resp.Raw = c.raw
+	c.resp.Extra = extra
+	return &c.resp, nil
+}
+
+// undecodable records line, which held no message, and returns the error
+// of ReadMessage for it.
+func (c *Conn) undecodable(line string, err error) error {
+	c.lineOpen = false
+	Decoding.failed.Add(1)
+	Decoding.sample(line)
+	c.logf("Error reading server response: undecodable line %q: %v", line, err)
+	if len(line) > maxSampleLen {
+		line = line[:maxSampleLen]
+	}
+	return &DecodeError{Line: line, Err: err}
+}
+
+// jsonKind names the kind of the JSON value raw.
+func jsonKind(raw []byte) string {
+	switch raw[0] {
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	default:
+		return "number"
+	}
+}
+
+// RegistrationError is returned by Register when the server answers the
+// registration with anything other than a leaderboard entry start.
+type RegistrationError struct {
+	Type    string
+	Code    int
+	Message string
+}
+
+func (e *RegistrationError) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("registration failed: Code %d, Message: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("registration resulted in unexpected response: Type='%s', Message='%s'", e.Type, e.Message)
+}
+
+// FailureClass implements errclass.Classifier.
+func (e *RegistrationError) FailureClass() errclass.Class {
+	switch {
+	case e.Code != 0 && e.PasswordPolicy():
+		return errclass.PasswordPolicy
+	case e.Code != 0:
+		return errclass.Rejected
+	}
+	return errclass.Unexpected
+}
+
+// policyWords are the words, besides "password", of the messages rejecting
+// a password for its form rather than for not matching the account's.
+var policyWords = []string{"policy", "weak", "too short", "too long", "at least", "at most", "must contain", "must include", "must have", "require", "complex", "strength", "character", "uppercase", "lowercase", "digit", "symbol", "special"}
+
+// PasswordPolicy reports whether the server rejected the password for not
+// meeting its rules, as its message tells. A wrong password for an existing
+// account ("invalid password") is not.
+func (e *RegistrationError) PasswordPolicy() bool {
+	msg := strings.ToLower(e.Message)
+	if !strings.Contains(msg, "password") {
+		return false
+	}
+	for _, w := range policyWords {
+		if strings.Contains(msg, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// Register logs in as username, creating the player if needed. It returns
+// the server's response, and a *RegistrationError if the server rejected it.
+// A timeout waiting for the answer is classified as
+// errclass.RegisterTimeout.
+func (c *Conn) Register(username, password string) (*ServerResponse, error) {
+	if err := c.SendJSON(RegistrationMsg{Username: username, Password: password}); err != nil {
+		return nil, err
+	}
+	readTimeout := c.ReadTimeout
+	if c.RegisterTimeout > 0 {
+		c.ReadTimeout = c.RegisterTimeout
+	}
+	resp, err := c.ReadMessage()
+	c.ReadTimeout = readTimeout
+	if err != nil {
+		return nil, errclass.TimeoutIn(errclass.RegisterTimeout, err)
+	}
+	// According to protocol, a successful registration returns an "event_player_leaderboard_entry_start"
+	if resp.Type != TypeLeaderboardEntryStart {
+		return resp, &RegistrationError{Type: resp.Type, Code: resp.Code, Message: resp.Message}
+	}
+	return resp, nil
+}
+
+// Join asks the server to seat the player at a game. No response is expected
+// immediately; the server starts sending game events instead.
+func (c *Conn) Join() error {
+	return c.SendJSON(ActionMsg{Action: ActionJoin})
+}
@@ -0,0 +1,211 @@
+package pokerclient
+
+import (
+	"io"
+	"net"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/errclass"
+)
+
+// sampleMessage is a bet prompt as the server sends it.
+const sampleMessage = `{"type":"action_player_bet","game_id":"g-1","stage":"flop","minimum_bet":20,` +
+	`"state":{"player":{"player_id":"team-1","chips":980}},` +
+	`"event":{"pot":60,"players":[{"player_id":"team-1","chips":980},{"player_id":"team-2","chips":1020}]}}` + "\n"
+
+// repeatConn is a connection whose reads return data over and over.
+type repeatConn struct {
+	net.Conn
+	data []byte
+	off  int
+}
+
+func (c *repeatConn) Read(p []byte) (int, error) {
+	n := copy(p, c.data[c.off:])
+	c.off = (c.off + n) % len(c.data)
+	return n, nil
+}
+
+func TestReadMessageLongLine(t *testing.T) {
+	// Far longer than the 64 KiB a bufio.Scanner allows by default.
+	pad := strings.Repeat("x", 200<<10)
+	big := `{"type":"event_game_over","game_id":"g-1","event":{"pad":"` + pad + `"}}` + "\n"
+	got, err := readAll(t, big, sampleMessage, big)
+	if err != io.EOF || len(got) != 3 || got[0] != TypeGameOver || got[1] != TypeActionPlayerBet || got[2] != TypeGameOver {
+		t.Fatalf("read %q, %v; want the three messages", got, err)
+	}
+
+	// The buffer grown by a large message is not kept for the small ones.
+	conn := NewConn(&repeatConn{data: []byte(big + sampleMessage)})
+	resp, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Raw) != len(big)-1 {
+		t.Errorf("message is %d bytes, want %d", len(resp.Raw), len(big)-1)
+	}
+	if _, err := conn.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if cap(conn.raw) > maxKeptReadBuffer {
+		t.Errorf("read buffer of %d bytes kept after a small message", cap(conn.raw))
+	}
+}
+
+// raceEnabled is set by race_test.go in race-detector builds, whose
+// shadow memory and speed make heap measurements meaningless.
+var raceEnabled bool
+
+// heapInUse returns the heap in use after a collection.
+func heapInUse() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapInuse
+}
+
+// TestReadMessageHeapBounded reads 100k messages, one in a thousand a large
+// game over, as a long session would, and checks that nothing read is
+// retained across messages.
+func TestReadMessageHeapBounded(t *testing.T) {
+	if testing.Short() || raceEnabled {
+		t.Skip("reads 100k messages and measures the heap")
+	}
+	const messages = 100_000
+	action := `{"type":"event_player_action","game_id":"g-1","event":{"player_id":"team-2","action":"raise","amount":40}}` + "\n"
+	players := strings.Repeat(`{"player_id":"team-2","chips":1020,"hand":["Ah","Kd"]},`, 500)
+	gameOver := `{"type":"event_game_over","game_id":"g-1","event":{"players":[` + strings.TrimSuffix(players, ",") + `]}}` + "\n"
+	stream := strings.Repeat(action, 999) + gameOver
+	conn := NewConn(&repeatConn{data: []byte(stream)})
+
+	var before uint64
+	for i := range messages {
+		if i == 1000 {
+			before = heapInUse()
+		}
+		resp, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Type == TypeGameOver {
+			continue
+		}
+		if a, ok := PlayerActionOf(resp); !ok || a.Action != MoveRaise {
+			t.Fatalf("message %d: action %+v, %v", i, a, ok)
+		}
+	}
+	// Allow for the runtime's own growth, far below the 100 MB of events read.
+	if after := heapInUse(); after > before+1<<20 {
+		t.Errorf("heap in use grew from %d to %d bytes over %d messages", before, after, messages)
+	}
+}
+
+func BenchmarkReadMessage(b *testing.B) {
+	conn := NewConn(&repeatConn{data: []byte(sampleMessage)})
+	b.ReportAllocs()
+	b.SetBytes(int64(len(sampleMessage)))
+	for i := 0; i < b.N; i++ {
+		if _, err := conn.ReadMessage(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestReadMessageFraming(t *testing.T) {
+	a, b := `{"type":"a"}`, `{"type":"b"}`
+	big := `{"type":"big","event":{"pad":"` + strings.Repeat("y", 100<<10) + `"}}`
+	tests := []struct {
+		name    string
+		chunks  []string
+		want    []string
+		wantErr error
+	}{
+		{"one message per write", []string{a + "\n", b + "\n"}, []string{"a", "b"}, io.EOF},
+		{"coalesced in one write", []string{a + "\n" + b + "\n" + a + "\n"}, []string{"a", "b", "a"}, io.EOF},
+		{"split across writes", []string{`{"ty`, `pe":"a`, `"}` + "\n" + `{"type"`, `:"b"}` + "\n"}, []string{"a", "b"}, io.EOF},
+		{"split at the newline", []string{a, "\n" + b, "\n"}, []string{"a", "b"}, io.EOF},
+		{"over 64 KiB in many writes", chunk(big+"\n"+a+"\n", 4096), []string{"big", "a"}, io.EOF},
+		{"last message without a newline", []string{a + "\n" + b}, []string{"a", "b"}, io.EOF},
+		{"closed mid-message", []string{a + "\n" + `{"type":"b"`}, []string{"a"}, io.ErrUnexpectedEOF},
+		{"closed mid-line of a large message", []string{a + "\n", big[:70<<10]}, []string{"a"}, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(t, tt.chunks...)
+			if err != tt.wantErr {
+				t.Errorf("final error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// chunk cuts s into pieces of n bytes.
+func chunk(s string, n int) []string {
+	var out []string
+	for len(s) > n {
+		out = append(out, s[:n])
+		s = s[n:]
+	}
+	return append(out, s)
+}
+
+// discardConn is a connection that accepts every write.
+type discardConn struct{ net.Conn }
+
+func (discardConn) Write(p []byte) (int, error)      { return len(p), nil }
+func (discardConn) SetWriteDeadline(time.Time) error { return nil }
+
+func BenchmarkSendJSON(b *testing.B) {
+	bet, _ := Bet(20)
+	benchmarks := []struct {
+		name    string
+		timeout time.Duration
+		msg     interface{}
+	}{
+		{"bet", 0, bet.Msg()},
+		{"join", 0, ActionMsg{Action: ActionJoin}},
+		{"bet with write deadline", 2 * time.Second, bet.Msg()},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			conn := NewConn(discardConn{})
+			conn.WriteTimeout = bm.timeout
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := conn.SendJSON(bm.msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistrationErrorPasswordPolicy(t *testing.T) {
+	tests := []struct {
+		code    int
+		message string
+		want    errclass.Class
+	}{
+		{400, "password does not meet the policy: at least 12 characters", errclass.PasswordPolicy},
+		{400, "Password too short", errclass.PasswordPolicy},
+		{422, "password must contain an uppercase letter and a digit", errclass.PasswordPolicy},
+		{400, "weak password", errclass.PasswordPolicy},
+		{401, "invalid password", errclass.Rejected},
+		{400, "username must contain letters", errclass.Rejected},
+		{409, "player already connected", errclass.Rejected},
+		{0, "password too short", errclass.Unexpected},
+	}
+	for _, tt := range tests {
+		err := &RegistrationError{Type: "error", Code: tt.code, Message: tt.message}
+		if got := errclass.Classify(err); got != tt.want {
+			t.Errorf("%d %q classified %s, want %s", tt.code, tt.message, got, tt.want)
+		}
+	}
+}
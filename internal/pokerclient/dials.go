@@ -0,0 +1,66 @@
+package pokerclient
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"elastic-ai-jam-2025/internal/latency"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// DialLimit, when set, bounds the handshakes Dial has in flight. The
+// commands that dial in bulk set it from -max-concurrent-dials.
+var DialLimit *DialLimiter
+
+// DialLimiter bounds the TCP handshakes in flight at once, so thousands of
+// sessions starting together do not time out their own dials in the local
+// SYN backlog, and measures how long dials queued for a slot. Established
+// connections do not hold a slot. A nil *DialLimiter does not limit.
+type DialLimiter struct {
+	slots chan struct{}
+	wait  latency.Histogram
+}
+
+// NewDialLimiter returns a limiter of n handshakes in flight, or nil if n
+// is not positive.
+func NewDialLimiter(n int) *DialLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return &DialLimiter{slots: make(chan struct{}, n)}
+}
+
+// acquire waits for a slot and returns how long that took.
+func (l *DialLimiter) acquire() time.Duration {
+	if l == nil {
+		return 0
+	}
+	start := time.Now()
+	l.slots <- struct{}{}
+	waited := time.Since(start)
+	l.wait.Record(waited)
+	return waited
+}
+
+func (l *DialLimiter) release() {
+	if l != nil {
+		<-l.slots
+	}
+}
+
+// Print writes how long dials queued for a slot.
+func (l *DialLimiter) Print(w io.Writer) {
+	if l == nil {
+		return
+	}
+	fmt.Fprintf(w, "Dial queue wait (at most %d dials in flight): %s\n", cap(l.slots), l.wait.Summary())
+}
+
+// Fill records the queue waits in rep as the "dial_queue_wait" latency.
+func (l *DialLimiter) Fill(rep *report.Report) {
+	if l == nil || l.wait.Count() == 0 {
+		return
+	}
+	rep.Latencies["dial_queue_wait"] = l.wait.Summary()
+}
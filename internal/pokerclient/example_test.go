@@ -0,0 +1,66 @@
+package pokerclient_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"elastic-ai-jam-2025/internal/mockserver"
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// callMinimum is a Strategy that checks when it is free and otherwise calls
+// the minimum bet, or goes all-in when that takes its whole stack.
+type callMinimum struct{}
+
+func (callMinimum) Bet(t pokerclient.Turn) pokerclient.Move {
+	if t.MinimumBet <= 0 {
+		return pokerclient.Check()
+	}
+	if t.MinimumBet >= t.Chips {
+		m, _ := pokerclient.AllIn(t.Chips)
+		return m
+	}
+	m, _ := pokerclient.Bet(t.MinimumBet)
+	return m
+}
+
+func (callMinimum) OnActionRejected(pokerclient.Turn, pokerclient.Move, int, string) (pokerclient.Move, bool) {
+	return pokerclient.Move{}, false
+}
+
+// ExampleRun plays two games against a local mock server, with a strategy
+// and a hook counting the games as they end.
+func ExampleRun() {
+	srv, err := mockserver.Start("127.0.0.1:0", mockserver.Config{
+		HandsPerGame: 3, StartChips: 1000, MinimumBet: 10, Bots: 2, Dealer: mockserver.DealerFold, Seed: 1,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer srv.Close()
+
+	result, err := pokerclient.Run(context.Background(), pokerclient.Config{
+		Addr:        srv.Addr(),
+		Credentials: pokerclient.Credentials{Username: "team-1", Password: "secret"},
+		Strategy:    callMinimum{},
+		Hooks: pokerclient.Hooks{
+			OnGameEnd: func(*pokerclient.ServerResponse) error {
+				fmt.Println("game over")
+				return nil
+			},
+		},
+		DialTimeout: 5 * time.Second,
+		ReadTimeout: 5 * time.Second,
+		Games:       2,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("registered: %v, games: %d, hands: %d, moves: %d\n", result.Registered, result.GamesPlayed, result.Hands, result.Moves)
+	// Output:
+	// game over
+	// game over
+	// registered: true, games: 2, hands: 6, moves: 6
+}
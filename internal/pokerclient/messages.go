@@ -0,0 +1,252 @@
+// Package pokerclient implements the client side of the jam's TCP game
+// protocol: newline-delimited JSON messages exchanged over a plain TCP
+// connection.
+package pokerclient
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// Message types sent by the server.
+const (
+	TypeLeaderboardEntryStart = "event_player_leaderboard_entry_start"
+	TypeLeaderboardEntryEnd   = "event_player_leaderboard_entry_end"
+	TypeActionPlayerBet       = "action_player_bet"
+	TypeGameOver              = "event_game_over"
+	TypePotWon                = "event_pot_won"
+)
+
+// Actions sent by the client.
+const (
+	ActionJoin = "join"
+	ActionBet  = "bet"
+	// ActionPing is not part of the documented protocol; it is only sent as
+	// an opt-in keepalive to learn how the server reacts.
+	ActionPing = "ping"
+)
+
+// RegistrationMsg is sent to the server to register/login.
+type RegistrationMsg struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ActionMsg is for sending actions like "join", "bet", "fold".
+type ActionMsg struct {
+	Action string `json:"action"`
+	Amount *int   `json:"amount,omitempty"` // Pointer to allow omitting for "join"
+}
+
+// ServerResponse is a generic structure to capture server's JSON responses.
+type ServerResponse struct {
+	Type string `json:"type,omitempty"` // e.g., "event_player_leaderboard_entry_start"
+	// Event is kept undecoded: game-over payloads are large, and most
+	// consumers only need a field or two. See DecodeEvent.
+	Event   json.RawMessage `json:"event,omitempty"`
+	Code    int             `json:"code,omitempty"`    // e.g., 400 for errors
+	Message string          `json:"message,omitempty"` // Error message
+	GameID  string          `json:"game_id,omitempty"` // Present in some events
+
+	// Fields for action_player_bet
+	Stage      string                   `json:"stage,omitempty"`
+	State      ActionPlayerBetFullState `json:"state,omitempty"`
+	MinimumBet int                      `json:"minimum_bet,omitempty"`
+
+	// Raw is the message as received. Like the response itself it is only
+	// valid until the next ReadMessage call.
+	Raw json.RawMessage `json:"-"`
+	// Extra holds the top-level fields that have no named field above, so
+	// a field added by the server is visible before we bind it.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Kinds of JSON values, as checked by Validate.
+const (
+	KindString = "string"
+	KindNumber = "number"
+	KindBool   = "bool"
+	KindObject = "object"
+	KindArray  = "array"
+)
+
+// FieldShape is the expected shape of one field of a server message. Path is
+// the dotted JSON path of the field, such as "state.player.chips".
+type FieldShape struct {
+	Path     string
+	Kind     string
+	Required bool
+	// Enum, when set, lists the values a string field may take.
+	Enum []string
+}
+
+// Betting stages an action_player_bet may name.
+const (
+	StagePreFlop = "pre_flop"
+	StageFlop    = "flop"
+	StageTurn    = "turn"
+	StageRiver   = "river"
+)
+
+// KnownStages lists the betting stages in the order of a hand.
+var KnownStages = []string{StagePreFlop, StageFlop, StageTurn, StageRiver}
+
+// commonShape applies to every server message.
+var commonShape = []FieldShape{
+	{Path: "type", Kind: KindString, Required: true, Enum: []string{
+		TypeLeaderboardEntryStart, TypeLeaderboardEntryEnd, TypeActionPlayerBet, TypeGameOver, TypePotWon,
+	}},
+	{Path: "event", Kind: KindObject},
+	{Path: "code", Kind: KindNumber},
+	{Path: "message", Kind: KindString},
+	{Path: "game_id", Kind: KindString},
+}
+
+// MessageShapes lists the fields each message type must carry on top of
+// commonShape. Keep it in sync with ServerResponse and the types it embeds.
+var MessageShapes = map[string][]FieldShape{
+	TypeLeaderboardEntryStart: nil,
+	TypeLeaderboardEntryEnd:   nil,
+	TypeActionPlayerBet: {
+		{Path: "stage", Kind: KindString, Required: true, Enum: KnownStages},
+		{Path: "state", Kind: KindObject, Required: true},
+		{Path: "state.player", Kind: KindObject, Required: true},
+		{Path: "state.player.player_id", Kind: KindString, Required: true},
+		{Path: "state.player.chips", Kind: KindNumber, Required: true},
+		{Path: "minimum_bet", Kind: KindNumber},
+	},
+	TypeGameOver: {
+		{Path: "event", Kind: KindObject, Required: true},
+	},
+	TypePotWon: {
+		{Path: "event", Kind: KindObject, Required: true},
+	},
+}
+
+// DecodeEvent unmarshals the event of r into v, which should only declare
+// the fields its caller needs. It returns an error when r has no event.
+// Fields of the wrong type are left unset, and reported like json.Unmarshal
+// does.
+func (r *ServerResponse) DecodeEvent(v any) error {
+	if len(r.Event) == 0 {
+		return errors.New("message has no event")
+	}
+	return json.Unmarshal(r.Event, v)
+}
+
+// knownResponseFields are the top-level keys bound to a ServerResponse field.
+var knownResponseFields = jsonFieldNames(reflect.TypeOf(ServerResponse{}))
+
+// jsonFieldNames returns the JSON keys of the fields of struct type t.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// unknownFields returns the top-level fields of raw that knownResponseFields
+// does not list, or nil if there are none.
+func unknownFields(raw []byte) (map[string]json.RawMessage, error) {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return nil, err
+	}
+	var extra map[string]json.RawMessage
+	for k, v := range all {
+		if knownResponseFields[k] {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]json.RawMessage)
+		}
+		extra[k] = v
+	}
+	return extra, nil
+}
+
+// SamePlayer reports whether the player IDs a and b name the same player:
+// equal once trimmed, ignoring case. The server has been seen echoing our
+// usernames with their case normalized.
+func SamePlayer(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// AssignedPlayerID returns the player_id a leaderboard entry start event
+// gives the registered player, from its event or its top-level fields, or
+// "" if it carries none.
+func AssignedPlayerID(resp *ServerResponse) string {
+	if resp == nil || resp.Type != TypeLeaderboardEntryStart {
+		return ""
+	}
+	var ev struct {
+		PlayerID string `json:"player_id"`
+	}
+	if resp.DecodeEvent(&ev) == nil && ev.PlayerID != "" {
+		return strings.TrimSpace(ev.PlayerID)
+	}
+	var id string
+	if raw, ok := resp.Extra["player_id"]; ok && json.Unmarshal(raw, &id) == nil {
+		return strings.TrimSpace(id)
+	}
+	return ""
+}
+
+// PlayerStateForBet is part of the action_player_bet event.
+type PlayerStateForBet struct {
+	PlayerID string `json:"player_id"`
+	Chips    int    `json:"chips"`
+	// Hand []string `json:"hand"` // Not strictly needed for this strategy
+}
+
+// ActionPlayerBetFullState is part of the action_player_bet event.
+type ActionPlayerBetFullState struct {
+	Player PlayerStateForBet `json:"player"`
+	// Table []string `json:"table"`
+	// Players []map[string]interface{} `json:"players"` // Other players' states
+}
+
+// Moves other players make, as reported in the action field of an event.
+const (
+	MoveFold  = "fold"
+	MoveCheck = "check"
+	MoveCall  = "call"
+	MoveBet   = "bet"
+	MoveRaise = "raise"
+	MoveAllIn = "all_in"
+)
+
+// PlayerAction is a move made by a player at the table.
+type PlayerAction struct {
+	PlayerID string
+	Action   string
+	Amount   int
+}
+
+// PlayerActionOf extracts the move reported by resp, if any. The server
+// reports moves as events whose event object carries player_id and action;
+// any message type with that shape is accepted, so a renamed event type
+// still feeds the opponent model.
+func PlayerActionOf(resp *ServerResponse) (PlayerAction, bool) {
+	var ev struct {
+		PlayerID string  `json:"player_id"`
+		Action   string  `json:"action"`
+		Amount   float64 `json:"amount"`
+	}
+	resp.DecodeEvent(&ev) // a mistyped field is left empty, like a missing one
+	if ev.PlayerID == "" || ev.Action == "" {
+		return PlayerAction{}, false
+	}
+	return PlayerAction{PlayerID: ev.PlayerID, Action: strings.ToLower(ev.Action), Amount: int(ev.Amount)}, true
+}
+
+// pint returns a pointer to an int, useful for omitempty JSON fields.
+func pint(i int) *int {
+	return &i
+}
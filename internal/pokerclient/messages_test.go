@@ -0,0 +1,77 @@
+package pokerclient
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSamePlayer(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"team-1", "team-1", true},
+		{"Team-1", "team-1", true},
+		{"TEAM-1", "team-1", true},
+		{" team-1", "team-1", true},
+		{"team-1\n", "team-1 ", true},
+		{"team-1", "team-2", false},
+		{"team-1", "team-10", false},
+		{"team 1", "team-1", false},
+		{"", "team-1", false},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		if got := SamePlayer(tt.a, tt.b); got != tt.want {
+			t.Errorf("SamePlayer(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestAssignedPlayerID(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{`{"type":"event_player_leaderboard_entry_start","event":{"player_id":"p-42"}}`, "p-42"},
+		{`{"type":"event_player_leaderboard_entry_start","player_id":" p-42 "}`, "p-42"},
+		{`{"type":"event_player_leaderboard_entry_start"}`, ""},
+		{`{"type":"event_pot_won","player_id":"p-42"}`, ""},
+	}
+	for _, tt := range tests {
+		var resp ServerResponse
+		if err := json.Unmarshal([]byte(tt.raw), &resp); err != nil {
+			t.Fatal(err)
+		}
+		resp.Extra, _ = unknownFields([]byte(tt.raw)) // as ReadMessage does
+		if got := AssignedPlayerID(&resp); got != tt.want {
+			t.Errorf("AssignedPlayerID(%s) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+	if got := AssignedPlayerID(nil); got != "" {
+		t.Errorf("AssignedPlayerID(nil) = %q", got)
+	}
+}
+
+func TestSessionIsMe(t *testing.T) {
+	tests := []struct {
+		playerID string
+		assigned string
+		want     bool
+	}{
+		{"team-1", "", true},
+		{"Team-1 ", "", true},
+		{"p-42", "", false},
+		{"p-42", "p-42", true},
+		{"P-42", "p-42", true},
+		{"team-1", "p-42", true},
+		{"team-2", "p-42", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		s := &session{cfg: Config{Credentials: Credentials{Username: "team-1"}, PlayerID: tt.assigned}}
+		if got := s.isMe(tt.playerID); got != tt.want {
+			t.Errorf("isMe(%q) with the assigned ID %q = %v, want %v", tt.playerID, tt.assigned, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package pokerclient
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidBet is returned by Bet and AllIn for an amount that is not
+// positive.
+var ErrInvalidBet = errors.New("bet amount must be positive")
+
+type moveKind int
+
+const (
+	moveFold moveKind = iota
+	moveCheck
+	moveBet
+	moveAllIn
+)
+
+// Move is an answer to a bet prompt, built with Fold, Check, Bet or AllIn.
+// The zero Move folds. Msg is the only place that knows how a move is
+// encoded on the wire.
+//
+// There is no Call: the protocol has no amount-less call that we know of,
+// so calling is a Bet of the amount to call.
+type Move struct {
+	kind   moveKind
+	amount int
+}
+
+// Fold gives up the hand.
+func Fold() Move { return Move{kind: moveFold} }
+
+// Check passes without betting, when the minimum bet is zero.
+func Check() Move { return Move{kind: moveCheck} }
+
+// AllIn bets all of chips, which must be positive: a player with no chips
+// can only fold or check.
+func AllIn(chips int) (Move, error) {
+	if chips <= 0 {
+		return Move{}, fmt.Errorf("%w, got %d chips to go all-in with", ErrInvalidBet, chips)
+	}
+	return Move{kind: moveAllIn, amount: chips}, nil
+}
+
+// Bet bets amount, which must be positive.
+func Bet(amount int) (Move, error) {
+	if amount <= 0 {
+		return Move{}, fmt.Errorf("%w, got %d", ErrInvalidBet, amount)
+	}
+	return Move{kind: moveBet, amount: amount}, nil
+}
+
+// Folds reports whether m is a fold.
+func (m Move) Folds() bool { return m.kind == moveFold }
+
+// IsAllIn reports whether m was built with AllIn. A Bet of the whole stack
+// also puts the player all-in; callers that care compare Amount with the
+// chips.
+func (m Move) IsAllIn() bool { return m.kind == moveAllIn }
+
+// Amount is the number of chips m puts in: zero for a fold or a check.
+func (m Move) Amount() int { return m.amount }
+
+func (m Move) String() string {
+	switch m.kind {
+	case moveCheck:
+		return "check"
+	case moveBet:
+		return fmt.Sprintf("bet %d", m.amount)
+	case moveAllIn:
+		return fmt.Sprintf("all-in %d", m.amount)
+	default:
+		return "fold"
+	}
+}
+
+// Msg encodes m: every move is a bet action, a fold being a bet of -1 and a
+// check a bet of 0.
+func (m Move) Msg() ActionMsg {
+	amount := m.amount
+	switch m.kind {
+	case moveFold:
+		amount = -1
+	case moveCheck:
+		amount = 0
+	}
+	return ActionMsg{Action: ActionBet, Amount: pint(amount)}
+}
@@ -0,0 +1,90 @@
+package pokerclient
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"testing"
+)
+
+// wire returns the bytes SendJSON writes for m.
+func wire(t *testing.T, m Move) string {
+	t.Helper()
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	sent := make(chan error, 1)
+	go func() { sent <- NewConn(client).SendJSON(m.Msg()) }()
+	line, err := bufio.NewReader(server).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-sent; err != nil {
+		t.Fatal(err)
+	}
+	return line
+}
+
+func TestMoveWire(t *testing.T) {
+	must := func(m Move, err error) Move {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	tests := []struct {
+		name string
+		move Move
+		want string
+	}{
+		{"fold", Fold(), `{"action":"bet","amount":-1}` + "\n"},
+		{"zero move", Move{}, `{"action":"bet","amount":-1}` + "\n"},
+		{"check", Check(), `{"action":"bet","amount":0}` + "\n"},
+		{"bet", must(Bet(25)), `{"action":"bet","amount":25}` + "\n"},
+		{"all-in", must(AllIn(1000)), `{"action":"bet","amount":1000}` + "\n"},
+		{"all-in of one chip", must(AllIn(1)), `{"action":"bet","amount":1}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wire(t, tt.move); got != tt.want {
+				t.Errorf("sent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNonPositiveAmountsRejected(t *testing.T) {
+	for _, amount := range []int{0, -1, -1000} {
+		if m, err := Bet(amount); !errors.Is(err, ErrInvalidBet) || !m.Folds() {
+			t.Errorf("Bet(%d) = %s, %v; want a fold and ErrInvalidBet", amount, m, err)
+		}
+		if m, err := AllIn(amount); !errors.Is(err, ErrInvalidBet) || !m.Folds() {
+			t.Errorf("AllIn(%d) = %s, %v; want a fold and ErrInvalidBet", amount, m, err)
+		}
+	}
+}
+
+func TestMoveAccessors(t *testing.T) {
+	bet, _ := Bet(25)
+	allIn, _ := AllIn(1000)
+	tests := []struct {
+		move   Move
+		str    string
+		folds  bool
+		allIn  bool
+		amount int
+	}{
+		{Fold(), "fold", true, false, 0},
+		{Check(), "check", false, false, 0},
+		{bet, "bet 25", false, false, 25},
+		{allIn, "all-in 1000", false, true, 1000},
+	}
+	for _, tt := range tests {
+		m := tt.move
+		if m.String() != tt.str || m.Folds() != tt.folds || m.IsAllIn() != tt.allIn || m.Amount() != tt.amount {
+			t.Errorf("%s: folds %v, all-in %v, amount %d; want %s: folds %v, all-in %v, amount %d",
+				m, m.Folds(), m.IsAllIn(), m.Amount(), tt.str, tt.folds, tt.allIn, tt.amount)
+		}
+	}
+}
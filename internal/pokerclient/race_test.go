@@ -0,0 +1,5 @@
+//go:build race
+
+package pokerclient
+
+func init() { raceEnabled = true }
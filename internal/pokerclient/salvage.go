@@ -0,0 +1,122 @@
+package pokerclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Decoding counts the malformed input of every connection: lines whose
+// valid object was kept while trailing garbage was skipped, and lines no
+// object could be read from. It keeps the first few skipped lines for the
+// report.
+var Decoding = &DecodeStats{}
+
+// maxDecodeSamples is how many skipped lines DecodeStats keeps, and
+// maxSampleLen how much of each.
+const (
+	maxDecodeSamples = 5
+	maxSampleLen     = 512
+)
+
+// maxGarbage bounds how far a connection reads looking for the end of a
+// malformed line before it gives up on it.
+const maxGarbage = 64 << 10
+
+// DecodeStats counts malformed server input. It is safe for concurrent
+// use.
+type DecodeStats struct {
+	salvaged, failed atomic.Int64
+
+	mu      sync.Mutex
+	samples []string
+}
+
+// DecodeError is returned by ReadMessage for a line that holds no valid
+// JSON object. The connection skips the line, so reading may go on.
+type DecodeError struct {
+	// Line is the skipped line, cut to a few hundred bytes.
+	Line string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("undecodable server line %q: %v", e.Line, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// FailureClass implements errclass.Classifier.
+func (e *DecodeError) FailureClass() errclass.Class {
+	return errclass.Decode
+}
+
+func (s *DecodeStats) sample(line string) {
+	if len(line) > maxSampleLen {
+		line = line[:maxSampleLen]
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.samples) < maxDecodeSamples {
+		s.samples = append(s.samples, line)
+	}
+}
+
+// Print writes the malformed lines seen, if any.
+func (s *DecodeStats) Print(w io.Writer) {
+	salvaged, failed := s.salvaged.Load(), s.failed.Load()
+	if salvaged+failed == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Malformed server lines: %d salvaged (trailing garbage skipped), %d undecodable\n", salvaged, failed)
+}
+
+// Fill records the malformed lines in rep: the salvaged_lines and
+// undecodable_lines counters, and the first skipped lines as the
+// malformed_line_<n> details.
+func (s *DecodeStats) Fill(rep *report.Report) {
+	salvaged, failed := s.salvaged.Load(), s.failed.Load()
+	if salvaged+failed == 0 {
+		return
+	}
+	rep.Counters["salvaged_lines"] = salvaged
+	rep.Counters["undecodable_lines"] = failed
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, line := range s.samples {
+		rep.Details[fmt.Sprintf("malformed_line_%d", i+1)] = line
+	}
+}
+
+// lineEnds reports whether nothing but the end of the line follows the
+// object just decoded, as far as the decoder has read.
+func (c *Conn) lineEnds() bool {
+	rest, _ := io.ReadAll(c.dec.Buffered())
+	rest = bytes.TrimLeft(rest, " \t\r")
+	return len(rest) == 0 || rest[0] == '\n'
+}
+
+// skipLine drops the rest of the current line, which the decoder failed
+// on, and restarts the decoder after it. It returns the dropped text.
+func (c *Conn) skipLine() string {
+	buf, _ := io.ReadAll(c.dec.Buffered())
+	buf = bytes.TrimLeft(buf, " \t\r\n")
+	for bytes.IndexByte(buf, '\n') < 0 && len(buf) < maxGarbage {
+		chunk := make([]byte, 4096)
+		n, err := c.src.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		if err != nil {
+			break
+		}
+	}
+	line, rest, _ := bytes.Cut(buf, []byte("\n"))
+	c.src = io.MultiReader(bytes.NewReader(rest), c.src)
+	c.dec = json.NewDecoder(c.src)
+	return string(bytes.TrimRight(line, "\r"))
+}
@@ -0,0 +1,101 @@
+package pokerclient
+
+import (
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// readAll writes chunks to a connection one Write at a time, closes it,
+// and returns what ReadMessage gives until the first error other than a
+// *DecodeError: the type of each message read, "!" for each
+// undecodable line, and that final error.
+func readAll(t *testing.T, chunks ...string) ([]string, error) {
+	t.Helper()
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		for _, c := range chunks {
+			if _, err := server.Write([]byte(c)); err != nil {
+				break
+			}
+		}
+		server.Close()
+	}()
+	conn := NewConn(client)
+	var got []string
+	for {
+		resp, err := conn.ReadMessage()
+		var decErr *DecodeError
+		switch {
+		case errors.As(err, &decErr):
+			got = append(got, "!")
+		case err != nil:
+			return got, err
+		default:
+			got = append(got, resp.Type)
+		}
+	}
+}
+
+func TestReadMessageSalvage(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "trailing garbage after an object",
+			input: `{"type":"a"} oops` + "\n" + `{"type":"b"}` + "\n",
+			want:  []string{"a", "b"},
+		},
+		{
+			name:  "two objects on one line",
+			input: `{"type":"a"}{"type":"b"}` + "\n" + `{"type":"c"}` + "\n",
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			name:  "pure garbage line",
+			input: `{"type":"a"}` + "\n" + "not json at all\n" + `{"type":"b"}` + "\n",
+			want:  []string{"a", "!", "b"},
+		},
+		{
+			name:  "scalar line followed by a valid message",
+			input: "12\n" + `{"type":"a"}` + "\n",
+			want:  []string{"!", "a"},
+		},
+		{
+			name:  "string line followed by a valid message",
+			input: `"hello"` + "\n" + `{"type":"a"}` + "\n",
+			want:  []string{"!", "a"},
+		},
+		{
+			name:  "array line followed by a valid message",
+			input: "[1,2]\n" + `{"type":"a"}` + "\n",
+			want:  []string{"!", "a"},
+		},
+		{
+			name:  "scalar with garbage on its line",
+			input: "12 oops\n" + `{"type":"a"}` + "\n",
+			want:  []string{"!", "a"},
+		},
+		{
+			name:  "scalar trailing an object",
+			input: `{"type":"a"} 12` + "\n" + `{"type":"b"}` + "\n",
+			want:  []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(t, tt.input)
+			if err != io.EOF {
+				t.Errorf("final error = %v, want io.EOF", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
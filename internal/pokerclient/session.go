@@ -0,0 +1,299 @@
+package pokerclient
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Credentials identify the player a session plays as.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Turn is a bet prompt addressed to the session.
+type Turn struct {
+	GameID     string
+	Stage      string // see KnownStages
+	Chips      int
+	MinimumBet int
+}
+
+// Strategy decides how a session run by Run answers bet prompts. It is only
+// called from Run's goroutine.
+type Strategy interface {
+	// Bet returns the move to make.
+	Bet(t Turn) Move
+	// OnActionRejected is called once per prompt when the server answers
+	// the move returned by Bet with an error. It returns the move to send
+	// instead, or ok false to let the rejection stand.
+	OnActionRejected(t Turn, m Move, code int, message string) (retry Move, ok bool)
+}
+
+// Action is a message Run sent: a join, or a move answering a prompt.
+type Action struct {
+	// Join is set for a join, which has no Turn or Move.
+	Join bool
+	Turn Turn
+	Move Move
+	// Retry is set for a move replacing one the server rejected.
+	Retry bool
+}
+
+// Hooks are the callbacks of Run, all optional and all called from Run's
+// goroutine. A hook returning an error ends the session, and Run returns
+// that error as is.
+type Hooks struct {
+	// BeforeRead is called before every read, e.g. to adjust the
+	// connection's read timeout.
+	BeforeRead func() error
+	// OnEvent is called with every message received, before Run acts on
+	// it. Returning SkipEvent makes Run ignore the message.
+	OnEvent func(resp *ServerResponse) error
+	// OnAction is called after every join and move sent.
+	OnAction func(a Action) error
+	// OnRejected is called when the server answers with an error, before
+	// the strategy gets to retry. last is the action sent last, nil if
+	// none was.
+	OnRejected func(last *Action, code int, message string) error
+	// OnGameEnd is called with the game over message of every game played.
+	OnGameEnd func(resp *ServerResponse) error
+}
+
+// SkipEvent is returned by Hooks.OnEvent to have Run ignore a message.
+var SkipEvent = errors.New("skip event")
+
+// Config is the configuration of a session run by Run.
+type Config struct {
+	// Addr is the host:port of the game server.
+	Addr        string
+	Credentials Credentials
+	// PlayerID is the ID the server assigned the player at registration,
+	// when it differs from the username; Run sets it when it registers.
+	// Prompts to either are the player's, ignoring case and spaces.
+	PlayerID string
+	Strategy Strategy
+	Hooks    Hooks
+
+	// Conn, when set, is a connection already registered as
+	// Credentials.Username. Run plays on it instead of dialing Addr, and
+	// leaves closing it to the caller.
+	Conn *Conn
+	// The timeouts of a connection Run dials; see Conn.
+	DialTimeout     time.Duration
+	RegisterTimeout time.Duration
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+
+	// Games is the number of games to play, rejoining after each game over;
+	// zero plays one. A leaderboard entry end always ends the session.
+	Games int
+}
+
+// SessionResult is what a session run by Run did.
+type SessionResult struct {
+	Username   string
+	Registered bool
+	// GameIDs are the games the session was seen at, in order.
+	GameIDs []string
+	// GamesPlayed is the number of game over messages received.
+	GamesPlayed int
+	// Hands is the number of prompts answered, and Moves the number of
+	// moves sent, retries included.
+	Hands int
+	Moves int
+	// FinalChips is the stack of the last prompt, -1 if there was none.
+	FinalChips int
+}
+
+// SessionError is returned by Run when a step of the session failed. Op is
+// "dial", "register", "join", "read" or "send".
+type SessionError struct {
+	Op  string
+	Err error
+}
+
+func (e *SessionError) Error() string { return e.Op + ": " + e.Err.Error() }
+func (e *SessionError) Unwrap() error { return e.Err }
+
+// Run plays one session: it registers cfg.Credentials, unless cfg.Conn is
+// set, joins a game and answers the prompts addressed to the player with
+// cfg.Strategy until cfg.Games games are over or the server ends the
+// leaderboard entry. A prompt to cover a bet with no chips is folded
+// without asking the strategy. Run returns nil when the session ended
+// normally, ctx.Err() when ctx was done, the error of a hook that ended
+// it, or a *SessionError. The connection is closed when ctx is done.
+//
+// A program driving a seat from its own decision engine implements Strategy
+// and calls
+//
+//	result, err := pokerclient.Run(ctx, pokerclient.Config{
+//		Addr:        "host:5000",
+//		Credentials: pokerclient.Credentials{Username: "team-1", Password: "secret"},
+//		Strategy:    engine,
+//		DialTimeout: 10 * time.Second,
+//		ReadTimeout: time.Minute,
+//	})
+func Run(ctx context.Context, cfg Config) (SessionResult, error) {
+	s := &session{cfg: cfg, result: SessionResult{Username: cfg.Credentials.Username, FinalChips: -1}}
+	err := s.run(ctx)
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	return s.result, err
+}
+
+type session struct {
+	cfg    Config
+	conn   *Conn
+	result SessionResult
+
+	// last is the action sent last, and retried is set once the strategy
+	// reacted to the rejection of the move answering the current prompt.
+	last    *Action
+	retried bool
+}
+
+func (s *session) run(ctx context.Context) error {
+	s.conn = s.cfg.Conn
+	if s.conn == nil {
+		conn, err := Dial(s.cfg.Addr, s.cfg.DialTimeout)
+		if err != nil {
+			return &SessionError{Op: "dial", Err: err}
+		}
+		defer conn.Close()
+		conn.RegisterTimeout = s.cfg.RegisterTimeout
+		conn.ReadTimeout = s.cfg.ReadTimeout
+		conn.WriteTimeout = s.cfg.WriteTimeout
+		resp, err := conn.Register(s.cfg.Credentials.Username, s.cfg.Credentials.Password)
+		if err != nil {
+			return &SessionError{Op: "register", Err: err}
+		}
+		if id := AssignedPlayerID(resp); id != "" {
+			s.cfg.PlayerID = id
+		}
+		s.conn = conn
+	}
+	s.result.Registered = true
+	stopClose := context.AfterFunc(ctx, func() { s.conn.Close() })
+	defer stopClose()
+
+	if err := s.join(); err != nil {
+		return err
+	}
+	for ctx.Err() == nil {
+		if h := s.cfg.Hooks.BeforeRead; h != nil {
+			if err := h(); err != nil {
+				return err
+			}
+		}
+		resp, err := s.conn.ReadMessage()
+		if err != nil {
+			return &SessionError{Op: "read", Err: err}
+		}
+		if resp.GameID != "" && (len(s.result.GameIDs) == 0 || s.result.GameIDs[len(s.result.GameIDs)-1] != resp.GameID) {
+			s.result.GameIDs = append(s.result.GameIDs, resp.GameID)
+		}
+		if h := s.cfg.Hooks.OnEvent; h != nil {
+			if err := h(resp); errors.Is(err, SkipEvent) {
+				continue
+			} else if err != nil {
+				return err
+			}
+		}
+		done, err := s.handle(resp)
+		if done || err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// handle acts on resp and reports whether it ended the session.
+func (s *session) handle(resp *ServerResponse) (done bool, err error) {
+	switch {
+	case resp.Type == TypeActionPlayerBet:
+		if resp.State.Player.PlayerID == "" || !s.isMe(resp.State.Player.PlayerID) {
+			return false, nil
+		}
+		return false, s.answer(resp)
+	case resp.Type == TypeGameOver:
+		s.result.GamesPlayed++
+		if h := s.cfg.Hooks.OnGameEnd; h != nil {
+			if err := h(resp); err != nil {
+				return true, err
+			}
+		}
+		if s.result.GamesPlayed >= max(s.cfg.Games, 1) {
+			return true, nil
+		}
+		return false, s.join()
+	case resp.Type == TypeLeaderboardEntryEnd:
+		return true, nil
+	case resp.Type == "" && resp.Code != 0:
+		return false, s.rejected(resp)
+	}
+	return false, nil
+}
+
+// isMe reports whether a prompt for playerID is addressed to the player.
+func (s *session) isMe(playerID string) bool {
+	return SamePlayer(playerID, s.cfg.Credentials.Username) || (s.cfg.PlayerID != "" && SamePlayer(playerID, s.cfg.PlayerID))
+}
+
+// answer sends the move answering the prompt resp.
+func (s *session) answer(resp *ServerResponse) error {
+	t := Turn{GameID: resp.GameID, Stage: resp.Stage, Chips: resp.State.Player.Chips, MinimumBet: resp.MinimumBet}
+	s.result.FinalChips = t.Chips
+	s.result.Hands++
+	s.retried = false
+	move := Fold()
+	if t.Chips > 0 || t.MinimumBet <= 0 {
+		move = s.cfg.Strategy.Bet(t)
+	}
+	return s.send(Action{Turn: t, Move: move})
+}
+
+// rejected lets the strategy retry the move the server answered with an
+// error, once per prompt.
+func (s *session) rejected(resp *ServerResponse) error {
+	if h := s.cfg.Hooks.OnRejected; h != nil {
+		if err := h(s.last, resp.Code, resp.Message); err != nil {
+			return err
+		}
+	}
+	if s.last == nil || s.last.Join || s.retried {
+		return nil
+	}
+	s.retried = true
+	move, ok := s.cfg.Strategy.OnActionRejected(s.last.Turn, s.last.Move, resp.Code, resp.Message)
+	if !ok {
+		return nil
+	}
+	return s.send(Action{Turn: s.last.Turn, Move: move, Retry: true})
+}
+
+func (s *session) join() error {
+	if err := s.conn.Join(); err != nil {
+		return &SessionError{Op: "join", Err: err}
+	}
+	return s.sent(Action{Join: true})
+}
+
+func (s *session) send(a Action) error {
+	if err := s.conn.SendJSON(a.Move.Msg()); err != nil {
+		return &SessionError{Op: "send", Err: err}
+	}
+	s.result.Moves++
+	return s.sent(a)
+}
+
+// sent records a as the last action and passes it to the OnAction hook.
+func (s *session) sent(a Action) error {
+	s.last = &a
+	if h := s.cfg.Hooks.OnAction; h != nil {
+		return h(a)
+	}
+	return nil
+}
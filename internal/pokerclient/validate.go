@@ -0,0 +1,106 @@
+package pokerclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Deviation is a difference between a received message and MessageShapes.
+type Deviation struct {
+	Type    string `json:"type"`
+	Problem string `json:"problem"`
+	Raw     string `json:"raw"`
+}
+
+func (d Deviation) String() string {
+	return fmt.Sprintf("%s: %s\n    %s", d.Type, d.Problem, d.Raw)
+}
+
+// Validate checks a raw server message against commonShape and the shape of
+// its type, and reports every deviation: a missing required field, a field
+// of the wrong kind, a value outside its known set or a top-level field no
+// shape or struct field declares. Code-only error messages, which carry no
+// type, are checked against commonShape alone.
+func Validate(raw []byte) []Deviation {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return []Deviation{{Problem: "not a JSON object: " + err.Error(), Raw: string(raw)}}
+	}
+	typ, _ := msg["type"].(string)
+	var out []Deviation
+	add := func(format string, args ...interface{}) {
+		out = append(out, Deviation{Type: typ, Problem: fmt.Sprintf(format, args...), Raw: string(raw)})
+	}
+
+	shapes := commonShape
+	if _, isErr := msg["code"]; isErr && typ == "" {
+		shapes = slices.DeleteFunc(slices.Clone(commonShape), func(f FieldShape) bool { return f.Path == "type" })
+	} else if extra, ok := MessageShapes[typ]; ok {
+		shapes = append(slices.Clone(commonShape), extra...)
+	}
+
+	declared := make(map[string]bool)
+	for _, f := range shapes {
+		declared[strings.Split(f.Path, ".")[0]] = true
+		v, ok := lookup(msg, f.Path)
+		if !ok {
+			if f.Required {
+				add("missing required field %q", f.Path)
+			}
+			continue
+		}
+		if k := kindOf(v); k != f.Kind {
+			add("field %q is %s, expected %s", f.Path, k, f.Kind)
+			continue
+		}
+		if s, isString := v.(string); isString && len(f.Enum) > 0 && !slices.Contains(f.Enum, s) {
+			add("field %q has unknown value %q", f.Path, s)
+		}
+	}
+	keys := make([]string, 0, len(msg))
+	for k := range msg {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if !declared[k] && !knownResponseFields[k] {
+			add("unknown field %q", k)
+		}
+	}
+	return out
+}
+
+// lookup follows a dotted path through nested JSON objects.
+func lookup(msg map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = msg
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func kindOf(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return KindString
+	case float64:
+		return KindNumber
+	case bool:
+		return KindBool
+	case map[string]interface{}:
+		return KindObject
+	case []interface{}:
+		return KindArray
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
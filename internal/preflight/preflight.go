@@ -0,0 +1,185 @@
+// Package preflight runs the connectivity checks behind -dry-run: each load
+// generating command lists the steps its real run depends on, and the steps
+// are executed in order without generating load.
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+
+	"elastic-ai-jam-2025/internal/endpoint"
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/netaddr"
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// Step is a single check. Fn returns a short detail on success.
+type Step struct {
+	Name string
+	Fn   func() (detail string, err error)
+}
+
+// Run executes steps in order and prints a line per step. It stops at the
+// first failure, since later steps depend on earlier ones, and reports
+// whether every step passed. Failures are labelled with the same
+// errclass.Class the real run would count them under.
+func Run(w io.Writer, steps []Step) bool {
+	for _, s := range steps {
+		if !run(w, s).OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// Result is the outcome of a step run by RunAll.
+type Result struct {
+	Name    string
+	Elapsed time.Duration
+	Detail  string
+	Err     error
+}
+
+// OK reports whether the step passed.
+func (r Result) OK() bool { return r.Err == nil }
+
+// RunAll executes every step, in order, whatever the outcome of the
+// previous ones, and prints a line per step. It is for checks that do not
+// depend on each other, or that fail on their own when they do.
+func RunAll(w io.Writer, steps []Step) []Result {
+	results := make([]Result, 0, len(steps))
+	for _, s := range steps {
+		results = append(results, run(w, s))
+	}
+	return results
+}
+
+func run(w io.Writer, s Step) Result {
+	start := time.Now()
+	detail, err := s.Fn()
+	r := Result{Name: s.Name, Elapsed: time.Since(start).Round(time.Millisecond), Detail: detail, Err: err}
+	if err != nil {
+		fmt.Fprintf(w, "  [FAIL] %-28s %8s  %s: %v\n", s.Name, r.Elapsed, errclass.Classify(err), err)
+	} else {
+		fmt.Fprintf(w, "  [ OK ] %-28s %8s  %s\n", s.Name, r.Elapsed, detail)
+	}
+	return r
+}
+
+// TCPEndpoints returns the ResolveHost and TCPConnect steps of every
+// endpoint of servers.
+func TCPEndpoints(servers endpoint.List, timeout time.Duration) []Step {
+	var steps []Step
+	for _, e := range servers.Endpoints() {
+		steps = append(steps, ResolveHost(e.Addr, timeout), TCPConnect(e.Addr, timeout))
+	}
+	return steps
+}
+
+// ResolveHost checks that the host of a host:port address resolves.
+func ResolveHost(addr string, timeout time.Duration) Step {
+	return Step{
+		Name: "DNS " + addr,
+		Fn: func() (string, error) {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return "", err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%v", addrs), nil
+		},
+	}
+}
+
+// ResolveURLHost checks that the host of an HTTP URL resolves.
+func ResolveURLHost(rawURL string, timeout time.Duration) Step {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Step{Name: "DNS " + rawURL, Fn: func() (string, error) { return "", err }}
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return ResolveHost(net.JoinHostPort(u.Hostname(), port), timeout)
+}
+
+// TCPConnect checks that a single TCP connection to addr can be established,
+// dialing the addresses of a host name in the order a run would.
+func TCPConnect(addr string, timeout time.Duration) Step {
+	return Step{
+		Name: "TCP connect " + addr,
+		Fn: func() (string, error) {
+			c, err := netaddr.Dial(addr, timeout)
+			if err != nil {
+				return "", err
+			}
+			defer c.Close()
+			return "connected from " + c.LocalAddr().String(), nil
+		},
+	}
+}
+
+// Registration logs in once as username and checks that the server answers
+// with a leaderboard entry start, the shape every real run expects, within
+// registerTimeout.
+func Registration(addr string, dialTimeout, registerTimeout time.Duration, username, password string) Step {
+	return Step{
+		Name: "registration " + username,
+		Fn: func() (string, error) {
+			conn, err := pokerclient.Dial(addr, dialTimeout)
+			if err != nil {
+				return "", err
+			}
+			defer conn.Close()
+			conn.RegisterTimeout = registerTimeout
+			resp, err := conn.Register(username, password)
+			if err != nil {
+				return "", err
+			}
+			return "response type " + resp.Type, nil
+		},
+	}
+}
+
+// GamesList fetches the games list once and checks that it decodes.
+func GamesList(api *httpapi.Client) Step {
+	return Step{
+		Name: "GET " + httpapi.APIPrefix + "/games",
+		Fn: func() (string, error) {
+			list, err := api.SharedGames(0)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d games listed", len(list.Games)), nil
+		},
+	}
+}
+
+// Leaderboard fetches the first entries of the leaderboard once and checks
+// that they decode.
+func Leaderboard(api *httpapi.Client, limit int) Step {
+	return Step{
+		Name: "GET " + httpapi.APIPrefix + "/leaderboard",
+		Fn: func() (string, error) {
+			lb, err := api.Leaderboard(limit)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d entries", len(lb.Entries)), nil
+		},
+	}
+}
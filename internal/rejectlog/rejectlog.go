@@ -0,0 +1,164 @@
+// Package rejectlog keeps, per error code, the first distinct messages the
+// server rejected requests with, verbatim, and how often each came back. A
+// mass failure then shows the server's own words in the summary instead of
+// burying them in thousands of stderr lines.
+package rejectlog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Bounds on what a Log keeps, whatever the server sends.
+const (
+	// maxCodes is the most error codes tracked; messages with further codes
+	// are only counted.
+	maxCodes = 32
+	// maxMessageLen truncates the verbatim messages kept.
+	maxMessageLen = 512
+)
+
+// digits are normalized so messages differing by a number count as one.
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// Log collects rejection messages. It is safe for concurrent use; a nil
+// *Log ignores everything.
+type Log struct {
+	// PerCode is the most distinct messages kept per code.
+	PerCode int
+
+	mu    sync.Mutex
+	codes map[int]*codeLog
+	// untracked counts the messages of codes beyond maxCodes.
+	untracked int64
+}
+
+type codeLog struct {
+	messages []*report.Rejection
+	byKey    map[string]*report.Rejection
+	// others counts the messages not among the first PerCode distinct ones.
+	others int64
+}
+
+// New returns a log keeping perCode distinct messages per code, or nil if
+// perCode is not positive.
+func New(perCode int) *Log {
+	if perCode <= 0 {
+		return nil
+	}
+	return &Log{PerCode: perCode, codes: make(map[int]*codeLog)}
+}
+
+// Normalize returns the form of msg two messages are compared by: player
+// replaced by "<player>" and every number by "<n>", so "user over-123
+// exists" and "user over-456 exists" are the same message.
+func Normalize(msg, player string) string {
+	if player != "" {
+		msg = strings.ReplaceAll(msg, player, "<player>")
+	}
+	return digits.ReplaceAllString(msg, "<n>")
+}
+
+// Add records a rejection with code and msg, received by player.
+func (l *Log) Add(code int, msg, player string) {
+	if l == nil {
+		return
+	}
+	key := Normalize(msg, player)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := l.codes[code]
+	if c == nil {
+		if len(l.codes) >= maxCodes {
+			l.untracked++
+			return
+		}
+		c = &codeLog{byKey: make(map[string]*report.Rejection)}
+		l.codes[code] = c
+	}
+	if r := c.byKey[key]; r != nil {
+		r.Count++
+		return
+	}
+	if len(c.messages) >= l.PerCode {
+		c.others++
+		return
+	}
+	if len(msg) > maxMessageLen {
+		msg = msg[:maxMessageLen] + "..."
+	}
+	r := &report.Rejection{Code: code, Message: msg, Normalized: key, Count: 1}
+	c.messages = append(c.messages, r)
+	c.byKey[key] = r
+}
+
+// AddErr records err if it is the server rejecting a registration, and
+// ignores it otherwise.
+func (l *Log) AddErr(err error, player string) {
+	var regErr *pokerclient.RegistrationError
+	if !errors.As(err, &regErr) {
+		return
+	}
+	msg := regErr.Message
+	if msg == "" {
+		msg = "unexpected " + regErr.Type + " message"
+	}
+	l.Add(regErr.Code, msg, player)
+}
+
+// Snapshot returns the messages kept, by code and then in the order they
+// were first seen. Every code with more distinct messages than PerCode gets
+// a last entry counting the rest, with an empty Message.
+func (l *Log) Snapshot() []report.Rejection {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	codes := make([]int, 0, len(l.codes))
+	for code := range l.codes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	var out []report.Rejection
+	for _, code := range codes {
+		c := l.codes[code]
+		for _, r := range c.messages {
+			out = append(out, *r)
+		}
+		if c.others > 0 {
+			out = append(out, report.Rejection{Code: code, Count: c.others})
+		}
+	}
+	if l.untracked > 0 {
+		out = append(out, report.Rejection{Code: -1, Count: l.untracked})
+	}
+	return out
+}
+
+// Print writes the messages kept, one line each, under title.
+func (l *Log) Print(w io.Writer, title string) {
+	rejections := l.Snapshot()
+	if len(rejections) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s (first %d distinct per code):\n", title, l.PerCode)
+	for _, r := range rejections {
+		switch {
+		case r.Code < 0:
+			fmt.Fprintf(w, "  other codes: %d messages\n", r.Count)
+		case r.Message == "":
+			fmt.Fprintf(w, "  code %d: %d more messages of other kinds\n", r.Code, r.Count)
+		default:
+			fmt.Fprintf(w, "  code %d, %d×: %s\n", r.Code, r.Count, r.Message)
+		}
+	}
+}
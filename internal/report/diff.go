@@ -0,0 +1,236 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Thresholds decide when a difference between two reports is a regression.
+// A zero value disables the corresponding check.
+type Thresholds struct {
+	// MaxSuccessDrop is the largest tolerated drop of a success rate, in
+	// percentage points.
+	MaxSuccessDrop float64
+	// MaxP95Increase is the largest tolerated p95 latency increase, in
+	// percent of the old value.
+	MaxP95Increase float64
+	// MaxRateDrop is the largest tolerated drop of a per-second counter
+	// rate, in percent of the old value.
+	MaxRateDrop float64
+}
+
+// DefaultThresholds flags a success rate drop of more than 5 points and a
+// p95 latency increase of more than 20%.
+var DefaultThresholds = Thresholds{MaxSuccessDrop: 5, MaxP95Increase: 20}
+
+// ignoredConfigKeys never make two runs incomparable.
+var ignoredConfigKeys = map[string]bool{
+	"config": true, "report-out": true, "log-file": true, "log-level": true, "seed": true, "dry-run": true, "run-id": true,
+}
+
+// CounterDelta compares one counter of two runs.
+type CounterDelta struct {
+	Name     string  `json:"name"`
+	Old      int64   `json:"old"`
+	New      int64   `json:"new"`
+	OldRate  float64 `json:"old_per_second"`
+	NewRate  float64 `json:"new_per_second"`
+	RateDiff float64 `json:"rate_change_percent"`
+	// Regression is set when the rate dropped past Thresholds.MaxRateDrop.
+	Regression bool `json:"regression"`
+}
+
+// SuccessDelta compares a success rate derived from a successful_X/failed_X
+// counter pair.
+type SuccessDelta struct {
+	Name       string  `json:"name"`
+	Old        float64 `json:"old_percent"`
+	New        float64 `json:"new_percent"`
+	Regression bool    `json:"regression"`
+}
+
+// LatencyDelta compares the percentiles of one latency series.
+type LatencyDelta struct {
+	Name       string  `json:"name"`
+	OldP50     float64 `json:"old_p50_ms"`
+	NewP50     float64 `json:"new_p50_ms"`
+	OldP95     float64 `json:"old_p95_ms"`
+	NewP95     float64 `json:"new_p95_ms"`
+	OldP99     float64 `json:"old_p99_ms"`
+	NewP99     float64 `json:"new_p99_ms"`
+	P95Change  float64 `json:"p95_change_percent"`
+	Regression bool    `json:"regression"`
+}
+
+// Diff is the comparison of two reports.
+type Diff struct {
+	// ConfigMismatches lists the configuration keys that differ, formatted
+	// as "key: old -> new". Raw counters are misleading when it is not empty;
+	// compare the rates instead.
+	ConfigMismatches []string       `json:"config_mismatches,omitempty"`
+	Counters         []CounterDelta `json:"counters"`
+	SuccessRates     []SuccessDelta `json:"success_rates"`
+	Latencies        []LatencyDelta `json:"latencies"`
+}
+
+// Regressed reports whether any threshold was breached.
+func (d *Diff) Regressed() bool {
+	for _, c := range d.Counters {
+		if c.Regression {
+			return true
+		}
+	}
+	for _, s := range d.SuccessRates {
+		if s.Regression {
+			return true
+		}
+	}
+	for _, l := range d.Latencies {
+		if l.Regression {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare aligns the counters, success rates and latency percentiles of two
+// reports of the same command.
+func Compare(old, cur *Report, th Thresholds) *Diff {
+	d := &Diff{ConfigMismatches: configMismatches(old.Config, cur.Config)}
+
+	for _, name := range unionKeys(old.Counters, cur.Counters) {
+		cd := CounterDelta{
+			Name:    name,
+			Old:     old.Counters[name],
+			New:     cur.Counters[name],
+			OldRate: counterRate(old, name),
+			NewRate: counterRate(cur, name),
+		}
+		cd.RateDiff = percentChange(cd.OldRate, cd.NewRate)
+		cd.Regression = th.MaxRateDrop > 0 && cd.OldRate > 0 && -cd.RateDiff > th.MaxRateDrop
+		d.Counters = append(d.Counters, cd)
+	}
+
+	for _, name := range successPairs(old.Counters, cur.Counters) {
+		oldRate, okOld := successRate(old.Counters, name)
+		newRate, okNew := successRate(cur.Counters, name)
+		if !okOld || !okNew {
+			continue
+		}
+		d.SuccessRates = append(d.SuccessRates, SuccessDelta{
+			Name:       name,
+			Old:        oldRate,
+			New:        newRate,
+			Regression: th.MaxSuccessDrop > 0 && oldRate-newRate > th.MaxSuccessDrop,
+		})
+	}
+
+	names := make([]string, 0, len(old.Latencies))
+	for name := range old.Latencies {
+		if _, ok := cur.Latencies[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		o, n := old.Latencies[name], cur.Latencies[name]
+		if o.Count == 0 || n.Count == 0 {
+			continue
+		}
+		ld := LatencyDelta{
+			Name:   name,
+			OldP50: o.P50Ms, NewP50: n.P50Ms,
+			OldP95: o.P95Ms, NewP95: n.P95Ms,
+			OldP99: o.P99Ms, NewP99: n.P99Ms,
+			P95Change: percentChange(o.P95Ms, n.P95Ms),
+		}
+		ld.Regression = th.MaxP95Increase > 0 && o.P95Ms > 0 && ld.P95Change > th.MaxP95Increase
+		d.Latencies = append(d.Latencies, ld)
+	}
+	return d
+}
+
+func configMismatches(old, cur map[string]string) []string {
+	var out []string
+	for _, k := range unionKeys(old, cur) {
+		if ignoredConfigKeys[k] {
+			continue
+		}
+		if old[k] != cur[k] {
+			out = append(out, fmt.Sprintf("%s: %q -> %q", k, old[k], cur[k]))
+		}
+	}
+	return out
+}
+
+// successPairs returns the X of every successful_X counter that has a
+// failed_X companion in either report.
+func successPairs(a, b map[string]int64) []string {
+	seen := make(map[string]bool)
+	for _, m := range []map[string]int64{a, b} {
+		for k := range m {
+			if x, ok := strings.CutPrefix(k, "successful_"); ok {
+				if _, hasFailed := m["failed_"+x]; hasFailed {
+					seen[x] = true
+				}
+			}
+		}
+	}
+	out := make([]string, 0, len(seen))
+	for x := range seen {
+		out = append(out, x)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func successRate(counters map[string]int64, name string) (float64, bool) {
+	ok, failed := counters["successful_"+name], counters["failed_"+name]
+	if ok+failed == 0 {
+		return 0, false
+	}
+	return 100 * float64(ok) / float64(ok+failed), true
+}
+
+// counterRate is the per-second rate of counter name as the run measured it,
+// or over the report's duration for counters without a rate: reports older
+// than the rates, and counters DeriveRates leaves out.
+func counterRate(r *Report, name string) float64 {
+	if r.Rates != nil {
+		if rate, ok := r.Rates.PerSecond[name]; ok {
+			return rate
+		}
+	}
+	return perSecond(r.Counters[name], r.DurationSeconds)
+}
+
+func perSecond(n int64, seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return float64(n) / seconds
+}
+
+func percentChange(old, cur float64) float64 {
+	if old == 0 {
+		return 0
+	}
+	return 100 * (cur - old) / old
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		seen[k] = true
+	}
+	for k := range b {
+		seen[k] = true
+	}
+	out := make([]string, 0, len(seen))
+	for k := range seen {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
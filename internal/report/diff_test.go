@@ -0,0 +1,187 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/latency"
+)
+
+// run returns a flood report of a run of seconds with counters, its rates
+// derived from them, and config.
+func run(seconds float64, counters map[string]int64, config map[string]string) *Report {
+	r := New("flood", config)
+	for name, n := range counters {
+		r.Counters[name] = n
+	}
+	r.DurationSeconds = seconds
+	r.Rates = DeriveRates(r.Counters, r.Errors, time.Duration(seconds*float64(time.Second)))
+	return r
+}
+
+// withP95 sets the register latency of r to a p95 of ms.
+func withP95(r *Report, ms float64) *Report {
+	r.Latencies["register"] = latency.Summary{Count: 100, P50Ms: ms / 2, P95Ms: ms, P99Ms: ms * 2}
+	return r
+}
+
+// regressions returns the names of what regressed in d.
+func regressions(d *Diff) []string {
+	var out []string
+	for _, c := range d.Counters {
+		if c.Regression {
+			out = append(out, "counter "+c.Name)
+		}
+	}
+	for _, s := range d.SuccessRates {
+		if s.Regression {
+			out = append(out, "success "+s.Name)
+		}
+	}
+	for _, l := range d.Latencies {
+		if l.Regression {
+			out = append(out, "latency "+l.Name)
+		}
+	}
+	return out
+}
+
+func TestCompare(t *testing.T) {
+	cfg := map[string]string{"players": "10", "seed": "1", "report-out": "old.json"}
+	strict := Thresholds{MaxSuccessDrop: 5, MaxP95Increase: 20, MaxRateDrop: 10}
+	tests := []struct {
+		name            string
+		old, cur        *Report
+		th              Thresholds
+		wantMismatches  []string
+		wantRegressions []string
+	}{
+		{
+			name: "equal runs",
+			old:  withP95(run(10, map[string]int64{"successful_register": 95, "failed_register": 5, "bets": 1000}, cfg), 10),
+			cur:  withP95(run(10, map[string]int64{"successful_register": 95, "failed_register": 5, "bets": 1000}, cfg), 10),
+			th:   strict,
+		},
+		{
+			name:            "success rate drop",
+			old:             run(10, map[string]int64{"successful_register": 95, "failed_register": 5}, cfg),
+			cur:             run(10, map[string]int64{"successful_register": 89, "failed_register": 11}, cfg),
+			th:              strict,
+			wantRegressions: []string{"success register"},
+		},
+		{
+			name: "success rate drop within the threshold",
+			old:  run(10, map[string]int64{"successful_register": 95, "failed_register": 5}, cfg),
+			cur:  run(10, map[string]int64{"successful_register": 91, "failed_register": 9}, cfg),
+			th:   strict,
+		},
+		{
+			name:            "p95 increase",
+			old:             withP95(run(10, nil, cfg), 10),
+			cur:             withP95(run(10, nil, cfg), 12.5),
+			th:              strict,
+			wantRegressions: []string{"latency register"},
+		},
+		{
+			name:            "rate drop",
+			old:             run(10, map[string]int64{"bets": 1000}, cfg),
+			cur:             run(20, map[string]int64{"bets": 1600}, cfg),
+			th:              strict,
+			wantRegressions: []string{"counter bets"},
+		},
+		{
+			name: "more of a counter over a longer run is no regression",
+			old:  run(10, map[string]int64{"bets": 1000}, cfg),
+			cur:  run(20, map[string]int64{"bets": 1900}, cfg),
+			th:   strict,
+		},
+		{
+			name: "checks disabled",
+			old:  withP95(run(10, map[string]int64{"successful_register": 95, "failed_register": 5, "bets": 1000}, cfg), 10),
+			cur:  withP95(run(10, map[string]int64{"successful_register": 50, "failed_register": 50, "bets": 10}, cfg), 100),
+			th:   Thresholds{},
+		},
+		{
+			name:           "config mismatch",
+			old:            run(10, map[string]int64{"bets": 1000}, cfg),
+			cur:            run(10, map[string]int64{"bets": 1000}, map[string]string{"players": "20", "seed": "2", "dealer": "call"}),
+			th:             strict,
+			wantMismatches: []string{`dealer: "" -> "call"`, `players: "10" -> "20"`},
+		},
+		{
+			name: "old zero",
+			old:  withP95(run(10, map[string]int64{"bets": 0, "successful_register": 0, "failed_register": 0}, cfg), 0),
+			cur:  withP95(run(10, map[string]int64{"bets": 50, "successful_register": 1, "failed_register": 9}, cfg), 40),
+			th:   strict,
+		},
+		{
+			name: "counter missing from the rates",
+			old:  run(10, map[string]int64{"bets": 1000}, cfg),
+			cur: func() *Report {
+				// Counted after the rates were derived.
+				r := run(10, nil, cfg)
+				r.Counters["bets"] = 1000
+				return r
+			}(),
+			th: strict,
+		},
+		{
+			name: "report without rates",
+			old: func() *Report {
+				r := run(10, map[string]int64{"bets": 1000}, cfg)
+				r.Rates = nil
+				return r
+			}(),
+			cur:             run(10, map[string]int64{"bets": 500}, cfg),
+			th:              strict,
+			wantRegressions: []string{"counter bets"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Compare(tt.old, tt.cur, tt.th)
+			if !reflect.DeepEqual(d.ConfigMismatches, tt.wantMismatches) {
+				t.Errorf("config mismatches = %q, want %q", d.ConfigMismatches, tt.wantMismatches)
+			}
+			if got := regressions(d); !reflect.DeepEqual(got, tt.wantRegressions) {
+				t.Errorf("regressions = %q, want %q", got, tt.wantRegressions)
+			}
+			if d.Regressed() != (len(tt.wantRegressions) > 0) {
+				t.Errorf("Regressed() = %v", d.Regressed())
+			}
+		})
+	}
+}
+
+func TestCounterRate(t *testing.T) {
+	r := run(10, map[string]int64{"bets": 100}, nil)
+	r.Counters["late"] = 50
+	r.Rates.PerSecond["measured"] = 7 // measured by the run, not from the counters
+	tests := []struct {
+		name string
+		want float64
+	}{
+		{"bets", 10},
+		{"late", 5},
+		{"measured", 7},
+		{"absent", 0},
+	}
+	for _, tt := range tests {
+		if got := counterRate(r, tt.name); got != tt.want {
+			t.Errorf("rate of %s = %g, want %g", tt.name, got, tt.want)
+		}
+	}
+	r.DurationSeconds = 0
+	if got := counterRate(r, "late"); got != 0 {
+		t.Errorf("rate over no time = %g, want 0", got)
+	}
+}
+
+func TestSuccessPairs(t *testing.T) {
+	old := map[string]int64{"successful_register": 1, "failed_register": 0, "successful_join": 3}
+	cur := map[string]int64{"successful_bet": 2, "failed_bet": 1, "failed_join": 1}
+	if got, want := successPairs(old, cur), []string{"bet", "register"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pairs = %q, want %q: a pair needs both counters in one report", got, want)
+	}
+}
@@ -0,0 +1,77 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Rates are the values derived from a section's counters and errors, so
+// runs of different lengths compare without mental math.
+type Rates struct {
+	// Seconds is the wall-clock duration the rates are measured over.
+	Seconds float64 `json:"seconds"`
+	// PerSecond holds every counter divided by Seconds. It is empty when
+	// the run took no measurable time.
+	PerSecond map[string]float64 `json:"per_second,omitempty"`
+	// SuccessPercent holds, for every successful_X counter with a failed_X
+	// companion, the share of the X attempts that succeeded. An X without
+	// attempts is left out.
+	SuccessPercent map[string]float64 `json:"success_percent,omitempty"`
+	// ErrorPercent holds the share of the failures in each error class.
+	ErrorPercent map[string]float64 `json:"error_percent,omitempty"`
+}
+
+// DeriveRates computes the rates of counters and errors over d.
+func DeriveRates(counters, errors map[string]int64, d time.Duration) *Rates {
+	r := &Rates{Seconds: d.Seconds()}
+	if r.Seconds > 0 {
+		r.PerSecond = make(map[string]float64, len(counters))
+		for name, n := range counters {
+			r.PerSecond[name] = perSecond(n, r.Seconds)
+		}
+	}
+	for _, name := range successPairs(counters, nil) {
+		if rate, ok := successRate(counters, name); ok {
+			if r.SuccessPercent == nil {
+				r.SuccessPercent = make(map[string]float64)
+			}
+			r.SuccessPercent[name] = rate
+		}
+	}
+	var failures int64
+	for _, n := range errors {
+		failures += n
+	}
+	if failures > 0 {
+		r.ErrorPercent = make(map[string]float64, len(errors))
+		for class, n := range errors {
+			r.ErrorPercent[class] = 100 * float64(n) / float64(failures)
+		}
+	}
+	return r
+}
+
+// Print writes the per-second rate of each of counters, then every success
+// percentage.
+func (r *Rates) Print(w io.Writer, counters ...string) {
+	if r.PerSecond == nil {
+		fmt.Fprintln(w, "Rates: none, nothing ran long enough to measure them")
+	} else {
+		parts := make([]string, 0, len(counters))
+		for _, name := range counters {
+			parts = append(parts, fmt.Sprintf("%s %.1f/s", strings.ReplaceAll(name, "_", " "), r.PerSecond[name]))
+		}
+		fmt.Fprintf(w, "Rates over %s: %s\n", time.Duration(r.Seconds*float64(time.Second)).Round(time.Millisecond), strings.Join(parts, ", "))
+	}
+	names := make([]string, 0, len(r.SuccessPercent))
+	for name := range r.SuccessPercent {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "Success rate of %s: %.1f%%\n", strings.ReplaceAll(name, "_", " "), r.SuccessPercent[name])
+	}
+}
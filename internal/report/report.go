@@ -0,0 +1,374 @@
+// Package report defines the machine-readable end-of-run report every
+// command writes with -report-out.
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/latency"
+)
+
+// SchemaVersion is bumped on every incompatible change to Report.
+const SchemaVersion = 1
+
+// Why a run ended.
+const (
+	StatusCompleted   = "completed"
+	StatusInterrupted = "interrupted"
+	StatusAborted     = "aborted"
+	StatusFailed      = "failed"
+)
+
+// Section holds the measurements of a whole run or of one part of it, such
+// as a single strategy or endpoint.
+type Section struct {
+	Counters  map[string]int64           `json:"counters,omitempty"`
+	Errors    map[string]int64           `json:"errors,omitempty"`
+	Latencies map[string]latency.Summary `json:"latencies,omitempty"`
+}
+
+// Report is the end-of-run artifact of a command.
+type Report struct {
+	SchemaVersion int    `json:"schema_version"`
+	Command       string `json:"command"`
+	// RunID identifies the run across its artifacts and log lines.
+	RunID string `json:"run_id,omitempty"`
+	// Status is one of the Status* constants; StatusReason explains it.
+	Status       string `json:"status"`
+	StatusReason string `json:"status_reason,omitempty"`
+
+	// Config holds the effective flag values, with secrets redacted.
+	Config map[string]string `json:"config"`
+
+	StartedAt       time.Time `json:"started_at"`
+	EndedAt         time.Time `json:"ended_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+
+	// Estimate is what the run was expected to cost before it started,
+	// for the commands that estimate it, to compare with the counters.
+	Estimate *RunEstimate `json:"estimate,omitempty"`
+
+	// Details holds facts established during the run, such as the game
+	// that was attacked.
+	Details map[string]string `json:"details,omitempty"`
+	// PlayerGames maps each player of the run to the games it was seated at,
+	// in order, so their details can be fetched from the HTTP API.
+	PlayerGames map[string][]string `json:"player_games,omitempty"`
+
+	// ChipReconciliation compares the chips sessions believed they had with
+	// the leaderboard, when the run verified them.
+	ChipReconciliation *ChipReconciliation `json:"chip_reconciliation,omitempty"`
+	// StrategyEffectiveness compares how the hands went by the move made
+	// in them, for the commands that track hands.
+	StrategyEffectiveness *StrategyEffectiveness `json:"strategy_effectiveness,omitempty"`
+	// GameDurations are the durations of the games the sessions saw, by
+	// table size, for the commands that track games.
+	GameDurations []GameDurations `json:"game_durations,omitempty"`
+	// MinimumBetByHand is the minimum bet asked at each hand number of the
+	// games seen, for the commands that track hands.
+	MinimumBetByHand []MinimumBetByHand `json:"minimum_bet_by_hand,omitempty"`
+	// Resources is how much of the client machine the run used, for the
+	// commands that sample it.
+	Resources *ResourceUsage `json:"resources,omitempty"`
+	// Rejections are the first distinct messages the server rejected
+	// registrations with, per code.
+	Rejections []Rejection `json:"rejections,omitempty"`
+	// ResponseHeaders summarize the -capture-headers values the HTTP API
+	// answered with, keyed by header name.
+	ResponseHeaders map[string]HeaderSignal `json:"response_headers,omitempty"`
+	// SlowestRequests are the slowest HTTP requests of the run, slowest
+	// first, for the commands that keep them.
+	SlowestRequests []latency.Request `json:"slowest_requests,omitempty"`
+
+	Section
+	// Rates are derived from the counters and errors of Section.
+	Rates *Rates `json:"rates,omitempty"`
+	// Sub holds per-strategy or per-endpoint sub-reports, keyed by name.
+	Sub map[string]*Section `json:"sub,omitempty"`
+}
+
+// Rejection is a distinct message the server rejected requests with. An
+// empty Message counts the messages of Code beyond those kept, and a
+// negative Code those of codes beyond the ones tracked.
+type Rejection struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+	// Normalized is the form messages were de-duplicated by.
+	Normalized string `json:"normalized,omitempty"`
+	Count      int64  `json:"count"`
+}
+
+// HeaderSignal summarizes the values of a response header. Numeric values,
+// such as a rate-limit counter, are aggregated; others, such as request IDs,
+// are sampled per status class ("2xx", "5xx"...) to quote when reporting
+// an issue.
+type HeaderSignal struct {
+	// Seen is the number of responses carrying the header, and Numeric
+	// those where it was a number, which Min, Max and Last are set for.
+	Seen    int64    `json:"seen"`
+	Numeric int64    `json:"numeric,omitempty"`
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	Last    *float64 `json:"last,omitempty"`
+	// AtZero counts the numeric values at or below zero, and FirstZero is
+	// when the first came (RFC 3339).
+	AtZero    int64               `json:"at_zero,omitempty"`
+	FirstZero string              `json:"first_zero,omitempty"`
+	Samples   map[string][]string `json:"samples,omitempty"`
+}
+
+// Outcomes of a chip check.
+const (
+	ChipsMatch        = "match"
+	ChipsMismatch     = "mismatch"
+	ChipsUnverifiable = "unverifiable"
+)
+
+// ChipCheck compares one player's chips as tracked by its session with the
+// chips the API reports.
+type ChipCheck struct {
+	Player       string `json:"player"`
+	SessionChips int    `json:"session_chips"`
+	APIChips     int    `json:"api_chips,omitempty"`
+	Status       string `json:"status"`
+}
+
+// ChipReconciliation is the outcome of checking a sample of players.
+type ChipReconciliation struct {
+	Checked      int `json:"checked"`
+	Matched      int `json:"matched"`
+	Mismatched   int `json:"mismatched"`
+	Unverifiable int `json:"unverifiable"`
+	// MatchRate is Matched over the verifiable players, in percent.
+	MatchRate float64     `json:"match_rate_percent"`
+	Players   []ChipCheck `json:"players"`
+}
+
+// StrategyEffectiveness checks the strategy of a run against folding every
+// hand.
+type StrategyEffectiveness struct {
+	// AllIn are the hands in which the session went all-in, and Folded
+	// those it folded without going all-in first.
+	AllIn  HandOutcomes `json:"all_in"`
+	Folded HandOutcomes `json:"folded"`
+	// Sessions are the sessions whose chips are known, and SessionEV their
+	// mean chips delta.
+	Sessions  int     `json:"sessions"`
+	SessionEV float64 `json:"session_ev"`
+	// BaselineSessions are the sessions seen folding every prompt, and
+	// BaselineEV their mean chips delta, nil without any.
+	BaselineSessions int      `json:"baseline_sessions"`
+	BaselineEV       *float64 `json:"baseline_ev,omitempty"`
+}
+
+// HandOutcomes summarizes the hands in which a kind of move was made.
+type HandOutcomes struct {
+	Hands int `json:"hands"`
+	// Won are the hands in which a pot went to the session; WinRate is
+	// their share in percent.
+	Won     int     `json:"won"`
+	WinRate float64 `json:"win_rate_percent"`
+	// Measured are the hands whose stacks before and after are known, and
+	// MeanChipsDelta how much they changed on average.
+	Measured       int     `json:"measured"`
+	MeanChipsDelta float64 `json:"mean_chips_delta"`
+}
+
+// GameDurations are the durations of the games seen at one table size.
+type GameDurations struct {
+	// TableSize is the number of players at the table, 0 when unknown.
+	TableSize int `json:"table_size"`
+	// Games are the games seen from their first message to their game
+	// over, and Duration how long that took.
+	Games    int             `json:"games"`
+	Duration latency.Summary `json:"duration"`
+	// Censored are the games every session at them left before the game
+	// over, and CensoredAt how long they had lasted by then: a lower bound
+	// of their duration, kept out of Duration.
+	Censored   int             `json:"censored"`
+	CensoredAt latency.Summary `json:"censored_at"`
+}
+
+// MinimumBetByHand is the minimum bet of the first prompt of a hand
+// number, over the games that reached it.
+type MinimumBetByHand struct {
+	Hand  int     `json:"hand"`
+	Games int     `json:"games"`
+	Min   int     `json:"min"`
+	Mean  float64 `json:"mean"`
+	Max   int     `json:"max"`
+	// MeanElapsedMs is when the prompt came on average, since the first
+	// message about its game.
+	MeanElapsedMs int64 `json:"mean_elapsed_ms"`
+}
+
+// ResourceUsage is what the client process used during a run, sampled
+// every IntervalSeconds, to tell whether the client machine was the
+// bottleneck.
+type ResourceUsage struct {
+	IntervalSeconds float64 `json:"interval_seconds"`
+	Goroutines      Stat    `json:"goroutines"`
+	HeapInuseBytes  Stat    `json:"heap_inuse_bytes"`
+	// OpenFDs is nil where the open file descriptors cannot be counted.
+	OpenFDs *Stat `json:"open_fds,omitempty"`
+	// CPUCores is the CPU time used per second of each interval: 1 is a
+	// core kept busy. CPUSeconds and the GC totals are of the whole run.
+	CPUCores       Stat    `json:"cpu_cores"`
+	CPUSeconds     float64 `json:"cpu_seconds"`
+	GCPauseTotalMs float64 `json:"gc_pause_total_ms"`
+	GCCycles       int64   `json:"gc_cycles"`
+	// Samples is the time series, thinned to every other sample whenever
+	// it grows too long; the stats cover every sample.
+	Samples  []ResourceSample `json:"samples"`
+	Warnings []string         `json:"warnings,omitempty"`
+}
+
+// Stat summarizes the samples of a gauge.
+type Stat struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+}
+
+// ResourceSample is one sample of a ResourceUsage.
+type ResourceSample struct {
+	ElapsedMs      int64   `json:"elapsed_ms"`
+	Goroutines     int64   `json:"goroutines"`
+	HeapInuseBytes int64   `json:"heap_inuse_bytes"`
+	GCPauseTotalMs float64 `json:"gc_pause_total_ms"`
+	GCCycles       int64   `json:"gc_cycles"`
+	// OpenFDs is -1 where they cannot be counted.
+	OpenFDs    int64   `json:"open_fds"`
+	CPUSeconds float64 `json:"cpu_seconds"`
+}
+
+// RunEstimate is the expected cost of a planned run.
+type RunEstimate struct {
+	// Basis describes the plan estimated, e.g. "1000 players", and
+	// CalibratedFrom the report the averages came from, empty for the
+	// built-in ones.
+	Basis          string `json:"basis"`
+	CalibratedFrom string `json:"calibrated_from,omitempty"`
+	// The averages the estimate assumed.
+	GamesPerSession        float64 `json:"games_per_session"`
+	MessagesPerGame        float64 `json:"messages_per_game"`
+	MovesPerGame           float64 `json:"moves_per_game"`
+	SessionLifetimeSeconds float64 `json:"session_lifetime_seconds"`
+
+	Sessions         int64   `json:"sessions"`
+	Connections      int64   `json:"connections"`
+	Registrations    int64   `json:"registrations"`
+	GamesJoined      int64   `json:"games_joined"`
+	Moves            int64   `json:"moves"`
+	MessagesReceived int64   `json:"messages_received"`
+	HTTPRequests     int64   `json:"http_requests"`
+	DurationSeconds  float64 `json:"duration_seconds"`
+	PeakSessions     int64   `json:"peak_sessions"`
+	PeakFDs          int64   `json:"peak_fds"`
+	PeakMemoryMB     float64 `json:"peak_memory_mb"`
+}
+
+// New starts the report of a command run.
+func New(command string, config map[string]string) *Report {
+	return &Report{
+		SchemaVersion: SchemaVersion,
+		Command:       command,
+		Status:        StatusCompleted,
+		Config:        config,
+		Details:       make(map[string]string),
+		StartedAt:     time.Now().UTC(),
+		Section:       newSection(),
+	}
+}
+
+func newSection() Section {
+	return Section{
+		Counters:  make(map[string]int64),
+		Errors:    make(map[string]int64),
+		Latencies: make(map[string]latency.Summary),
+	}
+}
+
+// SubSection returns the named sub-report, creating it if needed.
+func (r *Report) SubSection(name string) *Section {
+	if r.Sub == nil {
+		r.Sub = make(map[string]*Section)
+	}
+	s, ok := r.Sub[name]
+	if !ok {
+		sec := newSection()
+		s = &sec
+		r.Sub[name] = s
+	}
+	return s
+}
+
+// SetErrors copies an error breakdown into the section.
+func (s *Section) SetErrors(counts map[errclass.Class]int64) {
+	for c, n := range counts {
+		s.Errors[string(c)] = n
+	}
+}
+
+// Finish stamps the end time and, unless the command measured them over its
+// own span, derives the rates over the report's duration. A non-empty status
+// overrides the default StatusCompleted.
+func (r *Report) Finish(status, reason string) {
+	r.EndedAt = time.Now().UTC()
+	r.DurationSeconds = r.EndedAt.Sub(r.StartedAt).Seconds()
+	if r.Rates == nil {
+		r.Rates = DeriveRates(r.Counters, r.Errors, r.EndedAt.Sub(r.StartedAt))
+	}
+	if status != "" {
+		r.Status = status
+	}
+	r.StatusReason = reason
+}
+
+// WriteFile writes the report as indented JSON. The file is written to a
+// temporary name first and renamed, so readers never see a partial report.
+func (r *Report) WriteFile(path string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing report: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing report: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReadFile reads a report written by WriteFile.
+func ReadFile(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parsing report %s: %w", path, err)
+	}
+	if r.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("report %s has schema version %d, expected %d", path, r.SchemaVersion, SchemaVersion)
+	}
+	return &r, nil
+}
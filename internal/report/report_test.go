@@ -0,0 +1,94 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/latency"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// fullReport returns a report with every field set.
+func fullReport() *Report {
+	start := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	r := New("play", map[string]string{"players": "10", "password": "<redacted>"})
+	r.RunID = "abc123"
+	r.StartedAt = start
+	r.Estimate = &RunEstimate{Basis: "10 players", GamesPerSession: 2, Sessions: 10, Connections: 10, DurationSeconds: 30, PeakMemoryMB: 12.5}
+	r.Details["target_game"] = "g1"
+	r.PlayerGames = map[string][]string{"team-1": {"g1", "g2"}}
+	r.ChipReconciliation = &ChipReconciliation{Checked: 2, Matched: 1, Mismatched: 1, MatchRate: 50,
+		Players: []ChipCheck{{Player: "team-1", SessionChips: 100, APIChips: 100, Status: ChipsMatch}, {Player: "team-2", SessionChips: 5, APIChips: 7, Status: ChipsMismatch}}}
+	r.StrategyEffectiveness = &StrategyEffectiveness{AllIn: HandOutcomes{Hands: 4, Won: 1, WinRate: 25, Measured: 4, MeanChipsDelta: -10},
+		Folded: HandOutcomes{Hands: 6}, Sessions: 10, SessionEV: -3.5, BaselineSessions: 2, BaselineEV: ptr(-1.0)}
+	r.GameDurations = []GameDurations{{TableSize: 6, Games: 3, Duration: latency.Summary{Count: 3, MeanMs: 1500, MaxMs: 2000}, Censored: 1}}
+	r.MinimumBetByHand = []MinimumBetByHand{{Hand: 1, Games: 3, Min: 10, Mean: 10, Max: 10, MeanElapsedMs: 20}}
+	r.Resources = &ResourceUsage{IntervalSeconds: 1, Goroutines: Stat{Min: 5, Max: 50, Mean: 20}, OpenFDs: &Stat{Max: 12},
+		Samples: []ResourceSample{{ElapsedMs: 1000, Goroutines: 20, OpenFDs: -1}}, Warnings: []string{"fd limit close"}}
+	r.Rejections = []Rejection{{Code: 400, Message: "bad password", Normalized: "bad password", Count: 3}}
+	r.ResponseHeaders = map[string]HeaderSignal{"X-Ratelimit-Remaining": {Seen: 2, Numeric: 2, Min: ptr(0.0), Max: ptr(5.0), Last: ptr(0.0),
+		AtZero: 1, FirstZero: "2025-06-01T10:00:05Z", Samples: map[string][]string{"2xx": {"5"}}}}
+	r.SlowestRequests = []latency.Request{{At: start.Add(time.Second), URL: "http://api/games", Status: 200, LatencyMs: 950, PhasesMs: map[string]float64{"wait": 900}}}
+	r.Counters["sessions"] = 10
+	r.SetErrors(map[errclass.Class]int64{errclass.Timeout: 2})
+	r.Latencies["register"] = latency.Summary{Count: 10, P50Ms: 4}
+	sub := r.SubSection("allin")
+	sub.Counters["hands"] = 4
+	sub.Errors["eof"] = 1
+	sub.Latencies["turn"] = latency.Summary{Count: 4, MaxMs: 80}
+	r.Rates = DeriveRates(r.Counters, r.Errors, 30*time.Second)
+	r.Finish(StatusInterrupted, "signal")
+	r.EndedAt = start.Add(30 * time.Second)
+	r.DurationSeconds = 30
+	return r
+}
+
+func TestReportRoundTrip(t *testing.T) {
+	r := fullReport()
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := r.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, r) {
+		t.Errorf("the report read back differs:\ngot  %+v\nwant %+v", got, r)
+	}
+
+	// Every key written is a field of the defining structs.
+	data, _ := os.ReadFile(path)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var strict Report
+	if err := dec.Decode(&strict); err != nil {
+		t.Errorf("decoding the report strictly: %v", err)
+	}
+	if matches, _ := filepath.Glob(path + ".tmp*"); len(matches) > 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
+
+func TestReadFileSchemaVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(path, []byte(`{"schema_version": 99, "command": "play"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFile(path); err == nil {
+		t.Error("a report of another schema version was read")
+	}
+	if err := os.WriteFile(path, []byte(`{"schema_version": `), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFile(path); err == nil {
+		t.Error("a truncated report was read")
+	}
+}
@@ -0,0 +1,116 @@
+// Package reportcmd implements the "report" command, which works on the JSON
+// reports written with -report-out.
+package reportcmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Run is the entry point of the report command.
+func Run(args []string) int {
+	if len(args) == 0 || args[0] != "diff" {
+		fmt.Fprintln(os.Stderr, "Usage: report diff [flags] <old.json> <new.json>")
+		return 2
+	}
+	return runDiff(args[1:])
+}
+
+func runDiff(args []string) int {
+	th := report.DefaultThresholds
+	asJSON := false
+	fs := flag.NewFlagSet("report diff", flag.ContinueOnError)
+	fs.Float64Var(&th.MaxSuccessDrop, "max-success-drop", th.MaxSuccessDrop, "regression when a success rate drops by more than this many percentage points (0 disables)")
+	fs.Float64Var(&th.MaxP95Increase, "max-p95-increase", th.MaxP95Increase, "regression when a p95 latency grows by more than this percentage (0 disables)")
+	fs.Float64Var(&th.MaxRateDrop, "max-rate-drop", th.MaxRateDrop, "regression when a per-second counter rate drops by more than this percentage (0 disables)")
+	fs.BoolVar(&asJSON, "json", asJSON, "print the comparison as JSON")
+	if code, stop := cli.Parse(fs, args); stop {
+		return code
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: report diff [flags] <old.json> <new.json>")
+		return 2
+	}
+
+	old, err := report.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	cur, err := report.ReadFile(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if old.Command != cur.Command {
+		fmt.Fprintf(os.Stderr, "Error: cannot compare a %q report with a %q report\n", old.Command, cur.Command)
+		return 2
+	}
+
+	d := report.Compare(old, cur, th)
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(d)
+	} else {
+		printDiff(os.Stdout, old, cur, d)
+	}
+	if d.Regressed() {
+		return 1
+	}
+	return 0
+}
+
+func printDiff(w io.Writer, old, cur *report.Report, d *report.Diff) {
+	fmt.Fprintf(w, "--- %s report diff ---\n", cur.Command)
+	fmt.Fprintf(w, "Old: %s (%s, %.0fs)\n", old.StartedAt.Format("2006-01-02 15:04:05"), old.Status, old.DurationSeconds)
+	fmt.Fprintf(w, "New: %s (%s, %.0fs)\n", cur.StartedAt.Format("2006-01-02 15:04:05"), cur.Status, cur.DurationSeconds)
+
+	if len(d.ConfigMismatches) > 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "!!! CONFIGURATIONS DIFFER - raw counters are not comparable, compare the rates !!!")
+		for _, m := range d.ConfigMismatches {
+			fmt.Fprintf(w, "!!!   %s\n", m)
+		}
+	}
+
+	fmt.Fprintln(w, "\nCounters:")
+	fmt.Fprintf(w, "  %-28s %12s %12s %12s %12s %9s\n", "name", "old", "new", "old/s", "new/s", "rate")
+	for _, c := range d.Counters {
+		fmt.Fprintf(w, "  %-28s %12d %12d %12.2f %12.2f %+8.1f%%%s\n", c.Name, c.Old, c.New, c.OldRate, c.NewRate, c.RateDiff, mark(c.Regression))
+	}
+
+	if len(d.SuccessRates) > 0 {
+		fmt.Fprintln(w, "\nSuccess rates:")
+		for _, s := range d.SuccessRates {
+			fmt.Fprintf(w, "  %-28s %7.2f%% -> %7.2f%% (%+.2f pts)%s\n", s.Name, s.Old, s.New, s.New-s.Old, mark(s.Regression))
+		}
+	}
+
+	if len(d.Latencies) > 0 {
+		fmt.Fprintln(w, "\nLatencies (ms):")
+		for _, l := range d.Latencies {
+			fmt.Fprintf(w, "  %-28s p50 %.1f -> %.1f  p95 %.1f -> %.1f (%+.1f%%)  p99 %.1f -> %.1f%s\n",
+				l.Name, l.OldP50, l.NewP50, l.OldP95, l.NewP95, l.P95Change, l.OldP99, l.NewP99, mark(l.Regression))
+		}
+	}
+
+	if d.Regressed() {
+		fmt.Fprintln(w, "\nREGRESSION detected.")
+	} else {
+		fmt.Fprintln(w, "\nNo regression.")
+	}
+}
+
+func mark(regression bool) string {
+	if regression {
+		return "  <-- REGRESSION"
+	}
+	return ""
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package resusage
+
+import "time"
+
+// cpuTime returns 0: the CPU time of the process is only read on Unix.
+func cpuTime() time.Duration { return 0 }
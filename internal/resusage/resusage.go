@@ -0,0 +1,268 @@
+// Package resusage samples what the client process itself uses during a
+// run, goroutines, heap, GC, file descriptors and CPU time, so a heavy run
+// can tell whether the client machine was the bottleneck rather than the
+// server.
+package resusage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"elastic-ai-jam-2025/internal/report"
+	"elastic-ai-jam-2025/internal/timeseries"
+)
+
+// growthStreak is how many samples in a row a gauge must grow before its
+// threshold warns: a count that rises and falls with the load is normal,
+// one that only rises is leaking.
+const growthStreak = 5
+
+// maxSamples bounds the time series kept for the report; past it every
+// other sample is dropped, and only every other one kept from then on.
+const maxSamples = 2048
+
+// Config is how a Sampler samples and when it warns. A zero threshold
+// disables its warning.
+type Config struct {
+	Interval       time.Duration
+	WarnGoroutines int64
+	WarnFDs        int64
+}
+
+// Sampler samples the process every Interval from its own goroutine until
+// Stop. Reading the memory statistics briefly stops the world, which every
+// few seconds is negligible. It is safe for concurrent use; a nil *Sampler
+// samples nothing.
+type Sampler struct {
+	cfg   Config
+	start time.Time
+	warn  io.Writer
+
+	last atomic.Pointer[report.ResourceSample]
+
+	mu       sync.Mutex
+	samples  []report.ResourceSample
+	stride   int
+	seen     int
+	stats    [4]acc // goroutines, heap, fds, cpu cores
+	warnings []string
+	growth   [2]streak // goroutines, fds
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// acc accumulates the stats of a gauge.
+type acc struct {
+	n             int
+	min, max, sum float64
+}
+
+func (a *acc) add(v float64) {
+	if a.n == 0 || v < a.min {
+		a.min = v
+	}
+	if a.n == 0 || v > a.max {
+		a.max = v
+	}
+	a.n++
+	a.sum += v
+}
+
+func (a acc) stat() report.Stat {
+	if a.n == 0 {
+		return report.Stat{}
+	}
+	return report.Stat{Min: a.min, Max: a.max, Mean: a.sum / float64(a.n)}
+}
+
+// streak tracks the consecutive samples a gauge grew in.
+type streak struct {
+	prev   int64
+	rising int
+	warned bool
+}
+
+// Start samples now and every cfg.Interval after, writing warnings to warn,
+// or returns nil when cfg.Interval is not positive.
+func Start(cfg Config, warn io.Writer) *Sampler {
+	if cfg.Interval <= 0 {
+		return nil
+	}
+	s := &Sampler{cfg: cfg, start: time.Now(), warn: warn, stride: 1, stop: make(chan struct{}), done: make(chan struct{})}
+	s.sample()
+	go s.run()
+	return s
+}
+
+func (s *Sampler) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.sample()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Stop stops sampling, after a last sample. It may be called more than
+// once.
+func (s *Sampler) Stop() {
+	if s == nil {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		s.sample()
+	})
+}
+
+// sample takes one sample and records it.
+func (s *Sampler) sample() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	cur := report.ResourceSample{
+		ElapsedMs:      time.Since(s.start).Milliseconds(),
+		Goroutines:     int64(runtime.NumGoroutine()),
+		HeapInuseBytes: int64(m.HeapInuse),
+		GCPauseTotalMs: float64(m.PauseTotalNs) / float64(time.Millisecond),
+		GCCycles:       int64(m.NumGC),
+		OpenFDs:        openFDs(),
+		CPUSeconds:     cpuTime().Seconds(),
+	}
+	prev := s.last.Swap(&cur)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats[0].add(float64(cur.Goroutines))
+	s.stats[1].add(float64(cur.HeapInuseBytes))
+	if cur.OpenFDs >= 0 {
+		s.stats[2].add(float64(cur.OpenFDs))
+	}
+	if prev != nil && cur.ElapsedMs > prev.ElapsedMs {
+		s.stats[3].add((cur.CPUSeconds - prev.CPUSeconds) / (float64(cur.ElapsedMs-prev.ElapsedMs) / 1000))
+	}
+	s.grow(&s.growth[0], "goroutines", cur.Goroutines, s.cfg.WarnGoroutines, "-warn-goroutines")
+	if cur.OpenFDs >= 0 {
+		s.grow(&s.growth[1], "open file descriptors", cur.OpenFDs, s.cfg.WarnFDs, "-warn-fds")
+	}
+	if s.seen%s.stride == 0 {
+		s.samples = append(s.samples, cur)
+		if len(s.samples) >= maxSamples {
+			kept := s.samples[:0]
+			for i := 0; i < len(s.samples); i += 2 {
+				kept = append(kept, s.samples[i])
+			}
+			s.samples, s.stride = kept, s.stride*2
+		}
+	}
+	s.seen++
+}
+
+// grow follows the streak of a gauge at v, warning once per streak when
+// it grew growthStreak samples in a row past threshold.
+func (s *Sampler) grow(g *streak, name string, v, threshold int64, flag string) {
+	switch {
+	case v > g.prev:
+		g.rising++
+	case v < g.prev:
+		g.rising, g.warned = 0, false
+	}
+	g.prev = v
+	if threshold <= 0 || v <= threshold || g.rising < growthStreak || g.warned {
+		return
+	}
+	g.warned = true
+	msg := fmt.Sprintf("%s grew for %d samples in a row to %d, past %s %d; the client may be leaking them", name, g.rising, v, flag, threshold)
+	s.warnings = append(s.warnings, fmt.Sprintf("at +%s: %s", time.Duration(s.last.Load().ElapsedMs)*time.Millisecond, msg))
+	fmt.Fprintf(s.warn, "Warning: %s\n", msg)
+}
+
+// Columns are the time series columns of the latest sample.
+func (s *Sampler) Columns() []timeseries.Column {
+	if s == nil {
+		return nil
+	}
+	gauge := func(f func(report.ResourceSample) string) func() string {
+		return func() string { return f(*s.last.Load()) }
+	}
+	return []timeseries.Column{
+		{Name: "goroutines", Value: gauge(func(r report.ResourceSample) string { return strconv.FormatInt(r.Goroutines, 10) })},
+		{Name: "heap_inuse_bytes", Value: gauge(func(r report.ResourceSample) string { return strconv.FormatInt(r.HeapInuseBytes, 10) })},
+		{Name: "gc_pause_total_ms", Value: gauge(func(r report.ResourceSample) string { return strconv.FormatFloat(r.GCPauseTotalMs, 'f', 3, 64) })},
+		{Name: "open_fds", Value: gauge(func(r report.ResourceSample) string { return strconv.FormatInt(r.OpenFDs, 10) })},
+		{Name: "cpu_seconds", Value: gauge(func(r report.ResourceSample) string { return strconv.FormatFloat(r.CPUSeconds, 'f', 3, 64) })},
+	}
+}
+
+// Snapshot returns the usage sampled so far.
+func (s *Sampler) Snapshot() *report.ResourceUsage {
+	if s == nil {
+		return nil
+	}
+	last := *s.last.Load()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := &report.ResourceUsage{
+		IntervalSeconds: s.cfg.Interval.Seconds(),
+		Goroutines:      s.stats[0].stat(),
+		HeapInuseBytes:  s.stats[1].stat(),
+		CPUCores:        s.stats[3].stat(),
+		CPUSeconds:      last.CPUSeconds,
+		GCPauseTotalMs:  last.GCPauseTotalMs,
+		GCCycles:        last.GCCycles,
+		Samples:         append([]report.ResourceSample(nil), s.samples...),
+		Warnings:        append([]string(nil), s.warnings...),
+	}
+	if s.stats[2].n > 0 {
+		fds := s.stats[2].stat()
+		u.OpenFDs = &fds
+	}
+	return u
+}
+
+// Print writes the usage sampled so far.
+func (s *Sampler) Print(w io.Writer) {
+	u := s.Snapshot()
+	if u == nil {
+		return
+	}
+	fmt.Fprintf(w, "Client resources (every %s, %d samples kept):\n", s.cfg.Interval, len(u.Samples))
+	fmt.Fprintf(w, "  goroutines  min %.0f  mean %.0f  max %.0f\n", u.Goroutines.Min, u.Goroutines.Mean, u.Goroutines.Max)
+	fmt.Fprintf(w, "  heap in use min %.1f MiB  mean %.1f MiB  max %.1f MiB\n", u.HeapInuseBytes.Min/(1<<20), u.HeapInuseBytes.Mean/(1<<20), u.HeapInuseBytes.Max/(1<<20))
+	if u.OpenFDs != nil {
+		fmt.Fprintf(w, "  open FDs    min %.0f  mean %.0f  max %.0f\n", u.OpenFDs.Min, u.OpenFDs.Mean, u.OpenFDs.Max)
+	}
+	fmt.Fprintf(w, "  CPU         %.1fs, cores busy min %.2f  mean %.2f  max %.2f of %d\n", u.CPUSeconds, u.CPUCores.Min, u.CPUCores.Mean, u.CPUCores.Max, runtime.NumCPU())
+	fmt.Fprintf(w, "  GC          %d cycles, %.1fms paused\n", u.GCCycles, u.GCPauseTotalMs)
+	for _, msg := range u.Warnings {
+		fmt.Fprintf(w, "  Warning %s\n", msg)
+	}
+}
+
+// openFDs counts the open file descriptors of the process, or returns -1
+// where /proc/self/fd does not list them.
+func openFDs() int64 {
+	d, err := os.Open("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	defer d.Close()
+	names, err := d.Readdirnames(-1)
+	if err != nil {
+		return -1
+	}
+	return int64(len(names)) - 1 // the descriptor reading the directory
+}
@@ -0,0 +1,50 @@
+package rng
+
+import "math/bits"
+
+// Permutation is a seeded bijection of [0, n). It is computed per index, a
+// Feistel network over the smallest even power of two covering n with
+// cycle-walking back into range, so shuffling millions of players needs no
+// table.
+type Permutation struct {
+	n        uint64
+	halfBits uint
+	keys     [4]uint64
+}
+
+// NewPermutation returns the permutation of [0, n) under seed.
+func NewPermutation(seed int64, n int) *Permutation {
+	p := &Permutation{n: uint64(n)}
+	if n > 1 {
+		p.halfBits = uint(bits.Len64(uint64(n-1))+1) / 2
+	}
+	r := ForWorker(seed, -2)
+	for i := range p.keys {
+		p.keys[i] = r.Uint64()
+	}
+	return p
+}
+
+// At returns the i-th element of the permutation, for 0 <= i < n.
+func (p *Permutation) At(i int) int {
+	if p.n <= 1 {
+		return i
+	}
+	x := uint64(i)
+	for {
+		x = p.feistel(x)
+		// The domain is under 4n, so this takes few rounds on average.
+		if x < p.n {
+			return int(x)
+		}
+	}
+}
+
+func (p *Permutation) feistel(x uint64) uint64 {
+	mask := uint64(1)<<p.halfBits - 1
+	l, r := x>>p.halfBits, x&mask
+	for _, k := range p.keys {
+		l, r = r, l^(mix(r^k)&mask)
+	}
+	return l<<p.halfBits | r
+}
@@ -0,0 +1,41 @@
+// Package rng derives reproducible random sources from a run seed.
+//
+// Every goroutine that makes random decisions (jitter, strategy choices,
+// generated names) takes its own source from ForWorker instead of using the
+// global math/rand functions, so a run repeated with the same -seed and
+// configuration makes the same decisions regardless of goroutine scheduling.
+package rng
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"time"
+)
+
+// NewSeed returns a time-based seed, used when -seed is not given.
+func NewSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+// ForWorker returns the random source of worker index under seed.
+func ForWorker(seed int64, index int) *rand.Rand {
+	return rand.New(rand.NewPCG(uint64(seed), mix(uint64(index))))
+}
+
+// ForName returns the random source of the worker named name under seed,
+// for workers whose index depends on scheduling, such as the connections a
+// server accepts. A name may be reused with a different round.
+func ForName(seed int64, name string, round int) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return rand.New(rand.NewPCG(uint64(seed)^mix(h.Sum64()), mix(uint64(round))))
+}
+
+// mix spreads consecutive worker indices across the PCG stream space
+// (splitmix64 finalizer).
+func mix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
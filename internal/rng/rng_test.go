@@ -0,0 +1,76 @@
+package rng
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// draws returns the first n numbers of r.
+func draws(r *rand.Rand, n int) []uint64 {
+	out := make([]uint64, n)
+	for i := range out {
+		out[i] = r.Uint64()
+	}
+	return out
+}
+
+func TestForWorker(t *testing.T) {
+	if a, b := draws(ForWorker(42, 7), 8), draws(ForWorker(42, 7), 8); !slices.Equal(a, b) {
+		t.Errorf("same seed and index gave %v and %v", a, b)
+	}
+	seen := make(map[uint64]int)
+	for index := -2; index < 100; index++ {
+		first := ForWorker(42, index).Uint64()
+		if other, ok := seen[first]; ok {
+			t.Fatalf("workers %d and %d start alike", other, index)
+		}
+		seen[first] = index
+	}
+	if a, b := draws(ForWorker(1, 0), 8), draws(ForWorker(2, 0), 8); slices.Equal(a, b) {
+		t.Error("different seeds gave the same numbers")
+	}
+}
+
+func TestForName(t *testing.T) {
+	if a, b := draws(ForName(5, "over-1", 0), 8), draws(ForName(5, "over-1", 0), 8); !slices.Equal(a, b) {
+		t.Errorf("same seed, name and round gave %v and %v", a, b)
+	}
+	for _, other := range []*rand.Rand{ForName(5, "over-2", 0), ForName(5, "over-1", 1), ForName(6, "over-1", 0)} {
+		if slices.Equal(draws(ForName(5, "over-1", 0), 8), draws(other, 8)) {
+			t.Error("a different name, round or seed gave the same numbers")
+		}
+	}
+}
+
+func TestPermutation(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 7, 8, 100, 1000, 4097} {
+		p := NewPermutation(42, n)
+		seen := make([]bool, n)
+		identity := true
+		for i := range n {
+			v := p.At(i)
+			if v < 0 || v >= n || seen[v] {
+				t.Fatalf("n=%d: At(%d) = %d, out of range or repeated", n, i, v)
+			}
+			seen[v] = true
+			identity = identity && v == i
+			if again := NewPermutation(42, n).At(i); again != v {
+				t.Fatalf("n=%d: At(%d) = %d, then %d with the same seed", n, i, v, again)
+			}
+		}
+		if identity && n >= 8 {
+			t.Errorf("n=%d: the permutation is the identity", n)
+		}
+	}
+	same := 0
+	a, b := NewPermutation(1, 1000), NewPermutation(2, 1000)
+	for i := range 1000 {
+		if a.At(i) == b.At(i) {
+			same++
+		}
+	}
+	if same > 50 {
+		t.Errorf("seeds 1 and 2 agree on %d of 1000 positions", same)
+	}
+}
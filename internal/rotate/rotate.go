@@ -0,0 +1,92 @@
+// Package rotate rolls the files a long run appends to over into
+// timestamped archives, without losing the records written meanwhile.
+package rotate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// stampLayout is the UTC timestamp inserted in archive names.
+const stampLayout = "20060102T150405Z"
+
+// Name returns the archive name of path for the window that started at
+// start: the timestamp goes before the extension, as in
+// results.20251016T140000Z.ndjson.
+func Name(path string, start time.Time) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + start.UTC().Format(stampLayout) + ext
+}
+
+// Swap archives the file at path, open as cur, and returns a new file at
+// path that starts with header. The new file is written first, under a
+// temporary name, and renamed over path once cur is archived, so path never
+// names a missing or partial file. The caller must hold off writes to cur
+// during the swap.
+//
+// When the swap fails, the file returned is still appending to path, or
+// cur itself if the new file could not even be created, so the caller keeps
+// writing to it and no record is lost.
+func Swap(cur *os.File, path, archive string, header []byte) (*os.File, error) {
+	tmp := path + ".next"
+	next, err := os.Create(tmp)
+	if err == nil {
+		if _, err = next.Write(header); err != nil {
+			next.Close()
+			os.Remove(tmp)
+		}
+	}
+	if err != nil {
+		return cur, fmt.Errorf("rotating %s: %w", path, err)
+	}
+	syncErr := closeSynced(cur)
+	// Linking keeps path in place until the rename below replaces it;
+	// where links are not supported, path is briefly missing instead.
+	if err := os.Link(path, archive); err != nil {
+		if err := os.Rename(path, archive); err != nil {
+			return reopen(next, path, header, err)
+		}
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		// next is still named tmp: appending to it would lose the records.
+		return reopen(next, path, header, err)
+	}
+	if syncErr != nil {
+		return next, fmt.Errorf("rotating %s: %w", path, syncErr)
+	}
+	return next, nil
+}
+
+// reopen gives up a swap that failed with err: it closes and removes next,
+// and returns path open for appending, created with header if the swap
+// moved it away.
+func reopen(next *os.File, path string, header []byte, err error) (*os.File, error) {
+	next.Close()
+	os.Remove(next.Name())
+	f, ferr := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if ferr == nil {
+		var info os.FileInfo
+		if info, ferr = f.Stat(); ferr == nil && info.Size() == 0 {
+			_, ferr = f.Write(header)
+		}
+		if ferr != nil {
+			f.Close()
+		}
+	}
+	if ferr != nil {
+		return nil, fmt.Errorf("rotating %s: %w", path, errors.Join(err, ferr))
+	}
+	return f, fmt.Errorf("rotating %s: %w", path, err)
+}
+
+func closeSynced(f *os.File) error {
+	err := f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package rotate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lines returns the lines of the file at path.
+func lines(t *testing.T, path string) []string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+func TestSwapKeepsEveryRecord(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "results.csv")
+	archive := Name(path, time.Date(2025, 10, 16, 14, 0, 0, 0, time.UTC))
+	if want := filepath.Join(dir, "results.20251016T140000Z.csv"); archive != want {
+		t.Fatalf("archive name = %s, want %s", archive, want)
+	}
+	header := []byte("record\n")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(header)
+	for i := range 3 {
+		fmt.Fprintf(f, "%d\n", i)
+	}
+	if f, err = Swap(f, path, archive, header); err != nil {
+		t.Fatal(err)
+	}
+	for i := 3; i < 5; i++ {
+		fmt.Fprintf(f, "%d\n", i)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := append(lines(t, archive), lines(t, path)...)
+	want := []string{"record", "0", "1", "2", "record", "3", "4"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("archive and new file hold %q, want %q", got, want)
+	}
+	if _, err := os.Stat(path + ".next"); !os.IsNotExist(err) {
+		t.Errorf("the temporary file is left: %v", err)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	header := []byte("record\n")
+	for _, existing := range []bool{true, false} {
+		t.Run(fmt.Sprintf("existing %v", existing), func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("results-%v.csv", existing))
+			if existing {
+				if err := os.WriteFile(path, []byte("record\n0\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			next, err := os.Create(path + ".next")
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := reopen(next, path, header, errors.New("rename failed"))
+			if f == nil || err == nil || !strings.Contains(err.Error(), "rename failed") {
+				t.Fatalf("reopen = %v, %v; want path and the swap's error", f, err)
+			}
+			fmt.Fprintln(f, "1")
+			f.Close()
+			want := "record,1"
+			if existing {
+				want = "record,0,1"
+			}
+			if got := strings.Join(lines(t, path), ","); got != want {
+				t.Errorf("path holds %q, want %q", got, want)
+			}
+			if _, err := os.Stat(path + ".next"); !os.IsNotExist(err) {
+				t.Errorf("the temporary file is left: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// ExpectationError is returned by Run when an expect step fails.
+type ExpectationError struct {
+	Step Step
+	Diff string
+}
+
+func (e *ExpectationError) Error() string {
+	return fmt.Sprintf("%s failed:\n%s", e.Step, e.Diff)
+}
+
+// Runner plays a script over a registered connection.
+type Runner struct {
+	Conn   *pokerclient.Conn
+	Player string
+	// Timeout bounds every wait and expect step.
+	Timeout time.Duration
+	// Out receives a line per step and per message received.
+	Out io.Writer
+	// OnMessage, when set, is called with every message received.
+	OnMessage func(*pokerclient.ServerResponse)
+
+	// chips is the player's chips in the last prompt received, for allin.
+	chips int
+}
+
+// Run executes steps in order. It stops at the first step that fails, with
+// an *ExpectationError for a failed expectation. Callers close the
+// connection to interrupt it; ctx is only checked between steps.
+func (r *Runner) Run(ctx context.Context, steps []Step) error {
+	for _, s := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fmt.Fprintf(r.Out, "> %s\n", s)
+		if err := r.step(ctx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) step(ctx context.Context, s Step) error {
+	switch s.Kind {
+	case KindJoin:
+		return r.Conn.Join()
+	case KindBet:
+		m, err := pokerclient.Bet(s.Amount)
+		if err != nil {
+			return err
+		}
+		return r.Conn.SendJSON(m.Msg())
+	case KindCheck:
+		return r.Conn.SendJSON(pokerclient.Check().Msg())
+	case KindFold:
+		return r.Conn.SendJSON(pokerclient.Fold().Msg())
+	case KindAllIn:
+		m, err := pokerclient.AllIn(r.chips)
+		if err != nil {
+			return err
+		}
+		return r.Conn.SendJSON(m.Msg())
+	case KindSleep:
+		select {
+		case <-time.After(s.Duration):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	case KindWait:
+		return r.until(s, func(resp *pokerclient.ServerResponse) (bool, error) {
+			if s.Type == waitTurn {
+				return r.myTurn(resp), nil
+			}
+			return resp.Type == s.Type, nil
+		})
+	case KindExpect:
+		return r.until(s, func(resp *pokerclient.ServerResponse) (bool, error) {
+			done, ok := s.Expect.Check(resp, r.Player)
+			if done && !ok {
+				return true, &ExpectationError{Step: s, Diff: s.Expect.Diff(resp)}
+			}
+			return done, nil
+		})
+	}
+	return fmt.Errorf("%s: unknown step", s)
+}
+
+// until reads messages until match is done or fails, or until the step
+// times out.
+func (r *Runner) until(s Step, match func(*pokerclient.ServerResponse) (bool, error)) error {
+	deadline := time.Now().Add(r.Timeout)
+	for {
+		left := time.Until(deadline)
+		if left <= 0 {
+			return fmt.Errorf("%s: nothing matched within %s", s, r.Timeout)
+		}
+		r.Conn.ReadTimeout = left
+		resp, err := r.Conn.ReadMessage()
+		if err != nil {
+			var timeout interface{ Timeout() bool }
+			if errors.As(err, &timeout) && timeout.Timeout() {
+				return fmt.Errorf("%s: nothing matched within %s", s, r.Timeout)
+			}
+			return fmt.Errorf("%s: %w", s, err)
+		}
+		fmt.Fprintf(r.Out, "< %s\n", resp.Raw)
+		if r.myTurn(resp) {
+			r.chips = resp.State.Player.Chips
+		}
+		if r.OnMessage != nil {
+			r.OnMessage(resp)
+		}
+		if done, err := match(resp); done || err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Runner) myTurn(resp *pokerclient.ServerResponse) bool {
+	return resp.Type == pokerclient.TypeActionPlayerBet && resp.State.Player.PlayerID == r.Player
+}
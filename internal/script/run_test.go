@@ -0,0 +1,68 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/mockserver"
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// runner returns a runner registered as player on a new mock server.
+func runner(t *testing.T, player string) *Runner {
+	t.Helper()
+	srv, err := mockserver.Start("127.0.0.1:0", mockserver.Config{
+		HandsPerGame: 1, StartChips: 1000, MinimumBet: 10, Bots: 1, Dealer: mockserver.DealerCall, Seed: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	conn, err := pokerclient.Dial(srv.Addr(), 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if _, err := conn.Register(player, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	return &Runner{Conn: conn, Player: player, Timeout: 5 * time.Second, Out: io.Discard}
+}
+
+func TestRunExampleScripts(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.script"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			steps, err := ParseFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := runner(t, "scripted").Run(context.Background(), steps); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRunFailedExpectation(t *testing.T) {
+	steps, err := Parse(strings.NewReader("join\nexpect event_never_sent\nfold\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = runner(t, "scripted").Run(context.Background(), steps)
+	var failed *ExpectationError
+	if !errors.As(err, &failed) {
+		t.Fatalf("Run = %v, want an *ExpectationError", err)
+	}
+	if failed.Step.Line != 2 || !strings.HasPrefix(failed.Diff, "- expected: event_never_sent\n+ got:") {
+		t.Errorf("failed expectation of line %d with diff\n%s", failed.Step.Line, failed.Diff)
+	}
+}
@@ -0,0 +1,203 @@
+// Package script runs scripted sessions: a file of actions and expectations
+// played in order by one registered player, to reproduce server behaviour
+// deterministically.
+//
+// A script has one step per line; blank lines and lines starting with # are
+// ignored:
+//
+//	join                  ask for a seat
+//	bet 100               bet 100 chips
+//	check                 bet 0
+//	fold                  fold
+//	allin                 bet all the chips of the last prompt received
+//	sleep 2s              pause
+//	wait event_pot_won    read messages until one of that type
+//	wait turn             read messages until a bet prompt for this player
+//	expect event_game_over  the next message must be of that type
+//	expect error 400      the server's next answer must be error 400
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+// Step kinds.
+const (
+	KindJoin   = "join"
+	KindBet    = "bet"
+	KindCheck  = "check"
+	KindFold   = "fold"
+	KindAllIn  = "allin"
+	KindSleep  = "sleep"
+	KindWait   = "wait"
+	KindExpect = "expect"
+)
+
+// waitTurn is the wait target matching a bet prompt for the player.
+const waitTurn = "turn"
+
+// Step is one line of a script.
+type Step struct {
+	Line int
+	Kind string
+	// Amount is the bet of a KindBet step.
+	Amount int
+	// Duration is the pause of a KindSleep step.
+	Duration time.Duration
+	// Type is the message type a KindWait step waits for; "turn" waits for
+	// a bet prompt for the player.
+	Type string
+	// Expect is the expectation of a KindExpect step.
+	Expect Expectation
+
+	text string
+}
+
+func (s Step) String() string {
+	return fmt.Sprintf("line %d: %s", s.Line, s.text)
+}
+
+// Parse reads a script.
+func Parse(r io.Reader) ([]Step, error) {
+	var steps []Step
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		step, err := parseStep(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		step.Line, step.text = n, text
+		steps = append(steps, step)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// ParseFile reads the script at path.
+func ParseFile(path string) ([]Step, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	steps, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return steps, nil
+}
+
+func parseStep(text string) (Step, error) {
+	fields := strings.Fields(text)
+	kind, args := fields[0], fields[1:]
+	step := Step{Kind: kind}
+	switch kind {
+	case KindJoin, KindCheck, KindFold, KindAllIn:
+		if len(args) != 0 {
+			return step, fmt.Errorf("%s takes no argument", kind)
+		}
+	case KindBet:
+		if len(args) != 1 {
+			return step, fmt.Errorf("usage: bet <amount>")
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return step, fmt.Errorf("bet amount must be a positive integer, got %q", args[0])
+		}
+		step.Amount = n
+	case KindSleep:
+		if len(args) != 1 {
+			return step, fmt.Errorf("usage: sleep <duration>")
+		}
+		d, err := time.ParseDuration(args[0])
+		if err != nil || d < 0 {
+			return step, fmt.Errorf("invalid sleep duration %q", args[0])
+		}
+		step.Duration = d
+	case KindWait:
+		if len(args) != 1 {
+			return step, fmt.Errorf("usage: wait <message type>|turn")
+		}
+		step.Type = args[0]
+	case KindExpect:
+		e, err := ParseExpectation(args)
+		if err != nil {
+			return step, err
+		}
+		step.Expect = e
+	default:
+		return step, fmt.Errorf("unknown step %q", kind)
+	}
+	return step, nil
+}
+
+// Expectation is what an expect step requires of the server.
+type Expectation struct {
+	// Error is set for "expect error <code>", with Code the code required.
+	Error bool
+	Code  int
+	// Type is the message type required by "expect <type>".
+	Type string
+}
+
+// ParseExpectation parses the arguments of an expect step.
+func ParseExpectation(args []string) (Expectation, error) {
+	switch {
+	case len(args) == 2 && args[0] == "error":
+		code, err := strconv.Atoi(args[1])
+		if err != nil {
+			return Expectation{}, fmt.Errorf("invalid error code %q", args[1])
+		}
+		return Expectation{Error: true, Code: code}, nil
+	case len(args) == 1 && args[0] != "error":
+		return Expectation{Type: args[0]}, nil
+	}
+	return Expectation{}, fmt.Errorf("usage: expect <message type> | expect error <code>")
+}
+
+func (e Expectation) String() string {
+	if e.Error {
+		return fmt.Sprintf("error %d", e.Code)
+	}
+	return e.Type
+}
+
+// Check decides whether resp, received by player, settles e. An "expect
+// <type>" is settled by the next message. An "expect error" is settled by
+// the next error, or by a bet prompt for the player, which shows that the
+// action was accepted; any other message is skipped (done is false).
+func (e Expectation) Check(resp *pokerclient.ServerResponse, player string) (done, ok bool) {
+	if !e.Error {
+		return true, resp.Type == e.Type
+	}
+	switch {
+	case resp.Code != 0:
+		return true, resp.Code == e.Code
+	case resp.Type == pokerclient.TypeActionPlayerBet && resp.State.Player.PlayerID == player:
+		return true, false
+	}
+	return false, false
+}
+
+// Diff describes how resp failed e.
+func (e Expectation) Diff(resp *pokerclient.ServerResponse) string {
+	got := resp.Type
+	if resp.Code != 0 {
+		got = fmt.Sprintf("error %d (%s)", resp.Code, resp.Message)
+	}
+	return fmt.Sprintf("- expected: %s\n+ got:      %s\n  message:  %s", e, got, resp.Raw)
+}
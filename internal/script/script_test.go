@@ -0,0 +1,159 @@
+package script
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/pokerclient"
+)
+
+func TestParse(t *testing.T) {
+	src := `
+# a comment
+join
+  bet 100  
+check
+fold
+allin
+sleep 2s
+wait event_pot_won
+wait turn
+expect event_game_over
+expect error 400
+`
+	steps, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Step{
+		{Line: 3, Kind: KindJoin, text: "join"},
+		{Line: 4, Kind: KindBet, Amount: 100, text: "bet 100"},
+		{Line: 5, Kind: KindCheck, text: "check"},
+		{Line: 6, Kind: KindFold, text: "fold"},
+		{Line: 7, Kind: KindAllIn, text: "allin"},
+		{Line: 8, Kind: KindSleep, Duration: 2 * time.Second, text: "sleep 2s"},
+		{Line: 9, Kind: KindWait, Type: "event_pot_won", text: "wait event_pot_won"},
+		{Line: 10, Kind: KindWait, Type: waitTurn, text: "wait turn"},
+		{Line: 11, Kind: KindExpect, Expect: Expectation{Type: "event_game_over"}, text: "expect event_game_over"},
+		{Line: 12, Kind: KindExpect, Expect: Expectation{Error: true, Code: 400}, text: "expect error 400"},
+	}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("Parse =\n%+v\nwant\n%+v", steps, want)
+	}
+	if s := steps[1].String(); s != "line 4: bet 100" {
+		t.Errorf("String = %q", s)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"join\nraise 10", `line 2: unknown step "raise"`},
+		{"join now", "line 1: join takes no argument"},
+		{"bet", "line 1: usage: bet <amount>"},
+		{"bet 0", `line 1: bet amount must be a positive integer, got "0"`},
+		{"bet -5", `line 1: bet amount must be a positive integer, got "-5"`},
+		{"bet ten", `line 1: bet amount must be a positive integer, got "ten"`},
+		{"sleep", "line 1: usage: sleep <duration>"},
+		{"sleep soon", `line 1: invalid sleep duration "soon"`},
+		{"sleep -1s", `line 1: invalid sleep duration "-1s"`},
+		{"wait", "line 1: usage: wait <message type>|turn"},
+		{"expect", "line 1: usage: expect <message type> | expect error <code>"},
+		{"expect error", "line 1: usage: expect <message type> | expect error <code>"},
+		{"expect error bad", `line 1: invalid error code "bad"`},
+		{"expect a b c", "line 1: usage: expect <message type> | expect error <code>"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.src))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Parse(%q) error = %v, want %s", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestParseExampleScripts(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.script"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no example scripts in testdata")
+	}
+	for _, path := range paths {
+		steps, err := ParseFile(path)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if len(steps) == 0 {
+			t.Errorf("%s has no steps", path)
+		}
+	}
+	if _, err := ParseFile(filepath.Join("testdata", "missing.script")); err == nil {
+		t.Error("ParseFile of a missing file succeeded")
+	}
+}
+
+// response decodes raw as the client does.
+func response(t *testing.T, raw string) *pokerclient.ServerResponse {
+	t.Helper()
+	var resp pokerclient.ServerResponse
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		t.Fatal(err)
+	}
+	resp.Raw = json.RawMessage(raw)
+	return &resp
+}
+
+func TestExpectationCheck(t *testing.T) {
+	const (
+		potWon    = `{"type":"event_pot_won"}`
+		error400  = `{"code":400,"message":"bet too large"}`
+		error500  = `{"code":500,"message":"internal"}`
+		myTurn    = `{"type":"action_player_bet","state":{"player":{"player_id":"me","chips":100}}}`
+		theirTurn = `{"type":"action_player_bet","state":{"player":{"player_id":"other","chips":100}}}`
+	)
+	typed := Expectation{Type: "event_pot_won"}
+	rejected := Expectation{Error: true, Code: 400}
+	tests := []struct {
+		e        Expectation
+		raw      string
+		done, ok bool
+	}{
+		{typed, potWon, true, true},
+		{typed, theirTurn, true, false},
+		{typed, error400, true, false},
+		{rejected, error400, true, true},
+		{rejected, error500, true, false},
+		// A prompt to the player shows the action was accepted.
+		{rejected, myTurn, true, false},
+		// Anything else is skipped.
+		{rejected, theirTurn, false, false},
+		{rejected, potWon, false, false},
+	}
+	for _, tt := range tests {
+		done, ok := tt.e.Check(response(t, tt.raw), "me")
+		if done != tt.done || ok != tt.ok {
+			t.Errorf("expect %s on %s = done %v, ok %v; want done %v, ok %v", tt.e, tt.raw, done, ok, tt.done, tt.ok)
+		}
+	}
+}
+
+func TestExpectationDiff(t *testing.T) {
+	e := Expectation{Error: true, Code: 400}
+	got := e.Diff(response(t, `{"code":500,"message":"internal"}`))
+	want := "- expected: error 400\n+ got:      error 500 (internal)\n  message:  {\"code\":500,\"message\":\"internal\"}"
+	if got != want {
+		t.Errorf("Diff =\n%s\nwant\n%s", got, want)
+	}
+	got = Expectation{Type: "event_game_over"}.Diff(response(t, `{"type":"event_pot_won"}`))
+	want = "- expected: event_game_over\n+ got:      event_pot_won\n  message:  {\"type\":\"event_pot_won\"}"
+	if got != want {
+		t.Errorf("Diff =\n%s\nwant\n%s", got, want)
+	}
+}
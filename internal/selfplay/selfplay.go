@@ -0,0 +1,150 @@
+// Package selfplay implements the "selfplay" command: it starts the mock game
+// server on a random localhost port and runs play against it, in one process
+// and without network access. It exercises the whole client path (register,
+// join, bet, game over, rejoin) during development.
+package selfplay
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/mockserver"
+	"elastic-ai-jam-2025/internal/play"
+	"elastic-ai-jam-2025/internal/rng"
+)
+
+// failPercent is the share of bets the mock server rejects, and of moves it
+// ignores, with -failures.
+const failPercent = 5
+
+// Config is the configuration of a selfplay run. Flags after "--" are passed
+// on to play, after the ones selfplay sets, so they override them.
+type Config struct {
+	// Players is the number of sessions, all running at once.
+	Players int
+	// Games is the number of games each session plays; sessions rejoin
+	// after each one.
+	Games int
+	// Hands and Bots shape each game; Dealer scripts the bots.
+	Hands  int
+	Bots   int
+	Dealer string
+	// Failures makes the server reject some bets and drop some connections.
+	Failures bool
+	// MinPasswordLength makes the server reject shorter passwords.
+	MinPasswordLength int
+	// Seed drives the server and play; zero picks a time-based seed.
+	Seed int64
+}
+
+// DefaultConfig returns the selfplay defaults: a handful of players, enough
+// to cover the path in a few seconds.
+func DefaultConfig() Config {
+	return Config{
+		Players: 4,
+		Games:   3,
+		Hands:   5,
+		Bots:    3,
+		Dealer:  mockserver.DealerRandom,
+	}
+}
+
+// RegisterFlags adds the selfplay flags to fs.
+func (cfg *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&cfg.Players, "players", cfg.Players, "number of players, all playing at once")
+	fs.IntVar(&cfg.Games, "games", cfg.Games, "games each player plays, rejoining after each")
+	fs.IntVar(&cfg.Hands, "hands", cfg.Hands, "hands per game")
+	fs.IntVar(&cfg.Bots, "bots", cfg.Bots, "scripted opponents at each table")
+	fs.StringVar(&cfg.Dealer, "dealer", cfg.Dealer, "how the opponents move: "+strings.Join(mockserver.Dealers, ", "))
+	fs.BoolVar(&cfg.Failures, "failures", cfg.Failures, fmt.Sprintf("inject failures: reject %d%% of bets, ignore as many moves and drop some connections", failPercent))
+	fs.IntVar(&cfg.MinPasswordLength, "min-password-length", cfg.MinPasswordLength, "make the server reject passwords shorter than this, to try -password-secret (0 disables)")
+	fs.Int64Var(&cfg.Seed, "seed", cfg.Seed, "seed of the server and the players (default: time-based, printed at startup)")
+}
+
+func newFlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("selfplay", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	return fs
+}
+
+// Flags returns the selfplay flag set with default values.
+func Flags() *flag.FlagSet {
+	cfg := DefaultConfig()
+	return newFlagSet(&cfg)
+}
+
+// Run executes the selfplay command and returns the exit code of play.
+func Run(args []string) int {
+	cfg := DefaultConfig()
+	fs := newFlagSet(&cfg)
+	if code, stop := cli.Parse(fs, args); stop {
+		return code
+	}
+	if cfg.Players <= 0 || cfg.Games <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: -players and -games must be positive")
+		return 2
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = rng.NewSeed()
+	}
+	code, st, err := run(&cfg, fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: starting the mock server: %v\n", err)
+		return 2
+	}
+	fmt.Println("--- Mock server ---")
+	fmt.Printf("Connections: %d, registrations: %d, games: %d, hands: %d\n", st.Connections, st.Registered, st.Games, st.Hands)
+	fmt.Printf("Bets rejected: %d, moves ignored: %d, connections dropped: %d\n", st.RejectedBets, st.DroppedMoves, st.Drops)
+	return code
+}
+
+// run starts the mock server, runs play against it with the extra play
+// flags, and returns the exit code of play and what the server did.
+func run(cfg *Config, extra []string) (int, mockserver.Stats, error) {
+	failures := 0.0
+	if cfg.Failures {
+		failures = failPercent
+	}
+	srv, err := mockserver.Start("127.0.0.1:0", mockserver.Config{
+		HandsPerGame:      cfg.Hands,
+		StartChips:        1000,
+		MinimumBet:        10,
+		Bots:              cfg.Bots,
+		Dealer:            cfg.Dealer,
+		FailPercent:       failures,
+		DropPercent:       failures,
+		MinPasswordLength: cfg.MinPasswordLength,
+		Seed:              cfg.Seed,
+	})
+	if err != nil {
+		return 0, mockserver.Stats{}, err
+	}
+	defer srv.Close()
+	fmt.Printf("Mock server on %s (dealer %s, %d hands per game, failures %t, seed %d)\n", srv.Addr(), cfg.Dealer, cfg.Hands, cfg.Failures, cfg.Seed)
+
+	code := play.Run(append(playArgs(cfg, srv.Addr()), extra...))
+	return code, srv.Stats(), nil
+}
+
+// playArgs are the play flags of a run against the server at addr. The
+// spectate strategy is the one that rejoins after each game.
+func playArgs(cfg *Config, addr string) []string {
+	return []string{
+		"-server", addr,
+		"-players", strconv.Itoa(cfg.Players),
+		"-concurrency", strconv.Itoa(cfg.Players),
+		"-strategy", "spectate",
+		"-spectate-games", strconv.Itoa(cfg.Games),
+		"-spectate-min-chips", "1",
+		"-seed", strconv.FormatInt(cfg.Seed, 10),
+		"-connect-timeout", "2s",
+		"-game-timeout", "30s",
+		"-seat-timeout", "5s",
+		"-progress-interval", "0",
+		"-verbose=false",
+	}
+}
@@ -0,0 +1,80 @@
+package selfplay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/play"
+)
+
+func TestRunEndToEnd(t *testing.T) {
+	for _, failures := range []bool{false, true} {
+		cfg := DefaultConfig()
+		cfg.Players, cfg.Games, cfg.Hands = 3, 2, 3
+		cfg.Failures = failures
+		cfg.Seed = 11
+		code, st, err := run(&cfg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != 0 {
+			t.Errorf("failures %t: play exited with %d", failures, code)
+		}
+		if st.Registered < int64(cfg.Players) {
+			t.Errorf("failures %t: %d registrations, want at least %d", failures, st.Registered, cfg.Players)
+		}
+		// Without failures, every session plays its games to the end,
+		// rejoining after each.
+		if want := int64(cfg.Players * cfg.Games); !failures && (st.Games != want || st.Drops != 0) {
+			t.Errorf("%d games and %d drops, want %d games and none", st.Games, st.Drops, want)
+		}
+	}
+}
+
+// actions runs selfplay with seed and returns the moves and stacks of each
+// player's hands, read back from -results-out.
+func actions(t *testing.T, seed int64) map[string][]string {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Players, cfg.Hands, cfg.Failures, cfg.Seed = 4, 5, true, seed
+	out := filepath.Join(t.TempDir(), "results.ndjson")
+	code, _, err := run(&cfg, []string{"-strategy", "exploit", "-exploit-min-observations", "2", "-think-time", "2ms", "-results-out", out, "-record-hands"})
+	if err != nil || code != 0 {
+		t.Fatalf("run: exit code %d, %v", code, err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPlayer := make(map[string][]string)
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		var r play.SessionResult
+		if err := json.Unmarshal(line, &r); err != nil {
+			t.Fatal(err)
+		}
+		// Game IDs are numbered in the order games start, which is not
+		// reproducible; what was played in them is.
+		for _, h := range r.HandLog {
+			byPlayer[r.Player] = append(byPlayer[r.Player], fmt.Sprintf("hand %d: %v chips %d->%d won %t", h.Hand, h.Moves, h.ChipsStart, h.ChipsEnd, h.Won))
+		}
+		byPlayer[r.Player] = append(byPlayer[r.Player], fmt.Sprintf("%s with %d chips", r.Outcome, r.FinalChips))
+	}
+	return byPlayer
+}
+
+func TestSameSeedSameActions(t *testing.T) {
+	first, second := actions(t, 5), actions(t, 5)
+	if len(first) != 4 {
+		t.Fatalf("got the results of %d players, want 4", len(first))
+	}
+	for player, want := range first {
+		if got := second[player]; !slices.Equal(got, want) {
+			t.Errorf("%s played differently with the same seed:\n first: %q\nsecond: %q", player, want, got)
+		}
+	}
+}
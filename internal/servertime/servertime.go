@@ -0,0 +1,86 @@
+// Package servertime estimates how far the local clock is off the server's,
+// from the Date header of HTTP responses, so freshness checks against server
+// timestamps are made in server time. A laptop clock 40 seconds off is
+// enough to make a game that just started look stale.
+package servertime
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// dateResolution is the resolution of the Date header, which servers
+// truncate to the second.
+const dateResolution = time.Second
+
+// Estimate returns the skew of the local clock, positive when it is ahead of
+// the server, from the Date header value of a response to a request sent at
+// sent and answered at received, both local times. The server is assumed to
+// have stamped the response halfway through the round trip, in the middle of
+// the second the header names, so the estimate is off by at most half a
+// second plus half the round trip.
+func Estimate(date string, sent, received time.Time) (time.Duration, error) {
+	server, err := http.ParseTime(date)
+	if err != nil {
+		return 0, err
+	}
+	local := sent.Add(received.Sub(sent) / 2)
+	return local.Sub(server.Add(dateResolution / 2)), nil
+}
+
+// Estimator keeps the skew estimated from the response with the shortest
+// round trip seen so far, the most precise one. It is safe for concurrent
+// use; a nil *Estimator knows no skew.
+type Estimator struct {
+	mu    sync.Mutex
+	known bool
+	skew  time.Duration
+	rtt   time.Duration
+}
+
+// Observe estimates the skew from a response; a missing or malformed Date
+// header is ignored.
+func (e *Estimator) Observe(date string, sent, received time.Time) {
+	if e == nil || date == "" {
+		return
+	}
+	skew, err := Estimate(date, sent, received)
+	if err != nil {
+		return
+	}
+	rtt := received.Sub(sent)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.known || rtt < e.rtt {
+		e.known, e.skew, e.rtt = true, skew, rtt
+	}
+}
+
+// Skew returns the estimated skew, and false before any response had a
+// Date header.
+func (e *Estimator) Skew() (time.Duration, bool) {
+	if e == nil {
+		return 0, false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.skew, e.known
+}
+
+// Now returns the current time on the server's clock: the local time
+// corrected by the skew, if known.
+func (e *Estimator) Now() time.Time {
+	return e.ToServer(time.Now())
+}
+
+// ToServer converts the local time t to the server's clock.
+func (e *Estimator) ToServer(t time.Time) time.Time {
+	skew, _ := e.Skew()
+	return t.Add(-skew)
+}
+
+// Exceeds reports whether skew is larger than max in either direction.
+func Exceeds(skew, max time.Duration) bool {
+	return max > 0 && (skew > max || skew < -max)
+}
@@ -0,0 +1,107 @@
+package servertime
+
+import (
+	"testing"
+	"time"
+)
+
+// base is a local time on a whole second, the second a fixed Date header
+// of "Thu, 15 May 2025 10:00:00 GMT" names.
+var base = time.Date(2025, 5, 15, 10, 0, 0, 0, time.UTC)
+
+const date = "Thu, 15 May 2025 10:00:00 GMT"
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name     string
+		date     string
+		sent     time.Time
+		received time.Time
+		want     time.Duration
+		wantErr  bool
+	}{
+		{"in sync", date, base.Add(400 * time.Millisecond), base.Add(600 * time.Millisecond), 0, false},
+		{"local clock 40s ahead", date, base.Add(40*time.Second + 500*time.Millisecond), base.Add(40*time.Second + 500*time.Millisecond), 40 * time.Second, false},
+		{"local clock 40s behind", date, base.Add(-39*time.Second - 500*time.Millisecond), base.Add(-39*time.Second - 500*time.Millisecond), -40 * time.Second, false},
+		{"slow round trip", date, base.Add(-time.Second), base.Add(3 * time.Second), 500 * time.Millisecond, false},
+		{"RFC 850 date", "Thursday, 15-May-25 10:00:00 GMT", base.Add(500 * time.Millisecond), base.Add(500 * time.Millisecond), 0, false},
+		{"malformed date", "yesterday", base, base, 0, true},
+		{"empty date", "", base, base, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Estimate(tt.date, tt.sent, tt.received)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("skew = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimatorKeepsShortestRoundTrip(t *testing.T) {
+	var e Estimator
+	if _, known := e.Skew(); known {
+		t.Fatal("skew known before any response")
+	}
+	mid := base.Add(500 * time.Millisecond)
+	observations := []struct {
+		date     string
+		sent     time.Time
+		received time.Time
+		want     time.Duration
+	}{
+		// A slow round trip, 10s ahead.
+		{date, mid.Add(8 * time.Second), mid.Add(12 * time.Second), 10 * time.Second},
+		// Faster: replaces it, 40s ahead.
+		{date, mid.Add(39 * time.Second), mid.Add(41 * time.Second), 40 * time.Second},
+		// Slower again: ignored.
+		{date, mid, mid.Add(3 * time.Second), 40 * time.Second},
+		// Without a usable Date: ignored, however fast.
+		{"", mid, mid, 40 * time.Second},
+		{"garbage", mid, mid, 40 * time.Second},
+	}
+	for i, o := range observations {
+		e.Observe(o.date, o.sent, o.received)
+		if skew, known := e.Skew(); !known || skew != o.want {
+			t.Errorf("after observation %d: skew %s, %v; want %s", i, skew, known, o.want)
+		}
+	}
+	if got, want := e.ToServer(base.Add(time.Minute)), base.Add(20*time.Second); !got.Equal(want) {
+		t.Errorf("ToServer = %s, want %s", got, want)
+	}
+	if got := time.Until(e.Now()); got > -39*time.Second || got < -41*time.Second {
+		t.Errorf("Now is %s from the local clock, want about -40s", got)
+	}
+}
+
+func TestNilEstimator(t *testing.T) {
+	var e *Estimator
+	e.Observe(date, base, base)
+	if _, known := e.Skew(); known {
+		t.Error("a nil estimator knows a skew")
+	}
+	if got := e.ToServer(base); !got.Equal(base) {
+		t.Errorf("ToServer = %s, want the local time unchanged", got)
+	}
+}
+
+func TestExceeds(t *testing.T) {
+	tests := []struct {
+		skew, max time.Duration
+		want      bool
+	}{
+		{40 * time.Second, 5 * time.Second, true},
+		{-40 * time.Second, 5 * time.Second, true},
+		{5 * time.Second, 5 * time.Second, false},
+		{-5 * time.Second, 5 * time.Second, false},
+		{time.Hour, 0, false},
+	}
+	for _, tt := range tests {
+		if got := Exceeds(tt.skew, tt.max); got != tt.want {
+			t.Errorf("Exceeds(%s, %s) = %v, want %v", tt.skew, tt.max, got, tt.want)
+		}
+	}
+}
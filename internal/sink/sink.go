@@ -0,0 +1,198 @@
+// Package sink moves the writing of a run's output files off the paths that
+// produce their records. A session answering a bet prompt must not wait on
+// a slow disk, or it misses the server's turn timer; it hands its record to
+// a Sink, whose own goroutine writes it, and when the queue is full the
+// record is dropped and counted instead.
+package sink
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Target is where a Sink writes. Its methods are only called from the
+// sink's goroutine, one at a time.
+type Target interface {
+	// Write writes one record.
+	Write(record []byte) error
+	// Flush makes the records written so far durable enough, e.g. flushes
+	// a buffer. It is called whenever the queue runs empty, and by Flush
+	// and Close.
+	Flush() error
+}
+
+// Buffered returns a Target writing to w through a buffer, flushed whenever
+// the queue runs empty.
+func Buffered(w io.Writer) Target {
+	return buffered{bufio.NewWriterSize(w, 64<<10)}
+}
+
+type buffered struct{ buf *bufio.Writer }
+
+func (t buffered) Write(record []byte) error {
+	_, err := t.buf.Write(record)
+	return err
+}
+
+func (t buffered) Flush() error { return t.buf.Flush() }
+
+// item is a queued record, or with fn set, an operation to run in order
+// with the records, whose error is sent on done.
+type item struct {
+	record []byte
+	fn     func() error
+	done   chan error
+}
+
+// Sink queues records for a Target, up to a bound, and writes them from a
+// goroutine of its own. Once the Target fails, later records are discarded
+// and the error is returned by Flush, Do and Close. It is safe for
+// concurrent use; a nil *Sink discards everything.
+type Sink struct {
+	name   string
+	target Target
+	queue  chan item
+	done   chan struct{}
+
+	// mu guards closed against Put sending on the closed queue.
+	mu     sync.RWMutex
+	closed bool
+
+	written, dropped atomic.Int64
+	// err is the first error of the target; only the goroutine sets it.
+	err error
+}
+
+// New returns a sink of the given name, used in its report counters,
+// queueing up to capacity records for target.
+func New(name string, capacity int, target Target) *Sink {
+	s := &Sink{name: name, target: target, queue: make(chan item, max(capacity, 1)), done: make(chan struct{})}
+	go s.run()
+	return s
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+	for it := range s.queue {
+		switch {
+		case it.fn != nil:
+			if s.err == nil {
+				s.err = it.fn()
+			}
+			it.done <- s.err
+			continue
+		case s.err == nil:
+			if s.err = s.target.Write(it.record); s.err == nil {
+				s.written.Add(1)
+			}
+		}
+		if len(s.queue) == 0 && s.err == nil {
+			s.err = s.target.Flush()
+		}
+	}
+	if s.err == nil {
+		s.err = s.target.Flush()
+	}
+}
+
+// Put queues record, which the caller must not modify afterwards. It never
+// blocks: when the queue is full, or the sink closed, the record is dropped
+// and counted. It reports whether the record was queued.
+func (s *Sink) Put(record []byte) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.closed {
+		select {
+		case s.queue <- item{record: record}:
+			return true
+		default:
+		}
+	}
+	s.dropped.Add(1)
+	return false
+}
+
+// Do runs fn on the sink's goroutine once the records queued before it are
+// written, and returns its error; it does not run fn after the target
+// failed, and returns that error instead. Unlike Put it waits, for the
+// queue and for fn, so it is meant for shutdown and for rare operations
+// such as rotating the file under the target.
+func (s *Sink) Do(fn func() error) error {
+	if s == nil {
+		return nil
+	}
+	done := make(chan error, 1)
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return fmt.Errorf("%s sink is closed", s.name)
+	}
+	s.queue <- item{fn: fn, done: done}
+	s.mu.RUnlock()
+	return <-done
+}
+
+// Flush waits until the records queued so far are written and flushed.
+func (s *Sink) Flush() error {
+	if s == nil {
+		return nil
+	}
+	return s.Do(s.target.Flush)
+}
+
+// Close writes and flushes the records still queued, stops the goroutine
+// and returns the first error of the target. Later records are dropped.
+// The target itself is left open.
+func (s *Sink) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return s.err
+}
+
+// Written returns the number of records written.
+func (s *Sink) Written() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.written.Load()
+}
+
+// Dropped returns the number of records dropped because the queue was full
+// or the sink closed.
+func (s *Sink) Dropped() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.dropped.Load()
+}
+
+// Print writes how many records were dropped, if any.
+func (s *Sink) Print(w io.Writer) {
+	if n := s.Dropped(); n > 0 {
+		fmt.Fprintf(w, "Records dropped by the %s writer, which fell behind: %d (%d written)\n", s.name, n, s.Written())
+	}
+}
+
+// Fill records the drops in rep as the "<name>_records_dropped" counter.
+func (s *Sink) Fill(rep *report.Report) {
+	if s == nil {
+		return
+	}
+	rep.Counters[s.name+"_records_dropped"] = s.Dropped()
+}
@@ -0,0 +1,191 @@
+package sink
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// blockedTarget is a disk that stops answering: every write waits until
+// release is closed. started gets a value as each write begins.
+type blockedTarget struct {
+	started chan struct{}
+	release chan struct{}
+
+	mu      sync.Mutex
+	records []string
+	flushes int
+}
+
+func newBlockedTarget() *blockedTarget {
+	return &blockedTarget{started: make(chan struct{}, 100), release: make(chan struct{})}
+}
+
+func (t *blockedTarget) Write(record []byte) error {
+	t.started <- struct{}{}
+	<-t.release
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.records = append(t.records, string(record))
+	return nil
+}
+
+func (t *blockedTarget) Flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.flushes++
+	return nil
+}
+
+func TestPutNeverBlocks(t *testing.T) {
+	const capacity, sends = 4, 1000
+	target := newBlockedTarget()
+	s := New("results", capacity, target)
+	s.Put([]byte("first"))
+	<-target.started // the writer is now stuck on the first record
+
+	// The game loop keeps sending while the writer is stuck.
+	sent := make(chan int)
+	go func() {
+		queued := 0
+		for range sends {
+			if s.Put([]byte("r")) {
+				queued++
+			}
+		}
+		sent <- queued
+	}()
+	select {
+	case queued := <-sent:
+		if queued != capacity {
+			t.Errorf("%d records queued behind the blocked write, want %d", queued, capacity)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Put blocked behind a stuck writer")
+	}
+	if got := s.Dropped(); got != sends-capacity {
+		t.Errorf("dropped %d records, want %d", got, sends-capacity)
+	}
+
+	close(target.release)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Written(); got != capacity+1 {
+		t.Errorf("wrote %d records, want %d", got, capacity+1)
+	}
+	if len(target.records) != capacity+1 || target.records[0] != "first" {
+		t.Errorf("target got %q", target.records)
+	}
+	if target.flushes == 0 {
+		t.Error("the target was never flushed")
+	}
+
+	rep := report.New("play", nil)
+	s.Fill(rep)
+	if got := rep.Counters["results_records_dropped"]; got != sends-capacity {
+		t.Errorf("results_records_dropped = %d, want %d", got, sends-capacity)
+	}
+	var out strings.Builder
+	s.Print(&out)
+	if !strings.Contains(out.String(), "results writer") {
+		t.Errorf("printout %q does not name the sink", out.String())
+	}
+}
+
+func TestSinkWritesInOrder(t *testing.T) {
+	target := newBlockedTarget()
+	close(target.release)
+	s := New("transcript", 16, target)
+	for _, r := range []string{"a", "b", "c"} {
+		s.Put([]byte(r))
+	}
+	var seen []string
+	if err := s.Do(func() error { seen = slices.Clone(target.records); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c"}; !slices.Equal(seen, want) {
+		t.Errorf("Do ran with %q written, want the records queued before it", seen)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Put([]byte("d")) || s.Dropped() != 1 {
+		t.Errorf("a record put after Close was not dropped: %d dropped", s.Dropped())
+	}
+	if err := s.Do(func() error { return nil }); err == nil {
+		t.Error("Do after Close did not fail")
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("closing twice: %v", err)
+	}
+}
+
+func TestBufferedFlush(t *testing.T) {
+	var buf bytes.Buffer
+	s := New("transcript", 16, Buffered(&buf))
+	s.Put([]byte("a\n"))
+	s.Put([]byte("b\n"))
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "a\nb\n" {
+		t.Errorf("flushed %q", buf.String())
+	}
+	s.Put([]byte("c\n"))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "a\nb\nc\n" {
+		t.Errorf("closed with %q written", buf.String())
+	}
+}
+
+// failingTarget fails every write.
+type failingTarget struct{ writes int }
+
+var errDiskFull = errors.New("disk full")
+
+func (t *failingTarget) Write([]byte) error { t.writes++; return errDiskFull }
+func (t *failingTarget) Flush() error       { return nil }
+
+func TestSinkTargetFails(t *testing.T) {
+	target := &failingTarget{}
+	s := New("results", 16, target)
+	s.Put([]byte("a"))
+	ran := false
+	if err := s.Do(func() error { ran = true; return nil }); err != errDiskFull {
+		t.Errorf("Do after a failed write = %v, want %v", err, errDiskFull)
+	}
+	if ran {
+		t.Error("Do ran its function after the target failed")
+	}
+	s.Put([]byte("b"))
+	if err := s.Close(); err != errDiskFull {
+		t.Errorf("Close = %v, want %v", err, errDiskFull)
+	}
+	if target.writes != 1 || s.Written() != 0 {
+		t.Errorf("%d writes tried, %d written; want the first tried alone", target.writes, s.Written())
+	}
+}
+
+func TestNilSink(t *testing.T) {
+	var s *Sink
+	if s.Put([]byte("a")) {
+		t.Error("a nil sink queued a record")
+	}
+	if s.Flush() != nil || s.Do(func() error { return errDiskFull }) != nil || s.Close() != nil {
+		t.Error("a nil sink failed")
+	}
+	rep := report.New("play", nil)
+	s.Fill(rep)
+	if len(rep.Counters) != 0 {
+		t.Errorf("a nil sink reported %v", rep.Counters)
+	}
+}
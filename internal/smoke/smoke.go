@@ -0,0 +1,232 @@
+// Package smoke implements the "smoke" command: a quick end-to-end check of
+// a jam environment before a session with the team. It registers a
+// throwaway account over TCP and plays a single hand with it, then fetches
+// the leaderboard, the games list and the details of a game, and prints a
+// pass/fail line with the latency of each check.
+package smoke
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/httpapi"
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/preflight"
+	"elastic-ai-jam-2025/internal/report"
+)
+
+// Config is the configuration of a smoke run.
+type Config struct {
+	cli.Common
+
+	// UsernamePrefix is prefixed to the run ID to name the throwaway
+	// account the TCP check plays.
+	UsernamePrefix string
+	Password       string
+
+	// HandTimeout bounds the TCP check, from dialing to the end of the
+	// first hand, and HTTPTimeout each HTTP check.
+	HandTimeout time.Duration
+	HTTPTimeout time.Duration
+}
+
+// DefaultConfig returns the smoke defaults, which keep the whole run under a
+// minute.
+func DefaultConfig() Config {
+	return Config{
+		Common:         cli.DefaultCommon(),
+		UsernamePrefix: "smoke-",
+		Password:       "password",
+		HandTimeout:    30 * time.Second,
+		HTTPTimeout:    8 * time.Second,
+	}
+}
+
+// RegisterFlags adds the smoke flags to fs.
+func (cfg *Config) RegisterFlags(fs *flag.FlagSet) {
+	cfg.Common.Register(fs)
+	fs.StringVar(&cfg.UsernamePrefix, "username-prefix", cfg.UsernamePrefix, "prefix of the throwaway account, named after the run ID")
+	fs.StringVar(&cfg.Password, "password", cfg.Password, "password of the throwaway account")
+	fs.DurationVar(&cfg.HandTimeout, "hand-timeout", cfg.HandTimeout, "max duration of the TCP check, from dialing to the end of the first hand")
+	fs.DurationVar(&cfg.HTTPTimeout, "http-timeout", cfg.HTTPTimeout, "max duration of each HTTP check")
+}
+
+func newFlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("smoke", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	return fs
+}
+
+// Flags returns the smoke flag set with default values.
+func Flags() *flag.FlagSet {
+	cfg := DefaultConfig()
+	return newFlagSet(&cfg)
+}
+
+// Run is the entry point of the smoke command. It exits 1 when any check
+// failed.
+func Run(args []string) int {
+	cfg := DefaultConfig()
+	fs := newFlagSet(&cfg)
+	if code, stop := cli.Parse(fs, args); stop {
+		return code
+	}
+	closeLog, err := cfg.SetupLogging()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer closeLog()
+	if err := cfg.CheckIdent(cfg.UsernamePrefix); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	username := cfg.UsernamePrefix + cfg.RunID
+
+	ctx, stop := cli.InterruptContext()
+	defer stop()
+	rep := report.New("smoke", cli.Effective(fs))
+	api := httpapi.New(cfg.BaseURL.First(), cfg.HTTPTimeout)
+	var h hand
+	steps := []preflight.Step{
+		h.step(ctx, &cfg, username),
+		preflight.Leaderboard(api, 10),
+		preflight.GamesList(api),
+		gameDetail(api, &h),
+	}
+	fmt.Printf("--- Smoke test of %s and %s as %s ---\n", cfg.TCPServer.First(), cfg.BaseURL.First(), username)
+	results := preflight.RunAll(os.Stdout, steps)
+
+	var failed []string
+	for i, r := range results {
+		key := fmt.Sprintf("check_%d", i+1)
+		rep.Details[key] = r.Name
+		rep.Details[key+"_ms"] = fmt.Sprint(r.Elapsed.Milliseconds())
+		if r.OK() {
+			rep.Details[key+"_result"] = "ok: " + r.Detail
+			continue
+		}
+		rep.Details[key+"_result"] = "failed: " + r.Err.Error()
+		failed = append(failed, r.Name)
+	}
+	rep.Counters["checks_passed"] = int64(len(results) - len(failed))
+	rep.Counters["checks_failed"] = int64(len(failed))
+
+	fmt.Println("-----------------------------------------")
+	code, status, reason := 0, "", ""
+	if len(failed) > 0 {
+		fmt.Printf("Smoke test FAILED: %d of %d checks (%s).\n", len(failed), len(results), strings.Join(failed, ", "))
+		code, status, reason = 1, report.StatusFailed, fmt.Sprintf("%d checks failed", len(failed))
+	} else {
+		fmt.Printf("Smoke test passed: %d checks.\n", len(results))
+	}
+	rep.Finish(status, reason)
+	cfg.WriteReport(rep)
+	return code
+}
+
+// hand is the TCP check: it registers, joins and plays until the first
+// hand ends, checking or betting the minimum when prompted. gameID is the
+// game it was seated at, for the game detail check.
+type hand struct {
+	gameID string
+}
+
+// errHandOver ends the session once its first hand is over.
+var errHandOver = errors.New("hand over")
+
+func (h *hand) step(ctx context.Context, cfg *Config, username string) preflight.Step {
+	return preflight.Step{
+		Name: "TCP register, join, 1 hand",
+		Fn: func() (string, error) {
+			ctx, cancel := context.WithTimeout(ctx, cfg.HandTimeout)
+			defer cancel()
+			// There is no leave action in the protocol: Run closes the
+			// connection it dialed as soon as the hand is over.
+			result, err := pokerclient.Run(ctx, pokerclient.Config{
+				Addr:            cfg.TCPServer.First(),
+				Credentials:     pokerclient.Credentials{Username: username, Password: cfg.Password},
+				Strategy:        minimumBet{},
+				DialTimeout:     cfg.ConnectTimeout,
+				RegisterTimeout: cfg.RegisterTimeout,
+				ReadTimeout:     cfg.ReadTimeout,
+				WriteTimeout:    cfg.WriteTimeout,
+				Hooks: pokerclient.Hooks{
+					OnEvent: func(resp *pokerclient.ServerResponse) error {
+						if resp.GameID != "" {
+							h.gameID = resp.GameID
+						}
+						switch resp.Type {
+						case pokerclient.TypePotWon, pokerclient.TypeGameOver:
+							return errHandOver
+						}
+						return nil
+					},
+				},
+			})
+			switch {
+			case errors.Is(err, errHandOver):
+				return fmt.Sprintf("seated at %s, %d moves sent", h.gameID, result.Moves), nil
+			case errors.Is(err, context.DeadlineExceeded):
+				return "", fmt.Errorf("no complete hand within %s", cfg.HandTimeout)
+			case err != nil:
+				return "", err
+			}
+			return "", errors.New("server ended the session before a hand was over")
+		},
+	}
+}
+
+// gameDetail fetches the details of the game the TCP check was seated at,
+// or of the first listed game when it was not seated.
+func gameDetail(api *httpapi.Client, h *hand) preflight.Step {
+	return preflight.Step{
+		Name: "GET " + httpapi.APIPrefix + "/games/{id}",
+		Fn: func() (string, error) {
+			id := h.gameID
+			if id == "" {
+				_, _, err := api.EachSharedGame(0, func(g httpapi.ListedGame) bool {
+					id = g.GameID
+					return true
+				})
+				if err != nil {
+					return "", fmt.Errorf("no game to fetch: %w", err)
+				}
+				if id == "" {
+					return "", errors.New("no game to fetch: none seated at and none listed")
+				}
+			}
+			game, err := api.Game(id)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("game %s, %d players", id, len(game.GameState.Players)), nil
+		},
+	}
+}
+
+// minimumBet checks when it can and otherwise bets the minimum, or all in
+// when the minimum is more than its chips.
+type minimumBet struct{}
+
+func (minimumBet) Bet(t pokerclient.Turn) pokerclient.Move {
+	if t.MinimumBet <= 0 {
+		return pokerclient.Check()
+	}
+	if t.MinimumBet >= t.Chips {
+		m, _ := pokerclient.AllIn(t.Chips) // a fold with no chips
+		return m
+	}
+	m, _ := pokerclient.Bet(t.MinimumBet)
+	return m
+}
+
+func (minimumBet) OnActionRejected(pokerclient.Turn, pokerclient.Move, int, string) (pokerclient.Move, bool) {
+	return pokerclient.Move{}, false
+}
@@ -0,0 +1,150 @@
+package timeseries
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// Metric is one series of a JSON file: the values of a column, such as
+// "started" or "p95_latency_ms", one point per second. A file is an array
+// of metrics:
+//
+//	[{"metric": "started", "labels": {"run_id": "4f2a9c"}, "points": [[1735689600000, 12], ...]}, ...]
+type Metric struct {
+	Metric string            `json:"metric"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Points []Point           `json:"points"`
+}
+
+// Point is a value at a time in Unix milliseconds, or in milliseconds from
+// the start of the run once Merge made it relative.
+type Point [2]float64
+
+// WriteJSON writes metrics as a JSON series file.
+func WriteJSON(w io.Writer, metrics []Metric) error {
+	return json.NewEncoder(w).Encode(metrics)
+}
+
+// ReadFile reads a series file of either format. The metrics of a CSV file
+// have no labels.
+func ReadFile(path string) ([]Metric, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var metrics []Metric
+		if err := json.Unmarshal(data, &metrics); err != nil {
+			return nil, fmt.Errorf("reading time series %s: %w", path, err)
+		}
+		return metrics, nil
+	}
+	metrics, err := readCSV(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("reading time series %s: %w", path, err)
+	}
+	return metrics, nil
+}
+
+// readCSV turns the columns of a CSV series file into metrics, timed by
+// their unix_time column. Cells that are not numbers are left out.
+func readCSV(r io.Reader) ([]Metric, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	at := slices.Index(header, "unix_time")
+	if at < 0 {
+		return nil, fmt.Errorf("no unix_time column")
+	}
+	var metrics []Metric
+	columns := make([]int, 0, len(header))
+	for i, name := range header {
+		if name != "second" && name != "unix_time" {
+			columns = append(columns, i)
+			metrics = append(metrics, Metric{Metric: name, Points: []Point{}})
+		}
+	}
+	for _, rec := range records[1:] {
+		if at >= len(rec) {
+			continue
+		}
+		unix, err := strconv.ParseInt(rec[at], 10, 64)
+		if err != nil {
+			continue
+		}
+		for m, i := range columns {
+			if i >= len(rec) {
+				continue
+			}
+			if v, err := strconv.ParseFloat(rec[i], 64); err == nil {
+				metrics[m].Points = append(metrics[m].Points, Point{float64(unix * 1000), v})
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// Run is the series of one run to merge, and the run_id it is labeled
+// with.
+type Run struct {
+	ID      string
+	Metrics []Metric
+}
+
+// Merge puts the series of runs in one file, each metric labeled with the
+// run_id of its run, and grouped by metric name, in the order of the runs,
+// for overlays. With relative, the points of each run are timed from its
+// first point, so runs made at different times line up.
+func Merge(runs []Run, relative bool) []Metric {
+	var out []Metric
+	for _, run := range runs {
+		start := 0.0
+		if relative {
+			start = firstPoint(run.Metrics)
+		}
+		for _, m := range run.Metrics {
+			labels := maps.Clone(m.Labels)
+			if labels == nil {
+				labels = make(map[string]string, 1)
+			}
+			labels["run_id"] = run.ID
+			points := make([]Point, len(m.Points))
+			for i, p := range m.Points {
+				points[i] = Point{p[0] - start, p[1]}
+			}
+			out = append(out, Metric{Metric: m.Metric, Labels: labels, Points: points})
+		}
+	}
+	// Stable, so each metric keeps the runs in their order.
+	slices.SortStableFunc(out, func(a, b Metric) int { return cmp.Compare(a.Metric, b.Metric) })
+	return out
+}
+
+// firstPoint returns the time of the earliest point of metrics, zero if
+// they have none.
+func firstPoint(metrics []Metric) float64 {
+	first, found := 0.0, false
+	for _, m := range metrics {
+		for _, p := range m.Points {
+			if !found || p[0] < first {
+				first, found = p[0], true
+			}
+		}
+	}
+	return first
+}
@@ -0,0 +1,105 @@
+package timeseries
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// twoRuns are the series of two runs an hour apart: the first with labels
+// of its own, the second without bets, and starting with its latency.
+func twoRuns() []Run {
+	return []Run{
+		{ID: "r1", Metrics: []Metric{
+			{Metric: "started", Labels: map[string]string{"host": "a"}, Points: []Point{{1000, 3}, {2000, 4}}},
+			{Metric: "bets", Labels: map[string]string{"host": "a"}, Points: []Point{{1000, 1}, {2000, 0}}},
+		}},
+		{ID: "r2", Metrics: []Metric{
+			{Metric: "started", Points: []Point{{3602000, 5}, {3603000, 6}}},
+			{Metric: "mean_latency_ms", Points: []Point{{3601000, 12.5}}},
+		}},
+	}
+}
+
+func TestMerge(t *testing.T) {
+	r1 := map[string]string{"host": "a", "run_id": "r1"}
+	r2 := map[string]string{"run_id": "r2"}
+	tests := []struct {
+		name     string
+		relative bool
+		want     []Metric
+	}{
+		{"absolute", false, []Metric{
+			{Metric: "bets", Labels: r1, Points: []Point{{1000, 1}, {2000, 0}}},
+			{Metric: "mean_latency_ms", Labels: r2, Points: []Point{{3601000, 12.5}}},
+			{Metric: "started", Labels: r1, Points: []Point{{1000, 3}, {2000, 4}}},
+			{Metric: "started", Labels: r2, Points: []Point{{3602000, 5}, {3603000, 6}}},
+		}},
+		{"relative", true, []Metric{
+			{Metric: "bets", Labels: r1, Points: []Point{{0, 1}, {1000, 0}}},
+			{Metric: "mean_latency_ms", Labels: r2, Points: []Point{{0, 12.5}}},
+			{Metric: "started", Labels: r1, Points: []Point{{0, 3}, {1000, 4}}},
+			// Timed from the run's first point, in any metric.
+			{Metric: "started", Labels: r2, Points: []Point{{1000, 5}, {2000, 6}}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := twoRuns()
+			if got := Merge(runs, tt.relative); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merged %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(runs, twoRuns()) {
+				t.Errorf("merging changed the runs: %+v", runs)
+			}
+		})
+	}
+	if got := Merge([]Run{{ID: "empty", Metrics: []Metric{{Metric: "started", Points: []Point{}}}}}, true); len(got) != 1 || len(got[0].Points) != 0 {
+		t.Errorf("merging a run without points gave %+v", got)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Metric
+		wantErr bool
+	}{
+		{
+			name:    "json",
+			content: `[{"metric":"started","labels":{"run_id":"r1"},"points":[[1000,3]]}]`,
+			want:    []Metric{{Metric: "started", Labels: map[string]string{"run_id": "r1"}, Points: []Point{{1000, 3}}}},
+		},
+		{
+			name:    "csv",
+			content: "second,unix_time,started,cpu\n0,1,3,unknown\n1,2,4,0.5\n2,x,5,1\n3,4\n",
+			want: []Metric{
+				{Metric: "started", Points: []Point{{1000, 3}, {2000, 4}}},
+				{Metric: "cpu", Points: []Point{{2000, 0.5}}},
+			},
+		},
+		{name: "empty csv", content: ""},
+		{name: "csv without unix_time", content: "second,started\n0,3\n", wantErr: true},
+		{name: "broken json", content: `[{"metric":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "series")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("read %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if _, err := ReadFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("reading a missing file did not fail")
+	}
+}
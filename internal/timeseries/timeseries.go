@@ -0,0 +1,375 @@
+// Package timeseries writes per-second counters of a load run to a file
+// while the run progresses, for correlation with server-side graphs: a CSV
+// file, or a JSON file of one series per metric, which Grafana's JSON
+// datasources import as is; see Metric. Merge overlays the series of
+// several runs.
+package timeseries
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"elastic-ai-jam-2025/internal/errclass"
+	"elastic-ai-jam-2025/internal/latency"
+	"elastic-ai-jam-2025/internal/rotate"
+)
+
+// Formats of a series file.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Formats lists the formats Options.Format may name.
+var Formats = []string{FormatCSV, FormatJSON}
+
+// bucket holds the counters of one second. Writers only touch atomics, so
+// recording never takes a lock.
+type bucket struct {
+	second    int64
+	started   atomic.Int64
+	succeeded atomic.Int64
+	failed    atomic.Int64
+	latencyNs atomic.Int64 // sum over successes
+	latency   latency.Histogram
+	byClass   []atomic.Int64 // indexed like errclass.All
+}
+
+// classIndex maps a class to its byClass index.
+var classIndex = func() map[errclass.Class]int {
+	m := make(map[errclass.Class]int, len(errclass.All))
+	for i, c := range errclass.All {
+		m[c] = i
+	}
+	return m
+}()
+
+func newBucket() *bucket {
+	return &bucket{byClass: make([]atomic.Int64, len(errclass.All))}
+}
+
+func (b *bucket) reset(second int64) {
+	b.second = second
+	b.started.Store(0)
+	b.succeeded.Store(0)
+	b.failed.Store(0)
+	b.latencyNs.Store(0)
+	b.latency.Reset()
+	for i := range b.byClass {
+		b.byClass[i].Store(0)
+	}
+}
+
+// Row is a closed second as written to the file.
+type Row struct {
+	Second        int64
+	Started       int64
+	Succeeded     int64
+	Failed        int64
+	MeanLatencyMs float64
+}
+
+// Column is an extra column of the file, a gauge read as each second is
+// written, such as a resource sampled by the run.
+type Column struct {
+	Name  string
+	Value func() string
+}
+
+// Rate is a column of the increase of a counter read by load since the
+// previous second written, such as the bets sent per second.
+func Rate(name string, load func() int64) Column {
+	var last atomic.Int64
+	return Column{Name: name, Value: func() string {
+		n := load()
+		return strconv.FormatInt(n-last.Swap(n), 10)
+	}}
+}
+
+// Options are the settings of a series beyond its path and start.
+type Options struct {
+	// Format is one of Formats; empty means FormatCSV.
+	Format string
+	// Labels are attached to every metric of a JSON file, such as the
+	// run_id Merge tells the runs apart with.
+	Labels map[string]string
+	// Extra are the columns after the counters.
+	Extra []Column
+}
+
+// Series records attempts into one-second buckets and appends each bucket to
+// a file once it closes. A CSV file gets a line per second as it closes; a
+// JSON file, being one document, is only written when the series closes or
+// rolls over. A nil *Series discards everything.
+//
+// The buckets form a ring of three: the current one, the one closed at the
+// last tick, which stays writable for a second so attempts that loaded it
+// just before the swap still land in it, and the one being recycled.
+type Series struct {
+	start time.Time
+	cur   atomic.Pointer[bucket]
+	ring  [3]*bucket
+	tick  int64
+
+	mu     sync.Mutex // guards the file, rows and metrics
+	path   string
+	format string
+	header string
+	extra  []Column
+	f      *os.File
+	w      *bufio.Writer
+	rows   []Row
+	err    error
+	// metrics are the points of a JSON file not written yet.
+	metrics []Metric
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Create starts a series at start, writing to path as opts say. It ticks
+// every second until Close.
+func Create(path string, start time.Time, opts Options) (*Series, error) {
+	format := opts.Format
+	if format == "" {
+		format = FormatCSV
+	}
+	if !slices.Contains(Formats, format) {
+		return nil, fmt.Errorf("unknown time series format %q", format)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating time series: %w", err)
+	}
+	s := &Series{start: start, path: path, format: format, extra: opts.Extra, f: f, w: bufio.NewWriter(f), stop: make(chan struct{}), done: make(chan struct{})}
+	for i := range s.ring {
+		s.ring[i] = newBucket()
+	}
+	s.cur.Store(s.ring[0])
+
+	names := []string{"started", "successful", "failed", "mean_latency_ms", "p50_latency_ms", "p95_latency_ms", "p99_latency_ms", "failure_rate"}
+	for _, c := range errclass.All {
+		names = append(names, "failed_"+string(c))
+	}
+	for _, c := range opts.Extra {
+		names = append(names, c.Name)
+	}
+	if format == FormatJSON {
+		for _, name := range names {
+			s.metrics = append(s.metrics, Metric{Metric: name, Labels: opts.Labels, Points: []Point{}})
+		}
+	} else {
+		s.header = "second,unix_time," + strings.Join(names, ",") + "\n"
+		s.w.WriteString(s.header)
+		s.w.Flush()
+	}
+
+	go s.run()
+	return s, nil
+}
+
+func (s *Series) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.advance()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// advance opens a new bucket and writes the one closed a tick ago.
+func (s *Series) advance() {
+	s.tick++
+	next := s.ring[s.tick%3]
+	next.reset(s.tick)
+	s.cur.Store(next)
+	if s.tick >= 2 {
+		s.write(s.ring[(s.tick-2)%3])
+	}
+}
+
+// Started records the start of an attempt.
+func (s *Series) Started() {
+	if s == nil {
+		return
+	}
+	s.cur.Load().started.Add(1)
+}
+
+// Succeeded records a successful attempt that took latency.
+func (s *Series) Succeeded(latency time.Duration) {
+	if s == nil {
+		return
+	}
+	b := s.cur.Load()
+	b.succeeded.Add(1)
+	b.latencyNs.Add(int64(latency))
+	b.latency.Record(latency)
+}
+
+// Failed records a failed attempt under the class of err.
+func (s *Series) Failed(err error) {
+	if s == nil {
+		return
+	}
+	b := s.cur.Load()
+	b.failed.Add(1)
+	if i, ok := classIndex[errclass.Classify(err)]; ok {
+		b.byClass[i].Add(1)
+	}
+}
+
+func (s *Series) write(b *bucket) {
+	row := Row{Second: b.second, Started: b.started.Load(), Succeeded: b.succeeded.Load(), Failed: b.failed.Load()}
+	if row.Succeeded > 0 {
+		row.MeanLatencyMs = float64(b.latencyNs.Load()) / float64(row.Succeeded) / float64(time.Millisecond)
+	}
+	failureRate := 0.0
+	if n := row.Succeeded + row.Failed; n > 0 {
+		failureRate = float64(row.Failed) / float64(n)
+	}
+	ms := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+	}
+	values := []string{
+		strconv.FormatInt(row.Started, 10), strconv.FormatInt(row.Succeeded, 10), strconv.FormatInt(row.Failed, 10),
+		strconv.FormatFloat(row.MeanLatencyMs, 'f', 3, 64),
+		ms(b.latency.Quantile(0.5)), ms(b.latency.Quantile(0.95)), ms(b.latency.Quantile(0.99)),
+		strconv.FormatFloat(failureRate, 'f', 4, 64),
+	}
+	for i := range b.byClass {
+		values = append(values, strconv.FormatInt(b.byClass[i].Load(), 10))
+	}
+	for _, c := range s.extra {
+		values = append(values, c.Value())
+	}
+	at := s.start.Add(time.Duration(row.Second) * time.Second)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows = append(s.rows, row)
+	if s.format == FormatJSON {
+		for i, v := range values {
+			// A gauge that is not a number, such as an unknown
+			// resource, leaves a gap.
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				s.metrics[i].Points = append(s.metrics[i].Points, Point{float64(at.UnixMilli()), f})
+			}
+		}
+		return
+	}
+	if s.err == nil {
+		_, s.err = fmt.Fprintf(s.w, "%d,%d,%s\n", row.Second, at.Unix(), strings.Join(values, ","))
+	}
+	if s.err == nil {
+		s.err = s.w.Flush() // a crash loses at most the open seconds
+	}
+}
+
+// flushJSON writes the metrics of a JSON series to its file, replacing
+// what it held. The caller holds s.mu.
+func (s *Series) flushJSON() {
+	if s.format != FormatJSON || s.err != nil {
+		return
+	}
+	if _, s.err = s.f.Seek(0, 0); s.err != nil {
+		return
+	}
+	if s.err = s.f.Truncate(0); s.err != nil {
+		return
+	}
+	s.w.Reset(s.f)
+	if s.err = WriteJSON(s.w, s.metrics); s.err == nil {
+		s.err = s.w.Flush()
+	}
+}
+
+// Close stops ticking, writes the seconds still open and closes the file.
+// Call it once every attempt has been recorded.
+func (s *Series) Close() error {
+	if s == nil {
+		return nil
+	}
+	close(s.stop)
+	<-s.done
+	if s.tick >= 1 {
+		s.write(s.ring[(s.tick-1)%3])
+	}
+	s.write(s.ring[s.tick%3])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushJSON()
+	if err := s.f.Close(); s.err == nil {
+		s.err = err
+	}
+	return s.err
+}
+
+// Rollover moves the seconds written so far to archive and continues in a
+// new file at the series' path, starting with the header again; see
+// rotate.Swap. A JSON series writes its seconds before the swap and starts
+// the new file afresh. Seconds keep counting from the start of the series.
+func (s *Series) Rollover(archive string) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushJSON()
+	if s.err != nil {
+		return s.err
+	}
+	if s.err = s.w.Flush(); s.err != nil {
+		return s.err
+	}
+	f, err := rotate.Swap(s.f, s.path, archive, []byte(s.header))
+	if f == nil {
+		s.err = err
+		return err
+	}
+	if err == nil {
+		for i := range s.metrics {
+			s.metrics[i].Points = []Point{}
+		}
+	}
+	s.f = f
+	s.w.Reset(f)
+	return err
+}
+
+// BestWorst returns the second with the most successes and the one with the
+// most failures. ok is false when no second was written.
+func (s *Series) BestWorst() (best, worst Row, ok bool) {
+	if s == nil {
+		return Row{}, Row{}, false
+	}
+	s.mu.Lock()
+	rows := slices.Clone(s.rows)
+	s.mu.Unlock()
+	if len(rows) == 0 {
+		return Row{}, Row{}, false
+	}
+	best, worst = rows[0], rows[0]
+	for _, r := range rows[1:] {
+		if r.Succeeded > best.Succeeded {
+			best = r
+		}
+		if r.Failed > worst.Failed || (r.Failed == worst.Failed && r.Succeeded < worst.Succeeded) {
+			worst = r
+		}
+	}
+	return best, worst, true
+}
@@ -0,0 +1,185 @@
+package timeseries
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// manual stops the ticker of s, so the test closes its seconds with
+// advance instead of waiting for them.
+func manual(s *Series) {
+	close(s.stop)
+	<-s.done
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	close(s.done)
+}
+
+// values returns the values of the metric name of metrics, and whether it
+// is there.
+func values(metrics []Metric, name string) ([]float64, bool) {
+	for _, m := range metrics {
+		if m.Metric == name {
+			out := []float64{}
+			for _, p := range m.Points {
+				out = append(out, p[1])
+			}
+			return out, true
+		}
+	}
+	return nil, false
+}
+
+// record writes three seconds to s: three attempts, two successes of 10
+// and 30ms and a failure, then a success of 20ms, then nothing. The bets
+// counter reads 5 when the first second is written, and 7 after.
+func record(s *Series, bets *atomic.Int64) {
+	s.Started()
+	s.Started()
+	s.Started()
+	s.Succeeded(10 * time.Millisecond)
+	s.Succeeded(30 * time.Millisecond)
+	s.Failed(errors.New("boom"))
+	s.advance()
+	s.Started()
+	s.Succeeded(20 * time.Millisecond)
+	bets.Store(5)
+	s.advance() // writes the first second
+	bets.Store(7)
+}
+
+func TestSeriesFormats(t *testing.T) {
+	start := time.Unix(1735689600, 0)
+	for _, format := range []string{FormatCSV, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "series."+format)
+			var bets atomic.Int64
+			s, err := Create(path, start, Options{
+				Format: format,
+				Labels: map[string]string{"run_id": "r1"},
+				Extra: []Column{
+					Rate("bets", bets.Load),
+					{Name: "cpu", Value: func() string { return "unknown" }},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			manual(s)
+			record(s, &bets)
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			metrics, err := ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[string][]float64{
+				"started":         {3, 1, 0},
+				"successful":      {2, 1, 0},
+				"failed":          {1, 0, 0},
+				"mean_latency_ms": {20, 20, 0},
+				"failure_rate":    {0.3333, 0, 0},
+				"bets":            {5, 2, 0},
+				// A gauge that is not a number leaves gaps.
+				"cpu": {},
+			}
+			for name, w := range want {
+				if got, ok := values(metrics, name); !ok || !reflect.DeepEqual(got, w) {
+					t.Errorf("%s = %v, want %v", name, got, w)
+				}
+			}
+			if p99, _ := values(metrics, "p99_latency_ms"); p99[0] != 30 {
+				t.Errorf("p99 latency = %v, want the slowest, 30ms, first", p99)
+			}
+			if p50, _ := values(metrics, "p50_latency_ms"); p50[1] != 20 {
+				t.Errorf("p50 latency = %v, want the only success, 20ms, second", p50)
+			}
+			classified := 0.0
+			for _, m := range metrics {
+				if strings.HasPrefix(m.Metric, "failed_") {
+					classified += m.Points[0][1]
+				}
+			}
+			if classified != 1 {
+				t.Errorf("%g failures classified in the first second, want 1", classified)
+			}
+			for _, m := range metrics {
+				for i, p := range m.Points {
+					if want := float64(start.Add(time.Duration(i) * time.Second).UnixMilli()); m.Metric != "cpu" && p[0] != want {
+						t.Errorf("%s point %d at %g, want %g", m.Metric, i, p[0], want)
+					}
+				}
+				if wantLabel := map[string]string{"run_id": "r1"}; format == FormatJSON && !reflect.DeepEqual(m.Labels, wantLabel) {
+					t.Errorf("%s labels = %v, want %v", m.Metric, m.Labels, wantLabel)
+				}
+			}
+			if best, worst, ok := s.BestWorst(); !ok || best.Second != 0 || worst.Second != 0 {
+				t.Errorf("best and worst seconds = %d, %d, want the first", best.Second, worst.Second)
+			}
+		})
+	}
+}
+
+func TestSeriesRollover(t *testing.T) {
+	start := time.Unix(1735689600, 0)
+	for _, format := range []string{FormatCSV, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			path, archive := filepath.Join(dir, "series."+format), filepath.Join(dir, "series-1."+format)
+			s, err := Create(path, start, Options{Format: format})
+			if err != nil {
+				t.Fatal(err)
+			}
+			manual(s)
+			record(s, new(atomic.Int64))
+			if err := s.Rollover(archive); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			for file, want := range map[string][]float64{archive: {3}, path: {1, 0}} {
+				metrics, err := ReadFile(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, _ := values(metrics, "started"); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: started = %v, want %v", filepath.Base(file), got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateUnknownFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "series.xml")
+	if _, err := Create(path, time.Now(), Options{Format: "xml"}); err == nil {
+		t.Error("an unknown format was accepted")
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("an unknown format created the file")
+	}
+}
+
+func TestNilSeries(t *testing.T) {
+	var s *Series
+	s.Started()
+	s.Succeeded(time.Millisecond)
+	s.Failed(errors.New("boom"))
+	if err := s.Rollover("archive"); err != nil {
+		t.Error(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, _, ok := s.BestWorst(); ok {
+		t.Error("a nil series wrote seconds")
+	}
+}
@@ -0,0 +1,117 @@
+// Package transcript records the server messages a run receives as NDJSON,
+// one Record per line, so games can be analysed or replayed offline.
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"elastic-ai-jam-2025/internal/sink"
+)
+
+// Record is one received message.
+type Record struct {
+	Time    time.Time       `json:"time"`
+	RunID   string          `json:"run_id,omitempty"`
+	Player  string          `json:"player"`
+	GameID  string          `json:"game_id,omitempty"`
+	Message json.RawMessage `json:"message"`
+	// Anomaly, when set, marks a record that repeats an already recorded
+	// message to flag it, such as "duplicate"; see WriteAnomaly.
+	Anomaly string `json:"anomaly,omitempty"`
+}
+
+// queueSize bounds the records a Writer holds for its file: about a second
+// of messages at a large run's peak.
+const queueSize = 65536
+
+// Writer appends records to a file. It is safe for concurrent use by many
+// sessions, which never wait on the file: records are encoded by the caller
+// and written by a sink, which drops them when the disk falls behind.
+type Writer struct {
+	// RunID, when set before the first write, is stamped on every record.
+	RunID string
+
+	f    *os.File
+	sink *sink.Sink
+}
+
+// Create truncates or creates path and returns a Writer for it.
+func Create(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating transcript: %w", err)
+	}
+	return &Writer{f: f, sink: sink.New("transcript", queueSize, sink.Buffered(f))}, nil
+}
+
+// Write records raw as received by player while seated at gameID. The
+// message is copied, so raw may be reused afterwards. A nil Writer discards
+// everything, which lets callers skip the "is recording enabled" check.
+func (w *Writer) Write(player, gameID string, raw []byte) {
+	w.write(Record{Player: player, GameID: gameID, Message: raw})
+}
+
+// WriteAnomaly records that raw, already written as received, was flagged
+// as an anomaly of the given kind.
+func (w *Writer) WriteAnomaly(player, gameID, kind string, raw []byte) {
+	w.write(Record{Player: player, GameID: gameID, Message: raw, Anomaly: kind})
+}
+
+func (w *Writer) write(rec Record) {
+	if w == nil {
+		return
+	}
+	rec.Time = time.Now().UTC()
+	rec.RunID = w.RunID
+	var line bytes.Buffer
+	enc := json.NewEncoder(&line)
+	enc.SetEscapeHTML(false)
+	if enc.Encode(rec) == nil {
+		w.sink.Put(line.Bytes())
+	}
+}
+
+// Sink returns the sink writing the transcript, for its drop counts, or nil
+// for a nil Writer.
+func (w *Writer) Sink() *sink.Sink {
+	if w == nil {
+		return nil
+	}
+	return w.sink
+}
+
+// Close writes the records still queued and closes the file. It returns the
+// first error met while writing.
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+	err := w.sink.Close()
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Message returns the server message held by a transcript line. Lines that
+// are not a Record are returned unchanged, so files holding bare server
+// messages are accepted as well. Anomaly records repeat a message already
+// returned, so Message returns nil for them.
+func Message(line []byte) []byte {
+	var rec struct {
+		Player  *string         `json:"player"`
+		Message json.RawMessage `json:"message"`
+		Anomaly string          `json:"anomaly"`
+	}
+	if err := json.Unmarshal(line, &rec); err != nil || rec.Player == nil || len(rec.Message) == 0 || rec.Message[0] != '{' {
+		return line
+	}
+	if rec.Anomaly != "" {
+		return nil
+	}
+	return bytes.TrimSpace(rec.Message)
+}
@@ -0,0 +1,83 @@
+// Package tscmd implements the "timeseries" command, which works on the time
+// series files written with -timeseries-out.
+package tscmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/timeseries"
+)
+
+// Run is the entry point of the timeseries command.
+func Run(args []string) int {
+	if len(args) == 0 || args[0] != "merge" {
+		fmt.Fprintln(os.Stderr, "Usage: timeseries merge [flags] <series files...>")
+		return 2
+	}
+	return runMerge(args[1:])
+}
+
+func runMerge(args []string) int {
+	relative := false
+	out := ""
+	fs := flag.NewFlagSet("timeseries merge", flag.ContinueOnError)
+	fs.BoolVar(&relative, "relative", relative, "time the points of each run in milliseconds from its start, to overlay runs made at different times")
+	fs.StringVar(&out, "out", out, "write the merged JSON series to this file instead of stdout")
+	if code, stop := cli.Parse(fs, args); stop {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: timeseries merge [flags] <series files...>")
+		return 2
+	}
+
+	runs := make([]timeseries.Run, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		metrics, err := timeseries.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		runs = append(runs, timeseries.Run{ID: runID(path, metrics), Metrics: metrics})
+	}
+	merged := timeseries.Merge(runs, relative)
+
+	if out == "" {
+		if err := timeseries.WriteJSON(os.Stdout, merged); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		return 0
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	err = timeseries.WriteJSON(f, merged)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "Merged %d runs into %s\n", len(runs), out)
+	return 0
+}
+
+// runID is the run_id label of the file's series, or for files without one,
+// such as CSV files, the file name without its extension.
+func runID(path string, metrics []timeseries.Metric) string {
+	for _, m := range metrics {
+		if id := m.Labels["run_id"]; id != "" {
+			return id
+		}
+	}
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
@@ -0,0 +1,44 @@
+package tscmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"elastic-ai-jam-2025/internal/timeseries"
+)
+
+func TestMergeFiles(t *testing.T) {
+	dir := t.TempDir()
+	labeled := filepath.Join(dir, "play.json")
+	plain := filepath.Join(dir, "flood-night.csv")
+	if err := os.WriteFile(labeled, []byte(`[{"metric":"started","labels":{"run_id":"4f2a9c"},"points":[[5000,3],[6000,4]]}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(plain, []byte("second,unix_time,started\n0,100,7\n1,101,8\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "merged.json")
+	if code := Run([]string{"merge", "-relative", "-out", out, labeled, plain}); code != 0 {
+		t.Fatalf("merge exited with %d", code)
+	}
+	got, err := timeseries.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []timeseries.Metric{
+		{Metric: "started", Labels: map[string]string{"run_id": "4f2a9c"}, Points: []timeseries.Point{{0, 3}, {1000, 4}}},
+		// A CSV file is named after its file.
+		{Metric: "started", Labels: map[string]string{"run_id": "flood-night"}, Points: []timeseries.Point{{0, 7}, {1000, 8}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("merged %+v, want %+v", got, want)
+	}
+
+	for _, args := range [][]string{nil, {"split"}, {"merge"}, {"merge", filepath.Join(dir, "missing.json")}} {
+		if code := Run(args); code != 2 {
+			t.Errorf("Run(%q) exited with %d, want 2", args, code)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package validate implements the "validate-protocol" command: it plays one
+// hand over the TCP protocol, or replays a recorded transcript, and checks
+// every server message against the shapes declared in pokerclient.
+package validate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"elastic-ai-jam-2025/internal/cli"
+	"elastic-ai-jam-2025/internal/pokerclient"
+	"elastic-ai-jam-2025/internal/report"
+	"elastic-ai-jam-2025/internal/transcript"
+)
+
+// Config is the configuration of a validate-protocol run.
+type Config struct {
+	cli.Common
+
+	Username string
+	Password string
+
+	// HandTimeout bounds the whole live session.
+	HandTimeout time.Duration
+
+	// Transcript, when set, is a file of recorded server messages checked
+	// offline instead of connecting: a play -transcript-out file, or bare
+	// messages one JSON object per line.
+	Transcript string
+}
+
+// DefaultConfig returns the validate-protocol defaults.
+func DefaultConfig() Config {
+	return Config{
+		Common:      cli.DefaultCommon(),
+		Username:    "validate-0",
+		Password:    "password0",
+		HandTimeout: 2 * time.Minute,
+	}
+}
+
+// RegisterFlags adds the validate-protocol flags to fs.
+func (cfg *Config) RegisterFlags(fs *flag.FlagSet) {
+	cfg.Common.Register(fs)
+	fs.StringVar(&cfg.Username, "username", cfg.Username, "player to register for the live session")
+	fs.StringVar(&cfg.Password, "password", cfg.Password, "password of the player")
+	fs.DurationVar(&cfg.HandTimeout, "hand-timeout", cfg.HandTimeout, "max duration of the live session")
+	fs.StringVar(&cfg.Transcript, "transcript", cfg.Transcript, "check the server messages recorded in this file instead of connecting")
+}
+
+func newFlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("validate-protocol", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	return fs
+}
+
+// Flags returns the validate-protocol flag set with default values.
+func Flags() *flag.FlagSet {
+	cfg := DefaultConfig()
+	return newFlagSet(&cfg)
+}
+
+// checker accumulates the deviations of the messages it is shown.
+type checker struct {
+	messages   int
+	deviations []pokerclient.Deviation
+}
+
+func (c *checker) check(raw []byte) {
+	c.messages++
+	devs := pokerclient.Validate(raw)
+	for _, d := range devs {
+		fmt.Printf("  DEVIATION %s\n", d)
+	}
+	c.deviations = append(c.deviations, devs...)
+}
+
+// Run is the entry point of the validate-protocol command. It exits 1 when
+// any deviation was found.
+func Run(args []string) int {
+	cfg := DefaultConfig()
+	fs := newFlagSet(&cfg)
+	if code, stop := cli.Parse(fs, args); stop {
+		return code
+	}
+	closeLog, err := cfg.SetupLogging()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer closeLog()
+	if cfg.Transcript == "" {
+		if err := cfg.CheckIdent(cfg.Username); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+
+	rep := report.New("validate-protocol", cli.Effective(fs))
+	var c checker
+	if cfg.Transcript != "" {
+		fmt.Printf("--- Validating transcript %s ---\n", cfg.Transcript)
+		err = checkTranscript(cfg.Transcript, &c)
+	} else {
+		fmt.Printf("--- Validating protocol against %s as %s ---\n", cfg.TCPServer, cfg.Username)
+		ctx, stop := cli.InterruptContext()
+		defer stop()
+		err = playOneHand(ctx, &cfg, &c)
+	}
+
+	fmt.Println("-----------------------------------------")
+	fmt.Printf("Messages checked: %d\n", c.messages)
+	fmt.Printf("Deviations: %d\n", len(c.deviations))
+	rep.Counters["messages"] = int64(c.messages)
+	rep.Counters["deviations"] = int64(len(c.deviations))
+
+	code, status, reason := 0, "", ""
+	switch {
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		code, status, reason = 1, report.StatusFailed, err.Error()
+	case len(c.deviations) > 0:
+		fmt.Println("Protocol drift detected.")
+		code, status, reason = 1, report.StatusFailed, fmt.Sprintf("%d deviations", len(c.deviations))
+	default:
+		fmt.Println("All messages match the expected shapes.")
+	}
+	rep.Finish(status, reason)
+	cfg.WriteReport(rep)
+	return code
+}
+
+// checkTranscript validates every non-empty line of path, skipping the
+// records that only flag an anomaly.
+func checkTranscript(path string, c *checker) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		if line := bytes.TrimSpace(sc.Bytes()); len(line) > 0 {
+			if msg := transcript.Message(line); msg != nil {
+				c.check(msg)
+			}
+		}
+	}
+	return sc.Err()
+}
+
+// playOneHand registers, joins a game and validates every message until the
+// first hand ends. When prompted to bet it places the minimum bet.
+func playOneHand(ctx context.Context, cfg *Config, c *checker) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.HandTimeout)
+	defer cancel()
+
+	conn, err := pokerclient.Dial(cfg.TCPServer.First(), cfg.ConnectTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stopClose := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stopClose()
+
+	resp, err := conn.Register(cfg.Username, cfg.Password)
+	if resp != nil {
+		c.check(resp.Raw)
+	}
+	if err != nil {
+		return err
+	}
+	if err := conn.Join(); err != nil {
+		return err
+	}
+	for {
+		resp, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("no complete hand within %s", cfg.HandTimeout)
+			}
+			if errors.Is(err, io.EOF) {
+				return errors.New("server closed the connection before the hand ended")
+			}
+			return err
+		}
+		c.check(resp.Raw)
+
+		switch resp.Type {
+		case pokerclient.TypeActionPlayerBet:
+			if resp.State.Player.PlayerID == cfg.Username {
+				move := pokerclient.Check()
+				if resp.MinimumBet > 0 {
+					move, _ = pokerclient.AllIn(resp.State.Player.Chips) // a fold with no chips
+					if resp.MinimumBet < resp.State.Player.Chips {
+						move, _ = pokerclient.Bet(resp.MinimumBet)
+					}
+				}
+				if err := conn.SendJSON(move.Msg()); err != nil {
+					return err
+				}
+			}
+		case pokerclient.TypePotWon, pokerclient.TypeGameOver, pokerclient.TypeLeaderboardEntryEnd:
+			fmt.Printf("Hand ended with %s.\n", resp.Type)
+			return nil
+		}
+	}
+}